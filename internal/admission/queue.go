@@ -0,0 +1,181 @@
+// Package admission implements priority-aware admission control for concurrent requests.
+package admission
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Priority is the admission class of a request.
+type Priority int
+
+const (
+	PriorityHigh Priority = iota
+	PriorityNormal
+	PriorityLow
+
+	numPriorities = 3
+)
+
+var priorityNames = [numPriorities]string{"high", "normal", "low"}
+
+// priorityWeights controls how queued requests share freed slots: while all classes are
+// waiting, high gets 4 of every 7 slots, normal 2 and low 1, so low priority never starves.
+var priorityWeights = [numPriorities]int{4, 2, 1}
+
+// ParsePriority parses a priority name. An empty value means normal priority.
+func ParsePriority(s string) (Priority, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return PriorityNormal, nil
+	}
+	for i, name := range priorityNames {
+		if s == name {
+			return Priority(i), nil
+		}
+	}
+	return PriorityNormal, fmt.Errorf("unknown priority %q, expected one of high, normal, low", s)
+}
+
+func (p Priority) String() string {
+	if p < 0 || p >= numPriorities {
+		return "unknown"
+	}
+	return priorityNames[p]
+}
+
+type waiter struct {
+	ready chan struct{}
+}
+
+// Queue limits the number of in-flight requests. When the limit is reached, requests wait
+// in one queue per priority and freed slots are handed out by weighted round robin.
+type Queue struct {
+	capacity int
+	timeout  time.Duration
+
+	mu             sync.Mutex
+	inFlight       int
+	waiters        [numPriorities][]*waiter
+	currentWeights [numPriorities]int
+}
+
+// NewQueue creates a Queue. With a zero timeout requests over the limit are rejected immediately.
+func NewQueue(capacity int, timeout time.Duration) *Queue {
+	return &Queue{
+		capacity: capacity,
+		timeout:  timeout,
+	}
+}
+
+// Acquire takes a slot, waiting up to the queue timeout. It reports false if no slot was
+// obtained. Every successful Acquire must be paired with a Release.
+func (q *Queue) Acquire(ctx context.Context, priority Priority) bool {
+	if priority < 0 || priority >= numPriorities {
+		priority = PriorityNormal
+	}
+
+	q.mu.Lock()
+	if q.inFlight < q.capacity && q.queuedLocked() == 0 {
+		q.inFlight++
+		q.mu.Unlock()
+		return true
+	}
+	if q.timeout <= 0 {
+		q.mu.Unlock()
+		return false
+	}
+	w := &waiter{ready: make(chan struct{})}
+	q.waiters[priority] = append(q.waiters[priority], w)
+	q.mu.Unlock()
+
+	timer := time.NewTimer(q.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+		return true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.removeLocked(priority, w) {
+		return false
+	}
+	// The slot was handed over while giving up, keep it so the caller releases it.
+	return true
+}
+
+// Release frees a slot, handing it directly to the next queued request if there is one.
+func (q *Queue) Release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if w := q.nextLocked(); w != nil {
+		close(w.ready)
+		return
+	}
+	q.inFlight--
+}
+
+// Stats returns the number of in-flight requests and queued requests per priority.
+func (q *Queue) Stats() (inFlight int, queued map[string]int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued = make(map[string]int, numPriorities)
+	for i, list := range q.waiters {
+		queued[priorityNames[i]] = len(list)
+	}
+	return q.inFlight, queued
+}
+
+func (q *Queue) queuedLocked() int {
+	total := 0
+	for _, list := range q.waiters {
+		total += len(list)
+	}
+	return total
+}
+
+// nextLocked picks the next waiter using smooth weighted round robin over the non-empty classes.
+func (q *Queue) nextLocked() *waiter {
+	best := -1
+	totalWeight := 0
+	for i := range q.waiters {
+		if len(q.waiters[i]) == 0 {
+			q.currentWeights[i] = 0
+			continue
+		}
+		q.currentWeights[i] += priorityWeights[i]
+		totalWeight += priorityWeights[i]
+		if best == -1 || q.currentWeights[i] > q.currentWeights[best] {
+			best = i
+		}
+	}
+	if best == -1 {
+		return nil
+	}
+
+	q.currentWeights[best] -= totalWeight
+	w := q.waiters[best][0]
+	q.waiters[best][0] = nil
+	q.waiters[best] = q.waiters[best][1:]
+	return w
+}
+
+func (q *Queue) removeLocked(priority Priority, w *waiter) bool {
+	list := q.waiters[priority]
+	for i, candidate := range list {
+		if candidate == w {
+			q.waiters[priority] = append(list[:i], list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package admission
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParsePriority(t *testing.T) {
+	cases := map[string]Priority{"": PriorityNormal, "high": PriorityHigh, " Low ": PriorityLow, "normal": PriorityNormal}
+	for input, want := range cases {
+		got, err := ParsePriority(input)
+		if err != nil || got != want {
+			t.Errorf("ParsePriority(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParsePriority("urgent"); err == nil {
+		t.Error("ParsePriority(\"urgent\") should fail")
+	}
+}
+
+func TestQueueRejectsWithoutTimeout(t *testing.T) {
+	q := NewQueue(1, 0)
+	if !q.Acquire(context.Background(), PriorityNormal) {
+		t.Fatal("first acquire should succeed")
+	}
+	if q.Acquire(context.Background(), PriorityHigh) {
+		t.Fatal("acquire over the limit should be rejected when queueing is off")
+	}
+	q.Release()
+	if !q.Acquire(context.Background(), PriorityLow) {
+		t.Fatal("acquire after release should succeed")
+	}
+}
+
+// TestQueueWeightedOrder checks that freed slots go to high priority first but low priority is not starved.
+func TestQueueWeightedOrder(t *testing.T) {
+	q := NewQueue(1, time.Minute)
+	if !q.Acquire(context.Background(), PriorityNormal) {
+		t.Fatal("first acquire should succeed")
+	}
+
+	admitted := make(chan Priority, 16)
+	enqueue := func(p Priority, n int) {
+		for range n {
+			go func() {
+				if q.Acquire(context.Background(), p) {
+					admitted <- p
+				}
+			}()
+		}
+	}
+	enqueue(PriorityLow, 4)
+	enqueue(PriorityHigh, 8)
+	waitQueued(t, q, 12)
+
+	var order []Priority
+	for range 7 {
+		q.Release()
+		order = append(order, <-admitted)
+	}
+
+	counts := map[Priority]int{}
+	for _, p := range order {
+		counts[p]++
+	}
+	if order[0] != PriorityHigh {
+		t.Errorf("first admitted = %v, want high", order[0])
+	}
+	// High and low share the slots 4:1 while both are waiting.
+	if counts[PriorityHigh] < 5 || counts[PriorityLow] < 1 {
+		t.Errorf("admission order %v does not follow the priority weights", order)
+	}
+}
+
+func TestQueueTimeout(t *testing.T) {
+	q := NewQueue(1, 20*time.Millisecond)
+	q.Acquire(context.Background(), PriorityNormal)
+	if q.Acquire(context.Background(), PriorityHigh) {
+		t.Fatal("acquire should time out while the slot is held")
+	}
+	if _, queued := q.Stats(); queued["high"] != 0 {
+		t.Fatalf("timed out waiter should leave the queue, got %v", queued)
+	}
+}
+
+func waitQueued(t *testing.T, q *Queue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		queued := q.queuedLocked()
+		q.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued requests", n)
+}
@@ -0,0 +1,119 @@
+// Package anomaly compares the hourly statistics of a group with the same hour of the
+// previous days and reports the metrics that rose far above their baseline.
+package anomaly
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Metrics of an hour of traffic.
+const (
+	MetricErrorRate     = "error_rate"
+	MetricRateLimitRate = "rate_limit_rate"
+	MetricLatency       = "avg_latency_ms"
+)
+
+// BaselineDays is how many previous days make up the baseline of an hour.
+const BaselineDays = 7
+
+// minBaselineSamples is the number of baseline days with enough requests needed to judge an hour.
+const minBaselineSamples = 3
+
+// Sample holds the counters of one group for one hour.
+type Sample struct {
+	Requests    int64
+	Failures    int64
+	RateLimited int64
+	DurationMs  int64
+}
+
+// value returns the metric of the sample: rates in percent, latency in milliseconds.
+func (s Sample) value(metric string) float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	switch metric {
+	case MetricErrorRate:
+		return float64(s.Failures) / float64(s.Requests) * 100
+	case MetricRateLimitRate:
+		return float64(s.RateLimited) / float64(s.Requests) * 100
+	default:
+		return float64(s.DurationMs) / float64(s.Requests)
+	}
+}
+
+// Anomaly is a metric of a group that rose more than the allowed deviations above its baseline.
+type Anomaly struct {
+	GroupID   uint      `json:"group_id"`
+	GroupName string    `json:"group_name"`
+	Hour      time.Time `json:"hour"`
+	Metric    string    `json:"metric"`
+	Value     float64   `json:"value"`
+	Baseline  float64   `json:"baseline"`
+	StdDev    float64   `json:"std_dev"`
+	// Sigma is the number of standard deviations between Value and Baseline.
+	Sigma float64 `json:"sigma"`
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("group %s: %s is %.1f at %s, baseline %.1f (+%.1f sigma)",
+		a.GroupName, a.Metric, a.Value, a.Hour.Format(time.RFC3339), a.Baseline, a.Sigma)
+}
+
+// Detect returns the metrics of current that exceed the mean of the baseline samples by more
+// than sigma standard deviations. Hours and baseline days with fewer than minRequests requests
+// are ignored, and only rises are reported. The deviation has a floor, 1 percentage point for
+// rates and 5% of the baseline (at least 10 ms) for latency, so that a flat baseline does not
+// turn every change into an anomaly.
+func Detect(current Sample, baseline []Sample, sigma float64, minRequests int64) []Anomaly {
+	if sigma <= 0 || current.Requests < max(minRequests, 1) {
+		return nil
+	}
+	var usable []Sample
+	for _, s := range baseline {
+		if s.Requests >= max(minRequests, 1) {
+			usable = append(usable, s)
+		}
+	}
+	if len(usable) < minBaselineSamples {
+		return nil
+	}
+
+	var anomalies []Anomaly
+	for _, metric := range []string{MetricErrorRate, MetricRateLimitRate, MetricLatency} {
+		mean, stdDev := meanStdDev(usable, metric)
+		floor := 1.0
+		if metric == MetricLatency {
+			floor = math.Max(mean*0.05, 10)
+		}
+		deviation := math.Max(stdDev, floor)
+
+		value := current.value(metric)
+		if deviations := (value - mean) / deviation; deviations > sigma {
+			anomalies = append(anomalies, Anomaly{
+				Metric:   metric,
+				Value:    value,
+				Baseline: mean,
+				StdDev:   stdDev,
+				Sigma:    deviations,
+			})
+		}
+	}
+	return anomalies
+}
+
+func meanStdDev(samples []Sample, metric string) (float64, float64) {
+	sum := 0.0
+	for _, s := range samples {
+		sum += s.value(metric)
+	}
+	mean := sum / float64(len(samples))
+	variance := 0.0
+	for _, s := range samples {
+		d := s.value(metric) - mean
+		variance += d * d
+	}
+	return mean, math.Sqrt(variance / float64(len(samples)))
+}
@@ -0,0 +1,35 @@
+package anomaly
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	baseline := []Sample{
+		{Requests: 1000, Failures: 10, RateLimited: 5, DurationMs: 1000 * 800},
+		{Requests: 1200, Failures: 14, RateLimited: 6, DurationMs: 1200 * 850},
+		{Requests: 900, Failures: 8, RateLimited: 4, DurationMs: 900 * 780},
+		{Requests: 5, Failures: 5, RateLimited: 5, DurationMs: 5 * 9000}, // too few requests
+	}
+
+	if got := Detect(Sample{Requests: 1100, Failures: 12, RateLimited: 6, DurationMs: 1100 * 820}, baseline, 3, 20); len(got) != 0 {
+		t.Errorf("Detect() on a normal hour = %v, want none", got)
+	}
+
+	got := Detect(Sample{Requests: 1000, Failures: 200, RateLimited: 5, DurationMs: 1000 * 2500}, baseline, 3, 20)
+	if len(got) != 2 || got[0].Metric != MetricErrorRate || got[1].Metric != MetricLatency {
+		t.Fatalf("Detect() = %v, want error rate and latency anomalies", got)
+	}
+	if got[0].Value != 20 || got[0].Sigma <= 3 {
+		t.Errorf("error rate anomaly = %+v, want value 20 above 3 sigma", got[0])
+	}
+
+	// Drops are not anomalies, and an hour without enough traffic is not judged
+	if got := Detect(Sample{Requests: 1000, DurationMs: 1000 * 100}, baseline, 3, 20); len(got) != 0 {
+		t.Errorf("Detect() on an improving hour = %v, want none", got)
+	}
+	if got := Detect(Sample{Requests: 10, Failures: 10}, baseline, 3, 20); len(got) != 0 {
+		t.Errorf("Detect() on a quiet hour = %v, want none", got)
+	}
+	if got := Detect(Sample{Requests: 1000, Failures: 500}, baseline[:2], 3, 20); len(got) != 0 {
+		t.Errorf("Detect() with a short baseline = %v, want none", got)
+	}
+}
@@ -8,15 +8,23 @@ import (
 	"sync"
 	"time"
 
+	"gpt-load/internal/channel"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
+	database "gpt-load/internal/db"
 	db "gpt-load/internal/db/migrations"
+	"gpt-load/internal/drain"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/grpcapi"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/providerstatus"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 	"gpt-load/internal/version"
 
 	"github.com/gin-gonic/gin"
@@ -30,15 +38,34 @@ type App struct {
 	engine            *gin.Engine
 	configManager     types.ConfigManager
 	settingsManager   *config.SystemSettingsManager
+	encryptionSvc     encryption.Service
 	groupManager      *services.GroupManager
 	logCleanupService *services.LogCleanupService
+	storeHygiene      *services.StoreHygieneService
+	poolReconcile     *services.PoolReconcileService
+	usageSnapshot     *services.UsageSnapshotService
+	anomalies         *services.AnomalyService
+	trash             *services.TrashService
+	keyRotation       *services.KeyRotationService
 	requestLogService *services.RequestLogService
+	requestLogWriter  *services.RequestLogWriter
 	cronChecker       *keypool.CronChecker
+	quotaReset        *keypool.QuotaResetScheduler
 	keyPoolProvider   *keypool.KeyProvider
+	channelFactory    *channel.Factory
 	proxyServer       *proxy.ProxyServer
+	canary            *proxy.CanaryRunner
+	elector           *cluster.Elector
+	providerStatus    *providerstatus.Monitor
+	subGroupManager   *services.SubGroupManager
+	adminAuth         *services.AdminAuthService
 	storage           store.Store
 	db                *gorm.DB
+	dbHealth          *database.HealthMonitor
+	drain             *drain.Controller
 	httpServer        *http.Server
+	grpcServer        *grpcapi.Server
+	stopWarmUp        context.CancelFunc
 }
 
 // AppParams defines the dependencies for the App.
@@ -47,30 +74,73 @@ type AppParams struct {
 	Engine            *gin.Engine
 	ConfigManager     types.ConfigManager
 	SettingsManager   *config.SystemSettingsManager
+	EncryptionSvc     encryption.Service
 	GroupManager      *services.GroupManager
 	LogCleanupService *services.LogCleanupService
+	StoreHygiene      *services.StoreHygieneService
+	PoolReconcile     *services.PoolReconcileService
+	UsageSnapshot     *services.UsageSnapshotService
+	Anomalies         *services.AnomalyService
+	Trash             *services.TrashService
+	KeyRotation       *services.KeyRotationService
 	RequestLogService *services.RequestLogService
+	RequestLogWriter  *services.RequestLogWriter
 	CronChecker       *keypool.CronChecker
+	QuotaReset        *keypool.QuotaResetScheduler
 	KeyPoolProvider   *keypool.KeyProvider
+	ChannelFactory    *channel.Factory
 	ProxyServer       *proxy.ProxyServer
+	Canary            *proxy.CanaryRunner
+	Elector           *cluster.Elector
+	ProviderStatus    *providerstatus.Monitor
+	SubGroupManager   *services.SubGroupManager
+	AdminAuth         *services.AdminAuthService
 	Storage           store.Store
 	DB                *gorm.DB
+	DBHealth          *database.HealthMonitor
+	Drain             *drain.Controller
+	GRPCServer        *grpcapi.Server
 }
 
 // NewApp is the constructor for App, with dependencies injected by dig.
 func NewApp(params AppParams) *App {
+	// The log_level setting overrides LOG_LEVEL and applies as soon as it changes
+	params.SettingsManager.OnChange(func(prev, next types.SystemSettings) {
+		if prev.LogLevel != next.LogLevel {
+			utils.ApplyLogLevel(next.LogLevel, params.ConfigManager.GetLogConfig().Level)
+		}
+	})
+
 	return &App{
 		engine:            params.Engine,
 		configManager:     params.ConfigManager,
 		settingsManager:   params.SettingsManager,
+		encryptionSvc:     params.EncryptionSvc,
 		groupManager:      params.GroupManager,
 		logCleanupService: params.LogCleanupService,
+		storeHygiene:      params.StoreHygiene,
+		poolReconcile:     params.PoolReconcile,
+		usageSnapshot:     params.UsageSnapshot,
+		anomalies:         params.Anomalies,
+		trash:             params.Trash,
+		keyRotation:       params.KeyRotation,
 		requestLogService: params.RequestLogService,
+		requestLogWriter:  params.RequestLogWriter,
 		cronChecker:       params.CronChecker,
+		quotaReset:        params.QuotaReset,
 		keyPoolProvider:   params.KeyPoolProvider,
+		channelFactory:    params.ChannelFactory,
 		proxyServer:       params.ProxyServer,
+		canary:            params.Canary,
+		elector:           params.Elector,
+		providerStatus:    params.ProviderStatus,
+		subGroupManager:   params.SubGroupManager,
+		adminAuth:         params.AdminAuth,
 		storage:           params.Storage,
 		db:                params.DB,
+		dbHealth:          params.DBHealth,
+		drain:             params.Drain,
+		grpcServer:        params.GRPCServer,
 	}
 }
 
@@ -81,14 +151,27 @@ func (a *App) Start() error {
 		return fmt.Errorf("failed to initialize i18n: %w", err)
 	}
 	logrus.Info("i18n initialized successfully.")
+
+	// 缓存预热失败时在后台重试，直到依赖就绪
+	warmUpCtx, stopWarmUp := context.WithCancel(context.Background())
+	a.stopWarmUp = stopWarmUp
 	
 	// Master 节点执行初始化
 	if a.configManager.IsMaster() {
 		logrus.Info("Starting as Master Node.")
 
-		if err := a.storage.Clear(); err != nil {
-			return fmt.Errorf("cache cleanup failed: %w", err)
+		// 集群模式下已有其他实例在运行时，共享存储中的密钥池和冷却状态仍在使用，不能清空重建
+		joiningCluster := a.elector.HasOtherMembers()
+		if joiningCluster {
+			logrus.Info("Joining a running cluster, keeping the shared store.")
+		} else {
+			// 清空前写入上次运行遗留在 Store 中的 Key 状态更新
+			a.keyPoolProvider.ReplayPendingStatusUpdates()
+			if err := a.storage.Clear(); err != nil {
+				return fmt.Errorf("cache cleanup failed: %w", err)
+			}
 		}
+		a.elector.Start()
 
 		// 数据库迁移
 		db.HandleLegacyIndexes(a.db)
@@ -99,6 +182,13 @@ func (a *App) Start() error {
 			&models.APIKey{},
 			&models.RequestLog{},
 			&models.GroupHourlyStat{},
+			&models.CanaryProbe{},
+			&models.UsageSnapshot{},
+			&models.UsageSnapshotEntry{},
+			&models.KeyRevealLog{},
+			&models.HoneypotHit{},
+			&models.AdminUser{},
+			&models.AdminSession{},
 		); err != nil {
 			return fmt.Errorf("database auto-migration failed: %w", err)
 		}
@@ -114,27 +204,57 @@ func (a *App) Start() error {
 		}
 		logrus.Info("System settings initialized in DB.")
 
-		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
+		// 根据 ADMIN_PASSWORD 创建管理员账号
+		if err := a.adminAuth.EnsureAccount(); err != nil {
+			return fmt.Errorf("failed to create the admin account: %w", err)
+		}
+
+		a.warmUp(warmUpCtx, "Loading system settings", func() error {
+			return a.settingsManager.Initialize(a.storage, a.encryptionSvc)
+		})
 
 		// 从数据库加载密钥到 Redis
-		if err := a.keyPoolProvider.LoadKeysFromDB(); err != nil {
-			return fmt.Errorf("failed to load keys into key pool: %w", err)
+		if !joiningCluster {
+			a.warmUp(warmUpCtx, "Loading keys into the key pool", func() error {
+				if err := a.keyPoolProvider.LoadKeysFromDB(); err != nil {
+					return err
+				}
+				logrus.Debug("API keys loaded into Redis cache by master.")
+				return nil
+			})
 		}
-		logrus.Debug("API keys loaded into Redis cache by master.")
 
-		// 仅 Master 节点启动的服务
+		// 仅 Master 节点启动的服务，集群模式下只有选举出的 Leader 执行定时任务
 		a.requestLogService.Start()
 		a.logCleanupService.Start()
+		a.storeHygiene.Start()
+		a.poolReconcile.Start()
+		a.usageSnapshot.Start()
+		a.anomalies.Start()
+		a.canary.Start()
+		a.trash.Start()
+		a.keyRotation.Start()
 		a.cronChecker.Start()
+		a.quotaReset.Start()
 	} else {
 		logrus.Info("Starting as Slave Node.")
-		a.settingsManager.Initialize(a.storage, a.groupManager, a.configManager.IsMaster())
+		a.elector.Start()
+		a.warmUp(warmUpCtx, "Loading system settings", func() error {
+			return a.settingsManager.Initialize(a.storage, a.encryptionSvc)
+		})
 	}
 
 	// 显示配置并启动所有后台服务
 	a.configManager.DisplayServerConfig()
 
-	a.groupManager.Initialize()
+	a.dbHealth.Start()
+	a.keyPoolProvider.Start()
+	a.requestLogWriter.Start()
+	a.channelFactory.StartHealthChecks()
+	a.providerStatus.Start()
+	a.subGroupManager.Start()
+
+	a.warmUp(warmUpCtx, "Loading groups", a.groupManager.Initialize)
 
 	// Create HTTP server
 	serverConfig := a.configManager.GetEffectiveServerConfig()
@@ -157,14 +277,48 @@ func (a *App) Start() error {
 		}
 	}()
 
+	if err := a.grpcServer.Start(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
+// warmUp runs a cache warm-up step. When it fails, e.g. because the database is not ready yet,
+// it is retried in the background until it succeeds instead of requiring a restart.
+func (a *App) warmUp(ctx context.Context, operation string, fn func() error) {
+	err := fn()
+	if err == nil {
+		return
+	}
+	logrus.Warnf("%s failed, retrying in the background: %v", operation, err)
+	go func() {
+		if utils.RetryWithBackoff(ctx, operation, fn) == nil {
+			logrus.Infof("%s completed.", operation)
+		}
+	}()
+}
+
 // Stop gracefully shuts down the application.
 func (a *App) Stop(ctx context.Context) {
 	logrus.Info("Shutting down server...")
 
 	serverConfig := a.configManager.GetEffectiveServerConfig()
+
+	if a.stopWarmUp != nil {
+		a.stopWarmUp()
+	}
+
+	// 进入排空模式：拒绝新的代理请求，并让 /health 返回 503，使负载均衡器摘除本实例
+	a.drain.Start("shutdown")
+	if serverConfig.DrainDelay > 0 {
+		logrus.Infof("Waiting %ds for load balancers to stop routing to this instance...", serverConfig.DrainDelay)
+		select {
+		case <-time.After(time.Duration(serverConfig.DrainDelay) * time.Second):
+		case <-ctx.Done():
+		}
+	}
+
 	totalTimeout := time.Duration(serverConfig.GracefulShutdownTimeout) * time.Second
 
 	// 动态计算 HTTP 关机超时时间，为后台服务固定预留 5 秒
@@ -179,18 +333,37 @@ func (a *App) Stop(ctx context.Context) {
 			logrus.Errorf("Error forcing HTTP server to close: %v", closeErr)
 		}
 	}
+	if inFlight := a.drain.InFlight(); inFlight > 0 {
+		logrus.Warnf("%d proxy requests were cut off by the shutdown timeout.", inFlight)
+	}
 	logrus.Info("HTTP server has been shut down.")
+	a.grpcServer.Stop(httpShutdownCtx)
 
 	// 使用原始的总超时 context 继续关闭其他后台服务
 	stoppableServices := []func(context.Context){
 		a.groupManager.Stop,
 		a.settingsManager.Stop,
+		a.keyPoolProvider.Stop,
+		a.requestLogWriter.Stop,
+		a.channelFactory.Stop,
+		a.dbHealth.Stop,
+		a.elector.Stop,
+		a.providerStatus.Stop,
+		a.subGroupManager.Stop,
 	}
 
 	if serverConfig.IsMaster {
 		stoppableServices = append(stoppableServices,
 			a.cronChecker.Stop,
+			a.quotaReset.Stop,
 			a.logCleanupService.Stop,
+			a.storeHygiene.Stop,
+			a.poolReconcile.Stop,
+			a.usageSnapshot.Stop,
+			a.anomalies.Stop,
+			a.canary.Stop,
+			a.trash.Stop,
+			a.keyRotation.Stop,
 			a.requestLogService.Stop,
 		)
 	}
@@ -0,0 +1,84 @@
+// Package archive stores archived data in a local directory or an S3-compatible bucket.
+package archive
+
+import (
+	"context"
+	"fmt"
+	"gpt-load/internal/types"
+	"os"
+	"path/filepath"
+)
+
+// Target receives archive objects.
+type Target interface {
+	// Put stores data under name, replacing any object of the same name.
+	Put(ctx context.Context, name string, data []byte) error
+	// Get returns the object stored under name.
+	Get(ctx context.Context, name string) ([]byte, error)
+	// String describes the target for logs.
+	String() string
+}
+
+// NewTarget returns the archive target of the configuration, or nil if none is configured.
+// An S3-compatible bucket takes precedence over a local directory.
+func NewTarget(cfg types.LogArchiveConfig) Target {
+	switch {
+	case cfg.S3Endpoint != "":
+		return newS3Target(cfg)
+	case cfg.Dir != "":
+		return &dirTarget{dir: cfg.Dir}
+	default:
+		return nil
+	}
+}
+
+// SettingsConfig returns the archive configuration of the archive_s3_* system settings, or
+// fallback when no endpoint is set there.
+func SettingsConfig(settings types.SystemSettings, fallback types.LogArchiveConfig) types.LogArchiveConfig {
+	if settings.ArchiveS3Endpoint == "" {
+		return fallback
+	}
+	return types.LogArchiveConfig{
+		S3Endpoint:  settings.ArchiveS3Endpoint,
+		S3Region:    settings.ArchiveS3Region,
+		S3Bucket:    settings.ArchiveS3Bucket,
+		S3Prefix:    settings.ArchiveS3Prefix,
+		S3AccessKey: settings.ArchiveS3AccessKey,
+		S3SecretKey: settings.ArchiveS3SecretKey,
+	}
+}
+
+type dirTarget struct {
+	dir string
+}
+
+func (t *dirTarget) Put(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(t.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	// Written to a temporary file first so a partial object is never taken for a complete one
+	path := filepath.Join(t.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write archive %s: %w", name, err)
+	}
+	return nil
+}
+
+func (t *dirTarget) Get(_ context.Context, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(t.dir, filepath.Base(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", name, err)
+	}
+	return data, nil
+}
+
+func (t *dirTarget) String() string {
+	return "dir:" + t.dir
+}
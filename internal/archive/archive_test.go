@@ -0,0 +1,98 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gpt-load/internal/types"
+)
+
+func TestDirTargetPut(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	target := NewTarget(types.LogArchiveConfig{Dir: dir})
+
+	if err := target.Put(context.Background(), "logs.ndjson.gz", []byte("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "logs.ndjson.gz"))
+	if err != nil || string(got) != "data" {
+		t.Fatalf("archive content = %q, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "logs.ndjson.gz.tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file was left behind")
+	}
+	if got, err := target.Get(context.Background(), "logs.ndjson.gz"); err != nil || string(got) != "data" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+}
+
+func TestS3TargetPut(t *testing.T) {
+	var gotPath, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotAuth, gotBody = r.URL.EscapedPath(), r.Header.Get("Authorization"), string(body)
+	}))
+	defer server.Close()
+
+	target := newS3Target(types.LogArchiveConfig{
+		S3Endpoint:  server.URL,
+		S3Region:    "us-east-1",
+		S3Bucket:    "logs",
+		S3Prefix:    "gpt load/",
+		S3AccessKey: "AKID",
+		S3SecretKey: "secret",
+	})
+	target.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := target.Put(context.Background(), "a.ndjson.gz", []byte("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if gotPath != "/logs/gpt%20load/a.ndjson.gz" {
+		t.Errorf("path = %q", gotPath)
+	}
+	if gotBody != "data" {
+		t.Errorf("body = %q", gotBody)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/20250102/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=") {
+		t.Errorf("authorization = %q", gotAuth)
+	}
+}
+
+func TestNewTargetUnconfigured(t *testing.T) {
+	if target := NewTarget(types.LogArchiveConfig{S3Region: "us-east-1"}); target != nil {
+		t.Errorf("NewTarget() = %v, want nil", target)
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	type record struct {
+		ID   uint      `json:"id"`
+		Time time.Time `json:"time"`
+	}
+	first := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	records := []record{{ID: 1, Time: first}, {ID: 2, Time: first.Add(time.Hour)}}
+
+	data, err := Encode(records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := Decode[record](data)
+	if err != nil || len(decoded) != 2 || decoded[1].ID != 2 || !decoded[0].Time.Equal(first) {
+		t.Fatalf("Decode() = %+v, %v", decoded, err)
+	}
+
+	name := Name(KindHourlyStats, first, "1")
+	if name != "hourly-stats-20250102T030000Z-1.ndjson.gz" || KindOf("archive/"+name) != KindHourlyStats {
+		t.Errorf("Name() = %q, KindOf() = %q", name, KindOf(name))
+	}
+	if kind := KindOf("logs.ndjson.gz"); kind != "" {
+		t.Errorf("KindOf(unknown) = %q", kind)
+	}
+}
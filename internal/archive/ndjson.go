@@ -0,0 +1,78 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Kinds of archived data, used as the prefix of the object names.
+const (
+	KindRequestLogs = "request-logs"
+	KindHourlyStats = "hourly-stats"
+)
+
+// Name returns the object name of a batch of records of a kind, after the time and the ID
+// of its first record.
+func Name(kind string, first time.Time, id string) string {
+	return fmt.Sprintf("%s-%s-%s.ndjson.gz", kind, first.UTC().Format("20060102T150405Z"), id)
+}
+
+// KindOf returns the kind of an archive from its object or file name, or "" if unknown.
+func KindOf(name string) string {
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	for _, kind := range []string{KindRequestLogs, KindHourlyStats} {
+		if strings.HasPrefix(base, kind+"-") {
+			return kind
+		}
+	}
+	return ""
+}
+
+// Encode writes records as gzip-compressed NDJSON, one JSON object per line.
+func Encode[T any](records []T) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	for i := range records {
+		if err := encoder.Encode(&records[i]); err != nil {
+			return nil, fmt.Errorf("failed to encode record %d: %w", i, err)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress records: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode reads the records of gzip-compressed NDJSON written by Encode.
+func Decode[T any](data []byte) ([]T, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress archive: %w", err)
+	}
+	defer gz.Close()
+
+	var records []T
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record T
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	return records, nil
+}
@@ -0,0 +1,167 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"gpt-load/internal/types"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// s3Target uploads objects with path-style PUT requests signed with AWS Signature Version 4,
+// which AWS S3, MinIO, Cloudflare R2 and most other S3-compatible stores accept.
+type s3Target struct {
+	endpoint  string
+	region    string
+	bucket    string
+	prefix    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+	now       func() time.Time
+}
+
+func newS3Target(cfg types.LogArchiveConfig) *s3Target {
+	return &s3Target{
+		endpoint:  strings.TrimRight(cfg.S3Endpoint, "/"),
+		region:    cfg.S3Region,
+		bucket:    cfg.S3Bucket,
+		prefix:    cfg.S3Prefix,
+		accessKey: cfg.S3AccessKey,
+		secretKey: cfg.S3SecretKey,
+		client:    &http.Client{Timeout: 5 * time.Minute},
+		now:       time.Now,
+	}
+}
+
+func (t *s3Target) Put(ctx context.Context, name string, data []byte) error {
+	objectPath := "/" + uriEncode(t.bucket) + "/" + uriEncodePath(t.prefix+name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, t.endpoint+objectPath, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create archive upload request: %w", err)
+	}
+	req.ContentLength = int64(len(data))
+	t.sign(req, req.URL.EscapedPath(), data)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload archive %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to upload archive %s: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (t *s3Target) Get(ctx context.Context, name string) ([]byte, error) {
+	objectPath := "/" + uriEncode(t.bucket) + "/" + uriEncodePath(t.prefix+name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.endpoint+objectPath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create archive download request: %w", err)
+	}
+	t.sign(req, req.URL.EscapedPath(), nil)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("failed to download archive %s: status %d: %s", name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download archive %s: %w", name, err)
+	}
+	return data, nil
+}
+
+func (t *s3Target) String() string {
+	return fmt.Sprintf("s3:%s/%s/%s", t.endpoint, t.bucket, t.prefix)
+}
+
+// sign adds the AWS Signature Version 4 headers of a request without a query string.
+func (t *s3Target) sign(req *http.Request, canonicalURI string, payload []byte) {
+	now := t.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI,
+		"",
+		"host:" + req.URL.Host,
+		"x-amz-content-sha256:" + payloadHash,
+		"x-amz-date:" + amzDate,
+		"",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + t.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		sha256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+t.secretKey), date)
+	key = hmacSHA256(key, t.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.accessKey, scope, signedHeaders, signature))
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncodePath encodes an object key for the canonical request, keeping the slashes.
+func uriEncodePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// uriEncode percent-encodes everything except the unreserved characters, as SigV4 requires.
+func uriEncode(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,155 @@
+// Package balancing weights the sub-groups of an aggregate group by their live capacity, so
+// that traffic moves away from a sub-group that runs out of keys or quota before it is
+// exhausted.
+package balancing
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	defaultInterval = 30 * time.Second
+	// MinInterval is the shortest time between two evaluations, and how often they are due.
+	MinInterval   = 5 * time.Second
+	defaultWindow = 5 * time.Minute
+
+	defaultKeyExponent       = 1
+	defaultRateLimitExponent = 2
+	defaultLatencyExponent   = 1
+
+	// minSamples is the number of requests in the window below which the 429 rate and the
+	// latency of a sub-group are not trusted.
+	minSamples = 10
+	// scale is the effective weight of a score of 1, which keeps the resolution of small
+	// static weights.
+	scale = 100
+)
+
+// Config is the capacity balancing of an aggregate group, set as "capacity_balancing" in
+// the group config.
+//
+// The score of a sub-group is its static weight multiplied by
+//
+//	(active keys / most active keys of a sub-group) ^ key_exponent
+//	(1 - share of 429 responses) ^ rate_limit_exponent
+//	(lowest average latency of a sub-group / average latency) ^ latency_exponent
+//
+// An exponent of 0 ignores a signal, a larger one makes the weights follow it more closely.
+type Config struct {
+	// IntervalSeconds is the time between two evaluations, 30 by default and at least 5.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// WindowSeconds is how far back the 429 rate and latency go, 300 by default.
+	WindowSeconds int `json:"window_seconds,omitempty"`
+	// KeyExponent, RateLimitExponent and LatencyExponent default to 1, 2 and 1.
+	KeyExponent       *float64 `json:"key_exponent,omitempty"`
+	RateLimitExponent *float64 `json:"rate_limit_exponent,omitempty"`
+	LatencyExponent   *float64 `json:"latency_exponent,omitempty"`
+}
+
+// Parse converts the capacity_balancing value of a group config into a Config.
+func Parse(value any) (*Config, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("capacity_balancing is invalid: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("capacity_balancing is invalid: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if c.IntervalSeconds != 0 && time.Duration(c.IntervalSeconds)*time.Second < MinInterval {
+		return fmt.Errorf("interval_seconds must be at least %d", int(MinInterval.Seconds()))
+	}
+	if c.WindowSeconds < 0 {
+		return fmt.Errorf("window_seconds cannot be negative")
+	}
+	for _, exponent := range []*float64{c.KeyExponent, c.RateLimitExponent, c.LatencyExponent} {
+		if exponent != nil && (*exponent < 0 || *exponent > 10) {
+			return fmt.Errorf("exponents must be between 0 and 10")
+		}
+	}
+	return nil
+}
+
+// Interval returns the time between two evaluations.
+func (c *Config) Interval() time.Duration {
+	if c.IntervalSeconds > 0 {
+		return time.Duration(c.IntervalSeconds) * time.Second
+	}
+	return defaultInterval
+}
+
+// Window returns how far back the 429 rate and latency go.
+func (c *Config) Window() time.Duration {
+	if c.WindowSeconds > 0 {
+		return time.Duration(c.WindowSeconds) * time.Second
+	}
+	return defaultWindow
+}
+
+// Signals are the live capacity of a sub-group.
+type Signals struct {
+	ActiveKeys int64 `json:"active_keys"`
+	// Requests, RateLimited and AvgLatencyMs cover the window.
+	Requests     int     `json:"requests"`
+	RateLimited  int     `json:"rate_limited"`
+	AvgLatencyMs float64 `json:"avg_latency_ms"`
+}
+
+func (s Signals) trusted() bool {
+	return s.Requests >= minSamples
+}
+
+// Weights returns the effective weights of sub-groups from their static weights and signals.
+// Every sub-group keeps a weight of at least 1, so that it is still tried and its signals
+// can recover.
+func (c *Config) Weights(weights []int, signals []Signals) []int {
+	var mostKeys int64
+	lowestLatency := math.Inf(1)
+	for _, s := range signals {
+		mostKeys = max(mostKeys, s.ActiveKeys)
+		if s.trusted() && s.AvgLatencyMs > 0 {
+			lowestLatency = min(lowestLatency, s.AvgLatencyMs)
+		}
+	}
+
+	keyExponent := exponent(c.KeyExponent, defaultKeyExponent)
+	rateLimitExponent := exponent(c.RateLimitExponent, defaultRateLimitExponent)
+	latencyExponent := exponent(c.LatencyExponent, defaultLatencyExponent)
+
+	effective := make([]int, len(weights))
+	for i, weight := range weights {
+		s := signals[i]
+		score := float64(weight)
+		if mostKeys > 0 {
+			score *= math.Pow(float64(s.ActiveKeys)/float64(mostKeys), keyExponent)
+		}
+		if s.trusted() {
+			score *= math.Pow(1-float64(s.RateLimited)/float64(s.Requests), rateLimitExponent)
+			if s.AvgLatencyMs > 0 && !math.IsInf(lowestLatency, 1) {
+				score *= math.Pow(lowestLatency/s.AvgLatencyMs, latencyExponent)
+			}
+		}
+		effective[i] = max(int(math.Round(score*scale)), 1)
+	}
+	return effective
+}
+
+func exponent(value *float64, fallback float64) float64 {
+	if value != nil {
+		return *value
+	}
+	return fallback
+}
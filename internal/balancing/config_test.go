@@ -0,0 +1,70 @@
+package balancing
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	config, err := Parse(map[string]any{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Interval() != 30*time.Second || config.Window() != 5*time.Minute {
+		t.Errorf("defaults = %v, %v, want 30s and 5m", config.Interval(), config.Window())
+	}
+
+	for _, invalid := range []map[string]any{
+		{"interval_seconds": 1},
+		{"window_seconds": -1},
+		{"rate_limit_exponent": -1},
+		{"key_exponent": "high"},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestWeights(t *testing.T) {
+	config, _ := Parse(map[string]any{})
+
+	// Equal static weights: the sub-group with half the keys gets half the weight
+	got := config.Weights([]int{1, 1}, []Signals{{ActiveKeys: 10}, {ActiveKeys: 5}})
+	if want := []int{100, 50}; !slices.Equal(got, want) {
+		t.Errorf("Weights() by keys = %v, want %v", got, want)
+	}
+
+	// Half of the requests rate limited with the default exponent of 2 leaves a quarter
+	got = config.Weights([]int{2, 2}, []Signals{
+		{ActiveKeys: 4, Requests: 20},
+		{ActiveKeys: 4, Requests: 20, RateLimited: 10},
+	})
+	if want := []int{200, 50}; !slices.Equal(got, want) {
+		t.Errorf("Weights() by 429 rate = %v, want %v", got, want)
+	}
+
+	// Twice as slow halves the weight, too few requests are not trusted
+	got = config.Weights([]int{1, 1, 1}, []Signals{
+		{ActiveKeys: 1, Requests: 10, AvgLatencyMs: 500},
+		{ActiveKeys: 1, Requests: 10, AvgLatencyMs: 1000},
+		{ActiveKeys: 1, Requests: 3, AvgLatencyMs: 100, RateLimited: 3},
+	})
+	if want := []int{100, 50, 100}; !slices.Equal(got, want) {
+		t.Errorf("Weights() by latency = %v, want %v", got, want)
+	}
+
+	// An exhausted sub-group keeps the minimum weight, an ignored signal changes nothing
+	zero := 0.0
+	config.KeyExponent = &zero
+	got = config.Weights([]int{1, 1}, []Signals{{ActiveKeys: 8}, {ActiveKeys: 0}})
+	if want := []int{100, 100}; !slices.Equal(got, want) {
+		t.Errorf("Weights() with key_exponent 0 = %v, want %v", got, want)
+	}
+	config.KeyExponent = nil
+	got = config.Weights([]int{1, 1}, []Signals{{ActiveKeys: 8}, {ActiveKeys: 0}})
+	if want := []int{100, 1}; !slices.Equal(got, want) {
+		t.Errorf("Weights() with an exhausted sub-group = %v, want %v", got, want)
+	}
+}
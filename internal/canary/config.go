@@ -0,0 +1,139 @@
+// Package canary describes the synthetic probe requests sent through a group to monitor it
+// when organic traffic is low.
+package canary
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultInterval         = 5 * time.Minute
+	minInterval             = 30 * time.Second
+	defaultTimeout          = 30 * time.Second
+	defaultFailureThreshold = 2
+)
+
+// Config is the canary of a group, set as "canary" in the group config.
+type Config struct {
+	// IntervalSeconds is the time between two probes, 300 by default and at least 30.
+	IntervalSeconds int `json:"interval_seconds,omitempty"`
+	// Path and Body replace the default probe of the channel, a 1-token completion of the test
+	// model. Path is relative to the proxy endpoint of the group, e.g. /v1/chat/completions.
+	Path string          `json:"path,omitempty"`
+	Body json.RawMessage `json:"body,omitempty"`
+	// TimeoutSeconds bounds a probe, 30 by default.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// FailureThreshold is the number of consecutive failed probes that raises an alert, 2 by default.
+	FailureThreshold int `json:"failure_threshold,omitempty"`
+}
+
+// Parse converts the canary value of a group config into a Config.
+func Parse(value any) (*Config, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("canary is invalid: %w", err)
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("canary is invalid: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if c.IntervalSeconds != 0 && time.Duration(c.IntervalSeconds)*time.Second < minInterval {
+		return fmt.Errorf("interval_seconds must be at least %d", int(minInterval.Seconds()))
+	}
+	if c.TimeoutSeconds < 0 || c.FailureThreshold < 0 {
+		return fmt.Errorf("timeout_seconds and failure_threshold cannot be negative")
+	}
+	if c.Path != "" {
+		u, err := url.Parse(c.Path)
+		if err != nil || !strings.HasPrefix(c.Path, "/") || u.Host != "" {
+			return fmt.Errorf("path must be a relative path starting with /")
+		}
+	}
+	if len(c.Body) > 0 && !json.Valid(c.Body) {
+		return fmt.Errorf("body must be valid JSON")
+	}
+	return nil
+}
+
+// Interval returns the time between two probes.
+func (c *Config) Interval() time.Duration {
+	if c.IntervalSeconds > 0 {
+		return time.Duration(c.IntervalSeconds) * time.Second
+	}
+	return defaultInterval
+}
+
+// Timeout returns the time limit of a probe.
+func (c *Config) Timeout() time.Duration {
+	if c.TimeoutSeconds > 0 {
+		return time.Duration(c.TimeoutSeconds) * time.Second
+	}
+	return defaultTimeout
+}
+
+// Threshold returns the number of consecutive failures that raises an alert.
+func (c *Config) Threshold() int {
+	if c.FailureThreshold > 0 {
+		return c.FailureThreshold
+	}
+	return defaultFailureThreshold
+}
+
+// Request returns the method, path and body of a probe of a group of the given channel type
+// and test model. A probe with a custom path and no body is a GET.
+func (c *Config) Request(channelType, testModel string) (string, string, []byte) {
+	if c.Path != "" {
+		if len(c.Body) == 0 {
+			return http.MethodGet, c.Path, nil
+		}
+		return http.MethodPost, c.Path, c.Body
+	}
+
+	path, body := defaultProbe(channelType, testModel)
+	if len(c.Body) > 0 {
+		body = c.Body
+	}
+	return http.MethodPost, path, body
+}
+
+// defaultProbe is the cheapest completion of each channel: one output token, or the minimum
+// the API accepts.
+func defaultProbe(channelType, testModel string) (string, []byte) {
+	message := []map[string]string{{"role": "user", "content": "hi"}}
+	var path string
+	var payload map[string]any
+	switch channelType {
+	case "anthropic":
+		path = "/v1/messages"
+		payload = map[string]any{"model": testModel, "max_tokens": 1, "messages": message}
+	case "gemini":
+		path = "/v1beta/models/" + testModel + ":generateContent"
+		payload = map[string]any{
+			"contents":         []map[string]any{{"role": "user", "parts": []map[string]string{{"text": "hi"}}}},
+			"generationConfig": map[string]any{"maxOutputTokens": 1},
+		}
+	case "openai-response":
+		path = "/v1/responses"
+		payload = map[string]any{"model": testModel, "input": "hi", "max_output_tokens": 16}
+	default:
+		path = "/v1/chat/completions"
+		payload = map[string]any{"model": testModel, "max_tokens": 1, "messages": message}
+	}
+	body, _ := json.Marshal(payload)
+	return path, body
+}
@@ -0,0 +1,45 @@
+package canary
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigRequest(t *testing.T) {
+	config, err := Parse(map[string]any{})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Interval() != 5*time.Minute || config.Threshold() != 2 {
+		t.Errorf("defaults = %v, %d, want 5m and 2", config.Interval(), config.Threshold())
+	}
+
+	method, path, body := config.Request("gemini", "gemini-2.0-flash")
+	if method != http.MethodPost || path != "/v1beta/models/gemini-2.0-flash:generateContent" || !strings.Contains(string(body), `"maxOutputTokens":1`) {
+		t.Errorf("Request(gemini) = %s %s %s", method, path, body)
+	}
+	_, path, body = config.Request("anthropic", "claude-3-5-haiku")
+	if path != "/v1/messages" || !strings.Contains(string(body), `"max_tokens":1`) {
+		t.Errorf("Request(anthropic) = %s %s", path, body)
+	}
+
+	custom, err := Parse(map[string]any{"path": "/v1/models"})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if method, path, body := custom.Request("openai", "gpt-4o-mini"); method != http.MethodGet || path != "/v1/models" || body != nil {
+		t.Errorf("Request() with a custom path = %s %s %s, want GET /v1/models", method, path, body)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"interval_seconds": 10},
+		{"path": "https://example.com/v1/models"},
+		{"failure_threshold": -1},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) should fail", invalid)
+		}
+	}
+}
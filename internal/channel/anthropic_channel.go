@@ -73,6 +73,16 @@ func (ch *AnthropicChannel) ExtractModel(c *gin.Context, bodyBytes []byte) strin
 	return ""
 }
 
+// DisableStream turns a streaming messages request into a non-streaming one.
+func (ch *AnthropicChannel) DisableStream(req *http.Request, bodyBytes []byte) ([]byte, error) {
+	return disableBodyStream(bodyBytes)
+}
+
+// SimulateStream replays a message as its stream events.
+func (ch *AnthropicChannel) SimulateStream(req *http.Request, bodyBytes []byte) ([]byte, string, error) {
+	return simulateAnthropicStream(bodyBytes)
+}
+
 // ValidateKey checks if the given API key is valid by making a messages request.
 func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
 	upstreamURL := ch.getUpstreamURL()
@@ -112,6 +122,7 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 	req.Header.Set("x-api-key", apiKey.KeyValue)
 	req.Header.Set("anthropic-version", "2023-06-01")
 	req.Header.Set("Content-Type", "application/json")
+	ch.ApplyUpstreamAuth(req)
 
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
@@ -139,5 +150,5 @@ func (ch *AnthropicChannel) ValidateKey(ctx context.Context, apiKey *models.APIK
 	// Use the new parser to extract a clean error message.
 	parsedError := app_errors.ParseUpstreamError(errorBody)
 
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	return false, &ValidationError{StatusCode: resp.StatusCode, Message: parsedError}
 }
@@ -12,6 +12,8 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
@@ -22,6 +24,40 @@ type UpstreamInfo struct {
 	URL           *url.URL
 	Weight        int
 	CurrentWeight int
+
+	// Upstream-level credentials, sent in AuthHeader in addition to the pool key
+	AuthHeader string
+	authTokens []string
+	authCursor uint64
+
+	// Outbound proxy of the upstream, overriding the group's proxy_url. Upstreams with a proxy
+	// or transport settings have their own clients
+	ProxyURL     string
+	httpClient   *http.Client
+	streamClient *http.Client
+
+	// Circuit breaker state, guarded by BaseChannel.upstreamLock
+	consecutiveFailures int
+	openUntil           time.Time
+	probeStartedAt      time.Time
+
+	// Health state, guarded by BaseChannel.upstreamLock
+	unhealthy       bool
+	lastHealthCheck time.Time
+	errorRate       float64
+	latencyMs       float64
+}
+
+// isAvailable reports whether the upstream may receive a request. An open breaker
+// lets a single half-open probe through once its cooldown has elapsed.
+func (up *UpstreamInfo) isAvailable(now time.Time, cooldown time.Duration) bool {
+	if up.openUntil.IsZero() {
+		return true
+	}
+	if now.Before(up.openUntil) {
+		return false
+	}
+	return up.probeStartedAt.IsZero() || now.Sub(up.probeStartedAt) > cooldown
 }
 
 // BaseChannel provides common functionality for channel proxies.
@@ -33,6 +69,7 @@ type BaseChannel struct {
 	TestModel          string
 	ValidationEndpoint string
 	upstreamLock       sync.Mutex
+	healthChecking     atomic.Bool
 
 	// Cached fields from the group for stale check
 	channelType         string
@@ -40,6 +77,10 @@ type BaseChannel struct {
 	effectiveConfig     *types.SystemSettings
 	modelRedirectRules  datatypes.JSONMap
 	modelRedirectStrict bool
+	channelDefinition   datatypes.JSON
+
+	// groupProxyURL is the decrypted outbound proxy of the group
+	groupProxyURL string
 }
 
 // getUpstreamURL selects an upstream URL using a smooth weighted round-robin algorithm.
@@ -54,13 +95,35 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 		return b.Upstreams[0].URL
 	}
 
+	now := time.Now()
+	cooldown := b.circuitBreakerCooldown()
+	candidates := make([]*UpstreamInfo, 0, len(b.Upstreams))
+	for i := range b.Upstreams {
+		if !b.Upstreams[i].unhealthy && b.Upstreams[i].isAvailable(now, cooldown) {
+			candidates = append(candidates, &b.Upstreams[i])
+		}
+	}
+	// 所有上游熔断或不健康时仍然尝试全部上游，避免直接失败
+	if len(candidates) == 0 {
+		for i := range b.Upstreams {
+			candidates = append(candidates, &b.Upstreams[i])
+		}
+	}
+
+	fastestLatencyMs := 0.0
+	for _, up := range candidates {
+		if up.latencyMs > 0 && (fastestLatencyMs == 0 || up.latencyMs < fastestLatencyMs) {
+			fastestLatencyMs = up.latencyMs
+		}
+	}
+
 	totalWeight := 0
 	var best *UpstreamInfo
 
-	for i := range b.Upstreams {
-		up := &b.Upstreams[i]
-		totalWeight += up.Weight
-		up.CurrentWeight += up.Weight
+	for _, up := range candidates {
+		weight := up.scaledWeight(fastestLatencyMs)
+		totalWeight += weight
+		up.CurrentWeight += weight
 
 		if best == nil || up.CurrentWeight > best.CurrentWeight {
 			best = up
@@ -72,9 +135,88 @@ func (b *BaseChannel) getUpstreamURL() *url.URL {
 	}
 
 	best.CurrentWeight -= totalWeight
+	if !best.openUntil.IsZero() && !now.Before(best.openUntil) {
+		best.probeStartedAt = now
+	}
 	return best.URL
 }
 
+// ReportUpstreamResult feeds the outcome of a request into the circuit breaker and
+// passive health score of the upstream it was sent to.
+func (b *BaseChannel) ReportUpstreamResult(upstreamURL string, success bool, latency time.Duration) {
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+
+	up := b.findUpstream(upstreamURL)
+	if up == nil {
+		return
+	}
+
+	up.recordPassiveResult(success, latency)
+
+	if success {
+		if !up.openUntil.IsZero() {
+			logrus.WithField("upstream", up.URL.String()).Info("Upstream recovered, circuit breaker closed")
+		}
+		up.consecutiveFailures = 0
+		up.openUntil = time.Time{}
+		up.probeStartedAt = time.Time{}
+		return
+	}
+
+	up.consecutiveFailures++
+	up.probeStartedAt = time.Time{}
+	threshold := b.effectiveConfig.CircuitBreakerThreshold
+	if threshold > 0 && up.consecutiveFailures >= threshold {
+		if up.openUntil.IsZero() {
+			logrus.WithFields(logrus.Fields{
+				"upstream": up.URL.String(),
+				"failures": up.consecutiveFailures,
+			}).Warn("Upstream circuit breaker opened")
+		}
+		up.openUntil = time.Now().Add(b.circuitBreakerCooldown())
+	}
+}
+
+// UpstreamBaseURL returns the redacted base URL of the upstream serving upstreamURL, or
+// upstreamURL itself when no upstream matches.
+func (b *BaseChannel) UpstreamBaseURL(upstreamURL string) string {
+	up := b.findUpstream(upstreamURL)
+	if up == nil {
+		return upstreamURL
+	}
+	return up.URL.Redacted()
+}
+
+// findUpstream returns the upstream with the longest base URL that prefixes the request URL.
+func (b *BaseChannel) findUpstream(upstreamURL string) *UpstreamInfo {
+	var found *UpstreamInfo
+	longest := -1
+	for i := range b.Upstreams {
+		base := strings.TrimRight(b.Upstreams[i].URL.String(), "/")
+		if strings.HasPrefix(upstreamURL, base) && len(base) > longest {
+			found = &b.Upstreams[i]
+			longest = len(base)
+		}
+	}
+	return found
+}
+
+// ApplyUpstreamAuth injects the upstream-level credential of the upstream serving req.
+// Tokens of an upstream rotate round-robin, independently of the pool keys.
+func (b *BaseChannel) ApplyUpstreamAuth(req *http.Request) {
+	up := b.findUpstream(req.URL.String())
+	if up == nil || up.AuthHeader == "" || len(up.authTokens) == 0 {
+		return
+	}
+	next := atomic.AddUint64(&up.authCursor, 1) - 1
+	req.Header.Set(up.AuthHeader, up.authTokens[next%uint64(len(up.authTokens))])
+}
+
+func (b *BaseChannel) circuitBreakerCooldown() time.Duration {
+	return time.Duration(b.effectiveConfig.CircuitBreakerCooldownSeconds) * time.Second
+}
+
 // BuildUpstreamURL constructs the target URL for the upstream service.
 func (b *BaseChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error) {
 	base := b.getUpstreamURL()
@@ -118,6 +260,9 @@ func (b *BaseChannel) IsConfigStale(group *models.Group) bool {
 	if b.modelRedirectStrict != group.ModelRedirectStrict {
 		return true
 	}
+	if !bytes.Equal(b.channelDefinition, group.ChannelDefinition) {
+		return true
+	}
 	return false
 }
 
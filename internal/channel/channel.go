@@ -2,9 +2,11 @@ package channel
 
 import (
 	"context"
+	"fmt"
 	"gpt-load/internal/models"
 	"net/http"
 	"net/url"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -40,4 +42,51 @@ type ChannelProxy interface {
 
 	// TransformModelList transforms the model list response based on redirect rules.
 	TransformModelList(req *http.Request, bodyBytes []byte, group *models.Group) (map[string]any, error)
+
+	// ApplyUpstreamAuth injects the upstream-level credential, if any, of the upstream the request targets.
+	ApplyUpstreamAuth(req *http.Request)
+
+	// UpstreamBaseURL returns the configured base URL of the upstream serving upstreamURL, without credentials.
+	UpstreamBaseURL(upstreamURL string) string
+
+	// ReportUpstreamResult records whether the upstream serving upstreamURL responded healthily and how fast.
+	ReportUpstreamResult(upstreamURL string, success bool, latency time.Duration)
+
+	// TestConnectivity reports whether each upstream can be reached through its outbound proxy.
+	TestConnectivity(ctx context.Context) []UpstreamConnectivity
+}
+
+// ValidationError is returned by ValidateKey when the upstream answers with an error status.
+type ValidationError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("[status %d] %s", e.StatusCode, e.Message)
+}
+
+// RequestBodyTransformer is implemented by channels that rewrite the request body before it is sent upstream.
+type RequestBodyTransformer interface {
+	TransformRequestBody(bodyBytes []byte) ([]byte, error)
+}
+
+// ResponseBodyTransformer is implemented by channels that rewrite non-streaming success responses.
+type ResponseBodyTransformer interface {
+	TransformResponseBody(bodyBytes []byte) ([]byte, error)
+}
+
+// ErrorClassifier is implemented by channels that decide themselves whether an upstream error should fail over.
+type ErrorClassifier interface {
+	ClassifyError(statusCode int, bodyBytes []byte) ErrorAction
+}
+
+// StreamSimulator is implemented by channels that can serve a streaming request from a
+// non-streaming upstream call, replaying the complete response as the events of its stream.
+type StreamSimulator interface {
+	// DisableStream turns the upstream request into a non-streaming one and returns its body.
+	DisableStream(req *http.Request, bodyBytes []byte) ([]byte, error)
+	// SimulateStream converts a complete response to the client request into its stream,
+	// returned with its content type.
+	SimulateStream(req *http.Request, bodyBytes []byte) ([]byte, string, error)
 }
@@ -0,0 +1,218 @@
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	Register(CustomChannelType, newCustomChannel)
+}
+
+// CustomChannel is a generic HTTP channel whose behavior is described by the group's channel definition.
+type CustomChannel struct {
+	*BaseChannel
+	definition *CustomDefinition
+}
+
+func newCustomChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
+	definition, err := ParseCustomDefinition(group.ChannelDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("invalid channel definition for group %s: %w", group.Name, err)
+	}
+
+	base, err := f.newBaseChannel(CustomChannelType, group)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CustomChannel{
+		BaseChannel: base,
+		definition:  definition,
+	}, nil
+}
+
+// BuildUpstreamURL constructs the upstream URL after applying the definition's path mappings.
+func (ch *CustomChannel) BuildUpstreamURL(originalURL *url.URL, groupName string) (string, error) {
+	base := ch.getUpstreamURL()
+	if base == nil {
+		return "", fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	finalURL := *base
+	requestPath := strings.TrimPrefix(originalURL.Path, "/proxy/"+groupName)
+	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + ch.definition.MapPath(requestPath)
+	finalURL.RawQuery = originalURL.RawQuery
+
+	return finalURL.String(), nil
+}
+
+// ModifyRequest injects the key according to the definition's auth template.
+func (ch *CustomChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
+	headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+	ch.definition.ApplyAuth(req, utils.ResolveHeaderVariables(ch.definition.Auth.Template, headerCtx))
+}
+
+// IsStreamRequest checks the Accept header, the stream query and the configured stream field.
+func (ch *CustomChannel) IsStreamRequest(c *gin.Context, bodyBytes []byte) bool {
+	if strings.Contains(c.GetHeader("Accept"), "text/event-stream") {
+		return true
+	}
+
+	if c.Query("stream") == "true" {
+		return true
+	}
+
+	return ch.definition.isStreamBody(bodyBytes)
+}
+
+// ExtractModel reads the model from the configured model field.
+func (ch *CustomChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
+	return ch.definition.extractString(bodyBytes, ch.definition.ModelField)
+}
+
+// ApplyModelRedirect applies model redirection on the configured model field.
+func (ch *CustomChannel) ApplyModelRedirect(req *http.Request, bodyBytes []byte, group *models.Group) ([]byte, error) {
+	if len(group.ModelRedirectMap) == 0 || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+
+	var requestData map[string]any
+	if err := json.Unmarshal(bodyBytes, &requestData); err != nil {
+		return bodyBytes, nil
+	}
+
+	modelValue, _ := getJSONPath(requestData, ch.definition.ModelField)
+	model, ok := modelValue.(string)
+	if !ok {
+		return bodyBytes, nil
+	}
+
+	if targetModel, found := group.ModelRedirectMap[model]; found {
+		setJSONPath(requestData, ch.definition.ModelField, targetModel)
+
+		logrus.WithFields(logrus.Fields{
+			"group":          group.Name,
+			"original_model": model,
+			"target_model":   targetModel,
+			"channel":        "custom",
+		}).Debug("Model redirected")
+
+		return json.Marshal(requestData)
+	}
+
+	if group.ModelRedirectStrict {
+		return nil, fmt.Errorf("model '%s' is not configured in redirect rules", model)
+	}
+
+	return bodyBytes, nil
+}
+
+// TransformRequestBody implements RequestBodyTransformer.
+func (ch *CustomChannel) TransformRequestBody(bodyBytes []byte) ([]byte, error) {
+	return ch.definition.TransformRequest(bodyBytes)
+}
+
+// TransformResponseBody implements ResponseBodyTransformer.
+func (ch *CustomChannel) TransformResponseBody(bodyBytes []byte) ([]byte, error) {
+	return ch.definition.TransformResponse(bodyBytes)
+}
+
+// ClassifyError implements ErrorClassifier.
+func (ch *CustomChannel) ClassifyError(statusCode int, bodyBytes []byte) ErrorAction {
+	return ch.definition.ClassifyError(statusCode, bodyBytes)
+}
+
+// ValidateKey checks if the given API key is valid by sending the definition's validation request.
+func (ch *CustomChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
+	upstreamURL := ch.getUpstreamURL()
+	if upstreamURL == nil {
+		return false, fmt.Errorf("no upstream URL configured for channel %s", ch.Name)
+	}
+
+	// The group's validation endpoint takes precedence over the definition's path
+	validationPath := ch.ValidationEndpoint
+	if validationPath == "" {
+		validationPath = ch.definition.Validation.Path
+	}
+	if validationPath == "" {
+		return false, fmt.Errorf("no validation path configured for channel %s", ch.Name)
+	}
+
+	endpointURL, err := url.Parse(validationPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse validation endpoint: %w", err)
+	}
+
+	finalURL := *upstreamURL
+	finalURL.Path = strings.TrimRight(finalURL.Path, "/") + endpointURL.Path
+	finalURL.RawQuery = endpointURL.RawQuery
+
+	var body io.Reader
+	method := ch.definition.Validation.Method
+	if method != http.MethodGet && method != http.MethodHead {
+		payload := make(map[string]any, len(ch.definition.Validation.Body)+1)
+		for k, v := range ch.definition.Validation.Body {
+			payload[k] = v
+		}
+		setJSONPath(payload, ch.definition.ModelField, ch.TestModel)
+
+		payloadBytes, err := json.Marshal(payload)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal validation payload: %w", err)
+		}
+		payloadBytes, err = ch.definition.TransformRequest(payloadBytes)
+		if err != nil {
+			return false, fmt.Errorf("failed to transform validation payload: %w", err)
+		}
+		body = bytes.NewReader(payloadBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, finalURL.String(), body)
+	if err != nil {
+		return false, fmt.Errorf("failed to create validation request: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	ch.ModifyRequest(req, apiKey, group)
+	ch.ApplyUpstreamAuth(req)
+
+	// Apply custom header rules if available
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.HTTPClient.Do(req)
+	if err != nil {
+		// The key may be carried in the query string, so it can appear in transport errors
+		return false, fmt.Errorf("failed to send validation request: %s", utils.RedactSecret(err.Error(), apiKey.KeyValue))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return true, nil
+	}
+
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return false, fmt.Errorf("key is invalid (status %d), but failed to read error body: %w", resp.StatusCode, err)
+	}
+
+	parsedError := app_errors.ParseUpstreamError(errorBody)
+
+	return false, &ValidationError{StatusCode: resp.StatusCode, Message: parsedError}
+}
@@ -0,0 +1,422 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/failover"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CustomChannelType is the channel type of groups fully described by a CustomDefinition.
+const CustomChannelType = "custom"
+
+// ErrorAction decides how the proxy treats an upstream error response.
+type ErrorAction string
+
+const (
+	// ErrorActionDefault leaves the decision to the group's failover status codes.
+	ErrorActionDefault ErrorAction = ""
+	// ErrorActionFailover marks the key as failed and retries with another key.
+	ErrorActionFailover ErrorAction = "failover"
+	// ErrorActionPassthrough returns the upstream response to the client as-is.
+	ErrorActionPassthrough ErrorAction = "passthrough"
+)
+
+// Supported JSON transform operations.
+const (
+	TransformOpSet     = "set"
+	TransformOpDefault = "default"
+	TransformOpRemove  = "remove"
+	TransformOpRename  = "rename"
+)
+
+// CustomDefinition describes a generic HTTP channel entirely through configuration.
+type CustomDefinition struct {
+	Auth               CustomAuth           `json:"auth"`
+	PathMappings       []CustomPathMapping  `json:"path_mappings,omitempty"`
+	ModelField         string               `json:"model_field,omitempty"`
+	StreamField        string               `json:"stream_field,omitempty"`
+	RequestTransforms  []CustomTransform    `json:"request_transforms,omitempty"`
+	ResponseTransforms []CustomTransform    `json:"response_transforms,omitempty"`
+	ErrorRules         []CustomErrorRule    `json:"error_rules,omitempty"`
+	Validation         CustomValidationSpec `json:"validation"`
+}
+
+// CustomAuth describes how the upstream key is injected into requests.
+type CustomAuth struct {
+	In       string `json:"in"`       // "header" or "query"
+	Name     string `json:"name"`     // header or query parameter name
+	Template string `json:"template"` // e.g. "Bearer ${API_KEY}", supports header rule variables
+}
+
+// CustomPathMapping rewrites a request path prefix before it is sent upstream.
+type CustomPathMapping struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// CustomTransform is a single operation applied to a JSON body. Paths are dot separated object keys.
+type CustomTransform struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	To    string `json:"to,omitempty"`
+	Value any    `json:"value,omitempty"`
+}
+
+// CustomErrorRule classifies upstream error responses. A rule matches when all configured conditions match.
+type CustomErrorRule struct {
+	StatusCodes  string      `json:"status_codes,omitempty"`
+	BodyContains string      `json:"body_contains,omitempty"`
+	Action       ErrorAction `json:"action"`
+
+	matcher failover.StatusCodeMatcher
+}
+
+// CustomValidationSpec describes the request used to validate keys.
+type CustomValidationSpec struct {
+	Method string         `json:"method,omitempty"`
+	Path   string         `json:"path"`
+	Body   map[string]any `json:"body,omitempty"`
+}
+
+// ParseCustomDefinition decodes, normalizes and validates a channel definition.
+func ParseCustomDefinition(raw []byte) (*CustomDefinition, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, errors.New("channel definition is required")
+	}
+
+	var def CustomDefinition
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid channel definition: %w", err)
+	}
+
+	if err := def.normalize(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// normalize applies defaults and validates every section of the definition.
+func (d *CustomDefinition) normalize() error {
+	d.Auth.In = strings.ToLower(strings.TrimSpace(d.Auth.In))
+	d.Auth.Name = strings.TrimSpace(d.Auth.Name)
+	if d.Auth.In == "" {
+		d.Auth.In = "header"
+	}
+	if d.Auth.In != "header" && d.Auth.In != "query" {
+		return fmt.Errorf("auth.in must be 'header' or 'query', got '%s'", d.Auth.In)
+	}
+	if d.Auth.Name == "" {
+		return errors.New("auth.name is required")
+	}
+	if !strings.Contains(d.Auth.Template, "${API_KEY}") {
+		return errors.New("auth.template must contain ${API_KEY}")
+	}
+
+	for i := range d.PathMappings {
+		m := &d.PathMappings[i]
+		m.From = strings.TrimSpace(m.From)
+		m.To = strings.TrimSpace(m.To)
+		if !strings.HasPrefix(m.From, "/") || !strings.HasPrefix(m.To, "/") {
+			return fmt.Errorf("path_mappings[%d]: from and to must start with '/'", i)
+		}
+	}
+
+	d.ModelField = strings.TrimSpace(d.ModelField)
+	if d.ModelField == "" {
+		d.ModelField = "model"
+	}
+	d.StreamField = strings.TrimSpace(d.StreamField)
+	if d.StreamField == "" {
+		d.StreamField = "stream"
+	}
+
+	if err := validateTransforms("request_transforms", d.RequestTransforms); err != nil {
+		return err
+	}
+	if err := validateTransforms("response_transforms", d.ResponseTransforms); err != nil {
+		return err
+	}
+
+	for i := range d.ErrorRules {
+		rule := &d.ErrorRules[i]
+		if rule.Action != ErrorActionFailover && rule.Action != ErrorActionPassthrough {
+			return fmt.Errorf("error_rules[%d]: action must be 'failover' or 'passthrough'", i)
+		}
+		if strings.TrimSpace(rule.StatusCodes) == "" && rule.BodyContains == "" {
+			return fmt.Errorf("error_rules[%d]: status_codes or body_contains is required", i)
+		}
+		matcher, err := failover.ParseStatusCodeMatcher(rule.StatusCodes)
+		if err != nil {
+			return fmt.Errorf("error_rules[%d]: %w", i, err)
+		}
+		rule.matcher = matcher
+	}
+
+	d.Validation.Method = strings.ToUpper(strings.TrimSpace(d.Validation.Method))
+	if d.Validation.Method == "" {
+		d.Validation.Method = http.MethodPost
+	}
+	d.Validation.Path = strings.TrimSpace(d.Validation.Path)
+	if d.Validation.Path != "" && (!strings.HasPrefix(d.Validation.Path, "/") || strings.Contains(d.Validation.Path, "://")) {
+		return errors.New("validation.path must be a relative path starting with '/'")
+	}
+
+	return nil
+}
+
+func validateTransforms(section string, transforms []CustomTransform) error {
+	for i := range transforms {
+		t := &transforms[i]
+		t.Op = strings.ToLower(strings.TrimSpace(t.Op))
+		t.Path = strings.TrimSpace(t.Path)
+		t.To = strings.TrimSpace(t.To)
+		if t.Path == "" {
+			return fmt.Errorf("%s[%d]: path is required", section, i)
+		}
+		switch t.Op {
+		case TransformOpSet, TransformOpDefault, TransformOpRemove:
+		case TransformOpRename:
+			if t.To == "" {
+				return fmt.Errorf("%s[%d]: rename requires 'to'", section, i)
+			}
+		default:
+			return fmt.Errorf("%s[%d]: unsupported op '%s'", section, i, t.Op)
+		}
+	}
+	return nil
+}
+
+// MapPath applies the first matching path mapping to a request path.
+func (d *CustomDefinition) MapPath(path string) string {
+	for _, m := range d.PathMappings {
+		if strings.HasPrefix(path, m.From) {
+			return m.To + strings.TrimPrefix(path, m.From)
+		}
+	}
+	return path
+}
+
+// ApplyAuth injects the resolved auth value into the request header or query.
+func (d *CustomDefinition) ApplyAuth(req *http.Request, value string) {
+	if d.Auth.In == "query" {
+		q := req.URL.Query()
+		q.Set(d.Auth.Name, value)
+		req.URL.RawQuery = q.Encode()
+		return
+	}
+	req.Header.Set(d.Auth.Name, value)
+}
+
+// TransformRequest applies the request transforms to a JSON body.
+func (d *CustomDefinition) TransformRequest(bodyBytes []byte) ([]byte, error) {
+	return applyTransforms(bodyBytes, d.RequestTransforms)
+}
+
+// TransformResponse applies the response transforms to a JSON body.
+func (d *CustomDefinition) TransformResponse(bodyBytes []byte) ([]byte, error) {
+	return applyTransforms(bodyBytes, d.ResponseTransforms)
+}
+
+// ClassifyError returns the action of the first error rule matching the response.
+func (d *CustomDefinition) ClassifyError(statusCode int, bodyBytes []byte) ErrorAction {
+	for _, rule := range d.ErrorRules {
+		if !rule.matcher.IsEmpty() && !rule.matcher.Match(statusCode) {
+			continue
+		}
+		if rule.BodyContains != "" && !strings.Contains(string(bodyBytes), rule.BodyContains) {
+			continue
+		}
+		return rule.Action
+	}
+	return ErrorActionDefault
+}
+
+// applyTransforms runs the transforms against a JSON object body.
+// Bodies that are empty or not JSON objects are returned unchanged.
+func applyTransforms(bodyBytes []byte, transforms []CustomTransform) ([]byte, error) {
+	if len(transforms) == 0 || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+
+	var data map[string]any
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		return bodyBytes, nil
+	}
+
+	for _, t := range transforms {
+		switch t.Op {
+		case TransformOpSet:
+			setJSONPath(data, t.Path, t.Value)
+		case TransformOpDefault:
+			if _, ok := getJSONPath(data, t.Path); !ok {
+				setJSONPath(data, t.Path, t.Value)
+			}
+		case TransformOpRemove:
+			removeJSONPath(data, t.Path)
+		case TransformOpRename:
+			if value, ok := getJSONPath(data, t.Path); ok {
+				removeJSONPath(data, t.Path)
+				setJSONPath(data, t.To, value)
+			}
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+func getJSONPath(data map[string]any, path string) (any, bool) {
+	parts := strings.Split(path, ".")
+	current := data
+	for i, part := range parts {
+		value, ok := current[part]
+		if !ok {
+			return nil, false
+		}
+		if i == len(parts)-1 {
+			return value, true
+		}
+		next, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current = next
+	}
+	return nil, false
+}
+
+func setJSONPath(data map[string]any, path string, value any) {
+	parts := strings.Split(path, ".")
+	current := data
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			current[part] = next
+		}
+		current = next
+	}
+	current[parts[len(parts)-1]] = value
+}
+
+func removeJSONPath(data map[string]any, path string) {
+	parts := strings.Split(path, ".")
+	current := data
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, parts[len(parts)-1])
+}
+
+// CustomDryRunInput is a sample exchange used to exercise a definition without contacting the upstream.
+type CustomDryRunInput struct {
+	UpstreamURL  string          `json:"upstream_url"`
+	Method       string          `json:"method"`
+	Path         string          `json:"path"`
+	Query        string          `json:"query"`
+	APIKey       string          `json:"api_key"`
+	RequestBody  json.RawMessage `json:"request_body"`
+	StatusCode   int             `json:"status_code"`
+	ResponseBody json.RawMessage `json:"response_body"`
+}
+
+// CustomDryRunResult shows how the definition would shape the sample exchange.
+type CustomDryRunResult struct {
+	Method       string            `json:"method"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers"`
+	RequestBody  json.RawMessage   `json:"request_body,omitempty"`
+	Model        string            `json:"model"`
+	IsStream     bool              `json:"is_stream"`
+	ResponseBody json.RawMessage   `json:"response_body,omitempty"`
+	ErrorAction  ErrorAction       `json:"error_action,omitempty"`
+}
+
+// DryRun applies the definition to a sample exchange without sending anything upstream.
+// The api key is masked before it is injected, so the result is safe to display.
+func (d *CustomDefinition) DryRun(input CustomDryRunInput) (*CustomDryRunResult, error) {
+	base, err := url.Parse(strings.TrimSpace(input.UpstreamURL))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid upstream_url '%s'", input.UpstreamURL)
+	}
+
+	method := strings.ToUpper(strings.TrimSpace(input.Method))
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	target := *base
+	target.Path = strings.TrimRight(target.Path, "/") + d.MapPath(input.Path)
+	target.RawQuery = input.Query
+
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	headerCtx := utils.NewHeaderVariableContext(&models.Group{Name: "dry-run"}, &models.APIKey{KeyValue: utils.MaskAPIKey(input.APIKey)})
+	d.ApplyAuth(req, utils.ResolveHeaderVariables(d.Auth.Template, headerCtx))
+
+	result := &CustomDryRunResult{
+		Method:  method,
+		Headers: make(map[string]string, len(req.Header)),
+	}
+	for key := range req.Header {
+		result.Headers[key] = req.Header.Get(key)
+	}
+	result.URL = req.URL.String()
+
+	if len(input.RequestBody) > 0 {
+		transformed, err := d.TransformRequest(input.RequestBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform request body: %w", err)
+		}
+		result.RequestBody = transformed
+		result.Model = d.extractString(input.RequestBody, d.ModelField)
+		result.IsStream = d.isStreamBody(input.RequestBody)
+	}
+
+	if input.StatusCode >= 400 {
+		result.ErrorAction = d.ClassifyError(input.StatusCode, input.ResponseBody)
+		result.ResponseBody = input.ResponseBody
+	} else if len(input.ResponseBody) > 0 {
+		transformed, err := d.TransformResponse(input.ResponseBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to transform response body: %w", err)
+		}
+		result.ResponseBody = transformed
+	}
+
+	return result, nil
+}
+
+func (d *CustomDefinition) extractString(bodyBytes []byte, path string) string {
+	var data map[string]any
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		return ""
+	}
+	value, _ := getJSONPath(data, path)
+	s, _ := value.(string)
+	return s
+}
+
+func (d *CustomDefinition) isStreamBody(bodyBytes []byte) bool {
+	var data map[string]any
+	if err := json.Unmarshal(bodyBytes, &data); err != nil {
+		return false
+	}
+	value, _ := getJSONPath(data, d.StreamField)
+	stream, _ := value.(bool)
+	return stream
+}
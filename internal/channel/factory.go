@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
 	"gpt-load/internal/httpclient"
 	"gpt-load/internal/models"
+	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"net/url"
 	"sync"
@@ -43,17 +45,44 @@ func GetChannels() []string {
 type Factory struct {
 	settingsManager *config.SystemSettingsManager
 	clientManager   *httpclient.HTTPClientManager
+	encryptionSvc   encryption.Service
 	channelCache    map[uint]ChannelProxy
 	cacheLock       sync.Mutex
+	stopChan        chan struct{}
+	healthWg        sync.WaitGroup
 }
 
 // NewFactory creates a new channel factory.
-func NewFactory(settingsManager *config.SystemSettingsManager, clientManager *httpclient.HTTPClientManager) *Factory {
-	return &Factory{
+func NewFactory(settingsManager *config.SystemSettingsManager, clientManager *httpclient.HTTPClientManager, encryptionSvc encryption.Service) *Factory {
+	f := &Factory{
 		settingsManager: settingsManager,
 		clientManager:   clientManager,
+		encryptionSvc:   encryptionSvc,
 		channelCache:    make(map[uint]ChannelProxy),
+		stopChan:        make(chan struct{}),
 	}
+	settingsManager.OnChange(f.onSettingsChanged)
+	return f
+}
+
+// onSettingsChanged drops the cached HTTP clients when the transport settings change. Channels
+// of the groups whose effective config changed are rebuilt on their next request and get
+// clients with the new settings.
+func (f *Factory) onSettingsChanged(prev, next types.SystemSettings) {
+	if prev.ConnectTimeout == next.ConnectTimeout &&
+		prev.RequestTimeout == next.RequestTimeout &&
+		prev.IdleConnTimeout == next.IdleConnTimeout &&
+		prev.ResponseHeaderTimeout == next.ResponseHeaderTimeout &&
+		prev.MaxIdleConns == next.MaxIdleConns &&
+		prev.MaxIdleConnsPerHost == next.MaxIdleConnsPerHost &&
+		prev.HTTPVersion == next.HTTPVersion &&
+		prev.TCPKeepAlive == next.TCPKeepAlive &&
+		prev.DNSCacheTTL == next.DNSCacheTTL &&
+		prev.ProxyURL == next.ProxyURL {
+		return
+	}
+	f.clientManager.Reset()
+	logrus.Debug("Transport settings changed, HTTP clients will be recreated.")
 }
 
 // GetChannel returns a channel proxy based on the group's channel type.
@@ -69,11 +98,7 @@ func (f *Factory) GetChannel(group *models.Group) (ChannelProxy, error) {
 
 	logrus.Debugf("Creating new channel for group %d with type '%s'", group.ID, group.ChannelType)
 
-	constructor, ok := channelRegistry[group.ChannelType]
-	if !ok {
-		return nil, fmt.Errorf("unsupported channel type: %s", group.ChannelType)
-	}
-	channel, err := constructor(f, group)
+	channel, err := f.NewChannel(group)
 	if err != nil {
 		return nil, err
 	}
@@ -81,11 +106,26 @@ func (f *Factory) GetChannel(group *models.Group) (ChannelProxy, error) {
 	return channel, nil
 }
 
+// NewChannel creates a channel proxy for a group without caching it, e.g. to check a group
+// that is not saved yet.
+func (f *Factory) NewChannel(group *models.Group) (ChannelProxy, error) {
+	constructor, ok := channelRegistry[group.ChannelType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported channel type: %s", group.ChannelType)
+	}
+	return constructor(f, group)
+}
+
 // newBaseChannel is a helper function to create and configure a BaseChannel.
 func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel, error) {
 	type upstreamDef struct {
-		URL    string `json:"url"`
-		Weight int    `json:"weight"`
+		URL        string   `json:"url"`
+		Weight     int      `json:"weight"`
+		AuthHeader string   `json:"auth_header,omitempty"`
+		AuthTokens []string `json:"auth_tokens,omitempty"`
+		ProxyURL   string   `json:"proxy_url,omitempty"`
+
+		Transport *httpclient.UpstreamTransport `json:"transport,omitempty"`
 	}
 
 	var defs []upstreamDef
@@ -98,6 +138,7 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 	}
 
 	var upstreamInfos []UpstreamInfo
+	var transports []*httpclient.UpstreamTransport
 	for _, def := range defs {
 		u, err := url.Parse(def.URL)
 		if err != nil {
@@ -106,8 +147,18 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		if def.Weight <= 0 {
 			continue
 		}
-		upstreamInfos = append(upstreamInfos, UpstreamInfo{URL: u, Weight: def.Weight})
+		authTokens, err := f.decryptAuthTokens(def.AuthTokens)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt auth tokens of upstream '%s' for %s channel: %w", def.URL, name, err)
+		}
+		proxyURL, err := f.decryptProxyURL(def.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt proxy url of upstream '%s' for %s channel: %w", def.URL, name, err)
+		}
+		upstreamInfos = append(upstreamInfos, UpstreamInfo{URL: u, Weight: def.Weight, AuthHeader: def.AuthHeader, authTokens: authTokens, ProxyURL: proxyURL})
+		transports = append(transports, def.Transport)
 	}
+	groupProxyURL := f.groupProxyURL(group)
 
 	// Base configuration for regular requests, derived from the group's effective settings.
 	clientConfig := &httpclient.Config{
@@ -117,30 +168,41 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		MaxIdleConns:          group.EffectiveConfig.MaxIdleConns,
 		MaxIdleConnsPerHost:   group.EffectiveConfig.MaxIdleConnsPerHost,
 		ResponseHeaderTimeout: time.Duration(group.EffectiveConfig.ResponseHeaderTimeout) * time.Second,
-		ProxyURL:              group.EffectiveConfig.ProxyURL,
+		ProxyURL:              groupProxyURL,
 		DisableCompression:    false,
 		WriteBufferSize:       32 * 1024,
 		ReadBufferSize:        32 * 1024,
 		ForceAttemptHTTP2:     true,
 		TLSHandshakeTimeout:   15 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
+		HTTPVersion:           group.EffectiveConfig.HTTPVersion,
+		TCPKeepAlive:          httpclient.KeepAliveSeconds(group.EffectiveConfig.TCPKeepAlive),
+		DNSCacheTTL:           time.Duration(group.EffectiveConfig.DNSCacheTTL) * time.Second,
 	}
-
-	// Create a dedicated configuration for streaming requests.
-	streamConfig := *clientConfig
-	streamConfig.RequestTimeout = 0
-	streamConfig.DisableCompression = true
-	streamConfig.WriteBufferSize = 0
-	streamConfig.ReadBufferSize = 0
-	// Use a larger, independent connection pool for streaming clients to avoid exhaustion.
-	streamConfig.MaxIdleConns = max(group.EffectiveConfig.MaxIdleConns*2, 50)
-	streamConfig.MaxIdleConnsPerHost = max(group.EffectiveConfig.MaxIdleConnsPerHost*2, 20)
+	streamConfig := newStreamConfig(clientConfig)
 
 	// Get both clients from the manager using their respective configurations.
 	httpClient := f.clientManager.GetClient(clientConfig)
 	streamClient := f.clientManager.GetClient(&streamConfig)
 
-	return &BaseChannel{
+	// Upstreams with their own proxy or transport settings get their own clients, and so their
+	// own connection pools, apart from other upstreams with the same settings.
+	for i := range upstreamInfos {
+		if upstreamInfos[i].ProxyURL == "" && transports[i].IsZero() {
+			continue
+		}
+		upstreamClientConfig := *clientConfig
+		upstreamClientConfig.PoolKey = upstreamInfos[i].URL.String()
+		if upstreamInfos[i].ProxyURL != "" {
+			upstreamClientConfig.ProxyURL = upstreamInfos[i].ProxyURL
+		}
+		transports[i].Apply(&upstreamClientConfig)
+		upstreamStreamConfig := newStreamConfig(&upstreamClientConfig)
+		upstreamInfos[i].httpClient = f.clientManager.GetClient(&upstreamClientConfig)
+		upstreamInfos[i].streamClient = f.clientManager.GetClient(&upstreamStreamConfig)
+	}
+
+	base := &BaseChannel{
 		Name:                name,
 		Upstreams:           upstreamInfos,
 		HTTPClient:          httpClient,
@@ -152,5 +214,60 @@ func (f *Factory) newBaseChannel(name string, group *models.Group) (*BaseChannel
 		effectiveConfig:     &group.EffectiveConfig,
 		modelRedirectRules:  group.ModelRedirectRules,
 		modelRedirectStrict: group.ModelRedirectStrict,
-	}, nil
+		channelDefinition:   group.ChannelDefinition,
+		groupProxyURL:       groupProxyURL,
+	}
+	base.withUpstreamProxies()
+	return base, nil
+}
+
+// newStreamConfig derives the configuration of the client for streaming requests from that of
+// regular requests.
+func newStreamConfig(clientConfig *httpclient.Config) httpclient.Config {
+	streamConfig := *clientConfig
+	streamConfig.RequestTimeout = 0
+	streamConfig.DisableCompression = true
+	streamConfig.WriteBufferSize = 0
+	streamConfig.ReadBufferSize = 0
+	// Use a larger, independent connection pool for streaming clients to avoid exhaustion.
+	streamConfig.MaxIdleConns = max(clientConfig.MaxIdleConns*2, 50)
+	streamConfig.MaxIdleConnsPerHost = max(clientConfig.MaxIdleConnsPerHost*2, 20)
+	return streamConfig
+}
+
+// groupProxyURL returns the outbound proxy of a group. A proxy set in the group config is
+// encrypted like the upstream proxies, one stored before that was in plaintext and is used as is.
+func (f *Factory) groupProxyURL(group *models.Group) string {
+	proxyURL := group.EffectiveConfig.ProxyURL
+	if override, _ := group.Config["proxy_url"].(string); override == "" || f.encryptionSvc == nil {
+		return proxyURL
+	}
+	if plain, err := f.encryptionSvc.Decrypt(proxyURL); err == nil {
+		return plain
+	}
+	return proxyURL
+}
+
+// decryptProxyURL decrypts the outbound proxy of an upstream, which may hold proxy credentials.
+func (f *Factory) decryptProxyURL(proxyURL string) (string, error) {
+	if proxyURL == "" || f.encryptionSvc == nil {
+		return proxyURL, nil
+	}
+	return f.encryptionSvc.Decrypt(proxyURL)
+}
+
+// decryptAuthTokens decrypts the upstream-level credentials stored with the upstreams.
+func (f *Factory) decryptAuthTokens(tokens []string) ([]string, error) {
+	if len(tokens) == 0 || f.encryptionSvc == nil {
+		return tokens, nil
+	}
+	decrypted := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		plain, err := f.encryptionSvc.Decrypt(token)
+		if err != nil {
+			return nil, err
+		}
+		decrypted = append(decrypted, plain)
+	}
+	return decrypted, nil
 }
@@ -36,9 +36,10 @@ func newGeminiChannel(f *Factory, group *models.Group) (ChannelProxy, error) {
 	}, nil
 }
 
-// ModifyRequest adds the API key as a query parameter for Gemini requests.
+// ModifyRequest adds the API key as a query parameter for Gemini requests. Access tokens
+// minted by a credential refresher, e.g. for Vertex AI, are sent as bearer tokens.
 func (ch *GeminiChannel) ModifyRequest(req *http.Request, apiKey *models.APIKey, group *models.Group) {
-	if strings.Contains(req.URL.Path, "v1beta/openai") {
+	if strings.Contains(req.URL.Path, "v1beta/openai") || group.EffectiveConfig.CredentialRefresher != "" {
 		req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
 	} else {
 		q := req.URL.Query()
@@ -96,6 +97,49 @@ func (ch *GeminiChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 	return ""
 }
 
+// DisableStream turns a streamGenerateContent request into a generateContent request, and a
+// streaming request of the OpenAI-compatible API into a non-streaming one.
+func (ch *GeminiChannel) DisableStream(req *http.Request, bodyBytes []byte) ([]byte, error) {
+	if strings.Contains(req.URL.Path, "v1beta/openai") {
+		return disableBodyStream(bodyBytes)
+	}
+
+	if path, found := strings.CutSuffix(req.URL.Path, ":streamGenerateContent"); found {
+		req.URL.Path = path + ":generateContent"
+		req.URL.RawPath = ""
+	}
+	q := req.URL.Query()
+	q.Del("alt")
+	req.URL.RawQuery = q.Encode()
+	return bodyBytes, nil
+}
+
+// SimulateStream replays a generateContent response as the single chunk of its stream, sent
+// as an event with alt=sse and as a JSON array without.
+func (ch *GeminiChannel) SimulateStream(req *http.Request, bodyBytes []byte) ([]byte, string, error) {
+	if strings.Contains(req.URL.Path, "v1beta/openai") {
+		return simulateChatCompletionStream(bodyBytes)
+	}
+
+	// Responses are indented, an event holds a single line of data
+	var stream bytes.Buffer
+	if strings.HasSuffix(req.URL.Path, ":streamGenerateContent") && req.URL.Query().Get("alt") != "sse" {
+		stream.WriteByte('[')
+		if err := json.Compact(&stream, bodyBytes); err != nil {
+			return nil, "", err
+		}
+		stream.WriteByte(']')
+		return stream.Bytes(), "application/json", nil
+	}
+
+	stream.WriteString("data: ")
+	if err := json.Compact(&stream, bodyBytes); err != nil {
+		return nil, "", err
+	}
+	stream.WriteString("\n\n")
+	return stream.Bytes(), eventStreamContentType, nil
+}
+
 // ValidateKey checks if the given API key is valid by making a generateContent request.
 func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
 	upstreamURL := ch.getUpstreamURL()
@@ -108,7 +152,10 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	if err != nil {
 		return false, fmt.Errorf("failed to create gemini validation path: %w", err)
 	}
-	reqURL += "?key=" + apiKey.KeyValue
+	usesAccessToken := group.EffectiveConfig.CredentialRefresher != ""
+	if !usesAccessToken {
+		reqURL += "?key=" + apiKey.KeyValue
+	}
 
 	payload := gin.H{
 		"contents": []gin.H{
@@ -130,6 +177,10 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 		return false, fmt.Errorf("failed to create validation request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if usesAccessToken {
+		req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
+	}
+	ch.ApplyUpstreamAuth(req)
 
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
@@ -157,7 +208,7 @@ func (ch *GeminiChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	// Use the new parser to extract a clean error message.
 	parsedError := app_errors.ParseUpstreamError(errorBody)
 
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	return false, &ValidationError{StatusCode: resp.StatusCode, Message: parsedError}
 }
 
 // ApplyModelRedirect overrides the default implementation for Gemini channel.
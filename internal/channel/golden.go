@@ -0,0 +1,382 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+)
+
+const (
+	// GoldenAPIKey is the placeholder key injected when replaying fixtures.
+	GoldenAPIKey = "sk-golden-fixture-key"
+	// GoldenUpstreamURL replaces the real upstream host in captured fixtures.
+	GoldenUpstreamURL = "https://upstream.example.com"
+	// goldenGroupName is the proxy group name used when a fixture does not set one.
+	goldenGroupName = "golden"
+)
+
+// goldenSensitiveFields are body fields whose values are replaced when a fixture is sanitized.
+var goldenSensitiveFields = map[string]struct{}{
+	"api_key":       {},
+	"apikey":        {},
+	"key":           {},
+	"token":         {},
+	"access_token":  {},
+	"authorization": {},
+	"user":          {},
+	"email":         {},
+}
+
+// GoldenFixture is a recorded request/response pair replayed through a channel's transformation pipeline.
+type GoldenFixture struct {
+	Name        string          `json:"name"`
+	ChannelType string          `json:"channel_type"`
+	Group       GoldenGroup     `json:"group"`
+	Request     GoldenRequest   `json:"request"`
+	Response    *GoldenResponse `json:"response,omitempty"`
+	Expected    GoldenResult    `json:"expected"`
+}
+
+// GoldenGroup holds the group settings that influence the transformation pipeline.
+type GoldenGroup struct {
+	Name                string            `json:"name,omitempty"`
+	Upstreams           json.RawMessage   `json:"upstreams"`
+	TestModel           string            `json:"test_model,omitempty"`
+	ParamOverrides      map[string]any    `json:"param_overrides,omitempty"`
+	ModelRedirectRules  map[string]string `json:"model_redirect_rules,omitempty"`
+	ModelRedirectStrict bool              `json:"model_redirect_strict,omitempty"`
+	ChannelDefinition   json.RawMessage   `json:"channel_definition,omitempty"`
+}
+
+// GoldenRequest is the client request as received by the proxy, relative to /proxy/<group>.
+type GoldenRequest struct {
+	Method  string            `json:"method"`
+	Path    string            `json:"path"`
+	Query   string            `json:"query,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// GoldenResponse is the upstream response body fed into response transformations.
+type GoldenResponse struct {
+	Body json.RawMessage `json:"body"`
+}
+
+// GoldenResult is what the pipeline produced for a fixture.
+type GoldenResult struct {
+	IsStream     bool              `json:"is_stream"`
+	Model        string            `json:"model"`
+	UpstreamURL  string            `json:"upstream_url"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         json.RawMessage   `json:"body,omitempty"`
+	ResponseBody json.RawMessage   `json:"response_body,omitempty"`
+}
+
+// ReplayGolden runs a fixture through the same steps the proxy applies to a request:
+// stream and model detection, upstream URL building, model redirect, request
+// transformation and key injection, followed by the response transformation.
+func ReplayGolden(fixture *GoldenFixture) (*GoldenResult, error) {
+	group := fixture.buildGroup()
+
+	constructor, ok := channelRegistry[fixture.ChannelType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported channel type: %s", fixture.ChannelType)
+	}
+	factory := &Factory{
+		clientManager: httpclient.NewHTTPClientManager(),
+		channelCache:  make(map[uint]ChannelProxy),
+		stopChan:      make(chan struct{}),
+	}
+	ch, err := constructor(factory, group)
+	if err != nil {
+		return nil, err
+	}
+
+	bodyBytes := []byte(fixture.Request.Body)
+	if len(group.ParamOverrides) > 0 && len(bodyBytes) > 0 {
+		var requestData map[string]any
+		if err := json.Unmarshal(bodyBytes, &requestData); err == nil {
+			for key, value := range group.ParamOverrides {
+				requestData[key] = value
+			}
+			if bodyBytes, err = json.Marshal(requestData); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	requestURL := "/proxy/" + group.Name + fixture.Request.Path
+	if fixture.Request.Query != "" {
+		requestURL += "?" + fixture.Request.Query
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(fixture.Request.Method, requestURL, bytes.NewReader(bodyBytes))
+	for key, value := range fixture.Request.Headers {
+		c.Request.Header.Set(key, value)
+	}
+
+	result := &GoldenResult{
+		IsStream: ch.IsStreamRequest(c, bodyBytes),
+		Model:    ch.ExtractModel(c, bodyBytes),
+	}
+
+	upstreamURL, err := ch.BuildUpstreamURL(c.Request.URL, group.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(fixture.Request.Method, upstreamURL, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.Request.Header.Clone()
+	req.Header.Del("Authorization")
+	req.Header.Del("X-Api-Key")
+	req.Header.Del("X-Goog-Api-Key")
+
+	finalBodyBytes, err := ch.ApplyModelRedirect(req, bodyBytes, group)
+	if err != nil {
+		return nil, err
+	}
+	if transformer, ok := ch.(RequestBodyTransformer); ok {
+		if finalBodyBytes, err = transformer.TransformRequestBody(finalBodyBytes); err != nil {
+			return nil, err
+		}
+	}
+
+	ch.ModifyRequest(req, &models.APIKey{ID: 1, GroupID: group.ID, KeyValue: GoldenAPIKey}, group)
+
+	result.UpstreamURL = req.URL.String()
+	result.Headers = flattenHeaders(req.Header)
+	result.Body = rawJSONOrString(finalBodyBytes)
+
+	if fixture.Response != nil {
+		responseBody := []byte(fixture.Response.Body)
+		if transformer, ok := ch.(ResponseBodyTransformer); ok {
+			if responseBody, err = transformer.TransformResponseBody(responseBody); err != nil {
+				return nil, err
+			}
+		}
+		result.ResponseBody = rawJSONOrString(responseBody)
+	}
+
+	return result, nil
+}
+
+// NewGoldenFixtureFromLog builds a sanitized fixture from a logged request and its group.
+// The request body must have been logged, see enable_request_body_logging.
+func NewGoldenFixtureFromLog(name string, log *models.RequestLog, group *models.Group, responseBody []byte) (*GoldenFixture, error) {
+	if log.RequestBody == "" && log.RequestPath == "" {
+		return nil, fmt.Errorf("request log %s has no captured request", log.ID)
+	}
+
+	requestURL, err := url.Parse(log.RequestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse logged request path: %w", err)
+	}
+	path := requestURL.Path
+	if rest, ok := strings.CutPrefix(path, "/proxy/"); ok {
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			path = rest[idx:]
+		}
+	}
+	query := requestURL.Query()
+	query.Del("key")
+
+	method := http.MethodPost
+	if log.RequestBody == "" {
+		method = http.MethodGet
+	}
+
+	upstreams, err := sanitizeUpstreams(group.Upstreams)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectRules := make(map[string]string, len(group.ModelRedirectRules))
+	for key, value := range group.ModelRedirectRules {
+		if target, ok := value.(string); ok {
+			redirectRules[key] = target
+		}
+	}
+
+	fixture := &GoldenFixture{
+		Name:        name,
+		ChannelType: group.ChannelType,
+		Group: GoldenGroup{
+			Upstreams:           upstreams,
+			TestModel:           group.TestModel,
+			ParamOverrides:      group.ParamOverrides,
+			ModelRedirectRules:  redirectRules,
+			ModelRedirectStrict: group.ModelRedirectStrict,
+			ChannelDefinition:   json.RawMessage(group.ChannelDefinition),
+		},
+		Request: GoldenRequest{
+			Method:  method,
+			Path:    path,
+			Query:   query.Encode(),
+			Headers: map[string]string{"Content-Type": "application/json"},
+			Body:    sanitizeGoldenBody([]byte(log.RequestBody)),
+		},
+	}
+	if method == http.MethodGet {
+		fixture.Request.Headers = nil
+	}
+	if len(responseBody) > 0 {
+		fixture.Response = &GoldenResponse{Body: sanitizeGoldenBody(responseBody)}
+	}
+
+	expected, err := ReplayGolden(fixture)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay captured request: %w", err)
+	}
+	fixture.Expected = *expected
+
+	return fixture, nil
+}
+
+func (fixture *GoldenFixture) buildGroup() *models.Group {
+	name := fixture.Group.Name
+	if name == "" {
+		name = goldenGroupName
+	}
+
+	group := &models.Group{
+		ID:                  1,
+		Name:                name,
+		ChannelType:         fixture.ChannelType,
+		Upstreams:           datatypes.JSON(fixture.Group.Upstreams),
+		TestModel:           fixture.Group.TestModel,
+		ParamOverrides:      fixture.Group.ParamOverrides,
+		ModelRedirectStrict: fixture.Group.ModelRedirectStrict,
+		ModelRedirectMap:    fixture.Group.ModelRedirectRules,
+		EffectiveConfig:     utils.DefaultSystemSettings(),
+	}
+	if len(fixture.Group.ChannelDefinition) > 0 && string(fixture.Group.ChannelDefinition) != "null" {
+		group.ChannelDefinition = datatypes.JSON(fixture.Group.ChannelDefinition)
+	}
+	if len(fixture.Group.ModelRedirectRules) > 0 {
+		group.ModelRedirectRules = make(datatypes.JSONMap, len(fixture.Group.ModelRedirectRules))
+		for key, value := range fixture.Group.ModelRedirectRules {
+			group.ModelRedirectRules[key] = value
+		}
+	}
+	return group
+}
+
+// sanitizeUpstreams replaces upstream hosts with GoldenUpstreamURL, keeping the base paths.
+func sanitizeUpstreams(raw datatypes.JSON) (json.RawMessage, error) {
+	var upstreams []map[string]any
+	if err := json.Unmarshal(raw, &upstreams); err != nil {
+		return nil, fmt.Errorf("failed to parse upstreams: %w", err)
+	}
+	for _, upstream := range upstreams {
+		rawURL, _ := upstream["url"].(string)
+		parsed, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse upstream url: %w", err)
+		}
+		upstream["url"] = GoldenUpstreamURL + parsed.Path
+	}
+	return json.Marshal(upstreams)
+}
+
+// sanitizeGoldenBody replaces the values of sensitive fields anywhere in a JSON body.
+func sanitizeGoldenBody(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	var data any
+	if err := json.Unmarshal(body, &data); err != nil {
+		return rawJSONOrString(body)
+	}
+	sanitized, err := json.Marshal(redactSensitive(data))
+	if err != nil {
+		return rawJSONOrString(body)
+	}
+	return sanitized
+}
+
+func redactSensitive(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if _, ok := goldenSensitiveFields[strings.ToLower(key)]; ok {
+				if _, isString := item.(string); isString {
+					v[key] = "REDACTED"
+					continue
+				}
+			}
+			v[key] = redactSensitive(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactSensitive(item)
+		}
+	}
+	return value
+}
+
+// flattenHeaders keeps the first value of each header, which is enough for comparisons.
+func flattenHeaders(header http.Header) map[string]string {
+	if len(header) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	flat := make(map[string]string, len(keys))
+	for _, key := range keys {
+		flat[key] = header.Get(key)
+	}
+	return flat
+}
+
+// rawJSONOrString keeps JSON bodies as they are and encodes anything else as a JSON string.
+func rawJSONOrString(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if json.Valid(body) {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, body); err == nil {
+			return compact.Bytes()
+		}
+	}
+	encoded, _ := json.Marshal(string(body))
+	return encoded
+}
+
+// ReadGoldenFixture decodes a fixture file.
+func ReadGoldenFixture(r io.Reader) (*GoldenFixture, error) {
+	var fixture GoldenFixture
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&fixture); err != nil {
+		return nil, err
+	}
+	return &fixture, nil
+}
+
+// WriteGoldenFixture encodes a fixture in the indented format used under testdata.
+func WriteGoldenFixture(w io.Writer, fixture *GoldenFixture) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(fixture)
+}
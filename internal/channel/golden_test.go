@@ -0,0 +1,95 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Run `go test ./internal/channel -run TestGoldenFixtures -update` to re-record expectations
+// after an intended behavior change, then review the diff of testdata/golden.
+var updateGolden = flag.Bool("update", false, "rewrite the expected results of golden fixtures")
+
+// TestGoldenFixtures replays every recorded request/response pair through its channel
+// and fails on any difference in the upstream request or transformed response.
+func TestGoldenFixtures(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "golden", "*", "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list fixtures: %v", err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no golden fixtures found")
+	}
+
+	for _, file := range files {
+		t.Run(filepath.Base(filepath.Dir(file))+"/"+filepath.Base(file), func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatalf("failed to read fixture: %v", err)
+			}
+			fixture, err := ReadGoldenFixture(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("failed to decode fixture: %v", err)
+			}
+			if want := filepath.Base(filepath.Dir(file)); fixture.ChannelType != want {
+				t.Fatalf("fixture channel type %q does not match its directory %q", fixture.ChannelType, want)
+			}
+
+			got, err := ReplayGolden(fixture)
+			if err != nil {
+				t.Fatalf("replay failed: %v", err)
+			}
+
+			if *updateGolden {
+				fixture.Expected = *got
+				var encoded bytes.Buffer
+				if err := WriteGoldenFixture(&encoded, fixture); err != nil {
+					t.Fatalf("failed to encode fixture: %v", err)
+				}
+				if err := os.WriteFile(file, encoded.Bytes(), 0644); err != nil {
+					t.Fatalf("failed to write fixture: %v", err)
+				}
+				return
+			}
+
+			assertGoldenEqual(t, "is_stream", fixture.Expected.IsStream, got.IsStream)
+			assertGoldenEqual(t, "model", fixture.Expected.Model, got.Model)
+			assertGoldenEqual(t, "upstream_url", fixture.Expected.UpstreamURL, got.UpstreamURL)
+			assertGoldenEqual(t, "headers", fixture.Expected.Headers, got.Headers)
+			assertGoldenJSON(t, "body", fixture.Expected.Body, got.Body)
+			assertGoldenJSON(t, "response_body", fixture.Expected.ResponseBody, got.ResponseBody)
+		})
+	}
+}
+
+func assertGoldenEqual(t *testing.T, field string, want, got any) {
+	t.Helper()
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("%s mismatch:\n  want: %v\n  got:  %v", field, want, got)
+	}
+}
+
+// assertGoldenJSON compares two bodies semantically so formatting and key order do not matter.
+func assertGoldenJSON(t *testing.T, field string, want, got json.RawMessage) {
+	t.Helper()
+	if len(want) == 0 && len(got) == 0 {
+		return
+	}
+
+	var wantValue, gotValue any
+	if err := json.Unmarshal(want, &wantValue); err != nil {
+		t.Errorf("%s: invalid expected JSON: %v", field, err)
+		return
+	}
+	if err := json.Unmarshal(got, &gotValue); err != nil {
+		t.Errorf("%s: invalid actual JSON: %v", field, err)
+		return
+	}
+	if !reflect.DeepEqual(wantValue, gotValue) {
+		t.Errorf("%s mismatch:\n  want: %s\n  got:  %s", field, want, got)
+	}
+}
@@ -0,0 +1,114 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultModelListEndpoint is where OpenAI compatible upstreams list their models.
+const defaultModelListEndpoint = "/v1/models"
+
+// DiscoveredModel is a model an upstream serves.
+type DiscoveredModel struct {
+	ID      string `json:"id"`
+	OwnedBy string `json:"owned_by,omitempty"`
+	// Groups are the sub-groups serving the model, for the models of an aggregate group.
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ModelListEndpoint is implemented by channels whose upstream lists its models at another
+// endpoint than /v1/models.
+type ModelListEndpoint interface {
+	ModelListEndpoint() string
+}
+
+// ModelListEndpoint lists up to 1000 models, the Anthropic API returns 20 by default.
+func (ch *AnthropicChannel) ModelListEndpoint() string {
+	return "/v1/models?limit=1000"
+}
+
+// ModelListEndpoint lists up to 1000 models in the Gemini native format.
+func (ch *GeminiChannel) ModelListEndpoint() string {
+	return "/v1beta/models?pageSize=1000"
+}
+
+// FetchModels lists the models the upstream of the group serves, authenticating with apiKey
+// the way the channel authenticates proxied requests.
+func FetchModels(ctx context.Context, ch ChannelProxy, apiKey *models.APIKey, group *models.Group) ([]DiscoveredModel, error) {
+	endpoint := defaultModelListEndpoint
+	if lister, ok := ch.(ModelListEndpoint); ok {
+		endpoint = lister.ModelListEndpoint()
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse model list endpoint: %w", err)
+	}
+	endpointURL.Path = "/proxy/" + group.Name + endpointURL.Path
+
+	reqURL, err := ch.BuildUpstreamURL(endpointURL, group.Name)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create model list request: %w", err)
+	}
+	ch.ModifyRequest(req, apiKey, group)
+	ch.ApplyUpstreamAuth(req)
+	if len(group.HeaderRuleList) > 0 {
+		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
+		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
+	}
+
+	resp, err := ch.GetHTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send model list request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model list response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &ValidationError{StatusCode: resp.StatusCode, Message: app_errors.ParseUpstreamError(body)}
+	}
+	return parseModelList(body)
+}
+
+// parseModelList reads the OpenAI and Anthropic ("data") and the Gemini ("models") formats.
+func parseModelList(body []byte) ([]DiscoveredModel, error) {
+	var list struct {
+		Data []struct {
+			ID      string `json:"id"`
+			OwnedBy string `json:"owned_by"`
+		} `json:"data"`
+		Models []struct {
+			Name string `json:"name"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("invalid model list response: %w", err)
+	}
+
+	discovered := make([]DiscoveredModel, 0, len(list.Data)+len(list.Models))
+	for _, model := range list.Data {
+		if model.ID != "" {
+			discovered = append(discovered, DiscoveredModel{ID: model.ID, OwnedBy: model.OwnedBy})
+		}
+	}
+	for _, model := range list.Models {
+		if id := strings.TrimPrefix(model.Name, "models/"); id != "" {
+			discovered = append(discovered, DiscoveredModel{ID: id})
+		}
+	}
+	return discovered, nil
+}
@@ -0,0 +1,55 @@
+package channel
+
+import (
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestFetchModelsGemini checks that Gemini upstreams are listed at their native endpoint
+// with the key in the query, and that model names lose their "models/" prefix.
+func TestFetchModelsGemini(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models" || r.URL.Query().Get("key") != "gemini-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"API key not valid"}}`))
+			return
+		}
+		w.Write([]byte(`{"models":[{"name":"models/gemini-2.0-flash"},{"name":"models/text-embedding-004"}]}`))
+	}))
+	defer upstream.Close()
+
+	group := &models.Group{
+		Name:      "gemini",
+		Upstreams: []byte(`[{"url":"` + upstream.URL + `","weight":1}]`),
+		EffectiveConfig: types.SystemSettings{
+			ConnectTimeout:        5,
+			RequestTimeout:        5,
+			IdleConnTimeout:       5,
+			ResponseHeaderTimeout: 5,
+			MaxIdleConns:          10,
+			MaxIdleConnsPerHost:   10,
+		},
+	}
+	f := &Factory{clientManager: httpclient.NewHTTPClientManager()}
+	ch, err := newGeminiChannel(f, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	discovered, err := FetchModels(t.Context(), ch, &models.APIKey{KeyValue: "gemini-key"}, group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discovered) != 2 || discovered[0].ID != "gemini-2.0-flash" || discovered[1].ID != "text-embedding-004" {
+		t.Fatalf("FetchModels() = %+v, want the two Gemini models", discovered)
+	}
+
+	_, err = FetchModels(t.Context(), ch, &models.APIKey{KeyValue: "wrong"}, group)
+	if validationErr, ok := err.(*ValidationError); !ok || validationErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("FetchModels() with a wrong key error = %v, want the upstream status", err)
+	}
+}
@@ -72,6 +72,16 @@ func (ch *OpenAIChannel) ExtractModel(c *gin.Context, bodyBytes []byte) string {
 	return ""
 }
 
+// DisableStream turns a streaming chat completion request into a non-streaming one.
+func (ch *OpenAIChannel) DisableStream(req *http.Request, bodyBytes []byte) ([]byte, error) {
+	return disableBodyStream(bodyBytes)
+}
+
+// SimulateStream replays a chat completion as its chunks.
+func (ch *OpenAIChannel) SimulateStream(req *http.Request, bodyBytes []byte) ([]byte, string, error) {
+	return simulateChatCompletionStream(bodyBytes)
+}
+
 // ValidateKey checks if the given API key is valid by making a chat completion request.
 func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
 	upstreamURL := ch.getUpstreamURL()
@@ -109,6 +119,7 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
 	req.Header.Set("Content-Type", "application/json")
+	ch.ApplyUpstreamAuth(req)
 
 	// Apply custom header rules if available
 	if len(group.HeaderRuleList) > 0 {
@@ -136,5 +147,5 @@ func (ch *OpenAIChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey,
 	// Use the new parser to extract a clean error message.
 	parsedError := app_errors.ParseUpstreamError(errorBody)
 
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	return false, &ValidationError{StatusCode: resp.StatusCode, Message: parsedError}
 }
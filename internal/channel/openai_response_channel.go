@@ -70,6 +70,16 @@ func (ch *OpenAIResponseChannel) ExtractModel(c *gin.Context, bodyBytes []byte)
 	return ""
 }
 
+// DisableStream turns a streaming response request into a non-streaming one.
+func (ch *OpenAIResponseChannel) DisableStream(req *http.Request, bodyBytes []byte) ([]byte, error) {
+	return disableBodyStream(bodyBytes)
+}
+
+// SimulateStream replays a response as its stream events.
+func (ch *OpenAIResponseChannel) SimulateStream(req *http.Request, bodyBytes []byte) ([]byte, string, error) {
+	return simulateResponseStream(bodyBytes)
+}
+
 func (ch *OpenAIResponseChannel) ValidateKey(ctx context.Context, apiKey *models.APIKey, group *models.Group) (bool, error) {
 	upstreamURL := ch.getUpstreamURL()
 	if upstreamURL == nil {
@@ -101,6 +111,7 @@ func (ch *OpenAIResponseChannel) ValidateKey(ctx context.Context, apiKey *models
 	}
 	req.Header.Set("Authorization", "Bearer "+apiKey.KeyValue)
 	req.Header.Set("Content-Type", "application/json")
+	ch.ApplyUpstreamAuth(req)
 
 	if len(group.HeaderRuleList) > 0 {
 		headerCtx := utils.NewHeaderVariableContext(group, apiKey)
@@ -124,5 +135,5 @@ func (ch *OpenAIResponseChannel) ValidateKey(ctx context.Context, apiKey *models
 
 	parsedError := app_errors.ParseUpstreamError(errorBody)
 
-	return false, fmt.Errorf("[status %d] %s", resp.StatusCode, parsedError)
+	return false, &ValidationError{StatusCode: resp.StatusCode, Message: parsedError}
 }
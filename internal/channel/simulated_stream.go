@@ -0,0 +1,317 @@
+package channel
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sort"
+)
+
+const (
+	// simulatedChunkRunes is the length of the text deltas of a simulated stream.
+	simulatedChunkRunes = 32
+
+	eventStreamContentType = "text/event-stream"
+)
+
+// decodeJSONObject decodes a JSON object keeping its numbers as they are.
+func decodeJSONObject(bodyBytes []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(bodyBytes))
+	decoder.UseNumber()
+	var data map[string]any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("body is not a JSON object")
+	}
+	return data, nil
+}
+
+// disableBodyStream sets "stream" to false in a JSON request body and drops its stream options.
+func disableBodyStream(bodyBytes []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(bodyBytes)) == 0 {
+		return bodyBytes, nil
+	}
+	data, err := decodeJSONObject(bodyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON request body: %w", err)
+	}
+	data["stream"] = false
+	delete(data, "stream_options")
+	return json.Marshal(data)
+}
+
+// splitText splits a text into deltas of simulatedChunkRunes runes.
+func splitText(text string) []string {
+	runes := []rune(text)
+	chunks := make([]string, 0, len(runes)/simulatedChunkRunes+1)
+	for start := 0; start < len(runes); start += simulatedChunkRunes {
+		end := min(start+simulatedChunkRunes, len(runes))
+		chunks = append(chunks, string(runes[start:end]))
+	}
+	return chunks
+}
+
+// sseWriter builds the server-sent events of a simulated stream.
+type sseWriter struct {
+	buf bytes.Buffer
+	err error
+}
+
+// event appends an event, named unless name is empty.
+func (w *sseWriter) event(name string, data any) {
+	if w.err != nil {
+		return
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		w.err = err
+		return
+	}
+	if name != "" {
+		w.buf.WriteString("event: " + name + "\n")
+	}
+	w.buf.WriteString("data: ")
+	w.buf.Write(payload)
+	w.buf.WriteString("\n\n")
+}
+
+func (w *sseWriter) bytes() ([]byte, string, error) {
+	if w.err != nil {
+		return nil, "", w.err
+	}
+	return w.buf.Bytes(), eventStreamContentType, nil
+}
+
+// simulateChatCompletionStream replays an OpenAI chat completion as its chunks: the role of
+// each choice, its text fields in deltas, its tool calls, its finish reason, then the usage.
+func simulateChatCompletionStream(bodyBytes []byte) ([]byte, string, error) {
+	completion, err := decodeJSONObject(bodyBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	if completion["object"] != "chat.completion" {
+		return nil, "", fmt.Errorf("response is not a chat completion")
+	}
+	choices, _ := completion["choices"].([]any)
+
+	base := map[string]any{"object": "chat.completion.chunk"}
+	for _, field := range []string{"id", "created", "model", "system_fingerprint", "service_tier"} {
+		if value, ok := completion[field]; ok {
+			base[field] = value
+		}
+	}
+	chunk := func(choices []any) map[string]any {
+		data := maps.Clone(base)
+		data["choices"] = choices
+		return data
+	}
+	delta := func(index any, delta map[string]any) map[string]any {
+		return map[string]any{"index": index, "delta": delta, "finish_reason": nil}
+	}
+
+	var w sseWriter
+	for _, item := range choices {
+		choice, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		index := choice["index"]
+		message, _ := choice["message"].(map[string]any)
+
+		role := message["role"]
+		if role == nil {
+			role = "assistant"
+		}
+		w.event("", chunk([]any{delta(index, map[string]any{"role": role})}))
+
+		// Text fields such as the reasoning come before the content
+		textFields := make([]string, 0, len(message))
+		for field, value := range message {
+			if _, ok := value.(string); ok && field != "role" && field != "content" {
+				textFields = append(textFields, field)
+			}
+		}
+		sort.Strings(textFields)
+		if _, ok := message["content"].(string); ok {
+			textFields = append(textFields, "content")
+		}
+		for _, field := range textFields {
+			for _, text := range splitText(message[field].(string)) {
+				w.event("", chunk([]any{delta(index, map[string]any{field: text})}))
+			}
+		}
+
+		if toolCalls, ok := message["tool_calls"].([]any); ok && len(toolCalls) > 0 {
+			indexed := make([]any, 0, len(toolCalls))
+			for i, call := range toolCalls {
+				if callData, ok := call.(map[string]any); ok {
+					callData["index"] = i
+					indexed = append(indexed, callData)
+				}
+			}
+			w.event("", chunk([]any{delta(index, map[string]any{"tool_calls": indexed})}))
+		}
+
+		w.event("", chunk([]any{map[string]any{"index": index, "delta": map[string]any{}, "finish_reason": choice["finish_reason"]}}))
+	}
+
+	if usage, ok := completion["usage"]; ok && usage != nil {
+		data := chunk([]any{})
+		data["usage"] = usage
+		w.event("", data)
+	}
+	if w.err == nil {
+		w.buf.WriteString("data: [DONE]\n\n")
+	}
+	return w.bytes()
+}
+
+// simulateAnthropicStream replays an Anthropic message as its events: message_start, the start,
+// deltas and stop of each content block, message_delta and message_stop.
+func simulateAnthropicStream(bodyBytes []byte) ([]byte, string, error) {
+	message, err := decodeJSONObject(bodyBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	if message["type"] != "message" {
+		return nil, "", fmt.Errorf("response is not a message")
+	}
+	blocks, _ := message["content"].([]any)
+
+	started := maps.Clone(message)
+	started["content"] = []any{}
+	started["stop_reason"] = nil
+	started["stop_sequence"] = nil
+
+	var w sseWriter
+	w.event("message_start", map[string]any{"type": "message_start", "message": started})
+	for index, item := range blocks {
+		block, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		blockDelta := func(delta map[string]any) {
+			w.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": index, "delta": delta})
+		}
+
+		switch block["type"] {
+		case "text":
+			text, _ := block["text"].(string)
+			w.event("content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": map[string]any{"type": "text", "text": ""}})
+			for _, delta := range splitText(text) {
+				blockDelta(map[string]any{"type": "text_delta", "text": delta})
+			}
+		case "thinking":
+			thinking, _ := block["thinking"].(string)
+			w.event("content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": map[string]any{"type": "thinking", "thinking": ""}})
+			for _, delta := range splitText(thinking) {
+				blockDelta(map[string]any{"type": "thinking_delta", "thinking": delta})
+			}
+			if signature, ok := block["signature"].(string); ok {
+				blockDelta(map[string]any{"type": "signature_delta", "signature": signature})
+			}
+		case "tool_use", "server_tool_use":
+			input, err := json.Marshal(block["input"])
+			if err != nil {
+				return nil, "", err
+			}
+			startBlock := maps.Clone(block)
+			startBlock["input"] = map[string]any{}
+			w.event("content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": startBlock})
+			blockDelta(map[string]any{"type": "input_json_delta", "partial_json": string(input)})
+		default:
+			w.event("content_block_start", map[string]any{"type": "content_block_start", "index": index, "content_block": block})
+		}
+		w.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": index})
+	}
+
+	messageDelta := map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": message["stop_reason"], "stop_sequence": message["stop_sequence"]},
+	}
+	if usage, ok := message["usage"]; ok {
+		messageDelta["usage"] = usage
+	}
+	w.event("message_delta", messageDelta)
+	w.event("message_stop", map[string]any{"type": "message_stop"})
+	return w.bytes()
+}
+
+// simulateResponseStream replays an OpenAI Responses API response as its events: created,
+// each output item with the deltas of its output text, then completed.
+func simulateResponseStream(bodyBytes []byte) ([]byte, string, error) {
+	response, err := decodeJSONObject(bodyBytes)
+	if err != nil {
+		return nil, "", err
+	}
+	if response["object"] != "response" {
+		return nil, "", fmt.Errorf("response is not a Responses API response")
+	}
+	outputs, _ := response["output"].([]any)
+
+	created := maps.Clone(response)
+	created["status"] = "in_progress"
+	created["output"] = []any{}
+
+	var w sseWriter
+	sequence := 0
+	event := func(name string, data map[string]any) {
+		data["type"] = name
+		data["sequence_number"] = sequence
+		sequence++
+		w.event(name, data)
+	}
+
+	event("response.created", map[string]any{"response": created})
+	for outputIndex, item := range outputs {
+		output, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		itemID := output["id"]
+		parts, _ := output["content"].([]any)
+		if output["type"] != "message" {
+			parts = nil
+		}
+
+		added := maps.Clone(output)
+		if parts != nil {
+			added["content"] = []any{}
+			added["status"] = "in_progress"
+		}
+		event("response.output_item.added", map[string]any{"output_index": outputIndex, "item": added})
+
+		for contentIndex, partItem := range parts {
+			part, ok := partItem.(map[string]any)
+			if !ok {
+				continue
+			}
+			position := func(data map[string]any) map[string]any {
+				data["item_id"] = itemID
+				data["output_index"] = outputIndex
+				data["content_index"] = contentIndex
+				return data
+			}
+			text, isText := part["text"].(string)
+			if part["type"] != "output_text" || !isText {
+				event("response.content_part.added", position(map[string]any{"part": part}))
+				event("response.content_part.done", position(map[string]any{"part": part}))
+				continue
+			}
+
+			event("response.content_part.added", position(map[string]any{"part": map[string]any{"type": "output_text", "text": "", "annotations": []any{}}}))
+			for _, delta := range splitText(text) {
+				event("response.output_text.delta", position(map[string]any{"delta": delta}))
+			}
+			event("response.output_text.done", position(map[string]any{"text": text}))
+			event("response.content_part.done", position(map[string]any{"part": part}))
+		}
+
+		event("response.output_item.done", map[string]any{"output_index": outputIndex, "item": output})
+	}
+	event("response.completed", map[string]any{"response": response})
+	return w.bytes()
+}
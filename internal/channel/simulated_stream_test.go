@@ -0,0 +1,65 @@
+package channel
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSimulateChatCompletionStream checks that a chat completion is replayed as chunks that
+// add up to the original answer, followed by the usage and [DONE].
+func TestSimulateChatCompletionStream(t *testing.T) {
+	body, err := disableBodyStream([]byte(`{"model":"gpt-4o","stream":true,"stream_options":{"include_usage":true},"seed":12345678901234567}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != `{"model":"gpt-4o","seed":12345678901234567,"stream":false}` {
+		t.Errorf("disableBodyStream() = %s", body)
+	}
+
+	answer := strings.Repeat("streamed answer ", 5)
+	stream, contentType, err := simulateChatCompletionStream([]byte(`{"id":"c1","object":"chat.completion","created":1,"model":"gpt-4o",` +
+		`"choices":[{"index":0,"message":{"role":"assistant","content":"` + answer + `"},"finish_reason":"stop"}],"usage":{"total_tokens":9}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType != eventStreamContentType || !strings.HasSuffix(string(stream), "data: [DONE]\n\n") {
+		t.Fatalf("stream = %s %s", contentType, stream)
+	}
+	var content strings.Builder
+	for _, line := range strings.Split(string(stream), "\n") {
+		if _, piece, ok := strings.Cut(line, `"content":"`); ok {
+			content.WriteString(piece[:strings.Index(piece, `"`)])
+		}
+	}
+	if content.String() != answer || !strings.Contains(string(stream), `"finish_reason":"stop"`) || !strings.Contains(string(stream), `"usage":{"total_tokens":9}`) {
+		t.Errorf("stream = %s", stream)
+	}
+
+	if _, _, err := simulateChatCompletionStream([]byte(`{"error":{"message":"bad"}}`)); err == nil {
+		t.Error("simulateChatCompletionStream() of an error should fail")
+	}
+}
+
+// TestGeminiStreamSimulation checks that Gemini streaming requests use generateContent and
+// that the response is replayed in the format the client asked for.
+func TestGeminiStreamSimulation(t *testing.T) {
+	ch := &GeminiChannel{}
+	req := httptest.NewRequest("POST", "https://upstream/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse&key=k", nil)
+	if _, err := ch.DisableStream(req, []byte(`{}`)); err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.Path != "/v1beta/models/gemini-2.0-flash:generateContent" || req.URL.RawQuery != "key=k" {
+		t.Errorf("DisableStream() URL = %s", req.URL)
+	}
+
+	response := []byte("{\n  \"candidates\": []\n}")
+	sse := httptest.NewRequest("POST", "/proxy/g/v1beta/models/m:streamGenerateContent?alt=sse", nil)
+	if stream, contentType, _ := ch.SimulateStream(sse, response); string(stream) != "data: {\"candidates\":[]}\n\n" || contentType != eventStreamContentType {
+		t.Errorf("SimulateStream(alt=sse) = %q %s", stream, contentType)
+	}
+	array := httptest.NewRequest("POST", "/proxy/g/v1beta/models/m:streamGenerateContent", nil)
+	if stream, contentType, _ := ch.SimulateStream(array, response); string(stream) != `[{"candidates":[]}]` || contentType != "application/json" {
+		t.Errorf("SimulateStream() = %q %s", stream, contentType)
+	}
+}
@@ -0,0 +1,189 @@
+package channel
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// passiveHealthAlpha is the smoothing factor of the error rate and latency moving averages.
+	passiveHealthAlpha = 0.2
+	// minLatencyFactor keeps slow but working upstreams in rotation with a small share.
+	minLatencyFactor = 0.2
+	// weightScale turns fractional health scores into integer weights for smooth round-robin.
+	weightScale = 100
+	// maxHealthCheckTimeout caps a single active health check request.
+	maxHealthCheckTimeout = 10 * time.Second
+)
+
+// upstreamHealthChecker is implemented by channels embedding BaseChannel.
+type upstreamHealthChecker interface {
+	checkUpstreamHealth(ctx context.Context)
+}
+
+// recordPassiveResult updates the moving averages of an upstream from a proxied request.
+func (up *UpstreamInfo) recordPassiveResult(success bool, latency time.Duration) {
+	failed := 0.0
+	if !success {
+		failed = 1.0
+	}
+	up.errorRate = up.errorRate*(1-passiveHealthAlpha) + failed*passiveHealthAlpha
+
+	if success && latency > 0 {
+		ms := float64(latency.Milliseconds())
+		if up.latencyMs == 0 {
+			up.latencyMs = ms
+		} else {
+			up.latencyMs = up.latencyMs*(1-passiveHealthAlpha) + ms*passiveHealthAlpha
+		}
+	}
+}
+
+// scaledWeight returns the configured weight adjusted by the passive health score.
+// Errors and latency relative to the fastest candidate lower the weight, and as the
+// moving averages recover the weight shifts back to the configured value.
+func (up *UpstreamInfo) scaledWeight(fastestLatencyMs float64) int {
+	score := 1 - up.errorRate
+	if fastestLatencyMs > 0 && up.latencyMs > 0 {
+		score *= math.Max(fastestLatencyMs/up.latencyMs, minLatencyFactor)
+	}
+	return max(int(math.Round(float64(up.Weight*weightScale)*score)), 1)
+}
+
+// checkUpstreamHealth actively probes the upstreams that are due for a health check.
+func (b *BaseChannel) checkUpstreamHealth(ctx context.Context) {
+	path := b.effectiveConfig.UpstreamHealthCheckPath
+	if path == "" {
+		return
+	}
+	if !b.healthChecking.CompareAndSwap(false, true) {
+		return
+	}
+	defer b.healthChecking.Store(false)
+
+	interval := time.Duration(b.effectiveConfig.UpstreamHealthCheckInterval) * time.Second
+	now := time.Now()
+
+	b.upstreamLock.Lock()
+	var due []*url.URL
+	for i := range b.Upstreams {
+		if now.Sub(b.Upstreams[i].lastHealthCheck) >= interval {
+			b.Upstreams[i].lastHealthCheck = now
+			due = append(due, b.Upstreams[i].URL)
+		}
+	}
+	b.upstreamLock.Unlock()
+
+	for _, upstreamURL := range due {
+		healthy := b.probeUpstream(ctx, upstreamURL, path, min(interval, maxHealthCheckTimeout))
+		b.setUpstreamHealth(upstreamURL, healthy)
+	}
+}
+
+// probeUpstream sends a single health check request and compares the status with the expected one.
+func (b *BaseChannel) probeUpstream(ctx context.Context, upstreamURL *url.URL, path string, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	checkURL := *upstreamURL
+	checkURL.Path = strings.TrimRight(checkURL.Path, "/") + "/" + strings.TrimLeft(path, "/")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, checkURL.String(), nil)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"upstream": upstreamURL.String(), "error": err}).Warn("Failed to create upstream health check request")
+		return false
+	}
+	b.ApplyUpstreamAuth(req)
+
+	resp, err := b.HTTPClient.Do(req)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"upstream": upstreamURL.String(), "error": err}).Debug("Upstream health check failed")
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == b.effectiveConfig.UpstreamHealthCheckStatus
+}
+
+func (b *BaseChannel) setUpstreamHealth(upstreamURL *url.URL, healthy bool) {
+	b.upstreamLock.Lock()
+	defer b.upstreamLock.Unlock()
+
+	for i := range b.Upstreams {
+		up := &b.Upstreams[i]
+		if up.URL != upstreamURL || up.unhealthy == !healthy {
+			continue
+		}
+		up.unhealthy = !healthy
+		if healthy {
+			logrus.WithField("upstream", up.URL.String()).Info("Upstream health check passed, returned to rotation")
+		} else {
+			logrus.WithField("upstream", up.URL.String()).Warn("Upstream health check failed, removed from rotation")
+		}
+	}
+}
+
+// StartHealthChecks starts the loop that actively checks the upstreams of cached channels.
+func (f *Factory) StartHealthChecks() {
+	f.healthWg.Add(1)
+	go f.runHealthChecks()
+}
+
+// Stop stops the health check loop, respecting the context for shutdown timeout.
+func (f *Factory) Stop(ctx context.Context) {
+	close(f.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		f.healthWg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("Upstream health checker stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("Upstream health checker stop timed out.")
+	}
+}
+
+func (f *Factory) runHealthChecks() {
+	defer f.healthWg.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Each channel tracks its own interval, the ticker only bounds the check granularity
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.cacheLock.Lock()
+			channels := make([]ChannelProxy, 0, len(f.channelCache))
+			for _, ch := range f.channelCache {
+				channels = append(channels, ch)
+			}
+			f.cacheLock.Unlock()
+
+			for _, ch := range channels {
+				if checker, ok := ch.(upstreamHealthChecker); ok {
+					f.healthWg.Add(1)
+					go func() {
+						defer f.healthWg.Done()
+						checker.checkUpstreamHealth(ctx)
+					}()
+				}
+			}
+		case <-f.stopChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package channel
+
+import (
+	"context"
+	"gpt-load/internal/httpclient"
+	"net/http"
+	"time"
+)
+
+// connectivityTestTimeout caps the connectivity test of a single upstream.
+const connectivityTestTimeout = 10 * time.Second
+
+// UpstreamConnectivity is the result of a connectivity test of one upstream.
+type UpstreamConnectivity struct {
+	URL        string `json:"url"`
+	Proxy      string `json:"proxy,omitempty"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMs  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// upstreamTransport sends each request through the client of the upstream it targets, so that
+// upstreams with their own outbound proxy or transport settings keep a separate connection pool. Requests to other
+// upstreams use the group's transport.
+type upstreamTransport struct {
+	channel  *BaseChannel
+	stream   bool
+	fallback http.RoundTripper
+}
+
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 重定向后的请求沿用最初上游的代理，避免绕过代理直连
+	origin := req
+	for origin.Response != nil && origin.Response.Request != nil {
+		origin = origin.Response.Request
+	}
+
+	if up := t.channel.findUpstream(origin.URL.String()); up != nil {
+		client := up.httpClient
+		if t.stream {
+			client = up.streamClient
+		}
+		if client != nil {
+			return client.Transport.RoundTrip(req)
+		}
+	}
+	return t.fallback.RoundTrip(req)
+}
+
+// withUpstreamProxies wraps the channel clients so that upstreams with a proxy_url or transport
+// settings use their own.
+func (b *BaseChannel) withUpstreamProxies() {
+	hasProxy := false
+	for i := range b.Upstreams {
+		if b.Upstreams[i].httpClient != nil {
+			hasProxy = true
+			break
+		}
+	}
+	if !hasProxy {
+		return
+	}
+
+	b.HTTPClient = &http.Client{
+		Transport:     &upstreamTransport{channel: b, fallback: b.HTTPClient.Transport},
+		Timeout:       b.HTTPClient.Timeout,
+		CheckRedirect: b.HTTPClient.CheckRedirect,
+	}
+	b.StreamClient = &http.Client{
+		Transport:     &upstreamTransport{channel: b, stream: true, fallback: b.StreamClient.Transport},
+		Timeout:       b.StreamClient.Timeout,
+		CheckRedirect: b.StreamClient.CheckRedirect,
+	}
+}
+
+// TestConnectivity sends a request to every upstream through its outbound proxy, if any.
+// Any HTTP response counts as reachable, since the request carries no key.
+func (b *BaseChannel) TestConnectivity(ctx context.Context) []UpstreamConnectivity {
+	results := make([]UpstreamConnectivity, len(b.Upstreams))
+	for i := range b.Upstreams {
+		up := &b.Upstreams[i]
+		result := &results[i]
+		result.URL = up.URL.String()
+		if up.ProxyURL != "" {
+			result.Proxy = httpclient.RedactProxyURL(up.ProxyURL)
+		} else if b.groupProxyURL != "" {
+			result.Proxy = httpclient.RedactProxyURL(b.groupProxyURL)
+		}
+
+		reqCtx, cancel := context.WithTimeout(ctx, connectivityTestTimeout)
+		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, result.URL, nil)
+		if err != nil {
+			cancel()
+			result.Error = err.Error()
+			continue
+		}
+
+		start := time.Now()
+		resp, err := b.HTTPClient.Do(req)
+		result.LatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			resp.Body.Close()
+			result.Reachable = true
+			result.StatusCode = resp.StatusCode
+		}
+		cancel()
+	}
+	return results
+}
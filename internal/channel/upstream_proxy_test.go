@@ -0,0 +1,57 @@
+package channel
+
+import (
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestUpstreamProxyRouting checks that only the upstream with a proxy_url goes through the proxy.
+func TestUpstreamProxyRouting(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer proxy.Close()
+
+	direct := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer direct.Close()
+
+	group := &models.Group{
+		Name:      "proxied",
+		Upstreams: []byte(`[{"url":"http://geo.invalid","weight":1,"proxy_url":"` + proxy.URL + `"},{"url":"` + direct.URL + `","weight":1}]`),
+		EffectiveConfig: types.SystemSettings{
+			ConnectTimeout:        5,
+			RequestTimeout:        5,
+			IdleConnTimeout:       5,
+			ResponseHeaderTimeout: 5,
+			MaxIdleConns:          10,
+			MaxIdleConnsPerHost:   10,
+		},
+	}
+	f := &Factory{clientManager: httpclient.NewHTTPClientManager()}
+	base, err := f.newBaseChannel("openai", group)
+	if err != nil {
+		t.Fatalf("newBaseChannel() error = %v", err)
+	}
+
+	results := base.TestConnectivity(t.Context())
+	if len(results) != 2 {
+		t.Fatalf("TestConnectivity() returned %d results, want 2", len(results))
+	}
+	if !results[0].Reachable || results[0].StatusCode != http.StatusTeapot || results[0].Proxy == "" {
+		t.Errorf("proxied upstream result = %+v", results[0])
+	}
+	if !results[1].Reachable || results[1].StatusCode != http.StatusOK || results[1].Proxy != "" {
+		t.Errorf("direct upstream result = %+v", results[1])
+	}
+	if len(proxied) != 1 || proxied[0] != "http://geo.invalid/" {
+		t.Errorf("proxied requests = %v, want only the geo.invalid upstream", proxied)
+	}
+}
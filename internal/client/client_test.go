@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c, err := New(server.URL, "secret", WithRetryBackoff(time.Millisecond))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	return c
+}
+
+func TestClientRetriesIdempotentRequests(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"code":"DATABASE_DEGRADED","message":"degraded"}`)
+			return
+		}
+		fmt.Fprint(w, `{"code":0,"message":"ok","data":["openai","gemini"]}`)
+	})
+
+	types, err := c.GetChannelTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetChannelTypes() error = %v", err)
+	}
+	if len(types) != 2 || types[0] != "openai" || calls.Load() != 3 {
+		t.Errorf("GetChannelTypes() = %v after %d calls", types, calls.Load())
+	}
+}
+
+func TestClientDoesNotRetryPost(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"code":"SERVER_DRAINING","message":"draining"}`)
+	})
+
+	_, err := c.AddKeys(context.Background(), 1, "sk-1")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "SERVER_DRAINING" || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("AddKeys() error = %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("POST was sent %d times", calls.Load())
+	}
+}
+
+func TestClientNotFound(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/groups/7/stats" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"code":"NOT_FOUND","message":"Resource not found"}`)
+	})
+
+	if _, err := c.GetGroupStats(context.Background(), 7); !IsNotFound(err) {
+		t.Errorf("GetGroupStats() error = %v, want not found", err)
+	}
+}
+
+func TestLogStream(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("status_code") != "500-599" {
+			t.Errorf("status_code = %q", r.URL.Query().Get("status_code"))
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": ping\n\nevent: log\ndata: {\"id\":\"a\",\"status_code\":502}\n\n")
+	})
+
+	stream, err := c.StreamLogs(context.Background(), LogStreamFilter{StatusCodes: "500-599"})
+	if err != nil {
+		t.Fatalf("StreamLogs() error = %v", err)
+	}
+	defer stream.Close()
+
+	entry, err := stream.Next()
+	if err != nil || entry.ID != "a" || entry.StatusCode != 502 {
+		t.Fatalf("Next() = %+v, %v", entry, err)
+	}
+	if _, err := stream.Next(); err != io.EOF {
+		t.Errorf("Next() at the end of the stream = %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/drain"
+	"gpt-load/internal/handler"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/providerstatus"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/upstreamstats"
+)
+
+// EncryptionStatus reports whether the stored keys match the ENCRYPTION_KEY of the instance.
+type EncryptionStatus struct {
+	HasMismatch  bool   `json:"has_mismatch"`
+	ScenarioType string `json:"scenario_type"`
+	Message      string `json:"message"`
+	Suggestion   string `json:"suggestion"`
+}
+
+// MemoryStoreStatus reports the store backend, with its usage when it is the in-memory store.
+type MemoryStoreStatus struct {
+	Backend string             `json:"backend"`
+	Stats   *store.MemoryStats `json:"stats,omitempty"`
+}
+
+// StatsRange selects the period of the dashboard statistics: Range is "24h" (the default),
+// "7d" or "30d", or Start and End when both are set.
+type StatsRange struct {
+	Range      string
+	Start, End time.Time
+}
+
+func (r StatsRange) query() url.Values {
+	query := url.Values{}
+	if !r.Start.IsZero() && !r.End.IsZero() {
+		query.Set("range", "custom")
+		query.Set("start", r.Start.Format(time.RFC3339))
+		query.Set("end", r.End.Format(time.RFC3339))
+	} else if r.Range != "" {
+		query.Set("range", r.Range)
+	}
+	return query
+}
+
+// GetDashboardStats returns the statistics cards of the dashboard over the range.
+func (c *Client) GetDashboardStats(ctx context.Context, statsRange StatsRange) (*models.DashboardStatsResponse, error) {
+	var stats models.DashboardStatsResponse
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/stats", statsRange.query(), nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetDashboardChart returns the requests over the range, hourly or downsampled for long
+// ranges, of one group when groupID is not 0.
+func (c *Client) GetDashboardChart(ctx context.Context, groupID uint, statsRange StatsRange) (*models.ChartData, error) {
+	query := statsRange.query()
+	if groupID != 0 {
+		query.Set("groupId", strconv.FormatUint(uint64(groupID), 10))
+	}
+	var chart models.ChartData
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/chart", query, nil, &chart); err != nil {
+		return nil, err
+	}
+	return &chart, nil
+}
+
+// GetEncryptionStatus checks the stored keys against the encryption configuration.
+func (c *Client) GetEncryptionStatus(ctx context.Context) (*EncryptionStatus, error) {
+	var status EncryptionStatus
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/encryption-status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetKeyStatusQueue returns the state of the queue of pending key status updates.
+func (c *Client) GetKeyStatusQueue(ctx context.Context) (*keypool.StatusQueueStats, error) {
+	var stats keypool.StatusQueueStats
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/key-status-queue", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetRequestLogWriter returns the state of the buffer of request logs waiting to be written.
+func (c *Client) GetRequestLogWriter(ctx context.Context) (*services.RequestLogWriterStats, error) {
+	var stats services.RequestLogWriterStats
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/request-log-writer", nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetMemoryStore returns the store backend and the usage of the in-memory store.
+func (c *Client) GetMemoryStore(ctx context.Context) (*MemoryStoreStatus, error) {
+	var status MemoryStoreStatus
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/memory-store", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// RunStoreHygiene removes the store data no longer backed by the database.
+func (c *Client) RunStoreHygiene(ctx context.Context) (*services.StoreHygieneReport, error) {
+	var report services.StoreHygieneReport
+	if _, err := c.do(ctx, http.MethodPost, "/api/dashboard/store-hygiene", nil, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RunPoolReconcile compares the key pools in the store with the database and repairs the
+// drift, or only reports it when dryRun is set.
+func (c *Client) RunPoolReconcile(ctx context.Context, dryRun bool) (*services.PoolReconcileReport, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	var report services.PoolReconcileReport
+	if _, err := c.do(ctx, http.MethodPost, "/api/dashboard/pool-reconcile", query, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// FlushStore clears the store data of one scope, limited to a group when groupID is not 0.
+func (c *Client) FlushStore(ctx context.Context, scope string, groupID uint) (*services.StoreFlushReport, error) {
+	var report services.StoreFlushReport
+	req := handler.StoreFlushRequest{Scope: scope, GroupID: groupID}
+	if _, err := c.do(ctx, http.MethodPost, "/api/dashboard/store-flush", nil, req, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetDrainStatus reports whether the instance is draining.
+func (c *Client) GetDrainStatus(ctx context.Context) (*drain.Status, error) {
+	return c.drain(ctx, http.MethodGet)
+}
+
+// StartDrain puts the instance into drain mode, after which it shuts down.
+func (c *Client) StartDrain(ctx context.Context) (*drain.Status, error) {
+	return c.drain(ctx, http.MethodPost)
+}
+
+func (c *Client) drain(ctx context.Context, method string) (*drain.Status, error) {
+	var status drain.Status
+	if _, err := c.do(ctx, method, "/api/dashboard/drain", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetClusterStatus lists the live instances of the cluster and its leader.
+func (c *Client) GetClusterStatus(ctx context.Context) (*cluster.Status, error) {
+	var status cluster.Status
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/cluster", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetProviderStatus lists the unresolved incidents reported by the status pages of the providers.
+func (c *Client) GetProviderStatus(ctx context.Context) (*providerstatus.Status, error) {
+	var status providerstatus.Status
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/provider-status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetUpstreamStats returns the latency and status codes of each upstream over the last hour on
+// the instance, of one group when group is not empty.
+func (c *Client) GetUpstreamStats(ctx context.Context, group string) ([]upstreamstats.Stats, error) {
+	query := url.Values{}
+	if group != "" {
+		query.Set("group", group)
+	}
+	var stats []upstreamstats.Stats
+	if _, err := c.do(ctx, http.MethodGet, "/api/dashboard/upstreams", query, nil, &stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// UsageSnapshotOptions narrows the counters returned for a usage snapshot.
+type UsageSnapshotOptions struct {
+	GroupID     uint
+	IncludeKeys bool
+}
+
+func (o UsageSnapshotOptions) query() url.Values {
+	query := url.Values{}
+	if o.GroupID != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(o.GroupID), 10))
+	}
+	if o.IncludeKeys {
+		query.Set("include_keys", "true")
+	}
+	return query
+}
+
+// ListUsageSnapshots lists the usage snapshots taken at or before asOf, or the latest ones
+// when it is zero, newest first.
+func (c *Client) ListUsageSnapshots(ctx context.Context, asOf time.Time, limit int) ([]models.UsageSnapshot, error) {
+	query := url.Values{}
+	if !asOf.IsZero() {
+		query.Set("as_of", asOf.Format(time.RFC3339))
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var snapshots []models.UsageSnapshot
+	_, err := c.do(ctx, http.MethodGet, "/api/usage-snapshots", query, nil, &snapshots)
+	return snapshots, err
+}
+
+// TakeUsageSnapshot takes a snapshot of the current usage counters.
+func (c *Client) TakeUsageSnapshot(ctx context.Context) (*models.UsageSnapshot, error) {
+	var snapshot models.UsageSnapshot
+	if _, err := c.do(ctx, http.MethodPost, "/api/usage-snapshots", nil, nil, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// GetUsageSnapshot returns the counters of a snapshot.
+func (c *Client) GetUsageSnapshot(ctx context.Context, id uint, opts UsageSnapshotOptions) (*services.UsageSnapshotView, error) {
+	var view services.UsageSnapshotView
+	path := fmt.Sprintf("/api/usage-snapshots/%d", id)
+	if _, err := c.do(ctx, http.MethodGet, path, opts.query(), nil, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
+
+// GetUsageSnapshotAsOf returns the counters of the latest snapshot taken at or before asOf,
+// or of the latest snapshot when it is zero.
+func (c *Client) GetUsageSnapshotAsOf(ctx context.Context, asOf time.Time, opts UsageSnapshotOptions) (*services.UsageSnapshotView, error) {
+	query := opts.query()
+	if !asOf.IsZero() {
+		query.Set("as_of", asOf.Format(time.RFC3339))
+	}
+	var view services.UsageSnapshotView
+	if _, err := c.do(ctx, http.MethodGet, "/api/usage-snapshots/as-of", query, nil, &view); err != nil {
+		return nil, err
+	}
+	return &view, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"gpt-load/internal/handler"
+	"gpt-load/internal/models"
+)
+
+// GetGroupByExternalID returns the group carrying an external ID.
+func (c *Client) GetGroupByExternalID(ctx context.Context, externalID string) (*Group, error) {
+	var group Group
+	if _, err := c.do(ctx, http.MethodGet, groupExternalIDPath(externalID), nil, nil, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// UpsertGroupByExternalID creates or updates the group carrying an external ID. Being a PUT,
+// it is retried like the other idempotent requests.
+func (c *Client) UpsertGroupByExternalID(ctx context.Context, externalID string, req handler.GroupUpsertRequest) (*Group, error) {
+	var group Group
+	if _, err := c.do(ctx, http.MethodPut, groupExternalIDPath(externalID), nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteGroupByExternalID deletes the group carrying an external ID and its keys.
+func (c *Client) DeleteGroupByExternalID(ctx context.Context, externalID string) error {
+	_, err := c.do(ctx, http.MethodDelete, groupExternalIDPath(externalID), nil, nil, nil)
+	return err
+}
+
+// GetKeyByExternalID returns the key carrying an external ID, decrypted.
+func (c *Client) GetKeyByExternalID(ctx context.Context, externalID string) (*models.APIKey, error) {
+	var key models.APIKey
+	if _, err := c.do(ctx, http.MethodGet, keyExternalIDPath(externalID), nil, nil, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// UpsertKeyByExternalID creates the key carrying an external ID, or updates its notes and tags.
+func (c *Client) UpsertKeyByExternalID(ctx context.Context, externalID string, req handler.KeyUpsertRequest) (*models.APIKey, error) {
+	var key models.APIKey
+	if _, err := c.do(ctx, http.MethodPut, keyExternalIDPath(externalID), nil, req, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// DeleteKeyByExternalID moves the key carrying an external ID to the trash.
+func (c *Client) DeleteKeyByExternalID(ctx context.Context, externalID string) error {
+	_, err := c.do(ctx, http.MethodDelete, keyExternalIDPath(externalID), nil, nil, nil)
+	return err
+}
+
+func groupExternalIDPath(externalID string) string {
+	return "/api/groups/by-external-id/" + url.PathEscape(externalID)
+}
+
+func keyExternalIDPath(externalID string) string {
+	return "/api/keys/by-external-id/" + url.PathEscape(externalID)
+}
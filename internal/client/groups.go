@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/handler"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/services"
+	"gpt-load/internal/templates"
+)
+
+// Group is a group as returned by the admin API.
+type Group = handler.GroupResponse
+
+// ListGroups lists all groups.
+func (c *Client) ListGroups(ctx context.Context) ([]Group, error) {
+	var groups []Group
+	_, err := c.do(ctx, http.MethodGet, "/api/groups", nil, nil, &groups)
+	return groups, err
+}
+
+// ListGroupNames lists the ID, name and display name of all groups.
+func (c *Client) ListGroupNames(ctx context.Context) ([]models.Group, error) {
+	var groups []models.Group
+	_, err := c.do(ctx, http.MethodGet, "/api/groups/list", nil, nil, &groups)
+	return groups, err
+}
+
+// CreateGroup creates a group.
+func (c *Client) CreateGroup(ctx context.Context, req handler.GroupCreateRequest) (*Group, error) {
+	var group Group
+	if _, err := c.do(ctx, http.MethodPost, "/api/groups", nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// ValidateGroup checks a group payload without saving it and reports every invalid field,
+// the reachability of the upstreams and the live test call made with req.TestKey.
+func (c *Client) ValidateGroup(ctx context.Context, req handler.GroupValidateRequest) (*handler.GroupValidationResponse, error) {
+	var validation handler.GroupValidationResponse
+	if _, err := c.do(ctx, http.MethodPost, "/api/groups/validate", nil, req, &validation); err != nil {
+		return nil, err
+	}
+	return &validation, nil
+}
+
+// UpdateGroup updates the fields of a group set in req.
+func (c *Client) UpdateGroup(ctx context.Context, id uint, req handler.GroupUpdateRequest) (*Group, error) {
+	var group Group
+	if _, err := c.do(ctx, http.MethodPut, groupPath(id, ""), nil, req, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// DeleteGroup deletes a group and its keys.
+func (c *Client) DeleteGroup(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, groupPath(id, ""), nil, nil, nil)
+	return err
+}
+
+// CopyGroup copies a group. copyKeys is "none", "valid_only" or "all".
+func (c *Client) CopyGroup(ctx context.Context, id uint, copyKeys string) (*Group, error) {
+	var resp struct {
+		Group *Group `json:"group"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, groupPath(id, "/copy"), nil, handler.GroupCopyRequest{CopyKeys: copyKeys}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Group, nil
+}
+
+// ReorderGroups sets the sort order of groups.
+func (c *Client) ReorderGroups(ctx context.Context, items []handler.GroupReorderItemRequest) error {
+	_, err := c.do(ctx, http.MethodPut, "/api/groups/reorder", nil, handler.GroupReorderRequest{Items: items}, nil)
+	return err
+}
+
+// SetGroupsEnabled enables or disables the groups selected by req.
+func (c *Client) SetGroupsEnabled(ctx context.Context, req handler.GroupBulkRequest, enabled bool) (*services.GroupBulkResult, error) {
+	var result services.GroupBulkResult
+	body := handler.GroupBulkEnabledRequest{GroupBulkRequest: req, Enabled: &enabled}
+	if _, err := c.do(ctx, http.MethodPut, "/api/groups/bulk/enabled", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateGroupsConfig sets config fields on the groups selected by req, a nil value removes
+// the field.
+func (c *Client) UpdateGroupsConfig(ctx context.Context, req handler.GroupBulkRequest, config map[string]any) (*services.GroupBulkResult, error) {
+	var result services.GroupBulkResult
+	body := handler.GroupBulkConfigRequest{GroupBulkRequest: req, Config: config}
+	if _, err := c.do(ctx, http.MethodPut, "/api/groups/bulk/config", nil, body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetGroupStats returns the key and request statistics of a group.
+func (c *Client) GetGroupStats(ctx context.Context, id uint) (*services.GroupStats, error) {
+	var stats services.GroupStats
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id, "/stats"), nil, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// GetGroupCanary returns the canary probes of a group over the last hours, 24 when hours is 0.
+func (c *Client) GetGroupCanary(ctx context.Context, id uint, hours int) (*proxy.CanaryHistory, error) {
+	query := url.Values{}
+	if hours > 0 {
+		query.Set("hours", strconv.Itoa(hours))
+	}
+	var history proxy.CanaryHistory
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id, "/canary"), query, nil, &history); err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// DiscoverGroupModels lists the models the upstreams of a group serve, bypassing the cache when refresh is set.
+func (c *Client) DiscoverGroupModels(ctx context.Context, id uint, refresh bool) ([]channel.DiscoveredModel, error) {
+	query := url.Values{}
+	if refresh {
+		query.Set("refresh", "true")
+	}
+	var discovered []channel.DiscoveredModel
+	_, err := c.do(ctx, http.MethodGet, groupPath(id, "/models"), query, nil, &discovered)
+	return discovered, err
+}
+
+// GetGroupPool returns which keys of a group are in its key pool.
+func (c *Client) GetGroupPool(ctx context.Context, id uint) (*keypool.PoolMembership, error) {
+	var membership keypool.PoolMembership
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id, "/pool"), nil, nil, &membership); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// MoveGroupPoolKey puts a key of a group back into rotation (keypool.PoolStateActive), or takes it
+// out of rotation for cooldownSeconds (keypool.PoolStateCooling).
+func (c *Client) MoveGroupPoolKey(ctx context.Context, id, keyID uint, to string, cooldownSeconds int) (*keypool.PoolMembership, error) {
+	var membership keypool.PoolMembership
+	body := handler.PoolMoveRequest{To: to, CooldownSeconds: cooldownSeconds}
+	if _, err := c.do(ctx, http.MethodPost, groupPath(id, fmt.Sprintf("/pool/keys/%d/move", keyID)), nil, body, &membership); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// RebuildGroupPool rebuilds the key pool of a group from the database.
+func (c *Client) RebuildGroupPool(ctx context.Context, id uint) (*keypool.PoolMembership, error) {
+	var membership keypool.PoolMembership
+	if _, err := c.do(ctx, http.MethodPost, groupPath(id, "/pool/rebuild"), nil, nil, &membership); err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// RecoverGroupCooledKeys returns the keys whose cooldown is over to the pool of a group and
+// reports how many were recovered.
+func (c *Client) RecoverGroupCooledKeys(ctx context.Context, id uint) (int, error) {
+	var result struct {
+		Recovered int `json:"recovered"`
+	}
+	if _, err := c.do(ctx, http.MethodPost, groupPath(id, "/pool/recover-cooled"), nil, nil, &result); err != nil {
+		return 0, err
+	}
+	return result.Recovered, nil
+}
+
+// GetGroupReserve lists the keys parked in the reserve of a group.
+func (c *Client) GetGroupReserve(ctx context.Context, id uint) (*keypool.ReserveStatus, error) {
+	var reserve keypool.ReserveStatus
+	if _, err := c.do(ctx, http.MethodGet, groupPath(id, "/reserve"), nil, nil, &reserve); err != nil {
+		return nil, err
+	}
+	return &reserve, nil
+}
+
+// StockGroupReserve parks the keys found in keysText in the reserve of a group.
+func (c *Client) StockGroupReserve(ctx context.Context, id uint, keysText string) (*services.AddKeysResult, error) {
+	var result services.AddKeysResult
+	if _, err := c.do(ctx, http.MethodPost, groupPath(id, "/reserve"), nil, handler.ReserveStockRequest{KeysText: keysText}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DrainGroupReserve deletes the keys parked in the reserve of a group. It returns the
+// message of the server, which reports the number of keys deleted.
+func (c *Client) DrainGroupReserve(ctx context.Context, id uint) (string, error) {
+	return c.do(ctx, http.MethodDelete, groupPath(id, "/reserve"), nil, nil, nil)
+}
+
+// GetGroupConfigOptions lists the settings a group config can override.
+func (c *Client) GetGroupConfigOptions(ctx context.Context) ([]handler.ConfigOption, error) {
+	var options []handler.ConfigOption
+	_, err := c.do(ctx, http.MethodGet, "/api/groups/config-options", nil, nil, &options)
+	return options, err
+}
+
+// ListFeatureFlags lists the feature flags, with their state in the group when groupID is not 0.
+func (c *Client) ListFeatureFlags(ctx context.Context, groupID uint) ([]handler.FeatureFlagInfo, error) {
+	query := url.Values{}
+	if groupID != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(groupID), 10))
+	}
+	var flags []handler.FeatureFlagInfo
+	_, err := c.do(ctx, http.MethodGet, "/api/groups/feature-flags", query, nil, &flags)
+	return flags, err
+}
+
+// ListGroupTemplates lists the templates a group can be created from with GroupCreateRequest.Template.
+func (c *Client) ListGroupTemplates(ctx context.Context) ([]templates.Template, error) {
+	var list []templates.Template
+	_, err := c.do(ctx, http.MethodGet, "/api/groups/templates", nil, nil, &list)
+	return list, err
+}
+
+// PurgeGroupResponseCache drops all cached responses of a group.
+func (c *Client) PurgeGroupResponseCache(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, groupPath(id, "/response-cache"), nil, nil, nil)
+	return err
+}
+
+// TestGroupConnectivity checks that every upstream of a group can be reached.
+func (c *Client) TestGroupConnectivity(ctx context.Context, id uint) ([]channel.UpstreamConnectivity, error) {
+	var results []channel.UpstreamConnectivity
+	_, err := c.do(ctx, http.MethodPost, groupPath(id, "/test-connectivity"), nil, nil, &results)
+	return results, err
+}
+
+// GetSubGroups lists the sub groups of an aggregate group.
+func (c *Client) GetSubGroups(ctx context.Context, id uint) ([]models.SubGroupInfo, error) {
+	var subGroups []models.SubGroupInfo
+	_, err := c.do(ctx, http.MethodGet, groupPath(id, "/sub-groups"), nil, nil, &subGroups)
+	return subGroups, err
+}
+
+// AddSubGroups adds sub groups to an aggregate group.
+func (c *Client) AddSubGroups(ctx context.Context, id uint, subGroups []services.SubGroupInput) error {
+	_, err := c.do(ctx, http.MethodPost, groupPath(id, "/sub-groups"), nil, handler.AddSubGroupsRequest{SubGroups: subGroups}, nil)
+	return err
+}
+
+// UpdateSubGroupWeight sets the weight of a sub group in an aggregate group.
+func (c *Client) UpdateSubGroupWeight(ctx context.Context, id, subGroupID uint, weight int) error {
+	path := groupPath(id, fmt.Sprintf("/sub-groups/%d/weight", subGroupID))
+	_, err := c.do(ctx, http.MethodPut, path, nil, handler.UpdateSubGroupWeightRequest{Weight: weight}, nil)
+	return err
+}
+
+// DeleteSubGroup removes a sub group from an aggregate group.
+func (c *Client) DeleteSubGroup(ctx context.Context, id, subGroupID uint) error {
+	_, err := c.do(ctx, http.MethodDelete, groupPath(id, fmt.Sprintf("/sub-groups/%d", subGroupID)), nil, nil, nil)
+	return err
+}
+
+// GetParentAggregateGroups lists the aggregate groups that use a group.
+func (c *Client) GetParentAggregateGroups(ctx context.Context, id uint) ([]models.ParentAggregateGroupInfo, error) {
+	var groups []models.ParentAggregateGroupInfo
+	_, err := c.do(ctx, http.MethodGet, groupPath(id, "/parent-aggregate-groups"), nil, nil, &groups)
+	return groups, err
+}
+
+func groupPath(id uint, suffix string) string {
+	return fmt.Sprintf("/api/groups/%d%s", id, suffix)
+}
@@ -0,0 +1,26 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"gpt-load/internal/inflight"
+)
+
+// ListInFlightRequests lists the proxy requests the instance is serving, longest running
+// first, of one group if group is not empty.
+func (c *Client) ListInFlightRequests(ctx context.Context, group string) ([]inflight.Request, error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "group", group)
+	var requests []inflight.Request
+	_, err := c.do(ctx, http.MethodGet, "/api/inflight", query, nil, &requests)
+	return requests, err
+}
+
+// CancelInFlightRequest cancels an in-flight proxy request. The request must be in flight on
+// the instance the client talks to, IsNotFound reports otherwise.
+func (c *Client) CancelInFlightRequest(ctx context.Context, id string) error {
+	_, err := c.do(ctx, http.MethodDelete, "/api/inflight/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
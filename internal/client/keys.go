@@ -0,0 +1,237 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/handler"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+)
+
+// Page is one page of a paginated list.
+type Page[T any] struct {
+	Items      []T                 `json:"items"`
+	Pagination response.Pagination `json:"pagination"`
+}
+
+// KeyListOptions filters and paginates ListKeys.
+type KeyListOptions struct {
+	// Status is a key status such as "active" or "invalid", empty for all keys.
+	Status string
+	// KeyValue finds the key with this exact value.
+	KeyValue string
+	// Tags limits the list to the keys carrying all of them.
+	Tags     []string
+	Page     int
+	PageSize int
+}
+
+// KeyTestResponse is the result of TestKeys.
+type KeyTestResponse struct {
+	Results []keypool.KeyTestResult `json:"results"`
+	// TotalDuration is the duration of the whole test in milliseconds.
+	TotalDuration int64 `json:"total_duration"`
+}
+
+// ListKeys lists the keys of a group, decrypted.
+func (c *Client) ListKeys(ctx context.Context, groupID uint, opts KeyListOptions) (*Page[models.APIKey], error) {
+	query := groupQuery(groupID)
+	setIfNotEmpty(query, "status", opts.Status)
+	setIfNotEmpty(query, "key_value", opts.KeyValue)
+	setIfNotEmpty(query, "tags", strings.Join(opts.Tags, ","))
+	setPage(query, opts.Page, opts.PageSize)
+
+	var page Page[models.APIKey]
+	if _, err := c.do(ctx, http.MethodGet, "/api/keys", query, nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// ExportKeys streams the keys of a group as text, one key per line. status is "all",
+// "active" or "invalid", and tags limit the export to the keys carrying all of them. The
+// caller closes the returned reader.
+func (c *Client) ExportKeys(ctx context.Context, groupID uint, status string, tags ...string) (io.ReadCloser, error) {
+	query := groupQuery(groupID)
+	setIfNotEmpty(query, "status", status)
+	setIfNotEmpty(query, "tags", strings.Join(tags, ","))
+	resp, err := c.send(ctx, http.MethodGet, "/api/keys/export", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// AddKeys adds the keys found in keysText to a group.
+func (c *Client) AddKeys(ctx context.Context, groupID uint, keysText string) (*services.AddKeysResult, error) {
+	var result services.AddKeysResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/add-multiple", nil, keyText(groupID, keysText), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// AddKeysAsync starts a background task adding the keys found in keysText to a group.
+// Poll GetTaskStatus for its progress.
+func (c *Client) AddKeysAsync(ctx context.Context, groupID uint, keysText string) (*services.TaskStatus, error) {
+	return c.startTask(ctx, "/api/keys/add-async", keyText(groupID, keysText))
+}
+
+// DeleteKeys deletes the keys found in keysText from a group.
+func (c *Client) DeleteKeys(ctx context.Context, groupID uint, keysText string) (*services.DeleteKeysResult, error) {
+	var result services.DeleteKeysResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/delete-multiple", nil, keyText(groupID, keysText), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteKeysAsync starts a background task deleting the keys found in keysText from a group.
+func (c *Client) DeleteKeysAsync(ctx context.Context, groupID uint, keysText string) (*services.TaskStatus, error) {
+	return c.startTask(ctx, "/api/keys/delete-async", keyText(groupID, keysText))
+}
+
+// RestoreKeys sets the keys found in keysText back to active.
+func (c *Client) RestoreKeys(ctx context.Context, groupID uint, keysText string) (*services.RestoreKeysResult, error) {
+	var result services.RestoreKeysResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/restore-multiple", nil, keyText(groupID, keysText), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RestoreAllInvalidKeys sets all invalid keys of a group back to active. It returns the
+// message of the server, which reports the number of keys restored. Tags limit it to the
+// keys carrying all of them.
+func (c *Client) RestoreAllInvalidKeys(ctx context.Context, groupID uint, tags ...string) (string, error) {
+	return c.do(ctx, http.MethodPost, "/api/keys/restore-all-invalid", nil, handler.GroupIDRequest{GroupID: groupID, Tags: tags}, nil)
+}
+
+// ClearAllInvalidKeys deletes all invalid keys of a group. It returns the message of the
+// server, which reports the number of keys deleted. Tags limit it to the keys carrying all
+// of them.
+func (c *Client) ClearAllInvalidKeys(ctx context.Context, groupID uint, tags ...string) (string, error) {
+	return c.do(ctx, http.MethodPost, "/api/keys/clear-all-invalid", nil, handler.GroupIDRequest{GroupID: groupID, Tags: tags}, nil)
+}
+
+// ClearAllKeys deletes all keys of a group. It returns the message of the server, which
+// reports the number of keys deleted. Tags limit it to the keys carrying all of them.
+func (c *Client) ClearAllKeys(ctx context.Context, groupID uint, tags ...string) (string, error) {
+	return c.do(ctx, http.MethodPost, "/api/keys/clear-all", nil, handler.GroupIDRequest{GroupID: groupID, Tags: tags}, nil)
+}
+
+// ValidateGroupKeys starts a background task validating the keys of a group. status
+// limits it to "active" or "invalid" keys, empty for all keys, and tags to the keys carrying
+// all of them.
+func (c *Client) ValidateGroupKeys(ctx context.Context, groupID uint, status string, tags ...string) (*services.TaskStatus, error) {
+	return c.startTask(ctx, "/api/keys/validate-group", handler.ValidateGroupKeysRequest{GroupID: groupID, Status: status, Tags: tags})
+}
+
+// TestKeys tests the keys found in keysText against the upstream of a group without
+// changing their status.
+func (c *Client) TestKeys(ctx context.Context, groupID uint, keysText string) (*KeyTestResponse, error) {
+	var result KeyTestResponse
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/test-multiple", nil, keyText(groupID, keysText), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateKeyNotes sets the notes of a key.
+func (c *Client) UpdateKeyNotes(ctx context.Context, keyID uint, notes string) error {
+	path := fmt.Sprintf("/api/keys/%d/notes", keyID)
+	_, err := c.do(ctx, http.MethodPut, path, nil, handler.UpdateKeyNotesRequest{Notes: notes}, nil)
+	return err
+}
+
+// GetKeyStats returns the request statistics of a key over the last hours, 24 when hours is 0.
+func (c *Client) GetKeyStats(ctx context.Context, keyID uint, hours int) (*services.KeyUsageStats, error) {
+	query := url.Values{}
+	if hours > 0 {
+		query.Set("hours", strconv.Itoa(hours))
+	}
+	var stats services.KeyUsageStats
+	if _, err := c.do(ctx, http.MethodGet, fmt.Sprintf("/api/keys/%d/stats", keyID), query, nil, &stats); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// TransitionKeys moves keys of a group to another lifecycle status: "pending", "active",
+// "retiring" or "archived".
+func (c *Client) TransitionKeys(ctx context.Context, groupID uint, keyIDs []uint, to string) (*keypool.LifecycleResult, error) {
+	var result keypool.LifecycleResult
+	req := handler.KeyLifecycleRequest{GroupID: groupID, KeyIDs: keyIDs, To: to}
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/lifecycle", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TagKeys adds tags to the keys of a group given by ID or found in keysText.
+func (c *Client) TagKeys(ctx context.Context, req handler.KeyTagsRequest) (*services.KeyTagsResult, error) {
+	var result services.KeyTagsResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/tags/add", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UntagKeys removes tags from the keys of a group given by ID or found in keysText.
+func (c *Client) UntagKeys(ctx context.Context, req handler.KeyTagsRequest) (*services.KeyTagsResult, error) {
+	var result services.KeyTagsResult
+	if _, err := c.do(ctx, http.MethodPost, "/api/keys/tags/remove", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTaskStatus returns the status of the running or last background task.
+func (c *Client) GetTaskStatus(ctx context.Context) (*services.TaskStatus, error) {
+	var status services.TaskStatus
+	if _, err := c.do(ctx, http.MethodGet, "/api/tasks/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) startTask(ctx context.Context, path string, body any) (*services.TaskStatus, error) {
+	var status services.TaskStatus
+	if _, err := c.do(ctx, http.MethodPost, path, nil, body, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func keyText(groupID uint, keysText string) handler.KeyTextRequest {
+	return handler.KeyTextRequest{GroupID: groupID, KeysText: keysText}
+}
+
+func groupQuery(groupID uint) url.Values {
+	query := url.Values{}
+	query.Set("group_id", strconv.FormatUint(uint64(groupID), 10))
+	return query
+}
+
+func setIfNotEmpty(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
+	}
+}
+
+func setPage(query url.Values, page, pageSize int) {
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/handler"
+	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/services"
+)
+
+// LogFilter filters the request logs. Zero fields do not filter.
+type LogFilter struct {
+	GroupID         uint
+	GroupName       string
+	ParentGroupName string
+	KeyValue        string
+	Model           string
+	IsSuccess       *bool
+	IsOverflow      *bool
+	IsFlagged       *bool
+	RequestType     string
+	StatusCode      int
+	SourceIP        string
+	ErrorContains   string
+	StartTime       time.Time
+	EndTime         time.Time
+}
+
+func (f LogFilter) query() url.Values {
+	query := url.Values{}
+	if f.GroupID != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(f.GroupID), 10))
+	}
+	setIfNotEmpty(query, "group_name", f.GroupName)
+	setIfNotEmpty(query, "parent_group_name", f.ParentGroupName)
+	setIfNotEmpty(query, "key_value", f.KeyValue)
+	setIfNotEmpty(query, "model", f.Model)
+	if f.IsSuccess != nil {
+		query.Set("is_success", strconv.FormatBool(*f.IsSuccess))
+	}
+	if f.IsOverflow != nil {
+		query.Set("is_overflow", strconv.FormatBool(*f.IsOverflow))
+	}
+	if f.IsFlagged != nil {
+		query.Set("is_flagged", strconv.FormatBool(*f.IsFlagged))
+	}
+	setIfNotEmpty(query, "request_type", f.RequestType)
+	if f.StatusCode != 0 {
+		query.Set("status_code", strconv.Itoa(f.StatusCode))
+	}
+	setIfNotEmpty(query, "source_ip", f.SourceIP)
+	setIfNotEmpty(query, "error_contains", f.ErrorContains)
+	if !f.StartTime.IsZero() {
+		query.Set("start_time", f.StartTime.Format(time.RFC3339))
+	}
+	if !f.EndTime.IsZero() {
+		query.Set("end_time", f.EndTime.Format(time.RFC3339))
+	}
+	return query
+}
+
+// ListLogs lists the request logs matching filter, newest first.
+func (c *Client) ListLogs(ctx context.Context, filter LogFilter, page, pageSize int) (*Page[models.RequestLog], error) {
+	query := filter.query()
+	setPage(query, page, pageSize)
+
+	var result Page[models.RequestLog]
+	if _, err := c.do(ctx, http.MethodGet, "/api/logs", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetLogCapture returns the bodies captured for a request log in debug capture mode.
+func (c *Client) GetLogCapture(ctx context.Context, requestLogID string) (*services.DebugCapture, error) {
+	var capture services.DebugCapture
+	path := "/api/logs/" + url.PathEscape(requestLogID) + "/capture"
+	if _, err := c.do(ctx, http.MethodGet, path, nil, nil, &capture); err != nil {
+		return nil, err
+	}
+	return &capture, nil
+}
+
+// ReplayLog sends a logged request again, against the group or key of req when set, and
+// returns the logged and replayed outcomes side by side.
+func (c *Client) ReplayLog(ctx context.Context, requestLogID string, req handler.ReplayLogRequest) (*proxy.ReplayResult, error) {
+	var result proxy.ReplayResult
+	path := "/api/logs/" + url.PathEscape(requestLogID) + "/replay"
+	if _, err := c.do(ctx, http.MethodPost, path, nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportLogKeys streams the keys used by the request logs matching filter as CSV.
+// The caller closes the returned reader.
+func (c *Client) ExportLogKeys(ctx context.Context, filter LogFilter) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, http.MethodGet, "/api/logs/export", filter.query(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// ExportLogRecords streams the request logs matching filter as "ndjson" or "csv".
+// The caller closes the returned reader.
+func (c *Client) ExportLogRecords(ctx context.Context, filter LogFilter, format string) (io.ReadCloser, error) {
+	query := filter.query()
+	setIfNotEmpty(query, "format", format)
+	resp, err := c.send(ctx, http.MethodGet, "/api/logs/records/export", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// LogStreamFilter filters the live request logs of StreamLogs. Zero fields do not filter.
+type LogStreamFilter struct {
+	GroupID uint
+	// StatusCodes is a spec such as "429" or "500-599".
+	StatusCodes string
+	KeyValue    string
+	KeyHash     string
+}
+
+// LogStream receives live request logs. It is not safe for concurrent use.
+type LogStream struct {
+	body    io.ReadCloser
+	scanner *bufio.Scanner
+}
+
+// StreamLogs subscribes to the request logs written from now on.
+func (c *Client) StreamLogs(ctx context.Context, filter LogStreamFilter) (*LogStream, error) {
+	query := url.Values{}
+	if filter.GroupID != 0 {
+		query.Set("group_id", strconv.FormatUint(uint64(filter.GroupID), 10))
+	}
+	setIfNotEmpty(query, "status_code", filter.StatusCodes)
+	setIfNotEmpty(query, "key_value", filter.KeyValue)
+	setIfNotEmpty(query, "key_hash", filter.KeyHash)
+
+	resp, err := c.send(ctx, http.MethodGet, "/api/logs/stream", query, nil)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	return &LogStream{body: resp.Body, scanner: scanner}, nil
+}
+
+// Next blocks until the next request log arrives. It returns io.EOF when the server ends
+// the stream, and the context error once the context of StreamLogs is done.
+func (s *LogStream) Next() (*models.RequestLog, error) {
+	var data strings.Builder
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimSpace(strings.TrimPrefix(line, "data:")))
+		case line == "" && data.Len() > 0:
+			var entry models.RequestLog
+			if err := json.Unmarshal([]byte(data.String()), &entry); err != nil {
+				return nil, fmt.Errorf("gpt-load: decode log event: %w", err)
+			}
+			return &entry, nil
+		}
+	}
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Close ends the subscription.
+func (s *LogStream) Close() error {
+	return s.body.Close()
+}
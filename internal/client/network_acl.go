@@ -0,0 +1,16 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"gpt-load/internal/netacl"
+)
+
+// ListNetworkRejections lists the proxy requests recently rejected by the network ACLs on the
+// instance, newest first.
+func (c *Client) ListNetworkRejections(ctx context.Context) ([]netacl.Rejection, error) {
+	var rejections []netacl.Rejection
+	_, err := c.do(ctx, http.MethodGet, "/api/network-acl/rejections", nil, nil, &rejections)
+	return rejections, err
+}
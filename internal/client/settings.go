@@ -0,0 +1,44 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"gpt-load/internal/models"
+)
+
+// GetSettings returns the system settings grouped by category.
+func (c *Client) GetSettings(ctx context.Context) ([]models.CategorizedSettings, error) {
+	var settings []models.CategorizedSettings
+	_, err := c.do(ctx, http.MethodGet, "/api/settings", nil, nil, &settings)
+	return settings, err
+}
+
+// SearchSettings lists the settings of category, or of all categories when empty, whose key,
+// name or description contains search.
+func (c *Client) SearchSettings(ctx context.Context, search, category string, page, pageSize int) (*Page[models.SystemSettingInfo], error) {
+	query := url.Values{}
+	setIfNotEmpty(query, "search", search)
+	setIfNotEmpty(query, "category", category)
+	setPage(query, page, pageSize)
+
+	var result Page[models.SystemSettingInfo]
+	if _, err := c.do(ctx, http.MethodGet, "/api/settings/search", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PreviewSettings validates an update and returns the settings it would change, without saving.
+func (c *Client) PreviewSettings(ctx context.Context, settings map[string]any) ([]models.SettingChange, error) {
+	var changes []models.SettingChange
+	_, err := c.do(ctx, http.MethodPost, "/api/settings/preview", nil, settings, &changes)
+	return changes, err
+}
+
+// UpdateSettings saves the given settings, keyed by their JSON name.
+func (c *Client) UpdateSettings(ctx context.Context, settings map[string]any) error {
+	_, err := c.do(ctx, http.MethodPut, "/api/settings", nil, settings, nil)
+	return err
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/handler"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+)
+
+// HealthStatus is the answer of the health check.
+type HealthStatus struct {
+	// Status is "healthy", or "draining" while the instance shuts down.
+	Status    string `json:"status"`
+	Database  string `json:"database"`
+	Timestamp string `json:"timestamp"`
+	Uptime    string `json:"uptime"`
+}
+
+// Health returns the health of the instance. A draining instance answers with a 503,
+// which is reported through the status instead of an error.
+func (c *Client) Health(ctx context.Context) (*HealthStatus, error) {
+	resp, err := c.sendOnce(ctx, http.MethodGet, "/health", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return nil, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	var status HealthStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("gpt-load: decode health response: %w", err)
+	}
+	return &status, nil
+}
+
+// Login checks the auth key of the client. It returns false when the server rejects it.
+func (c *Client) Login(ctx context.Context) (bool, error) {
+	payload, err := json.Marshal(handler.LoginRequest{AuthKey: c.authKey})
+	if err != nil {
+		return false, fmt.Errorf("gpt-load: encode request body: %w", err)
+	}
+	resp, err := c.sendOnce(ctx, http.MethodPost, "/api/auth/login", nil, payload)
+	if err != nil {
+		return false, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return false, decodeError(resp)
+	}
+	defer resp.Body.Close()
+
+	var result handler.LoginResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return false, fmt.Errorf("gpt-load: decode login response: %w", err)
+	}
+	return result.Success, nil
+}
+
+// GetIntegrationInfo lists the groups a proxy key can use, with their proxy paths.
+func (c *Client) GetIntegrationInfo(ctx context.Context, proxyKey string) ([]handler.IntegrationGroupInfo, error) {
+	query := url.Values{}
+	query.Set("key", proxyKey)
+	var groups []handler.IntegrationGroupInfo
+	_, err := c.do(ctx, http.MethodGet, "/api/integration/info", query, nil, &groups)
+	return groups, err
+}
+
+// GetMigrationStatus returns the progress of the last migrate-keys run that shares the store
+// of the instance.
+func (c *Client) GetMigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	var status models.MigrationStatus
+	if _, err := c.do(ctx, http.MethodGet, "/api/migrations/status", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetKeyRotationStatus returns the progress of the online encryption key rotation.
+func (c *Client) GetKeyRotationStatus(ctx context.Context) (*services.KeyRotationStatus, error) {
+	var status services.KeyRotationStatus
+	if _, err := c.do(ctx, http.MethodGet, "/api/encryption/rotation", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// FinishKeyRotation re-encrypts what is left of the online encryption key rotation and drops
+// the secondary key on the instance that handles the request.
+func (c *Client) FinishKeyRotation(ctx context.Context) (*services.KeyRotationStatus, error) {
+	var status services.KeyRotationStatus
+	if _, err := c.do(ctx, http.MethodPost, "/api/encryption/rotation/finish", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// GetChannelTypes lists the channel types groups can use.
+func (c *Client) GetChannelTypes(ctx context.Context) ([]string, error) {
+	var types []string
+	_, err := c.do(ctx, http.MethodGet, "/api/channel-types", nil, nil, &types)
+	return types, err
+}
+
+// CustomChannelDryRun is the result of DryRunCustomChannel.
+type CustomChannelDryRun struct {
+	Definition *channel.CustomDefinition   `json:"definition"`
+	Result     *channel.CustomDryRunResult `json:"result"`
+}
+
+// DryRunCustomChannel validates a custom channel definition and shows how it shapes a sample
+// exchange. Nothing is sent upstream.
+func (c *Client) DryRunCustomChannel(ctx context.Context, req handler.CustomChannelDryRunRequest) (*CustomChannelDryRun, error) {
+	var result CustomChannelDryRun
+	if _, err := c.do(ctx, http.MethodPost, "/api/channel-types/custom/dry-run", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
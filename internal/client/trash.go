@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"gpt-load/internal/handler"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/services"
+)
+
+// ListTrashedGroups lists the deleted groups that can still be restored.
+func (c *Client) ListTrashedGroups(ctx context.Context) ([]services.TrashedGroup, error) {
+	var groups []services.TrashedGroup
+	_, err := c.do(ctx, http.MethodGet, "/api/trash/groups", nil, nil, &groups)
+	return groups, err
+}
+
+// ListTrashedKeys lists the deleted keys of a group, decrypted.
+func (c *Client) ListTrashedKeys(ctx context.Context, groupID uint, page, pageSize int) (*Page[services.TrashedKey], error) {
+	query := groupQuery(groupID)
+	setPage(query, page, pageSize)
+
+	var result Page[services.TrashedKey]
+	if _, err := c.do(ctx, http.MethodGet, "/api/trash/keys", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RestoreTrashedGroup takes a group and the keys deleted with it out of the trash.
+func (c *Client) RestoreTrashedGroup(ctx context.Context, id uint) (*keypool.TrashRestoreResult, error) {
+	var result keypool.TrashRestoreResult
+	if _, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/api/trash/groups/%d/restore", id), nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PurgeTrashedGroup permanently deletes a group in the trash and its keys.
+func (c *Client) PurgeTrashedGroup(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, http.MethodDelete, fmt.Sprintf("/api/trash/groups/%d", id), nil, nil, nil)
+	return err
+}
+
+// RestoreTrashedKeys takes keys of a group out of the trash.
+func (c *Client) RestoreTrashedKeys(ctx context.Context, groupID uint, keyIDs []uint) (*keypool.TrashRestoreResult, error) {
+	var result keypool.TrashRestoreResult
+	req := handler.TrashKeysRequest{GroupID: groupID, KeyIDs: keyIDs}
+	if _, err := c.do(ctx, http.MethodPost, "/api/trash/keys/restore", nil, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PurgeTrashedKeys permanently deletes keys of a group that are in the trash. It returns the
+// message of the server, which reports the number of keys deleted.
+func (c *Client) PurgeTrashedKeys(ctx context.Context, groupID uint, keyIDs []uint) (string, error) {
+	return c.do(ctx, http.MethodPost, "/api/trash/keys/purge", nil, handler.TrashKeysRequest{GroupID: groupID, KeyIDs: keyIDs}, nil)
+}
@@ -0,0 +1,358 @@
+// Package cluster elects the instance that runs the scheduled background jobs when
+// several gpt-load instances share one store and database.
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/version"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	leaderKey       = "cluster:leader"
+	memberKeyPrefix = "cluster:member:"
+	// memberChannel carries the member heartbeats when the store is not shared.
+	memberChannel = "cluster:members"
+
+	// leaseTTL is how long the leader lease and member heartbeats stay valid without renewal.
+	// The leader renews well within the TTL, so a lease only expires when its holder is gone.
+	leaseTTL      = 15 * time.Second
+	renewInterval = 5 * time.Second
+)
+
+// Member describes a live instance of the cluster.
+type Member struct {
+	ID        string    `json:"id"`
+	Hostname  string    `json:"hostname"`
+	Version   string    `json:"version"`
+	IsMaster  bool      `json:"is_master"`
+	IsLeader  bool      `json:"is_leader"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Status reports the cluster as seen by this instance.
+type Status struct {
+	Enabled    bool     `json:"enabled"`
+	InstanceID string   `json:"instance_id"`
+	LeaderID   string   `json:"leader_id,omitempty"`
+	IsLeader   bool     `json:"is_leader"`
+	Members    []Member `json:"members"`
+}
+
+// Elector holds a lease in the store so that exactly one master instance runs the scheduled
+// background jobs. When the leader stops renewing its lease, another master takes over once
+// the lease expires. Outside cluster mode the master is always the leader.
+//
+// Without Redis, the instances sharing a PostgreSQL database elect their leader with an
+// advisory lock instead, and exchange their heartbeats over the store pub/sub, which then
+// relays through PostgreSQL, as each instance keeps its own in-memory store.
+type Elector struct {
+	store     store.Store
+	enabled   bool
+	isMaster  bool
+	id        string
+	hostname  string
+	startedAt time.Time
+	lease     *postgresLease
+	members   store.Subscription
+
+	leader   atomic.Bool
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewElector creates a new Elector.
+func NewElector(sharedStore store.Store, configManager types.ConfigManager) *Elector {
+	hostname, _ := os.Hostname()
+	enabled := configManager.GetEffectiveServerConfig().ClusterMode
+	var lease *postgresLease
+	if enabled && configManager.GetRedisDSN() == "" {
+		if store.UsesPostgresPubSub(configManager) {
+			lease = &postgresLease{dsn: configManager.GetDatabaseConfig().DSN}
+		} else {
+			logrus.Warn("CLUSTER_MODE requires Redis or PostgreSQL shared by all instances, running as a single instance.")
+			enabled = false
+		}
+	}
+
+	e := &Elector{
+		store:     sharedStore,
+		enabled:   enabled,
+		isMaster:  configManager.IsMaster(),
+		id:        fmt.Sprintf("%s-%s", hostname, uuid.NewString()[:8]),
+		hostname:  hostname,
+		startedAt: time.Now(),
+		lease:     lease,
+		stopChan:  make(chan struct{}),
+	}
+	if !enabled {
+		e.leader.Store(e.isMaster)
+	}
+	return e
+}
+
+// Enabled reports whether cluster mode is on.
+func (e *Elector) Enabled() bool {
+	return e.enabled
+}
+
+// IsLeader reports whether this instance should run the scheduled background jobs.
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// ID returns the identifier of this instance in the cluster.
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// Start campaigns for the lease once, so that IsLeader is settled before the services
+// start, and then keeps renewing it and the member heartbeat in the background.
+func (e *Elector) Start() {
+	if !e.enabled {
+		return
+	}
+
+	if e.lease != nil {
+		members, err := e.store.Subscribe(memberChannel)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to subscribe to cluster member heartbeats")
+		} else {
+			e.members = members
+			e.wg.Add(1)
+			go e.receiveHeartbeats()
+		}
+	}
+
+	e.tick()
+	logrus.Infof("Cluster mode: instance %s joined (leader: %t)", e.id, e.IsLeader())
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(renewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				e.tick()
+			case <-e.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops campaigning and hands the lease over, so that another master takes it right away.
+func (e *Elector) Stop(ctx context.Context) {
+	if !e.enabled {
+		return
+	}
+	close(e.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logrus.Warn("Cluster elector stop timed out.")
+		return
+	}
+
+	if e.members != nil {
+		e.members.Close()
+	}
+	if e.lease != nil {
+		e.leader.Store(false)
+		e.lease.release()
+	}
+	if e.leader.Swap(false) {
+		// Only the lease of this instance is released, never one another master took over
+		if _, err := e.store.CompareAndDelete(leaderKey, []byte(e.id)); err != nil {
+			logrus.WithError(err).Warn("Failed to release the cluster leader lease")
+		}
+	}
+	if err := e.store.Delete(memberKeyPrefix + e.id); err != nil {
+		logrus.WithError(err).Warn("Failed to remove the cluster member heartbeat")
+	}
+	logrus.Info("Cluster elector stopped gracefully.")
+}
+
+func (e *Elector) tick() {
+	if e.isMaster {
+		e.campaign()
+	}
+	e.heartbeat()
+}
+
+// campaign acquires the lease when it is free and renews it while this instance holds it.
+func (e *Elector) campaign() {
+	if e.lease != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), renewInterval)
+		defer cancel()
+		held, err := e.lease.acquire(ctx)
+		e.setLeader(held, err)
+		return
+	}
+
+	acquired, err := e.store.SetNX(leaderKey, []byte(e.id), leaseTTL)
+	if err != nil {
+		e.setLeader(false, err)
+		return
+	}
+	if acquired {
+		e.setLeader(true, nil)
+		return
+	}
+
+	// The lease is renewed only while it still names this instance, so that a master whose lease
+	// expired and was taken over never extends the lease of the new leader
+	renewed, err := e.store.CompareAndExpire(leaderKey, []byte(e.id), leaseTTL)
+	e.setLeader(renewed, err)
+}
+
+func (e *Elector) setLeader(leader bool, err error) {
+	if err != nil {
+		logrus.WithError(err).Warn("Cluster leader election failed, standing down")
+	}
+	if e.leader.Swap(leader) != leader {
+		if leader {
+			logrus.Infof("Cluster mode: instance %s is now the leader and runs the background jobs", e.id)
+		} else {
+			logrus.Infof("Cluster mode: instance %s is no longer the leader", e.id)
+		}
+	}
+}
+
+func (e *Elector) heartbeat() {
+	member := Member{
+		ID:        e.id,
+		Hostname:  e.hostname,
+		Version:   version.Version,
+		IsMaster:  e.isMaster,
+		IsLeader:  e.IsLeader(),
+		StartedAt: e.startedAt,
+		LastSeen:  time.Now(),
+	}
+	data, err := json.Marshal(member)
+	if err != nil {
+		return
+	}
+	if e.lease != nil {
+		if err := e.store.Publish(memberChannel, data); err != nil {
+			logrus.WithError(err).Warn("Failed to publish the cluster member heartbeat")
+		}
+		return
+	}
+	if err := e.store.Set(memberKeyPrefix+e.id, data, leaseTTL); err != nil {
+		logrus.WithError(err).Warn("Failed to write the cluster member heartbeat")
+	}
+}
+
+// receiveHeartbeats records the heartbeats of all instances, this one included, in the local
+// store, so that the members and the leader are listed as with a shared store.
+func (e *Elector) receiveHeartbeats() {
+	defer e.wg.Done()
+	for {
+		select {
+		case msg, ok := <-e.members.Channel():
+			if !ok {
+				return
+			}
+			var member Member
+			if err := json.Unmarshal(msg.Payload, &member); err != nil {
+				logrus.Warnf("Invalid cluster member heartbeat: %v", err)
+				continue
+			}
+			e.store.Set(memberKeyPrefix+member.ID, msg.Payload, leaseTTL)
+			if member.IsLeader {
+				e.store.Set(leaderKey, []byte(member.ID), leaseTTL)
+			} else if holder, err := e.store.Get(leaderKey); err == nil && string(holder) == member.ID {
+				e.store.Delete(leaderKey)
+			}
+		case <-e.stopChan:
+			return
+		}
+	}
+}
+
+// Status returns the current leader and the live members of the cluster.
+func (e *Elector) Status() (Status, error) {
+	status := Status{
+		Enabled:    e.enabled,
+		InstanceID: e.id,
+		IsLeader:   e.IsLeader(),
+		Members:    []Member{},
+	}
+	if !e.enabled {
+		return status, nil
+	}
+
+	if holder, err := e.store.Get(leaderKey); err == nil {
+		status.LeaderID = string(holder)
+	} else if !errors.Is(err, store.ErrNotFound) {
+		return status, fmt.Errorf("failed to read the cluster leader: %w", err)
+	}
+
+	keys, err := e.store.Keys(memberKeyPrefix)
+	if err != nil {
+		return status, fmt.Errorf("failed to list cluster members: %w", err)
+	}
+	for _, key := range keys {
+		data, err := e.store.Get(key)
+		if err != nil {
+			continue
+		}
+		var member Member
+		if err := json.Unmarshal(data, &member); err != nil {
+			logrus.Warnf("Invalid cluster member heartbeat %s: %v", strings.TrimPrefix(key, memberKeyPrefix), err)
+			continue
+		}
+		member.IsLeader = member.ID == status.LeaderID
+		status.Members = append(status.Members, member)
+	}
+	sort.Slice(status.Members, func(i, j int) bool {
+		return status.Members[i].StartedAt.Before(status.Members[j].StartedAt)
+	})
+
+	return status, nil
+}
+
+// HasOtherMembers reports whether other instances are alive, in which case the shared
+// store must not be reset by this one.
+func (e *Elector) HasOtherMembers() bool {
+	// Without Redis every instance has its own store to load
+	if !e.enabled || e.lease != nil {
+		return false
+	}
+	keys, err := e.store.Keys(memberKeyPrefix)
+	if err != nil {
+		return true
+	}
+	for _, key := range keys {
+		if key != memberKeyPrefix+e.id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+func newTestElector(s store.Store, id string) *Elector {
+	return &Elector{
+		store:     s,
+		enabled:   true,
+		isMaster:  true,
+		id:        id,
+		startedAt: time.Now(),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+func TestElectorFailover(t *testing.T) {
+	s := store.NewMemoryStore(0)
+	first := newTestElector(s, "first")
+	second := newTestElector(s, "second")
+
+	first.tick()
+	second.tick()
+	if !first.IsLeader() || second.IsLeader() {
+		t.Fatalf("leaders after first campaign: first = %t, second = %t", first.IsLeader(), second.IsLeader())
+	}
+	if !second.HasOtherMembers() {
+		t.Error("HasOtherMembers() should see the first instance")
+	}
+
+	status, err := second.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if status.LeaderID != "first" || len(status.Members) != 2 {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	first.tick()
+	if !first.IsLeader() {
+		t.Fatal("the leader should keep its lease on renewal")
+	}
+
+	first.Stop(context.Background())
+	second.tick()
+	if !second.IsLeader() {
+		t.Error("the second instance should take over the released lease")
+	}
+}
+
+func TestElectorDoesNotRenewTakenOverLease(t *testing.T) {
+	s := store.NewMemoryStore(0)
+	stale := newTestElector(s, "stale")
+	stale.tick()
+	if !stale.IsLeader() {
+		t.Fatal("the first instance should win the free lease")
+	}
+
+	// The lease expired and another master took it over before the stale leader renewed it
+	if err := s.Set(leaderKey, []byte("current"), leaseTTL); err != nil {
+		t.Fatal(err)
+	}
+	stale.tick()
+	if stale.IsLeader() {
+		t.Error("the stale leader should stand down")
+	}
+
+	stale.Stop(context.Background())
+	if holder, err := s.Get(leaderKey); err != nil || string(holder) != "current" {
+		t.Errorf("Stop() should keep the lease of the new leader, got %q, %v", holder, err)
+	}
+}
@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// leaderLockID is the PostgreSQL advisory lock held by the leader.
+const leaderLockID int64 = 0x67707430_6c656164
+
+// postgresLease is the leader lease of a cluster without Redis: a session-level advisory lock,
+// released by PostgreSQL as soon as the session of its holder ends.
+type postgresLease struct {
+	dsn  string
+	conn *pgx.Conn
+	held bool
+}
+
+// acquire takes the lock when it is free and checks that the session holding it is still
+// alive, reporting whether this instance holds it.
+func (l *postgresLease) acquire(ctx context.Context) (bool, error) {
+	if l.conn == nil || l.conn.IsClosed() {
+		conn, err := pgx.Connect(ctx, l.dsn)
+		if err != nil {
+			l.held = false
+			return false, fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		l.conn, l.held = conn, false
+	}
+
+	if l.held {
+		if err := l.conn.Ping(ctx); err != nil {
+			l.close()
+			return false, fmt.Errorf("lost the leader lock session: %w", err)
+		}
+		return true, nil
+	}
+
+	if err := l.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockID).Scan(&l.held); err != nil {
+		l.close()
+		return false, fmt.Errorf("failed to take the leader lock: %w", err)
+	}
+	return l.held, nil
+}
+
+// release gives the lock up by ending the session.
+func (l *postgresLease) release() {
+	if l.conn != nil && l.held {
+		l.conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", leaderLockID)
+	}
+	l.close()
+}
+
+func (l *postgresLease) close() {
+	if l.conn != nil {
+		l.conn.Close(context.Background())
+	}
+	l.conn, l.held = nil, false
+}
@@ -0,0 +1,361 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/container"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RunGroup handles the group command entry point
+func RunGroup(args []string) {
+	if len(args) == 0 || args[0] != "list" {
+		printGroupUsage()
+		os.Exit(0)
+	}
+
+	listCmd := flag.NewFlagSet("group list", flag.ExitOnError)
+	asJSON := listCmd.Bool("json", false, "Print the groups as JSON")
+	listCmd.Usage = func() {
+		printGroupUsage()
+		fmt.Println()
+		fmt.Println("Arguments:")
+		listCmd.PrintDefaults()
+	}
+	if err := listCmd.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+
+	withAdminServices(func(db *gorm.DB) {
+		summaries, err := listGroups(db)
+		if err != nil {
+			logrus.Fatalf("Failed to list groups: %v", err)
+		}
+
+		if *asJSON {
+			printJSON(summaries)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tTYPE\tCHANNEL\tENABLED\tACTIVE\tINVALID\tTOTAL")
+		for _, g := range summaries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%d\t%d\t%d\n", g.ID, g.Name, g.GroupType, g.ChannelType, g.Enabled, g.ActiveKeys, g.InvalidKeys, g.TotalKeys)
+		}
+		w.Flush()
+	})
+}
+
+func printGroupUsage() {
+	fmt.Println("GPT-Load Group Tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load group list")
+	fmt.Println("  gpt-load group list --json")
+}
+
+// groupSummary is a group with its key counts, as printed by group list.
+type groupSummary struct {
+	ID          uint   `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
+	GroupType   string `json:"group_type"`
+	ChannelType string `json:"channel_type"`
+	Enabled     bool   `json:"enabled"`
+	ActiveKeys  int64  `json:"active_keys"`
+	InvalidKeys int64  `json:"invalid_keys"`
+	TotalKeys   int64  `json:"total_keys"`
+}
+
+func listGroups(db *gorm.DB) ([]groupSummary, error) {
+	var groups []models.Group
+	if err := db.Order("sort asc, id asc").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	var counts []struct {
+		GroupID uint
+		Status  string
+		Count   int64
+	}
+	if err := db.Model(&models.APIKey{}).Select("group_id, status, count(*) as count").Group("group_id, status").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+
+	summaries := make([]groupSummary, len(groups))
+	index := make(map[uint]*groupSummary, len(groups))
+	for i, group := range groups {
+		summaries[i] = groupSummary{
+			ID:          group.ID,
+			Name:        group.Name,
+			DisplayName: group.DisplayName,
+			GroupType:   group.GroupType,
+			ChannelType: group.ChannelType,
+			Enabled:     group.Enabled,
+		}
+		index[group.ID] = &summaries[i]
+	}
+	for _, c := range counts {
+		summary, ok := index[c.GroupID]
+		if !ok {
+			continue
+		}
+		summary.TotalKeys += c.Count
+		switch c.Status {
+		case models.KeyStatusActive:
+			summary.ActiveKeys += c.Count
+		case models.KeyStatusInvalid:
+			summary.InvalidKeys += c.Count
+		}
+	}
+	return summaries, nil
+}
+
+// RunKey handles the key command entry point
+func RunKey(args []string) {
+	if len(args) == 0 || (args[0] != "import" && args[0] != "validate") {
+		printKeyUsage()
+		os.Exit(0)
+	}
+	action := args[0]
+
+	keyCmd := flag.NewFlagSet("key "+action, flag.ExitOnError)
+	groupName := keyCmd.String("group", "", "Name of the group")
+	file := new(string)
+	status := new(string)
+	tags := new(string)
+	if action == "import" {
+		file = keyCmd.String("file", "", "File with the keys, one per line or comma-separated, - for stdin")
+	} else {
+		status = keyCmd.String("status", "", "Only validate keys with this status, e.g. invalid (default: all keys)")
+		tags = keyCmd.String("tags", "", "Only validate keys carrying all of these comma-separated tags")
+	}
+	keyCmd.Usage = func() {
+		printKeyUsage()
+		fmt.Println()
+		fmt.Println("Arguments:")
+		keyCmd.PrintDefaults()
+	}
+
+	if err := keyCmd.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *groupName == "" || (action == "import" && *file == "") {
+		keyCmd.Usage()
+		os.Exit(0)
+	}
+	if *status != "" && !models.IsKeyStatus(*status) {
+		logrus.Fatalf("Invalid key status: %s", *status)
+	}
+
+	var keysText string
+	if action == "import" {
+		data, err := readInput(*file)
+		if err != nil {
+			logrus.Fatalf("Failed to read keys: %v", err)
+		}
+		keysText = string(data)
+	}
+
+	withAdminServices(func(groupManager *services.GroupManager, keyService *services.KeyService, importService *services.KeyImportService, validationService *services.KeyManualValidationService) {
+		group, err := groupManager.GetGroupByName(*groupName)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.Fatalf("Group %s not found", *groupName)
+		} else if err != nil {
+			logrus.Fatalf("Failed to load group %s: %v", *groupName, err)
+		}
+		if group.GroupType == "aggregate" {
+			logrus.Fatalf("Group %s is an aggregate group, which has no keys", group.Name)
+		}
+
+		if action == "import" {
+			keys := keyService.ParseKeysFromText(keysText)
+			if len(keys) == 0 {
+				logrus.Fatal("No valid keys found in the input")
+			}
+			result, err := importService.ImportKeys(group.ID, keys, nil)
+			if err != nil {
+				logrus.Fatalf("Failed to import keys: %v", err)
+			}
+			logrus.Infof("Imported %d keys into group %s, %d ignored as duplicates or invalid. New keys stay pending until verified.", result.AddedCount, group.Name, result.IgnoredCount)
+			return
+		}
+
+		result, err := validationService.ValidateKeys(group, *status, splitPrefixes(*tags), func(processed int) {
+			logrus.Infof("Validated %d keys...", processed)
+		})
+		if err != nil {
+			logrus.Fatalf("Failed to validate keys: %v", err)
+		}
+		logrus.Infof("Validated %d keys of group %s: %d valid, %d invalid", result.TotalKeys, group.Name, result.ValidKeys, result.InvalidKeys)
+	})
+}
+
+func printKeyUsage() {
+	fmt.Println("GPT-Load Key Tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load key import --group openai --file keys.txt")
+	fmt.Println("  gpt-load key validate --group openai --status invalid")
+	fmt.Println()
+	fmt.Println("⚠️  Important Notes:")
+	fmt.Println("  1. Imported keys are pending and verified by the running instances within 5 minutes")
+	fmt.Println("  2. Without Redis, running instances load the keys on their next restart")
+}
+
+// RunSettings handles the settings command entry point
+func RunSettings(args []string) {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "set") || (args[0] == "set" && len(args) < 2) {
+		printSettingsUsage()
+		os.Exit(0)
+	}
+
+	if args[0] == "list" {
+		withAdminServices(func(settingsManager *config.SystemSettingsManager) {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "KEY\tVALUE")
+			for _, meta := range settingsManager.SettingsMetadata() {
+				fmt.Fprintf(w, "%s\t%v\n", meta.Key, meta.Value)
+			}
+			w.Flush()
+		})
+		return
+	}
+
+	settingsMap := make(map[string]any)
+	for _, arg := range args[1:] {
+		key, raw, ok := strings.Cut(arg, "=")
+		if !ok {
+			logrus.Fatalf("Invalid argument %q, expected key=value", arg)
+		}
+		value, err := parseSettingValue(strings.TrimSpace(key), raw)
+		if err != nil {
+			logrus.Fatal(err)
+		}
+		settingsMap[strings.TrimSpace(key)] = value
+	}
+
+	withAdminServices(func(settingsManager *config.SystemSettingsManager) {
+		if err := settingsManager.UpdateSettings(settingsMap); err != nil {
+			logrus.Fatalf("Failed to update settings: %v", err)
+		}
+		logrus.Infof("Updated %d settings", len(settingsMap))
+	})
+}
+
+func printSettingsUsage() {
+	fmt.Println("GPT-Load Settings Tool")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load settings list")
+	fmt.Println("  gpt-load settings set request_timeout=300 enable_request_body_logging=true")
+	fmt.Println()
+	fmt.Println("Running instances sharing Redis or PostgreSQL reload the settings right away.")
+}
+
+// parseSettingValue converts a command line value to the type of the setting, as the
+// settings API receives it in JSON.
+func parseSettingValue(key, raw string) (any, error) {
+	t := reflect.TypeOf(types.SystemSettings{})
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] != key {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.Int:
+			value, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: expected a number, got %q", key, raw)
+			}
+			return value, nil
+		case reflect.Bool:
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: expected true or false, got %q", key, raw)
+			}
+			return value, nil
+		default:
+			if key == "proxy_keys" {
+				return strings.Join(utils.SplitAndTrim(raw, ","), ","), nil
+			}
+			return raw, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid setting key: %s", key)
+}
+
+// withAdminServices builds the container, loads the settings and groups like the server
+// does and runs fn, whose parameters are resolved from the container.
+func withAdminServices(fn any) {
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+	// Logs go to stderr so that scripts can parse the output of the list commands
+	logrus.SetOutput(os.Stderr)
+
+	if err := cont.Invoke(func(cacheStore store.Store, encryptionSvc encryption.Service, settingsManager *config.SystemSettingsManager, groupManager *services.GroupManager) {
+		if err := settingsManager.Initialize(cacheStore, encryptionSvc); err != nil {
+			logrus.Fatalf("Failed to load system settings: %v", err)
+		}
+		if err := groupManager.Initialize(); err != nil {
+			logrus.Fatalf("Failed to load groups: %v", err)
+		}
+	}); err != nil {
+		logrus.Fatalf("Failed to initialize services: %v", err)
+	}
+
+	if err := cont.Invoke(fn); err != nil {
+		logrus.Fatalf("Failed to run command: %v", err)
+	}
+
+	if err := cont.Invoke(func(cacheStore store.Store, settingsManager *config.SystemSettingsManager, groupManager *services.GroupManager) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		groupManager.Stop(ctx)
+		settingsManager.Stop(ctx)
+		cacheStore.Close()
+	}); err != nil {
+		logrus.Warnf("Failed to stop services: %v", err)
+	}
+}
+
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func printJSON(v any) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		logrus.Fatalf("Failed to encode output: %v", err)
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,248 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/archive"
+	"gpt-load/internal/container"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"os"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const archiveRestoreBatchSize = 500
+
+// RunArchive handles the archive command entry point
+func RunArchive(args []string) {
+	if len(args) == 0 || (args[0] != "inspect" && args[0] != "restore") {
+		printArchiveUsage()
+		os.Exit(0)
+	}
+	action := args[0]
+
+	archiveCmd := flag.NewFlagSet("archive "+action, flag.ExitOnError)
+	file := archiveCmd.String("file", "", "Local archive file, e.g. a file of LOG_ARCHIVE_DIR")
+	object := archiveCmd.String("object", "", "Object name in the configured archive bucket or directory")
+	kind := archiveCmd.String("kind", "", "Kind of the archive, request-logs or hourly-stats (default: taken from the name)")
+	limit := 0
+	if action == "inspect" {
+		archiveCmd.IntVar(&limit, "limit", 0, "Number of records to print")
+	}
+	archiveCmd.Usage = func() {
+		printArchiveUsage()
+		fmt.Println()
+		fmt.Println("Arguments:")
+		archiveCmd.PrintDefaults()
+	}
+
+	if err := archiveCmd.Parse(args[1:]); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if (*file == "") == (*object == "") {
+		archiveCmd.Usage()
+		os.Exit(0)
+	}
+
+	name := *file + *object
+	if *kind == "" {
+		*kind = archive.KindOf(name)
+	}
+	if *kind != archive.KindRequestLogs && *kind != archive.KindHourlyStats {
+		logrus.Fatalf("Unknown kind of archive %s, please set --kind to %s or %s", name, archive.KindRequestLogs, archive.KindHourlyStats)
+	}
+
+	// Local files are inspected without touching the database
+	var data []byte
+	if *file != "" && action == "inspect" {
+		var err error
+		if data, err = os.ReadFile(*file); err != nil {
+			logrus.Fatalf("Failed to read archive: %v", err)
+		}
+		inspectArchive(*kind, name, data, limit)
+		return
+	}
+
+	withArchiveDB(func(db *gorm.DB, configManager types.ConfigManager, encryptionSvc encryption.Service) {
+		var err error
+		if *file != "" {
+			data, err = os.ReadFile(*file)
+		} else {
+			data, err = fetchArchiveObject(db, configManager, encryptionSvc, *object)
+		}
+		if err != nil {
+			logrus.Fatalf("Failed to read archive: %v", err)
+		}
+
+		if action == "inspect" {
+			inspectArchive(*kind, name, data, limit)
+			return
+		}
+		restoreArchive(db, *kind, name, data)
+	})
+}
+
+func printArchiveUsage() {
+	fmt.Println("GPT-Load Archive Tool")
+	fmt.Println()
+	fmt.Println("Inspects or restores the request logs and hourly stats archived by the log retention action.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  gpt-load archive inspect --file request-logs-20250101T000000Z-<id>.ndjson.gz --limit 5")
+	fmt.Println("  gpt-load archive inspect --object hourly-stats-20250101T000000Z-42.ndjson.gz")
+	fmt.Println("  gpt-load archive restore --object request-logs-20250101T000000Z-<id>.ndjson.gz")
+	fmt.Println()
+	fmt.Println("Objects are read from the archive_s3_* system settings, or LOG_ARCHIVE_S3_* and LOG_ARCHIVE_DIR.")
+	fmt.Println()
+	fmt.Println("⚠️  Important Notes:")
+	fmt.Println("  1. Records already in the database are skipped, so restoring twice is harmless")
+	fmt.Println("  2. Raise the retention days first, or restored records are archived again by the next cleanup")
+}
+
+// withArchiveDB builds the container and runs fn with the database.
+func withArchiveDB(fn func(db *gorm.DB, configManager types.ConfigManager, encryptionSvc encryption.Service)) {
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	if err := cont.Invoke(fn); err != nil {
+		logrus.Fatalf("Failed to access database: %v", err)
+	}
+}
+
+// fetchArchiveObject downloads an object from the archive target used by the log cleanup.
+func fetchArchiveObject(db *gorm.DB, configManager types.ConfigManager, encryptionSvc encryption.Service, name string) ([]byte, error) {
+	var rows []models.SystemSetting
+	if err := db.Where("setting_key LIKE ?", "archive_s3_%").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load archive settings: %w", err)
+	}
+	settings := utils.DefaultSystemSettings()
+	for _, row := range rows {
+		switch row.SettingKey {
+		case "archive_s3_endpoint":
+			settings.ArchiveS3Endpoint = row.SettingValue
+		case "archive_s3_region":
+			settings.ArchiveS3Region = row.SettingValue
+		case "archive_s3_bucket":
+			settings.ArchiveS3Bucket = row.SettingValue
+		case "archive_s3_prefix":
+			settings.ArchiveS3Prefix = row.SettingValue
+		case "archive_s3_access_key":
+			settings.ArchiveS3AccessKey = row.SettingValue
+		case "archive_s3_secret_key":
+			// Stored encrypted, or in plaintext before the setting was encrypted
+			settings.ArchiveS3SecretKey = row.SettingValue
+			if secret, err := encryptionSvc.Decrypt(row.SettingValue); err == nil {
+				settings.ArchiveS3SecretKey = secret
+			}
+		}
+	}
+
+	target := archive.NewTarget(archive.SettingsConfig(settings, configManager.GetLogArchiveConfig()))
+	if target == nil {
+		return nil, fmt.Errorf("no archive bucket or LOG_ARCHIVE_DIR is configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	return target.Get(ctx, name)
+}
+
+func inspectArchive(kind, name string, data []byte, limit int) {
+	var err error
+	switch kind {
+	case archive.KindRequestLogs:
+		err = printArchive(name, data, limit, func(log *models.RequestLog) time.Time { return log.Timestamp })
+	case archive.KindHourlyStats:
+		err = printArchive(name, data, limit, func(stat *models.GroupHourlyStat) time.Time { return stat.Time })
+	}
+	if err != nil {
+		logrus.Fatalf("Failed to inspect archive: %v", err)
+	}
+}
+
+func printArchive[T any](name string, data []byte, limit int, timeOf func(*T) time.Time) error {
+	records, err := archive.Decode[T](data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Archive:  %s\n", name)
+	fmt.Printf("Size:     %d bytes\n", len(data))
+	fmt.Printf("Records:  %d\n", len(records))
+	if len(records) > 0 {
+		first, last := timeOf(&records[0]), timeOf(&records[0])
+		for i := range records {
+			t := timeOf(&records[i])
+			if t.Before(first) {
+				first = t
+			}
+			if t.After(last) {
+				last = t
+			}
+		}
+		fmt.Printf("From:     %s\n", first.Format(time.RFC3339))
+		fmt.Printf("To:       %s\n", last.Format(time.RFC3339))
+	}
+
+	for i := range min(limit, len(records)) {
+		line, err := json.Marshal(&records[i])
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(line))
+	}
+	return nil
+}
+
+func restoreArchive(db *gorm.DB, kind, name string, data []byte) {
+	var restored int64
+	var err error
+	switch kind {
+	case archive.KindRequestLogs:
+		var logs []models.RequestLog
+		if logs, err = archive.Decode[models.RequestLog](data); err == nil {
+			restored, err = insertIgnoringExisting(db, logs)
+		}
+	case archive.KindHourlyStats:
+		var stats []models.GroupHourlyStat
+		if stats, err = archive.Decode[models.GroupHourlyStat](data); err == nil {
+			// IDs are reassigned, the group and hour identify a stat
+			for i := range stats {
+				stats[i].ID = 0
+			}
+			restored, err = insertIgnoringExisting(db, stats)
+		}
+	}
+	if err != nil {
+		logrus.Fatalf("Failed to restore archive: %v", err)
+	}
+	logrus.Infof("Restored %d records from %s", restored, name)
+}
+
+func insertIgnoringExisting[T any](db *gorm.DB, records []T) (int64, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+	// The table may not exist yet when restoring into a new database
+	var model T
+	if err := db.AutoMigrate(&model); err != nil {
+		return 0, fmt.Errorf("failed to migrate table: %w", err)
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(records, archiveRestoreBatchSize)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,108 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"gpt-load/internal/channel"
+	"gpt-load/internal/container"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"os"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// RunFixtureCapture handles the fixture-capture command entry point
+func RunFixtureCapture(args []string) {
+	captureCmd := flag.NewFlagSet("fixture-capture", flag.ExitOnError)
+	logID := captureCmd.String("log-id", "", "ID of the request log to capture")
+	name := captureCmd.String("name", "", "Fixture name, used as the file name")
+	responseFile := captureCmd.String("response", "", "Optional file with the upstream response body to record")
+	dir := captureCmd.String("dir", filepath.Join("internal", "channel", "testdata", "golden"), "Golden fixture directory")
+
+	captureCmd.Usage = func() {
+		fmt.Println("GPT-Load Golden Fixture Capture Tool")
+		fmt.Println()
+		fmt.Println("Records a logged request as a sanitized golden fixture for the channel transformation tests.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load fixture-capture --log-id <request-log-id> --name chat_with_tools")
+		fmt.Println("  gpt-load fixture-capture --log-id <request-log-id> --name custom_error --response body.json")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		captureCmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("⚠️  Important Notes:")
+		fmt.Println("  1. The request body is only available when enable_request_body_logging is on")
+		fmt.Println("  2. Upstream hosts, keys and sensitive body fields are replaced, review the fixture before committing")
+		fmt.Println("  3. Expected results are recorded from the current code, run the tests to confirm them")
+	}
+
+	if err := captureCmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *logID == "" || *name == "" {
+		captureCmd.Usage()
+		os.Exit(0)
+	}
+
+	var responseBody []byte
+	if *responseFile != "" {
+		data, err := os.ReadFile(*responseFile)
+		if err != nil {
+			logrus.Fatalf("Failed to read response file: %v", err)
+		}
+		responseBody = data
+	}
+
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	if err := cont.Invoke(func(db *gorm.DB) {
+		var requestLog models.RequestLog
+		if err := db.First(&requestLog, "id = ?", *logID).Error; err != nil {
+			logrus.Fatalf("Failed to load request log %s: %v", *logID, err)
+		}
+
+		var group models.Group
+		if err := db.First(&group, requestLog.GroupID).Error; err != nil {
+			logrus.Fatalf("Failed to load group %d: %v", requestLog.GroupID, err)
+		}
+
+		fixture, err := channel.NewGoldenFixtureFromLog(*name, &requestLog, &group, responseBody)
+		if err != nil {
+			logrus.Fatalf("Failed to build fixture: %v", err)
+		}
+
+		channelDir := filepath.Join(*dir, group.ChannelType)
+		if err := os.MkdirAll(channelDir, 0755); err != nil {
+			logrus.Fatalf("Failed to create fixture directory: %v", err)
+		}
+
+		path := filepath.Join(channelDir, *name+".json")
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			logrus.Fatalf("Failed to create fixture file: %v", err)
+		}
+		defer file.Close()
+
+		if err := channel.WriteGoldenFixture(file, fixture); err != nil {
+			logrus.Fatalf("Failed to write fixture: %v", err)
+		}
+
+		logrus.Infof("Captured request log %s as %s", *logID, path)
+	}); err != nil {
+		logrus.Fatalf("Failed to access database: %v", err)
+	}
+}
@@ -3,14 +3,17 @@ package commands
 import (
 	"flag"
 	"fmt"
+	"gpt-load/internal/config"
 	"gpt-load/internal/container"
 	db "gpt-load/internal/db/migrations"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"gpt-load/internal/services"
 	"gpt-load/internal/store"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 	"os"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -22,6 +25,8 @@ func RunMigrateKeys(args []string) {
 	migrateCmd := flag.NewFlagSet("migrate-keys", flag.ExitOnError)
 	fromKey := migrateCmd.String("from", "", "Source encryption key (for decrypting existing data)")
 	toKey := migrateCmd.String("to", "", "Target encryption key (for encrypting new data)")
+	dryRun := migrateCmd.Bool("dry-run", false, "Check that every key can be re-encrypted and estimate the duration, without changing data")
+	resume := migrateCmd.Bool("resume", false, "Continue an interrupted migration from its temporary table")
 
 	// Set custom usage message
 	migrateCmd.Usage = func() {
@@ -31,6 +36,8 @@ func RunMigrateKeys(args []string) {
 		fmt.Println("  Enable encryption: gpt-load migrate-keys --to new-key")
 		fmt.Println("  Disable encryption: gpt-load migrate-keys --from old-key")
 		fmt.Println("  Change key: gpt-load migrate-keys --from old-key --to new-key")
+		fmt.Println("  Check first: gpt-load migrate-keys --from old-key --to new-key --dry-run")
+		fmt.Println("  Continue after an interruption: gpt-load migrate-keys --from old-key --to new-key --resume")
 		fmt.Println()
 		fmt.Println("Arguments:")
 		migrateCmd.PrintDefaults()
@@ -68,6 +75,8 @@ func RunMigrateKeys(args []string) {
 	// Execute migration command
 	if err := cont.Invoke(func(db *gorm.DB, configManager types.ConfigManager, cacheStore store.Store) {
 		migrateKeysCmd := NewMigrateKeysCommand(db, configManager, cacheStore, *fromKey, *toKey)
+		migrateKeysCmd.DryRun = *dryRun
+		migrateKeysCmd.Resume = *resume
 		if err := migrateKeysCmd.Execute(); err != nil {
 			logrus.Fatalf("Key migration failed: %v", err)
 		}
@@ -88,12 +97,19 @@ type MigrateKeysCommand struct {
 	cacheStore    store.Store
 	fromKey       string
 	toKey         string
+	progress      *migrationProgress
+
+	// DryRun re-encrypts the data in memory only and estimates the duration of the migration.
+	DryRun bool
+	// Resume continues from the temporary table left by an interrupted migration.
+	Resume bool
 }
 
 // NewMigrateKeysCommand creates a new migration command
 func NewMigrateKeysCommand(db *gorm.DB, configManager types.ConfigManager, cacheStore store.Store, fromKey, toKey string) *MigrateKeysCommand {
 	return &MigrateKeysCommand{
-		db:            db,
+		// Keys and groups in the trash are migrated too
+		db:            db.Unscoped().Session(&gorm.Session{}),
 		configManager: configManager,
 		cacheStore:    cacheStore,
 		fromKey:       fromKey,
@@ -102,11 +118,13 @@ func NewMigrateKeysCommand(db *gorm.DB, configManager types.ConfigManager, cache
 }
 
 // Execute performs the key migration
-func (cmd *MigrateKeysCommand) Execute() error {
-	db.HandleLegacyIndexes(cmd.db)
-	// pre. Database migration and repair
-	if err := cmd.db.AutoMigrate(&models.APIKey{}); err != nil {
-		return fmt.Errorf("database auto-migration failed: %w", err)
+func (cmd *MigrateKeysCommand) Execute() (err error) {
+	if !cmd.DryRun {
+		db.HandleLegacyIndexes(cmd.db)
+		// pre. Database migration and repair
+		if err := cmd.db.AutoMigrate(&models.APIKey{}); err != nil {
+			return fmt.Errorf("database auto-migration failed: %w", err)
+		}
 	}
 
 	// 1. Validate parameters and get scenario
@@ -115,13 +133,24 @@ func (cmd *MigrateKeysCommand) Execute() error {
 		return fmt.Errorf("parameter validation failed: %w", err)
 	}
 
-	logrus.Infof("Starting key migration, scenario: %s", scenario)
+	cmd.progress = newMigrationProgress(cmd.cacheStore, scenario, cmd.DryRun)
+	defer func() { cmd.progress.finish(err) }()
+
+	if cmd.DryRun {
+		logrus.Infof("Starting key migration dry run, scenario: %s", scenario)
+	} else {
+		logrus.Infof("Starting key migration, scenario: %s", scenario)
+	}
 
 	// 2. Pre-check - verify current keys can decrypt all data
 	if err := cmd.preCheck(); err != nil {
 		return fmt.Errorf("pre-check failed: %w", err)
 	}
 
+	if cmd.DryRun {
+		return cmd.dryRun()
+	}
+
 	// 3. Migrate data to temporary columns
 	if err := cmd.createBackupTableAndMigrate(); err != nil {
 		return fmt.Errorf("data migration failed: %w", err)
@@ -134,17 +163,32 @@ func (cmd *MigrateKeysCommand) Execute() error {
 	}
 
 	// 5. Switch columns atomically
+	cmd.progress.setPhase(models.MigrationPhaseSwitch)
 	if err := cmd.switchColumns(); err != nil {
 		logrus.Errorf("Column switch failed: %v", err)
 		return fmt.Errorf("column switch failed: %w", err)
 	}
 
-	// 6. Clear cache
+	// 6. Re-encrypt the secrets stored with the groups, the admin accounts and the settings
+	if err := cmd.migrateGroupSecrets(); err != nil {
+		logrus.Errorf("Group secret migration failed: %v", err)
+		return fmt.Errorf("group secret migration failed: %w", err)
+	}
+	if err := cmd.migrateAdminSecrets(); err != nil {
+		logrus.Errorf("Admin secret migration failed: %v", err)
+		return fmt.Errorf("admin secret migration failed: %w", err)
+	}
+	if err := cmd.migrateSettingSecrets(); err != nil {
+		logrus.Errorf("Secret setting migration failed: %v", err)
+		return fmt.Errorf("secret setting migration failed: %w", err)
+	}
+
+	// 7. Clear cache
 	if err := cmd.clearCache(); err != nil {
 		logrus.Warnf("Cache cleanup failed, recommend manual service restart: %v", err)
 	}
 
-	// 7. Clean up temporary table
+	// 8. Clean up temporary table
 	if err := cmd.dropTempTable(); err != nil {
 		logrus.Warnf("Temporary table cleanup failed, can manually drop temp_migration table: %v", err)
 	}
@@ -220,6 +264,7 @@ func (cmd *MigrateKeysCommand) preCheck() error {
 	}
 
 	logrus.Infof("Starting validation of %d keys...", totalCount)
+	cmd.progress.startPhase(models.MigrationPhasePreCheck, totalCount, 0)
 
 	// Batch verify all keys can be decrypted correctly
 	offset := 0
@@ -244,12 +289,7 @@ func (cmd *MigrateKeysCommand) preCheck() error {
 		}
 
 		offset += migrationBatchSize
-		// Ensure we don't display more than total count
-		actualVerified := offset
-		if int64(offset) > totalCount {
-			actualVerified = int(totalCount)
-		}
-		logrus.Infof("Verified %d/%d keys", actualVerified, totalCount)
+		cmd.progress.advance(len(keys))
 	}
 
 	if failedCount > 0 {
@@ -349,17 +389,29 @@ func (cmd *MigrateKeysCommand) detectIfAlreadyEncrypted() error {
 func (cmd *MigrateKeysCommand) createBackupTableAndMigrate() error {
 	logrus.Info("Starting key migration using temporary table...")
 
-	// 1. Create temporary table
-	if err := cmd.createTempTable(); err != nil {
-		return fmt.Errorf("failed to create temporary table: %w", err)
-	}
-
-	// 2. Create old and new encryption services
+	// 1. Create old and new encryption services
 	oldService, newService, err := cmd.createMigrationServices()
 	if err != nil {
 		return err
 	}
 
+	// 2. Continue from the temporary table of an interrupted run, or create it
+	var processedCount int64
+	lastID := uint(0)
+	resumed := false
+	if cmd.Resume {
+		if lastID, processedCount, resumed, err = cmd.loadCheckpoint(newService); err != nil {
+			return err
+		}
+	} else if cmd.db.Migrator().HasTable("temp_migration") {
+		logrus.Warn("Discarding the temp_migration table of an earlier run, use --resume to continue it instead")
+	}
+	if !resumed {
+		if err := cmd.createTempTable(); err != nil {
+			return fmt.Errorf("failed to create temporary table: %w", err)
+		}
+	}
+
 	// 3. Get total count to migrate
 	var totalCount int64
 	if err := cmd.db.Model(&models.APIKey{}).Count(&totalCount).Error; err != nil {
@@ -372,11 +424,9 @@ func (cmd *MigrateKeysCommand) createBackupTableAndMigrate() error {
 	}
 
 	logrus.Infof("Starting migration of %d keys...", totalCount)
+	cmd.progress.startPhase(models.MigrationPhaseMigrate, totalCount, processedCount)
 
 	// 4. Process migration in batches
-	processedCount := 0
-	lastID := uint(0)
-
 	for {
 		var keys []models.APIKey
 		// Use ID-based pagination for stable results
@@ -393,15 +443,110 @@ func (cmd *MigrateKeysCommand) createBackupTableAndMigrate() error {
 			return fmt.Errorf("failed to process batch data: %w", err)
 		}
 
-		processedCount += len(keys)
 		lastID = keys[len(keys)-1].ID
-		logrus.Infof("Processed %d/%d keys", processedCount, totalCount)
+		cmd.progress.advance(len(keys))
 	}
 
 	logrus.Info("Data migration to temporary table completed")
 	return nil
 }
 
+// loadCheckpoint returns the last key ID and the number of keys in the temporary table left
+// by an interrupted run. ok is false when there is no such table.
+func (cmd *MigrateKeysCommand) loadCheckpoint(newService encryption.Service) (lastID uint, count int64, ok bool, err error) {
+	if !cmd.db.Migrator().HasTable("temp_migration") {
+		logrus.Info("No interrupted migration found, starting from the beginning")
+		return 0, 0, false, nil
+	}
+
+	var checkpoint struct {
+		LastID *uint
+		Count  int64
+	}
+	if err := cmd.db.Table("temp_migration").Select("MAX(id) AS last_id, COUNT(*) AS count").Scan(&checkpoint).Error; err != nil {
+		return 0, 0, false, fmt.Errorf("failed to read migration checkpoint: %w", err)
+	}
+	if checkpoint.LastID == nil {
+		return 0, 0, true, nil
+	}
+
+	// The rows must have been written for the same target key
+	var sample string
+	if err := cmd.db.Table("temp_migration").Select("key_value_new").Order("id").Limit(1).Scan(&sample).Error; err != nil {
+		return 0, 0, false, fmt.Errorf("failed to read migration checkpoint: %w", err)
+	}
+	if _, err := newService.Decrypt(sample); err != nil {
+		return 0, 0, false, fmt.Errorf("the interrupted migration used a different --to key, run again without --resume: %w", err)
+	}
+
+	logrus.Infof("Resuming migration after key ID %d, %d keys already migrated", *checkpoint.LastID, checkpoint.Count)
+	return *checkpoint.LastID, checkpoint.Count, true, nil
+}
+
+// dryRun re-encrypts every key and upstream credential in memory without writing anything,
+// and estimates how long the migration takes.
+func (cmd *MigrateKeysCommand) dryRun() error {
+	oldService, newService, err := cmd.createMigrationServices()
+	if err != nil {
+		return err
+	}
+
+	var totalCount int64
+	if err := cmd.db.Model(&models.APIKey{}).Count(&totalCount).Error; err != nil {
+		return fmt.Errorf("failed to get key count: %w", err)
+	}
+	cmd.progress.startPhase(models.MigrationPhaseDryRun, totalCount, 0)
+
+	failedCount := 0
+	lastID := uint(0)
+	for {
+		var keys []models.APIKey
+		if err := cmd.db.Where("id > ?", lastID).Order("id").Limit(migrationBatchSize).Find(&keys).Error; err != nil {
+			return fmt.Errorf("failed to get key data: %w", err)
+		}
+		if len(keys) == 0 {
+			break
+		}
+
+		for _, key := range keys {
+			encrypted, _, err := reencryptKey(key, oldService, newService)
+			if err == nil {
+				_, err = newService.Decrypt(encrypted)
+			}
+			if err != nil {
+				logrus.Errorf("Key ID %d cannot be re-encrypted: %v", key.ID, err)
+				failedCount++
+			}
+		}
+
+		lastID = keys[len(keys)-1].ID
+		cmd.progress.advance(len(keys))
+	}
+	if failedCount > 0 {
+		return fmt.Errorf("found %d keys that cannot be re-encrypted", failedCount)
+	}
+
+	var groups []models.Group
+	if err := cmd.db.Select("id", "upstreams", "config", "header_rules").Find(&groups).Error; err != nil {
+		return fmt.Errorf("failed to load groups: %w", err)
+	}
+	credentialGroups := 0
+	for _, group := range groups {
+		changed, err := reencryptGroupSecrets(&group, oldService, newService)
+		if err != nil {
+			return err
+		}
+		if changed {
+			credentialGroups++
+		}
+	}
+
+	elapsed := time.Since(cmd.progress.status.StartedAt).Round(time.Second)
+	logrus.Infof("Dry run passed: %d keys and the secrets of %d groups can be re-encrypted, no data was changed", totalCount, credentialGroups)
+	logrus.Infof("Checking and re-encrypting took %s; the migration does the same work and also writes every key twice, so expect it to take longer than that", elapsed)
+	return nil
+}
+
 // createTempTable creates a temporary table for migration
 func (cmd *MigrateKeysCommand) createTempTable() error {
 	logrus.Info("Creating temporary migration table...")
@@ -524,21 +669,11 @@ func (cmd *MigrateKeysCommand) processBatchToTempTable(keys []models.APIKey, old
 	var tempRecords []TempMigration
 
 	for _, key := range keys {
-		// 1. Decrypt using old service
-		decrypted, err := oldService.Decrypt(key.KeyValue)
-		if err != nil {
-			return fmt.Errorf("key ID %d decryption failed: %w", key.ID, err)
-		}
-
-		// 2. Encrypt using new service
-		encrypted, err := newService.Encrypt(decrypted)
+		encrypted, newHash, err := reencryptKey(key, oldService, newService)
 		if err != nil {
-			return fmt.Errorf("key ID %d encryption failed: %w", key.ID, err)
+			return err
 		}
 
-		// 3. Generate new hash using new service
-		newHash := newService.Hash(decrypted)
-
 		tempRecords = append(tempRecords, TempMigration{
 			ID:          key.ID,
 			KeyValueNew: encrypted,
@@ -555,6 +690,25 @@ func (cmd *MigrateKeysCommand) processBatchToTempTable(keys []models.APIKey, old
 	})
 }
 
+// reencryptKey decrypts a key with the old service and returns its value encrypted and hashed
+// with the new service.
+func reencryptKey(key models.APIKey, oldService, newService encryption.Service) (encrypted, hash string, err error) {
+	// 1. Decrypt using old service
+	decrypted, err := oldService.Decrypt(key.KeyValue)
+	if err != nil {
+		return "", "", fmt.Errorf("key ID %d decryption failed: %w", key.ID, err)
+	}
+
+	// 2. Encrypt using new service
+	encrypted, err = newService.Encrypt(decrypted)
+	if err != nil {
+		return "", "", fmt.Errorf("key ID %d encryption failed: %w", key.ID, err)
+	}
+
+	// 3. Generate new hash using new service
+	return encrypted, newService.Hash(decrypted), nil
+}
+
 // verifyTempColumns verifies temporary table data integrity
 func (cmd *MigrateKeysCommand) verifyTempColumns() error {
 	logrus.Info("Verifying temporary table data integrity...")
@@ -678,6 +832,138 @@ func (cmd *MigrateKeysCommand) switchColumns() error {
 	})
 }
 
+// migrateGroupSecrets re-encrypts the secrets stored in the group upstreams, config and
+// header rules
+func (cmd *MigrateKeysCommand) migrateGroupSecrets() error {
+	oldService, newService, err := cmd.createMigrationServices()
+	if err != nil {
+		return err
+	}
+
+	var groups []models.Group
+	if err := cmd.db.Select("id", "upstreams", "config", "header_rules").Find(&groups).Error; err != nil {
+		return fmt.Errorf("failed to load groups: %w", err)
+	}
+
+	return cmd.db.Transaction(func(tx *gorm.DB) error {
+		migrated := 0
+		for _, group := range groups {
+			changed, err := reencryptGroupSecrets(&group, oldService, newService)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+
+			columns := map[string]any{"upstreams": group.Upstreams, "config": group.Config, "header_rules": group.HeaderRules}
+			if err := tx.Model(&models.Group{}).Where("id = ?", group.ID).UpdateColumns(columns).Error; err != nil {
+				return fmt.Errorf("failed to update the secrets of group ID %d: %w", group.ID, err)
+			}
+			migrated++
+		}
+
+		if migrated > 0 {
+			logrus.Infof("Re-encrypted the secrets of %d groups", migrated)
+		}
+		return nil
+	})
+}
+
+// migrateAdminSecrets re-encrypts the two-factor secrets of the admin accounts
+func (cmd *MigrateKeysCommand) migrateAdminSecrets() error {
+	if !cmd.db.Migrator().HasTable(&models.AdminUser{}) {
+		return nil
+	}
+	oldService, newService, err := cmd.createMigrationServices()
+	if err != nil {
+		return err
+	}
+
+	var users []models.AdminUser
+	if err := cmd.db.Select("id", "totp_secret").Where("totp_secret <> ''").Find(&users).Error; err != nil {
+		return fmt.Errorf("failed to load admin accounts: %w", err)
+	}
+
+	return cmd.db.Transaction(func(tx *gorm.DB) error {
+		migrated := 0
+		for _, user := range users {
+			secret, err := oldService.Decrypt(user.TOTPSecret)
+			if err != nil {
+				// The keys are already switched, so the account is left to reset its 2FA
+				logrus.Warnf("TOTP secret of admin user ID %d cannot be decrypted, skipping: %v", user.ID, err)
+				continue
+			}
+			encrypted, err := newService.Encrypt(secret)
+			if err != nil {
+				return fmt.Errorf("encryption failed: %w", err)
+			}
+			if err := tx.Model(&models.AdminUser{}).Where("id = ?", user.ID).UpdateColumn("totp_secret", encrypted).Error; err != nil {
+				return fmt.Errorf("failed to update the TOTP secret of admin user ID %d: %w", user.ID, err)
+			}
+			migrated++
+		}
+
+		if migrated > 0 {
+			logrus.Infof("Re-encrypted the TOTP secrets of %d admin accounts", migrated)
+		}
+		return nil
+	})
+}
+
+// migrateSettingSecrets re-encrypts the secret system settings
+func (cmd *MigrateKeysCommand) migrateSettingSecrets() error {
+	oldService, newService, err := cmd.createMigrationServices()
+	if err != nil {
+		return err
+	}
+
+	var settings []models.SystemSetting
+	if err := cmd.db.Where("setting_key IN ? AND setting_value <> ''", config.SecretSettings).Find(&settings).Error; err != nil {
+		return fmt.Errorf("failed to load secret settings: %w", err)
+	}
+
+	return cmd.db.Transaction(func(tx *gorm.DB) error {
+		for _, setting := range settings {
+			// A value the old key does not decrypt was stored in plaintext before the
+			// setting was encrypted, and is encrypted as such
+			secret, err := oldService.Decrypt(setting.SettingValue)
+			if err != nil {
+				secret = setting.SettingValue
+			}
+			encrypted, err := newService.Encrypt(secret)
+			if err != nil {
+				return fmt.Errorf("encryption failed: %w", err)
+			}
+			if err := tx.Model(&models.SystemSetting{}).Where("setting_key = ?", setting.SettingKey).UpdateColumn("setting_value", encrypted).Error; err != nil {
+				return fmt.Errorf("failed to update setting %s: %w", setting.SettingKey, err)
+			}
+		}
+
+		if len(settings) > 0 {
+			logrus.Infof("Re-encrypted %d secret settings", len(settings))
+		}
+		return nil
+	})
+}
+
+// reencryptGroupSecrets re-encrypts the secrets of a group in place, changed is false when the
+// group has none. A value the old key does not decrypt is a config secret stored in plaintext
+// before it was encrypted, and is encrypted as such.
+func reencryptGroupSecrets(group *models.Group, oldService, newService encryption.Service) (changed bool, err error) {
+	return services.TransformGroupSecrets(group, func(stored string) (string, error) {
+		decrypted, err := oldService.Decrypt(stored)
+		if err != nil {
+			decrypted = stored
+		}
+		encrypted, err := newService.Encrypt(decrypted)
+		if err != nil {
+			return "", fmt.Errorf("encryption failed: %w", err)
+		}
+		return encrypted, nil
+	})
+}
+
 // clearCache cleans cache
 func (cmd *MigrateKeysCommand) clearCache() error {
 	logrus.Info("Starting cache cleanup...")
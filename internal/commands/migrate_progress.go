@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"encoding/json"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// migrationStatusTTL keeps the status of a finished run readable for a day.
+const migrationStatusTTL = 24 * time.Hour
+
+// migrationProgress tracks a migrate-keys run, logs it and publishes it to the store so
+// that it can be polled from /api/migrations/status.
+type migrationProgress struct {
+	store      store.Store
+	status     models.MigrationStatus
+	phaseStart time.Time
+	// resumed are the keys processed by an earlier run, left out of the rate.
+	resumed int64
+}
+
+func newMigrationProgress(cacheStore store.Store, scenario string, dryRun bool) *migrationProgress {
+	now := time.Now()
+	return &migrationProgress{
+		store: cacheStore,
+		status: models.MigrationStatus{
+			Scenario:  scenario,
+			DryRun:    dryRun,
+			StartedAt: now,
+			UpdatedAt: now,
+		},
+	}
+}
+
+// startPhase begins a phase over total keys, processed of which are already done.
+func (p *migrationProgress) startPhase(phase string, total, processed int64) {
+	p.phaseStart = time.Now()
+	p.resumed = processed
+	p.status.Phase = phase
+	p.status.Total = total
+	p.status.Processed = processed
+	p.status.KeysPerSecond = 0
+	p.status.RemainingSeconds = 0
+	p.publish()
+}
+
+// setPhase moves to a phase that is not measured in keys.
+func (p *migrationProgress) setPhase(phase string) {
+	p.status.Phase = phase
+	p.status.RemainingSeconds = 0
+	p.publish()
+}
+
+// advance records n more processed keys and logs the progress of the phase.
+func (p *migrationProgress) advance(n int) {
+	p.status.Processed += int64(n)
+	if elapsed := time.Since(p.phaseStart).Seconds(); elapsed > 0 {
+		p.status.KeysPerSecond = float64(p.status.Processed-p.resumed) / elapsed
+	}
+	if p.status.KeysPerSecond > 0 {
+		p.status.RemainingSeconds = int64(float64(max(p.status.Total-p.status.Processed, 0)) / p.status.KeysPerSecond)
+	}
+	logrus.Infof("[%s] Processed %d/%d keys (%.0f keys/s, about %s left)",
+		p.status.Phase, min(p.status.Processed, p.status.Total), p.status.Total,
+		p.status.KeysPerSecond, p.remaining())
+	p.publish()
+}
+
+// remaining returns the estimated time left in the current phase.
+func (p *migrationProgress) remaining() time.Duration {
+	return time.Duration(p.status.RemainingSeconds) * time.Second
+}
+
+// finish marks the run as done, or failed with err.
+func (p *migrationProgress) finish(err error) {
+	p.status.Phase = models.MigrationPhaseDone
+	p.status.RemainingSeconds = 0
+	if err != nil {
+		p.status.Phase = models.MigrationPhaseFailed
+		p.status.Error = err.Error()
+	}
+	p.publish()
+}
+
+func (p *migrationProgress) publish() {
+	if p.store == nil {
+		return
+	}
+	p.status.UpdatedAt = time.Now()
+	data, err := json.Marshal(p.status)
+	if err != nil {
+		return
+	}
+	if err := p.store.Set(models.MigrationStatusKey, data, migrationStatusTTL); err != nil {
+		logrus.WithError(err).Debug("Failed to publish migration progress")
+	}
+}
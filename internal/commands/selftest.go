@@ -0,0 +1,106 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/selftest"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunSelftest handles the selftest command entry point
+func RunSelftest(args []string) {
+	selftestCmd := flag.NewFlagSet("selftest", flag.ExitOnError)
+	baseURL := selftestCmd.String("url", "http://localhost:3001", "Address of the gpt-load instance")
+	authKey := selftestCmd.String("auth-key", os.Getenv("AUTH_KEY"), "Admin auth key, defaults to the AUTH_KEY environment variable")
+	proxyKey := selftestCmd.String("proxy-key", "", "Proxy key for proxy checks that target an existing group")
+	suitePath := selftestCmd.String("suite", "", "JSON suite file, the built-in suite is used when empty")
+	mockListen := selftestCmd.String("mock-listen", "127.0.0.1:0", "Listen address of the mock upstream")
+	mockURL := selftestCmd.String("mock-url", "", "Address of the mock upstream as seen from the instance, defaults to the listen address")
+	noMock := selftestCmd.Bool("no-mock", false, "Skip the checks that need the mock upstream")
+	timeout := selftestCmd.Duration("timeout", 30*time.Second, "Timeout of each check")
+	format := selftestCmd.String("format", "text", "Report format: text or json")
+
+	selftestCmd.Usage = func() {
+		fmt.Println("GPT-Load Selftest Tool")
+		fmt.Println()
+		fmt.Println("Runs end-to-end checks against a running instance and exits with status 1 if any check fails.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load selftest --url https://gpt-load.example.com --auth-key <auth-key>")
+		fmt.Println("  gpt-load selftest --suite selftest.json --proxy-key <proxy-key> --format json")
+		fmt.Println("  gpt-load selftest --mock-listen 0.0.0.0:3901 --mock-url http://ci-runner:3901")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		selftestCmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("⚠️  Important Notes:")
+		fmt.Println("  1. The mock upstream runs inside this command, the instance must be able to reach it")
+		fmt.Println("  2. A temporary group named selftest-<random> is created and deleted after the run")
+		fmt.Println("  3. Use --no-mock when the instance cannot reach this host")
+	}
+
+	if err := selftestCmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *authKey == "" {
+		selftestCmd.Usage()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" {
+		logrus.Fatalf("Unknown report format: %s", *format)
+	}
+
+	suite := selftest.DefaultSuite()
+	if *suitePath != "" {
+		loaded, err := selftest.LoadSuite(*suitePath)
+		if err != nil {
+			logrus.Fatalf("Failed to load suite: %v", err)
+		}
+		suite = loaded
+	}
+
+	report, err := selftest.Run(context.Background(), suite, selftest.Options{
+		BaseURL:     *baseURL,
+		AuthKey:     *authKey,
+		ProxyKey:    *proxyKey,
+		MockListen:  *mockListen,
+		MockURL:     *mockURL,
+		DisableMock: *noMock,
+		Timeout:     *timeout,
+	})
+	if err != nil {
+		logrus.Fatalf("Selftest failed to run: %v", err)
+	}
+
+	if *format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			logrus.Fatalf("Failed to write report: %v", err)
+		}
+	} else {
+		printSelftestReport(report)
+	}
+
+	if !report.OK() {
+		os.Exit(1)
+	}
+}
+
+func printSelftestReport(report *selftest.Report) {
+	fmt.Printf("Selftest of %s\n\n", report.BaseURL)
+	for _, result := range report.Results {
+		line := fmt.Sprintf("%-4s  %s (%dms)", strings.ToUpper(result.Status), result.Name, result.DurationMs)
+		if result.Message != "" {
+			line += ": " + result.Message
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\n%d passed, %d failed, %d skipped in %dms\n", report.Passed, report.Failed, report.Skipped, report.DurationMs)
+}
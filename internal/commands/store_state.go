@@ -0,0 +1,171 @@
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gpt-load/internal/container"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// keyPoolPrefixes are rebuilt from the database by the master instance and never restored.
+var keyPoolPrefixes = []string{"group:", "key:"}
+
+// RunStoreExport handles the store-export command entry point
+func RunStoreExport(args []string) {
+	exportCmd := flag.NewFlagSet("store-export", flag.ExitOnError)
+	output := exportCmd.String("output", "", "Snapshot file to write")
+	prefixes := exportCmd.String("prefix", "", "Comma-separated key prefixes to export, e.g. budget_usage:,response_cache_generation (default: all keys)")
+
+	exportCmd.Usage = func() {
+		fmt.Println("GPT-Load Store Export Tool")
+		fmt.Println()
+		fmt.Println("Exports runtime state (key lists, counters, budgets, caches) from Redis to a file.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load store-export --output state.json")
+		fmt.Println("  gpt-load store-export --output budgets.json --prefix budget_usage:")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		exportCmd.PrintDefaults()
+	}
+
+	if err := exportCmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *output == "" {
+		exportCmd.Usage()
+		os.Exit(0)
+	}
+
+	withSnapshotter(func(snapshotter store.Snapshotter) {
+		snapshot, err := snapshotter.Snapshot(splitPrefixes(*prefixes))
+		if err != nil {
+			logrus.Fatalf("Failed to export store: %v", err)
+		}
+
+		data, err := json.MarshalIndent(snapshot, "", "  ")
+		if err != nil {
+			logrus.Fatalf("Failed to encode snapshot: %v", err)
+		}
+		if err := os.WriteFile(*output, data, 0600); err != nil {
+			logrus.Fatalf("Failed to write snapshot file: %v", err)
+		}
+
+		logrus.Infof("Exported %d keys to %s", len(snapshot.Entries), *output)
+	})
+}
+
+// RunStoreImport handles the store-import command entry point
+func RunStoreImport(args []string) {
+	importCmd := flag.NewFlagSet("store-import", flag.ExitOnError)
+	input := importCmd.String("input", "", "Snapshot file to restore")
+	prefixes := importCmd.String("prefix", "", "Comma-separated key prefixes to restore (default: all keys in the snapshot)")
+
+	importCmd.Usage = func() {
+		fmt.Println("GPT-Load Store Import Tool")
+		fmt.Println()
+		fmt.Println("Restores runtime state exported by store-export into Redis.")
+		fmt.Println()
+		fmt.Println("Usage:")
+		fmt.Println("  gpt-load store-import --input state.json")
+		fmt.Println()
+		fmt.Println("Arguments:")
+		importCmd.PrintDefaults()
+		fmt.Println()
+		fmt.Println("⚠️  Important Notes:")
+		fmt.Println("  1. Keys present in the snapshot overwrite existing keys")
+		fmt.Println("  2. Stop all instances during import")
+		fmt.Println("  3. Key pools are reloaded from the database on startup, counters and budgets are kept")
+	}
+
+	if err := importCmd.Parse(args); err != nil {
+		logrus.Fatalf("Parameter parsing failed: %v", err)
+	}
+	if *input == "" {
+		importCmd.Usage()
+		os.Exit(0)
+	}
+
+	data, err := os.ReadFile(*input)
+	if err != nil {
+		logrus.Fatalf("Failed to read snapshot file: %v", err)
+	}
+
+	var snapshot store.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		logrus.Fatalf("Failed to decode snapshot: %v", err)
+	}
+
+	filter := splitPrefixes(*prefixes)
+	entries := snapshot.Entries[:0]
+	for _, entry := range snapshot.Entries {
+		if hasAnyPrefix(entry.Key, keyPoolPrefixes) {
+			continue
+		}
+		if len(filter) > 0 && !hasAnyPrefix(entry.Key, filter) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	snapshot.Entries = entries
+
+	withSnapshotter(func(snapshotter store.Snapshotter) {
+		if err := snapshotter.Restore(&snapshot); err != nil {
+			logrus.Fatalf("Failed to import store: %v", err)
+		}
+		logrus.Infof("Imported %d keys from %s (snapshot taken at %s)", len(snapshot.Entries), *input, snapshot.CreatedAt.Format("2006-01-02 15:04:05"))
+	})
+}
+
+// withSnapshotter builds the container and runs fn with the configured store.
+// The in-memory store only lives inside the server process, so Redis is required.
+func withSnapshotter(fn func(snapshotter store.Snapshotter)) {
+	cont, err := container.BuildContainer()
+	if err != nil {
+		logrus.Fatalf("Failed to build container: %v", err)
+	}
+
+	if err := cont.Invoke(func(configManager types.ConfigManager) {
+		utils.SetupLogger(configManager)
+	}); err != nil {
+		logrus.Fatalf("Failed to setup logger: %v", err)
+	}
+
+	if err := cont.Invoke(func(cacheStore store.Store) {
+		defer cacheStore.Close()
+
+		snapshotter, ok := cacheStore.(store.Snapshotter)
+		if !ok {
+			logrus.Fatal("Store snapshots require Redis, please configure REDIS_DSN")
+		}
+		fn(snapshotter)
+	}); err != nil {
+		logrus.Fatalf("Failed to access store: %v", err)
+	}
+}
+
+func splitPrefixes(value string) []string {
+	var prefixes []string
+	for _, prefix := range strings.Split(value, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
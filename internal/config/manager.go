@@ -6,7 +6,9 @@ import (
 	"os"
 	"strings"
 
+	"gpt-load/internal/db"
 	"gpt-load/internal/errors"
+	"gpt-load/internal/netacl"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
@@ -47,9 +49,15 @@ type Config struct {
 	CORS          types.CORSConfig
 	Performance   types.PerformanceConfig
 	Log           types.LogConfig
+	LogArchive    types.LogArchiveConfig
+	LogWriter     types.RequestLogWriterConfig
 	Database      types.DatabaseConfig
 	RedisDSN      string
 	EncryptionKey string
+	// EncryptionKeySecondary is the previous key while rotating to EncryptionKey online.
+	EncryptionKeySecondary string
+	// GroupTemplatesFile is a JSON file of group templates extending the built-in ones.
+	GroupTemplatesFile string
 }
 
 // NewManager creates a new configuration manager
@@ -74,13 +82,34 @@ func (m *Manager) ReloadConfig() error {
 			IsMaster:                !utils.ParseBoolean(os.Getenv("IS_SLAVE"), false),
 			Port:                    utils.ParseInteger(os.Getenv("PORT"), 3001),
 			Host:                    utils.GetEnvOrDefault("HOST", "0.0.0.0"),
+			GRPCPort:                utils.ParseInteger(os.Getenv("GRPC_PORT"), 0),
 			ReadTimeout:             utils.ParseInteger(os.Getenv("SERVER_READ_TIMEOUT"), 60),
 			WriteTimeout:            utils.ParseInteger(os.Getenv("SERVER_WRITE_TIMEOUT"), 600),
 			IdleTimeout:             utils.ParseInteger(os.Getenv("SERVER_IDLE_TIMEOUT"), 120),
 			GracefulShutdownTimeout: utils.ParseInteger(os.Getenv("SERVER_GRACEFUL_SHUTDOWN_TIMEOUT"), 10),
+			DrainDelay:              utils.ParseInteger(os.Getenv("SERVER_DRAIN_DELAY"), 0),
+			ClusterMode:             utils.ParseBoolean(os.Getenv("CLUSTER_MODE"), false),
+			ReadyRequiresKeys:       utils.ParseBoolean(os.Getenv("READINESS_REQUIRE_ACTIVE_KEYS"), false),
+			TrustedProxies:          utils.ParseArray(os.Getenv("TRUSTED_PROXIES"), []string{"0.0.0.0/0", "::/0"}),
+			TrustedProxyHeaders:     utils.ParseArray(os.Getenv("TRUSTED_PROXY_HEADERS"), []string{"X-Forwarded-For", "X-Real-IP"}),
 		},
 		Auth: types.AuthConfig{
-			Key: os.Getenv("AUTH_KEY"),
+			Key:             os.Getenv("AUTH_KEY"),
+			KeyLogin:        utils.ParseBoolean(os.Getenv("AUTH_KEY_LOGIN"), true),
+			SessionTTLHours: utils.ParseInteger(os.Getenv("SESSION_TTL_HOURS"), 12),
+			AdminUsername:   utils.GetEnvOrDefault("ADMIN_USERNAME", "admin"),
+			AdminPassword:   os.Getenv("ADMIN_PASSWORD"),
+			OIDC: types.OIDCConfig{
+				Issuer:              os.Getenv("OIDC_ISSUER"),
+				ClientID:            os.Getenv("OIDC_CLIENT_ID"),
+				ClientSecret:        os.Getenv("OIDC_CLIENT_SECRET"),
+				RedirectURL:         os.Getenv("OIDC_REDIRECT_URL"),
+				Scopes:              utils.ParseArray(os.Getenv("OIDC_SCOPES"), []string{"openid", "email", "profile"}),
+				AllowedEmailDomains: utils.ParseArray(os.Getenv("OIDC_ALLOWED_EMAIL_DOMAINS"), []string{}),
+				GroupsClaim:         utils.GetEnvOrDefault("OIDC_GROUPS_CLAIM", "groups"),
+				RoleMapping:         parseRoleMapping(os.Getenv("OIDC_ROLE_MAPPING")),
+				DefaultRole:         os.Getenv("OIDC_DEFAULT_ROLE"),
+			},
 		},
 		CORS: types.CORSConfig{
 			Enabled:          utils.ParseBoolean(os.Getenv("ENABLE_CORS"), false),
@@ -90,7 +119,13 @@ func (m *Manager) ReloadConfig() error {
 			AllowCredentials: utils.ParseBoolean(os.Getenv("ALLOW_CREDENTIALS"), false),
 		},
 		Performance: types.PerformanceConfig{
-			MaxConcurrentRequests: utils.ParseInteger(os.Getenv("MAX_CONCURRENT_REQUESTS"), 100),
+			MaxConcurrentRequests:  utils.ParseInteger(os.Getenv("MAX_CONCURRENT_REQUESTS"), 100),
+			KeyStatusWorkers:       utils.ParseInteger(os.Getenv("KEY_STATUS_WORKERS"), 8),
+			KeyStatusQueueSize:     utils.ParseInteger(os.Getenv("KEY_STATUS_QUEUE_SIZE"), 10000),
+			KeyStatusFlushInterval: utils.ParseInteger(os.Getenv("KEY_STATUS_FLUSH_INTERVAL"), 0),
+			KeyStatusFlushBatch:    utils.ParseInteger(os.Getenv("KEY_STATUS_FLUSH_BATCH"), 500),
+			QueueTimeout:           utils.ParseInteger(os.Getenv("QUEUE_TIMEOUT"), 0),
+			MemoryStoreMaxMB:       utils.ParseInteger(os.Getenv("MEMORY_STORE_MAX_MB"), 0),
 		},
 		Log: types.LogConfig{
 			Level:      utils.GetEnvOrDefault("LOG_LEVEL", "info"),
@@ -98,11 +133,32 @@ func (m *Manager) ReloadConfig() error {
 			EnableFile: utils.ParseBoolean(os.Getenv("LOG_ENABLE_FILE"), false),
 			FilePath:   utils.GetEnvOrDefault("LOG_FILE_PATH", "./data/logs/app.log"),
 		},
+		LogArchive: types.LogArchiveConfig{
+			Dir:         os.Getenv("LOG_ARCHIVE_DIR"),
+			S3Endpoint:  os.Getenv("LOG_ARCHIVE_S3_ENDPOINT"),
+			S3Region:    utils.GetEnvOrDefault("LOG_ARCHIVE_S3_REGION", "us-east-1"),
+			S3Bucket:    os.Getenv("LOG_ARCHIVE_S3_BUCKET"),
+			S3Prefix:    os.Getenv("LOG_ARCHIVE_S3_PREFIX"),
+			S3AccessKey: os.Getenv("LOG_ARCHIVE_S3_ACCESS_KEY"),
+			S3SecretKey: os.Getenv("LOG_ARCHIVE_S3_SECRET_KEY"),
+		},
+		LogWriter: types.RequestLogWriterConfig{
+			BufferSize:      utils.ParseInteger(os.Getenv("REQUEST_LOG_BUFFER_SIZE"), 10000),
+			BatchSize:       utils.ParseInteger(os.Getenv("REQUEST_LOG_BATCH_SIZE"), 500),
+			FlushIntervalMs: utils.ParseInteger(os.Getenv("REQUEST_LOG_FLUSH_INTERVAL_MS"), 1000),
+			Overflow:        utils.GetEnvOrDefault("REQUEST_LOG_OVERFLOW", types.RequestLogOverflowSpill),
+		},
 		Database: types.DatabaseConfig{
-			DSN: utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
+			DSN:           utils.GetEnvOrDefault("DATABASE_DSN", "./data/gpt-load.db"),
+			DegradedMode:  utils.ParseBoolean(os.Getenv("DB_DEGRADED_MODE"), true),
+			RedisFailover: utils.ParseBoolean(os.Getenv("REDIS_FAILOVER"), true),
+			StartupWait:   utils.ParseInteger(os.Getenv("STARTUP_WAIT_TIMEOUT"), 60),
+			PGPubSub:      utils.ParseBoolean(os.Getenv("POSTGRES_PUBSUB"), true),
 		},
-		RedisDSN:      os.Getenv("REDIS_DSN"),
-		EncryptionKey: os.Getenv("ENCRYPTION_KEY"),
+		RedisDSN:               os.Getenv("REDIS_DSN"),
+		EncryptionKey:          os.Getenv("ENCRYPTION_KEY"),
+		EncryptionKeySecondary: os.Getenv("ENCRYPTION_KEY_SECONDARY"),
+		GroupTemplatesFile:     os.Getenv("GROUP_TEMPLATES_FILE"),
 	}
 	m.config = config
 
@@ -139,6 +195,16 @@ func (m *Manager) GetLogConfig() types.LogConfig {
 	return m.config.Log
 }
 
+// GetLogArchiveConfig returns the request log archive configuration.
+func (m *Manager) GetLogArchiveConfig() types.LogArchiveConfig {
+	return m.config.LogArchive
+}
+
+// GetRequestLogWriterConfig returns the request log writer configuration.
+func (m *Manager) GetRequestLogWriterConfig() types.RequestLogWriterConfig {
+	return m.config.LogWriter
+}
+
 // GetRedisDSN returns the Redis DSN string.
 func (m *Manager) GetRedisDSN() string {
 	return m.config.RedisDSN
@@ -154,6 +220,16 @@ func (m *Manager) GetEncryptionKey() string {
 	return m.config.EncryptionKey
 }
 
+// GetEncryptionKeySecondary returns the previous encryption key during an online rotation.
+func (m *Manager) GetEncryptionKeySecondary() string {
+	return m.config.EncryptionKeySecondary
+}
+
+// GetGroupTemplatesFile returns the path of the custom group templates file.
+func (m *Manager) GetGroupTemplatesFile() string {
+	return m.config.GroupTemplatesFile
+}
+
 // GetEffectiveServerConfig returns server configuration merged with system settings
 func (m *Manager) GetEffectiveServerConfig() types.ServerConfig {
 	return m.config.Server
@@ -167,17 +243,88 @@ func (m *Manager) Validate() error {
 	if m.config.Server.Port < DefaultConstants.MinPort || m.config.Server.Port > DefaultConstants.MaxPort {
 		validationErrors = append(validationErrors, fmt.Sprintf("port must be between %d-%d", DefaultConstants.MinPort, DefaultConstants.MaxPort))
 	}
+	if grpcPort := m.config.Server.GRPCPort; grpcPort != 0 {
+		if grpcPort < DefaultConstants.MinPort || grpcPort > DefaultConstants.MaxPort {
+			validationErrors = append(validationErrors, fmt.Sprintf("GRPC_PORT must be between %d-%d", DefaultConstants.MinPort, DefaultConstants.MaxPort))
+		} else if grpcPort == m.config.Server.Port {
+			validationErrors = append(validationErrors, "GRPC_PORT must differ from PORT")
+		}
+	}
 
 	if m.config.Performance.MaxConcurrentRequests < 1 {
 		validationErrors = append(validationErrors, "max concurrent requests cannot be less than 1")
 	}
 
+	if m.config.Performance.KeyStatusWorkers < 1 {
+		validationErrors = append(validationErrors, "key status workers cannot be less than 1")
+	}
+
+	if m.config.Performance.KeyStatusQueueSize < 1 {
+		validationErrors = append(validationErrors, "key status queue size cannot be less than 1")
+	}
+
+	if m.config.Performance.KeyStatusFlushInterval < 0 {
+		validationErrors = append(validationErrors, "key status flush interval cannot be negative")
+	}
+
+	if m.config.Performance.KeyStatusFlushBatch < 1 {
+		validationErrors = append(validationErrors, "key status flush batch cannot be less than 1")
+	}
+
+	if m.config.Performance.QueueTimeout < 0 {
+		validationErrors = append(validationErrors, "queue timeout cannot be negative")
+	}
+
+	if m.config.Performance.MemoryStoreMaxMB < 0 {
+		validationErrors = append(validationErrors, "memory store max MB cannot be negative")
+	}
+
+	if writer := m.config.LogWriter; writer.BufferSize < 1 || writer.BatchSize < 1 || writer.FlushIntervalMs < 1 {
+		validationErrors = append(validationErrors, "REQUEST_LOG_BUFFER_SIZE, REQUEST_LOG_BATCH_SIZE and REQUEST_LOG_FLUSH_INTERVAL_MS must be at least 1")
+	}
+
+	if overflow := m.config.LogWriter.Overflow; overflow != types.RequestLogOverflowDrop && overflow != types.RequestLogOverflowSpill {
+		validationErrors = append(validationErrors, fmt.Sprintf("REQUEST_LOG_OVERFLOW must be %q or %q", types.RequestLogOverflowDrop, types.RequestLogOverflowSpill))
+	}
+
+	if archive := m.config.LogArchive; archive.S3Endpoint != "" && (archive.S3Bucket == "" || archive.S3AccessKey == "" || archive.S3SecretKey == "") {
+		validationErrors = append(validationErrors, "LOG_ARCHIVE_S3_ENDPOINT requires LOG_ARCHIVE_S3_BUCKET, LOG_ARCHIVE_S3_ACCESS_KEY and LOG_ARCHIVE_S3_SECRET_KEY")
+	}
+
+	if secondary := m.config.EncryptionKeySecondary; secondary != "" {
+		if m.config.EncryptionKey == "" {
+			validationErrors = append(validationErrors, "ENCRYPTION_KEY_SECONDARY requires ENCRYPTION_KEY, use migrate-keys to disable encryption")
+		} else if secondary == m.config.EncryptionKey {
+			validationErrors = append(validationErrors, "ENCRYPTION_KEY_SECONDARY must differ from ENCRYPTION_KEY")
+		}
+	}
+
 	// Validate auth key
 	if m.config.Auth.Key == "" {
 		validationErrors = append(validationErrors, "AUTH_KEY is required and cannot be empty")
 	} else {
 		utils.ValidatePasswordStrength(m.config.Auth.Key, "AUTH_KEY")
 	}
+	if m.config.Auth.AdminPassword != "" {
+		utils.ValidatePasswordStrength(m.config.Auth.AdminPassword, "ADMIN_PASSWORD")
+	}
+	if oidc := m.config.Auth.OIDC; oidc.Enabled() {
+		if oidc.ClientID == "" || oidc.RedirectURL == "" {
+			validationErrors = append(validationErrors, "OIDC_ISSUER requires OIDC_CLIENT_ID and OIDC_REDIRECT_URL")
+		}
+		if oidc.DefaultRole != "" && !isAdminRole(oidc.DefaultRole) {
+			validationErrors = append(validationErrors, fmt.Sprintf("OIDC_DEFAULT_ROLE must be %q or %q", types.AdminRoleAdmin, types.AdminRoleViewer))
+		}
+		for group, role := range oidc.RoleMapping {
+			if !isAdminRole(role) {
+				validationErrors = append(validationErrors, fmt.Sprintf("OIDC_ROLE_MAPPING maps %q to %q, want %q or %q", group, role, types.AdminRoleAdmin, types.AdminRoleViewer))
+			}
+		}
+	}
+	if m.config.Auth.SessionTTLHours < 1 {
+		logrus.Warnf("SESSION_TTL_HOURS value %d is invalid, resetting to 12.", m.config.Auth.SessionTTLHours)
+		m.config.Auth.SessionTTLHours = 12
+	}
 
 	// Validate GracefulShutdownTimeout and reset if necessary
 	if m.config.Server.GracefulShutdownTimeout < 10 {
@@ -185,6 +332,18 @@ func (m *Manager) Validate() error {
 		m.config.Server.GracefulShutdownTimeout = 10
 	}
 
+	if m.config.Server.DrainDelay < 0 {
+		validationErrors = append(validationErrors, "drain delay cannot be negative")
+	}
+
+	if m.config.Database.StartupWait < 0 {
+		validationErrors = append(validationErrors, "STARTUP_WAIT_TIMEOUT cannot be negative")
+	}
+
+	if _, err := netacl.Parse(strings.Join(m.config.Server.TrustedProxies, ",")); err != nil {
+		validationErrors = append(validationErrors, fmt.Sprintf("TRUSTED_PROXIES is invalid: %v", err))
+	}
+
 	if m.config.CORS.Enabled {
 		if len(m.config.CORS.AllowedOrigins) == 0 {
 			validationErrors = append(validationErrors, "CORS is enabled but ALLOWED_ORIGINS is not set. UI will not work from a browser.")
@@ -210,6 +369,7 @@ func (m *Manager) DisplayServerConfig() {
 	corsConfig := m.GetCORSConfig()
 	perfConfig := m.GetPerformanceConfig()
 	logConfig := m.GetLogConfig()
+	archiveConfig := m.GetLogArchiveConfig()
 	dbConfig := m.GetDatabaseConfig()
 	redisDSN := m.GetRedisDSN()
 	encryptionKey := m.GetEncryptionKey()
@@ -218,17 +378,45 @@ func (m *Manager) DisplayServerConfig() {
 	logrus.Info("======= Server Configuration =======")
 	logrus.Info("  --- Server ---")
 	logrus.Infof("    Listen Address: %s:%d", serverConfig.Host, serverConfig.Port)
+	if serverConfig.GRPCPort > 0 {
+		logrus.Infof("    gRPC Admin API: %s:%d", serverConfig.Host, serverConfig.GRPCPort)
+	}
 	logrus.Infof("    Graceful Shutdown Timeout: %d seconds", serverConfig.GracefulShutdownTimeout)
+	if serverConfig.DrainDelay > 0 {
+		logrus.Infof("    Drain Delay: %d seconds", serverConfig.DrainDelay)
+	}
 	logrus.Infof("    Read Timeout: %d seconds", serverConfig.ReadTimeout)
 	logrus.Infof("    Write Timeout: %d seconds", serverConfig.WriteTimeout)
 	logrus.Infof("    Idle Timeout: %d seconds", serverConfig.IdleTimeout)
+	logrus.Infof("    Cluster Mode: %t", serverConfig.ClusterMode)
+	if serverConfig.ReadyRequiresKeys {
+		logrus.Info("    Readiness: requires active keys")
+	}
+	logrus.Infof("    Trusted Proxies: %s (headers: %s)", strings.Join(serverConfig.TrustedProxies, ", "), strings.Join(serverConfig.TrustedProxyHeaders, ", "))
 
 	logrus.Info("  --- Performance ---")
 	logrus.Infof("    Max Concurrent Requests: %d", perfConfig.MaxConcurrentRequests)
+	if perfConfig.QueueTimeout > 0 {
+		logrus.Infof("    Priority Queue Timeout: %d seconds", perfConfig.QueueTimeout)
+	}
+	logrus.Infof("    Key Status Workers: %d (queue size: %d)", perfConfig.KeyStatusWorkers, perfConfig.KeyStatusQueueSize)
+	if perfConfig.KeyStatusFlushInterval > 0 {
+		logrus.Infof("    Key Status Buffer: flushed every %d seconds or %d updates", perfConfig.KeyStatusFlushInterval, perfConfig.KeyStatusFlushBatch)
+	}
 
 	logrus.Info("  --- Security ---")
 	logrus.Infof("    Authentication: enabled (key loaded)")
-	if encryptionKey != "" {
+	if m.config.Auth.KeyLogin {
+		logrus.Infof("    Admin Sessions: %d hours (AUTH_KEY login enabled)", m.config.Auth.SessionTTLHours)
+	} else {
+		logrus.Infof("    Admin Sessions: %d hours (AUTH_KEY login disabled)", m.config.Auth.SessionTTLHours)
+	}
+	if oidc := m.config.Auth.OIDC; oidc.Enabled() {
+		logrus.Infof("    Single Sign-On: %s (%d role mappings)", oidc.Issuer, len(oidc.RoleMapping))
+	}
+	if encryptionKey != "" && m.GetEncryptionKeySecondary() != "" {
+		logrus.Info("    Encryption: enabled, rotating from ENCRYPTION_KEY_SECONDARY")
+	} else if encryptionKey != "" {
 		logrus.Info("    Encryption: enabled")
 	} else {
 		logrus.Warn("    Encryption: disabled - WARNING: Sensitive data may be stored unencrypted, which poses security risks including potential key exposure")
@@ -246,18 +434,50 @@ func (m *Manager) DisplayServerConfig() {
 	if logConfig.EnableFile {
 		logrus.Infof("    Log File Path: %s", logConfig.FilePath)
 	}
+	writerConfig := m.GetRequestLogWriterConfig()
+	logrus.Infof("    Request Log Writer: batches of %d every %dms (buffer: %d, overflow: %s)", writerConfig.BatchSize, writerConfig.FlushIntervalMs, writerConfig.BufferSize, writerConfig.Overflow)
+	switch {
+	case archiveConfig.S3Endpoint != "":
+		logrus.Infof("    Request Log Archive: s3 (bucket: %s)", archiveConfig.S3Bucket)
+	case archiveConfig.Dir != "":
+		logrus.Infof("    Request Log Archive: %s", archiveConfig.Dir)
+	}
 
 	logrus.Info("  --- Dependencies ---")
+	logrus.Infof("    Startup Wait Timeout: %ds", dbConfig.StartupWait)
 	if dbConfig.DSN != "" {
 		logrus.Info("    Database: configured")
+		logrus.Infof("    Degraded Mode on DB Outage: %t", dbConfig.DegradedMode)
 	} else {
 		logrus.Info("    Database: not configured")
 	}
 	if redisDSN != "" {
 		logrus.Info("    Redis: configured")
+		logrus.Infof("    In-Memory Failover on Redis Outage: %t", dbConfig.RedisFailover)
 	} else {
 		logrus.Info("    Redis: not configured")
+		if dbConfig.PGPubSub && db.IsPostgresDSN(dbConfig.DSN) {
+			logrus.Info("    Pub/Sub: PostgreSQL LISTEN/NOTIFY")
+		}
+		if perfConfig.MemoryStoreMaxMB > 0 {
+			logrus.Infof("    Memory Store Limit: %d MB", perfConfig.MemoryStoreMaxMB)
+		}
 	}
 	logrus.Info("====================================")
 	logrus.Info("")
 }
+
+// parseRoleMapping parses comma-separated group=role pairs. A pair without a role maps the
+// group to an empty role, which validation reports.
+func parseRoleMapping(value string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range utils.ParseArray(value, nil) {
+		group, role, _ := strings.Cut(pair, "=")
+		mapping[strings.TrimSpace(group)] = strings.TrimSpace(role)
+	}
+	return mapping
+}
+
+func isAdminRole(role string) bool {
+	return role == types.AdminRoleAdmin || role == types.AdminRoleViewer
+}
@@ -4,17 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/admission"
+	"gpt-load/internal/credentials"
 	"gpt-load/internal/db"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/endpoints"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/honeypot"
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/keymask"
+	"gpt-load/internal/modelpolicy"
 	"gpt-load/internal/models"
+	"gpt-load/internal/netacl"
+	"gpt-load/internal/requestid"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
+	"maps"
+	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
@@ -23,9 +38,23 @@ import (
 
 const SettingsUpdateChannel = "system_settings:updated"
 
+// SecretSettings are the settings stored encrypted. The manager holds them in plaintext and
+// the management API shows them masked.
+var SecretSettings = []string{"archive_s3_secret_key"}
+
+// SettingsListener is called with the previous and the new settings each time this instance
+// has reloaded them. The previous settings are zero on the initial load.
+type SettingsListener func(prev, next types.SystemSettings)
+
 // SystemSettingsManager 管理系统配置
 type SystemSettingsManager struct {
 	syncer *syncer.CacheSyncer[types.SystemSettings]
+
+	encryptionSvc encryption.Service
+
+	listenerMu sync.Mutex
+	listeners  []SettingsListener
+	current    types.SystemSettings
 }
 
 // NewSystemSettingsManager creates a new, uninitialized SystemSettingsManager.
@@ -39,15 +68,136 @@ func validateStringSettingValue(key, val string) error {
 			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
 		}
 	}
+	if key == "blacklist_status_thresholds" {
+		if _, err := failover.ParseStatusThresholds(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "quota_reset_time" {
+		if _, err := utils.ParseDailyReset(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "allowed_endpoints" {
+		if _, err := endpoints.Parse(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "allowed_models" || key == "denied_models" {
+		if _, err := modelpolicy.Parse(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "ip_allowlist" || key == "ip_denylist" {
+		if _, err := netacl.Parse(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "honeypot_paths" {
+		if _, err := honeypot.ParsePaths(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "archive_s3_endpoint" && val != "" {
+		if u, err := url.Parse(val); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid value for %s (%q): must be an http or https URL", key, val)
+		}
+	}
+	if key == "alert_webhook_url" && val != "" {
+		if u, err := url.Parse(val); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid value for %s (%q): must be an http or https URL", key, val)
+		}
+	}
+	if key == "proxy_url" && val != "" {
+		if err := httpclient.ValidateProxyURL(val); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	if key == "http_version" {
+		if _, err := httpclient.ParseHTTPVersion(val); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	if key == "key_validation_windows" {
+		if _, err := utils.ParseTimeWindows(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "usage_snapshot_schedule" {
+		if _, err := utils.ParseSnapshotSchedule(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "request_log_retention_action" && val != models.LogRetentionDelete && val != models.LogRetentionArchive {
+		return fmt.Errorf("invalid value for %s (%q): must be %q or %q", key, val, models.LogRetentionDelete, models.LogRetentionArchive)
+	}
+	if key == "pool_mode" && val != models.PoolModeSimple && val != models.PoolModeLayered {
+		return fmt.Errorf("invalid value for %s (%q): must be %q or %q", key, val, models.PoolModeSimple, models.PoolModeLayered)
+	}
+	if key == "key_masking" && !keymask.Valid(val) {
+		return fmt.Errorf("invalid value for %s (%q): must be one of %s, %s, %s or %s", key, val, keymask.Full, keymask.Last4, keymask.Hash, keymask.Plaintext)
+	}
+	if key == "debug_capture_until" && val != "" {
+		if _, err := time.Parse(time.RFC3339, val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): must be an RFC 3339 time", key, val)
+		}
+	}
+	if key == "log_level" && val != "" {
+		if _, err := logrus.ParseLevel(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
+	if key == "credential_refresher" {
+		if _, err := credentials.Lookup(val); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", key, err)
+		}
+	}
+	if key == "request_id_header" && val != "" && !requestid.ValidHeaderName(val) {
+		return fmt.Errorf("invalid value for %s (%q): must be an HTTP header name", key, val)
+	}
+	if key == "rate_limit_headers" {
+		for _, pattern := range strings.Split(val, ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" && !requestid.ValidHeaderName(strings.TrimSuffix(pattern, "*")) {
+				return fmt.Errorf("invalid value for %s (%q): %q is not a header name", key, val, pattern)
+			}
+		}
+	}
+	if key == "request_priority" {
+		if _, err := admission.ParsePriority(val); err != nil {
+			return fmt.Errorf("invalid value for %s (%q): %w", key, val, err)
+		}
+	}
 	return nil
 }
 
-type groupManager interface {
-	Invalidate() error
+// OnChange registers a listener that re-configures a subsystem when the settings change, so
+// that updates through the admin API apply without a restart. Listeners registered before
+// Initialize also see the initial load.
+func (sm *SystemSettingsManager) OnChange(listener SettingsListener) {
+	sm.listenerMu.Lock()
+	defer sm.listenerMu.Unlock()
+	sm.listeners = append(sm.listeners, listener)
+}
+
+// notifyListeners passes the reloaded settings to the listeners, in registration order.
+func (sm *SystemSettingsManager) notifyListeners(settings types.SystemSettings) {
+	sm.listenerMu.Lock()
+	prev := sm.current
+	sm.current = settings
+	listeners := slices.Clone(sm.listeners)
+	sm.listenerMu.Unlock()
+
+	for _, listener := range listeners {
+		listener(prev, settings)
+	}
 }
 
 // Initialize initializes the SystemSettingsManager with database and store dependencies.
-func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager, isMaster bool) error {
+// Every instance reloads the settings on change and notifies its own listeners. encryptionSvc
+// encrypts the secret settings, it is not a constructor dependency because the configuration
+// manager it is built from depends on the settings manager.
+func (sm *SystemSettingsManager) Initialize(store store.Store, encryptionSvc encryption.Service) error {
+	sm.encryptionSvc = encryptionSvc
 	settingsLoader := func() (types.SystemSettings, error) {
 		var dbSettings []models.SystemSetting
 		if err := db.DB.Find(&dbSettings).Error; err != nil {
@@ -58,6 +208,11 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 		for _, setting := range dbSettings {
 			settingsMap[setting.SettingKey] = setting.SettingValue
 		}
+		for _, key := range SecretSettings {
+			if stored, ok := settingsMap[key]; ok {
+				settingsMap[key] = sm.revealSecret(stored)
+			}
+		}
 
 		// Start with default settings, then override with values from the database.
 		settings := utils.DefaultSystemSettings()
@@ -84,25 +239,20 @@ func (sm *SystemSettingsManager) Initialize(store store.Store, gm groupManager,
 		}
 
 		settings.ProxyKeysMap = utils.StringToSet(settings.ProxyKeys, ",")
+		settings.HoneypotDecoyKeysMap = utils.StringToSet(settings.HoneypotDecoyKeys, ",")
+		settings.HoneypotPathList, _ = honeypot.ParsePaths(settings.HoneypotPaths)
 
 		sm.DisplaySystemConfig(settings)
 
 		return settings, nil
 	}
 
-	afterLoader := func(newData types.SystemSettings) {
-		if !isMaster {
-			return
-		}
-		gm.Invalidate()
-	}
-
 	syncer, err := syncer.NewCacheSyncer(
 		settingsLoader,
 		store,
 		SettingsUpdateChannel,
 		logrus.WithField("syncer", "system_settings"),
-		afterLoader,
+		sm.notifyListeners,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create system settings syncer: %w", err)
@@ -194,6 +344,10 @@ func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) erro
 	if err := sm.ValidateSettings(settingsMap); err != nil {
 		return err
 	}
+	settingsMap, err := sm.sealSecrets(settingsMap)
+	if err != nil {
+		return err
+	}
 
 	// 更新数据库
 	var settingsToUpdate []models.SystemSetting
@@ -214,7 +368,100 @@ func (sm *SystemSettingsManager) UpdateSettings(settingsMap map[string]any) erro
 	}
 
 	// 触发所有实例重新加载
-	return sm.syncer.Invalidate()
+	return sm.syncer.InvalidateFor(syncer.EventSettingsChanged, 0)
+}
+
+// PreviewSettings 校验待更新的配置，并返回与当前配置相比实际会发生变化的配置项
+func (sm *SystemSettingsManager) PreviewSettings(settingsMap map[string]any) ([]models.SettingChange, error) {
+	if err := sm.ValidateSettings(settingsMap); err != nil {
+		return nil, err
+	}
+
+	currentSettings := sm.GetSettings()
+	changes := make([]models.SettingChange, 0, len(settingsMap))
+	// Iterate the metadata rather than the map so that changes come back in display order.
+	for _, meta := range utils.GenerateSettingsMetadata(&currentSettings) {
+		value, ok := settingsMap[meta.Key]
+		if !ok || fmt.Sprintf("%v", value) == fmt.Sprintf("%v", meta.Value) {
+			continue
+		}
+		if slices.Contains(SecretSettings, meta.Key) {
+			secret := fmt.Sprintf("%v", value)
+			if encryption.IsMaskedSecret(secret) {
+				// Validated to stand for the stored secret, so it is unchanged
+				continue
+			}
+			meta.Value = encryption.MaskSecret(sm.encryptionSvc, fmt.Sprintf("%v", meta.Value))
+			value = encryption.MaskSecret(sm.encryptionSvc, secret)
+		}
+		changes = append(changes, models.SettingChange{
+			Key:             meta.Key,
+			Name:            meta.Name,
+			Category:        meta.Category,
+			OldValue:        meta.Value,
+			NewValue:        value,
+			RequiresRestart: meta.RequiresRestart,
+		})
+	}
+
+	return changes, nil
+}
+
+// SettingsMetadata returns the metadata of the current settings, with the secret settings masked.
+func (sm *SystemSettingsManager) SettingsMetadata() []models.SystemSettingInfo {
+	currentSettings := sm.GetSettings()
+	metadata := utils.GenerateSettingsMetadata(&currentSettings)
+	for i := range metadata {
+		if slices.Contains(SecretSettings, metadata[i].Key) {
+			metadata[i].Value = encryption.MaskSecret(sm.encryptionSvc, fmt.Sprintf("%v", metadata[i].Value))
+		}
+	}
+	return metadata
+}
+
+// maskedSecret returns the masked form of the current value of a secret setting.
+func (sm *SystemSettingsManager) maskedSecret(key string) string {
+	currentSettings := sm.GetSettings()
+	for _, meta := range utils.GenerateSettingsMetadata(&currentSettings) {
+		if meta.Key == key {
+			return encryption.MaskSecret(sm.encryptionSvc, fmt.Sprintf("%v", meta.Value))
+		}
+	}
+	return ""
+}
+
+// revealSecret decrypts a stored secret setting. A value that does not decrypt was stored in
+// plaintext before the setting was encrypted, and is encrypted on its next update.
+func (sm *SystemSettingsManager) revealSecret(stored string) string {
+	if plaintext, err := sm.encryptionSvc.Decrypt(stored); err == nil {
+		return plaintext
+	}
+	return stored
+}
+
+// sealSecrets returns a copy of a validated update with the secret settings encrypted. A
+// masked secret sent back stands for the stored secret, which is left as it is.
+func (sm *SystemSettingsManager) sealSecrets(settingsMap map[string]any) (map[string]any, error) {
+	sealed := maps.Clone(settingsMap)
+	for _, key := range SecretSettings {
+		value, ok := sealed[key].(string)
+		if !ok {
+			continue
+		}
+		if encryption.IsMaskedSecret(value) {
+			delete(sealed, key)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		encrypted, err := sm.encryptionSvc.Encrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encrypt %s: %w", key, err)
+		}
+		sealed[key] = encrypted
+	}
+	return sealed, nil
 }
 
 // GetEffectiveConfig 获取有效配置 (系统配置 + 分组覆盖)
@@ -299,6 +546,13 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.Bool:
 			if _, ok := value.(bool); !ok {
@@ -320,6 +574,9 @@ func (sm *SystemSettingsManager) ValidateSettings(settingsMap map[string]any) er
 			if err := validateStringSettingValue(key, strVal); err != nil {
 				return err
 			}
+			if slices.Contains(SecretSettings, key) && encryption.IsMaskedSecret(strVal) && strVal != sm.maskedSecret(key) {
+				return fmt.Errorf("the masked secret %s of %s does not match the stored secret, enter it again", strVal, key)
+			}
 		default:
 			return fmt.Errorf("unsupported type for setting key validation: %s", key)
 		}
@@ -376,6 +633,13 @@ func (sm *SystemSettingsManager) ValidateGroupConfigOverrides(configMap map[stri
 						return fmt.Errorf("value for %s (%d) is below minimum value (%d)", key, intVal, minVal)
 					}
 				}
+				if strings.HasPrefix(trimmedRule, "max=") {
+					maxValStr := strings.TrimPrefix(trimmedRule, "max=")
+					maxVal, _ := strconv.Atoi(maxValStr)
+					if intVal > maxVal {
+						return fmt.Errorf("value for %s (%d) is above maximum value (%d)", key, intVal, maxVal)
+					}
+				}
 			}
 		case reflect.String:
 			strVal, ok := value.(string)
@@ -412,8 +676,25 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 	logrus.Info("========= System Settings =========")
 	logrus.Info("  --- Basic Settings ---")
 	logrus.Infof("    App URL: %s", settings.AppUrl)
-	logrus.Infof("    Request Log Retention: %d days", settings.RequestLogRetentionDays)
+	logrus.Infof("    Request Log Retention: %d days (%s)", settings.RequestLogRetentionDays, settings.RequestLogRetentionAction)
+	if settings.HourlyStatsRetentionDays > 0 {
+		logrus.Infof("    Hourly Stats Retention: %d days", settings.HourlyStatsRetentionDays)
+	}
+	if settings.ArchiveS3Endpoint != "" {
+		logrus.Infof("    Archive Bucket: %s/%s/%s", strings.TrimRight(settings.ArchiveS3Endpoint, "/"), settings.ArchiveS3Bucket, settings.ArchiveS3Prefix)
+	}
 	logrus.Infof("    Request Log Write Interval: %d minutes", settings.RequestLogWriteIntervalMinutes)
+	logrus.Infof("    Monthly Request Budget: %d", settings.MonthlyRequestBudget)
+	if settings.UsageSnapshotSchedule != "" {
+		logrus.Infof("    Usage Snapshot Schedule: %s", settings.UsageSnapshotSchedule)
+	}
+	if settings.LogLevel != "" {
+		logrus.Infof("    Log Level: %s", settings.LogLevel)
+	}
+	logrus.Infof("    Key Masking: %s", settings.KeyMasking)
+	if settings.DebugCaptureRate > 0 {
+		logrus.Infof("    Debug Capture: %d%% (until: %s)", settings.DebugCaptureRate, settings.DebugCaptureUntil)
+	}
 
 	logrus.Info("  --- Request Behavior ---")
 	logrus.Infof("    Request Timeout: %d seconds", settings.RequestTimeout)
@@ -422,12 +703,79 @@ func (sm *SystemSettingsManager) DisplaySystemConfig(settings types.SystemSettin
 	logrus.Infof("    Idle Connection Timeout: %d seconds", settings.IdleConnTimeout)
 	logrus.Infof("    Max Idle Connections: %d", settings.MaxIdleConns)
 	logrus.Infof("    Max Idle Connections Per Host: %d", settings.MaxIdleConnsPerHost)
+	logrus.Infof("    HTTP Version: %s, TCP Keep-Alive: %d seconds, DNS Cache TTL: %d seconds", settings.HTTPVersion, settings.TCPKeepAlive, settings.DNSCacheTTL)
+	logrus.Infof("    Circuit Breaker Threshold: %d", settings.CircuitBreakerThreshold)
+	logrus.Infof("    Circuit Breaker Cooldown: %d seconds", settings.CircuitBreakerCooldownSeconds)
+	logrus.Infof("    Stream Timeouts: first byte %ds, idle %ds, total %ds (0 = disabled)", settings.StreamFirstByteTimeout, settings.StreamIdleTimeout, settings.StreamMaxDuration)
+	logrus.Infof("    Retry Stalled Streams: %t", settings.StreamRetryOnStall)
+	logrus.Infof("    Simulate Streaming: %t, Streaming Kill Switch: %t", settings.SimulateStreaming, settings.StreamingKillSwitch)
+	logrus.Infof("    Compress Responses: %t", settings.CompressResponses)
+	if settings.AllowedEndpoints != "" {
+		logrus.Infof("    Allowed Endpoints: %s", settings.AllowedEndpoints)
+	}
+	if settings.AllowedModels != "" {
+		logrus.Infof("    Allowed Models: %s", settings.AllowedModels)
+	}
+	if settings.DeniedModels != "" {
+		logrus.Infof("    Denied Models: %s", settings.DeniedModels)
+	}
+	if settings.IPAllowlist != "" {
+		logrus.Infof("    IP Allowlist: %s", settings.IPAllowlist)
+	}
+	if settings.IPDenylist != "" {
+		logrus.Infof("    IP Denylist: %s", settings.IPDenylist)
+	}
+	logrus.Infof("    Per-IP Rate Limit: proxy %d/min, auth %d/min (0 = disabled)", settings.IPRateLimitPerMinute, settings.AuthRateLimitPerMinute)
+	if settings.IPBanThreshold > 0 {
+		logrus.Infof("    IP Ban: after %d authentication failures for %d seconds", settings.IPBanThreshold, settings.IPBanDurationSeconds)
+	}
+	if len(settings.HoneypotDecoyKeysMap) > 0 || settings.HoneypotPaths != "" || settings.HoneypotBannedIPs {
+		logrus.Infof("    Honeypot: %d decoy keys, paths %q, banned IPs %t, delay %d ms", len(settings.HoneypotDecoyKeysMap), settings.HoneypotPaths, settings.HoneypotBannedIPs, settings.HoneypotDelayMs)
+	}
+	if settings.HoneypotAutoDenyHits > 0 {
+		logrus.Infof("    Honeypot Auto Deny: after %d hits in 24 hours", settings.HoneypotAutoDenyHits)
+	}
+	if settings.UpstreamHealthCheckPath != "" {
+		logrus.Infof("    Upstream Health Check: %s every %d seconds (expect %d)", settings.UpstreamHealthCheckPath, settings.UpstreamHealthCheckInterval, settings.UpstreamHealthCheckStatus)
+	} else {
+		logrus.Info("    Upstream Health Check: disabled")
+	}
+	logrus.Infof("    Response Cache TTL: %d seconds", settings.ResponseCacheTTL)
+	if settings.MaxRequestBodyKB > 0 {
+		logrus.Infof("    Max Request Body: %d KB", settings.MaxRequestBodyKB)
+	}
+	if settings.GroupMaxConcurrentRequests > 0 {
+		logrus.Infof("    Group Concurrency: %d in flight, %d queued for up to %d seconds", settings.GroupMaxConcurrentRequests, settings.GroupMaxQueuedRequests, settings.GroupQueueTimeout)
+	}
+	logrus.Infof("    Request Priority: %s", settings.RequestPriority)
+	logrus.Infof("    Rate Limit Headers: %q (aggregate: %t)", settings.RateLimitHeaders, settings.RateLimitHeadersAggregate)
+	if settings.RequestIDHeader != "" {
+		logrus.Infof("    Upstream Request ID Header: %s", settings.RequestIDHeader)
+	} else {
+		logrus.Info("    Upstream Request ID Header: disabled")
+	}
 
 	logrus.Info("  --- Key & Group Behavior ---")
 	logrus.Infof("    Max Retries: %d", settings.MaxRetries)
+	logrus.Infof("    Retry Backoff: %d-%d ms", settings.RetryBackoffBaseMs, settings.RetryBackoffMaxMs)
 	logrus.Infof("    Blacklist Threshold: %d", settings.BlacklistThreshold)
+	logrus.Infof("    Rate Limit Max Cooldown: %d seconds (per model: %t)", settings.RateLimitMaxCooldown, settings.RateLimitPerModel)
+	logrus.Infof("    Provider Status Polling: %t", settings.ProviderStatusPolling)
+	if settings.QuotaResetTime != "" {
+		logrus.Infof("    Quota Reset Time: %s", settings.QuotaResetTime)
+	}
+	if settings.BlacklistStatusThresholds != "" {
+		logrus.Infof("    Blacklist Status Thresholds: %s", settings.BlacklistStatusThresholds)
+	}
 	logrus.Infof("    Failover Status Codes: %s", settings.FailoverStatusCodes)
+	logrus.Infof("    Pool Mode: %s", settings.PoolMode)
 	logrus.Infof("    Key Validation Interval: %d minutes", settings.KeyValidationIntervalMinutes)
+	if settings.KeyValidationWindows != "" {
+		logrus.Infof("    Key Validation Windows: %s", settings.KeyValidationWindows)
+	}
+	if settings.KeyValidationBudget > 0 {
+		logrus.Infof("    Key Validation Budget: %d", settings.KeyValidationBudget)
+	}
 	logrus.Info("====================================")
 	logrus.Info("")
 }
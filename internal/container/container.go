@@ -4,17 +4,27 @@ package container
 import (
 	"gpt-load/internal/app"
 	"gpt-load/internal/channel"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
+	"gpt-load/internal/credentials"
 	"gpt-load/internal/db"
+	"gpt-load/internal/drain"
 	"gpt-load/internal/encryption"
+	"gpt-load/internal/grpcapi"
 	"gpt-load/internal/handler"
 	"gpt-load/internal/httpclient"
+	"gpt-load/internal/inflight"
 	"gpt-load/internal/keypool"
+	"gpt-load/internal/moderation"
+	"gpt-load/internal/netacl"
+	"gpt-load/internal/providerstatus"
 	"gpt-load/internal/proxy"
 	"gpt-load/internal/router"
 	"gpt-load/internal/services"
 	"gpt-load/internal/store"
+	"gpt-load/internal/templates"
 	"gpt-load/internal/types"
+	"gpt-load/internal/upstreamstats"
 
 	"go.uber.org/dig"
 )
@@ -28,6 +38,9 @@ func BuildContainer() (*dig.Container, error) {
 		return nil, err
 	}
 	if err := container.Provide(func(configManager types.ConfigManager) (encryption.Service, error) {
+		if secondary := configManager.GetEncryptionKeySecondary(); secondary != "" {
+			return encryption.NewRotatingService(configManager.GetEncryptionKey(), secondary)
+		}
 		return encryption.NewService(configManager.GetEncryptionKey())
 	}); err != nil {
 		return nil, err
@@ -35,15 +48,45 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(db.NewDB); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(db.NewHealthMonitor); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(drain.NewController); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(config.NewSystemSettingsManager); err != nil {
 		return nil, err
 	}
 	if err := container.Provide(store.NewStore); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(cluster.NewElector); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(httpclient.NewHTTPClientManager); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(credentials.NewManager); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(templates.NewCatalog); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(inflight.NewRegistry); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(netacl.NewRejections); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(moderation.NewModerator); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(upstreamstats.NewTracker); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(providerstatus.NewMonitor); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(channel.NewFactory); err != nil {
 		return nil, err
 	}
@@ -67,12 +110,69 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(services.NewLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewKeyMaskingService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAdminAuthService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewOIDCService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewLogCleanupService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewStoreFlushService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewStoreHygieneService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewPoolReconcileService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewTrashService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewKeyRotationService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewUsageSnapshotService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAlertNotifier); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewAnomalyService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewLogStreamService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewRequestLogWriter); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewRequestLogService); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(services.NewHoneypotService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewIPThrottleService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewBudgetService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewDebugCaptureService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewResponseCacheService); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(services.NewModelDiscoveryService); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(services.NewSubGroupManager); err != nil {
 		return nil, err
 	}
@@ -88,12 +188,24 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(keypool.NewProvider); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(keypool.NewPools); err != nil {
+		return nil, err
+	}
+	if err := container.Provide(keypool.NewKeyPool); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(keypool.NewKeyValidator); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(keypool.NewValidationBudget); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(keypool.NewCronChecker); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(keypool.NewQuotaResetScheduler); err != nil {
+		return nil, err
+	}
 
 	// Handlers
 	if err := container.Provide(handler.NewServer); err != nil {
@@ -102,11 +214,17 @@ func BuildContainer() (*dig.Container, error) {
 	if err := container.Provide(handler.NewCommonHandler); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(grpcapi.NewServer); err != nil {
+		return nil, err
+	}
 
 	// Proxy & Router
 	if err := container.Provide(proxy.NewProxyServer); err != nil {
 		return nil, err
 	}
+	if err := container.Provide(proxy.NewCanaryRunner); err != nil {
+		return nil, err
+	}
 	if err := container.Provide(router.NewRouter); err != nil {
 		return nil, err
 	}
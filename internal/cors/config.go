@@ -0,0 +1,146 @@
+// Package cors describes the CORS settings of a group, with which the proxy answers the
+// preflight requests of browser-based clients itself, without consuming a key.
+package cors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxMaxAge bounds how long browsers may cache a preflight response, in seconds.
+const maxMaxAge = 86400
+
+// defaultMethods are allowed when a Config lists none.
+var defaultMethods = []string{http.MethodGet, http.MethodPost}
+
+// Config is the CORS policy of a group, set as "cors" in the group config.
+type Config struct {
+	// AllowedOrigins are the origins allowed to call the group, "*" for any, or with a
+	// wildcard subdomain such as "https://*.example.com".
+	AllowedOrigins []string `json:"allowed_origins"`
+	// AllowedHeaders are the request headers allowed, those the browser asks for when empty
+	// or "*".
+	AllowedHeaders []string `json:"allowed_headers,omitempty"`
+	// AllowedMethods are the methods allowed, GET and POST when empty.
+	AllowedMethods []string `json:"allowed_methods,omitempty"`
+	// MaxAge is how long browsers may cache a preflight response, in seconds.
+	MaxAge int `json:"max_age,omitempty"`
+}
+
+// Parse converts the cors value of a group config into a Config.
+func Parse(value any) (*Config, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("cors is invalid: %w", err)
+	}
+	if err := config.normalize(); err != nil {
+		return nil, fmt.Errorf("cors is invalid: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *Config) normalize() error {
+	if len(c.AllowedOrigins) == 0 {
+		return fmt.Errorf("allowed_origins is required")
+	}
+	for i, origin := range c.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		if origin != "*" {
+			u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+				return fmt.Errorf("invalid origin %q", c.AllowedOrigins[i])
+			}
+		}
+		c.AllowedOrigins[i] = origin
+	}
+	for i, header := range c.AllowedHeaders {
+		header = strings.TrimSpace(header)
+		if header != "*" && (header == "" || strings.ContainsAny(header, " \t:,")) {
+			return fmt.Errorf("invalid header %q", c.AllowedHeaders[i])
+		}
+		c.AllowedHeaders[i] = header
+	}
+	for i, method := range c.AllowedMethods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		if method == "" || strings.ContainsAny(method, " \t:,") {
+			return fmt.Errorf("invalid method %q", c.AllowedMethods[i])
+		}
+		c.AllowedMethods[i] = method
+	}
+	if c.MaxAge < 0 || c.MaxAge > maxMaxAge {
+		return fmt.Errorf("max_age must be between 0 and %d", maxMaxAge)
+	}
+	return nil
+}
+
+// AllowsOrigin reports whether a request from origin is allowed.
+func (c *Config) AllowsOrigin(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	origin = strings.ToLower(origin)
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+		// A wildcard matches subdomains at any depth, but not the domain itself
+		if scheme, domain, ok := strings.Cut(allowed, "://*."); ok {
+			if rest, ok := strings.CutPrefix(origin, scheme+"://"); ok && strings.HasSuffix(rest, "."+domain) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AllowOriginHeader returns the Access-Control-Allow-Origin value for an allowed origin.
+func (c *Config) AllowOriginHeader(origin string) string {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+	}
+	return origin
+}
+
+// AllowMethodsHeader returns the Access-Control-Allow-Methods value of a preflight response.
+func (c *Config) AllowMethodsHeader() string {
+	if len(c.AllowedMethods) == 0 {
+		return strings.Join(defaultMethods, ", ")
+	}
+	return strings.Join(c.AllowedMethods, ", ")
+}
+
+// AllowHeadersHeader returns the Access-Control-Allow-Headers value of a preflight response
+// asking for the requested headers.
+func (c *Config) AllowHeadersHeader(requested string) string {
+	if len(c.AllowedHeaders) == 0 {
+		return requested
+	}
+	for _, header := range c.AllowedHeaders {
+		if header == "*" {
+			return requested
+		}
+	}
+	return strings.Join(c.AllowedHeaders, ", ")
+}
+
+// MaxAgeHeader returns the Access-Control-Max-Age value of a preflight response, empty when
+// browsers use their default.
+func (c *Config) MaxAgeHeader() string {
+	if c.MaxAge == 0 {
+		return ""
+	}
+	return strconv.Itoa(c.MaxAge)
+}
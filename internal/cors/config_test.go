@@ -0,0 +1,56 @@
+package cors
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	config, err := Parse(map[string]any{
+		"allowed_origins": []string{" https://App.example.com/ ", "https://*.example.org"},
+		"allowed_methods": []string{"post"},
+		"max_age":         600,
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.AllowedOrigins[0] != "https://app.example.com" || config.AllowMethodsHeader() != "POST" || config.MaxAgeHeader() != "600" {
+		t.Errorf("Parse() = %+v", config)
+	}
+
+	for _, invalid := range []map[string]any{
+		{},
+		{"allowed_origins": []string{"app.example.com"}},
+		{"allowed_origins": []string{"https://app.example.com/path"}},
+		{"allowed_origins": []string{"*"}, "allowed_headers": []string{"X-A, X-B"}},
+		{"allowed_origins": []string{"*"}, "max_age": 86401},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestAllowsOrigin(t *testing.T) {
+	config, err := Parse(map[string]any{"allowed_origins": []string{"https://app.example.com", "https://*.example.org"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for origin, want := range map[string]bool{
+		"https://app.example.com":    true,
+		"https://APP.example.com":    true,
+		"http://app.example.com":     false,
+		"https://a.b.example.org":    true,
+		"https://example.org":        false,
+		"https://evil-example.org":   false,
+		"https://app.example.com.io": false,
+		"":                           false,
+	} {
+		if got := config.AllowsOrigin(origin); got != want {
+			t.Errorf("AllowsOrigin(%q) = %t, want %t", origin, got, want)
+		}
+	}
+	if got := config.AllowOriginHeader("https://app.example.com"); got != "https://app.example.com" {
+		t.Errorf("AllowOriginHeader() = %q, want the origin", got)
+	}
+	if got := config.AllowHeadersHeader("authorization, content-type"); got != "authorization, content-type" {
+		t.Errorf("AllowHeadersHeader() = %q, want the requested headers", got)
+	}
+}
@@ -0,0 +1,39 @@
+package credentials
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const azureCognitiveServicesScope = "https://cognitiveservices.azure.com/.default"
+
+// azureAD mints Microsoft Entra ID (Azure AD) tokens for Azure OpenAI and gateways behind
+// it with the client credentials flow. The key is stored as "tenant_id:client_id:client_secret".
+type azureAD struct {
+	authorityHost string
+}
+
+// Refresh exchanges the client secret of an app registration for a token.
+func (r *azureAD) Refresh(ctx context.Context, client *http.Client, credential string) (*Token, error) {
+	parts, err := splitCredential(credential, 3, "tenant_id:client_id:client_secret")
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", parts[1])
+	form.Set("client_secret", parts[2])
+	form.Set("scope", azureCognitiveServicesScope)
+
+	tokenURL := fmt.Sprintf("%s/%s/oauth2/v2.0/token", r.authorityHost, url.PathEscape(parts[0]))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return requestToken(client, req)
+}
@@ -0,0 +1,121 @@
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	tokenKeyPrefix = "credential_token:"
+	// refreshMargin is how long before it expires a cached token is replaced.
+	refreshMargin = 5 * time.Minute
+)
+
+// Manager mints access tokens for the keys of groups that use a credential refresher and
+// caches them in the store, encrypted, until shortly before they expire.
+type Manager struct {
+	store         store.Store
+	encryptionSvc encryption.Service
+	// locks holds a mutex per key so that concurrent requests mint one token.
+	locks sync.Map
+}
+
+// NewManager creates a new Manager.
+func NewManager(store store.Store, encryptionSvc encryption.Service) *Manager {
+	return &Manager{
+		store:         store,
+		encryptionSvc: encryptionSvc,
+	}
+}
+
+// AuthKey returns the key to authenticate upstream with: key itself, or a copy carrying an
+// access token minted from it when the group uses a credential refresher. client is used
+// to reach the token endpoint.
+func (m *Manager) AuthKey(ctx context.Context, client *http.Client, group *models.Group, key *models.APIKey) (*models.APIKey, error) {
+	name := group.EffectiveConfig.CredentialRefresher
+	refresher, err := Lookup(name)
+	if err != nil || refresher == nil {
+		return key, err
+	}
+
+	token, err := m.token(ctx, client, name, refresher, key)
+	if err != nil {
+		return nil, err
+	}
+	authKey := *key
+	authKey.KeyValue = token
+	return &authKey, nil
+}
+
+func (m *Manager) token(ctx context.Context, client *http.Client, name string, refresher Refresher, key *models.APIKey) (string, error) {
+	cacheKey := fmt.Sprintf("%s%s:%d", tokenKeyPrefix, name, key.ID)
+	if cached := m.cached(cacheKey); cached != nil && time.Until(cached.ExpiresAt) > refreshMargin {
+		return cached.AccessToken, nil
+	}
+
+	lock, _ := m.locks.LoadOrStore(key.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Another request may have minted the token meanwhile
+	cached := m.cached(cacheKey)
+	if cached != nil && time.Until(cached.ExpiresAt) > refreshMargin {
+		return cached.AccessToken, nil
+	}
+
+	fresh, err := refresher.Refresh(ctx, client, key.KeyValue)
+	if err != nil {
+		if cached != nil && time.Now().Before(cached.ExpiresAt) {
+			logrus.WithError(err).WithField("key_id", key.ID).Warn("Failed to refresh access token, using the current one until it expires")
+			return cached.AccessToken, nil
+		}
+		return "", fmt.Errorf("failed to mint access token with %s: %w", name, err)
+	}
+
+	if err := m.save(cacheKey, fresh); err != nil {
+		logrus.WithError(err).WithField("key_id", key.ID).Warn("Failed to cache access token")
+	}
+	return fresh.AccessToken, nil
+}
+
+// cached returns the token cached under cacheKey, nil if there is none.
+func (m *Manager) cached(cacheKey string) *Token {
+	data, err := m.store.Get(cacheKey)
+	if err != nil {
+		return nil
+	}
+	decrypted, err := m.encryptionSvc.Decrypt(string(data))
+	if err != nil {
+		return nil
+	}
+	var token Token
+	if err := json.Unmarshal([]byte(decrypted), &token); err != nil {
+		return nil
+	}
+	return &token
+}
+
+func (m *Manager) save(cacheKey string, token *Token) error {
+	ttl := time.Until(token.ExpiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	encrypted, err := m.encryptionSvc.Encrypt(string(data))
+	if err != nil {
+		return err
+	}
+	return m.store.Set(cacheKey, []byte(encrypted), ttl)
+}
@@ -0,0 +1,105 @@
+package credentials
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+type fakeRefresher struct {
+	calls    int
+	lifetime time.Duration
+	err      error
+}
+
+func (r *fakeRefresher) Refresh(ctx context.Context, client *http.Client, credential string) (*Token, error) {
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &Token{AccessToken: credential + "-token", ExpiresAt: time.Now().Add(r.lifetime)}, nil
+}
+
+func TestManagerCachesTokens(t *testing.T) {
+	refresher := &fakeRefresher{lifetime: time.Hour}
+	refreshers["fake"] = refresher
+	defer delete(refreshers, "fake")
+
+	encryptionSvc, err := encryption.NewService("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewManager(store.NewMemoryStore(0), encryptionSvc)
+	group := &models.Group{ID: 1, EffectiveConfig: types.SystemSettings{CredentialRefresher: "fake"}}
+	key := &models.APIKey{ID: 7, GroupID: 1, KeyValue: "secret"}
+
+	for range 2 {
+		authKey, err := manager.AuthKey(context.Background(), http.DefaultClient, group, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if authKey.KeyValue != "secret-token" || key.KeyValue != "secret" {
+			t.Fatalf("AuthKey = %q, key = %q, want the token on a copy of the key", authKey.KeyValue, key.KeyValue)
+		}
+	}
+	if refresher.calls != 1 {
+		t.Fatalf("refresher called %d times, want the cached token to be reused", refresher.calls)
+	}
+
+	// A token about to expire is replaced, and kept while the refresh fails
+	refresher.lifetime = time.Minute
+	key.ID = 8
+	if _, err := manager.AuthKey(context.Background(), http.DefaultClient, group, key); err != nil {
+		t.Fatal(err)
+	}
+	refresher.err = errors.New("token endpoint down")
+	authKey, err := manager.AuthKey(context.Background(), http.DefaultClient, group, key)
+	if err != nil || authKey.KeyValue != "secret-token" {
+		t.Fatalf("AuthKey with a failing refresh = %v, %v, want the unexpired token", authKey, err)
+	}
+	if refresher.calls != 3 {
+		t.Fatalf("refresher called %d times, want a refresh attempt for the expiring token", refresher.calls)
+	}
+
+	// Groups without a refresher send the key as it is
+	authKey, err = manager.AuthKey(context.Background(), http.DefaultClient, &models.Group{ID: 2}, key)
+	if err != nil || authKey != key {
+		t.Fatalf("AuthKey without a refresher = %v, %v, want the key itself", authKey, err)
+	}
+}
+
+func TestAzureADRefresher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/tenant/oauth2/v2.0/token" || r.FormValue("client_id") != "client" || r.FormValue("client_secret") != "se:cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"access_token":"aad-token","expires_in":3599,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	refresher := &azureAD{authorityHost: server.URL}
+	token, err := refresher.Refresh(context.Background(), server.Client(), "tenant:client:se:cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.AccessToken != "aad-token" || time.Until(token.ExpiresAt) < 59*time.Minute {
+		t.Fatalf("token = %+v, want aad-token valid for an hour", token)
+	}
+
+	if _, err := refresher.Refresh(context.Background(), server.Client(), "tenant:client:wrong"); err == nil {
+		t.Fatal("Refresh with a wrong secret succeeded")
+	}
+	if _, err := refresher.Refresh(context.Background(), server.Client(), "tenant-only"); err == nil {
+		t.Fatal("Refresh with a malformed credential succeeded")
+	}
+}
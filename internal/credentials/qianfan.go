@@ -0,0 +1,31 @@
+package credentials
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// qianfan mints Baidu Qianfan access tokens from the API key and secret key of an
+// application. The key is stored as "api_key:secret_key".
+type qianfan struct {
+	tokenURL string
+}
+
+// Refresh exchanges the application keys for a token.
+func (r *qianfan) Refresh(ctx context.Context, client *http.Client, credential string) (*Token, error) {
+	parts, err := splitCredential(credential, 2, "api_key:secret_key")
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("grant_type", "client_credentials")
+	query.Set("client_id", parts[0])
+	query.Set("client_secret", parts[1])
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.tokenURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return requestToken(client, req)
+}
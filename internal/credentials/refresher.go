@@ -0,0 +1,106 @@
+// Package credentials mints short-lived upstream access tokens from the long-lived
+// credentials stored as keys, for providers that do not accept static API keys.
+package credentials
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultTokenLifetime is assumed when a token endpoint does not report the lifetime.
+const defaultTokenLifetime = time.Hour
+
+// Token is an access token minted by a Refresher.
+type Token struct {
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Refresher mints access tokens for one kind of provider.
+type Refresher interface {
+	// Refresh exchanges the long-lived credential stored as a key for an access token.
+	Refresh(ctx context.Context, client *http.Client, credential string) (*Token, error)
+}
+
+// refreshers are the refreshers groups can select with credential_refresher.
+var refreshers = map[string]Refresher{
+	"google_service_account": &googleServiceAccount{},
+	"azure_ad":               &azureAD{authorityHost: "https://login.microsoftonline.com"},
+	"qianfan":                &qianfan{tokenURL: "https://aip.baidubce.com/oauth/2.0/token"},
+}
+
+// Lookup returns the refresher registered under name, nil for an empty name.
+func Lookup(name string) (Refresher, error) {
+	if name == "" {
+		return nil, nil
+	}
+	refresher, ok := refreshers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown credential refresher %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	return refresher, nil
+}
+
+// Names lists the registered refreshers.
+func Names() []string {
+	names := make([]string, 0, len(refreshers))
+	for name := range refreshers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// requestToken sends a request to an OAuth-style token endpoint and decodes the
+// access_token and expires_in of the answer.
+func requestToken(client *http.Client, req *http.Request) (*Token, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var payload struct {
+		AccessToken      string          `json:"access_token"`
+		ExpiresIn        json.Number     `json:"expires_in"`
+		Error            json.RawMessage `json:"error"`
+		ErrorDescription string          `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("token endpoint answered with status %d and an invalid body", resp.StatusCode)
+	}
+	if resp.StatusCode != http.StatusOK || payload.AccessToken == "" {
+		message := payload.ErrorDescription
+		if message == "" {
+			message = strings.Trim(string(payload.Error), `"`)
+		}
+		return nil, fmt.Errorf("token endpoint answered with status %d: %s", resp.StatusCode, message)
+	}
+
+	lifetime := defaultTokenLifetime
+	if seconds, err := payload.ExpiresIn.Int64(); err == nil && seconds > 0 {
+		lifetime = time.Duration(seconds) * time.Second
+	}
+	return &Token{AccessToken: payload.AccessToken, ExpiresAt: time.Now().Add(lifetime)}, nil
+}
+
+// splitCredential splits a credential of n colon-separated parts, the last of which may
+// contain colons itself.
+func splitCredential(credential string, n int, format string) ([]string, error) {
+	parts := strings.SplitN(credential, ":", n)
+	if len(parts) != n || slices.Contains(parts, "") {
+		return nil, fmt.Errorf("credential must have the format %s", format)
+	}
+	return parts, nil
+}
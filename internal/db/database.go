@@ -1,8 +1,10 @@
 package db
 
 import (
+	"context"
 	"fmt"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 	"log"
 	"os"
 	"path/filepath"
@@ -39,7 +41,7 @@ func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 	}
 
 	var dialector gorm.Dialector
-	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+	if IsPostgresDSN(dsn) {
 		dialector = postgres.New(postgres.Config{
 			DSN:                  dsn,
 			PreferSimpleProtocol: true,
@@ -57,16 +59,19 @@ func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 		if err := os.MkdirAll(filepath.Dir(dsn), 0755); err != nil {
 			return nil, fmt.Errorf("failed to create database directory: %w", err)
 		}
-		dialector = sqlite.Open(dsn + "?_busy_timeout=5000")
+		dialector = sqlite.Open(sqliteDSN(dsn))
 	}
 
-	var err error
-	DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger:      newLogger,
-		PrepareStmt: true,
+	// The database may still be starting, e.g. a container launched alongside this one
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(dbConfig.StartupWait)*time.Second)
+	defer cancel()
+	err := utils.RetryWithBackoff(ctx, "Connecting to the database", func() error {
+		var err error
+		DB, err = connect(dialector, newLogger)
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+		return nil, err
 	}
 
 	sqlDB, err := DB.DB()
@@ -80,3 +85,54 @@ func NewDB(configManager types.ConfigManager) (*gorm.DB, error) {
 
 	return DB, nil
 }
+
+// connect opens the database and checks that it answers.
+func connect(dialector gorm.Dialector, newLogger logger.Interface) (*gorm.DB, error) {
+	gormDB, err := gorm.Open(dialector, &gorm.Config{
+		Logger:      newLogger,
+		PrepareStmt: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB, err := gormDB.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sql.DB: %w", err)
+	}
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return gormDB, nil
+}
+
+// sqlitePragmas are applied to every SQLite connection. WAL lets readers proceed while a write
+// is in progress, and busy_timeout makes a writer wait for the lock instead of failing with
+// "database is locked".
+var sqlitePragmas = []string{
+	"journal_mode(WAL)",
+	"busy_timeout(5000)",
+	"synchronous(NORMAL)",
+}
+
+func sqliteDSN(dsn string) string {
+	params := make([]string, 0, len(sqlitePragmas))
+	for _, pragma := range sqlitePragmas {
+		params = append(params, "_pragma="+pragma)
+	}
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + strings.Join(params, "&")
+}
+
+// IsPostgresDSN reports whether a DATABASE_DSN points to PostgreSQL.
+func IsPostgresDSN(dsn string) bool {
+	return strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://")
+}
+
+// IsSQLite reports whether the database is SQLite, which serializes all writes.
+func IsSQLite(db *gorm.DB) bool {
+	return db.Dialector.Name() == "sqlite"
+}
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	healthCheckInterval = 5 * time.Second
+	healthCheckTimeout  = 3 * time.Second
+)
+
+// HealthStatus reports whether the instance is running in degraded mode.
+type HealthStatus struct {
+	Enabled       bool       `json:"enabled"`
+	Degraded      bool       `json:"degraded"`
+	DegradedSince *time.Time `json:"degraded_since,omitempty"`
+}
+
+// HealthMonitor periodically pings the database. While it is unreachable the instance
+// runs in degraded mode: the proxy keeps serving from cached groups and the store,
+// the admin API is read-only and DB writes are deferred until it recovers.
+type HealthMonitor struct {
+	db      *gorm.DB
+	enabled bool
+
+	degradedSince atomic.Int64
+	mu            sync.Mutex
+	recoverHooks  []func()
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewHealthMonitor creates a new HealthMonitor.
+func NewHealthMonitor(db *gorm.DB, configManager types.ConfigManager) *HealthMonitor {
+	return &HealthMonitor{
+		db:       db,
+		enabled:  configManager.GetDatabaseConfig().DegradedMode,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// OnRecover registers a hook that runs once the database is reachable again.
+func (m *HealthMonitor) OnRecover(hook func()) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recoverHooks = append(m.recoverHooks, hook)
+}
+
+// IsDegraded reports whether the database is currently considered unavailable.
+func (m *HealthMonitor) IsDegraded() bool {
+	return m.degradedSince.Load() != 0
+}
+
+// Status returns the current degraded mode status.
+func (m *HealthMonitor) Status() HealthStatus {
+	status := HealthStatus{Enabled: m.enabled}
+	if since := m.degradedSince.Load(); since != 0 {
+		t := time.Unix(0, since)
+		status.Degraded = true
+		status.DegradedSince = &t
+	}
+	return status
+}
+
+// Start begins the health check loop. It does nothing when degraded mode is disabled.
+func (m *HealthMonitor) Start() {
+	if !m.enabled {
+		return
+	}
+	logrus.Debug("Starting database health monitor...")
+	m.wg.Add(1)
+	go m.runLoop()
+}
+
+// Stop stops the health check loop, respecting the context for shutdown timeout.
+func (m *HealthMonitor) Stop(ctx context.Context) {
+	if !m.enabled {
+		return
+	}
+	close(m.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("Database health monitor stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("Database health monitor stop timed out.")
+	}
+}
+
+func (m *HealthMonitor) runLoop() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.check()
+		case <-m.stopChan:
+			return
+		}
+	}
+}
+
+func (m *HealthMonitor) check() {
+	err := m.ping()
+	wasDegraded := m.IsDegraded()
+
+	switch {
+	case err != nil && !wasDegraded:
+		m.degradedSince.Store(time.Now().UnixNano())
+		logrus.WithError(err).Error("Database is unreachable, entering degraded mode: serving from cache, admin API is read-only")
+	case err == nil && wasDegraded:
+		m.degradedSince.Store(0)
+		logrus.Info("Database is reachable again, leaving degraded mode and replaying deferred writes")
+
+		m.mu.Lock()
+		hooks := append([]func(){}, m.recoverHooks...)
+		m.mu.Unlock()
+		for _, hook := range hooks {
+			hook()
+		}
+	}
+}
+
+func (m *HealthMonitor) ping() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
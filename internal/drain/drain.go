@@ -0,0 +1,91 @@
+// Package drain coordinates taking an instance out of rotation before it shuts down.
+package drain
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Status reports the drain state of the instance.
+type Status struct {
+	Draining         bool       `json:"draining"`
+	DrainingSince    *time.Time `json:"draining_since,omitempty"`
+	Reason           string     `json:"reason,omitempty"`
+	InFlightRequests int64      `json:"in_flight_requests"`
+}
+
+// Controller tracks in-flight proxy requests and whether the instance is draining.
+// While draining, new proxy requests are rejected and /health reports the instance
+// as unavailable so that load balancers stop routing to it, while requests that are
+// already running, including streams, are allowed to finish.
+type Controller struct {
+	drainingSince atomic.Int64
+	inFlight      atomic.Int64
+
+	mu        sync.Mutex
+	reason    string
+	requested chan struct{}
+}
+
+// NewController creates a new Controller.
+func NewController() *Controller {
+	return &Controller{
+		requested: make(chan struct{}),
+	}
+}
+
+// Start puts the instance into drain mode. It returns false if it was already draining.
+func (c *Controller) Start(reason string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.IsDraining() {
+		return false
+	}
+	c.reason = reason
+	c.drainingSince.Store(time.Now().UnixNano())
+	close(c.requested)
+
+	logrus.Infof("Draining instance (%s): rejecting new proxy requests, %d in flight", reason, c.inFlight.Load())
+	return true
+}
+
+// Requested is closed once drain mode has been started.
+func (c *Controller) Requested() <-chan struct{} {
+	return c.requested
+}
+
+// IsDraining reports whether the instance is draining.
+func (c *Controller) IsDraining() bool {
+	return c.drainingSince.Load() != 0
+}
+
+// Track marks the start of a proxy request and returns the function that marks its end.
+func (c *Controller) Track() func() {
+	c.inFlight.Add(1)
+	return func() {
+		c.inFlight.Add(-1)
+	}
+}
+
+// InFlight returns the number of proxy requests currently being served.
+func (c *Controller) InFlight() int64 {
+	return c.inFlight.Load()
+}
+
+// Status returns the current drain status.
+func (c *Controller) Status() Status {
+	status := Status{InFlightRequests: c.inFlight.Load()}
+	if since := c.drainingSince.Load(); since != 0 {
+		t := time.Unix(0, since)
+		status.Draining = true
+		status.DrainingSince = &t
+		c.mu.Lock()
+		status.Reason = c.reason
+		c.mu.Unlock()
+	}
+	return status
+}
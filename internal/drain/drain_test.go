@@ -0,0 +1,29 @@
+package drain
+
+import "testing"
+
+func TestController(t *testing.T) {
+	c := NewController()
+	done := c.Track()
+	if c.IsDraining() || c.InFlight() != 1 {
+		t.Fatalf("new controller: draining = %t, in flight = %d", c.IsDraining(), c.InFlight())
+	}
+
+	if !c.Start("test") {
+		t.Fatal("Start() should report the transition into drain mode")
+	}
+	if c.Start("again") {
+		t.Error("Start() on a draining controller should return false")
+	}
+	select {
+	case <-c.Requested():
+	default:
+		t.Error("Requested() should be closed once draining")
+	}
+
+	done()
+	status := c.Status()
+	if !status.Draining || status.Reason != "test" || status.InFlightRequests != 0 || status.DrainingSince == nil {
+		t.Errorf("Status() = %+v", status)
+	}
+}
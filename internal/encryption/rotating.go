@@ -0,0 +1,107 @@
+package encryption
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// RotatingService encrypts with a primary key and still decrypts data written with a
+// secondary key, so that the key can be rotated while the service keeps running.
+type RotatingService struct {
+	primary   Service
+	secondary atomic.Pointer[Service]
+}
+
+// NewRotatingService creates a service that rotates from secondaryKey to primaryKey.
+// Both keys are required; enabling or disabling encryption goes through migrate-keys.
+func NewRotatingService(primaryKey, secondaryKey string) (*RotatingService, error) {
+	if primaryKey == "" || secondaryKey == "" {
+		return nil, fmt.Errorf("key rotation requires both a primary and a secondary key")
+	}
+	if primaryKey == secondaryKey {
+		return nil, fmt.Errorf("the primary and secondary keys cannot be the same")
+	}
+
+	primary, err := NewService(primaryKey)
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := NewService(secondaryKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &RotatingService{primary: primary}
+	s.secondary.Store(&secondary)
+	return s, nil
+}
+
+// Encrypt encrypts with the primary key.
+func (s *RotatingService) Encrypt(plaintext string) (string, error) {
+	return s.primary.Encrypt(plaintext)
+}
+
+// Decrypt decrypts with the primary key, falling back to the secondary key until it is dropped.
+func (s *RotatingService) Decrypt(ciphertext string) (string, error) {
+	plaintext, err := s.primary.Decrypt(ciphertext)
+	if err == nil {
+		return plaintext, nil
+	}
+	if secondary := s.secondary.Load(); secondary != nil {
+		if plaintext, secondaryErr := (*secondary).Decrypt(ciphertext); secondaryErr == nil {
+			return plaintext, nil
+		}
+	}
+	return "", err
+}
+
+// Hash hashes with the primary key.
+func (s *RotatingService) Hash(plaintext string) string {
+	return s.primary.Hash(plaintext)
+}
+
+// Rotating reports whether the secondary key is still in use.
+func (s *RotatingService) Rotating() bool {
+	return s.secondary.Load() != nil
+}
+
+// Reencrypt returns data written with the secondary key encrypted and hashed with the
+// primary key. rotated is false when the data already uses the primary key.
+func (s *RotatingService) Reencrypt(ciphertext string) (encrypted, hash string, rotated bool, err error) {
+	if _, err := s.primary.Decrypt(ciphertext); err == nil {
+		return "", "", false, nil
+	}
+	secondary := s.secondary.Load()
+	if secondary == nil {
+		return "", "", false, fmt.Errorf("the secondary key was already dropped")
+	}
+	plaintext, err := (*secondary).Decrypt(ciphertext)
+	if err != nil {
+		return "", "", false, fmt.Errorf("decryption failed with both keys: %w", err)
+	}
+	if encrypted, err = s.primary.Encrypt(plaintext); err != nil {
+		return "", "", false, err
+	}
+	return encrypted, s.primary.Hash(plaintext), true, nil
+}
+
+// DropSecondary stops decrypting with the secondary key.
+func (s *RotatingService) DropSecondary() {
+	s.secondary.Store(nil)
+}
+
+// Hashes returns the hashes of plaintext under every key svc decrypts with, the primary
+// first, to find rows not yet rotated to the primary key. It is empty for an empty plaintext.
+func Hashes(svc Service, plaintext string) []string {
+	hash := svc.Hash(plaintext)
+	if hash == "" {
+		return nil
+	}
+	hashes := []string{hash}
+	if rotating, ok := svc.(*RotatingService); ok {
+		if secondary := rotating.secondary.Load(); secondary != nil {
+			hashes = append(hashes, (*secondary).Hash(plaintext))
+		}
+	}
+	return hashes
+}
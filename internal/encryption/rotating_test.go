@@ -0,0 +1,47 @@
+package encryption
+
+import "testing"
+
+func TestRotatingService(t *testing.T) {
+	oldService, err := NewService("old-secret-key-for-rotation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotating, err := NewRotatingService("new-secret-key-for-rotation-test", "old-secret-key-for-rotation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldCiphertext, err := oldService.Encrypt("sk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := rotating.Decrypt(oldCiphertext); err != nil || plaintext != "sk-test" {
+		t.Fatalf("Decrypt(old ciphertext) = %q, %v, want the plaintext", plaintext, err)
+	}
+	if hashes := Hashes(rotating, "sk-test"); len(hashes) != 2 || hashes[1] != oldService.Hash("sk-test") {
+		t.Fatalf("Hashes while rotating = %v, want the primary and the secondary hash", hashes)
+	}
+
+	encrypted, hash, rotated, err := rotating.Reencrypt(oldCiphertext)
+	if err != nil || !rotated {
+		t.Fatalf("Reencrypt(old ciphertext) = %t, %v, want rotated", rotated, err)
+	}
+	if hash != rotating.Hash("sk-test") {
+		t.Fatalf("Reencrypt hash = %q, want the primary hash", hash)
+	}
+	if _, _, rotated, err := rotating.Reencrypt(encrypted); err != nil || rotated {
+		t.Fatalf("Reencrypt(new ciphertext) = %t, %v, want left as is", rotated, err)
+	}
+
+	rotating.DropSecondary()
+	if _, err := rotating.Decrypt(oldCiphertext); err == nil {
+		t.Fatal("Decrypt(old ciphertext) succeeded after the secondary key was dropped")
+	}
+	if plaintext, err := rotating.Decrypt(encrypted); err != nil || plaintext != "sk-test" {
+		t.Fatalf("Decrypt(new ciphertext) = %q, %v, want the plaintext", plaintext, err)
+	}
+	if hashes := Hashes(rotating, "sk-test"); len(hashes) != 1 {
+		t.Fatalf("Hashes after the rotation = %v, want the primary hash only", hashes)
+	}
+}
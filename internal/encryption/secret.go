@@ -0,0 +1,24 @@
+package encryption
+
+import "strings"
+
+// SecretMask starts the masked form of a secret in API responses.
+const SecretMask = "********"
+
+// secretFingerprintLength is the number of hash characters following the mask.
+const secretFingerprintLength = 8
+
+// MaskSecret returns the masked form of a secret: the mask followed by a short fingerprint
+// of its plaintext, so that secrets can be told apart and a masked value sent back matched
+// to the secret it stands for.
+func MaskSecret(svc Service, plaintext string) string {
+	if plaintext == "" {
+		return ""
+	}
+	return SecretMask + svc.Hash(plaintext)[:secretFingerprintLength]
+}
+
+// IsMaskedSecret reports whether value is the masked form of a secret.
+func IsMaskedSecret(value string) bool {
+	return strings.HasPrefix(value, SecretMask)
+}
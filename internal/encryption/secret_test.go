@@ -0,0 +1,24 @@
+package encryption
+
+import "testing"
+
+func TestMaskSecret(t *testing.T) {
+	svc, err := NewService("secret-key-for-the-mask-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	masked := MaskSecret(svc, "Bearer gateway-token")
+	if !IsMaskedSecret(masked) || len(masked) != len(SecretMask)+secretFingerprintLength {
+		t.Fatalf("MaskSecret() = %q, want the mask and a fingerprint", masked)
+	}
+	if masked != MaskSecret(svc, "Bearer gateway-token") {
+		t.Error("MaskSecret() is not stable for the same secret")
+	}
+	if masked == MaskSecret(svc, "Bearer other-token") {
+		t.Error("MaskSecret() is the same for different secrets")
+	}
+	if MaskSecret(svc, "") != "" || IsMaskedSecret("Bearer gateway-token") {
+		t.Error("an empty secret or a plaintext is reported as masked")
+	}
+}
@@ -0,0 +1,116 @@
+// Package endpoints restricts the upstream endpoints a group proxies.
+package endpoints
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Decision is the outcome of checking a request against an allowlist.
+type Decision int
+
+const (
+	Allowed Decision = iota
+	// PathNotAllowed means no rule matches the path.
+	PathNotAllowed
+	// MethodNotAllowed means rules match the path, but none for the method.
+	MethodNotAllowed
+)
+
+// Rule allows a set of methods on a path, or on every path under a prefix ending in "*".
+type Rule struct {
+	Methods []string // empty allows any method
+	Path    string
+	Prefix  bool
+}
+
+// Allowlist is a list of allowed endpoints. The zero value allows everything.
+type Allowlist []Rule
+
+// Parse parses comma- or newline-separated entries of the form "[METHODS] PATH", e.g.
+// "POST /v1/chat/completions, GET /v1/models, GET|POST /v1beta/models/*". Methods are
+// separated by "|", and an omitted method or "*" allows any method.
+func Parse(spec string) (Allowlist, error) {
+	var list Allowlist
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid entry %q, expected \"[METHODS] PATH\"", strings.TrimSpace(entry))
+		}
+
+		var rule Rule
+		path := fields[len(fields)-1]
+		if len(fields) == 2 && fields[0] != "*" {
+			for _, method := range strings.Split(fields[0], "|") {
+				method = strings.ToUpper(strings.TrimSpace(method))
+				if method == "" || strings.ContainsFunc(method, func(r rune) bool { return r < 'A' || r > 'Z' }) {
+					return nil, fmt.Errorf("invalid method %q in entry %q", method, strings.TrimSpace(entry))
+				}
+				rule.Methods = append(rule.Methods, method)
+			}
+		}
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid path %q, must start with /", path)
+		}
+		if strings.HasSuffix(path, "*") {
+			rule.Prefix = true
+			path = strings.TrimSuffix(path, "*")
+		}
+		if strings.Contains(path, "*") {
+			return nil, fmt.Errorf("invalid path %q, * is only allowed at the end", path)
+		}
+		rule.Path = path
+		list = append(list, rule)
+	}
+	return list, nil
+}
+
+// Check decides whether a request may be proxied. For MethodNotAllowed it also returns the
+// methods allowed on the path, for the Allow header.
+func (a Allowlist) Check(method, path string) (Decision, []string) {
+	if len(a) == 0 {
+		return Allowed, nil
+	}
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	pathMatched := false
+	allowed := make(map[string]struct{})
+	for _, rule := range a {
+		if !rule.matchPath(path) {
+			continue
+		}
+		pathMatched = true
+		if len(rule.Methods) == 0 {
+			return Allowed, nil
+		}
+		for _, m := range rule.Methods {
+			if m == method {
+				return Allowed, nil
+			}
+			allowed[m] = struct{}{}
+		}
+	}
+	if !pathMatched {
+		return PathNotAllowed, nil
+	}
+
+	methods := make([]string, 0, len(allowed))
+	for m := range allowed {
+		methods = append(methods, m)
+	}
+	sort.Strings(methods)
+	return MethodNotAllowed, methods
+}
+
+func (r Rule) matchPath(path string) bool {
+	if r.Prefix {
+		return strings.HasPrefix(path, r.Path)
+	}
+	return path == strings.TrimSuffix(r.Path, "/") || path == r.Path
+}
@@ -0,0 +1,42 @@
+package endpoints
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAllowlistCheck(t *testing.T) {
+	list, err := Parse("POST /v1/chat/completions, GET /v1/models\nGET|POST /v1beta/models/*")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	tests := []struct {
+		method, path string
+		want         Decision
+		allow        []string
+	}{
+		{"POST", "/v1/chat/completions", Allowed, nil},
+		{"GET", "/v1/models/", Allowed, nil},
+		{"POST", "/v1beta/models/gemini-pro:generateContent", Allowed, nil},
+		{"GET", "/v1/chat/completions", MethodNotAllowed, []string{"POST"}},
+		{"POST", "/v1/files", PathNotAllowed, nil},
+		{"POST", "/v1/fine_tuning/jobs", PathNotAllowed, nil},
+	}
+	for _, tt := range tests {
+		got, allow := list.Check(tt.method, tt.path)
+		if got != tt.want || !reflect.DeepEqual(allow, tt.allow) {
+			t.Errorf("Check(%s %s) = %v, %v, want %v, %v", tt.method, tt.path, got, allow, tt.want, tt.allow)
+		}
+	}
+
+	if got, _ := Allowlist(nil).Check("DELETE", "/anything"); got != Allowed {
+		t.Errorf("empty allowlist should allow everything, got %v", got)
+	}
+
+	for _, spec := range []string{"POST v1/chat", "POST /v1/* /x", "P0ST /v1", "GET /v1/*/models"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) should fail", spec)
+		}
+	}
+}
@@ -24,20 +24,39 @@ func (e *APIError) Error() string {
 
 // Predefined API errors
 var (
-	ErrBadRequest         = &APIError{HTTPStatus: http.StatusBadRequest, Code: "BAD_REQUEST", Message: "Invalid request parameters"}
-	ErrInvalidJSON        = &APIError{HTTPStatus: http.StatusBadRequest, Code: "INVALID_JSON", Message: "Invalid JSON format"}
-	ErrValidation         = &APIError{HTTPStatus: http.StatusBadRequest, Code: "VALIDATION_FAILED", Message: "Input validation failed"}
-	ErrDuplicateResource  = &APIError{HTTPStatus: http.StatusConflict, Code: "DUPLICATE_RESOURCE", Message: "Resource already exists"}
-	ErrResourceNotFound   = &APIError{HTTPStatus: http.StatusNotFound, Code: "NOT_FOUND", Message: "Resource not found"}
-	ErrInternalServer     = &APIError{HTTPStatus: http.StatusInternalServerError, Code: "INTERNAL_SERVER_ERROR", Message: "An unexpected error occurred"}
-	ErrDatabase           = &APIError{HTTPStatus: http.StatusInternalServerError, Code: "DATABASE_ERROR", Message: "Database operation failed"}
-	ErrUnauthorized       = &APIError{HTTPStatus: http.StatusUnauthorized, Code: "UNAUTHORIZED", Message: "Authentication failed"}
-	ErrForbidden          = &APIError{HTTPStatus: http.StatusForbidden, Code: "FORBIDDEN", Message: "You do not have permission to access this resource"}
-	ErrTaskInProgress     = &APIError{HTTPStatus: http.StatusConflict, Code: "TASK_IN_PROGRESS", Message: "A task is already in progress"}
-	ErrBadGateway         = &APIError{HTTPStatus: http.StatusBadGateway, Code: "BAD_GATEWAY", Message: "Upstream service error"}
-	ErrNoActiveKeys       = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
-	ErrMaxRetriesExceeded = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
-	ErrNoKeysAvailable    = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrBadRequest              = &APIError{HTTPStatus: http.StatusBadRequest, Code: "BAD_REQUEST", Message: "Invalid request parameters"}
+	ErrInvalidJSON             = &APIError{HTTPStatus: http.StatusBadRequest, Code: "INVALID_JSON", Message: "Invalid JSON format"}
+	ErrValidation              = &APIError{HTTPStatus: http.StatusBadRequest, Code: "VALIDATION_FAILED", Message: "Input validation failed"}
+	ErrDuplicateResource       = &APIError{HTTPStatus: http.StatusConflict, Code: "DUPLICATE_RESOURCE", Message: "Resource already exists"}
+	ErrResourceNotFound        = &APIError{HTTPStatus: http.StatusNotFound, Code: "NOT_FOUND", Message: "Resource not found"}
+	ErrInternalServer          = &APIError{HTTPStatus: http.StatusInternalServerError, Code: "INTERNAL_SERVER_ERROR", Message: "An unexpected error occurred"}
+	ErrDatabase                = &APIError{HTTPStatus: http.StatusInternalServerError, Code: "DATABASE_ERROR", Message: "Database operation failed"}
+	ErrUnauthorized            = &APIError{HTTPStatus: http.StatusUnauthorized, Code: "UNAUTHORIZED", Message: "Authentication failed"}
+	ErrForbidden               = &APIError{HTTPStatus: http.StatusForbidden, Code: "FORBIDDEN", Message: "You do not have permission to access this resource"}
+	ErrTaskInProgress          = &APIError{HTTPStatus: http.StatusConflict, Code: "TASK_IN_PROGRESS", Message: "A task is already in progress"}
+	ErrBadGateway              = &APIError{HTTPStatus: http.StatusBadGateway, Code: "BAD_GATEWAY", Message: "Upstream service error"}
+	ErrNoActiveKeys            = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_ACTIVE_KEYS", Message: "No active API keys available for this group"}
+	ErrMaxRetriesExceeded      = &APIError{HTTPStatus: http.StatusBadGateway, Code: "MAX_RETRIES_EXCEEDED", Message: "Request failed after maximum retries"}
+	ErrNoKeysAvailable         = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "NO_KEYS_AVAILABLE", Message: "No API keys available to process the request"}
+	ErrRequestDeadlineExceeded = &APIError{HTTPStatus: http.StatusGatewayTimeout, Code: "REQUEST_DEADLINE_EXCEEDED", Message: "The request deadline was reached before the upstream responded"}
+	ErrEndpointNotAllowed      = &APIError{HTTPStatus: http.StatusNotFound, Code: "ENDPOINT_NOT_ALLOWED", Message: "This endpoint is not allowed for the group"}
+	ErrMethodNotAllowed        = &APIError{HTTPStatus: http.StatusMethodNotAllowed, Code: "METHOD_NOT_ALLOWED", Message: "This method is not allowed on the endpoint for the group"}
+	ErrModelNotAllowed         = &APIError{HTTPStatus: http.StatusForbidden, Code: "MODEL_NOT_ALLOWED", Message: "This model is not allowed for the group"}
+	ErrIPNotAllowed            = &APIError{HTTPStatus: http.StatusForbidden, Code: "IP_NOT_ALLOWED", Message: "Your IP address is not allowed to use this group"}
+	ErrIPRateLimited           = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "IP_RATE_LIMITED", Message: "Too many requests from your IP address"}
+	ErrIPBanned                = &APIError{HTTPStatus: http.StatusForbidden, Code: "IP_BANNED", Message: "Your IP address is temporarily banned after repeated authentication failures"}
+	ErrRequestPolicyViolation  = &APIError{HTTPStatus: http.StatusBadRequest, Code: "REQUEST_POLICY_VIOLATION", Message: "The request exceeds the limits of the group"}
+	ErrContentBlocked          = &APIError{HTTPStatus: http.StatusBadRequest, Code: "CONTENT_BLOCKED", Message: "The request was blocked by content moderation"}
+	ErrModerationUnavailable   = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "MODERATION_UNAVAILABLE", Message: "The content moderation endpoint is unavailable"}
+	ErrServerDraining          = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "SERVER_DRAINING", Message: "The server is shutting down and no longer accepts new requests"}
+	ErrGroupDisabled           = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "GROUP_DISABLED", Message: "This group is under maintenance"}
+	ErrRequestCancelled        = &APIError{HTTPStatus: http.StatusServiceUnavailable, Code: "REQUEST_CANCELLED", Message: "The request was cancelled by an administrator"}
+	ErrOutsideValidationWindow = &APIError{HTTPStatus: http.StatusConflict, Code: "OUTSIDE_VALIDATION_WINDOW", Message: "Key validation of this group is limited to its validation windows"}
+	ErrTOTPRequired            = &APIError{HTTPStatus: http.StatusUnauthorized, Code: "TOTP_REQUIRED", Message: "A two-factor authentication code is required"}
+	ErrAccountLocked           = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "ACCOUNT_LOCKED", Message: "The account is locked after repeated failed logins"}
+	ErrRequestTooLarge         = &APIError{HTTPStatus: http.StatusRequestEntityTooLarge, Code: "REQUEST_TOO_LARGE", Message: "The request body exceeds the size limit of the group"}
+	ErrGroupConcurrencyLimit   = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "GROUP_CONCURRENCY_LIMIT", Message: "Too many concurrent requests for this group"}
+	ErrTooManyRequests         = &APIError{HTTPStatus: http.StatusTooManyRequests, Code: "TOO_MANY_REQUESTS", Message: "Too many concurrent requests"}
 )
 
 // NewAPIError creates a new APIError with a custom message.
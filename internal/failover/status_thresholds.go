@@ -0,0 +1,80 @@
+package failover
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// StatusThreshold is the blacklist threshold of one class of upstream status codes.
+type StatusThreshold struct {
+	// Class identifies the rule, it is the normalized status code spec, e.g. "401,403".
+	Class     string
+	Threshold int
+	matcher   StatusCodeMatcher
+}
+
+// StatusThresholds is an ordered list of per-class blacklist thresholds.
+// The zero value has no rules.
+type StatusThresholds []StatusThreshold
+
+// Match returns the first rule whose status codes include code.
+func (t StatusThresholds) Match(code int) (StatusThreshold, bool) {
+	for _, rule := range t {
+		if rule.matcher.Match(code) {
+			return rule, true
+		}
+	}
+	return StatusThreshold{}, false
+}
+
+// ParseStatusThresholds parses a semicolon-separated list of "<codes>:<threshold>" rules.
+//
+// Spec grammar:
+//   - Codes use the status code grammar of ParseStatusCodeMatcher: "401,403", "500-599"
+//   - Threshold is the number of failures of that class before the key is disabled, 0 never disables
+//   - Multiple rules separated by semicolons or newlines: "401,403:1;500-599:20"
+//
+// When a code matches several rules, the first one wins.
+func ParseStatusThresholds(spec string) (StatusThresholds, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+
+	spec = strings.ReplaceAll(spec, "\n", ";")
+
+	var thresholds StatusThresholds
+	for _, raw := range strings.Split(spec, ";") {
+		rule := strings.TrimSpace(raw)
+		if rule == "" {
+			continue
+		}
+
+		codes, thresholdStr, ok := strings.Cut(rule, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid threshold rule %q: expected <codes>:<threshold>", rule)
+		}
+
+		threshold, err := strconv.Atoi(strings.TrimSpace(thresholdStr))
+		if err != nil || threshold < 0 {
+			return nil, fmt.Errorf("invalid threshold in rule %q: must be a non-negative integer", rule)
+		}
+
+		matcher, err := ParseStatusCodeMatcher(codes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid status codes in rule %q: %w", rule, err)
+		}
+		if matcher.IsEmpty() {
+			return nil, fmt.Errorf("invalid threshold rule %q: no status codes", rule)
+		}
+
+		thresholds = append(thresholds, StatusThreshold{
+			Class:     strings.ReplaceAll(strings.TrimSpace(codes), " ", ""),
+			Threshold: threshold,
+			matcher:   matcher,
+		})
+	}
+
+	return thresholds, nil
+}
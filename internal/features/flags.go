@@ -0,0 +1,92 @@
+// Package features holds the per-group feature flags that gate risky proxy behaviors,
+// so that they can ship disabled and be turned on group by group.
+package features
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Flag names.
+const (
+	// ResponseCache serves identical non-streaming requests from the response cache.
+	ResponseCache = "response_cache"
+	// BodyTransforms applies the request and response translation of channels that define one.
+	BodyTransforms = "body_transforms"
+	// RateLimitCooldown paces rate limited keys until their upstream quota resets.
+	RateLimitCooldown = "rate_limit_cooldown"
+	// FormatTranslation serves OpenAI chat completion clients from Anthropic upstreams and
+	// Anthropic messages clients from OpenAI upstreams, translating requests and responses.
+	FormatTranslation = "format_translation"
+)
+
+// Flag describes a feature that groups can switch on or off.
+type Flag struct {
+	Name string `json:"name"`
+	// Description is an i18n message key.
+	Description string `json:"description"`
+	// Default applies to groups that do not set the flag.
+	Default bool `json:"default"`
+}
+
+var registry = map[string]Flag{
+	ResponseCache:     {Name: ResponseCache, Description: "feature.response_cache", Default: true},
+	BodyTransforms:    {Name: BodyTransforms, Description: "feature.body_transforms", Default: true},
+	RateLimitCooldown: {Name: RateLimitCooldown, Description: "feature.rate_limit_cooldown", Default: true},
+	FormatTranslation: {Name: FormatTranslation, Description: "feature.format_translation", Default: false},
+}
+
+// All returns every registered flag sorted by name.
+func All() []Flag {
+	flags := make([]Flag, 0, len(registry))
+	for _, flag := range registry {
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+// Flags holds the flags a group sets explicitly. The zero value uses every default.
+type Flags map[string]bool
+
+// Enabled reports whether a flag is on, falling back to its default. Unknown flags are off.
+func (f Flags) Enabled(name string) bool {
+	if enabled, ok := f[name]; ok {
+		return enabled
+	}
+	return registry[name].Default
+}
+
+// Parse converts the feature_flags value of a group config into Flags, rejecting unknown
+// flags and non-boolean values.
+func Parse(value any) (Flags, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	var raw map[string]any
+	switch v := value.(type) {
+	case map[string]any:
+		raw = v
+	case map[string]bool:
+		raw = make(map[string]any, len(v))
+		for name, enabled := range v {
+			raw[name] = enabled
+		}
+	default:
+		return nil, fmt.Errorf("feature_flags must be an object of flag names to booleans")
+	}
+
+	flags := make(Flags, len(raw))
+	for name, enabled := range raw {
+		if _, ok := registry[name]; !ok {
+			return nil, fmt.Errorf("unknown feature flag %q", name)
+		}
+		b, ok := enabled.(bool)
+		if !ok {
+			return nil, fmt.Errorf("feature flag %q must be true or false", name)
+		}
+		flags[name] = b
+	}
+	return flags, nil
+}
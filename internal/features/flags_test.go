@@ -0,0 +1,25 @@
+package features
+
+import "testing"
+
+func TestFlags(t *testing.T) {
+	flags, err := Parse(map[string]any{ResponseCache: false})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if flags.Enabled(ResponseCache) {
+		t.Error("response_cache should be disabled by the group")
+	}
+	if !flags.Enabled(BodyTransforms) {
+		t.Error("body_transforms should fall back to its default")
+	}
+	if Flags(nil).Enabled("unknown") {
+		t.Error("unknown flags should be off")
+	}
+
+	for _, value := range []any{map[string]any{"unknown": true}, map[string]any{ResponseCache: "yes"}, []string{ResponseCache}} {
+		if _, err := Parse(value); err == nil {
+			t.Errorf("Parse(%v) should fail", value)
+		}
+	}
+}
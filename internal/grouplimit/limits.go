@@ -0,0 +1,113 @@
+// Package grouplimit caps the requests each group has in flight, queueing those over the cap.
+package grouplimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limits holds the limiter of each group with requests in flight or queued. A limiter takes
+// the limits last asked for its group, keeping the requests it has in flight when they change,
+// and is dropped once idle, so groups that are deleted leave nothing behind.
+type Limits struct {
+	mu     sync.Mutex
+	groups map[uint]*limiter
+}
+
+type limiter struct {
+	capacity int
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// New creates an empty Limits.
+func New() *Limits {
+	return &Limits{groups: make(map[uint]*limiter)}
+}
+
+// Acquire takes an in-flight slot of the group, waiting up to timeout in its queue when
+// capacity slots are taken. At most maxQueued requests wait, 0 for no limit, and with a zero
+// timeout requests over the cap are rejected at once. A capacity of 0 or less means no cap.
+// It reports false if no slot was obtained; otherwise release must be called once done.
+func (l *Limits) Acquire(ctx context.Context, group uint, capacity, maxQueued int, timeout time.Duration) (release func(), ok bool) {
+	l.mu.Lock()
+	if capacity <= 0 {
+		// The cap was lifted, admit the requests still waiting for it
+		if lim, exists := l.groups[group]; exists {
+			lim.capacity = lim.inFlight + len(lim.waiters)
+			l.admitLocked(lim)
+		}
+		l.mu.Unlock()
+		return func() {}, true
+	}
+	lim, exists := l.groups[group]
+	if !exists {
+		lim = &limiter{}
+		l.groups[group] = lim
+	}
+	lim.capacity = capacity
+	// A raised capacity admits the waiting requests it has room for
+	l.admitLocked(lim)
+	release = func() { l.release(group, lim) }
+
+	if lim.inFlight < lim.capacity && len(lim.waiters) == 0 {
+		lim.inFlight++
+		l.mu.Unlock()
+		return release, true
+	}
+	if timeout <= 0 || (maxQueued > 0 && len(lim.waiters) >= maxQueued) {
+		l.pruneLocked(group, lim)
+		l.mu.Unlock()
+		return nil, false
+	}
+	ready := make(chan struct{})
+	lim.waiters = append(lim.waiters, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-ready:
+		return release, true
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, w := range lim.waiters {
+		if w == ready {
+			lim.waiters = append(lim.waiters[:i], lim.waiters[i+1:]...)
+			l.pruneLocked(group, lim)
+			return nil, false
+		}
+	}
+	// The slot was handed over while giving up, keep it so the caller releases it
+	return release, true
+}
+
+// release frees a slot, handing it to the longest waiting request if the capacity allows.
+func (l *Limits) release(group uint, lim *limiter) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lim.inFlight--
+	l.admitLocked(lim)
+	l.pruneLocked(group, lim)
+}
+
+// admitLocked hands the free slots of lim to its waiting requests in arrival order.
+func (l *Limits) admitLocked(lim *limiter) {
+	for lim.inFlight < lim.capacity && len(lim.waiters) > 0 {
+		lim.inFlight++
+		close(lim.waiters[0])
+		lim.waiters = lim.waiters[1:]
+	}
+}
+
+// pruneLocked drops the limiter of a group once it has nothing in flight or queued.
+func (l *Limits) pruneLocked(group uint, lim *limiter) {
+	if lim.inFlight == 0 && len(lim.waiters) == 0 && l.groups[group] == lim {
+		delete(l.groups, group)
+	}
+}
@@ -0,0 +1,122 @@
+package grouplimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAcquireRejectsWithoutQueue(t *testing.T) {
+	l := New()
+	release, ok := l.Acquire(context.Background(), 1, 1, 0, 0)
+	if !ok {
+		t.Fatal("first acquire should succeed")
+	}
+	if _, ok := l.Acquire(context.Background(), 1, 1, 0, 0); ok {
+		t.Fatal("acquire over the cap should be rejected when queueing is off")
+	}
+	if _, ok := l.Acquire(context.Background(), 2, 1, 0, 0); !ok {
+		t.Fatal("the cap of a group should not limit other groups")
+	}
+	release()
+	if _, ok := l.Acquire(context.Background(), 1, 1, 0, 0); !ok {
+		t.Fatal("acquire after release should succeed")
+	}
+}
+
+func TestAcquireBoundedQueue(t *testing.T) {
+	l := New()
+	release, _ := l.Acquire(context.Background(), 1, 1, 1, time.Minute)
+
+	admitted := make(chan bool, 1)
+	go func() {
+		_, ok := l.Acquire(context.Background(), 1, 1, 1, time.Minute)
+		admitted <- ok
+	}()
+	waitQueued(t, l, 1, 1)
+
+	if _, ok := l.Acquire(context.Background(), 1, 1, 1, time.Minute); ok {
+		t.Fatal("acquire should be rejected while the queue is full")
+	}
+	release()
+	if !<-admitted {
+		t.Fatal("queued request should take the released slot")
+	}
+}
+
+func TestAcquireTimeout(t *testing.T) {
+	l := New()
+	l.Acquire(context.Background(), 1, 1, 0, 20*time.Millisecond)
+	if _, ok := l.Acquire(context.Background(), 1, 1, 0, 20*time.Millisecond); ok {
+		t.Fatal("acquire should time out while the slot is held")
+	}
+	waitQueued(t, l, 1, 0)
+}
+
+func TestAcquireKeepsInFlightAcrossLimitChanges(t *testing.T) {
+	l := New()
+	l.Acquire(context.Background(), 1, 2, 0, 0)
+	l.Acquire(context.Background(), 1, 2, 0, 0)
+	if _, ok := l.Acquire(context.Background(), 1, 1, 0, 0); ok {
+		t.Fatal("a lowered cap should count the requests already in flight")
+	}
+	if _, ok := l.Acquire(context.Background(), 1, 3, 0, 0); !ok {
+		t.Fatal("a raised cap should admit requests up to it")
+	}
+	if _, ok := l.Acquire(context.Background(), 1, 3, 0, 0); ok {
+		t.Fatal("the raised cap should still count the requests in flight")
+	}
+}
+
+func TestAcquireRaisedCapAdmitsWaiters(t *testing.T) {
+	l := New()
+	l.Acquire(context.Background(), 1, 1, 0, time.Minute)
+
+	admitted := make(chan bool, 1)
+	go func() {
+		_, ok := l.Acquire(context.Background(), 1, 1, 0, time.Minute)
+		admitted <- ok
+	}()
+	waitQueued(t, l, 1, 1)
+
+	l.Acquire(context.Background(), 1, 3, 0, time.Minute)
+	if !<-admitted {
+		t.Fatal("a waiting request should be admitted once the cap is raised")
+	}
+}
+
+func TestIdleGroupsArePruned(t *testing.T) {
+	l := New()
+	release, _ := l.Acquire(context.Background(), 1, 1, 0, 0)
+	l.Acquire(context.Background(), 1, 1, 0, 0)
+	l.Acquire(context.Background(), 2, 1, 0, 10*time.Millisecond)
+	l.Acquire(context.Background(), 2, 1, 0, 10*time.Millisecond)
+	release()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.groups[1]; ok {
+		t.Error("limiter of a group with nothing in flight should be dropped")
+	}
+	if _, ok := l.groups[2]; !ok {
+		t.Error("limiter of a group with a request in flight should be kept")
+	}
+}
+
+func waitQueued(t *testing.T, l *Limits, group uint, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		l.mu.Lock()
+		queued := 0
+		if lim := l.groups[group]; lim != nil {
+			queued = len(lim.waiters)
+		}
+		l.mu.Unlock()
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d queued requests", n)
+}
@@ -0,0 +1,1991 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: admin.proto
+
+package adminv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Upstream struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Url           string                 `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Weight        int32                  `protobuf:"varint,2,opt,name=weight,proto3" json:"weight,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Upstream) Reset() {
+	*x = Upstream{}
+	mi := &file_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Upstream) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Upstream) ProtoMessage() {}
+
+func (x *Upstream) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Upstream.ProtoReflect.Descriptor instead.
+func (*Upstream) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Upstream) GetUrl() string {
+	if x != nil {
+		return x.Url
+	}
+	return ""
+}
+
+func (x *Upstream) GetWeight() int32 {
+	if x != nil {
+		return x.Weight
+	}
+	return 0
+}
+
+type Group struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name        string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	DisplayName string                 `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Description string                 `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	// "standard" or "aggregate".
+	GroupType          string      `protobuf:"bytes,5,opt,name=group_type,json=groupType,proto3" json:"group_type,omitempty"`
+	ChannelType        string      `protobuf:"bytes,6,opt,name=channel_type,json=channelType,proto3" json:"channel_type,omitempty"`
+	Upstreams          []*Upstream `protobuf:"bytes,7,rep,name=upstreams,proto3" json:"upstreams,omitempty"`
+	TestModel          string      `protobuf:"bytes,8,opt,name=test_model,json=testModel,proto3" json:"test_model,omitempty"`
+	ValidationEndpoint string      `protobuf:"bytes,9,opt,name=validation_endpoint,json=validationEndpoint,proto3" json:"validation_endpoint,omitempty"`
+	Sort               int32       `protobuf:"varint,10,opt,name=sort,proto3" json:"sort,omitempty"`
+	Enabled            bool        `protobuf:"varint,11,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	MaintenanceMessage string      `protobuf:"bytes,12,opt,name=maintenance_message,json=maintenanceMessage,proto3" json:"maintenance_message,omitempty"`
+	ProxyKeys          string      `protobuf:"bytes,13,opt,name=proxy_keys,json=proxyKeys,proto3" json:"proxy_keys,omitempty"`
+	// Group configuration overriding the system settings.
+	Config     *structpb.Struct `protobuf:"bytes,14,opt,name=config,proto3" json:"config,omitempty"`
+	ExternalId string           `protobuf:"bytes,15,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	// Proxy endpoint of the group.
+	Endpoint      string                 `protobuf:"bytes,16,opt,name=endpoint,proto3" json:"endpoint,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,17,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,18,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Group) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Group) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Group) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *Group) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Group) GetGroupType() string {
+	if x != nil {
+		return x.GroupType
+	}
+	return ""
+}
+
+func (x *Group) GetChannelType() string {
+	if x != nil {
+		return x.ChannelType
+	}
+	return ""
+}
+
+func (x *Group) GetUpstreams() []*Upstream {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
+func (x *Group) GetTestModel() string {
+	if x != nil {
+		return x.TestModel
+	}
+	return ""
+}
+
+func (x *Group) GetValidationEndpoint() string {
+	if x != nil {
+		return x.ValidationEndpoint
+	}
+	return ""
+}
+
+func (x *Group) GetSort() int32 {
+	if x != nil {
+		return x.Sort
+	}
+	return 0
+}
+
+func (x *Group) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Group) GetMaintenanceMessage() string {
+	if x != nil {
+		return x.MaintenanceMessage
+	}
+	return ""
+}
+
+func (x *Group) GetProxyKeys() string {
+	if x != nil {
+		return x.ProxyKeys
+	}
+	return ""
+}
+
+func (x *Group) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *Group) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+func (x *Group) GetEndpoint() string {
+	if x != nil {
+		return x.Endpoint
+	}
+	return ""
+}
+
+func (x *Group) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Group) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListGroupsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsRequest) Reset() {
+	*x = ListGroupsRequest{}
+	mi := &file_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsRequest) ProtoMessage() {}
+
+func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{2}
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []*Group               `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListGroupsResponse) GetGroups() []*Group {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+type GetGroupRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Group:
+	//
+	//	*GetGroupRequest_Id
+	//	*GetGroupRequest_Name
+	//	*GetGroupRequest_ExternalId
+	Group         isGetGroupRequest_Group `protobuf_oneof:"group"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupRequest) Reset() {
+	*x = GetGroupRequest{}
+	mi := &file_admin_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupRequest) ProtoMessage() {}
+
+func (x *GetGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupRequest.ProtoReflect.Descriptor instead.
+func (*GetGroupRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *GetGroupRequest) GetGroup() isGetGroupRequest_Group {
+	if x != nil {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *GetGroupRequest) GetId() uint64 {
+	if x != nil {
+		if x, ok := x.Group.(*GetGroupRequest_Id); ok {
+			return x.Id
+		}
+	}
+	return 0
+}
+
+func (x *GetGroupRequest) GetName() string {
+	if x != nil {
+		if x, ok := x.Group.(*GetGroupRequest_Name); ok {
+			return x.Name
+		}
+	}
+	return ""
+}
+
+func (x *GetGroupRequest) GetExternalId() string {
+	if x != nil {
+		if x, ok := x.Group.(*GetGroupRequest_ExternalId); ok {
+			return x.ExternalId
+		}
+	}
+	return ""
+}
+
+type isGetGroupRequest_Group interface {
+	isGetGroupRequest_Group()
+}
+
+type GetGroupRequest_Id struct {
+	Id uint64 `protobuf:"varint,1,opt,name=id,proto3,oneof"`
+}
+
+type GetGroupRequest_Name struct {
+	Name string `protobuf:"bytes,2,opt,name=name,proto3,oneof"`
+}
+
+type GetGroupRequest_ExternalId struct {
+	ExternalId string `protobuf:"bytes,3,opt,name=external_id,json=externalId,proto3,oneof"`
+}
+
+func (*GetGroupRequest_Id) isGetGroupRequest_Group() {}
+
+func (*GetGroupRequest_Name) isGetGroupRequest_Group() {}
+
+func (*GetGroupRequest_ExternalId) isGetGroupRequest_Group() {}
+
+type CreateGroupRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Name               string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	DisplayName        string                 `protobuf:"bytes,2,opt,name=display_name,json=displayName,proto3" json:"display_name,omitempty"`
+	Description        string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	GroupType          string                 `protobuf:"bytes,4,opt,name=group_type,json=groupType,proto3" json:"group_type,omitempty"`
+	ChannelType        string                 `protobuf:"bytes,5,opt,name=channel_type,json=channelType,proto3" json:"channel_type,omitempty"`
+	Upstreams          []*Upstream            `protobuf:"bytes,6,rep,name=upstreams,proto3" json:"upstreams,omitempty"`
+	TestModel          string                 `protobuf:"bytes,7,opt,name=test_model,json=testModel,proto3" json:"test_model,omitempty"`
+	ValidationEndpoint string                 `protobuf:"bytes,8,opt,name=validation_endpoint,json=validationEndpoint,proto3" json:"validation_endpoint,omitempty"`
+	Sort               int32                  `protobuf:"varint,9,opt,name=sort,proto3" json:"sort,omitempty"`
+	ProxyKeys          string                 `protobuf:"bytes,10,opt,name=proxy_keys,json=proxyKeys,proto3" json:"proxy_keys,omitempty"`
+	Config             *structpb.Struct       `protobuf:"bytes,11,opt,name=config,proto3" json:"config,omitempty"`
+	ExternalId         string                 `protobuf:"bytes,12,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *CreateGroupRequest) Reset() {
+	*x = CreateGroupRequest{}
+	mi := &file_admin_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGroupRequest) ProtoMessage() {}
+
+func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupRequest.ProtoReflect.Descriptor instead.
+func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CreateGroupRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetDisplayName() string {
+	if x != nil {
+		return x.DisplayName
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetGroupType() string {
+	if x != nil {
+		return x.GroupType
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetChannelType() string {
+	if x != nil {
+		return x.ChannelType
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetUpstreams() []*Upstream {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
+func (x *CreateGroupRequest) GetTestModel() string {
+	if x != nil {
+		return x.TestModel
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetValidationEndpoint() string {
+	if x != nil {
+		return x.ValidationEndpoint
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetSort() int32 {
+	if x != nil {
+		return x.Sort
+	}
+	return 0
+}
+
+func (x *CreateGroupRequest) GetProxyKeys() string {
+	if x != nil {
+		return x.ProxyKeys
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *CreateGroupRequest) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+type UpstreamList struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Upstreams     []*Upstream            `protobuf:"bytes,1,rep,name=upstreams,proto3" json:"upstreams,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpstreamList) Reset() {
+	*x = UpstreamList{}
+	mi := &file_admin_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpstreamList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpstreamList) ProtoMessage() {}
+
+func (x *UpstreamList) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpstreamList.ProtoReflect.Descriptor instead.
+func (*UpstreamList) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpstreamList) GetUpstreams() []*Upstream {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
+type UpdateGroupRequest struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Id                 uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name               *string                `protobuf:"bytes,2,opt,name=name,proto3,oneof" json:"name,omitempty"`
+	DisplayName        *string                `protobuf:"bytes,3,opt,name=display_name,json=displayName,proto3,oneof" json:"display_name,omitempty"`
+	Description        *string                `protobuf:"bytes,4,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	ChannelType        *string                `protobuf:"bytes,5,opt,name=channel_type,json=channelType,proto3,oneof" json:"channel_type,omitempty"`
+	Upstreams          *UpstreamList          `protobuf:"bytes,6,opt,name=upstreams,proto3" json:"upstreams,omitempty"`
+	TestModel          *string                `protobuf:"bytes,7,opt,name=test_model,json=testModel,proto3,oneof" json:"test_model,omitempty"`
+	ValidationEndpoint *string                `protobuf:"bytes,8,opt,name=validation_endpoint,json=validationEndpoint,proto3,oneof" json:"validation_endpoint,omitempty"`
+	Sort               *int32                 `protobuf:"varint,9,opt,name=sort,proto3,oneof" json:"sort,omitempty"`
+	Enabled            *bool                  `protobuf:"varint,10,opt,name=enabled,proto3,oneof" json:"enabled,omitempty"`
+	MaintenanceMessage *string                `protobuf:"bytes,11,opt,name=maintenance_message,json=maintenanceMessage,proto3,oneof" json:"maintenance_message,omitempty"`
+	ProxyKeys          *string                `protobuf:"bytes,12,opt,name=proxy_keys,json=proxyKeys,proto3,oneof" json:"proxy_keys,omitempty"`
+	// Replaces the whole group configuration when set.
+	Config        *structpb.Struct `protobuf:"bytes,13,opt,name=config,proto3" json:"config,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateGroupRequest) Reset() {
+	*x = UpdateGroupRequest{}
+	mi := &file_admin_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateGroupRequest) ProtoMessage() {}
+
+func (x *UpdateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateGroupRequest.ProtoReflect.Descriptor instead.
+func (*UpdateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UpdateGroupRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UpdateGroupRequest) GetName() string {
+	if x != nil && x.Name != nil {
+		return *x.Name
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetDisplayName() string {
+	if x != nil && x.DisplayName != nil {
+		return *x.DisplayName
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetChannelType() string {
+	if x != nil && x.ChannelType != nil {
+		return *x.ChannelType
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetUpstreams() *UpstreamList {
+	if x != nil {
+		return x.Upstreams
+	}
+	return nil
+}
+
+func (x *UpdateGroupRequest) GetTestModel() string {
+	if x != nil && x.TestModel != nil {
+		return *x.TestModel
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetValidationEndpoint() string {
+	if x != nil && x.ValidationEndpoint != nil {
+		return *x.ValidationEndpoint
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetSort() int32 {
+	if x != nil && x.Sort != nil {
+		return *x.Sort
+	}
+	return 0
+}
+
+func (x *UpdateGroupRequest) GetEnabled() bool {
+	if x != nil && x.Enabled != nil {
+		return *x.Enabled
+	}
+	return false
+}
+
+func (x *UpdateGroupRequest) GetMaintenanceMessage() string {
+	if x != nil && x.MaintenanceMessage != nil {
+		return *x.MaintenanceMessage
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetProxyKeys() string {
+	if x != nil && x.ProxyKeys != nil {
+		return *x.ProxyKeys
+	}
+	return ""
+}
+
+func (x *UpdateGroupRequest) GetConfig() *structpb.Struct {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type DeleteGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteGroupRequest) Reset() {
+	*x = DeleteGroupRequest{}
+	mi := &file_admin_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteGroupRequest) ProtoMessage() {}
+
+func (x *DeleteGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteGroupRequest.ProtoReflect.Descriptor instead.
+func (*DeleteGroupRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteGroupRequest) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteGroupResponse) Reset() {
+	*x = DeleteGroupResponse{}
+	mi := &file_admin_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteGroupResponse) ProtoMessage() {}
+
+func (x *DeleteGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteGroupResponse.ProtoReflect.Descriptor instead.
+func (*DeleteGroupResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{9}
+}
+
+type Key struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Id      uint64                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	GroupId uint64                 `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// Set only when the listing asked for values.
+	KeyValue      string                 `protobuf:"bytes,3,opt,name=key_value,json=keyValue,proto3" json:"key_value,omitempty"`
+	KeyHash       string                 `protobuf:"bytes,4,opt,name=key_hash,json=keyHash,proto3" json:"key_hash,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"`
+	Notes         string                 `protobuf:"bytes,6,opt,name=notes,proto3" json:"notes,omitempty"`
+	Tags          []string               `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
+	RequestCount  int64                  `protobuf:"varint,8,opt,name=request_count,json=requestCount,proto3" json:"request_count,omitempty"`
+	FailureCount  int64                  `protobuf:"varint,9,opt,name=failure_count,json=failureCount,proto3" json:"failure_count,omitempty"`
+	LastUsedAt    *timestamppb.Timestamp `protobuf:"bytes,10,opt,name=last_used_at,json=lastUsedAt,proto3" json:"last_used_at,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	ExternalId    string                 `protobuf:"bytes,12,opt,name=external_id,json=externalId,proto3" json:"external_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Key) Reset() {
+	*x = Key{}
+	mi := &file_admin_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Key) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Key) ProtoMessage() {}
+
+func (x *Key) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Key.ProtoReflect.Descriptor instead.
+func (*Key) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *Key) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Key) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *Key) GetKeyValue() string {
+	if x != nil {
+		return x.KeyValue
+	}
+	return ""
+}
+
+func (x *Key) GetKeyHash() string {
+	if x != nil {
+		return x.KeyHash
+	}
+	return ""
+}
+
+func (x *Key) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Key) GetNotes() string {
+	if x != nil {
+		return x.Notes
+	}
+	return ""
+}
+
+func (x *Key) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Key) GetRequestCount() int64 {
+	if x != nil {
+		return x.RequestCount
+	}
+	return 0
+}
+
+func (x *Key) GetFailureCount() int64 {
+	if x != nil {
+		return x.FailureCount
+	}
+	return 0
+}
+
+func (x *Key) GetLastUsedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUsedAt
+	}
+	return nil
+}
+
+func (x *Key) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Key) GetExternalId() string {
+	if x != nil {
+		return x.ExternalId
+	}
+	return ""
+}
+
+type ListKeysRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	GroupId uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	// Key status such as "active" or "invalid", all keys when empty.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	// Limits the list to the keys carrying all of these tags.
+	Tags []string `protobuf:"bytes,3,rep,name=tags,proto3" json:"tags,omitempty"`
+	// 100 by default, at most 1000.
+	PageSize int32 `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// next_page_token of the previous page.
+	PageToken string `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// Includes the key values, masked at the key_masking level.
+	IncludeValues bool `protobuf:"varint,6,opt,name=include_values,json=includeValues,proto3" json:"include_values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListKeysRequest) Reset() {
+	*x = ListKeysRequest{}
+	mi := &file_admin_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeysRequest) ProtoMessage() {}
+
+func (x *ListKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListKeysRequest.ProtoReflect.Descriptor instead.
+func (*ListKeysRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ListKeysRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *ListKeysRequest) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *ListKeysRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ListKeysRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListKeysRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListKeysRequest) GetIncludeValues() bool {
+	if x != nil {
+		return x.IncludeValues
+	}
+	return false
+}
+
+type ListKeysResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Keys  []*Key                 `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	// Empty on the last page.
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListKeysResponse) Reset() {
+	*x = ListKeysResponse{}
+	mi := &file_admin_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListKeysResponse) ProtoMessage() {}
+
+func (x *ListKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListKeysResponse.ProtoReflect.Descriptor instead.
+func (*ListKeysResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *ListKeysResponse) GetKeys() []*Key {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+func (x *ListKeysResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type AddKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddKeysRequest) Reset() {
+	*x = AddKeysRequest{}
+	mi := &file_admin_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddKeysRequest) ProtoMessage() {}
+
+func (x *AddKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddKeysRequest.ProtoReflect.Descriptor instead.
+func (*AddKeysRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *AddKeysRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *AddKeysRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type AddKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AddedCount    int32                  `protobuf:"varint,1,opt,name=added_count,json=addedCount,proto3" json:"added_count,omitempty"`
+	IgnoredCount  int32                  `protobuf:"varint,2,opt,name=ignored_count,json=ignoredCount,proto3" json:"ignored_count,omitempty"`
+	TotalInGroup  int64                  `protobuf:"varint,3,opt,name=total_in_group,json=totalInGroup,proto3" json:"total_in_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddKeysResponse) Reset() {
+	*x = AddKeysResponse{}
+	mi := &file_admin_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddKeysResponse) ProtoMessage() {}
+
+func (x *AddKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddKeysResponse.ProtoReflect.Descriptor instead.
+func (*AddKeysResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *AddKeysResponse) GetAddedCount() int32 {
+	if x != nil {
+		return x.AddedCount
+	}
+	return 0
+}
+
+func (x *AddKeysResponse) GetIgnoredCount() int32 {
+	if x != nil {
+		return x.IgnoredCount
+	}
+	return 0
+}
+
+func (x *AddKeysResponse) GetTotalInGroup() int64 {
+	if x != nil {
+		return x.TotalInGroup
+	}
+	return 0
+}
+
+type DeleteKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteKeysRequest) Reset() {
+	*x = DeleteKeysRequest{}
+	mi := &file_admin_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteKeysRequest) ProtoMessage() {}
+
+func (x *DeleteKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteKeysRequest.ProtoReflect.Descriptor instead.
+func (*DeleteKeysRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteKeysRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *DeleteKeysRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type DeleteKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DeletedCount  int32                  `protobuf:"varint,1,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"`
+	IgnoredCount  int32                  `protobuf:"varint,2,opt,name=ignored_count,json=ignoredCount,proto3" json:"ignored_count,omitempty"`
+	TotalInGroup  int64                  `protobuf:"varint,3,opt,name=total_in_group,json=totalInGroup,proto3" json:"total_in_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteKeysResponse) Reset() {
+	*x = DeleteKeysResponse{}
+	mi := &file_admin_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteKeysResponse) ProtoMessage() {}
+
+func (x *DeleteKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteKeysResponse.ProtoReflect.Descriptor instead.
+func (*DeleteKeysResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *DeleteKeysResponse) GetDeletedCount() int32 {
+	if x != nil {
+		return x.DeletedCount
+	}
+	return 0
+}
+
+func (x *DeleteKeysResponse) GetIgnoredCount() int32 {
+	if x != nil {
+		return x.IgnoredCount
+	}
+	return 0
+}
+
+func (x *DeleteKeysResponse) GetTotalInGroup() int64 {
+	if x != nil {
+		return x.TotalInGroup
+	}
+	return 0
+}
+
+type RestoreKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Keys          []string               `protobuf:"bytes,2,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreKeysRequest) Reset() {
+	*x = RestoreKeysRequest{}
+	mi := &file_admin_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreKeysRequest) ProtoMessage() {}
+
+func (x *RestoreKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreKeysRequest.ProtoReflect.Descriptor instead.
+func (*RestoreKeysRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *RestoreKeysRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *RestoreKeysRequest) GetKeys() []string {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type RestoreKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RestoredCount int32                  `protobuf:"varint,1,opt,name=restored_count,json=restoredCount,proto3" json:"restored_count,omitempty"`
+	IgnoredCount  int32                  `protobuf:"varint,2,opt,name=ignored_count,json=ignoredCount,proto3" json:"ignored_count,omitempty"`
+	TotalInGroup  int64                  `protobuf:"varint,3,opt,name=total_in_group,json=totalInGroup,proto3" json:"total_in_group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreKeysResponse) Reset() {
+	*x = RestoreKeysResponse{}
+	mi := &file_admin_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreKeysResponse) ProtoMessage() {}
+
+func (x *RestoreKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreKeysResponse.ProtoReflect.Descriptor instead.
+func (*RestoreKeysResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *RestoreKeysResponse) GetRestoredCount() int32 {
+	if x != nil {
+		return x.RestoredCount
+	}
+	return 0
+}
+
+func (x *RestoreKeysResponse) GetIgnoredCount() int32 {
+	if x != nil {
+		return x.IgnoredCount
+	}
+	return 0
+}
+
+func (x *RestoreKeysResponse) GetTotalInGroup() int64 {
+	if x != nil {
+		return x.TotalInGroup
+	}
+	return 0
+}
+
+type TransitionKeysRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	GroupId uint64                 `protobuf:"varint,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	KeyIds  []uint64               `protobuf:"varint,2,rep,packed,name=key_ids,json=keyIds,proto3" json:"key_ids,omitempty"`
+	// Target status: "active", "pending", "reserve", "retiring" or "archived".
+	To            string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransitionKeysRequest) Reset() {
+	*x = TransitionKeysRequest{}
+	mi := &file_admin_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransitionKeysRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransitionKeysRequest) ProtoMessage() {}
+
+func (x *TransitionKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransitionKeysRequest.ProtoReflect.Descriptor instead.
+func (*TransitionKeysRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *TransitionKeysRequest) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *TransitionKeysRequest) GetKeyIds() []uint64 {
+	if x != nil {
+		return x.KeyIds
+	}
+	return nil
+}
+
+func (x *TransitionKeysRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+type TransitionKeysResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Moved         []uint64               `protobuf:"varint,1,rep,packed,name=moved,proto3" json:"moved,omitempty"`
+	Skipped       []uint64               `protobuf:"varint,2,rep,packed,name=skipped,proto3" json:"skipped,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransitionKeysResponse) Reset() {
+	*x = TransitionKeysResponse{}
+	mi := &file_admin_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransitionKeysResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransitionKeysResponse) ProtoMessage() {}
+
+func (x *TransitionKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransitionKeysResponse.ProtoReflect.Descriptor instead.
+func (*TransitionKeysResponse) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *TransitionKeysResponse) GetMoved() []uint64 {
+	if x != nil {
+		return x.Moved
+	}
+	return nil
+}
+
+func (x *TransitionKeysResponse) GetSkipped() []uint64 {
+	if x != nil {
+		return x.Skipped
+	}
+	return nil
+}
+
+type WatchKeyEventsRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Limits the stream to these groups, all groups when empty.
+	GroupIds      []uint64 `protobuf:"varint,1,rep,packed,name=group_ids,json=groupIds,proto3" json:"group_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchKeyEventsRequest) Reset() {
+	*x = WatchKeyEventsRequest{}
+	mi := &file_admin_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchKeyEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchKeyEventsRequest) ProtoMessage() {}
+
+func (x *WatchKeyEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchKeyEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchKeyEventsRequest) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *WatchKeyEventsRequest) GetGroupIds() []uint64 {
+	if x != nil {
+		return x.GroupIds
+	}
+	return nil
+}
+
+type KeyEvent struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// "added", "removed", "restored", "recovered", "blacklisted", "lifecycle",
+	// "reserve_promoted", "rate_limited" or "cooldown_ended".
+	Type    string   `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	GroupId uint64   `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	KeyIds  []uint64 `protobuf:"varint,3,rep,packed,name=key_ids,json=keyIds,proto3" json:"key_ids,omitempty"`
+	// Empty for added keys.
+	From string `protobuf:"bytes,4,opt,name=from,proto3" json:"from,omitempty"`
+	// Empty for removed keys.
+	To string                 `protobuf:"bytes,5,opt,name=to,proto3" json:"to,omitempty"`
+	At *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=at,proto3" json:"at,omitempty"`
+	// Number of keys of the group in rotation after the change.
+	ActiveKeys    int64 `protobuf:"varint,7,opt,name=active_keys,json=activeKeys,proto3" json:"active_keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *KeyEvent) Reset() {
+	*x = KeyEvent{}
+	mi := &file_admin_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *KeyEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*KeyEvent) ProtoMessage() {}
+
+func (x *KeyEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_admin_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use KeyEvent.ProtoReflect.Descriptor instead.
+func (*KeyEvent) Descriptor() ([]byte, []int) {
+	return file_admin_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *KeyEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *KeyEvent) GetGroupId() uint64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *KeyEvent) GetKeyIds() []uint64 {
+	if x != nil {
+		return x.KeyIds
+	}
+	return nil
+}
+
+func (x *KeyEvent) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *KeyEvent) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *KeyEvent) GetAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.At
+	}
+	return nil
+}
+
+func (x *KeyEvent) GetActiveKeys() int64 {
+	if x != nil {
+		return x.ActiveKeys
+	}
+	return 0
+}
+
+var File_admin_proto protoreflect.FileDescriptor
+
+const file_admin_proto_rawDesc = "" +
+	"\n" +
+	"\vadmin.proto\x12\x10gptload.admin.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"4\n" +
+	"\bUpstream\x12\x10\n" +
+	"\x03url\x18\x01 \x01(\tR\x03url\x12\x16\n" +
+	"\x06weight\x18\x02 \x01(\x05R\x06weight\"\x9e\x05\n" +
+	"\x05Group\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12!\n" +
+	"\fdisplay_name\x18\x03 \x01(\tR\vdisplayName\x12 \n" +
+	"\vdescription\x18\x04 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"group_type\x18\x05 \x01(\tR\tgroupType\x12!\n" +
+	"\fchannel_type\x18\x06 \x01(\tR\vchannelType\x128\n" +
+	"\tupstreams\x18\a \x03(\v2\x1a.gptload.admin.v1.UpstreamR\tupstreams\x12\x1d\n" +
+	"\n" +
+	"test_model\x18\b \x01(\tR\ttestModel\x12/\n" +
+	"\x13validation_endpoint\x18\t \x01(\tR\x12validationEndpoint\x12\x12\n" +
+	"\x04sort\x18\n" +
+	" \x01(\x05R\x04sort\x12\x18\n" +
+	"\aenabled\x18\v \x01(\bR\aenabled\x12/\n" +
+	"\x13maintenance_message\x18\f \x01(\tR\x12maintenanceMessage\x12\x1d\n" +
+	"\n" +
+	"proxy_keys\x18\r \x01(\tR\tproxyKeys\x12/\n" +
+	"\x06config\x18\x0e \x01(\v2\x17.google.protobuf.StructR\x06config\x12\x1f\n" +
+	"\vexternal_id\x18\x0f \x01(\tR\n" +
+	"externalId\x12\x1a\n" +
+	"\bendpoint\x18\x10 \x01(\tR\bendpoint\x129\n" +
+	"\n" +
+	"created_at\x18\x11 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x12 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\x13\n" +
+	"\x11ListGroupsRequest\"E\n" +
+	"\x12ListGroupsResponse\x12/\n" +
+	"\x06groups\x18\x01 \x03(\v2\x17.gptload.admin.v1.GroupR\x06groups\"e\n" +
+	"\x0fGetGroupRequest\x12\x10\n" +
+	"\x02id\x18\x01 \x01(\x04H\x00R\x02id\x12\x14\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04name\x12!\n" +
+	"\vexternal_id\x18\x03 \x01(\tH\x00R\n" +
+	"externalIdB\a\n" +
+	"\x05group\"\xbe\x03\n" +
+	"\x12CreateGroupRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\fdisplay_name\x18\x02 \x01(\tR\vdisplayName\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12\x1d\n" +
+	"\n" +
+	"group_type\x18\x04 \x01(\tR\tgroupType\x12!\n" +
+	"\fchannel_type\x18\x05 \x01(\tR\vchannelType\x128\n" +
+	"\tupstreams\x18\x06 \x03(\v2\x1a.gptload.admin.v1.UpstreamR\tupstreams\x12\x1d\n" +
+	"\n" +
+	"test_model\x18\a \x01(\tR\ttestModel\x12/\n" +
+	"\x13validation_endpoint\x18\b \x01(\tR\x12validationEndpoint\x12\x12\n" +
+	"\x04sort\x18\t \x01(\x05R\x04sort\x12\x1d\n" +
+	"\n" +
+	"proxy_keys\x18\n" +
+	" \x01(\tR\tproxyKeys\x12/\n" +
+	"\x06config\x18\v \x01(\v2\x17.google.protobuf.StructR\x06config\x12\x1f\n" +
+	"\vexternal_id\x18\f \x01(\tR\n" +
+	"externalId\"H\n" +
+	"\fUpstreamList\x128\n" +
+	"\tupstreams\x18\x01 \x03(\v2\x1a.gptload.admin.v1.UpstreamR\tupstreams\"\xad\x05\n" +
+	"\x12UpdateGroupRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x17\n" +
+	"\x04name\x18\x02 \x01(\tH\x00R\x04name\x88\x01\x01\x12&\n" +
+	"\fdisplay_name\x18\x03 \x01(\tH\x01R\vdisplayName\x88\x01\x01\x12%\n" +
+	"\vdescription\x18\x04 \x01(\tH\x02R\vdescription\x88\x01\x01\x12&\n" +
+	"\fchannel_type\x18\x05 \x01(\tH\x03R\vchannelType\x88\x01\x01\x12<\n" +
+	"\tupstreams\x18\x06 \x01(\v2\x1e.gptload.admin.v1.UpstreamListR\tupstreams\x12\"\n" +
+	"\n" +
+	"test_model\x18\a \x01(\tH\x04R\ttestModel\x88\x01\x01\x124\n" +
+	"\x13validation_endpoint\x18\b \x01(\tH\x05R\x12validationEndpoint\x88\x01\x01\x12\x17\n" +
+	"\x04sort\x18\t \x01(\x05H\x06R\x04sort\x88\x01\x01\x12\x1d\n" +
+	"\aenabled\x18\n" +
+	" \x01(\bH\aR\aenabled\x88\x01\x01\x124\n" +
+	"\x13maintenance_message\x18\v \x01(\tH\bR\x12maintenanceMessage\x88\x01\x01\x12\"\n" +
+	"\n" +
+	"proxy_keys\x18\f \x01(\tH\tR\tproxyKeys\x88\x01\x01\x12/\n" +
+	"\x06config\x18\r \x01(\v2\x17.google.protobuf.StructR\x06configB\a\n" +
+	"\x05_nameB\x0f\n" +
+	"\r_display_nameB\x0e\n" +
+	"\f_descriptionB\x0f\n" +
+	"\r_channel_typeB\r\n" +
+	"\v_test_modelB\x16\n" +
+	"\x14_validation_endpointB\a\n" +
+	"\x05_sortB\n" +
+	"\n" +
+	"\b_enabledB\x16\n" +
+	"\x14_maintenance_messageB\r\n" +
+	"\v_proxy_keys\"$\n" +
+	"\x12DeleteGroupRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\"\x15\n" +
+	"\x13DeleteGroupResponse\"\x8e\x03\n" +
+	"\x03Key\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x04R\x02id\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x04R\agroupId\x12\x1b\n" +
+	"\tkey_value\x18\x03 \x01(\tR\bkeyValue\x12\x19\n" +
+	"\bkey_hash\x18\x04 \x01(\tR\akeyHash\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x12\x14\n" +
+	"\x05notes\x18\x06 \x01(\tR\x05notes\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tags\x12#\n" +
+	"\rrequest_count\x18\b \x01(\x03R\frequestCount\x12#\n" +
+	"\rfailure_count\x18\t \x01(\x03R\ffailureCount\x12<\n" +
+	"\flast_used_at\x18\n" +
+	" \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastUsedAt\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1f\n" +
+	"\vexternal_id\x18\f \x01(\tR\n" +
+	"externalId\"\xbb\x01\n" +
+	"\x0fListKeysRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x12\n" +
+	"\x04tags\x18\x03 \x03(\tR\x04tags\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\x12%\n" +
+	"\x0einclude_values\x18\x06 \x01(\bR\rincludeValues\"e\n" +
+	"\x10ListKeysResponse\x12)\n" +
+	"\x04keys\x18\x01 \x03(\v2\x15.gptload.admin.v1.KeyR\x04keys\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"?\n" +
+	"\x0eAddKeysRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"}\n" +
+	"\x0fAddKeysResponse\x12\x1f\n" +
+	"\vadded_count\x18\x01 \x01(\x05R\n" +
+	"addedCount\x12#\n" +
+	"\rignored_count\x18\x02 \x01(\x05R\fignoredCount\x12$\n" +
+	"\x0etotal_in_group\x18\x03 \x01(\x03R\ftotalInGroup\"B\n" +
+	"\x11DeleteKeysRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"\x84\x01\n" +
+	"\x12DeleteKeysResponse\x12#\n" +
+	"\rdeleted_count\x18\x01 \x01(\x05R\fdeletedCount\x12#\n" +
+	"\rignored_count\x18\x02 \x01(\x05R\fignoredCount\x12$\n" +
+	"\x0etotal_in_group\x18\x03 \x01(\x03R\ftotalInGroup\"C\n" +
+	"\x12RestoreKeysRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x12\n" +
+	"\x04keys\x18\x02 \x03(\tR\x04keys\"\x87\x01\n" +
+	"\x13RestoreKeysResponse\x12%\n" +
+	"\x0erestored_count\x18\x01 \x01(\x05R\rrestoredCount\x12#\n" +
+	"\rignored_count\x18\x02 \x01(\x05R\fignoredCount\x12$\n" +
+	"\x0etotal_in_group\x18\x03 \x01(\x03R\ftotalInGroup\"[\n" +
+	"\x15TransitionKeysRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\x04R\agroupId\x12\x17\n" +
+	"\akey_ids\x18\x02 \x03(\x04R\x06keyIds\x12\x0e\n" +
+	"\x02to\x18\x03 \x01(\tR\x02to\"H\n" +
+	"\x16TransitionKeysResponse\x12\x14\n" +
+	"\x05moved\x18\x01 \x03(\x04R\x05moved\x12\x18\n" +
+	"\askipped\x18\x02 \x03(\x04R\askipped\"4\n" +
+	"\x15WatchKeyEventsRequest\x12\x1b\n" +
+	"\tgroup_ids\x18\x01 \x03(\x04R\bgroupIds\"\xc3\x01\n" +
+	"\bKeyEvent\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x04R\agroupId\x12\x17\n" +
+	"\akey_ids\x18\x03 \x03(\x04R\x06keyIds\x12\x12\n" +
+	"\x04from\x18\x04 \x01(\tR\x04from\x12\x0e\n" +
+	"\x02to\x18\x05 \x01(\tR\x02to\x12*\n" +
+	"\x02at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x02at\x12\x1f\n" +
+	"\vactive_keys\x18\a \x01(\x03R\n" +
+	"activeKeys2\xbd\a\n" +
+	"\fAdminService\x12W\n" +
+	"\n" +
+	"ListGroups\x12#.gptload.admin.v1.ListGroupsRequest\x1a$.gptload.admin.v1.ListGroupsResponse\x12F\n" +
+	"\bGetGroup\x12!.gptload.admin.v1.GetGroupRequest\x1a\x17.gptload.admin.v1.Group\x12L\n" +
+	"\vCreateGroup\x12$.gptload.admin.v1.CreateGroupRequest\x1a\x17.gptload.admin.v1.Group\x12L\n" +
+	"\vUpdateGroup\x12$.gptload.admin.v1.UpdateGroupRequest\x1a\x17.gptload.admin.v1.Group\x12Z\n" +
+	"\vDeleteGroup\x12$.gptload.admin.v1.DeleteGroupRequest\x1a%.gptload.admin.v1.DeleteGroupResponse\x12Q\n" +
+	"\bListKeys\x12!.gptload.admin.v1.ListKeysRequest\x1a\".gptload.admin.v1.ListKeysResponse\x12N\n" +
+	"\aAddKeys\x12 .gptload.admin.v1.AddKeysRequest\x1a!.gptload.admin.v1.AddKeysResponse\x12W\n" +
+	"\n" +
+	"DeleteKeys\x12#.gptload.admin.v1.DeleteKeysRequest\x1a$.gptload.admin.v1.DeleteKeysResponse\x12Z\n" +
+	"\vRestoreKeys\x12$.gptload.admin.v1.RestoreKeysRequest\x1a%.gptload.admin.v1.RestoreKeysResponse\x12c\n" +
+	"\x0eTransitionKeys\x12'.gptload.admin.v1.TransitionKeysRequest\x1a(.gptload.admin.v1.TransitionKeysResponse\x12W\n" +
+	"\x0eWatchKeyEvents\x12'.gptload.admin.v1.WatchKeyEventsRequest\x1a\x1a.gptload.admin.v1.KeyEvent0\x01B+Z)gpt-load/internal/grpcapi/adminv1;adminv1b\x06proto3"
+
+var (
+	file_admin_proto_rawDescOnce sync.Once
+	file_admin_proto_rawDescData []byte
+)
+
+func file_admin_proto_rawDescGZIP() []byte {
+	file_admin_proto_rawDescOnce.Do(func() {
+		file_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)))
+	})
+	return file_admin_proto_rawDescData
+}
+
+var file_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_admin_proto_goTypes = []any{
+	(*Upstream)(nil),               // 0: gptload.admin.v1.Upstream
+	(*Group)(nil),                  // 1: gptload.admin.v1.Group
+	(*ListGroupsRequest)(nil),      // 2: gptload.admin.v1.ListGroupsRequest
+	(*ListGroupsResponse)(nil),     // 3: gptload.admin.v1.ListGroupsResponse
+	(*GetGroupRequest)(nil),        // 4: gptload.admin.v1.GetGroupRequest
+	(*CreateGroupRequest)(nil),     // 5: gptload.admin.v1.CreateGroupRequest
+	(*UpstreamList)(nil),           // 6: gptload.admin.v1.UpstreamList
+	(*UpdateGroupRequest)(nil),     // 7: gptload.admin.v1.UpdateGroupRequest
+	(*DeleteGroupRequest)(nil),     // 8: gptload.admin.v1.DeleteGroupRequest
+	(*DeleteGroupResponse)(nil),    // 9: gptload.admin.v1.DeleteGroupResponse
+	(*Key)(nil),                    // 10: gptload.admin.v1.Key
+	(*ListKeysRequest)(nil),        // 11: gptload.admin.v1.ListKeysRequest
+	(*ListKeysResponse)(nil),       // 12: gptload.admin.v1.ListKeysResponse
+	(*AddKeysRequest)(nil),         // 13: gptload.admin.v1.AddKeysRequest
+	(*AddKeysResponse)(nil),        // 14: gptload.admin.v1.AddKeysResponse
+	(*DeleteKeysRequest)(nil),      // 15: gptload.admin.v1.DeleteKeysRequest
+	(*DeleteKeysResponse)(nil),     // 16: gptload.admin.v1.DeleteKeysResponse
+	(*RestoreKeysRequest)(nil),     // 17: gptload.admin.v1.RestoreKeysRequest
+	(*RestoreKeysResponse)(nil),    // 18: gptload.admin.v1.RestoreKeysResponse
+	(*TransitionKeysRequest)(nil),  // 19: gptload.admin.v1.TransitionKeysRequest
+	(*TransitionKeysResponse)(nil), // 20: gptload.admin.v1.TransitionKeysResponse
+	(*WatchKeyEventsRequest)(nil),  // 21: gptload.admin.v1.WatchKeyEventsRequest
+	(*KeyEvent)(nil),               // 22: gptload.admin.v1.KeyEvent
+	(*structpb.Struct)(nil),        // 23: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil),  // 24: google.protobuf.Timestamp
+}
+var file_admin_proto_depIdxs = []int32{
+	0,  // 0: gptload.admin.v1.Group.upstreams:type_name -> gptload.admin.v1.Upstream
+	23, // 1: gptload.admin.v1.Group.config:type_name -> google.protobuf.Struct
+	24, // 2: gptload.admin.v1.Group.created_at:type_name -> google.protobuf.Timestamp
+	24, // 3: gptload.admin.v1.Group.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 4: gptload.admin.v1.ListGroupsResponse.groups:type_name -> gptload.admin.v1.Group
+	0,  // 5: gptload.admin.v1.CreateGroupRequest.upstreams:type_name -> gptload.admin.v1.Upstream
+	23, // 6: gptload.admin.v1.CreateGroupRequest.config:type_name -> google.protobuf.Struct
+	0,  // 7: gptload.admin.v1.UpstreamList.upstreams:type_name -> gptload.admin.v1.Upstream
+	6,  // 8: gptload.admin.v1.UpdateGroupRequest.upstreams:type_name -> gptload.admin.v1.UpstreamList
+	23, // 9: gptload.admin.v1.UpdateGroupRequest.config:type_name -> google.protobuf.Struct
+	24, // 10: gptload.admin.v1.Key.last_used_at:type_name -> google.protobuf.Timestamp
+	24, // 11: gptload.admin.v1.Key.created_at:type_name -> google.protobuf.Timestamp
+	10, // 12: gptload.admin.v1.ListKeysResponse.keys:type_name -> gptload.admin.v1.Key
+	24, // 13: gptload.admin.v1.KeyEvent.at:type_name -> google.protobuf.Timestamp
+	2,  // 14: gptload.admin.v1.AdminService.ListGroups:input_type -> gptload.admin.v1.ListGroupsRequest
+	4,  // 15: gptload.admin.v1.AdminService.GetGroup:input_type -> gptload.admin.v1.GetGroupRequest
+	5,  // 16: gptload.admin.v1.AdminService.CreateGroup:input_type -> gptload.admin.v1.CreateGroupRequest
+	7,  // 17: gptload.admin.v1.AdminService.UpdateGroup:input_type -> gptload.admin.v1.UpdateGroupRequest
+	8,  // 18: gptload.admin.v1.AdminService.DeleteGroup:input_type -> gptload.admin.v1.DeleteGroupRequest
+	11, // 19: gptload.admin.v1.AdminService.ListKeys:input_type -> gptload.admin.v1.ListKeysRequest
+	13, // 20: gptload.admin.v1.AdminService.AddKeys:input_type -> gptload.admin.v1.AddKeysRequest
+	15, // 21: gptload.admin.v1.AdminService.DeleteKeys:input_type -> gptload.admin.v1.DeleteKeysRequest
+	17, // 22: gptload.admin.v1.AdminService.RestoreKeys:input_type -> gptload.admin.v1.RestoreKeysRequest
+	19, // 23: gptload.admin.v1.AdminService.TransitionKeys:input_type -> gptload.admin.v1.TransitionKeysRequest
+	21, // 24: gptload.admin.v1.AdminService.WatchKeyEvents:input_type -> gptload.admin.v1.WatchKeyEventsRequest
+	3,  // 25: gptload.admin.v1.AdminService.ListGroups:output_type -> gptload.admin.v1.ListGroupsResponse
+	1,  // 26: gptload.admin.v1.AdminService.GetGroup:output_type -> gptload.admin.v1.Group
+	1,  // 27: gptload.admin.v1.AdminService.CreateGroup:output_type -> gptload.admin.v1.Group
+	1,  // 28: gptload.admin.v1.AdminService.UpdateGroup:output_type -> gptload.admin.v1.Group
+	9,  // 29: gptload.admin.v1.AdminService.DeleteGroup:output_type -> gptload.admin.v1.DeleteGroupResponse
+	12, // 30: gptload.admin.v1.AdminService.ListKeys:output_type -> gptload.admin.v1.ListKeysResponse
+	14, // 31: gptload.admin.v1.AdminService.AddKeys:output_type -> gptload.admin.v1.AddKeysResponse
+	16, // 32: gptload.admin.v1.AdminService.DeleteKeys:output_type -> gptload.admin.v1.DeleteKeysResponse
+	18, // 33: gptload.admin.v1.AdminService.RestoreKeys:output_type -> gptload.admin.v1.RestoreKeysResponse
+	20, // 34: gptload.admin.v1.AdminService.TransitionKeys:output_type -> gptload.admin.v1.TransitionKeysResponse
+	22, // 35: gptload.admin.v1.AdminService.WatchKeyEvents:output_type -> gptload.admin.v1.KeyEvent
+	25, // [25:36] is the sub-list for method output_type
+	14, // [14:25] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_admin_proto_init() }
+func file_admin_proto_init() {
+	if File_admin_proto != nil {
+		return
+	}
+	file_admin_proto_msgTypes[4].OneofWrappers = []any{
+		(*GetGroupRequest_Id)(nil),
+		(*GetGroupRequest_Name)(nil),
+		(*GetGroupRequest_ExternalId)(nil),
+	}
+	file_admin_proto_msgTypes[7].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_admin_proto_rawDesc), len(file_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_admin_proto_goTypes,
+		DependencyIndexes: file_admin_proto_depIdxs,
+		MessageInfos:      file_admin_proto_msgTypes,
+	}.Build()
+	File_admin_proto = out.File
+	file_admin_proto_goTypes = nil
+	file_admin_proto_depIdxs = nil
+}
@@ -0,0 +1,5 @@
+// Package adminv1 holds the protobuf definitions of the gRPC admin API and the code
+// generated from them.
+package adminv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative admin.proto
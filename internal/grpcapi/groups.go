@@ -0,0 +1,195 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/grpcapi/adminv1"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// upstream is the stored form of an upstream. Auth tokens and proxies of upstreams are
+// managed through the REST API.
+type upstream struct {
+	URL    string `json:"url"`
+	Weight int32  `json:"weight"`
+}
+
+// ListGroups returns every group.
+func (s *Server) ListGroups(ctx context.Context, _ *adminv1.ListGroupsRequest) (*adminv1.ListGroupsResponse, error) {
+	groups, err := s.groupService.ListGroups(ctx)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	resp := &adminv1.ListGroupsResponse{Groups: make([]*adminv1.Group, 0, len(groups))}
+	for i := range groups {
+		resp.Groups = append(resp.Groups, s.groupMessage(ctx, &groups[i]))
+	}
+	return resp, nil
+}
+
+// GetGroup returns a group by ID, name or external ID.
+func (s *Server) GetGroup(ctx context.Context, req *adminv1.GetGroupRequest) (*adminv1.Group, error) {
+	query := s.db.WithContext(ctx)
+	switch by := req.Group.(type) {
+	case *adminv1.GetGroupRequest_Id:
+		query = query.Where("id = ?", by.Id)
+	case *adminv1.GetGroupRequest_Name:
+		query = query.Where("name = ?", by.Name)
+	case *adminv1.GetGroupRequest_ExternalId:
+		query = query.Where("external_id = ?", by.ExternalId)
+	default:
+		return nil, invalidArgument(ctx, "validation.invalid_group_id")
+	}
+
+	var group models.Group
+	if err := query.First(&group).Error; err != nil {
+		return nil, toStatus(ctx, app_errors.ParseDBError(err))
+	}
+	return s.groupMessage(ctx, &group), nil
+}
+
+// CreateGroup creates a group.
+func (s *Server) CreateGroup(ctx context.Context, req *adminv1.CreateGroupRequest) (*adminv1.Group, error) {
+	upstreams, err := upstreamsJSON(req.Upstreams)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	group, err := s.groupService.CreateGroup(ctx, services.GroupCreateParams{
+		Name:               req.Name,
+		DisplayName:        req.DisplayName,
+		Description:        req.Description,
+		GroupType:          req.GroupType,
+		Upstreams:          upstreams,
+		ChannelType:        req.ChannelType,
+		Sort:               int(req.Sort),
+		TestModel:          req.TestModel,
+		ValidationEndpoint: req.ValidationEndpoint,
+		Config:             req.Config.AsMap(),
+		ProxyKeys:          req.ProxyKeys,
+		ExternalID:         req.ExternalId,
+	})
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return s.groupMessage(ctx, group), nil
+}
+
+// UpdateGroup updates the fields of a group set in the request.
+func (s *Server) UpdateGroup(ctx context.Context, req *adminv1.UpdateGroupRequest) (*adminv1.Group, error) {
+	params := services.GroupUpdateParams{
+		Name:               req.Name,
+		DisplayName:        req.DisplayName,
+		Description:        req.Description,
+		ChannelType:        req.ChannelType,
+		Enabled:            req.Enabled,
+		MaintenanceMessage: req.MaintenanceMessage,
+		ValidationEndpoint: req.ValidationEndpoint,
+		ProxyKeys:          req.ProxyKeys,
+	}
+	if req.Upstreams != nil {
+		upstreams, err := upstreamsJSON(req.Upstreams.Upstreams)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		params.Upstreams, params.HasUpstreams = upstreams, true
+	}
+	if req.TestModel != nil {
+		params.TestModel, params.HasTestModel = *req.TestModel, true
+	}
+	if req.Sort != nil {
+		sort := int(*req.Sort)
+		params.Sort = &sort
+	}
+	if req.Config != nil {
+		params.Config = req.Config.AsMap()
+	}
+
+	group, err := s.groupService.UpdateGroup(ctx, uint(req.Id), params)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return s.groupMessage(ctx, group), nil
+}
+
+// DeleteGroup moves a group and its keys to the trash.
+func (s *Server) DeleteGroup(ctx context.Context, req *adminv1.DeleteGroupRequest) (*adminv1.DeleteGroupResponse, error) {
+	if err := s.groupService.DeleteGroup(ctx, uint(req.Id)); err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &adminv1.DeleteGroupResponse{}, nil
+}
+
+// upstreamsJSON returns upstreams in the form the group service validates.
+func upstreamsJSON(upstreams []*adminv1.Upstream) (json.RawMessage, error) {
+	defs := make([]upstream, 0, len(upstreams))
+	for _, u := range upstreams {
+		defs = append(defs, upstream{URL: u.Url, Weight: u.Weight})
+	}
+	return json.Marshal(defs)
+}
+
+func (s *Server) groupMessage(ctx context.Context, group *models.Group) *adminv1.Group {
+	group = services.MaskGroupSecrets(s.encryptionSvc, group)
+	msg := &adminv1.Group{
+		Id:                 uint64(group.ID),
+		Name:               group.Name,
+		DisplayName:        group.DisplayName,
+		Description:        group.Description,
+		GroupType:          group.GroupType,
+		ChannelType:        group.ChannelType,
+		TestModel:          group.TestModel,
+		ValidationEndpoint: group.ValidationEndpoint,
+		Sort:               int32(group.Sort),
+		Enabled:            group.Enabled,
+		MaintenanceMessage: group.MaintenanceMessage,
+		ProxyKeys:          services.MaskProxyKeys(group.ProxyKeys, callerRole(ctx)),
+		Endpoint:           s.groupEndpoint(group.Name),
+		CreatedAt:          timestamppb.New(group.CreatedAt),
+		UpdatedAt:          timestamppb.New(group.UpdatedAt),
+	}
+	if group.ExternalID != nil {
+		msg.ExternalId = *group.ExternalID
+	}
+
+	var upstreams []upstream
+	if len(group.Upstreams) > 0 {
+		if err := json.Unmarshal(group.Upstreams, &upstreams); err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Warn("Failed to unmarshal group upstreams")
+		}
+	}
+	for _, u := range upstreams {
+		msg.Upstreams = append(msg.Upstreams, &adminv1.Upstream{Url: u.URL, Weight: u.Weight})
+	}
+
+	if len(group.Config) > 0 {
+		config, err := structpb.NewStruct(group.Config)
+		if err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Warn("Failed to convert group config")
+		}
+		msg.Config = config
+	}
+	return msg
+}
+
+// groupEndpoint returns the proxy endpoint of a group, as the REST API does.
+func (s *Server) groupEndpoint(name string) string {
+	appURL := s.settingsManager.GetAppUrl()
+	if appURL == "" {
+		return ""
+	}
+	u, err := url.Parse(appURL)
+	if err != nil {
+		return ""
+	}
+	u.Path = strings.TrimRight(u.Path, "/") + "/proxy/" + name
+	return u.String()
+}
@@ -0,0 +1,247 @@
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/grpcapi/adminv1"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultKeyPageSize = 100
+	maxKeyPageSize     = 1000
+)
+
+// ListKeys pages through the keys of a group in ID order, the page token being the last ID
+// of the previous page.
+func (s *Server) ListKeys(ctx context.Context, req *adminv1.ListKeysRequest) (*adminv1.ListKeysResponse, error) {
+	if err := s.checkGroup(ctx, req.GroupId); err != nil {
+		return nil, err
+	}
+	if req.Status != "" && !models.IsKeyStatus(req.Status) {
+		return nil, invalidArgument(ctx, "validation.invalid_status_filter")
+	}
+	tags, err := models.NormalizeKeyTags(req.Tags)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var afterID uint64
+	if req.PageToken != "" {
+		if afterID, err = strconv.ParseUint(req.PageToken, 10, 64); err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+	}
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultKeyPageSize
+	}
+	pageSize = min(pageSize, maxKeyPageSize)
+
+	query := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("group_id = ? AND id > ?", req.GroupId, afterID).
+		Scopes(models.WithKeyTags(tags))
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	var keys []models.APIKey
+	if err := query.Order("id").Limit(pageSize + 1).Find(&keys).Error; err != nil {
+		return nil, toStatus(ctx, app_errors.ParseDBError(err))
+	}
+
+	resp := &adminv1.ListKeysResponse{}
+	if len(keys) > pageSize {
+		keys = keys[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(uint64(keys[pageSize-1].ID), 10)
+	}
+	var view *services.KeyView
+	if req.IncludeValues {
+		view = s.keyView(ctx, "ListKeys")
+	}
+	resp.Keys = make([]*adminv1.Key, 0, len(keys))
+	for i := range keys {
+		resp.Keys = append(resp.Keys, s.keyMessage(&keys[i], view))
+	}
+	if view != nil {
+		view.Audit(uint(req.GroupId))
+	}
+	return resp, nil
+}
+
+// AddKeys adds keys to a group. New keys are pending until they are verified.
+func (s *Server) AddKeys(ctx context.Context, req *adminv1.AddKeysRequest) (*adminv1.AddKeysResponse, error) {
+	if err := s.checkKeyBatch(ctx, req.GroupId, req.Keys); err != nil {
+		return nil, err
+	}
+	result, err := s.keyService.AddMultipleKeys(uint(req.GroupId), strings.Join(req.Keys, "\n"))
+	if err != nil {
+		return nil, keyBatchError(ctx, err)
+	}
+	return &adminv1.AddKeysResponse{
+		AddedCount:   int32(result.AddedCount),
+		IgnoredCount: int32(result.IgnoredCount),
+		TotalInGroup: result.TotalInGroup,
+	}, nil
+}
+
+// DeleteKeys moves keys of a group to the trash.
+func (s *Server) DeleteKeys(ctx context.Context, req *adminv1.DeleteKeysRequest) (*adminv1.DeleteKeysResponse, error) {
+	if err := s.checkKeyBatch(ctx, req.GroupId, req.Keys); err != nil {
+		return nil, err
+	}
+	result, err := s.keyService.DeleteMultipleKeys(uint(req.GroupId), strings.Join(req.Keys, "\n"))
+	if err != nil {
+		return nil, keyBatchError(ctx, err)
+	}
+	return &adminv1.DeleteKeysResponse{
+		DeletedCount: int32(result.DeletedCount),
+		IgnoredCount: int32(result.IgnoredCount),
+		TotalInGroup: result.TotalInGroup,
+	}, nil
+}
+
+// RestoreKeys reactivates invalid keys of a group.
+func (s *Server) RestoreKeys(ctx context.Context, req *adminv1.RestoreKeysRequest) (*adminv1.RestoreKeysResponse, error) {
+	if err := s.checkKeyBatch(ctx, req.GroupId, req.Keys); err != nil {
+		return nil, err
+	}
+	result, err := s.keyService.RestoreMultipleKeys(uint(req.GroupId), strings.Join(req.Keys, "\n"))
+	if err != nil {
+		return nil, keyBatchError(ctx, err)
+	}
+	return &adminv1.RestoreKeysResponse{
+		RestoredCount: int32(result.RestoredCount),
+		IgnoredCount:  int32(result.IgnoredCount),
+		TotalInGroup:  result.TotalInGroup,
+	}, nil
+}
+
+// TransitionKeys moves keys of a group along their lifecycle.
+func (s *Server) TransitionKeys(ctx context.Context, req *adminv1.TransitionKeysRequest) (*adminv1.TransitionKeysResponse, error) {
+	if !keypool.IsLifecycleTarget(req.To) {
+		return nil, invalidArgument(ctx, "validation.invalid_lifecycle_status")
+	}
+	if err := s.checkGroup(ctx, req.GroupId); err != nil {
+		return nil, err
+	}
+	keyIDs := make([]uint, 0, len(req.KeyIds))
+	for _, id := range req.KeyIds {
+		keyIDs = append(keyIDs, uint(id))
+	}
+
+	result, err := s.keyService.TransitionKeys(uint(req.GroupId), keyIDs, req.To)
+	if err != nil {
+		return nil, keyBatchError(ctx, err)
+	}
+	return &adminv1.TransitionKeysResponse{Moved: keyIDList(result.Moved), Skipped: keyIDList(result.Skipped)}, nil
+}
+
+// WatchKeyEvents streams the key status events of every instance until the call ends or
+// the server stops.
+func (s *Server) WatchKeyEvents(req *adminv1.WatchKeyEventsRequest, stream adminv1.AdminService_WatchKeyEventsServer) error {
+	sub, err := s.store.Subscribe(keypool.KeyStatusEventChannel)
+	if err != nil {
+		return toStatus(stream.Context(), err)
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stopping:
+			return status.Error(codes.Unavailable, "server is shutting down")
+		case msg, ok := <-sub.Channel():
+			if !ok {
+				return status.Error(codes.Unavailable, "key event subscription closed")
+			}
+			var event keypool.KeyStatusEvent
+			if err := json.Unmarshal(msg.Payload, &event); err != nil {
+				logrus.WithError(err).Warn("Failed to unmarshal key status event")
+				continue
+			}
+			if len(req.GroupIds) > 0 && !slices.Contains(req.GroupIds, uint64(event.GroupID)) {
+				continue
+			}
+			if err := stream.Send(&adminv1.KeyEvent{
+				Type:       event.Type,
+				GroupId:    uint64(event.GroupID),
+				KeyIds:     keyIDList(event.KeyIDs),
+				From:       event.From,
+				To:         event.To,
+				ActiveKeys: event.ActiveKeys,
+				At:         timestamppb.New(event.At),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// checkGroup returns a NotFound status when the group does not exist.
+func (s *Server) checkGroup(ctx context.Context, groupID uint64) error {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id").First(&group, groupID).Error; err != nil {
+		return toStatus(ctx, app_errors.ParseDBError(err))
+	}
+	return nil
+}
+
+func (s *Server) checkKeyBatch(ctx context.Context, groupID uint64, keys []string) error {
+	if len(keys) == 0 {
+		return invalidArgument(ctx, "validation.keys_text_empty")
+	}
+	return s.checkGroup(ctx, groupID)
+}
+
+// keyBatchError maps the errors of the key service batch operations as the REST API does.
+func keyBatchError(ctx context.Context, err error) error {
+	if strings.Contains(err.Error(), "batch size exceeds the limit") || err.Error() == "no valid keys found in the input text" {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return toStatus(ctx, app_errors.ParseDBError(err))
+}
+
+// keyMessage converts a key, with its value as view shows it unless view is nil.
+func (s *Server) keyMessage(key *models.APIKey, view *services.KeyView) *adminv1.Key {
+	msg := &adminv1.Key{
+		Id:           uint64(key.ID),
+		GroupId:      uint64(key.GroupID),
+		KeyHash:      key.KeyHash,
+		Status:       key.Status,
+		Notes:        key.Notes,
+		Tags:         key.Tags,
+		RequestCount: key.RequestCount,
+		FailureCount: key.FailureCount,
+		CreatedAt:    timestamppb.New(key.CreatedAt),
+	}
+	if key.LastUsedAt != nil {
+		msg.LastUsedAt = timestamppb.New(*key.LastUsedAt)
+	}
+	if key.ExternalID != nil {
+		msg.ExternalId = *key.ExternalID
+	}
+	if view != nil {
+		msg.KeyValue = view.Show(key.KeyValue, key.KeyHash)
+	}
+	return msg
+}
+
+func keyIDList(ids []uint) []uint64 {
+	list := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		list = append(list, uint64(id))
+	}
+	return list
+}
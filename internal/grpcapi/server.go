@@ -0,0 +1,276 @@
+// Package grpcapi serves the gRPC admin API, which manages groups and keys as the REST API
+// does for control-plane services using typed clients.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/grpcapi/adminv1"
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"go.uber.org/dig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// Server implements the AdminService on top of the services used by the REST API.
+type Server struct {
+	adminv1.UnimplementedAdminServiceServer
+
+	db              *gorm.DB
+	store           store.Store
+	configManager   types.ConfigManager
+	settingsManager *config.SystemSettingsManager
+	groupService    *services.GroupService
+	keyService      *services.KeyService
+	keyMasking      *services.KeyMaskingService
+	encryptionSvc   encryption.Service
+	adminAuth       *services.AdminAuthService
+
+	grpcServer *grpc.Server
+	// stopping ends the watch streams, which would otherwise hold a graceful stop forever
+	stopping chan struct{}
+}
+
+// ServerParams defines the dependencies of the Server.
+type ServerParams struct {
+	dig.In
+	DB              *gorm.DB
+	Store           store.Store
+	ConfigManager   types.ConfigManager
+	SettingsManager *config.SystemSettingsManager
+	GroupService    *services.GroupService
+	KeyService      *services.KeyService
+	KeyMasking      *services.KeyMaskingService
+	EncryptionSvc   encryption.Service
+	AdminAuth       *services.AdminAuthService
+}
+
+// NewServer creates the gRPC admin API server.
+func NewServer(params ServerParams) *Server {
+	return &Server{
+		db:              params.DB,
+		store:           params.Store,
+		configManager:   params.ConfigManager,
+		settingsManager: params.SettingsManager,
+		groupService:    params.GroupService,
+		keyService:      params.KeyService,
+		keyMasking:      params.KeyMasking,
+		encryptionSvc:   params.EncryptionSvc,
+		adminAuth:       params.AdminAuth,
+		stopping:        make(chan struct{}),
+	}
+}
+
+// Start listens on GRPC_PORT and serves in the background. It does nothing when the port
+// is not set.
+func (s *Server) Start() error {
+	serverConfig := s.configManager.GetEffectiveServerConfig()
+	if serverConfig.GRPCPort == 0 {
+		return nil
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%d", serverConfig.Host, serverConfig.GRPCPort))
+	if err != nil {
+		return fmt.Errorf("failed to listen for the gRPC admin API: %w", err)
+	}
+
+	s.grpcServer = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.authUnary),
+		grpc.ChainStreamInterceptor(s.authStream),
+	)
+	adminv1.RegisterAdminServiceServer(s.grpcServer, s)
+
+	go func() {
+		logrus.Infof("gRPC admin API listening on %s", listener.Addr())
+		if err := s.grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			logrus.Errorf("gRPC admin API stopped: %v", err)
+		}
+	}()
+	return nil
+}
+
+// Stop waits for the running calls to finish, cancelling them when ctx is done.
+func (s *Server) Stop(ctx context.Context) {
+	if s.grpcServer == nil {
+		return
+	}
+	close(s.stopping)
+
+	done := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("gRPC admin API has been shut down.")
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		logrus.Warn("gRPC admin API shutdown timed out, remaining calls were cancelled.")
+	}
+}
+
+// readOnlyMethods are the calls that change nothing, which the viewer role may make.
+var readOnlyMethods = map[string]bool{
+	adminv1.AdminService_ListGroups_FullMethodName:     true,
+	adminv1.AdminService_GetGroup_FullMethodName:       true,
+	adminv1.AdminService_ListKeys_FullMethodName:       true,
+	adminv1.AdminService_WatchKeyEvents_FullMethodName: true,
+}
+
+// roleKey holds the role of the caller in the context of a call.
+type roleKey struct{}
+
+// authUnary and authStream require a bearer token in the call metadata, accepted as the REST
+// API does: an admin session token, or AUTH_KEY while AUTH_KEY_LOGIN is enabled. Sessions of
+// the viewer role may only make read-only calls.
+func (s *Server) authUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) authStream(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream carries the role of the caller in the context of a stream.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var key string
+	if values := md.Get("authorization"); len(values) > 0 {
+		key = strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	}
+
+	authConfig := s.configManager.GetAuthConfig()
+	role := ""
+	switch {
+	case key == "":
+	case authConfig.KeyLogin && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1:
+		role = types.AdminRoleAdmin
+	default:
+		if session := s.adminAuth.Authenticate(key); session != nil {
+			role = session.Role
+		}
+	}
+
+	if role == "" {
+		return nil, status.Error(codes.Unauthenticated, app_errors.ErrUnauthorized.Message)
+	}
+	if role != types.AdminRoleAdmin && !readOnlyMethods[method] {
+		return nil, status.Error(codes.PermissionDenied, translate(ctx, "auth.read_only_role"))
+	}
+	return context.WithValue(ctx, roleKey{}, role), nil
+}
+
+// keyView returns the view of the key values of a call at the key_masking level, audited
+// as method.
+func (s *Server) keyView(ctx context.Context, method string) *services.KeyView {
+	source := services.KeyRevealSource{Endpoint: "gRPC " + method}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			source.SourceIP = host
+		}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("user-agent"); len(values) > 0 {
+		source.UserAgent = values[0]
+	}
+	return s.keyMasking.View(source, false, callerRole(ctx))
+}
+
+// callerRole returns the role of the caller of a call.
+func callerRole(ctx context.Context) string {
+	role, _ := ctx.Value(roleKey{}).(string)
+	return role
+}
+
+// toStatus turns a service error into a gRPC status, with the message in the language of
+// the accept-language metadata as the REST API does.
+func toStatus(ctx context.Context, err error) error {
+	var svcErr *services.I18nError
+	if errors.As(err, &svcErr) && svcErr != nil {
+		return status.Error(statusCode(svcErr.APIError), translate(ctx, svcErr.MessageID, svcErr.Template))
+	}
+
+	var apiErr *app_errors.APIError
+	if errors.As(err, &apiErr) {
+		return status.Error(statusCode(apiErr), apiErr.Message)
+	}
+
+	logrus.WithContext(ctx).WithError(err).Error("unexpected gRPC admin API error")
+	return status.Error(codes.Internal, app_errors.ErrInternalServer.Message)
+}
+
+// invalidArgument is a validation error with a localized message.
+func invalidArgument(ctx context.Context, messageID string, template ...map[string]any) error {
+	return status.Error(codes.InvalidArgument, translate(ctx, messageID, template...))
+}
+
+func translate(ctx context.Context, messageID string, template ...map[string]any) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var acceptLang string
+	if values := md.Get("accept-language"); len(values) > 0 {
+		acceptLang = values[0]
+	}
+	return i18n.T(i18n.GetLocalizer(acceptLang), messageID, template...)
+}
+
+// statusCode maps the HTTP status of an API error to the closest gRPC code.
+func statusCode(apiErr *app_errors.APIError) codes.Code {
+	switch apiErr.HTTPStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		if apiErr.Code == app_errors.ErrDuplicateResource.Code {
+			return codes.AlreadyExists
+		}
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,202 @@
+// Package guardrails clamps or rejects request parameters according to the policy of a group.
+package guardrails
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Actions on a max_tokens value over the cap.
+const (
+	ActionClamp  = "clamp"
+	ActionReject = "reject"
+)
+
+// stream_options modes.
+const (
+	// StreamOptionsIncludeUsage sets stream_options.include_usage on streaming requests.
+	StreamOptionsIncludeUsage = "include_usage"
+	// StreamOptionsStrip removes stream_options, for upstreams that reject it.
+	StreamOptionsStrip = "strip"
+)
+
+// maxTokensFields are the output token limits of the OpenAI, Anthropic and Responses APIs.
+var maxTokensFields = []string{"max_tokens", "max_completion_tokens", "max_output_tokens"}
+
+// Policy limits the parameters of the requests of a group. The zero value changes nothing.
+// Only parameters present in a request are clamped, none is added.
+type Policy struct {
+	// MaxTokens caps the output token limit of a request, 0 for no cap.
+	MaxTokens int `json:"max_tokens,omitempty"`
+	// MaxTokensAction is ActionClamp (the default) or ActionReject.
+	MaxTokensAction string   `json:"max_tokens_action,omitempty"`
+	TemperatureMin  *float64 `json:"temperature_min,omitempty"`
+	TemperatureMax  *float64 `json:"temperature_max,omitempty"`
+	// StreamOptions is StreamOptionsIncludeUsage, StreamOptionsStrip or empty to leave it as sent.
+	StreamOptions string `json:"stream_options,omitempty"`
+	// MaxMessages rejects requests with more messages, 0 for no limit.
+	MaxMessages int `json:"max_messages,omitempty"`
+}
+
+// Violation describes why a request was rejected.
+type Violation struct {
+	Field  string `json:"field"`
+	Limit  any    `json:"limit"`
+	Actual any    `json:"actual"`
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("request parameter '%s' is %v, the group allows at most %v", v.Field, v.Actual, v.Limit)
+}
+
+// Parse converts the request_guardrails value of a group config into a Policy.
+func Parse(value any) (*Policy, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, fmt.Errorf("request_guardrails is invalid: %w", err)
+	}
+	if err := policy.validate(); err != nil {
+		return nil, fmt.Errorf("request_guardrails is invalid: %w", err)
+	}
+	return &policy, nil
+}
+
+func (p *Policy) validate() error {
+	if p.MaxTokens < 0 || p.MaxMessages < 0 {
+		return fmt.Errorf("max_tokens and max_messages cannot be negative")
+	}
+	switch p.MaxTokensAction {
+	case "", ActionClamp, ActionReject:
+	default:
+		return fmt.Errorf("max_tokens_action must be %q or %q", ActionClamp, ActionReject)
+	}
+	switch p.StreamOptions {
+	case "", StreamOptionsIncludeUsage, StreamOptionsStrip:
+	default:
+		return fmt.Errorf("stream_options must be %q or %q", StreamOptionsIncludeUsage, StreamOptionsStrip)
+	}
+	if p.TemperatureMin != nil && p.TemperatureMax != nil && *p.TemperatureMin > *p.TemperatureMax {
+		return fmt.Errorf("temperature_min cannot be greater than temperature_max")
+	}
+	return nil
+}
+
+// Apply returns the body with the policy applied, or a Violation when the request must be
+// rejected. Bodies that are not JSON objects are returned as they are.
+func (p *Policy) Apply(bodyBytes []byte) ([]byte, *Violation) {
+	if p == nil || len(bodyBytes) == 0 {
+		return bodyBytes, nil
+	}
+	var body map[string]any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return bodyBytes, nil
+	}
+
+	if violation := p.checkMessages(body); violation != nil {
+		return nil, violation
+	}
+
+	changed := false
+	// Gemini keeps its generation parameters in generationConfig
+	generationConfig, _ := body["generationConfig"].(map[string]any)
+
+	if p.MaxTokens > 0 {
+		for _, field := range maxTokensFields {
+			c, violation := p.capTokens(body, field, field)
+			if violation != nil {
+				return nil, violation
+			}
+			changed = changed || c
+		}
+		if generationConfig != nil {
+			c, violation := p.capTokens(generationConfig, "maxOutputTokens", "generationConfig.maxOutputTokens")
+			if violation != nil {
+				return nil, violation
+			}
+			changed = changed || c
+		}
+	}
+
+	changed = p.clampTemperature(body) || changed
+	if generationConfig != nil {
+		changed = p.clampTemperature(generationConfig) || changed
+	}
+
+	switch p.StreamOptions {
+	case StreamOptionsStrip:
+		if _, ok := body["stream_options"]; ok {
+			delete(body, "stream_options")
+			changed = true
+		}
+	case StreamOptionsIncludeUsage:
+		if stream, _ := body["stream"].(bool); stream {
+			options, _ := body["stream_options"].(map[string]any)
+			if options == nil {
+				options = map[string]any{}
+			}
+			if includeUsage, _ := options["include_usage"].(bool); !includeUsage {
+				options["include_usage"] = true
+				body["stream_options"] = options
+				changed = true
+			}
+		}
+	}
+
+	if !changed {
+		return bodyBytes, nil
+	}
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return bodyBytes, nil
+	}
+	return encoded, nil
+}
+
+// checkMessages rejects conversations longer than MaxMessages: messages (OpenAI, Anthropic),
+// contents (Gemini) or an input list (Responses).
+func (p *Policy) checkMessages(body map[string]any) *Violation {
+	if p.MaxMessages <= 0 {
+		return nil
+	}
+	for _, field := range []string{"messages", "contents", "input"} {
+		if list, ok := body[field].([]any); ok && len(list) > p.MaxMessages {
+			return &Violation{Field: field, Limit: p.MaxMessages, Actual: len(list)}
+		}
+	}
+	return nil
+}
+
+func (p *Policy) capTokens(params map[string]any, key, field string) (bool, *Violation) {
+	value, ok := params[key].(float64)
+	if !ok || value <= float64(p.MaxTokens) {
+		return false, nil
+	}
+	if p.MaxTokensAction == ActionReject {
+		return false, &Violation{Field: field, Limit: p.MaxTokens, Actual: value}
+	}
+	params[key] = p.MaxTokens
+	return true, nil
+}
+
+func (p *Policy) clampTemperature(params map[string]any) bool {
+	value, ok := params["temperature"].(float64)
+	if !ok {
+		return false
+	}
+	switch {
+	case p.TemperatureMin != nil && value < *p.TemperatureMin:
+		params["temperature"] = *p.TemperatureMin
+	case p.TemperatureMax != nil && value > *p.TemperatureMax:
+		params["temperature"] = *p.TemperatureMax
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package guardrails
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPolicyApply(t *testing.T) {
+	policy, err := Parse(map[string]any{"max_tokens": 100, "temperature_max": 1.0, "stream_options": "include_usage", "max_messages": 2})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	body, violation := policy.Apply([]byte(`{"model":"gpt-4o","max_tokens":500,"temperature":1.5,"stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	if violation != nil {
+		t.Fatalf("Apply() violation = %v", violation)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["max_tokens"] != 100.0 || got["temperature"] != 1.0 {
+		t.Errorf("Apply() = %s, want max_tokens 100 and temperature 1", body)
+	}
+	if options, _ := got["stream_options"].(map[string]any); options["include_usage"] != true {
+		t.Errorf("Apply() = %s, want stream_options.include_usage", body)
+	}
+
+	_, violation = policy.Apply([]byte(`{"messages":[{},{},{}]}`))
+	if violation == nil || violation.Field != "messages" || violation.Actual != 3 {
+		t.Errorf("Apply() violation = %+v, want messages over the limit", violation)
+	}
+
+	reject := &Policy{MaxTokens: 100, MaxTokensAction: ActionReject}
+	if _, violation := reject.Apply([]byte(`{"generationConfig":{"maxOutputTokens":200}}`)); violation == nil || violation.Field != "generationConfig.maxOutputTokens" {
+		t.Errorf("Apply() violation = %+v, want generationConfig.maxOutputTokens rejected", violation)
+	}
+
+	if _, err := Parse(map[string]any{"stream_options": "force"}); err == nil {
+		t.Error("Parse() should reject an unknown stream_options mode")
+	}
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/i18n"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// loginWithAccount signs in to the admin account and responds with a session token.
+func (s *Server) loginWithAccount(c *gin.Context, req LoginRequest) {
+	session, err := s.AdminAuth.Login(req.Username, req.Password, req.TOTPCode, c.ClientIP(), c.Request.UserAgent())
+	if err == nil {
+		c.JSON(http.StatusOK, LoginResponse{
+			Success:   true,
+			Message:   i18n.Message(c, "auth.authentication_successful"),
+			Token:     session.Token,
+			ExpiresAt: &session.ExpiresAt,
+		})
+		return
+	}
+
+	var svcErr *services.I18nError
+	if !errors.As(err, &svcErr) {
+		logrus.WithContext(c.Request.Context()).WithError(err).Error("Admin login failed")
+		c.JSON(http.StatusInternalServerError, LoginResponse{
+			Success: false,
+			Message: i18n.Message(c, "auth.authentication_failed"),
+		})
+		return
+	}
+	if svcErr.APIError == app_errors.ErrUnauthorized {
+		middleware.MarkAuthFailed(c)
+	}
+	c.JSON(svcErr.APIError.HTTPStatus, LoginResponse{
+		Success:      false,
+		Message:      i18n.Message(c, svcErr.MessageID, svcErr.Template),
+		TOTPRequired: svcErr.APIError == app_errors.ErrTOTPRequired,
+	})
+}
+
+// Logout ends the session the request was authenticated with.
+func (s *Server) Logout(c *gin.Context) {
+	if token := middleware.SessionToken(c); token != "" {
+		if err := s.AdminAuth.Logout(token); err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+	}
+	response.SuccessI18n(c, "auth.logout_success", nil)
+}
+
+// AccountResponse describes the admin account and the caller.
+type AccountResponse struct {
+	services.AdminAccount
+	// AuthMethod is how the caller is authenticated, "session", "sso" or "key".
+	AuthMethod string `json:"auth_method"`
+	Role       string `json:"role"`
+	// Identity is the user signed in with single sign-on.
+	Identity string `json:"identity,omitempty"`
+}
+
+// GetAccount returns the admin account.
+func (s *Server) GetAccount(c *gin.Context) {
+	account, err := s.AdminAuth.Account()
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, AccountResponse{
+		AdminAccount: *account,
+		AuthMethod:   middleware.AuthMethod(c),
+		Role:         middleware.AdminRole(c),
+		Identity:     middleware.AdminIdentity(c),
+	})
+}
+
+// SetPasswordRequest changes the password of the admin account. CurrentPassword is required
+// with a session; with AUTH_KEY, Username renames the account or names a new one.
+type SetPasswordRequest struct {
+	Username        string `json:"username"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+// SetPassword changes the password of the admin account, or creates the account when called
+// with AUTH_KEY and there is none. The other sessions of the account end.
+func (s *Server) SetPassword(c *gin.Context) {
+	var req SetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	breakGlass := middleware.AuthMethod(c) == middleware.AuthMethodKey
+	err := s.AdminAuth.SetPassword(req.Username, req.CurrentPassword, req.NewPassword, middleware.SessionToken(c), breakGlass)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.SuccessI18n(c, "auth.password_updated", nil)
+}
+
+// SetupTOTP generates a two-factor secret to be confirmed with EnableTOTP.
+func (s *Server) SetupTOTP(c *gin.Context) {
+	setup, err := s.AdminAuth.SetupTOTP()
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, setup)
+}
+
+// TOTPCodeRequest carries a code of the authenticator app.
+type TOTPCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// EnableTOTP turns two-factor authentication on with a code of the new secret.
+func (s *Server) EnableTOTP(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if s.handleGroupError(c, s.AdminAuth.EnableTOTP(req.Code)) {
+		return
+	}
+	response.SuccessI18n(c, "auth.totp_enabled", nil)
+}
+
+// DisableTOTP turns two-factor authentication off. A current code is required unless the
+// request is authenticated with AUTH_KEY.
+func (s *Server) DisableTOTP(c *gin.Context) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	breakGlass := middleware.AuthMethod(c) == middleware.AuthMethodKey
+	if s.handleGroupError(c, s.AdminAuth.DisableTOTP(req.Code, breakGlass)) {
+		return
+	}
+	response.SuccessI18n(c, "auth.totp_disabled", nil)
+}
+
+// AuthOptions tells the login page how it can sign in.
+type AuthOptions struct {
+	KeyLogin bool `json:"key_login"`
+	SSO      bool `json:"sso"`
+}
+
+// GetAuthOptions returns the sign-in methods enabled besides the admin account.
+func (s *Server) GetAuthOptions(c *gin.Context) {
+	response.Success(c, AuthOptions{
+		KeyLogin: s.config.GetAuthConfig().KeyLogin,
+		SSO:      s.OIDC.Enabled(),
+	})
+}
+
+const (
+	// oidcFlowCookie keeps the state, nonce and PKCE verifier of a single sign-on login until
+	// the identity provider redirects back.
+	oidcFlowCookie = "gpt_load_oidc"
+	oidcCookiePath = "/api/auth/oidc"
+	oidcFlowMaxAge = 10 * 60
+	ssoLoginPage   = "/login"
+)
+
+// OIDCLogin sends the browser to the identity provider to sign in.
+func (s *Server) OIDCLogin(c *gin.Context) {
+	authURL, flow, err := s.OIDC.Begin(c.Request.Context())
+	if err != nil {
+		s.redirectSSOError(c, err)
+		return
+	}
+	s.setOIDCFlowCookie(c, flow.Encode(), oidcFlowMaxAge)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback completes a single sign-on login and sends the browser to the login page with
+// the session token in the URL fragment, which is not sent to any server.
+func (s *Server) OIDCCallback(c *gin.Context) {
+	value, _ := c.Cookie(oidcFlowCookie)
+	s.setOIDCFlowCookie(c, "", -1)
+	if providerErr := c.Query("error"); providerErr != "" {
+		logrus.Warnf("OIDC identity provider rejected the login: %s %s", providerErr, c.Query("error_description"))
+		s.redirectSSOError(c, services.NewI18nError(app_errors.ErrUnauthorized, "auth.sso_failed", nil))
+		return
+	}
+
+	flow, _ := services.ParseOIDCFlow(value)
+	session, err := s.OIDC.Finish(c.Request.Context(), flow, c.Query("state"), c.Query("code"), c.ClientIP(), c.Request.UserAgent())
+	if err != nil {
+		s.redirectSSOError(c, err)
+		return
+	}
+	fragment := url.Values{
+		"sso_token":  {session.Token},
+		"expires_at": {session.ExpiresAt.Format(time.RFC3339)},
+	}
+	c.Redirect(http.StatusFound, ssoLoginPage+"#"+fragment.Encode())
+}
+
+// redirectSSOError sends the browser to the login page with the reason a single sign-on
+// login failed.
+func (s *Server) redirectSSOError(c *gin.Context, err error) {
+	message := i18n.Message(c, "auth.sso_failed")
+	var svcErr *services.I18nError
+	if errors.As(err, &svcErr) {
+		if svcErr.APIError == app_errors.ErrUnauthorized {
+			middleware.MarkAuthFailed(c)
+		}
+		message = i18n.Message(c, svcErr.MessageID, svcErr.Template)
+	} else {
+		logrus.WithContext(c.Request.Context()).WithError(err).Error("SSO login failed")
+	}
+	c.Redirect(http.StatusFound, ssoLoginPage+"#"+url.Values{"sso_error": {message}}.Encode())
+}
+
+func (s *Server) setOIDCFlowCookie(c *gin.Context, value string, maxAge int) {
+	secure := c.Request.TLS != nil || strings.HasPrefix(s.config.GetAuthConfig().OIDC.RedirectURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcFlowCookie, value, maxAge, oidcCookiePath, "", secure, true)
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"encoding/json"
 	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/response"
 
 	"github.com/gin-gonic/gin"
@@ -20,3 +22,36 @@ func (h *CommonHandler) GetChannelTypes(c *gin.Context) {
 	channelTypes := channel.GetChannels()
 	response.Success(c, channelTypes)
 }
+
+// CustomChannelDryRunRequest defines the payload for dry-running a custom channel definition.
+type CustomChannelDryRunRequest struct {
+	Definition json.RawMessage `json:"definition"`
+	channel.CustomDryRunInput
+}
+
+// DryRunCustomChannel validates a custom channel definition and shows how it shapes a sample exchange.
+// Nothing is sent upstream.
+func (h *CommonHandler) DryRunCustomChannel(c *gin.Context) {
+	var req CustomChannelDryRunRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	definition, err := channel.ParseCustomDefinition(req.Definition)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_channel_definition", map[string]any{"error": err.Error()})
+		return
+	}
+
+	result, err := definition.DryRun(req.CustomDryRunInput)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_dry_run_input", map[string]any{"error": err.Error()})
+		return
+	}
+
+	response.Success(c, gin.H{
+		"definition": definition,
+		"result":     result,
+	})
+}
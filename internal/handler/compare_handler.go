@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompareTargetRequest is a group, and optionally a model, a comparison prompt is sent to.
+type CompareTargetRequest struct {
+	GroupID uint `json:"group_id"`
+	// Model defaults to the test model of the group.
+	Model string `json:"model"`
+}
+
+// CompareRequest is a prompt to send to several groups and models side by side.
+type CompareRequest struct {
+	Prompt string `json:"prompt"`
+	// System is an optional system prompt.
+	System string `json:"system"`
+	// MaxTokens caps the output of each target, 256 by default.
+	MaxTokens int `json:"max_tokens"`
+	// TimeoutSeconds bounds the time each target is given, 60 by default.
+	TimeoutSeconds int                    `json:"timeout_seconds"`
+	Targets        []CompareTargetRequest `json:"targets"`
+}
+
+// Compare sends a prompt to several groups and models at once and returns their responses,
+// latency and token usage side by side.
+func (s *Server) Compare(c *gin.Context) {
+	var req CompareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if strings.TrimSpace(req.Prompt) == "" {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "prompt is required"))
+		return
+	}
+	if len(req.Targets) == 0 || len(req.Targets) > proxy.MaxCompareTargets {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("between 1 and %d targets are required", proxy.MaxCompareTargets)))
+		return
+	}
+	timeout := time.Duration(req.TimeoutSeconds) * time.Second
+	if req.MaxTokens < 0 || req.TimeoutSeconds < 0 || timeout > proxy.MaxCompareTimeout {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("max_tokens cannot be negative and timeout_seconds must be at most %d", int(proxy.MaxCompareTimeout.Seconds()))))
+		return
+	}
+
+	opts := proxy.CompareOptions{
+		Prompt:     req.Prompt,
+		System:     req.System,
+		MaxTokens:  req.MaxTokens,
+		Timeout:    timeout,
+		RemoteAddr: c.Request.RemoteAddr,
+	}
+	for _, target := range req.Targets {
+		group, ok := s.findGroupByID(c, target.GroupID)
+		if !ok {
+			return
+		}
+		if target.Model == "" && (group.TestModel == "" || group.TestModel == "-") {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, fmt.Sprintf("group %s has no test model, set the model of the target", group.Name)))
+			return
+		}
+		opts.Targets = append(opts.Targets, proxy.CompareTarget{Group: group, Model: strings.TrimSpace(target.Model)})
+	}
+
+	response.Success(c, s.ProxyServer.Compare(c.Request.Context(), opts))
+}
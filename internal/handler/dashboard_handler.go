@@ -1,12 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
 	"strings"
 	"time"
 
@@ -20,21 +23,26 @@ func (s *Server) Stats(c *gin.Context) {
 	s.DB.Model(&models.APIKey{}).Where("status = ?", models.KeyStatusActive).Count(&activeKeys)
 	s.DB.Model(&models.APIKey{}).Where("status = ?", models.KeyStatusInvalid).Count(&invalidKeys)
 
+	// The request, error rate and latency cards cover the selected range and compare it with
+	// the range of the same length before it
+	startTime, endTime, ok := parseStatsRange(c)
+	if !ok {
+		return
+	}
+
 	now := time.Now()
 	rpmStats, err := s.getRPMStats(now)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.rpm_stats_failed")
 		return
 	}
-	twentyFourHoursAgo := now.Add(-24 * time.Hour)
-	fortyEightHoursAgo := now.Add(-48 * time.Hour)
 
-	currentPeriod, err := s.getHourlyStats(twentyFourHoursAgo, now)
+	currentPeriod, err := s.getHourlyStats(startTime, endTime)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.current_stats_failed")
 		return
 	}
-	previousPeriod, err := s.getHourlyStats(fortyEightHoursAgo, twentyFourHoursAgo)
+	previousPeriod, err := s.getHourlyStats(startTime.Add(-endTime.Sub(startTime)), startTime)
 	if err != nil {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrDatabase, "database.previous_stats_failed")
 		return
@@ -88,6 +96,14 @@ func (s *Server) Stats(c *gin.Context) {
 		errorRateTrendIsGrowth = true
 	}
 
+	// 计算平均延迟及其趋势，延迟下降为正面
+	currentLatency := currentPeriod.avgLatency()
+	previousLatency := previousPeriod.avgLatency()
+	latencyTrend := 0.0
+	if previousLatency > 0 && currentLatency > 0 {
+		latencyTrend = (currentLatency - previousLatency) / previousLatency * 100
+	}
+
 	// 获取安全警告信息
 	securityWarnings := s.getSecurityWarnings(c)
 
@@ -108,23 +124,153 @@ func (s *Server) Stats(c *gin.Context) {
 			Trend:         errorRateTrend,
 			TrendIsGrowth: errorRateTrendIsGrowth,
 		},
+		AvgLatency: models.StatCard{
+			Value:         currentLatency,
+			Trend:         latencyTrend,
+			TrendIsGrowth: latencyTrend <= 0,
+		},
 		SecurityWarnings: securityWarnings,
+		Anomalies:        s.Anomalies.Active(),
 	}
 
+	maintenanceGroups := []models.MaintenanceGroup{}
+	if err := s.DB.Model(&models.Group{}).Where("enabled = ?", false).Order("sort asc, id desc").Find(&maintenanceGroups).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to list the groups in maintenance")
+	}
+	stats.MaintenanceGroups = maintenanceGroups
+
 	response.Success(c, stats)
 }
 
-// Chart Get dashboard chart data
+// KeyStatusQueue returns the saturation metrics of the key status update workers on this instance
+func (s *Server) KeyStatusQueue(c *gin.Context) {
+	response.Success(c, s.KeyProvider.StatusQueueStats())
+}
+
+// RequestLogWriterStats returns the buffer metrics of the request log writer on this instance
+func (s *Server) RequestLogWriterStats(c *gin.Context) {
+	response.Success(c, s.RequestLogWriter.Stats())
+}
+
+// MemoryStore returns the memory usage and eviction counters of the in-memory store on this
+// instance, or the state of its in-memory failover when Redis is used
+func (s *Server) MemoryStore(c *gin.Context) {
+	if failover, ok := s.Store.(*store.FailoverStore); ok {
+		response.Success(c, gin.H{"backend": "redis", "failover": failover.Status()})
+		return
+	}
+	memoryStore, ok := s.Store.(*store.MemoryStore)
+	if !ok {
+		response.Success(c, gin.H{"backend": "redis"})
+		return
+	}
+	response.Success(c, gin.H{"backend": "memory", "stats": memoryStore.MemoryStats()})
+}
+
+// RunStoreHygiene removes store data no longer backed by the database and reports what was reclaimed
+func (s *Server) RunStoreHygiene(c *gin.Context) {
+	report, err := s.StoreHygieneService.Run()
+	if errors.Is(err, services.ErrStoreHygieneRunning) {
+		response.Error(c, app_errors.ErrTaskInProgress)
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, report)
+}
+
+// RunPoolReconcile compares the key pools in the store with the database and repairs the drift,
+// or only reports it with ?dry_run=true
+func (s *Server) RunPoolReconcile(c *gin.Context) {
+	report, err := s.PoolReconcileService.Run(c.Query("dry_run") == "true")
+	if errors.Is(err, services.ErrPoolReconcileRunning) {
+		response.Error(c, app_errors.ErrTaskInProgress)
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, report)
+}
+
+// PoolReconcileStatus returns the report of the last pool reconciliation run by this instance
+func (s *Server) PoolReconcileStatus(c *gin.Context) {
+	response.Success(c, s.PoolReconcileService.LastReport())
+}
+
+// StoreFlushRequest selects the store data to flush.
+type StoreFlushRequest struct {
+	Scope   string `json:"scope" binding:"required"`
+	GroupID uint   `json:"group_id"`
+}
+
+// FlushStore clears the store data of one subsystem or one group instead of the whole store
+func (s *Server) FlushStore(c *gin.Context) {
+	var req StoreFlushRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	report, err := s.StoreFlushService.Flush(req.Scope, req.GroupID)
+	if errors.Is(err, services.ErrInvalidFlushScope) || errors.Is(err, services.ErrFlushScopeNeedsGroup) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, report)
+}
+
+// DrainStatus reports whether this instance is draining and how many proxy requests are still in flight
+func (s *Server) DrainStatus(c *gin.Context) {
+	response.Success(c, s.Drain.Status())
+}
+
+// StartDrain puts this instance into drain mode and shuts it down once in-flight requests have finished
+func (s *Server) StartDrain(c *gin.Context) {
+	s.Drain.Start("admin request")
+	response.Success(c, s.Drain.Status())
+}
+
+// ClusterStatus lists the live instances of the cluster and the one running the background jobs
+func (s *Server) ClusterStatus(c *gin.Context) {
+	status, err := s.Elector.Status()
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, status)
+}
+
+// ProviderStatus lists the unresolved incidents reported by the status pages of the providers
+func (s *Server) ProviderStatus(c *gin.Context) {
+	response.Success(c, s.ProviderMonitor.Status())
+}
+
+// ListUpstreamStats reports the latency and status codes of each upstream over the last hour on
+// this instance, of one group when the group query parameter is set.
+func (s *Server) ListUpstreamStats(c *gin.Context) {
+	response.Success(c, s.UpstreamStats.Snapshot(c.Query("group")))
+}
+
+// Chart Get dashboard chart data. Ranges longer than maxChartPoints hours are downsampled
+// into buckets of several hours.
 func (s *Server) Chart(c *gin.Context) {
 	groupID := c.Query("groupId")
-
-	now := time.Now()
-	endHour := now.Truncate(time.Hour)
-	startHour := endHour.Add(-23 * time.Hour)
+	startTime, endTime, ok := parseStatsRange(c)
+	if !ok {
+		return
+	}
 
 	var hourlyStats []models.GroupHourlyStat
 	query := s.DB.Table("group_hourly_stats").
-		Where("time >= ? AND time < ?", startHour, endHour.Add(time.Hour))
+		Where("time >= ? AND time < ?", startTime, endTime)
 	if groupID != "" {
 		query = query.Where("group_id = ?", groupID)
 	} else {
@@ -136,29 +282,30 @@ func (s *Server) Chart(c *gin.Context) {
 		return
 	}
 
-	statsByHour := make(map[time.Time]map[string]int64)
+	hours := int(endTime.Sub(startTime) / time.Hour)
+	bucketHours := chartBucketHours(hours)
+	bucket := time.Duration(bucketHours) * time.Hour
+	points := (hours + bucketHours - 1) / bucketHours
+
+	successData := make([]int64, points)
+	failureData := make([]int64, points)
+	durations := make([]int64, points)
 	for _, stat := range hourlyStats {
-		hour := stat.Time.Local().Truncate(time.Hour)
-		if _, ok := statsByHour[hour]; !ok {
-			statsByHour[hour] = make(map[string]int64)
+		i := int(stat.Time.Sub(startTime) / bucket)
+		if i < 0 || i >= points {
+			continue
 		}
-		statsByHour[hour]["success"] += stat.SuccessCount
-		statsByHour[hour]["failure"] += stat.FailureCount
+		successData[i] += stat.SuccessCount
+		failureData[i] += stat.FailureCount
+		durations[i] += stat.DurationMs
 	}
 
-	var labels []string
-	var successData, failureData []int64
-
-	for i := range 24 {
-		hour := startHour.Add(time.Duration(i) * time.Hour)
-		labels = append(labels, hour.Format(time.RFC3339))
-
-		if data, ok := statsByHour[hour]; ok {
-			successData = append(successData, data["success"])
-			failureData = append(failureData, data["failure"])
-		} else {
-			successData = append(successData, 0)
-			failureData = append(failureData, 0)
+	labels := make([]string, points)
+	avgLatency := make([]int64, points)
+	for i := range points {
+		labels[i] = startTime.Add(time.Duration(i) * bucket).Format(time.RFC3339)
+		if requests := successData[i] + failureData[i]; requests > 0 {
+			avgLatency[i] = durations[i] / requests
 		}
 	}
 
@@ -176,14 +323,89 @@ func (s *Server) Chart(c *gin.Context) {
 				Color: "rgba(255, 70, 70, 1)",
 			},
 		},
+		BucketHours:  bucketHours,
+		AvgLatencyMs: avgLatency,
 	}
 
 	response.Success(c, chartData)
 }
 
+const (
+	// maxChartPoints is the number of points of a chart above which it is downsampled.
+	maxChartPoints = 168
+	// maxStatsRange is the longest range of the dashboard statistics.
+	maxStatsRange = 366 * 24 * time.Hour
+)
+
+// statsRanges are the preset ranges of the dashboard, ending with the current hour.
+var statsRanges = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+// parseStatsRange reads the range of the dashboard statistics: range=24h (the default), 7d or
+// 30d, or range=custom with start and end in RFC 3339. The range is widened to whole hours.
+// It writes the error response itself when the range is invalid.
+func parseStatsRange(c *gin.Context) (time.Time, time.Time, bool) {
+	rangeName := c.DefaultQuery("range", "24h")
+	if rangeName != "custom" {
+		length, ok := statsRanges[rangeName]
+		if !ok {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_time_range")
+			return time.Time{}, time.Time{}, false
+		}
+		end := time.Now().Truncate(time.Hour).Add(time.Hour)
+		return end.Add(-length), end, true
+	}
+
+	start, err := time.Parse(time.RFC3339, c.Query("start"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_time_range")
+		return time.Time{}, time.Time{}, false
+	}
+	end, err := time.Parse(time.RFC3339, c.Query("end"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_time_range")
+		return time.Time{}, time.Time{}, false
+	}
+	start = start.Local().Truncate(time.Hour)
+	if truncated := end.Local().Truncate(time.Hour); truncated.Equal(end) {
+		end = truncated
+	} else {
+		end = truncated.Add(time.Hour)
+	}
+	if !end.After(start) || end.Sub(start) > maxStatsRange {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_time_range")
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// chartBucketHours returns the hours per point that keep a chart of the given hours within
+// maxChartPoints points.
+func chartBucketHours(hours int) int {
+	for _, size := range []int{1, 2, 3, 6, 12, 24} {
+		if hours <= size*maxChartPoints {
+			return size
+		}
+	}
+	days := (hours + 24*maxChartPoints - 1) / (24 * maxChartPoints)
+	return days * 24
+}
+
 type hourlyStatResult struct {
-	TotalRequests int64
-	TotalFailures int64
+	TotalRequests   int64
+	TotalFailures   int64
+	TotalDurationMs int64
+}
+
+// avgLatency returns the average request duration in milliseconds, 0 without requests.
+func (r hourlyStatResult) avgLatency() float64 {
+	if r.TotalRequests == 0 {
+		return 0
+	}
+	return float64(r.TotalDurationMs) / float64(r.TotalRequests)
 }
 
 func (s *Server) getHourlyStats(startTime, endTime time.Time) (hourlyStatResult, error) {
@@ -192,7 +414,7 @@ func (s *Server) getHourlyStats(startTime, endTime time.Time) (hourlyStatResult,
 		Where("time >= ? AND time < ?", startTime, endTime).
 		Where("group_id NOT IN (?)",
 			s.DB.Table("groups").Select("id").Where("group_type = ?", "aggregate")).
-		Select("COALESCE(SUM(success_count), 0) + COALESCE(SUM(failure_count), 0) as total_requests, COALESCE(SUM(failure_count), 0) as total_failures").
+		Select("COALESCE(SUM(success_count), 0) + COALESCE(SUM(failure_count), 0) as total_requests, COALESCE(SUM(failure_count), 0) as total_failures, COALESCE(SUM(duration_ms), 0) as total_duration_ms").
 		Scan(&result).Error
 	return result, err
 }
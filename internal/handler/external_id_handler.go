@@ -0,0 +1,126 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GroupUpsertRequest is the desired state of a group managed by external ID. Fields left
+// empty are cleared, except enabled and maintenance_message which are kept when omitted.
+type GroupUpsertRequest struct {
+	GroupCreateRequest
+	Enabled            *bool   `json:"enabled,omitempty"`
+	MaintenanceMessage *string `json:"maintenance_message,omitempty"`
+}
+
+// GetGroupByExternalID returns the group carrying an external ID.
+func (s *Server) GetGroupByExternalID(c *gin.Context) {
+	group, err := s.GroupService.FindGroupByExternalID(c.Request.Context(), c.Param("externalId"))
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, s.newGroupResponse(c, group))
+}
+
+// UpsertGroupByExternalID creates or updates the group carrying an external ID, so that
+// repeating the same request leaves the group unchanged.
+func (s *Server) UpsertGroupByExternalID(c *gin.Context) {
+	var req GroupUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !s.applyGroupTemplate(c, &req.GroupCreateRequest) {
+		return
+	}
+
+	group, created, err := s.GroupService.UpsertGroupByExternalID(c.Request.Context(), c.Param("externalId"), req.createParams(), req.Enabled, req.MaintenanceMessage)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	if created {
+		logrus.WithField("external_id", c.Param("externalId")).Infof("Group %s created by external ID", group.Name)
+	}
+	response.Success(c, s.newGroupResponse(c, group))
+}
+
+// DeleteGroupByExternalID moves the group carrying an external ID to the trash.
+func (s *Server) DeleteGroupByExternalID(c *gin.Context) {
+	group, err := s.GroupService.FindGroupByExternalID(c.Request.Context(), c.Param("externalId"))
+	if s.handleGroupError(c, err) {
+		return
+	}
+	if s.handleGroupError(c, s.GroupService.DeleteGroup(c.Request.Context(), group.ID)) {
+		return
+	}
+	response.SuccessI18n(c, "success.group_deleted", nil)
+}
+
+// KeyUpsertRequest is the desired state of a key managed by external ID. Notes and tags are
+// kept when omitted.
+type KeyUpsertRequest struct {
+	GroupID  uint     `json:"group_id" binding:"required"`
+	KeyValue string   `json:"key_value" binding:"required"`
+	Notes    *string  `json:"notes,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// GetKeyByExternalID returns the key carrying an external ID.
+func (s *Server) GetKeyByExternalID(c *gin.Context) {
+	key, err := s.KeyService.FindKeyByExternalID(c.Param("externalId"))
+	if s.handleGroupError(c, err) {
+		return
+	}
+	s.respondWithKey(c, key)
+}
+
+// UpsertKeyByExternalID creates the key carrying an external ID, or updates its notes and tags.
+func (s *Server) UpsertKeyByExternalID(c *gin.Context) {
+	var req KeyUpsertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	params := services.KeyUpsertParams{GroupID: req.GroupID, KeyValue: req.KeyValue, Notes: req.Notes}
+	if req.Tags != nil {
+		tags, ok := validateKeyTags(c, req.Tags)
+		if !ok {
+			return
+		}
+		params.Tags = &tags
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	key, _, err := s.KeyService.UpsertKeyByExternalID(c.Param("externalId"), params)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	s.respondWithKey(c, key)
+}
+
+// DeleteKeyByExternalID moves the key carrying an external ID to the trash.
+func (s *Server) DeleteKeyByExternalID(c *gin.Context) {
+	if s.handleGroupError(c, s.KeyService.DeleteKeyByExternalID(c.Param("externalId"))) {
+		return
+	}
+	response.Success(c, nil)
+}
+
+// respondWithKey responds with a key and its value masked as the key list does.
+func (s *Server) respondWithKey(c *gin.Context, key *models.APIKey) {
+	view := s.KeyMaskingService.ViewForRequest(c, middleware.AdminRole(c))
+	result := *key
+	result.KeyValue = view.Show(key.KeyValue, key.KeyHash)
+	view.Audit(key.GroupID)
+	response.Success(c, result)
+}
@@ -3,20 +3,29 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
+	"gpt-load/internal/channel"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/features"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/templates"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 func (s *Server) handleGroupError(c *gin.Context, err error) bool {
@@ -54,6 +63,7 @@ type GroupCreateRequest struct {
 	GroupType           string              `json:"group_type"` // 'standard' or 'aggregate'
 	Upstreams           json.RawMessage     `json:"upstreams"`
 	ChannelType         string              `json:"channel_type"`
+	ChannelDefinition   json.RawMessage     `json:"channel_definition"`
 	Sort                int                 `json:"sort"`
 	TestModel           string              `json:"test_model"`
 	ValidationEndpoint  string              `json:"validation_endpoint"`
@@ -63,23 +73,71 @@ type GroupCreateRequest struct {
 	Config              map[string]any      `json:"config"`
 	HeaderRules         []models.HeaderRule `json:"header_rules"`
 	ProxyKeys           string              `json:"proxy_keys"`
+	// ExternalID is a stable ID set by a declarative tool, see UpsertGroupByExternalID.
+	ExternalID string `json:"external_id,omitempty"`
+	// Template is the ID of a group template filling the fields left empty.
+	Template string `json:"template,omitempty"`
 }
 
-// CreateGroup handles the creation of a new group.
-func (s *Server) CreateGroup(c *gin.Context) {
-	var req GroupCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
-		return
+// applyTemplate fills the fields of the request left empty from the template, and the
+// config options the request does not set from the recommended config.
+func (req *GroupCreateRequest) applyTemplate(template templates.Template) error {
+	if req.DisplayName == "" {
+		req.DisplayName = template.Name
+	}
+	if req.ChannelType == "" {
+		req.ChannelType = template.ChannelType
+	}
+	if len(req.Upstreams) == 0 {
+		upstreams, err := json.Marshal(template.Upstreams)
+		if err != nil {
+			return err
+		}
+		req.Upstreams = upstreams
 	}
+	if req.TestModel == "" {
+		req.TestModel = template.TestModel
+	}
+	if req.ValidationEndpoint == "" {
+		req.ValidationEndpoint = template.ValidationEndpoint
+	}
+	if len(template.Config) > 0 {
+		config := make(map[string]any, len(template.Config)+len(req.Config))
+		maps.Copy(config, template.Config)
+		maps.Copy(config, req.Config)
+		req.Config = config
+	}
+	return nil
+}
 
-	params := services.GroupCreateParams{
+// applyGroupTemplate fills the request from its template, if any. It writes the error response
+// and returns false when the template cannot be applied.
+func (s *Server) applyGroupTemplate(c *gin.Context, req *GroupCreateRequest) bool {
+	if req.Template == "" {
+		return true
+	}
+	template, ok := s.Templates.Get(req.Template)
+	if !ok {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.unknown_group_template", map[string]any{"template": req.Template})
+		return false
+	}
+	if err := req.applyTemplate(template); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return false
+	}
+	return true
+}
+
+// createParams converts the request into the parameters of the group service.
+func (req *GroupCreateRequest) createParams() services.GroupCreateParams {
+	return services.GroupCreateParams{
 		Name:                req.Name,
 		DisplayName:         req.DisplayName,
 		Description:         req.Description,
 		GroupType:           req.GroupType,
 		Upstreams:           req.Upstreams,
 		ChannelType:         req.ChannelType,
+		ChannelDefinition:   req.ChannelDefinition,
 		Sort:                req.Sort,
 		TestModel:           req.TestModel,
 		ValidationEndpoint:  req.ValidationEndpoint,
@@ -89,14 +147,97 @@ func (s *Server) CreateGroup(c *gin.Context) {
 		Config:              req.Config,
 		HeaderRules:         req.HeaderRules,
 		ProxyKeys:           req.ProxyKeys,
+		ExternalID:          req.ExternalID,
 	}
+}
 
+// CreateGroup handles the creation of a new group.
+func (s *Server) CreateGroup(c *gin.Context) {
+	var req GroupCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !s.applyGroupTemplate(c, &req) {
+		return
+	}
+
+	params := req.createParams()
 	group, err := s.GroupService.CreateGroup(c.Request.Context(), params)
 	if s.handleGroupError(c, err) {
 		return
 	}
 
-	response.Success(c, s.newGroupResponse(group))
+	response.Success(c, s.newGroupResponse(c, group))
+}
+
+// GroupValidateRequest defines the payload for checking a group before saving it.
+type GroupValidateRequest struct {
+	GroupCreateRequest
+	// ID is the group being edited, if any.
+	ID uint `json:"id"`
+	// SkipConnectivity skips sending a request to every upstream.
+	SkipConnectivity bool `json:"skip_connectivity"`
+	// TestKey, when set, is used for a live test call of the test model. It is not stored.
+	TestKey string `json:"test_key"`
+}
+
+// GroupFieldErrorResponse is a problem found in a field of a group payload.
+type GroupFieldErrorResponse struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// GroupValidationResponse is the outcome of checking a group before saving it.
+type GroupValidationResponse struct {
+	// Valid is set when every field is valid, every upstream is reachable and the live test, if
+	// any, succeeded.
+	Valid        bool                           `json:"valid"`
+	Errors       []GroupFieldErrorResponse      `json:"errors"`
+	Connectivity []channel.UpstreamConnectivity `json:"connectivity,omitempty"`
+	LiveTest     *services.GroupLiveTest        `json:"live_test,omitempty"`
+}
+
+// ValidateGroup handles checking a group payload without saving it.
+func (s *Server) ValidateGroup(c *gin.Context) {
+	var req GroupValidateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !s.applyGroupTemplate(c, &req.GroupCreateRequest) {
+		return
+	}
+
+	validation, err := s.GroupService.ValidateGroup(c.Request.Context(), services.GroupValidationParams{
+		GroupCreateParams: req.createParams(),
+		GroupID:           req.ID,
+		SkipConnectivity:  req.SkipConnectivity,
+		TestKey:           req.TestKey,
+	})
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	resp := GroupValidationResponse{
+		Valid:        len(validation.Errors) == 0,
+		Errors:       make([]GroupFieldErrorResponse, 0, len(validation.Errors)),
+		Connectivity: validation.Connectivity,
+		LiveTest:     validation.LiveTest,
+	}
+	for _, fieldErr := range validation.Errors {
+		resp.Errors = append(resp.Errors, GroupFieldErrorResponse{
+			Field:   fieldErr.Field,
+			Message: i18n.Message(c, fieldErr.Err.MessageID, fieldErr.Err.Template),
+		})
+	}
+	for _, upstream := range validation.Connectivity {
+		resp.Valid = resp.Valid && upstream.Reachable
+	}
+	if validation.LiveTest != nil {
+		resp.Valid = resp.Valid && validation.LiveTest.Success
+	}
+	response.Success(c, resp)
 }
 
 // ListGroups handles listing all groups.
@@ -108,7 +249,7 @@ func (s *Server) ListGroups(c *gin.Context) {
 
 	groupResponses := make([]GroupResponse, 0, len(groups))
 	for i := range groups {
-		groupResponses = append(groupResponses, *s.newGroupResponse(&groups[i]))
+		groupResponses = append(groupResponses, *s.newGroupResponse(c, &groups[i]))
 	}
 
 	response.Success(c, groupResponses)
@@ -123,7 +264,10 @@ type GroupUpdateRequest struct {
 	GroupType           *string             `json:"group_type,omitempty"`
 	Upstreams           json.RawMessage     `json:"upstreams"`
 	ChannelType         *string             `json:"channel_type,omitempty"`
+	ChannelDefinition   json.RawMessage     `json:"channel_definition"`
 	Sort                *int                `json:"sort"`
+	Enabled             *bool               `json:"enabled,omitempty"`
+	MaintenanceMessage  *string             `json:"maintenance_message,omitempty"`
 	TestModel           string              `json:"test_model"`
 	ValidationEndpoint  *string             `json:"validation_endpoint,omitempty"`
 	ParamOverrides      map[string]any      `json:"param_overrides"`
@@ -186,6 +330,8 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		GroupType:           req.GroupType,
 		ChannelType:         req.ChannelType,
 		Sort:                req.Sort,
+		Enabled:             req.Enabled,
+		MaintenanceMessage:  req.MaintenanceMessage,
 		ValidationEndpoint:  req.ValidationEndpoint,
 		ParamOverrides:      req.ParamOverrides,
 		ModelRedirectRules:  req.ModelRedirectRules,
@@ -199,6 +345,11 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		params.HasUpstreams = true
 	}
 
+	if req.ChannelDefinition != nil {
+		params.ChannelDefinition = req.ChannelDefinition
+		params.HasChannelDefinition = true
+	}
+
 	if req.TestModel != "" {
 		params.TestModel = req.TestModel
 		params.HasTestModel = true
@@ -214,7 +365,7 @@ func (s *Server) UpdateGroup(c *gin.Context) {
 		return
 	}
 
-	response.Success(c, s.newGroupResponse(group))
+	response.Success(c, s.newGroupResponse(c, group))
 }
 
 // ReorderGroups handles batch reorder updates for groups.
@@ -244,17 +395,76 @@ func (s *Server) ReorderGroups(c *gin.Context) {
 	response.SuccessI18n(c, "success.groups_reordered", nil)
 }
 
+// GroupBulkRequest selects the groups of a bulk operation by ID, by channel type, or both.
+type GroupBulkRequest struct {
+	GroupIDs    []uint `json:"group_ids"`
+	ChannelType string `json:"channel_type"`
+}
+
+func (req GroupBulkRequest) selector() services.GroupSelector {
+	return services.GroupSelector{GroupIDs: req.GroupIDs, ChannelType: req.ChannelType}
+}
+
+// GroupBulkEnabledRequest defines the payload for enabling or disabling groups.
+type GroupBulkEnabledRequest struct {
+	GroupBulkRequest
+	Enabled *bool `json:"enabled" binding:"required"`
+	// MaintenanceMessage, when set, replaces the message disabled groups answer with.
+	MaintenanceMessage *string `json:"maintenance_message,omitempty"`
+}
+
+// GroupBulkConfigRequest defines the payload for setting config fields on groups. A null
+// value removes the field from the groups.
+type GroupBulkConfigRequest struct {
+	GroupBulkRequest
+	Config map[string]any `json:"config"`
+}
+
+// BulkSetGroupsEnabled handles enabling or disabling several groups at once.
+func (s *Server) BulkSetGroupsEnabled(c *gin.Context) {
+	var req GroupBulkEnabledRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	result, err := s.GroupService.SetGroupsEnabled(c.Request.Context(), req.selector(), *req.Enabled, req.MaintenanceMessage)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, result)
+}
+
+// BulkUpdateGroupsConfig handles setting config fields on several groups at once.
+func (s *Server) BulkUpdateGroupsConfig(c *gin.Context) {
+	var req GroupBulkConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	result, err := s.GroupService.UpdateGroupsConfig(c.Request.Context(), req.selector(), req.Config)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, result)
+}
+
 // GroupResponse defines the structure for a group response, excluding sensitive or large fields.
 type GroupResponse struct {
 	ID                  uint                `json:"id"`
 	Name                string              `json:"name"`
+	ExternalID          *string             `json:"external_id,omitempty"`
 	Endpoint            string              `json:"endpoint"`
 	DisplayName         string              `json:"display_name"`
 	Description         string              `json:"description"`
 	GroupType           string              `json:"group_type"`
 	Upstreams           datatypes.JSON      `json:"upstreams"`
 	ChannelType         string              `json:"channel_type"`
+	ChannelDefinition   datatypes.JSON      `json:"channel_definition,omitempty"`
 	Sort                int                 `json:"sort"`
+	Enabled             bool                `json:"enabled"`
+	MaintenanceMessage  string              `json:"maintenance_message"`
 	TestModel           string              `json:"test_model"`
 	ValidationEndpoint  string              `json:"validation_endpoint"`
 	ParamOverrides      datatypes.JSONMap   `json:"param_overrides"`
@@ -268,8 +478,10 @@ type GroupResponse struct {
 	UpdatedAt           time.Time           `json:"updated_at"`
 }
 
-// newGroupResponse creates a new GroupResponse from a models.Group.
-func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
+// newGroupResponse creates a new GroupResponse from a models.Group, with its secrets masked
+// and its proxy keys as the role of the caller sees them.
+func (s *Server) newGroupResponse(c *gin.Context, group *models.Group) *GroupResponse {
+	group = services.MaskGroupSecrets(s.EncryptionSvc, group)
 	appURL := s.SettingsManager.GetAppUrl()
 	endpoint := ""
 	if appURL != "" {
@@ -292,13 +504,17 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 	return &GroupResponse{
 		ID:                  group.ID,
 		Name:                group.Name,
+		ExternalID:          group.ExternalID,
 		Endpoint:            endpoint,
 		DisplayName:         group.DisplayName,
 		Description:         group.Description,
 		GroupType:           group.GroupType,
 		Upstreams:           group.Upstreams,
 		ChannelType:         group.ChannelType,
+		ChannelDefinition:   group.ChannelDefinition,
 		Sort:                group.Sort,
+		Enabled:             group.Enabled,
+		MaintenanceMessage:  group.MaintenanceMessage,
 		TestModel:           group.TestModel,
 		ValidationEndpoint:  group.ValidationEndpoint,
 		ParamOverrides:      group.ParamOverrides,
@@ -306,7 +522,7 @@ func (s *Server) newGroupResponse(group *models.Group) *GroupResponse {
 		ModelRedirectStrict: group.ModelRedirectStrict,
 		Config:              group.Config,
 		HeaderRules:         headerRules,
-		ProxyKeys:           group.ProxyKeys,
+		ProxyKeys:           services.MaskProxyKeys(group.ProxyKeys, middleware.AdminRole(c)),
 		LastValidatedAt:     group.LastValidatedAt,
 		CreatedAt:           group.CreatedAt,
 		UpdatedAt:           group.UpdatedAt,
@@ -364,6 +580,59 @@ func (s *Server) GetGroupConfigOptions(c *gin.Context) {
 	response.Success(c, translated)
 }
 
+// ListGroupTemplates lists the group templates groups can be created from.
+func (s *Server) ListGroupTemplates(c *gin.Context) {
+	response.Success(c, s.Templates.All())
+}
+
+// FeatureFlagInfo describes a feature flag and, when a group is given, its state in that group.
+type FeatureFlagInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+	Enabled     *bool  `json:"enabled,omitempty"`
+}
+
+// ListFeatureFlags lists the feature flags groups can switch, with their state in the group given by group_id.
+func (s *Server) ListFeatureFlags(c *gin.Context) {
+	var groupFlags features.Flags
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		groupID, err := strconv.Atoi(groupIDStr)
+		if err != nil || groupID <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return
+		}
+		group, ok := s.findGroupByID(c, uint(groupID))
+		if !ok {
+			return
+		}
+		if groupFlags, err = features.Parse(group.Config["feature_flags"]); err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+			return
+		}
+		if groupFlags == nil {
+			groupFlags = features.Flags{}
+		}
+	}
+
+	flags := features.All()
+	result := make([]FeatureFlagInfo, 0, len(flags))
+	for _, flag := range flags {
+		info := FeatureFlagInfo{
+			Name:        flag.Name,
+			Description: i18n.Message(c, flag.Description),
+			Default:     flag.Default,
+		}
+		if groupFlags != nil {
+			enabled := groupFlags.Enabled(flag.Name)
+			info.Enabled = &enabled
+		}
+		result = append(result, info)
+	}
+
+	response.Success(c, result)
+}
+
 // calculateRequestStats is a helper to compute request statistics.
 func (s *Server) GetGroupStats(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -380,6 +649,234 @@ func (s *Server) GetGroupStats(c *gin.Context) {
 	response.Success(c, stats)
 }
 
+// GetGroupCanary handles reporting the canary probes of a group over the last hours (24 by default).
+func (s *Server) GetGroupCanary(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	hours := 24
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours <= 0 || hours > proxy.MaxCanaryHistoryHours {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("hours must be between 1 and %d", proxy.MaxCanaryHistoryHours)))
+			return
+		}
+	}
+
+	var group models.Group
+	if err := s.DB.Select("id").First(&group, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	history, err := s.Canary.History(group.ID, hours)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, history)
+}
+
+// GetGroupPool handles showing which keys of a group are in its key pool.
+func (s *Server) GetGroupPool(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	membership, err := s.GroupService.GetPoolMembership(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, membership)
+}
+
+// DiscoverGroupModels lists the models the upstreams of a group serve, from the cache unless
+// refresh=true is given. Aggregate groups list the models of their sub-groups.
+func (s *Server) DiscoverGroupModels(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	dbGroup, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+	group, err := s.GroupManager.GetGroupByName(dbGroup.Name)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	discovered, err := s.ModelDiscoveryService.Discover(c.Request.Context(), group, c.Query("refresh") == "true")
+	if err != nil {
+		if errors.Is(err, app_errors.ErrNoActiveKeys) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		return
+	}
+
+	response.Success(c, discovered)
+}
+
+// PoolMoveRequest defines the payload for moving a key in or out of rotation.
+type PoolMoveRequest struct {
+	To              string `json:"to"`
+	CooldownSeconds int    `json:"cooldown_seconds"`
+}
+
+// MoveGroupPoolKey handles moving a key of a group in or out of rotation.
+func (s *Server) MoveGroupPoolKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	keyID, err := strconv.Atoi(c.Param("keyId"))
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Invalid key ID format"))
+		return
+	}
+
+	var req PoolMoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	membership, err := s.GroupService.MovePoolKey(c.Request.Context(), uint(id), uint(keyID), req.To, req.CooldownSeconds)
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, membership)
+}
+
+// RebuildGroupPool handles rebuilding the key pool of a group from the database.
+func (s *Server) RebuildGroupPool(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	membership, err := s.GroupService.RebuildKeyPool(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, membership)
+}
+
+// RecoverGroupCooledKeys handles returning the keys whose cooldown is over to the pool of a group.
+func (s *Server) RecoverGroupCooledKeys(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	recovered, err := s.GroupService.RecoverCooledKeys(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, gin.H{"recovered": recovered})
+}
+
+// ReserveStockRequest defines the payload for parking keys in the reserve of a group.
+type ReserveStockRequest struct {
+	KeysText string `json:"keys_text" binding:"required"`
+}
+
+// GetGroupReserve handles listing the reserve of a group.
+func (s *Server) GetGroupReserve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	reserve, err := s.GroupService.GetKeyReserve(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, reserve)
+}
+
+// StockGroupReserve handles parking new keys in the reserve of a group.
+func (s *Server) StockGroupReserve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	var req ReserveStockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+	if !validateKeysText(c, req.KeysText) {
+		return
+	}
+
+	result, err := s.GroupService.StockKeyReserve(c.Request.Context(), uint(id), req.KeysText)
+	if err != nil && (strings.Contains(err.Error(), "batch size exceeds the limit") || err.Error() == "no valid keys found in the input text") {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// DrainGroupReserve handles removing the keys parked in the reserve of a group.
+func (s *Server) DrainGroupReserve(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	removed, err := s.GroupService.DrainKeyReserve(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.SuccessI18n(c, "success.reserve_drained", nil, map[string]any{"count": removed})
+}
+
+// PurgeGroupResponseCache handles dropping all cached responses of a group.
+func (s *Server) PurgeGroupResponseCache(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	if s.handleGroupError(c, s.GroupService.PurgeResponseCache(c.Request.Context(), uint(id))) {
+		return
+	}
+
+	response.SuccessI18n(c, "success.response_cache_purged", nil)
+}
+
 // GroupCopyRequest defines the payload for copying a group.
 type GroupCopyRequest struct {
 	CopyKeys string `json:"copy_keys"` // "none"|"valid_only"|"all"
@@ -410,7 +907,7 @@ func (s *Server) CopyGroup(c *gin.Context) {
 		return
 	}
 
-	groupResponse := s.newGroupResponse(newGroup)
+	groupResponse := s.newGroupResponse(c, newGroup)
 	copyResponse := &GroupCopyResponse{
 		Group: groupResponse,
 	}
@@ -538,3 +1035,33 @@ func (s *Server) GetParentAggregateGroups(c *gin.Context) {
 
 	response.Success(c, parentGroups)
 }
+
+// TestGroupConnectivity checks that every upstream of a group can be reached through its outbound proxy.
+func (s *Server) TestGroupConnectivity(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+	dbGroup, ok := s.findGroupByID(c, uint(id))
+	if !ok {
+		return
+	}
+	if dbGroup.GroupType == "aggregate" {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.aggregate_no_upstreams")
+		return
+	}
+
+	group, err := s.GroupManager.GetGroupByName(dbGroup.Name)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	channelHandler, err := s.ChannelFactory.GetChannel(group)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+
+	response.Success(c, channelHandler.TestConnectivity(c.Request.Context()))
+}
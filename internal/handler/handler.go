@@ -6,11 +6,24 @@ import (
 	"net/http"
 	"time"
 
+	"gpt-load/internal/channel"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
+	"gpt-load/internal/db"
+	"gpt-load/internal/drain"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/inflight"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/netacl"
+	"gpt-load/internal/providerstatus"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/templates"
 	"gpt-load/internal/types"
+	"gpt-load/internal/upstreamstats"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/dig"
@@ -31,8 +44,37 @@ type Server struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	KeyMaskingService          *services.KeyMaskingService
+	AdminAuth                  *services.AdminAuthService
+	OIDC                       *services.OIDCService
+	StoreHygieneService        *services.StoreHygieneService
+	PoolReconcileService       *services.PoolReconcileService
+	LogStreamService           *services.LogStreamService
+	DebugCaptureService        *services.DebugCaptureService
+	StoreFlushService          *services.StoreFlushService
+	UsageSnapshotService       *services.UsageSnapshotService
+	TrashService               *services.TrashService
+	KeyRotationService         *services.KeyRotationService
+	ModelDiscoveryService      *services.ModelDiscoveryService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
+	KeyProvider                *keypool.KeyProvider
+	ChannelFactory             *channel.Factory
+	DBHealth                   *db.HealthMonitor
+	Drain                      *drain.Controller
+	Elector                    *cluster.Elector
+	ProviderMonitor            *providerstatus.Monitor
+	InFlight                   *inflight.Registry
+	NetworkRejections          *netacl.Rejections
+	IPThrottle                 *services.IPThrottleService
+	HoneypotService            *services.HoneypotService
+	UpstreamStats              *upstreamstats.Tracker
+	Anomalies                  *services.AnomalyService
+	ProxyServer                *proxy.ProxyServer
+	Canary                     *proxy.CanaryRunner
+	RequestLogWriter           *services.RequestLogWriter
+	Store                      store.Store
+	Templates                  *templates.Catalog
 }
 
 // NewServerParams defines the dependencies for the NewServer constructor.
@@ -50,8 +92,37 @@ type NewServerParams struct {
 	KeyImportService           *services.KeyImportService
 	KeyDeleteService           *services.KeyDeleteService
 	LogService                 *services.LogService
+	KeyMaskingService          *services.KeyMaskingService
+	AdminAuth                  *services.AdminAuthService
+	OIDC                       *services.OIDCService
+	StoreHygieneService        *services.StoreHygieneService
+	PoolReconcileService       *services.PoolReconcileService
+	LogStreamService           *services.LogStreamService
+	DebugCaptureService        *services.DebugCaptureService
+	StoreFlushService          *services.StoreFlushService
+	UsageSnapshotService       *services.UsageSnapshotService
+	TrashService               *services.TrashService
+	KeyRotationService         *services.KeyRotationService
+	ModelDiscoveryService      *services.ModelDiscoveryService
 	CommonHandler              *CommonHandler
 	EncryptionSvc              encryption.Service
+	KeyProvider                *keypool.KeyProvider
+	ChannelFactory             *channel.Factory
+	DBHealth                   *db.HealthMonitor
+	Drain                      *drain.Controller
+	Elector                    *cluster.Elector
+	ProviderMonitor            *providerstatus.Monitor
+	InFlight                   *inflight.Registry
+	NetworkRejections          *netacl.Rejections
+	IPThrottle                 *services.IPThrottleService
+	HoneypotService            *services.HoneypotService
+	UpstreamStats              *upstreamstats.Tracker
+	Anomalies                  *services.AnomalyService
+	ProxyServer                *proxy.ProxyServer
+	Canary                     *proxy.CanaryRunner
+	RequestLogWriter           *services.RequestLogWriter
+	Store                      store.Store
+	Templates                  *templates.Catalog
 }
 
 // NewServer creates a new handler instance with dependencies injected by dig.
@@ -69,26 +140,65 @@ func NewServer(params NewServerParams) *Server {
 		KeyImportService:           params.KeyImportService,
 		KeyDeleteService:           params.KeyDeleteService,
 		LogService:                 params.LogService,
+		KeyMaskingService:          params.KeyMaskingService,
+		AdminAuth:                  params.AdminAuth,
+		OIDC:                       params.OIDC,
+		StoreHygieneService:        params.StoreHygieneService,
+		PoolReconcileService:       params.PoolReconcileService,
+		LogStreamService:           params.LogStreamService,
+		DebugCaptureService:        params.DebugCaptureService,
+		StoreFlushService:          params.StoreFlushService,
+		UsageSnapshotService:       params.UsageSnapshotService,
+		TrashService:               params.TrashService,
+		KeyRotationService:         params.KeyRotationService,
+		ModelDiscoveryService:      params.ModelDiscoveryService,
 		CommonHandler:              params.CommonHandler,
 		EncryptionSvc:              params.EncryptionSvc,
+		KeyProvider:                params.KeyProvider,
+		ChannelFactory:             params.ChannelFactory,
+		DBHealth:                   params.DBHealth,
+		Drain:                      params.Drain,
+		Elector:                    params.Elector,
+		ProviderMonitor:            params.ProviderMonitor,
+		InFlight:                   params.InFlight,
+		NetworkRejections:          params.NetworkRejections,
+		IPThrottle:                 params.IPThrottle,
+		HoneypotService:            params.HoneypotService,
+		UpstreamStats:              params.UpstreamStats,
+		Anomalies:                  params.Anomalies,
+		ProxyServer:                params.ProxyServer,
+		Canary:                     params.Canary,
+		RequestLogWriter:           params.RequestLogWriter,
+		Store:                      params.Store,
+		Templates:                  params.Templates,
 	}
 }
 
-// LoginRequest represents the login request payload
+// LoginRequest represents the login request payload. The admin account signs in with a
+// username, password and, with two-factor authentication enabled, a TOTP code; auth_key
+// signs in with AUTH_KEY while AUTH_KEY_LOGIN is enabled.
 type LoginRequest struct {
-	AuthKey string `json:"auth_key" binding:"required"`
+	AuthKey  string `json:"auth_key"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"`
 }
 
 // LoginResponse represents the login response
 type LoginResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
+	// Token and ExpiresAt are the session of an account login, sent as the bearer token.
+	Token     string     `json:"token,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// TOTPRequired asks for the login to be repeated with a TOTP code.
+	TOTPRequired bool `json:"totp_required,omitempty"`
 }
 
 // Login handles authentication verification
 func (s *Server) Login(c *gin.Context) {
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil || (req.AuthKey == "" && (req.Username == "" || req.Password == "")) {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
 			"message": i18n.Message(c, "auth.invalid_request"),
@@ -96,7 +206,20 @@ func (s *Server) Login(c *gin.Context) {
 		return
 	}
 
+	if req.AuthKey == "" {
+		s.loginWithAccount(c, req)
+		return
+	}
+
 	authConfig := s.config.GetAuthConfig()
+	if !authConfig.KeyLogin {
+		middleware.MarkAuthFailed(c)
+		c.JSON(http.StatusUnauthorized, LoginResponse{
+			Success: false,
+			Message: i18n.Message(c, "auth.key_login_disabled"),
+		})
+		return
+	}
 
 	isValid := subtle.ConstantTimeCompare([]byte(req.AuthKey), []byte(authConfig.Key)) == 1
 
@@ -106,6 +229,7 @@ func (s *Server) Login(c *gin.Context) {
 			Message: i18n.Message(c, "auth.authentication_successful"),
 		})
 	} else {
+		middleware.MarkAuthFailed(c)
 		c.JSON(http.StatusUnauthorized, LoginResponse{
 			Success: false,
 			Message: i18n.Message(c, "auth.authentication_failed"),
@@ -122,8 +246,32 @@ func (s *Server) Health(c *gin.Context) {
 		}
 	}
 
+	database := "healthy"
+	if s.DBHealth.IsDegraded() {
+		database = "degraded"
+	}
+
+	storeStatus := "healthy"
+	if failover, ok := s.Store.(*store.FailoverStore); ok && failover.IsDegraded() {
+		storeStatus = "degraded"
+	}
+
+	// A draining instance reports itself as unavailable so that load balancers stop routing to it.
+	if s.Drain.IsDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "draining",
+			"database":  database,
+			"store":     storeStatus,
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+			"uptime":    uptime,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status":    "healthy",
+		"database":  database,
+		"store":     storeStatus,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 		"uptime":    uptime,
 	})
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListHoneypotHits lists the requests answered by the honeypot, newest first, optionally of
+// one client IP.
+func (s *Server) ListHoneypotHits(c *gin.Context) {
+	var hits []models.HoneypotHit
+	paginatedResult, err := response.Paginate(c, s.HoneypotService.HitsQuery(c.Query("client_ip")), &hits)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, paginatedResult)
+}
+
+// GetHoneypotReport returns the attacker profiles of the honeypot hits over the last hours,
+// 24 by default.
+func (s *Server) GetHoneypotReport(c *gin.Context) {
+	hours := 24
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		var err error
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours <= 0 || hours > services.MaxHoneypotReportHours {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("hours must be between 1 and %d", services.MaxHoneypotReportHours)))
+			return
+		}
+	}
+
+	report, err := s.HoneypotService.Report(hours)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, report)
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListInFlightRequests lists the proxy requests this instance is serving, longest running
+// first. An optional "group" narrows the list to one group.
+func (s *Server) ListInFlightRequests(c *gin.Context) {
+	response.Success(c, s.InFlight.List(c.Query("group")))
+}
+
+// CancelInFlightRequest cancels an in-flight proxy request. The client receives a
+// REQUEST_CANCELLED error, or a truncated stream once streaming has started.
+func (s *Server) CancelInFlightRequest(c *gin.Context) {
+	if !s.InFlight.Cancel(c.Param("id")) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrResourceNotFound, "Request is not in flight on this instance"))
+		return
+	}
+	response.Success(c, nil)
+}
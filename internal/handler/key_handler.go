@@ -2,9 +2,13 @@ package handler
 
 import (
 	"fmt"
+	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"io"
 	"log"
 	"path/filepath"
@@ -14,7 +18,6 @@ import (
 	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
@@ -68,14 +71,46 @@ type KeyTextRequest struct {
 }
 
 // GroupIDRequest defines a generic payload for operations requiring only a group ID.
+// Tags limit bulk operations to the keys carrying all of them.
 type GroupIDRequest struct {
-	GroupID uint `json:"group_id" binding:"required"`
+	GroupID uint     `json:"group_id" binding:"required"`
+	Tags    []string `json:"tags,omitempty"`
 }
 
 // ValidateGroupKeysRequest defines the payload for validating keys in a group.
 type ValidateGroupKeysRequest struct {
-	GroupID uint   `json:"group_id" binding:"required"`
-	Status  string `json:"status,omitempty"`
+	GroupID uint     `json:"group_id" binding:"required"`
+	Status  string   `json:"status,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+	// Override runs an urgent validation outside the validation windows of the group and
+	// without waiting for the validation budget.
+	Override bool `json:"override,omitempty"`
+}
+
+// KeyTagsRequest defines the payload for tagging or untagging keys selected by ID or value.
+type KeyTagsRequest struct {
+	GroupID  uint     `json:"group_id" binding:"required"`
+	KeyIDs   []uint   `json:"key_ids,omitempty"`
+	KeysText string   `json:"keys_text,omitempty"`
+	Tags     []string `json:"tags" binding:"required"`
+}
+
+// validateKeyTags normalizes the tags of a request.
+func validateKeyTags(c *gin.Context, tags []string) (models.KeyTags, bool) {
+	normalized, err := models.NormalizeKeyTags(tags)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return nil, false
+	}
+	return normalized, true
+}
+
+// keyTagsFromQuery reads comma-separated tags from the tags query parameter.
+func keyTagsFromQuery(c *gin.Context) (models.KeyTags, bool) {
+	if c.Query("tags") == "" {
+		return nil, true
+	}
+	return validateKeyTags(c, strings.Split(c.Query("tags"), ","))
 }
 
 // AddMultipleKeys handles creating new keys from a text block within a specific group.
@@ -202,18 +237,22 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 	}
 
 	statusFilter := c.Query("status")
-	if statusFilter != "" && statusFilter != models.KeyStatusActive && statusFilter != models.KeyStatusInvalid {
+	if statusFilter != "" && !models.IsKeyStatus(statusFilter) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_status_filter")
 		return
 	}
 
-	searchKeyword := c.Query("key_value")
-	searchHash := ""
-	if searchKeyword != "" {
-		searchHash = s.EncryptionSvc.Hash(searchKeyword)
+	tags, ok := keyTagsFromQuery(c)
+	if !ok {
+		return
+	}
+
+	var searchHashes []string
+	if searchKeyword := c.Query("key_value"); searchKeyword != "" {
+		searchHashes = encryption.Hashes(s.EncryptionSvc, searchKeyword)
 	}
 
-	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHash)
+	query := s.KeyService.ListKeysInGroupQuery(groupID, statusFilter, searchHashes, tags)
 
 	var keys []models.APIKey
 	paginatedResult, err := response.Paginate(c, query, &keys)
@@ -222,16 +261,11 @@ func (s *Server) ListKeysInGroup(c *gin.Context) {
 		return
 	}
 
-	// Decrypt all keys for display
+	view := s.KeyMaskingService.ViewForRequest(c, middleware.AdminRole(c))
 	for i := range keys {
-		decryptedValue, err := s.EncryptionSvc.Decrypt(keys[i].KeyValue)
-		if err != nil {
-			logrus.WithError(err).WithField("key_id", keys[i].ID).Error("Failed to decrypt key value for listing")
-			keys[i].KeyValue = "failed-to-decrypt"
-		} else {
-			keys[i].KeyValue = decryptedValue
-		}
+		keys[i].KeyValue = view.Show(keys[i].KeyValue, keys[i].KeyHash)
 	}
+	view.Audit(groupID)
 	paginatedResult.Items = keys
 
 	response.Success(c, paginatedResult)
@@ -377,11 +411,16 @@ func (s *Server) ValidateGroupKeys(c *gin.Context) {
 	}
 
 	// Validate status if provided
-	if req.Status != "" && req.Status != models.KeyStatusActive && req.Status != models.KeyStatusInvalid {
+	if req.Status != "" && !models.IsKeyStatus(req.Status) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_status_value")
 		return
 	}
 
+	tags, ok := validateKeyTags(c, req.Tags)
+	if !ok {
+		return
+	}
+
 	groupDB, ok := s.findGroupByID(c, req.GroupID)
 	if !ok {
 		return
@@ -393,7 +432,12 @@ func (s *Server) ValidateGroupKeys(c *gin.Context) {
 		return
 	}
 
-	taskStatus, err := s.KeyManualValidationService.StartValidationTask(group, req.Status)
+	if !req.Override && !keypool.InValidationWindow(group, time.Now()) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrOutsideValidationWindow, "validation.outside_validation_window")
+		return
+	}
+
+	taskStatus, err := s.KeyManualValidationService.StartValidationTask(group, req.Status, tags, req.Override)
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrTaskInProgress, err.Error()))
 		return
@@ -414,7 +458,12 @@ func (s *Server) RestoreAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := s.KeyService.RestoreAllInvalidKeys(req.GroupID)
+	tags, ok := validateKeyTags(c, req.Tags)
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := s.KeyService.RestoreAllInvalidKeys(req.GroupID, tags)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
@@ -435,7 +484,12 @@ func (s *Server) ClearAllInvalidKeys(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := s.KeyService.ClearAllInvalidKeys(req.GroupID)
+	tags, ok := validateKeyTags(c, req.Tags)
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := s.KeyService.ClearAllInvalidKeys(req.GroupID, tags)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
@@ -456,7 +510,12 @@ func (s *Server) ClearAllKeys(c *gin.Context) {
 		return
 	}
 
-	rowsAffected, err := s.KeyService.ClearAllKeys(req.GroupID)
+	tags, ok := validateKeyTags(c, req.Tags)
+	if !ok {
+		return
+	}
+
+	rowsAffected, err := s.KeyService.ClearAllKeys(req.GroupID, tags)
 	if err != nil {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
@@ -477,13 +536,16 @@ func (s *Server) ExportKeys(c *gin.Context) {
 		statusFilter = "all"
 	}
 
-	switch statusFilter {
-	case "all", models.KeyStatusActive, models.KeyStatusInvalid:
-	default:
+	if statusFilter != "all" && !models.IsKeyStatus(statusFilter) {
 		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_status_filter")
 		return
 	}
 
+	tags, ok := keyTagsFromQuery(c)
+	if !ok {
+		return
+	}
+
 	group, ok := s.findGroupByID(c, groupID)
 	if !ok {
 		return
@@ -493,11 +555,35 @@ func (s *Server) ExportKeys(c *gin.Context) {
 	c.Header("Content-Disposition", "attachment; filename="+filename)
 	c.Header("Content-Type", "text/plain; charset=utf-8")
 
-	if err := s.KeyService.StreamKeysToWriter(groupID, statusFilter, c.Writer); err != nil {
+	view := s.KeyMaskingService.ViewForExport(c, middleware.AdminRole(c))
+	defer view.Audit(groupID)
+	if err := s.KeyService.StreamKeysToWriter(groupID, statusFilter, tags, c.Writer, view); err != nil {
 		log.Printf("Failed to stream keys: %v", err)
 	}
 }
 
+// ListKeyRevealLogs lists the audit entries of responses that carried plaintext keys, newest
+// first, optionally of one group.
+func (s *Server) ListKeyRevealLogs(c *gin.Context) {
+	var groupID uint
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		id, err := strconv.Atoi(groupIDStr)
+		if err != nil || id <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return
+		}
+		groupID = uint(id)
+	}
+
+	var entries []models.KeyRevealLog
+	paginatedResult, err := response.Paginate(c, s.KeyMaskingService.RevealLogsQuery(groupID), &entries)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, paginatedResult)
+}
+
 // UpdateKeyNotesRequest defines the payload for updating a key's notes.
 type UpdateKeyNotesRequest struct {
 	Notes string `json:"notes"`
@@ -544,3 +630,117 @@ func (s *Server) UpdateKeyNotes(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// GetKeyStats handles reporting the request statistics of a key over the last hours (24 by default).
+func (s *Server) GetKeyStats(c *gin.Context) {
+	keyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || keyID <= 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "invalid key ID format"))
+		return
+	}
+
+	hours := 24
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		hours, err = strconv.Atoi(hoursStr)
+		if err != nil || hours <= 0 || hours > services.MaxKeyUsageStatsHours {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("hours must be between 1 and %d", services.MaxKeyUsageStatsHours)))
+			return
+		}
+	}
+
+	var key models.APIKey
+	if err := s.DB.First(&key, keyID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			response.Error(c, app_errors.ErrResourceNotFound)
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	stats, err := s.KeyService.UsageStats(&key, hours)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, stats)
+}
+
+// KeyLifecycleRequest defines the payload for moving keys along their lifecycle.
+type KeyLifecycleRequest struct {
+	GroupID uint   `json:"group_id" binding:"required"`
+	KeyIDs  []uint `json:"key_ids" binding:"required"`
+	To      string `json:"to" binding:"required"`
+}
+
+// TransitionKeys handles moving keys of a group to another lifecycle status.
+func (s *Server) TransitionKeys(c *gin.Context) {
+	var req KeyLifecycleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if !keypool.IsLifecycleTarget(req.To) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrValidation, "validation.invalid_lifecycle_status")
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	result, err := s.KeyService.TransitionKeys(req.GroupID, req.KeyIDs, req.To)
+	if err != nil {
+		if strings.Contains(err.Error(), "batch size exceeds the limit") {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		} else {
+			response.Error(c, app_errors.ParseDBError(err))
+		}
+		return
+	}
+
+	response.Success(c, result)
+}
+
+// TagKeys handles adding tags to keys of a group.
+func (s *Server) TagKeys(c *gin.Context) {
+	s.updateKeyTags(c, true)
+}
+
+// UntagKeys handles removing tags from keys of a group.
+func (s *Server) UntagKeys(c *gin.Context) {
+	s.updateKeyTags(c, false)
+}
+
+func (s *Server) updateKeyTags(c *gin.Context, add bool) {
+	var req KeyTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	tags, ok := validateKeyTags(c, req.Tags)
+	if !ok {
+		return
+	}
+	if len(tags) == 0 {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "at least one tag is required"))
+		return
+	}
+
+	if _, ok := s.findGroupByID(c, req.GroupID); !ok {
+		return
+	}
+
+	result, err := s.KeyService.UpdateKeyTags(req.GroupID, req.KeyIDs, req.KeysText, tags, add)
+	if err != nil && (strings.Contains(err.Error(), "batch size exceeds the limit") || err.Error() == "no valid keys found in the input text") {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+	if s.handleGroupError(c, err) {
+		return
+	}
+
+	response.Success(c, result)
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/response"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WatchKeys pushes the key status changes of every instance as Server-Sent Events, e.g. keys
+// being rate limited, blacklisted or restored, optionally limited to the group of group_id.
+// Every event carries the number of keys of the group left in rotation.
+func (s *Server) WatchKeys(c *gin.Context) {
+	var groupID uint
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		id, err := strconv.Atoi(groupIDStr)
+		if err != nil || id <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id_format")
+			return
+		}
+		if _, ok := s.findGroupByID(c, uint(id)); !ok {
+			return
+		}
+		groupID = uint(id)
+	}
+
+	subscription, err := s.Store.Subscribe(keypool.KeyStatusEventChannel)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	defer subscription.Close()
+
+	// The stream outlives the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case msg, ok := <-subscription.Channel():
+			if !ok {
+				return
+			}
+			var event keypool.KeyStatusEvent
+			if err := json.Unmarshal(msg.Payload, &event); err != nil || (groupID != 0 && event.GroupID != groupID) {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, msg.Payload); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
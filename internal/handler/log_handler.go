@@ -1,12 +1,21 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/failover"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
+	"gpt-load/internal/proxy"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"io"
 	"log"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -30,21 +39,123 @@ func (s *Server) GetLogs(c *gin.Context) {
 		return
 	}
 
-	// 解密所有日志中的密钥用于前端显示
+	s.showLogKeys(c, logs)
+
+	pagination.Items = logs
+	response.Success(c, pagination)
+}
+
+// GetRequestLogs returns every log entry of a client request by the request ID returned in
+// its response, so that the retries of the request can be followed.
+func (s *Server) GetRequestLogs(c *gin.Context) {
+	logs, err := s.LogService.GetRequestLogs(c.Param("id"))
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	if len(logs) == 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "log.request_not_found")
+		return
+	}
+
+	s.showLogKeys(c, logs)
+	response.Success(c, logs)
+}
+
+// showLogKeys masks the keys of log entries for display.
+func (s *Server) showLogKeys(c *gin.Context, logs []models.RequestLog) {
+	view := s.KeyMaskingService.ViewForRequest(c, middleware.AdminRole(c))
 	for i := range logs {
-		if logs[i].KeyValue != "" {
-			decryptedValue, err := s.EncryptionSvc.Decrypt(logs[i].KeyValue)
-			if err != nil {
-				logrus.WithError(err).WithField("log_id", logs[i].ID).Error("Failed to decrypt log key value")
-				logs[i].KeyValue = "failed-to-decrypt"
-			} else {
-				logs[i].KeyValue = decryptedValue
+		logs[i].KeyValue = view.Show(logs[i].KeyValue, logs[i].KeyHash)
+	}
+	view.Audit(0)
+}
+
+// GetLogCapture returns the request and response bodies captured for a request log
+// of a group in debug capture mode.
+func (s *Server) GetLogCapture(c *gin.Context) {
+	capture, err := s.DebugCaptureService.Get(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, services.ErrDebugCaptureNotFound) {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "log.capture_not_found")
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, capture)
+}
+
+// ReplayLogRequest selects the group and key a logged request is replayed against.
+type ReplayLogRequest struct {
+	// GroupID defaults to the group of the key, or to the group that served the request.
+	GroupID uint `json:"group_id"`
+	// KeyID forces a key of the group, otherwise a key is selected from the pool.
+	KeyID uint `json:"key_id"`
+	// UseCapture replays the method and body of the debug capture of the request.
+	UseCapture bool `json:"use_capture"`
+}
+
+// ReplayLog sends a logged request again and returns the logged and replayed outcomes.
+func (s *Server) ReplayLog(c *gin.Context) {
+	var req ReplayLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	var logEntry models.RequestLog
+	if err := s.DB.Where("id = ?", c.Param("id")).First(&logEntry).Error; err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	opts := proxy.ReplayOptions{Log: &logEntry, RemoteAddr: c.Request.RemoteAddr}
+	if req.UseCapture {
+		capture, err := s.DebugCaptureService.Get(logEntry.ID)
+		if err != nil {
+			if errors.Is(err, services.ErrDebugCaptureNotFound) {
+				response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "log.capture_not_found")
+				return
 			}
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+			return
 		}
+		opts.Capture = capture
+	} else if logEntry.RequestBody == "" && logEntry.Model != "" {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "the request body was not logged, enable request body logging or replay a debug capture"))
+		return
 	}
 
-	pagination.Items = logs
-	response.Success(c, pagination)
+	groupID := logEntry.GroupID
+	if req.KeyID != 0 {
+		var key models.APIKey
+		if err := s.DB.First(&key, req.KeyID).Error; err != nil {
+			response.Error(c, app_errors.ParseDBError(err))
+			return
+		}
+		if req.GroupID != 0 && req.GroupID != key.GroupID {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, "the key does not belong to the group"))
+			return
+		}
+		opts.Key = &key
+		groupID = key.GroupID
+	} else if req.GroupID != 0 {
+		groupID = req.GroupID
+	}
+
+	group, ok := s.findGroupByID(c, groupID)
+	if !ok {
+		return
+	}
+	opts.Group = group
+
+	result, err := s.ProxyServer.Replay(c.Request.Context(), opts)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+		return
+	}
+	response.Success(c, result)
 }
 
 // ExportLogs handles exporting filtered log keys to a CSV file.
@@ -54,10 +165,131 @@ func (s *Server) ExportLogs(c *gin.Context) {
 	c.Header("Content-Type", "text/csv; charset=utf-8")
 
 	// Stream the response
-	err := s.LogService.StreamLogKeysToCSV(c, c.Writer)
+	view := s.KeyMaskingService.ViewForExport(c, middleware.AdminRole(c))
+	defer view.Audit(0)
+	err := s.LogService.StreamLogKeysToCSV(c, c.Writer, view)
 	if err != nil {
 		log.Printf("Failed to stream log keys to CSV: %v", err)
 		c.JSON(500, gin.H{"error": i18n.Message(c, "error.export_logs")})
 		return
 	}
 }
+
+// ExportLogRecords streams the filtered request logs as NDJSON (default) or CSV (format=csv).
+// It accepts the same filters as GetLogs, e.g. start_time, end_time, group_id and status_code.
+func (s *Server) ExportLogRecords(c *gin.Context) {
+	format := c.DefaultQuery("format", services.LogExportNDJSON)
+	contentType := "application/x-ndjson; charset=utf-8"
+	switch format {
+	case services.LogExportNDJSON:
+	case services.LogExportCSV:
+		contentType = "text/csv; charset=utf-8"
+	default:
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, fmt.Sprintf("unsupported export format %q, use ndjson or csv", format)))
+		return
+	}
+
+	// Large exports outlive the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("request_logs_export_%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", contentType)
+
+	view := s.KeyMaskingService.ViewForExport(c, middleware.AdminRole(c))
+	defer view.Audit(0)
+	if err := s.LogService.StreamLogRecords(c, c.Writer, format, view); err != nil {
+		logrus.WithError(err).Error("Failed to stream request logs")
+		if !c.Writer.Written() {
+			c.Writer.Header().Del("Content-Disposition")
+			c.Writer.Header().Del("Content-Type")
+			c.JSON(http.StatusInternalServerError, gin.H{"error": i18n.Message(c, "error.export_logs")})
+		}
+	}
+}
+
+// logStreamHeartbeat is how often an idle live log stream sends a comment to keep proxies from closing it.
+const logStreamHeartbeat = 15 * time.Second
+
+// StreamLogs pushes new request logs as Server-Sent Events. Optional filters: group_id,
+// status_code (a spec such as "429" or "500-599"), and key_hash or key_value.
+func (s *Server) StreamLogs(c *gin.Context) {
+	filter := services.LogStreamFilter{KeyHash: c.Query("key_hash")}
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		groupID, err := strconv.Atoi(groupIDStr)
+		if err != nil || groupID <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return
+		}
+		filter.GroupID = uint(groupID)
+	}
+	if statusCodes := c.Query("status_code"); statusCodes != "" {
+		matcher, err := failover.ParseStatusCodeMatcher(statusCodes)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			return
+		}
+		filter.StatusCodes = matcher
+	}
+	if keyValue := c.Query("key_value"); keyValue != "" {
+		filter.KeyHash = s.EncryptionSvc.Hash(keyValue)
+	}
+
+	subscription, err := s.LogStreamService.Subscribe()
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	defer subscription.Close()
+
+	// The stream outlives the server write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Plaintext keys sent by the stream are audited as it goes
+	view := s.KeyMaskingService.ViewForRequest(c, middleware.AdminRole(c))
+	defer view.Audit(0)
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	keepAlive := time.NewTicker(services.LogStreamListenerTTL / 3)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			s.LogStreamService.KeepAlive()
+			view.Audit(0)
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case msg, ok := <-subscription.Channel():
+			if !ok {
+				return
+			}
+			var entry models.RequestLog
+			if err := json.Unmarshal(msg.Payload, &entry); err != nil || !filter.Match(&entry) {
+				continue
+			}
+			entry.KeyValue = view.Show(entry.KeyValue, entry.KeyHash)
+			data, err := json.Marshal(entry)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(c.Writer, "event: log\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
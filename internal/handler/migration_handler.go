@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetMigrationStatus returns the progress of the last migrate-keys run that shares the store
+// of this instance.
+func (s *Server) GetMigrationStatus(c *gin.Context) {
+	data, err := s.Store.Get(models.MigrationStatusKey)
+	if errors.Is(err, store.ErrNotFound) {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrResourceNotFound, "no key migration has run recently"))
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+
+	var status models.MigrationStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		response.Error(c, app_errors.ErrInternalServer)
+		return
+	}
+	response.Success(c, status)
+}
+
+// GetKeyRotationStatus returns the progress of the online encryption key rotation.
+func (s *Server) GetKeyRotationStatus(c *gin.Context) {
+	response.Success(c, s.KeyRotationService.Status())
+}
+
+// FinishKeyRotation re-encrypts what is left and drops the secondary encryption key.
+func (s *Server) FinishKeyRotation(c *gin.Context) {
+	status, err := s.KeyRotationService.Finish(c.Request.Context())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, status)
+}
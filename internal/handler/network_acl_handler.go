@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListNetworkRejections lists the proxy requests recently rejected by the network ACLs on this
+// instance, newest first.
+func (s *Server) ListNetworkRejections(c *gin.Context) {
+	response.Success(c, s.NetworkRejections.Recent())
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// readinessCheckTimeout bounds each dependency check of the readiness probe.
+const readinessCheckTimeout = 2 * time.Second
+
+// Dependency states reported by the readiness probe. Only a dependency that is down makes the
+// instance not ready, a degraded one is bridged by degraded mode or the store failover.
+const (
+	dependencyUp       = "up"
+	dependencyDegraded = "degraded"
+	dependencyDown     = "down"
+	dependencySkipped  = "skipped"
+)
+
+// DependencyStatus is the state of a dependency checked by the readiness probe.
+type DependencyStatus struct {
+	Status    string     `json:"status"`
+	LatencyMs int64      `json:"latency_ms,omitempty"`
+	LoadedAt  *time.Time `json:"loaded_at,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Livez answers as long as the process serves HTTP, for liveness probes that restart it otherwise.
+func (s *Server) Livez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// Readyz checks the dependencies needed to serve proxy requests and answers 503 while one of
+// them is down or the instance is draining, for readiness probes that route traffic to it.
+func (s *Server) Readyz(c *gin.Context) {
+	checks := map[string]DependencyStatus{
+		"database":    s.checkDatabase(c.Request.Context()),
+		"store":       s.checkStore(c.Request.Context()),
+		"key_pool":    s.checkKeyPool(),
+		"active_keys": s.checkActiveKeys(),
+	}
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		if check.Status == dependencyDown {
+			status, code = "not_ready", http.StatusServiceUnavailable
+		}
+	}
+	if s.Drain.IsDraining() {
+		status, code = "draining", http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"status":    status,
+		"checks":    checks,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// checkDatabase pings the database. An unreachable database only degrades the instance when
+// degraded mode is enabled.
+func (s *Server) checkDatabase(ctx context.Context) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	sqlDB, err := s.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	check := DependencyStatus{Status: dependencyUp, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status = dependencyDown
+		if s.DBHealth.Status().Enabled {
+			check.Status = dependencyDegraded
+		}
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// checkStore pings Redis. While the store runs on its in-memory failover it is degraded, and
+// the in-memory store is always up.
+func (s *Server) checkStore(ctx context.Context) DependencyStatus {
+	if failover, ok := s.Store.(*store.FailoverStore); ok && failover.IsDegraded() {
+		return DependencyStatus{Status: dependencyDegraded}
+	}
+	pinger, ok := s.Store.(store.Pinger)
+	if !ok {
+		return DependencyStatus{Status: dependencyUp}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := pinger.Ping(ctx)
+	check := DependencyStatus{Status: dependencyUp, LatencyMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		check.Status = dependencyDown
+		check.Error = err.Error()
+	}
+	return check
+}
+
+// checkKeyPool checks that the key pools were loaded into the store from the database.
+func (s *Server) checkKeyPool() DependencyStatus {
+	loadedAt, loaded, err := s.KeyProvider.LoadedAt()
+	switch {
+	case err != nil:
+		return DependencyStatus{Status: dependencyDown, Error: err.Error()}
+	case !loaded:
+		return DependencyStatus{Status: dependencyDown, Error: "key pools have not been loaded from the database"}
+	}
+	return DependencyStatus{Status: dependencyUp, LoadedAt: &loadedAt}
+}
+
+// checkActiveKeys checks that at least one group has a key in rotation, when required.
+func (s *Server) checkActiveKeys() DependencyStatus {
+	if !s.config.GetEffectiveServerConfig().ReadyRequiresKeys {
+		return DependencyStatus{Status: dependencySkipped}
+	}
+	hasKeys, err := s.KeyProvider.HasActiveKeys()
+	switch {
+	case err != nil:
+		return DependencyStatus{Status: dependencyDown, Error: err.Error()}
+	case !hasKeys:
+		return DependencyStatus{Status: dependencyDown, Error: "no group has an active key"}
+	}
+	return DependencyStatus{Status: dependencyUp}
+}
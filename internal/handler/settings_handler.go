@@ -3,8 +3,10 @@ package handler
 import (
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/i18n"
+	"gpt-load/internal/middleware"
 	"gpt-load/internal/models"
 	"gpt-load/internal/response"
+	"gpt-load/internal/services"
 	"gpt-load/internal/utils"
 	"strings"
 	"time"
@@ -12,14 +14,17 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// GetSettings handles the GET /api/settings request.
-// It retrieves all system settings, groups them by category, and returns them.
-func (s *Server) GetSettings(c *gin.Context) {
+// translatedSettingsInfo returns the metadata of the current settings with i18n keys translated.
+func (s *Server) translatedSettingsInfo(c *gin.Context) []models.SystemSettingInfo {
 	currentSettings := s.SettingsManager.GetSettings()
-	settingsInfo := utils.GenerateSettingsMetadata(&currentSettings)
+	settingsInfo := s.SettingsManager.SettingsMetadata()
 
 	// Translate settings info
+	role := middleware.AdminRole(c)
 	for i := range settingsInfo {
+		if settingsInfo[i].Key == "proxy_keys" {
+			settingsInfo[i].Value = services.MaskProxyKeys(currentSettings.ProxyKeys, role)
+		}
 		// Translate name if it's an i18n key
 		if strings.HasPrefix(settingsInfo[i].Name, "config.") {
 			settingsInfo[i].Name = i18n.Message(c, settingsInfo[i].Name)
@@ -33,6 +38,13 @@ func (s *Server) GetSettings(c *gin.Context) {
 			settingsInfo[i].Category = i18n.Message(c, settingsInfo[i].Category)
 		}
 	}
+	return settingsInfo
+}
+
+// GetSettings handles the GET /api/settings request.
+// It retrieves all system settings, groups them by category, and returns them.
+func (s *Server) GetSettings(c *gin.Context) {
+	settingsInfo := s.translatedSettingsInfo(c)
 
 	// Group settings by category while preserving order
 	categorized := make(map[string][]models.SystemSettingInfo)
@@ -56,17 +68,36 @@ func (s *Server) GetSettings(c *gin.Context) {
 	response.Success(c, responseData)
 }
 
-// UpdateSettings handles the PUT /api/settings request.
-func (s *Server) UpdateSettings(c *gin.Context) {
+// SearchSettings handles the GET /api/settings/search request.
+// It returns a paginated, flat list of settings, optionally filtered by category and a search term
+// matched against the key, name and description.
+func (s *Server) SearchSettings(c *gin.Context) {
+	category := c.Query("category")
+	search := strings.ToLower(strings.TrimSpace(c.Query("search")))
+
+	var matched []models.SystemSettingInfo
+	for _, info := range s.translatedSettingsInfo(c) {
+		if category != "" && info.Category != category {
+			continue
+		}
+		if search != "" &&
+			!strings.Contains(strings.ToLower(info.Key), search) &&
+			!strings.Contains(strings.ToLower(info.Name), search) &&
+			!strings.Contains(strings.ToLower(info.Description), search) {
+			continue
+		}
+		matched = append(matched, info)
+	}
+
+	response.Success(c, response.PaginateSlice(c, matched))
+}
+
+// bindSettingsMap parses a settings update body and normalizes its values.
+func bindSettingsMap(c *gin.Context) (map[string]any, bool) {
 	var settingsMap map[string]any
 	if err := c.ShouldBindJSON(&settingsMap); err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
-		return
-	}
-
-	if len(settingsMap) == 0 {
-		response.Success(c, nil)
-		return
+		return nil, false
 	}
 
 	// Sanitize proxy_keys input
@@ -77,6 +108,52 @@ func (s *Server) UpdateSettings(c *gin.Context) {
 		}
 	}
 
+	return settingsMap, true
+}
+
+// PreviewSettings handles the POST /api/settings/preview request.
+// It validates the pending update and returns the settings it would change, without saving anything.
+func (s *Server) PreviewSettings(c *gin.Context) {
+	settingsMap, ok := bindSettingsMap(c)
+	if !ok {
+		return
+	}
+
+	changes, err := s.SettingsManager.PreviewSettings(settingsMap)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
+	for i := range changes {
+		if strings.HasPrefix(changes[i].Name, "config.") {
+			changes[i].Name = i18n.Message(c, changes[i].Name)
+		}
+		if strings.HasPrefix(changes[i].Category, "config.") {
+			changes[i].Category = i18n.Message(c, changes[i].Category)
+		}
+	}
+
+	response.Success(c, changes)
+}
+
+// UpdateSettings handles the PUT /api/settings request.
+func (s *Server) UpdateSettings(c *gin.Context) {
+	settingsMap, ok := bindSettingsMap(c)
+	if !ok {
+		return
+	}
+
+	if len(settingsMap) == 0 {
+		response.Success(c, nil)
+		return
+	}
+
+	if err := s.SettingsManager.ValidateSettings(settingsMap); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrValidation, err.Error()))
+		return
+	}
+
 	// 更新配置
 	if err := s.SettingsManager.UpdateSettings(settingsMap); err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrDatabase, err.Error()))
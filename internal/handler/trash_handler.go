@@ -0,0 +1,109 @@
+package handler
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/middleware"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashKeysRequest defines the payload for restoring or purging keys in the trash.
+type TrashKeysRequest struct {
+	GroupID uint   `json:"group_id" binding:"required"`
+	KeyIDs  []uint `json:"key_ids" binding:"required"`
+}
+
+// ListTrashedGroups lists the deleted groups that can still be restored.
+func (s *Server) ListTrashedGroups(c *gin.Context) {
+	groups, err := s.TrashService.ListGroups(c.Request.Context())
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, groups)
+}
+
+// ListTrashedKeys lists the deleted keys of a group that can still be restored.
+func (s *Server) ListTrashedKeys(c *gin.Context) {
+	groupID, ok := validateGroupIDFromQuery(c)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	paginatedResult, err := response.Paginate(c, s.TrashService.KeysQuery(groupID), &keys)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+
+	view := s.KeyMaskingService.ViewForRequest(c, middleware.AdminRole(c))
+	for i := range keys {
+		keys[i].KeyValue = view.Show(keys[i].KeyValue, keys[i].KeyHash)
+	}
+	view.Audit(groupID)
+	paginatedResult.Items = s.TrashService.TrashedKeys(keys)
+
+	response.Success(c, paginatedResult)
+}
+
+// RestoreTrashedGroup takes a group and the keys deleted with it out of the trash.
+func (s *Server) RestoreTrashedGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	result, err := s.TrashService.RestoreGroup(c.Request.Context(), uint(id))
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, result)
+}
+
+// PurgeTrashedGroup permanently deletes a group in the trash and its keys.
+func (s *Server) PurgeTrashedGroup(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+		return
+	}
+
+	if s.handleGroupError(c, s.TrashService.PurgeGroup(c.Request.Context(), uint(id))) {
+		return
+	}
+	response.SuccessI18n(c, "success.trash_purged", nil)
+}
+
+// RestoreTrashedKeys takes keys of a group out of the trash.
+func (s *Server) RestoreTrashedKeys(c *gin.Context) {
+	var req TrashKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	result, err := s.TrashService.RestoreKeys(c.Request.Context(), req.GroupID, req.KeyIDs)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.Success(c, result)
+}
+
+// PurgeTrashedKeys permanently deletes keys of a group that are in the trash.
+func (s *Server) PurgeTrashedKeys(c *gin.Context) {
+	var req TrashKeysRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	purged, err := s.TrashService.PurgeKeys(c.Request.Context(), req.GroupID, req.KeyIDs)
+	if s.handleGroupError(c, err) {
+		return
+	}
+	response.SuccessI18n(c, "success.trash_keys_purged", nil, map[string]any{"count": purged})
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"errors"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUsageSnapshotListLimit = 50
+	maxUsageSnapshotListLimit     = 500
+)
+
+// ListUsageSnapshots lists the usage snapshots, newest first. An optional "as_of" RFC 3339
+// time only lists the snapshots taken at or before it.
+func (s *Server) ListUsageSnapshots(c *gin.Context) {
+	asOf, ok := parseSnapshotAsOf(c)
+	if !ok {
+		return
+	}
+
+	limit := defaultUsageSnapshotListLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		value, err := strconv.Atoi(limitStr)
+		if err != nil || value <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_limit")
+			return
+		}
+		limit = min(value, maxUsageSnapshotListLimit)
+	}
+
+	snapshots, err := s.UsageSnapshotService.List(asOf, limit)
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, snapshots)
+}
+
+// TakeUsageSnapshot takes a manual snapshot of the current usage counters.
+func (s *Server) TakeUsageSnapshot(c *gin.Context) {
+	snapshot, err := s.UsageSnapshotService.Take(nil)
+	if err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, err.Error()))
+		return
+	}
+	response.Success(c, snapshot)
+}
+
+// GetUsageSnapshot returns the counters of a snapshot.
+func (s *Server) GetUsageSnapshot(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_snapshot_id")
+		return
+	}
+	groupID, includeKeys, ok := parseSnapshotFilters(c)
+	if !ok {
+		return
+	}
+
+	view, err := s.UsageSnapshotService.Get(uint(id), groupID, includeKeys)
+	s.respondUsageSnapshot(c, view, err)
+}
+
+// GetUsageSnapshotAsOf returns the counters of the latest snapshot taken at or before "as_of",
+// or of the latest snapshot when it is omitted.
+func (s *Server) GetUsageSnapshotAsOf(c *gin.Context) {
+	asOf, ok := parseSnapshotAsOf(c)
+	if !ok {
+		return
+	}
+	groupID, includeKeys, ok := parseSnapshotFilters(c)
+	if !ok {
+		return
+	}
+
+	view, err := s.UsageSnapshotService.AsOf(asOf, groupID, includeKeys)
+	s.respondUsageSnapshot(c, view, err)
+}
+
+func (s *Server) respondUsageSnapshot(c *gin.Context, view *services.UsageSnapshotView, err error) {
+	if errors.Is(err, services.ErrUsageSnapshotNotFound) {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrResourceNotFound, "usage_snapshot.not_found")
+		return
+	}
+	if err != nil {
+		response.Error(c, app_errors.ParseDBError(err))
+		return
+	}
+	response.Success(c, view)
+}
+
+func parseSnapshotAsOf(c *gin.Context) (time.Time, bool) {
+	asOfStr := c.Query("as_of")
+	if asOfStr == "" {
+		return time.Now(), true
+	}
+	asOf, err := time.Parse(time.RFC3339, asOfStr)
+	if err != nil {
+		response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_as_of")
+		return time.Time{}, false
+	}
+	return asOf, true
+}
+
+// parseSnapshotFilters reads the optional "group_id" and "include_keys" query parameters.
+func parseSnapshotFilters(c *gin.Context) (uint, bool, bool) {
+	var groupID uint
+	if groupIDStr := c.Query("group_id"); groupIDStr != "" {
+		id, err := strconv.Atoi(groupIDStr)
+		if err != nil || id <= 0 {
+			response.ErrorI18nFromAPIError(c, app_errors.ErrBadRequest, "validation.invalid_group_id")
+			return 0, false, false
+		}
+		groupID = uint(id)
+	}
+	return groupID, c.Query("include_keys") == "true", true
+}
@@ -0,0 +1,284 @@
+// Package honeypot answers requests from suspected attackers with fabricated, slow and useless
+// LLM responses, so that they waste time without reaching the upstreams or the key pools.
+package honeypot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Triggers that send a request to the honeypot.
+const (
+	TriggerDecoyKey = "decoy_key"
+	TriggerPath     = "path"
+	TriggerBannedIP = "banned_ip"
+)
+
+const (
+	defaultModelName = "gpt-4o"
+	maxBodyBytes     = 1 << 20
+)
+
+// reservedPaths cannot be turned into decoys, so that the management API, the health checks
+// and the proxy as a whole stay reachable.
+var reservedPaths = []string{"/api/", "/health", "/livez", "/readyz", "/proxy/"}
+
+// ParsePaths parses comma-separated path prefixes answered by the honeypot, e.g.
+// "/v1/, /proxy/legacy/". A prefix covering a reserved path is rejected.
+func ParsePaths(spec string) ([]string, error) {
+	var prefixes []string
+	for _, prefix := range strings.Split(spec, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if prefix == "" {
+			continue
+		}
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("path %q must start with /", prefix)
+		}
+		for _, reserved := range reservedPaths {
+			if strings.HasPrefix(reserved, prefix) || (reserved == "/api/" && strings.HasPrefix(prefix, reserved)) {
+				return nil, fmt.Errorf("path %q would capture %s", prefix, reserved)
+			}
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	return prefixes, nil
+}
+
+// MatchesPath reports whether the path starts with one of the prefixes.
+func MatchesPath(prefixes []string, path string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// fillers are the sentences fabricated answers are made of.
+var fillers = []string{
+	"That is an interesting question and there are several ways to look at it.",
+	"It depends on the context, so the answer may vary.",
+	"Generally speaking, it is best to consider all of the relevant factors first.",
+	"There is no single correct approach here.",
+	"Some sources suggest one thing while others suggest another.",
+	"I would recommend reviewing the details carefully before deciding.",
+	"This topic has been discussed extensively.",
+	"In summary, it could go either way.",
+}
+
+// fakeModels are listed by model list requests.
+var fakeModels = []string{"gpt-4o", "gpt-4o-mini", "o3-mini", "claude-3-5-sonnet-latest", "gemini-2.0-flash"}
+
+// Serve answers a request with a fabricated response in the format of the API the path belongs
+// to: OpenAI chat completions by default, Anthropic messages or Gemini generateContent, each
+// streamed when the request asks for it. The response starts after delay, and streams send a
+// word every tenth of it.
+func Serve(c *gin.Context, delay time.Duration) {
+	path := c.Request.URL.Path
+	var body map[string]any
+	if data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes)); err == nil {
+		_ = json.Unmarshal(data, &body)
+	}
+
+	model, _ := body["model"].(string)
+	if model == "" {
+		model = defaultModelName
+	}
+	stream, _ := body["stream"].(bool)
+
+	if !sleep(c, delay) {
+		return
+	}
+
+	switch {
+	case c.Request.Method == http.MethodGet && strings.HasSuffix(path, "/models"):
+		serveModelList(c)
+	case strings.Contains(path, ":generateContent") || strings.Contains(path, ":streamGenerateContent"):
+		serveGemini(c, strings.Contains(path, ":streamGenerateContent"), delay/10)
+	case strings.HasSuffix(path, "/messages"):
+		serveAnthropic(c, model, stream, delay/10)
+	default:
+		serveOpenAI(c, model, stream, delay/10)
+	}
+}
+
+// sleep waits for d, returning false when the client went away.
+func sleep(c *gin.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-c.Request.Context().Done():
+		return false
+	}
+}
+
+func fabricateText() string {
+	count := 2 + rand.IntN(3)
+	sentences := make([]string, count)
+	for i := range sentences {
+		sentences[i] = fillers[rand.IntN(len(fillers))]
+	}
+	return strings.Join(sentences, " ")
+}
+
+func usage(text string) (int, int) {
+	return 10 + rand.IntN(40), len(strings.Fields(text)) * 4 / 3
+}
+
+// streamWords writes the words of text as server-sent events built by event, one every interval.
+func streamWords(c *gin.Context, text string, interval time.Duration, event func(word string) string) bool {
+	for i, word := range strings.Fields(text) {
+		if i > 0 {
+			word = " " + word
+		}
+		if _, err := io.WriteString(c.Writer, event(word)); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		if !sleep(c, interval) {
+			return false
+		}
+	}
+	return true
+}
+
+func startStream(c *gin.Context) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+}
+
+func sseData(v any) string {
+	data, _ := json.Marshal(v)
+	return fmt.Sprintf("data: %s\n\n", data)
+}
+
+func serveModelList(c *gin.Context) {
+	data := make([]gin.H, 0, len(fakeModels))
+	for _, id := range fakeModels {
+		data = append(data, gin.H{"id": id, "object": "model", "created": 1715367049, "owned_by": "system"})
+	}
+	c.JSON(http.StatusOK, gin.H{"object": "list", "data": data})
+}
+
+func serveOpenAI(c *gin.Context, model string, stream bool, interval time.Duration) {
+	id := "chatcmpl-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+	created := time.Now().Unix()
+	text := fabricateText()
+	promptTokens, completionTokens := usage(text)
+
+	if !stream {
+		c.JSON(http.StatusOK, gin.H{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": created,
+			"model":   model,
+			"choices": []gin.H{{
+				"index":         0,
+				"message":       gin.H{"role": "assistant", "content": text},
+				"finish_reason": "stop",
+			}},
+			"usage": gin.H{"prompt_tokens": promptTokens, "completion_tokens": completionTokens, "total_tokens": promptTokens + completionTokens},
+		})
+		return
+	}
+
+	chunk := func(delta gin.H, finishReason any) string {
+		return sseData(gin.H{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []gin.H{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+		})
+	}
+	startStream(c)
+	if !streamWords(c, text, interval, func(word string) string { return chunk(gin.H{"content": word}, nil) }) {
+		return
+	}
+	io.WriteString(c.Writer, chunk(gin.H{}, "stop"))
+	io.WriteString(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
+}
+
+func serveAnthropic(c *gin.Context, model string, stream bool, interval time.Duration) {
+	id := "msg_" + strings.ReplaceAll(uuid.NewString(), "-", "")[:24]
+	text := fabricateText()
+	inputTokens, outputTokens := usage(text)
+
+	if !stream {
+		c.JSON(http.StatusOK, gin.H{
+			"id":            id,
+			"type":          "message",
+			"role":          "assistant",
+			"model":         model,
+			"content":       []gin.H{{"type": "text", "text": text}},
+			"stop_reason":   "end_turn",
+			"stop_sequence": nil,
+			"usage":         gin.H{"input_tokens": inputTokens, "output_tokens": outputTokens},
+		})
+		return
+	}
+
+	event := func(name string, data gin.H) string {
+		encoded, _ := json.Marshal(data)
+		return fmt.Sprintf("event: %s\ndata: %s\n\n", name, encoded)
+	}
+	startStream(c)
+	io.WriteString(c.Writer, event("message_start", gin.H{"type": "message_start", "message": gin.H{
+		"id": id, "type": "message", "role": "assistant", "model": model, "content": []gin.H{},
+		"stop_reason": nil, "usage": gin.H{"input_tokens": inputTokens, "output_tokens": 1},
+	}}))
+	io.WriteString(c.Writer, event("content_block_start", gin.H{"type": "content_block_start", "index": 0, "content_block": gin.H{"type": "text", "text": ""}}))
+	if !streamWords(c, text, interval, func(word string) string {
+		return event("content_block_delta", gin.H{"type": "content_block_delta", "index": 0, "delta": gin.H{"type": "text_delta", "text": word}})
+	}) {
+		return
+	}
+	io.WriteString(c.Writer, event("content_block_stop", gin.H{"type": "content_block_stop", "index": 0}))
+	io.WriteString(c.Writer, event("message_delta", gin.H{"type": "message_delta", "delta": gin.H{"stop_reason": "end_turn", "stop_sequence": nil}, "usage": gin.H{"output_tokens": outputTokens}}))
+	io.WriteString(c.Writer, event("message_stop", gin.H{"type": "message_stop"}))
+	c.Writer.Flush()
+}
+
+func serveGemini(c *gin.Context, stream bool, interval time.Duration) {
+	text := fabricateText()
+	promptTokens, candidateTokens := usage(text)
+	response := func(text string, finishReason any) gin.H {
+		candidate := gin.H{"content": gin.H{"parts": []gin.H{{"text": text}}, "role": "model"}, "index": 0}
+		if finishReason != nil {
+			candidate["finishReason"] = finishReason
+		}
+		return gin.H{
+			"candidates":    []gin.H{candidate},
+			"usageMetadata": gin.H{"promptTokenCount": promptTokens, "candidatesTokenCount": candidateTokens, "totalTokenCount": promptTokens + candidateTokens},
+		}
+	}
+
+	if !stream {
+		c.JSON(http.StatusOK, response(text, "STOP"))
+		return
+	}
+
+	startStream(c)
+	if !streamWords(c, text, interval, func(word string) string { return sseData(response(word, nil)) }) {
+		return
+	}
+	io.WriteString(c.Writer, sseData(response("", "STOP")))
+	c.Writer.Flush()
+}
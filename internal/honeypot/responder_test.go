@@ -0,0 +1,42 @@
+package honeypot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestServeStreamsOpenAIChunks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"model":"gpt-4o-mini","stream":true}`))
+
+	Serve(c, 0)
+
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `"object":"chat.completion.chunk"`) || !strings.Contains(body, `"model":"gpt-4o-mini"`) {
+		t.Fatalf("Serve() = %d %s, want OpenAI chunks for the requested model", w.Code, body)
+	}
+	if !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("Serve() stream should end with [DONE], got %q", body[max(0, len(body)-40):])
+	}
+}
+
+func TestParsePaths(t *testing.T) {
+	prefixes, err := ParsePaths("/v1/, /proxy/legacy/")
+	if err != nil {
+		t.Fatalf("ParsePaths() error = %v", err)
+	}
+	if !MatchesPath(prefixes, "/v1/chat/completions") || !MatchesPath(prefixes, "/proxy/legacy/v1/models") || MatchesPath(prefixes, "/proxy/openai/v1/models") {
+		t.Errorf("MatchesPath() with %v matched the wrong paths", prefixes)
+	}
+	for _, spec := range []string{"/", "/proxy", "/api/keys", "v1/"} {
+		if _, err := ParsePaths(spec); err == nil {
+			t.Errorf("ParsePaths(%q) should be rejected", spec)
+		}
+	}
+}
@@ -27,6 +27,15 @@ type Config struct {
 	TLSHandshakeTimeout   time.Duration
 	ExpectContinueTimeout time.Duration
 	ProxyURL              string
+	// HTTPVersion limits the transport to HTTP/1.1 or HTTP/2, see ParseHTTPVersion
+	HTTPVersion string
+	// TCPKeepAlive is the keep-alive period of connections, negative to disable probes
+	TCPKeepAlive time.Duration
+	// DNSCacheTTL caches the addresses of dialed hosts for that long, 0 to look them up per dial
+	DNSCacheTTL time.Duration
+	// PoolKey gives the client its own transport, apart from clients with the same settings,
+	// e.g. for the upstreams tuned on their own
+	PoolKey string
 }
 
 // HTTPClientManager manages the lifecycle of HTTP clients.
@@ -68,12 +77,25 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	}
 
 	// Create a new transport and client with the specified configuration.
+	keepAlive := config.TCPKeepAlive
+	if keepAlive == 0 {
+		keepAlive = 30 * time.Second
+	}
+	dialContext := (&net.Dialer{
+		Timeout:   config.ConnectTimeout,
+		KeepAlive: keepAlive,
+	}).DialContext
+	if config.DNSCacheTTL > 0 {
+		dialContext = newDNSCache(config.DNSCacheTTL).dialContext(dialContext)
+	}
+	httpVersion, err := ParseHTTPVersion(config.HTTPVersion)
+	if err != nil {
+		logrus.Warnf("%v, falling back to %s", err, HTTPVersionAuto)
+	}
 	transport := &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   config.ConnectTimeout,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		ForceAttemptHTTP2:     config.ForceAttemptHTTP2,
+		DialContext:           dialContext,
+		ForceAttemptHTTP2:     config.ForceAttemptHTTP2 && httpVersion != HTTPVersion1,
+		Protocols:             protocols(httpVersion),
 		MaxIdleConns:          config.MaxIdleConns,
 		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
 		IdleConnTimeout:       config.IdleConnTimeout,
@@ -89,7 +111,7 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	if config.ProxyURL != "" {
 		proxyURL, err := url.Parse(config.ProxyURL)
 		if err != nil {
-			logrus.Warnf("Invalid proxy URL '%s' provided, falling back to environment settings: %v", config.ProxyURL, err)
+			logrus.Warnf("Invalid proxy URL '%s' provided, falling back to environment settings: %v", RedactProxyURL(config.ProxyURL), withoutURL(err))
 			transport.Proxy = http.ProxyFromEnvironment
 		} else {
 			transport.Proxy = http.ProxyURL(proxyURL)
@@ -108,6 +130,19 @@ func (m *HTTPClientManager) GetClient(config *Config) *http.Client {
 	return newClient
 }
 
+// Reset drops the cached clients and closes their idle connections, so that clients are
+// created anew for the next requests. Clients still held by callers keep working.
+func (m *HTTPClientManager) Reset() {
+	m.lock.Lock()
+	clients := m.clients
+	m.clients = make(map[string]*http.Client)
+	m.lock.Unlock()
+
+	for _, client := range clients {
+		client.CloseIdleConnections()
+	}
+}
+
 // sensitiveProxyHeaders are custom-named credential headers that proxy channels
 // attach to upstream requests (e.g. x-api-key set by the messages-format
 // channel's ModifyRequest). Unlike the standard Authorization header, net/http
@@ -143,7 +178,7 @@ func stripSensitiveOnCrossHostRedirect(req *http.Request, via []*http.Request) e
 // getFingerprint generates a unique string representation of the client configuration.
 func (c *Config) getFingerprint() string {
 	return fmt.Sprintf(
-		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s",
+		"ct:%.0fs|rt:%.0fs|it:%.0fs|mic:%d|mich:%d|rht:%.0fs|dc:%t|wbs:%d|rbs:%d|fh2:%t|tlst:%.0fs|ect:%.0fs|proxy:%s|hv:%s|ka:%.0fs|dns:%.0fs|pool:%s",
 		c.ConnectTimeout.Seconds(),
 		c.RequestTimeout.Seconds(),
 		c.IdleConnTimeout.Seconds(),
@@ -157,5 +192,9 @@ func (c *Config) getFingerprint() string {
 		c.TLSHandshakeTimeout.Seconds(),
 		c.ExpectContinueTimeout.Seconds(),
 		c.ProxyURL,
+		c.HTTPVersion,
+		c.TCPKeepAlive.Seconds(),
+		c.DNSCacheTTL.Seconds(),
+		c.PoolKey,
 	)
 }
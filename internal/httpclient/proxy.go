@@ -0,0 +1,48 @@
+package httpclient
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// supportedProxySchemes are the outbound proxy schemes net/http dials itself.
+var supportedProxySchemes = map[string]bool{
+	"http":    true,
+	"https":   true,
+	"socks5":  true,
+	"socks5h": true,
+}
+
+// ValidateProxyURL checks that raw is an http, https, socks5 or socks5h proxy URL with a host.
+func ValidateProxyURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL: %w", withoutURL(err))
+	}
+	if !supportedProxySchemes[u.Scheme] {
+		return fmt.Errorf("unsupported proxy scheme %q, expected http, https, socks5 or socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("proxy URL has no host")
+	}
+	return nil
+}
+
+// RedactProxyURL hides the password of a proxy URL for logs and API responses.
+func RedactProxyURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "[invalid proxy URL]"
+	}
+	return u.Redacted()
+}
+
+// withoutURL drops the URL, and with it the proxy password, from a url.Parse error.
+func withoutURL(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTP versions a transport may be limited to.
+const (
+	HTTPVersionAuto = "auto"
+	HTTPVersion1    = "http1"
+	HTTPVersion2    = "http2"
+)
+
+// ParseHTTPVersion normalizes an HTTP version setting, empty meaning auto.
+func ParseHTTPVersion(s string) (string, error) {
+	switch v := strings.ToLower(strings.TrimSpace(s)); v {
+	case "", HTTPVersionAuto:
+		return HTTPVersionAuto, nil
+	case HTTPVersion1, HTTPVersion2:
+		return v, nil
+	}
+	return "", fmt.Errorf("unknown HTTP version %q, expected one of %s, %s, %s", s, HTTPVersionAuto, HTTPVersion1, HTTPVersion2)
+}
+
+// protocols returns the protocols of a transport limited to version, nil to negotiate
+// HTTP/2 over TLS and fall back to HTTP/1.1. HTTP/2 only also speaks HTTP/2 without TLS to
+// plain http upstreams, which must support it.
+func protocols(version string) *http.Protocols {
+	var p http.Protocols
+	switch version {
+	case HTTPVersion1:
+		p.SetHTTP1(true)
+	case HTTPVersion2:
+		p.SetHTTP2(true)
+		p.SetUnencryptedHTTP2(true)
+	default:
+		return nil
+	}
+	return &p
+}
+
+// UpstreamTransport tunes the HTTP client of a single upstream, set as "transport" in its
+// definition. Unset fields keep the settings of the group.
+type UpstreamTransport struct {
+	MaxIdleConns        *int   `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost *int   `json:"max_idle_conns_per_host,omitempty"`
+	IdleConnTimeout     *int   `json:"idle_conn_timeout,omitempty"`
+	HTTPVersion         string `json:"http_version,omitempty"`
+	TCPKeepAlive        *int   `json:"tcp_keepalive_seconds,omitempty"`
+	DNSCacheTTL         *int   `json:"dns_cache_ttl_seconds,omitempty"`
+}
+
+// Normalize validates the settings and normalizes the HTTP version.
+func (t *UpstreamTransport) Normalize() error {
+	for name, value := range map[string]*int{
+		"max_idle_conns":          t.MaxIdleConns,
+		"max_idle_conns_per_host": t.MaxIdleConnsPerHost,
+		"idle_conn_timeout":       t.IdleConnTimeout,
+	} {
+		if value != nil && *value < 1 {
+			return fmt.Errorf("%s must be at least 1", name)
+		}
+	}
+	for name, value := range map[string]*int{
+		"tcp_keepalive_seconds": t.TCPKeepAlive,
+		"dns_cache_ttl_seconds": t.DNSCacheTTL,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if t.HTTPVersion != "" {
+		version, err := ParseHTTPVersion(t.HTTPVersion)
+		if err != nil {
+			return err
+		}
+		t.HTTPVersion = version
+	}
+	return nil
+}
+
+// IsZero reports whether the upstream keeps all the settings of the group.
+func (t *UpstreamTransport) IsZero() bool {
+	return t == nil || *t == UpstreamTransport{}
+}
+
+// Apply overrides the settings of config with those set on the upstream.
+func (t *UpstreamTransport) Apply(config *Config) {
+	if t == nil {
+		return
+	}
+	if t.MaxIdleConns != nil {
+		config.MaxIdleConns = *t.MaxIdleConns
+	}
+	if t.MaxIdleConnsPerHost != nil {
+		config.MaxIdleConnsPerHost = *t.MaxIdleConnsPerHost
+	}
+	if t.IdleConnTimeout != nil {
+		config.IdleConnTimeout = time.Duration(*t.IdleConnTimeout) * time.Second
+	}
+	if t.HTTPVersion != "" {
+		config.HTTPVersion = t.HTTPVersion
+	}
+	if t.TCPKeepAlive != nil {
+		config.TCPKeepAlive = KeepAliveSeconds(*t.TCPKeepAlive)
+	}
+	if t.DNSCacheTTL != nil {
+		config.DNSCacheTTL = time.Duration(*t.DNSCacheTTL) * time.Second
+	}
+}
+
+// KeepAliveSeconds converts a TCP keep-alive setting in seconds, 0 disabling keep-alive probes,
+// into the keep-alive period of a dialer, where 0 means the system default and negative off.
+func KeepAliveSeconds(seconds int) time.Duration {
+	if seconds <= 0 {
+		return -1
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,88 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDNSCache(t *testing.T) {
+	lookups := 0
+	now := time.Unix(0, 0)
+	cache := newDNSCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		return []net.IPAddr{{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+
+	var dialed []string
+	dial := cache.dialContext(func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		client, server := net.Pipe()
+		server.Close()
+		return client, nil
+	})
+	for range 2 {
+		if _, err := dial(context.Background(), "tcp", "upstream.example:443"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if lookups != 1 || dialed[0] != "127.0.0.1:443" {
+		t.Fatalf("lookups = %d, dialed = %v, want one lookup and the cached address", lookups, dialed)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := dial(context.Background(), "tcp", "upstream.example:443"); err != nil {
+		t.Fatal(err)
+	}
+	if lookups != 2 {
+		t.Errorf("lookups = %d after the TTL, want 2", lookups)
+	}
+
+	if _, err := dial(context.Background(), "tcp", "10.0.0.1:443"); err != nil || lookups != 2 {
+		t.Errorf("dialing an IP looked it up: lookups = %d, err = %v", lookups, err)
+	}
+}
+
+func TestHTTPVersion(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	manager := NewHTTPClientManager()
+	for version, want := range map[string]string{HTTPVersionAuto: "HTTP/2.0", HTTPVersion1: "HTTP/1.1", HTTPVersion2: "HTTP/2.0"} {
+		client := manager.GetClient(&Config{ForceAttemptHTTP2: true, HTTPVersion: version, DNSCacheTTL: time.Minute})
+		client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: server.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs}
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("%s: %v", version, err)
+		}
+		resp.Body.Close()
+		if resp.Proto != want {
+			t.Errorf("%s: got %s, want %s", version, resp.Proto, want)
+		}
+	}
+
+	if _, err := ParseHTTPVersion("http3"); err == nil {
+		t.Error("ParseHTTPVersion(http3) succeeded, want an error")
+	}
+}
+
+func TestPoolKey(t *testing.T) {
+	manager := NewHTTPClientManager()
+	shared := manager.GetClient(&Config{})
+	if manager.GetClient(&Config{}) != shared {
+		t.Error("clients with the same settings are not shared")
+	}
+	if manager.GetClient(&Config{PoolKey: "https://upstream.example"}) == shared {
+		t.Error("a client with a pool key shares the transport of others")
+	}
+}
@@ -0,0 +1,131 @@
+// Package inflight keeps track of the proxy requests being served by this instance, so that
+// they can be inspected and a runaway request can be cancelled.
+package inflight
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrCancelled is the cause of the context of a request cancelled through the registry.
+var ErrCancelled = errors.New("request cancelled by an administrator")
+
+// Request describes an in-flight proxy request.
+type Request struct {
+	ID string `json:"id"`
+	// RequestID is the ID returned to the client, which clients may choose and so may repeat.
+	RequestID string `json:"request_id,omitempty"`
+	// Group is the group the client called, SubGroup the group of an aggregate serving it.
+	Group     string    `json:"group"`
+	SubGroup  string    `json:"sub_group,omitempty"`
+	KeyHash   string    `json:"key_hash,omitempty"`
+	Model     string    `json:"model,omitempty"`
+	IsStream  bool      `json:"is_stream"`
+	Attempt   int       `json:"attempt"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	ClientIP  string    `json:"client_ip"`
+	ProxyKey  string    `json:"proxy_key"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	ElapsedMs int64     `json:"elapsed_ms"`
+}
+
+type entry struct {
+	request Request
+	cancel  context.CancelCauseFunc
+}
+
+type contextKey struct{}
+
+// Registry holds the in-flight requests of this instance.
+type Registry struct {
+	mu       sync.Mutex
+	requests map[string]*entry
+}
+
+// NewRegistry creates a new Registry.
+func NewRegistry() *Registry {
+	return &Registry{requests: make(map[string]*entry)}
+}
+
+// Start registers a request and returns the context to serve it with, which is cancelled by
+// Cancel, and the function to call once the request is done.
+func (r *Registry) Start(ctx context.Context, request Request) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	request.ID = uuid.NewString()
+	if request.StartedAt.IsZero() {
+		request.StartedAt = time.Now()
+	}
+
+	r.mu.Lock()
+	r.requests[request.ID] = &entry{request: request, cancel: cancel}
+	r.mu.Unlock()
+
+	done := func() {
+		r.mu.Lock()
+		delete(r.requests, request.ID)
+		r.mu.Unlock()
+		cancel(nil)
+	}
+	return context.WithValue(ctx, contextKey{}, request.ID), done
+}
+
+// Update changes the description of the request served with ctx, as details such as the key
+// become known. It does nothing for a context not started by the registry.
+func (r *Registry) Update(ctx context.Context, update func(*Request)) {
+	id, ok := ctx.Value(contextKey{}).(string)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.requests[id]; ok {
+		update(&e.request)
+	}
+}
+
+// List returns the in-flight requests, of one group if group is not empty, longest running first.
+func (r *Registry) List(group string) []Request {
+	now := time.Now()
+
+	r.mu.Lock()
+	requests := make([]Request, 0, len(r.requests))
+	for _, e := range r.requests {
+		if group != "" && e.request.Group != group && e.request.SubGroup != group {
+			continue
+		}
+		request := e.request
+		request.ElapsedMs = now.Sub(request.StartedAt).Milliseconds()
+		requests = append(requests, request)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].StartedAt.Before(requests[j].StartedAt)
+	})
+	return requests
+}
+
+// Cancel cancels an in-flight request. It returns false if no such request is in flight.
+func (r *Registry) Cancel(id string) bool {
+	r.mu.Lock()
+	e, ok := r.requests[id]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	e.cancel(ErrCancelled)
+	return true
+}
+
+// IsCancelled reports whether the request served with ctx was cancelled through the registry.
+func IsCancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCancelled)
+}
@@ -0,0 +1,35 @@
+package inflight
+
+import (
+	"context"
+	"testing"
+)
+
+func TestRegistryCancel(t *testing.T) {
+	r := NewRegistry()
+	ctx, done := r.Start(context.Background(), Request{Group: "openai"})
+	r.Update(ctx, func(req *Request) {
+		req.Model = "gpt-4o"
+		req.IsStream = true
+	})
+
+	requests := r.List("openai")
+	if len(requests) != 1 || requests[0].Model != "gpt-4o" || !requests[0].IsStream {
+		t.Fatalf("List() = %+v", requests)
+	}
+	if len(r.List("gemini")) != 0 {
+		t.Error("List() of another group should be empty")
+	}
+
+	if !r.Cancel(requests[0].ID) {
+		t.Fatal("Cancel() of an in-flight request = false")
+	}
+	if ctx.Err() == nil || !IsCancelled(ctx) {
+		t.Errorf("context after Cancel(): err = %v, cancelled = %t", ctx.Err(), IsCancelled(ctx))
+	}
+
+	done()
+	if len(r.List("")) != 0 || r.Cancel(requests[0].ID) {
+		t.Error("request still registered after done()")
+	}
+}
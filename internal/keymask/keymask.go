@@ -0,0 +1,52 @@
+// Package keymask masks the API key values returned by the management API according to the
+// key_masking setting.
+package keymask
+
+// Masking levels of the key_masking setting.
+const (
+	// Full replaces the whole key.
+	Full = "full"
+	// Last4 keeps the last four characters.
+	Last4 = "last4"
+	// Hash shows a prefix of the key hash, which tells keys apart without revealing them.
+	Hash = "hash"
+	// Plaintext shows keys as they are to administrators. Every response carrying plaintext
+	// keys is audited.
+	Plaintext = "plaintext"
+)
+
+const (
+	mask       = "********"
+	hashPrefix = "hash:"
+	hashLength = 16
+)
+
+// Valid reports whether level is a masking level.
+func Valid(level string) bool {
+	switch level {
+	case Full, Last4, Hash, Plaintext:
+		return true
+	}
+	return false
+}
+
+// Mask returns a key as shown at a masking level. hash is the stored hash of the key. An
+// unknown level masks the key fully.
+func Mask(level, plaintext, hash string) string {
+	switch level {
+	case Plaintext:
+		return plaintext
+	case Last4:
+		if len(plaintext) <= 8 {
+			return mask
+		}
+		return "****" + plaintext[len(plaintext)-4:]
+	case Hash:
+		if hash == "" {
+			return mask
+		}
+		return hashPrefix + hash[:min(len(hash), hashLength)]
+	default:
+		return mask
+	}
+}
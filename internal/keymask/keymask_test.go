@@ -0,0 +1,29 @@
+package keymask
+
+import "testing"
+
+func TestMask(t *testing.T) {
+	const key = "sk-abcdefghijklmnop1234"
+	const hash = "0123456789abcdef0123456789abcdef"
+
+	tests := []struct {
+		level, plaintext, hash, want string
+	}{
+		{Plaintext, key, hash, key},
+		{Last4, key, hash, "****1234"},
+		{Last4, "short", hash, "********"},
+		{Hash, key, hash, "hash:0123456789abcdef"},
+		{Hash, key, "", "********"},
+		{Full, key, hash, "********"},
+		{"unknown", key, hash, "********"},
+	}
+	for _, tt := range tests {
+		if got := Mask(tt.level, tt.plaintext, tt.hash); got != tt.want {
+			t.Errorf("Mask(%q, %q) = %q, want %q", tt.level, tt.plaintext, got, tt.want)
+		}
+	}
+
+	if Valid("last-4") || !Valid(Hash) {
+		t.Error("Valid() does not match the masking levels")
+	}
+}
@@ -0,0 +1,259 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// CoolingKeysSet is a sorted set of "<groupID>:<keyID>" members scored by the Unix
+	// millisecond timestamp at which the key may be used again.
+	CoolingKeysSet         = "cooling_keys"
+	modelCooldownKeyPrefix = "model_cooldown:"
+	cooldownCheckInterval  = time.Second
+)
+
+// CoolDown takes a rate limited key out of rotation until its quota resets. Unlike
+// UpdateStatus it does not count a failure, the key returns to the pool at the given time.
+func (p *KeyProvider) CoolDown(apiKey *models.APIKey, group *models.Group, until time.Time) {
+	p.statusQueue.submit(apiKey, group, func(update *statusUpdate) {
+		update.addCooldown(until)
+	})
+}
+
+// coolKey removes a key from the active list and schedules its return.
+func (p *KeyProvider) coolKey(keyID, groupID uint, activeKeysListKey string, until time.Time) error {
+	if !until.After(time.Now()) {
+		return nil
+	}
+
+	member := fmt.Sprintf("%d:%d", groupID, keyID)
+	if err := p.store.ZAdd(CoolingKeysSet, map[string]float64{member: float64(until.UnixMilli())}); err != nil {
+		return fmt.Errorf("failed to schedule key cooldown: %w", err)
+	}
+	if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+		return fmt.Errorf("failed to LRem rate limited key from active list: %w", err)
+	}
+	p.publishKeyMove(KeyEventRateLimited, groupID, []models.APIKey{{ID: keyID}}, models.KeyStatusActive, KeyStateRateLimited)
+
+	logrus.WithFields(logrus.Fields{"keyID": keyID, "until": until.Format(time.RFC3339)}).Debug("Key is rate limited, cooling down until its quota resets.")
+	return nil
+}
+
+// startCooldownLoop returns cooled keys to their pools. Only the leading master runs it so
+// that a key is not pushed back twice.
+func (p *KeyProvider) startCooldownLoop() {
+	if !p.isMaster {
+		return
+	}
+
+	p.statusQueue.wg.Add(1)
+	go func() {
+		defer p.statusQueue.wg.Done()
+
+		ticker := time.NewTicker(cooldownCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if p.elector.IsLeader() {
+					p.restoreCooledKeys()
+				}
+			case <-p.statusQueue.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+func (p *KeyProvider) restoreCooledKeys() {
+	if _, err := p.RecoverCooledKeys(0); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// releaseCooledGroupKeys ends the cooldown of every cooling key of a group right away,
+// or of every cooling key when groupID is 0.
+func (p *KeyProvider) releaseCooledGroupKeys(groupID uint) (int, error) {
+	members, err := p.store.ZRangeByScore(CoolingKeysSet, 0, math.Inf(1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cooled keys: %w", err)
+	}
+
+	var prefix string
+	if groupID != 0 {
+		prefix = fmt.Sprintf("%d:", groupID)
+	}
+	released := 0
+	for _, member := range members {
+		if strings.HasPrefix(member, prefix) && p.returnCooledKey(member) {
+			released++
+		}
+	}
+	return released, nil
+}
+
+// returnCooledKey removes member from the cooling set and pushes the key back to its active list.
+func (p *KeyProvider) returnCooledKey(member string) bool {
+	if err := p.store.ZRem(CoolingKeysSet, member); err != nil {
+		logrus.Warnf("Failed to remove cooled key %s: %v", member, err)
+		return false
+	}
+
+	groupIDStr, keyID, ok := strings.Cut(member, ":")
+	if !ok {
+		return false
+	}
+
+	// Keys blacklisted or deleted while cooling stay out of the pool
+	keyDetails, err := p.store.HGetAll("key:" + keyID)
+	if err != nil {
+		logrus.Warnf("Failed to get details of cooled key %s: %v", keyID, err)
+		return false
+	}
+	if keyDetails["status"] != models.KeyStatusActive {
+		return false
+	}
+
+	activeKeysListKey := fmt.Sprintf("group:%s:active_keys", groupIDStr)
+	if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+		logrus.Warnf("Failed to LRem cooled key %s before LPush: %v", keyID, err)
+		return false
+	}
+	if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
+		logrus.Warnf("Failed to return cooled key %s to the active list: %v", keyID, err)
+		return false
+	}
+	logrus.WithField("keyID", keyID).Debug("Rate limit cooldown is over, key is back in the active pool.")
+	groupID, _ := strconv.ParseUint(groupIDStr, 10, 64)
+	id, _ := strconv.ParseUint(keyID, 10, 64)
+	p.publishKeyMove(KeyEventCooldownEnded, uint(groupID), []models.APIKey{{ID: uint(id)}}, KeyStateRateLimited, models.KeyStatusActive)
+	return true
+}
+
+// maxModelCooldownSkips bounds how many keys SelectKey skips because they are cooling for the requested model.
+const maxModelCooldownSkips = 10
+
+// CoolDownModel marks a key as rate limited for one model only. The key stays in the
+// pool for other models, the mark expires when the quota of the model resets.
+func (p *KeyProvider) CoolDownModel(apiKey *models.APIKey, model string, until time.Time) {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return
+	}
+	if err := p.store.Set(modelCooldownKey(apiKey.ID, model), []byte(strconv.FormatInt(until.Unix(), 10)), ttl); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "error": err}).Error("Failed to cool down key for model")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "model": model, "until": until.Format(time.RFC3339)}).Debug("Key is rate limited for model, cooling down until its quota resets.")
+}
+
+// rotateKey rotates the active list and returns the next key ID, skipping keys that are
+// cooling for the model. If every key is cooling it returns ErrNoActiveKeys, if only the
+// first maxModelCooldownSkips are it gives up and returns the last one. Demoted keys are
+// passed over the same way, but the first of them is returned when no other key is found.
+func (p *KeyProvider) rotateKey(activeKeysListKey, model string, demoted map[uint64]bool) (uint64, error) {
+	attempts := 1
+	checkedAll := false
+	if model != "" || len(demoted) > 0 {
+		length, err := p.store.LLen(activeKeysListKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get active key count: %w", err)
+		}
+		attempts = int(min(max(length, 1), maxModelCooldownSkips))
+		checkedAll = length <= maxModelCooldownSkips
+	}
+
+	var keyID, fallbackID uint64
+	for i := range attempts {
+		keyIDStr, err := p.store.Rotate(activeKeysListKey)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return 0, app_errors.ErrNoActiveKeys
+			}
+			return 0, fmt.Errorf("failed to rotate key from store: %w", err)
+		}
+
+		keyID, err = strconv.ParseUint(keyIDStr, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+		}
+
+		if model != "" {
+			if cooling, err := p.store.Exists(modelCooldownKey(uint(keyID), model)); err == nil && cooling {
+				if i == attempts-1 && checkedAll && fallbackID == 0 {
+					return 0, app_errors.ErrNoActiveKeys
+				}
+				continue
+			}
+		}
+		if !demoted[keyID] {
+			return keyID, nil
+		}
+		if fallbackID == 0 {
+			fallbackID = keyID
+		}
+	}
+
+	if fallbackID != 0 {
+		return fallbackID, nil
+	}
+	return keyID, nil
+}
+
+// FlushCooldowns ends the rate limit cooldowns of a group's keys, or of all keys when groupID
+// is 0: cooling keys return to their pools and per-model cooldown marks are removed.
+func (p *KeyProvider) FlushCooldowns(groupID uint) (int, error) {
+	released, err := p.releaseCooledGroupKeys(groupID)
+	if err != nil {
+		return 0, err
+	}
+
+	marks, err := p.store.Keys(modelCooldownKeyPrefix)
+	if err != nil {
+		return released, fmt.Errorf("failed to list model cooldowns: %w", err)
+	}
+	if len(marks) == 0 {
+		return released, nil
+	}
+
+	var groupKeyIDs map[string]struct{}
+	if groupID != 0 {
+		var ids []uint
+		if err := p.db.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("id", &ids).Error; err != nil {
+			return released, fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+		}
+		groupKeyIDs = make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			groupKeyIDs[strconv.FormatUint(uint64(id), 10)] = struct{}{}
+		}
+	}
+
+	var toDelete []string
+	for _, mark := range marks {
+		keyID, _, _ := strings.Cut(strings.TrimPrefix(mark, modelCooldownKeyPrefix), ":")
+		if _, ok := groupKeyIDs[keyID]; groupKeyIDs == nil || ok {
+			toDelete = append(toDelete, mark)
+		}
+	}
+	if len(toDelete) > 0 {
+		if err := p.store.Del(toDelete...); err != nil {
+			return released, fmt.Errorf("failed to remove model cooldowns: %w", err)
+		}
+	}
+	return released + len(toDelete), nil
+}
+
+func modelCooldownKey(keyID uint, model string) string {
+	return fmt.Sprintf("%s%d:%s", modelCooldownKeyPrefix, keyID, model)
+}
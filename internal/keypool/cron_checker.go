@@ -2,9 +2,11 @@ package keypool
 
 import (
 	"context"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
+	"math/rand/v2"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -13,12 +15,17 @@ import (
 	"gorm.io/gorm"
 )
 
-// NewCronChecker is responsible for periodically validating invalid keys.
+// CronChecker is responsible for periodically validating invalid keys and verifying
+// pending keys. Invalid keys are only validated within the validation windows of their
+// group, pending keys are verified at any time.
 type CronChecker struct {
 	DB              *gorm.DB
 	SettingsManager *config.SystemSettingsManager
 	Validator       *KeyValidator
+	KeyProvider     *KeyProvider
 	EncryptionSvc   encryption.Service
+	Elector         *cluster.Elector
+	Budget          *ValidationBudget
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 }
@@ -28,13 +35,19 @@ func NewCronChecker(
 	db *gorm.DB,
 	settingsManager *config.SystemSettingsManager,
 	validator *KeyValidator,
+	keyProvider *KeyProvider,
 	encryptionSvc encryption.Service,
+	elector *cluster.Elector,
+	budget *ValidationBudget,
 ) *CronChecker {
 	return &CronChecker{
 		DB:              db,
 		SettingsManager: settingsManager,
 		Validator:       validator,
+		KeyProvider:     keyProvider,
 		EncryptionSvc:   encryptionSvc,
+		Elector:         elector,
+		Budget:          budget,
 		stopChan:        make(chan struct{}),
 	}
 }
@@ -78,6 +91,15 @@ func (s *CronChecker) runLoop() {
 		case <-ticker.C:
 			logrus.Debug("CronChecker: Running as Master, submitting validation jobs.")
 			s.submitValidationJobs()
+		case groupID := <-s.KeyProvider.verifications:
+			// Imported keys are verified right away instead of at the next cycle
+			var group models.Group
+			if err := s.DB.First(&group, groupID).Error; err != nil {
+				logrus.Errorf("CronChecker: Failed to load group %d to verify its pending keys: %v", groupID, err)
+				continue
+			}
+			group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+			s.verifyPendingKeys(&group)
 		case <-s.stopChan:
 			return
 		}
@@ -86,8 +108,13 @@ func (s *CronChecker) runLoop() {
 
 // submitValidationJobs finds groups whose keys need validation and validates them concurrently.
 func (s *CronChecker) submitValidationJobs() {
+	if !s.Elector.IsLeader() {
+		return
+	}
+
 	var groups []models.Group
-	if err := s.DB.Where("group_type != ? OR group_type IS NULL", "aggregate").Find(&groups).Error; err != nil {
+	// Groups in maintenance are not validated until they are enabled again
+	if err := s.DB.Where("(group_type != ? OR group_type IS NULL) AND enabled = ?", "aggregate", true).Find(&groups).Error; err != nil {
 		logrus.Errorf("CronChecker: Failed to get groups: %v", err)
 		return
 	}
@@ -100,6 +127,22 @@ func (s *CronChecker) submitValidationJobs() {
 		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
 		interval := time.Duration(group.EffectiveConfig.KeyValidationIntervalMinutes) * time.Minute
 
+		// Catch up on deficits whose immediate refill was missed
+		if _, err := s.KeyProvider.RefillFromReserve(group.ID, group.EffectiveConfig.ReserveMinActiveKeys); err != nil {
+			logrus.Errorf("CronChecker: %v", err)
+		}
+
+		// Pending keys whose immediate verification was missed are verified every cycle
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.verifyPendingKeys(group)
+		}()
+
+		if !InValidationWindow(group, validationStartTime) {
+			logrus.Debugf("CronChecker: Group '%s' is outside its validation windows, invalid keys are not checked.", group.Name)
+			continue
+		}
 		if group.LastValidatedAt == nil || validationStartTime.Sub(*group.LastValidatedAt) > interval {
 			wg.Add(1)
 			g := group
@@ -132,55 +175,7 @@ func (s *CronChecker) validateGroupKeys(group *models.Group) {
 		return
 	}
 
-	var becameValidCount int32
-	var keyWg sync.WaitGroup
-	jobs := make(chan *models.APIKey, len(invalidKeys))
-
-	concurrency := group.EffectiveConfig.KeyValidationConcurrency
-	for range concurrency {
-		keyWg.Add(1)
-		go func() {
-			defer keyWg.Done()
-			for {
-				select {
-				case key, ok := <-jobs:
-					if !ok {
-						return
-					}
-
-					// Decrypt the key before validation
-					decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
-					if err != nil {
-						logrus.WithError(err).WithField("key_id", key.ID).Error("CronChecker: Failed to decrypt key for validation, skipping")
-						continue
-					}
-
-					// Create a copy with decrypted value for validation
-					keyForValidation := *key
-					keyForValidation.KeyValue = decryptedKey
-
-					isValid, _ := s.Validator.ValidateSingleKey(&keyForValidation, group)
-					if isValid {
-						atomic.AddInt32(&becameValidCount, 1)
-					}
-				case <-s.stopChan:
-					return
-				}
-			}
-		}()
-	}
-
-DistributeLoop:
-	for i := range invalidKeys {
-		select {
-		case jobs <- &invalidKeys[i]:
-		case <-s.stopChan:
-			break DistributeLoop
-		}
-	}
-	close(jobs)
-
-	keyWg.Wait()
+	becameValidCount, limiter := s.validateKeys(group, invalidKeys)
 
 	if err := s.DB.Model(group).Update("last_validated_at", time.Now()).Error; err != nil {
 		logrus.Errorf("CronChecker: Failed to update last_validated_at for group %s: %v", group.Name, err)
@@ -188,10 +183,69 @@ DistributeLoop:
 
 	duration := time.Since(groupProcessStart)
 	logrus.Infof(
-		"CronChecker: Group '%s' validation finished. Total checked: %d, became valid: %d. Duration: %s.",
+		"CronChecker: Group '%s' validation finished. Total checked: %d, became valid: %d. Final concurrency: %d after %d backoffs. Duration: %s.",
 		group.Name,
 		len(invalidKeys),
 		becameValidCount,
+		limiter.Concurrency(),
+		limiter.Backoffs(),
 		duration.String(),
 	)
 }
+
+// verifyPendingKeys validates the pending keys of a group. Verified keys enter rotation,
+// the others are disabled and checked again with the invalid keys.
+func (s *CronChecker) verifyPendingKeys(group *models.Group) {
+	var pendingKeys []models.APIKey
+	if err := s.DB.Where("group_id = ? AND status = ?", group.ID, models.KeyStatusPending).Find(&pendingKeys).Error; err != nil {
+		logrus.Errorf("CronChecker: Failed to get pending keys for group %s: %v", group.Name, err)
+		return
+	}
+	if len(pendingKeys) == 0 {
+		return
+	}
+
+	verifiedCount, _ := s.validateKeys(group, pendingKeys)
+	logrus.Infof("CronChecker: Group '%s' verified %d of %d pending keys.", group.Name, verifiedCount, len(pendingKeys))
+}
+
+// validateKeys validates keys of a group concurrently and returns how many of them are valid.
+func (s *CronChecker) validateKeys(group *models.Group, keys []models.APIKey) (int32, *ValidationLimiter) {
+	// Validate in random order so that no key is always checked first
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	limiter := NewValidationLimiter(group.EffectiveConfig.KeyValidationConcurrency, group.EffectiveConfig.KeyValidationMaxRPS).WithBudget(s.Budget)
+
+	var validCount int32
+	var keyWg sync.WaitGroup
+	for i := range keys {
+		if !limiter.Acquire(s.stopChan) {
+			break
+		}
+		key := &keys[i]
+		keyWg.Add(1)
+		go func() {
+			defer keyWg.Done()
+
+			// Decrypt the key before validation
+			decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+			if err != nil {
+				logrus.WithError(err).WithField("key_id", key.ID).Error("CronChecker: Failed to decrypt key for validation, skipping")
+				limiter.Release(err)
+				return
+			}
+
+			// Create a copy with decrypted value for validation
+			keyForValidation := *key
+			keyForValidation.KeyValue = decryptedKey
+
+			isValid, err := s.Validator.ValidateSingleKey(&keyForValidation, group)
+			limiter.Release(err)
+			if isValid {
+				atomic.AddInt32(&validCount, 1)
+			}
+		}()
+	}
+
+	keyWg.Wait()
+	return validCount, limiter
+}
@@ -0,0 +1,87 @@
+package keypool
+
+import (
+	"encoding/json"
+	"gpt-load/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KeyStatusEventChannel carries a KeyStatusEvent whenever keys are added, removed or change
+// status, on every instance sharing the store.
+const KeyStatusEventChannel = "key_status_events"
+
+// Types of KeyStatusEvent.
+const (
+	KeyEventAdded       = "added"
+	KeyEventRemoved     = "removed"
+	KeyEventRestored    = "restored"
+	KeyEventRecovered   = "recovered"
+	KeyEventBlacklisted = "blacklisted"
+	KeyEventLifecycle   = "lifecycle"
+	KeyEventPromoted    = "reserve_promoted"
+	// KeyEventRateLimited and KeyEventCooldownEnded report active keys leaving the rotation
+	// until their quota resets and coming back.
+	KeyEventRateLimited   = "rate_limited"
+	KeyEventCooldownEnded = "cooldown_ended"
+)
+
+// KeyStateRateLimited is the From or To of an event for an active key cooling down. It is
+// not a stored key status.
+const KeyStateRateLimited = "rate_limited"
+
+// KeyStatusEvent reports keys of a group moving from one status to another. From is empty
+// for added keys and To for removed ones.
+type KeyStatusEvent struct {
+	Type    string `json:"type"`
+	GroupID uint   `json:"group_id"`
+	KeyIDs  []uint `json:"key_ids"`
+	From    string `json:"from,omitempty"`
+	To      string `json:"to,omitempty"`
+	// ActiveKeys is the number of keys of the group in rotation after the change.
+	ActiveKeys int64     `json:"active_keys"`
+	At         time.Time `json:"at"`
+}
+
+// publishKeyStatus publishes the status changes of keys, one event per pair of statuses.
+// Events are best effort: a failure to publish is logged and does not fail the change.
+func (p *KeyProvider) publishKeyStatus(eventType string, groupID uint, keys []models.APIKey, transition func(key *models.APIKey) (from, to string)) {
+	if len(keys) == 0 {
+		return
+	}
+
+	activeKeys, err := p.ActiveKeyCount(groupID)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to count active keys for key status event")
+	}
+
+	var events []*KeyStatusEvent
+	byTransition := make(map[[2]string]*KeyStatusEvent)
+	now := time.Now()
+	for i := range keys {
+		from, to := transition(&keys[i])
+		event, ok := byTransition[[2]string{from, to}]
+		if !ok {
+			event = &KeyStatusEvent{Type: eventType, GroupID: groupID, From: from, To: to, ActiveKeys: activeKeys, At: now}
+			byTransition[[2]string{from, to}] = event
+			events = append(events, event)
+		}
+		event.KeyIDs = append(event.KeyIDs, keys[i].ID)
+	}
+
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			continue
+		}
+		if err := p.store.Publish(KeyStatusEventChannel, payload); err != nil {
+			logrus.WithError(err).Debug("Failed to publish key status event")
+		}
+	}
+}
+
+// publishKeyMove publishes keys that all moved from one status to another.
+func (p *KeyProvider) publishKeyMove(eventType string, groupID uint, keys []models.APIKey, from, to string) {
+	p.publishKeyStatus(eventType, groupID, keys, func(*models.APIKey) (string, string) { return from, to })
+}
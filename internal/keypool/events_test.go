@@ -0,0 +1,55 @@
+package keypool
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+)
+
+func TestCooldownPublishesKeyStatusEvents(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	activeKeysListKey := "group:1:active_keys"
+	if err := p.store.LPush(activeKeysListKey, 1, 3); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := p.store.Subscribe(KeyStatusEventChannel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+
+	next := func() KeyStatusEvent {
+		t.Helper()
+		select {
+		case msg := <-sub.Channel():
+			var event KeyStatusEvent
+			if err := json.Unmarshal(msg.Payload, &event); err != nil {
+				t.Fatal(err)
+			}
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("no key status event published")
+			return KeyStatusEvent{}
+		}
+	}
+
+	if err := p.coolKey(1, 1, activeKeysListKey, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	event := next()
+	if event.Type != KeyEventRateLimited || event.From != models.KeyStatusActive || event.To != KeyStateRateLimited ||
+		len(event.KeyIDs) != 1 || event.KeyIDs[0] != 1 || event.ActiveKeys != 1 {
+		t.Errorf("rate limited event = %+v", event)
+	}
+
+	if !p.returnCooledKey("1:1") {
+		t.Fatal("cooled key was not returned")
+	}
+	event = next()
+	if event.Type != KeyEventCooldownEnded || event.From != KeyStateRateLimited || event.To != models.KeyStatusActive || event.ActiveKeys != 2 {
+		t.Errorf("cooldown ended event = %+v", event)
+	}
+}
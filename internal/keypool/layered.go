@@ -0,0 +1,135 @@
+package keypool
+
+import (
+	"fmt"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// fallbackLayerPeriod is how long a key that failed stays in the fallback layer of a layered pool.
+const fallbackLayerPeriod = 5 * time.Minute
+
+// fallbackLayerKey is a sorted set of the key IDs in the fallback layer of a group, scored by
+// the Unix millisecond timestamp at which they return to the primary layer.
+func fallbackLayerKey(groupID uint) string {
+	return fmt.Sprintf("group:%d:fallback_keys", groupID)
+}
+
+// layeredPool is pool_mode layered: the keys in rotation form a primary layer and a fallback
+// layer. A key whose request fails drops to the fallback layer for fallbackLayerPeriod, or
+// until it succeeds again, and fallback keys are only served while no primary key is
+// available. The layers share the rotation of the simple pool and only add the fallback set.
+type layeredPool struct {
+	*KeyProvider
+}
+
+func (l layeredPool) SelectKey(group *models.Group, model string) (*models.APIKey, error) {
+	demoted, err := l.fallbackKeys(group.ID)
+	if err != nil {
+		// Without the layers the pool still serves its keys in turn
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to load the fallback layer, selecting from the whole rotation")
+	}
+	return l.selectKey(group.ID, model, demoted)
+}
+
+func (l layeredPool) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int, errorMessage string) {
+	switch {
+	case isSuccess:
+		l.promote(group.ID, apiKey.ID)
+	case !app_errors.IsUnCounted(errorMessage):
+		l.demote(group.ID, apiKey.ID)
+	}
+	l.KeyProvider.UpdateStatus(apiKey, group, isSuccess, statusCode, errorMessage)
+}
+
+func (l layeredPool) PoolMembership(group *models.Group) (*PoolMembership, error) {
+	membership, err := l.KeyProvider.PoolMembership(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	demoted, err := l.fallbackKeys(group.ID)
+	if err != nil {
+		return nil, err
+	}
+	membership.PoolMode = models.PoolModeLayered
+	for _, keyID := range membership.ActiveKeys {
+		if demoted[uint64(keyID)] {
+			membership.FallbackKeys = append(membership.FallbackKeys, keyID)
+		}
+	}
+	membership.Counts.Fallback = len(membership.FallbackKeys)
+	return membership, nil
+}
+
+func (l layeredPool) MoveKey(group *models.Group, keyID uint, state string, until time.Time) error {
+	if err := l.KeyProvider.MoveKey(group.ID, keyID, state, until); err != nil {
+		return err
+	}
+	if state == PoolStateActive {
+		if err := l.store.ZRem(fallbackLayerKey(group.ID), keyID); err != nil {
+			return fmt.Errorf("failed to move key to the primary layer: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l layeredPool) RebuildPool(group *models.Group) error {
+	if err := l.KeyProvider.RebuildPool(group.ID); err != nil {
+		return err
+	}
+	if err := l.store.Delete(fallbackLayerKey(group.ID)); err != nil {
+		return fmt.Errorf("failed to clear the fallback layer: %w", err)
+	}
+	return nil
+}
+
+// fallbackKeys returns the keys of a group currently in its fallback layer.
+func (l layeredPool) fallbackKeys(groupID uint) (map[uint64]bool, error) {
+	members, err := l.store.ZRangeByScore(fallbackLayerKey(groupID), float64(time.Now().UnixMilli()), math.Inf(1))
+	if err != nil {
+		return nil, err
+	}
+	demoted := make(map[uint64]bool, len(members))
+	for _, member := range members {
+		if keyID, err := strconv.ParseUint(member, 10, 64); err == nil {
+			demoted[keyID] = true
+		}
+	}
+	return demoted, nil
+}
+
+// demote moves a key to the fallback layer of its group, and drops the keys whose period in
+// the layer is over.
+func (l layeredPool) demote(groupID, keyID uint) {
+	layerKey := fallbackLayerKey(groupID)
+	now := time.Now()
+	until := float64(now.Add(fallbackLayerPeriod).UnixMilli())
+	if err := l.store.ZAdd(layerKey, map[string]float64{strconv.FormatUint(uint64(keyID), 10): until}); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "keyID": keyID, "error": err}).Error("Failed to move key to the fallback layer")
+		return
+	}
+
+	expired, err := l.store.ZRangeByScore(layerKey, 0, float64(now.UnixMilli()))
+	if err != nil || len(expired) == 0 {
+		return
+	}
+	members := make([]any, len(expired))
+	for i, member := range expired {
+		members[i] = member
+	}
+	if err := l.store.ZRem(layerKey, members...); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Warn("Failed to prune the fallback layer")
+	}
+}
+
+// promote moves a key back to the primary layer of its group.
+func (l layeredPool) promote(groupID, keyID uint) {
+	if err := l.store.ZRem(fallbackLayerKey(groupID), keyID); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "keyID": keyID, "error": err}).Warn("Failed to move key to the primary layer")
+	}
+}
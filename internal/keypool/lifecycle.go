@@ -0,0 +1,129 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"slices"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const verificationBacklog = 64
+
+// keyTransitions lists the statuses an administrator can move a key to from each status.
+// Pending keys become active by themselves once verified, invalid keys once they recover.
+var keyTransitions = map[string][]string{
+	models.KeyStatusPending:  {models.KeyStatusActive, models.KeyStatusArchived},
+	models.KeyStatusActive:   {models.KeyStatusRetiring},
+	models.KeyStatusInvalid:  {models.KeyStatusPending, models.KeyStatusArchived},
+	models.KeyStatusRetiring: {models.KeyStatusActive, models.KeyStatusArchived},
+	models.KeyStatusArchived: {models.KeyStatusPending},
+}
+
+// IsLifecycleTarget reports whether keys can be moved to status.
+func IsLifecycleTarget(status string) bool {
+	for _, targets := range keyTransitions {
+		if slices.Contains(targets, status) {
+			return true
+		}
+	}
+	return false
+}
+
+// LifecycleResult reports the keys moved by TransitionKeys.
+type LifecycleResult struct {
+	Moved []uint `json:"moved"`
+	// Skipped are the keys not found in the group or whose status cannot move to the target.
+	Skipped []uint `json:"skipped"`
+}
+
+// TransitionKeys moves keys of a group along their lifecycle. Retiring keys leave the
+// rotation, archived keys leave the store but keep their row and stats, and keys moved
+// back to pending are verified again before they serve requests.
+func (p *KeyProvider) TransitionKeys(groupID uint, keyIDs []uint, to string) (*LifecycleResult, error) {
+	result := &LifecycleResult{Moved: []uint{}, Skipped: []uint{}}
+
+	var moved []models.APIKey
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		var keys []models.APIKey
+		if err := tx.Where("group_id = ? AND id IN ?", groupID, keyIDs).Find(&keys).Error; err != nil {
+			return err
+		}
+
+		moving := slices.DeleteFunc(keys, func(key models.APIKey) bool {
+			return !slices.Contains(keyTransitions[key.Status], to)
+		})
+		if len(moving) == 0 {
+			return nil
+		}
+
+		moved = slices.Clone(moving)
+		updates := map[string]any{"status": to}
+		if to == models.KeyStatusActive || to == models.KeyStatusPending {
+			updates["failure_count"] = 0
+		}
+		if err := tx.Model(&models.APIKey{}).Where("id IN ?", pluckIDs(moving)).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		for i := range moving {
+			moving[i].Status = to
+			if _, reset := updates["failure_count"]; reset {
+				moving[i].FailureCount = 0
+			}
+			if err := p.applyLifecycleToStore(&moving[i]); err != nil {
+				return err
+			}
+		}
+		result.Moved = pluckIDs(moving)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to move keys of group %d to %s: %w", groupID, to, err)
+	}
+
+	for _, keyID := range keyIDs {
+		if !slices.Contains(result.Moved, keyID) && !slices.Contains(result.Skipped, keyID) {
+			result.Skipped = append(result.Skipped, keyID)
+		}
+	}
+	if len(result.Moved) > 0 {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "status": to, "keys": len(result.Moved)}).Info("Keys moved along their lifecycle by an administrator.")
+		p.publishKeyStatus(KeyEventLifecycle, groupID, moved, func(key *models.APIKey) (string, string) { return key.Status, to })
+	}
+	if to == models.KeyStatusPending && len(result.Moved) > 0 {
+		p.requestVerification(groupID)
+	}
+	return result, nil
+}
+
+// applyLifecycleToStore updates the store after the status of a key was changed.
+func (p *KeyProvider) applyLifecycleToStore(key *models.APIKey) error {
+	if key.Status == models.KeyStatusArchived {
+		if err := p.store.ZRem(CoolingKeysSet, fmt.Sprintf("%d:%d", key.GroupID, key.ID)); err != nil {
+			return fmt.Errorf("failed to end cooldown of key %d: %w", key.ID, err)
+		}
+		return p.removeKeyFromStore(key.ID, key.GroupID)
+	}
+
+	if err := p.addKeyToStore(key); err != nil {
+		return err
+	}
+	if key.Status == models.KeyStatusRetiring {
+		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", key.GroupID)
+		if err := p.store.LRem(activeKeysListKey, 0, key.ID); err != nil {
+			return fmt.Errorf("failed to LRem retiring key %d: %w", key.ID, err)
+		}
+	}
+	return nil
+}
+
+// requestVerification schedules the verification of the pending keys of a group.
+func (p *KeyProvider) requestVerification(groupID uint) {
+	select {
+	case p.verifications <- groupID:
+	default:
+		// The periodic check verifies the keys when the backlog is full
+	}
+}
@@ -0,0 +1,429 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// KeyPool is the key pool of the groups as seen by the proxy and the services: it hands out
+// keys, takes their outcome back and shows or repairs where the keys stand. Each group uses
+// the implementation of its pool_mode, see Pools.
+type KeyPool interface {
+	SelectKey(group *models.Group, model string) (*models.APIKey, error)
+	UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int, errorMessage string)
+	CoolDown(apiKey *models.APIKey, group *models.Group, until time.Time)
+	CoolDownModel(apiKey *models.APIKey, model string, until time.Time)
+	ActiveKeyCount(groupID uint) (int64, error)
+	PoolMembership(group *models.Group) (*PoolMembership, error)
+	MoveKey(group *models.Group, keyID uint, state string, until time.Time) error
+	RebuildPool(group *models.Group) error
+}
+
+var (
+	_ KeyPool = (*Pools)(nil)
+	_ KeyPool = simplePool{}
+	_ KeyPool = layeredPool{}
+)
+
+// simplePool is pool_mode simple: the keys in rotation are served in turn.
+type simplePool struct {
+	*KeyProvider
+}
+
+func (s simplePool) SelectKey(group *models.Group, model string) (*models.APIKey, error) {
+	return s.KeyProvider.SelectKey(group.ID, model)
+}
+
+func (s simplePool) PoolMembership(group *models.Group) (*PoolMembership, error) {
+	return s.KeyProvider.PoolMembership(group.ID)
+}
+
+func (s simplePool) MoveKey(group *models.Group, keyID uint, state string, until time.Time) error {
+	return s.KeyProvider.MoveKey(group.ID, keyID, state, until)
+}
+
+func (s simplePool) RebuildPool(group *models.Group) error {
+	return s.KeyProvider.RebuildPool(group.ID)
+}
+
+// Pools dispatches the key pool operations of each group to the pool of its pool_mode, and
+// migrates the pool of a group when its mode changes.
+type Pools struct {
+	provider *KeyProvider
+	simple   simplePool
+	layered  layeredPool
+
+	mu sync.Mutex
+	// modes holds the pool mode of each group as of the last SyncPoolModes
+	modes map[uint]string
+}
+
+// NewPools creates the key pools of the groups on top of the KeyProvider.
+func NewPools(provider *KeyProvider) *Pools {
+	return &Pools{
+		provider: provider,
+		simple:   simplePool{provider},
+		layered:  layeredPool{provider},
+	}
+}
+
+// NewKeyPool provides the Pools as the KeyPool of the proxy and the services.
+func NewKeyPool(pools *Pools) KeyPool {
+	return pools
+}
+
+// poolMode returns the pool mode of a group, simple unless it is set to layered.
+func poolMode(group *models.Group) string {
+	if group.EffectiveConfig.PoolMode == models.PoolModeLayered {
+		return models.PoolModeLayered
+	}
+	return models.PoolModeSimple
+}
+
+func (p *Pools) pool(group *models.Group) KeyPool {
+	if poolMode(group) == models.PoolModeLayered {
+		return p.layered
+	}
+	return p.simple
+}
+
+// SelectKey selects the next key of a group.
+func (p *Pools) SelectKey(group *models.Group, model string) (*models.APIKey, error) {
+	return p.pool(group).SelectKey(group, model)
+}
+
+// UpdateStatus reports the outcome of a request made with a key of a group.
+func (p *Pools) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int, errorMessage string) {
+	p.pool(group).UpdateStatus(apiKey, group, isSuccess, statusCode, errorMessage)
+}
+
+// CoolDown takes a rate limited key of a group out of rotation until the given time.
+func (p *Pools) CoolDown(apiKey *models.APIKey, group *models.Group, until time.Time) {
+	p.pool(group).CoolDown(apiKey, group, until)
+}
+
+// CoolDownModel skips a key for one model until the given time, in every mode.
+func (p *Pools) CoolDownModel(apiKey *models.APIKey, model string, until time.Time) {
+	p.provider.CoolDownModel(apiKey, model, until)
+}
+
+// ActiveKeyCount returns the number of keys of a group in rotation, in every mode.
+func (p *Pools) ActiveKeyCount(groupID uint) (int64, error) {
+	return p.provider.ActiveKeyCount(groupID)
+}
+
+// PoolMembership returns the actual membership of a group's key pool.
+func (p *Pools) PoolMembership(group *models.Group) (*PoolMembership, error) {
+	return p.pool(group).PoolMembership(group)
+}
+
+// MoveKey puts a key of a group back into rotation or cools it down until the given time.
+func (p *Pools) MoveKey(group *models.Group, keyID uint, state string, until time.Time) error {
+	return p.pool(group).MoveKey(group, keyID, state, until)
+}
+
+// RebuildPool rebuilds the key pool of a group from the database.
+func (p *Pools) RebuildPool(group *models.Group) error {
+	return p.pool(group).RebuildPool(group)
+}
+
+// SyncPoolModes migrates the pools of the groups whose pool mode changed since the last call.
+// The group cache calls it after every reload, so each instance migrates the groups it
+// serves; the first call only records the modes.
+func (p *Pools) SyncPoolModes(groups map[string]*models.Group) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	modes := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		mode := poolMode(group)
+		modes[group.ID] = mode
+		if prev, seen := p.modes[group.ID]; seen && prev != mode {
+			if err := p.MigratePoolMode(group.ID, mode); err != nil {
+				logrus.WithFields(logrus.Fields{"groupID": group.ID, "mode": mode, "error": err}).Error("Failed to migrate the key pool to its new mode")
+			}
+		}
+	}
+	p.modes = modes
+}
+
+// MigratePoolMode moves the store state of a group to the given pool mode while its keys keep
+// being served. Both modes share the rotation, so only the fallback layer is built or dropped:
+// a group turning layered starts with the active keys that failed since their last success in
+// the fallback layer, a group turning simple drops the layer and its keys stay in rotation.
+// It is idempotent, so several instances may run it for the same change.
+func (p *Pools) MigratePoolMode(groupID uint, mode string) error {
+	layerKey := fallbackLayerKey(groupID)
+	if mode != models.PoolModeLayered {
+		if err := p.provider.store.Delete(layerKey); err != nil {
+			return fmt.Errorf("failed to drop the fallback layer: %w", err)
+		}
+		logrus.WithField("groupID", groupID).Info("Key pool migrated to simple mode.")
+		return nil
+	}
+
+	var keyIDs []uint
+	if err := p.provider.db.Model(&models.APIKey{}).
+		Where("group_id = ? AND status = ? AND failure_count > 0", groupID, models.KeyStatusActive).
+		Pluck("id", &keyIDs).Error; err != nil {
+		return fmt.Errorf("failed to load failing keys of group %d: %w", groupID, err)
+	}
+	if len(keyIDs) > 0 {
+		until := float64(time.Now().Add(fallbackLayerPeriod).UnixMilli())
+		members := make(map[string]float64, len(keyIDs))
+		for _, keyID := range keyIDs {
+			members[strconv.FormatUint(uint64(keyID), 10)] = until
+		}
+		if err := p.provider.store.ZAdd(layerKey, members); err != nil {
+			return fmt.Errorf("failed to fill the fallback layer: %w", err)
+		}
+	}
+	logrus.WithFields(logrus.Fields{"groupID": groupID, "fallbackKeys": len(keyIDs)}).Info("Key pool migrated to layered mode.")
+	return nil
+}
+
+// Pool states a key can be moved to.
+const (
+	PoolStateActive  = "active"
+	PoolStateCooling = "cooling"
+)
+
+var (
+	// ErrKeyNotInGroup is returned when moving a key that does not belong to the group.
+	ErrKeyNotInGroup = errors.New("key does not belong to the group")
+	// ErrKeyNotActive is returned when moving a key that is not active in the database.
+	ErrKeyNotActive = errors.New("key is not active")
+)
+
+// PoolCounts counts the keys of a group in each state.
+type PoolCounts struct {
+	Active       int `json:"active"`
+	Cooling      int `json:"cooling"`
+	ModelCooling int `json:"model_cooling"`
+	Invalid      int `json:"invalid"`
+	Missing      int `json:"missing"`
+	Stale        int `json:"stale"`
+	Fallback     int `json:"fallback"`
+}
+
+// PoolMembership describes the keys of a group as held in the store, next to what the
+// database says, to diagnose keys that are active but never selected or the other way round.
+type PoolMembership struct {
+	GroupID  uint       `json:"group_id"`
+	PoolMode string     `json:"pool_mode"`
+	Counts   PoolCounts `json:"counts"`
+	// ActiveKeys are the keys in rotation, the next one to be selected first.
+	ActiveKeys []uint `json:"active_keys"`
+	// CoolingKeys are rate limited and return to the rotation once their quota resets.
+	CoolingKeys []uint `json:"cooling_keys"`
+	// ModelCooldowns lists the models each key is skipped for.
+	ModelCooldowns map[uint][]string `json:"model_cooldowns"`
+	// MissingKeys are active in the database but neither in rotation nor cooling down.
+	MissingKeys []uint `json:"missing_keys"`
+	// StaleKeys are in rotation but not active in the database.
+	StaleKeys []uint `json:"stale_keys"`
+	// FallbackKeys are the keys in rotation that a layered pool only serves while no other
+	// key is available.
+	FallbackKeys []uint `json:"fallback_keys"`
+}
+
+// ActiveKeyCount returns the number of keys of a group in rotation.
+func (p *KeyProvider) ActiveKeyCount(groupID uint) (int64, error) {
+	return p.store.LLen(fmt.Sprintf("group:%d:active_keys", groupID))
+}
+
+// PoolMembership returns the actual membership of a group's key pool.
+func (p *KeyProvider) PoolMembership(groupID uint) (*PoolMembership, error) {
+	membership := &PoolMembership{
+		GroupID:        groupID,
+		PoolMode:       models.PoolModeSimple,
+		ActiveKeys:     []uint{},
+		CoolingKeys:    []uint{},
+		ModelCooldowns: map[uint][]string{},
+		MissingKeys:    []uint{},
+		StaleKeys:      []uint{},
+		FallbackKeys:   []uint{},
+	}
+
+	members, err := p.store.LRange(fmt.Sprintf("group:%d:active_keys", groupID), 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active keys: %w", err)
+	}
+	// Rotate takes keys from the tail of the list
+	for _, member := range slices.Backward(members) {
+		if keyID, err := strconv.ParseUint(member, 10, 64); err == nil {
+			membership.ActiveKeys = append(membership.ActiveKeys, uint(keyID))
+		}
+	}
+
+	cooling, err := p.store.ZRangeByScore(CoolingKeysSet, 0, math.Inf(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cooling keys: %w", err)
+	}
+	groupPrefix := fmt.Sprintf("%d:", groupID)
+	for _, member := range cooling {
+		if id, ok := strings.CutPrefix(member, groupPrefix); ok {
+			if keyID, err := strconv.ParseUint(id, 10, 64); err == nil {
+				membership.CoolingKeys = append(membership.CoolingKeys, uint(keyID))
+			}
+		}
+	}
+
+	var dbKeys []models.APIKey
+	if err := p.db.Model(&models.APIKey{}).Select("id", "status").Where("group_id = ?", groupID).Find(&dbKeys).Error; err != nil {
+		return nil, fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+	}
+	dbActive := make(map[uint]bool, len(dbKeys))
+	for _, key := range dbKeys {
+		dbActive[key.ID] = key.Status == models.KeyStatusActive
+		if key.Status == models.KeyStatusInvalid {
+			membership.Counts.Invalid++
+		}
+	}
+
+	for mark, err := range p.store.Scan(modelCooldownKeyPrefix) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list model cooldowns: %w", err)
+		}
+		id, model, _ := strings.Cut(strings.TrimPrefix(mark, modelCooldownKeyPrefix), ":")
+		keyID, err := strconv.ParseUint(id, 10, 64)
+		if _, inGroup := dbActive[uint(keyID)]; err == nil && inGroup {
+			membership.ModelCooldowns[uint(keyID)] = append(membership.ModelCooldowns[uint(keyID)], model)
+		}
+	}
+
+	for _, keyID := range membership.ActiveKeys {
+		if !dbActive[keyID] {
+			membership.StaleKeys = append(membership.StaleKeys, keyID)
+		}
+	}
+	for _, key := range dbKeys {
+		if dbActive[key.ID] && !slices.Contains(membership.ActiveKeys, key.ID) && !slices.Contains(membership.CoolingKeys, key.ID) {
+			membership.MissingKeys = append(membership.MissingKeys, key.ID)
+		}
+	}
+
+	membership.Counts.Active = len(membership.ActiveKeys)
+	membership.Counts.Cooling = len(membership.CoolingKeys)
+	membership.Counts.ModelCooling = len(membership.ModelCooldowns)
+	membership.Counts.Missing = len(membership.MissingKeys)
+	membership.Counts.Stale = len(membership.StaleKeys)
+	return membership, nil
+}
+
+// MoveKey puts an active key of a group back into rotation, ending its cooldowns, or takes
+// it out of rotation until the given time as if it had been rate limited.
+func (p *KeyProvider) MoveKey(groupID, keyID uint, state string, until time.Time) error {
+	var key models.APIKey
+	if err := p.db.Select("id", "status").Where("id = ? AND group_id = ?", keyID, groupID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrKeyNotInGroup
+		}
+		return fmt.Errorf("failed to load key %d: %w", keyID, err)
+	}
+	if key.Status != models.KeyStatusActive {
+		return ErrKeyNotActive
+	}
+
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+	switch state {
+	case PoolStateCooling:
+		if !until.After(time.Now()) {
+			return fmt.Errorf("cooldown end %s is not in the future", until.Format(time.RFC3339))
+		}
+		return p.coolKey(keyID, groupID, activeKeysListKey, until)
+	case PoolStateActive:
+		if err := p.store.ZRem(CoolingKeysSet, fmt.Sprintf("%d:%d", groupID, keyID)); err != nil {
+			return fmt.Errorf("failed to end key cooldown: %w", err)
+		}
+		var marks []string
+		for mark, err := range p.store.Scan(fmt.Sprintf("%s%d:", modelCooldownKeyPrefix, keyID)) {
+			if err != nil {
+				return fmt.Errorf("failed to list model cooldowns: %w", err)
+			}
+			marks = append(marks, mark)
+		}
+		if len(marks) > 0 {
+			if err := p.store.Del(marks...); err != nil {
+				return fmt.Errorf("failed to remove model cooldowns: %w", err)
+			}
+		}
+		if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+			return fmt.Errorf("failed to LRem key before LPush: %w", err)
+		}
+		if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
+			return fmt.Errorf("failed to LPush key to active list: %w", err)
+		}
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "keyID": keyID}).Info("Key moved back into rotation by an administrator.")
+		return nil
+	}
+	return fmt.Errorf("unknown pool state %q", state)
+}
+
+// RebuildPool rebuilds the key pool of a group from the database: key details are reloaded
+// and the rotation holds exactly the active keys that are not cooling down.
+func (p *KeyProvider) RebuildPool(groupID uint) error {
+	var keys []models.APIKey
+	if err := p.db.Where("group_id = ? AND status <> ?", groupID, models.KeyStatusArchived).Find(&keys).Error; err != nil {
+		return fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+	}
+
+	cooling, err := p.store.ZRangeByScore(CoolingKeysSet, 0, math.Inf(1))
+	if err != nil {
+		return fmt.Errorf("failed to list cooling keys: %w", err)
+	}
+
+	var activeIDs []any
+	for i := range keys {
+		key := &keys[i]
+		if err := p.store.HSet(fmt.Sprintf("key:%d", key.ID), p.apiKeyToMap(key)); err != nil {
+			return fmt.Errorf("failed to reload details of key %d: %w", key.ID, err)
+		}
+		if key.Status == models.KeyStatusActive && !slices.Contains(cooling, fmt.Sprintf("%d:%d", groupID, key.ID)) {
+			activeIDs = append(activeIDs, key.ID)
+		}
+	}
+
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+	if err := p.store.Delete(activeKeysListKey); err != nil {
+		return fmt.Errorf("failed to clear active list: %w", err)
+	}
+	if len(activeIDs) > 0 {
+		if err := p.store.LPush(activeKeysListKey, activeIDs...); err != nil {
+			return fmt.Errorf("failed to fill active list: %w", err)
+		}
+	}
+	logrus.WithFields(logrus.Fields{"groupID": groupID, "activeKeys": len(activeIDs)}).Info("Key pool rebuilt from the database.")
+	return nil
+}
+
+// RecoverCooledKeys returns the keys whose cooldown is over to their pools without waiting
+// for the cooldown loop, for one group or for all groups when groupID is 0.
+func (p *KeyProvider) RecoverCooledKeys(groupID uint) (int, error) {
+	members, err := p.store.ZRangeByScore(CoolingKeysSet, 0, float64(time.Now().UnixMilli()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to get cooled keys: %w", err)
+	}
+
+	var prefix string
+	if groupID != 0 {
+		prefix = fmt.Sprintf("%d:", groupID)
+	}
+	recovered := 0
+	for _, member := range members {
+		if strings.HasPrefix(member, prefix) && p.returnCooledKey(member) {
+			recovered++
+		}
+	}
+	return recovered, nil
+}
@@ -0,0 +1,106 @@
+package keypool
+
+import (
+	"fmt"
+	"testing"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+)
+
+// selectKeyIDs selects n keys of a group and returns their IDs.
+func selectKeyIDs(t *testing.T, pools *Pools, group *models.Group, n int) []uint {
+	t.Helper()
+	var ids []uint
+	for range n {
+		key, err := pools.SelectKey(group, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, key.ID)
+	}
+	return ids
+}
+
+func TestLayeredPool(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	p.encryptionSvc, _ = encryption.NewService("")
+	if err := p.RebuildPool(1); err != nil {
+		t.Fatal(err)
+	}
+	pools := NewPools(p)
+	group := &models.Group{ID: 1}
+	group.EffectiveConfig.PoolMode = models.PoolModeLayered
+
+	// A key that fails is only served once every other key has failed too
+	pools.UpdateStatus(&models.APIKey{ID: 1, GroupID: 1}, group, false, 500, "upstream error")
+	for _, id := range selectKeyIDs(t, pools, group, 6) {
+		if id == 1 {
+			t.Fatal("a key in the fallback layer was served while primary keys were available")
+		}
+	}
+	m, err := pools.PoolMembership(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.PoolMode != models.PoolModeLayered || fmt.Sprint(m.FallbackKeys) != "[1]" || m.Counts.Fallback != 1 {
+		t.Errorf("membership = mode %s, fallback %v", m.PoolMode, m.FallbackKeys)
+	}
+
+	pools.UpdateStatus(&models.APIKey{ID: 2, GroupID: 1}, group, false, 500, "upstream error")
+	pools.UpdateStatus(&models.APIKey{ID: 3, GroupID: 1}, group, false, 500, "upstream error")
+	if ids := selectKeyIDs(t, pools, group, 3); len(ids) != 3 {
+		t.Fatalf("selected %v with every key in the fallback layer", ids)
+	}
+
+	pools.UpdateStatus(&models.APIKey{ID: 2, GroupID: 1}, group, true, 200, "")
+	for _, id := range selectKeyIDs(t, pools, group, 3) {
+		if id != 2 {
+			t.Fatalf("selected key %d, want the only key back in the primary layer", id)
+		}
+	}
+
+	// The simple pool ignores the layers
+	simple := &models.Group{ID: 1}
+	if ids := selectKeyIDs(t, pools, simple, 3); fmt.Sprint(ids) == "[2 2 2]" {
+		t.Errorf("the simple pool selected %v", ids)
+	}
+}
+
+func TestMigratePoolMode(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	if err := p.RebuildPool(1); err != nil {
+		t.Fatal(err)
+	}
+	pools := NewPools(p)
+	group := &models.Group{ID: 1, Name: "group"}
+	groups := map[string]*models.Group{group.Name: group}
+
+	// The first sync only records the modes
+	pools.SyncPoolModes(groups)
+	if exists, _ := p.store.Exists(fallbackLayerKey(1)); exists {
+		t.Fatal("the first sync should not migrate")
+	}
+
+	// Turning layered puts the active keys that failed since their last success, key 2, in
+	// the fallback layer and leaves the rotation as it is
+	layered := &models.Group{ID: 1, Name: "group"}
+	layered.EffectiveConfig.PoolMode = models.PoolModeLayered
+	pools.SyncPoolModes(map[string]*models.Group{layered.Name: layered})
+	m, err := pools.PoolMembership(layered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.FallbackKeys) != "[2]" || len(m.ActiveKeys) != 3 {
+		t.Errorf("after migrating to layered: fallback = %v, active = %v", m.FallbackKeys, m.ActiveKeys)
+	}
+
+	pools.SyncPoolModes(groups)
+	if exists, _ := p.store.Exists(fallbackLayerKey(1)); exists {
+		t.Error("migrating to simple should drop the fallback layer")
+	}
+	if count, _ := pools.ActiveKeyCount(1); count != 3 {
+		t.Errorf("active keys after migrating to simple = %d, want 3", count)
+	}
+}
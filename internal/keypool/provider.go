@@ -1,16 +1,18 @@
 package keypool
 
 import (
-	"errors"
 	"fmt"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
+	"gpt-load/internal/db"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/failover"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
-	"math/rand"
+	"gpt-load/internal/types"
+	"slices"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -22,34 +24,56 @@ type KeyProvider struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	encryptionSvc   encryption.Service
+	statusQueue     *statusQueue
+	statusBuffer    *statusBuffer
+	reserveRefills  chan reserveRefill
+	verifications   chan uint
+	dbHealth        *db.HealthMonitor
+	elector         *cluster.Elector
+	isMaster        bool
 }
 
 // NewProvider 创建一个新的 KeyProvider 实例。
-func NewProvider(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyProvider {
+func NewProvider(database *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service, configManager types.ConfigManager, dbHealth *db.HealthMonitor, elector *cluster.Elector) *KeyProvider {
+	perfConfig := configManager.GetPerformanceConfig()
+
+	// SQLite has a single writer, so concurrent workers would only contend for its lock.
+	// One worker commits the updates in batches instead.
+	workers, batchSize := perfConfig.KeyStatusWorkers, 1
+	if db.IsSQLite(database) {
+		workers, batchSize = 1, sqliteWriteBatchSize
+	}
+
 	return &KeyProvider{
-		db:              db,
+		db:              database,
 		store:           store,
 		settingsManager: settingsManager,
 		encryptionSvc:   encryptionSvc,
+		statusQueue:     newStatusQueue(workers, perfConfig.KeyStatusQueueSize, batchSize),
+		statusBuffer:    newStatusBuffer(perfConfig),
+		reserveRefills:  make(chan reserveRefill, reserveRefillBacklog),
+		verifications:   make(chan uint, verificationBacklog),
+		dbHealth:        dbHealth,
+		elector:         elector,
+		isMaster:        configManager.IsMaster(),
 	}
 }
 
 // SelectKey 为指定的分组原子性地选择并轮换一个可用的 APIKey。
-func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
+// model 不为空时跳过针对该模型处于限流冷却中的 Key。
+func (p *KeyProvider) SelectKey(groupID uint, model string) (*models.APIKey, error) {
+	return p.selectKey(groupID, model, nil)
+}
+
+// selectKey selects the next key of a group, passing over the demoted keys while one that is
+// not demoted is found.
+func (p *KeyProvider) selectKey(groupID uint, model string, demoted map[uint64]bool) (*models.APIKey, error) {
 	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
 
 	// 1. Atomically rotate the key ID from the list
-	keyIDStr, err := p.store.Rotate(activeKeysListKey)
-	if err != nil {
-		if errors.Is(err, store.ErrNotFound) {
-			return nil, app_errors.ErrNoActiveKeys
-		}
-		return nil, fmt.Errorf("failed to rotate key from store: %w", err)
-	}
-
-	keyID, err := strconv.ParseUint(keyIDStr, 10, 64)
+	keyID, err := p.rotateKey(activeKeysListKey, model, demoted)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse key ID '%s': %w", keyIDStr, err)
+		return nil, err
 	}
 
 	// 2. Get key details from HASH
@@ -87,111 +111,104 @@ func (p *KeyProvider) SelectKey(groupID uint) (*models.APIKey, error) {
 	return apiKey, nil
 }
 
-// UpdateStatus 异步地提交一个 Key 状态更新任务。
-func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, errorMessage string) {
-	go func() {
-		keyHashKey := fmt.Sprintf("key:%d", apiKey.ID)
-		activeKeysListKey := fmt.Sprintf("group:%d:active_keys", group.ID)
-
-		if isSuccess {
-			if err := p.handleSuccess(apiKey.ID, keyHashKey, activeKeysListKey); err != nil {
-				logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key success")
-			}
-		} else {
-			if app_errors.IsUnCounted(errorMessage) {
-				logrus.WithFields(logrus.Fields{
-					"keyID": apiKey.ID,
-					"error": errorMessage,
-				}).Debug("Uncounted error, skipping failure handling")
-			} else {
-				if err := p.handleFailure(apiKey, group, keyHashKey, activeKeysListKey); err != nil {
-					logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Error("Failed to handle key failure")
-				}
-			}
-		}
-	}()
-}
-
-// executeTransactionWithRetry wraps a database transaction with a retry mechanism.
-func (p *KeyProvider) executeTransactionWithRetry(operation func(tx *gorm.DB) error) error {
-	const maxRetries = 3
-	const baseDelay = 50 * time.Millisecond
-	const maxJitter = 150 * time.Millisecond
-	var err error
+// UpdateStatus 将 Key 状态更新提交到有界队列，由固定数量的 worker 异步处理。
+// 同一个 Key 排队中的更新会被合并，队列满时新的更新会被丢弃。
+// 启用 KEY_STATUS_FLUSH_INTERVAL 时更新先在 Store 中按 Key 累积，再定期批量写入数据库。
+// statusCode 为上游返回的状态码，用于匹配分组按状态码配置的拉黑阈值，未知时传 0。
+func (p *KeyProvider) UpdateStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int, errorMessage string) {
+	if !isSuccess && app_errors.IsUnCounted(errorMessage) {
+		logrus.WithFields(logrus.Fields{
+			"keyID": apiKey.ID,
+			"error": errorMessage,
+		}).Debug("Uncounted error, skipping failure handling")
+		return
+	}
 
-	for i := range maxRetries {
-		err = p.db.Transaction(operation)
+	if p.statusBuffer.enabled() {
+		err := p.bufferStatus(apiKey, group, isSuccess, statusCode)
 		if err == nil {
-			return nil
+			return
 		}
-
-		if strings.Contains(err.Error(), "database is locked") {
-			jitter := time.Duration(rand.Intn(int(maxJitter)))
-			totalDelay := baseDelay + jitter
-			logrus.Debugf("Database is locked, retrying in %v... (attempt %d/%d)", totalDelay, i+1, maxRetries)
-			time.Sleep(totalDelay)
-			continue
-		}
-
-		break
+		logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "error": err}).Warn("Failed to buffer key status update, queueing it instead")
 	}
 
-	return err
+	p.statusQueue.submit(apiKey, group, func(update *statusUpdate) {
+		if isSuccess {
+			update.setSuccess()
+		} else {
+			update.addFailure(statusCode)
+		}
+	})
 }
 
 func (p *KeyProvider) handleSuccess(keyID uint, keyHashKey, activeKeysListKey string) error {
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		return p.applySuccess(tx, keyID, keyHashKey, activeKeysListKey)
+	})
+}
+
+// applySuccess resets the failures of a key within tx and restores it to the active pool.
+func (p *KeyProvider) applySuccess(tx *gorm.DB, keyID uint, keyHashKey, activeKeysListKey string) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
 	}
 
+	if keyDetails["status"] == "" || keyDetails["status"] == models.KeyStatusArchived {
+		return nil
+	}
+
 	failureCount, _ := strconv.ParseInt(keyDetails["failure_count"], 10, 64)
-	isActive := keyDetails["status"] == models.KeyStatusActive
+	// Reserve and retiring keys stay out of rotation, only their failures are reset.
+	// Pending keys enter rotation once verified.
+	isActive := keyDetails["status"] == models.KeyStatusActive || keyDetails["status"] == models.KeyStatusReserve || keyDetails["status"] == models.KeyStatusRetiring
 
 	if failureCount == 0 && isActive {
 		return nil
 	}
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
-		var key models.APIKey
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, keyID).Error; err != nil {
-			return fmt.Errorf("failed to lock key %d for update: %w", keyID, err)
-		}
+	var key models.APIKey
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, keyID).Error; err != nil {
+		return fmt.Errorf("failed to lock key %d for update: %w", keyID, err)
+	}
 
-		updates := map[string]any{"failure_count": 0}
-		if !isActive {
-			updates["status"] = models.KeyStatusActive
-		}
+	updates := map[string]any{"failure_count": 0}
+	if !isActive {
+		updates["status"] = models.KeyStatusActive
+	}
 
-		if err := tx.Model(&key).Updates(updates).Error; err != nil {
-			return fmt.Errorf("failed to update key in DB: %w", err)
-		}
+	if err := tx.Model(&key).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update key in DB: %w", err)
+	}
 
-		if err := p.store.HSet(keyHashKey, updates); err != nil {
-			return fmt.Errorf("failed to update key details in store: %w", err)
-		}
+	if err := p.store.HSet(keyHashKey, updates); err != nil {
+		return fmt.Errorf("failed to update key details in store: %w", err)
+	}
 
-		if !isActive {
-			logrus.WithField("keyID", keyID).Debug("Key has recovered and is being restored to active pool.")
-			if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
-				return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
-			}
-			if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
-				return fmt.Errorf("failed to LPush key back to active list: %w", err)
-			}
+	if !isActive {
+		logrus.WithField("keyID", keyID).Debug("Key has recovered and is being restored to active pool.")
+		if err := p.store.LRem(activeKeysListKey, 0, keyID); err != nil {
+			return fmt.Errorf("failed to LRem key before LPush on recovery: %w", err)
+		}
+		if err := p.store.LPush(activeKeysListKey, keyID); err != nil {
+			return fmt.Errorf("failed to LPush key back to active list: %w", err)
 		}
+		p.publishKeyMove(KeyEventRecovered, key.GroupID, []models.APIKey{key}, keyDetails["status"], models.KeyStatusActive)
+	}
 
-		return nil
-	})
+	return nil
 }
 
-func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string) error {
+// applyFailure records failures merged into a single update within tx, failures is at least 1,
+// and blacklists the key once a threshold is reached. failuresByStatus holds the part of them
+// with a known upstream status code.
+func (p *KeyProvider) applyFailure(tx *gorm.DB, apiKey *models.APIKey, group *models.Group, keyHashKey, activeKeysListKey string, failures int64, failuresByStatus map[int]int64) error {
 	keyDetails, err := p.store.HGetAll(keyHashKey)
 	if err != nil {
 		return fmt.Errorf("failed to get key details from store: %w", err)
 	}
 
-	if keyDetails["status"] == models.KeyStatusInvalid {
+	if keyDetails["status"] == models.KeyStatusInvalid || keyDetails["status"] == "" || keyDetails["status"] == models.KeyStatusArchived {
 		return nil
 	}
 
@@ -200,40 +217,106 @@ func (p *KeyProvider) handleFailure(apiKey *models.APIKey, group *models.Group,
 	// 获取该分组的有效配置
 	blacklistThreshold := group.EffectiveConfig.BlacklistThreshold
 
-	return p.executeTransactionWithRetry(func(tx *gorm.DB) error {
-		var key models.APIKey
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, apiKey.ID).Error; err != nil {
-			return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
+	// 匹配按状态码分类的阈值的失败只计入各自类别的计数器，不计入通用阈值
+	classIncrements := make(map[string]int64)
+	for code, count := range failuresByStatus {
+		if rule, ok := group.BlacklistStatusThresholds.Match(code); ok {
+			classIncrements[rule.Class] += count
 		}
+	}
+
+	var key models.APIKey
+	if err := tx.Set("gorm:query_option", "FOR UPDATE").First(&key, apiKey.ID).Error; err != nil {
+		return fmt.Errorf("failed to lock key %d for update: %w", apiKey.ID, err)
+	}
+
+	newFailureCount := failureCount + failures
+
+	classCounts, classified, classBlacklist := classFailureCounts(group.BlacklistStatusThresholds, keyDetails, failureCount, classIncrements)
+	unclassified := max(newFailureCount-classified, 0)
 
-		newFailureCount := failureCount + 1
+	updates := map[string]any{"failure_count": newFailureCount}
+	shouldBlacklist := blacklistThreshold > 0 && unclassified >= int64(blacklistThreshold)
+	if classBlacklist != nil {
+		shouldBlacklist = true
+		blacklistThreshold = classBlacklist.Threshold
+	}
+	switch keyDetails["status"] {
+	case models.KeyStatusPending:
+		// A pending key is only used to verify it, a failed verification disables it
+		shouldBlacklist = true
+	case models.KeyStatusRetiring:
+		// Retiring keys leave the pool by being archived
+		shouldBlacklist = false
+	}
+	if shouldBlacklist {
+		updates["status"] = models.KeyStatusInvalid
+	}
 
-		updates := map[string]any{"failure_count": newFailureCount}
-		shouldBlacklist := blacklistThreshold > 0 && newFailureCount >= int64(blacklistThreshold)
-		if shouldBlacklist {
-			updates["status"] = models.KeyStatusInvalid
+	if err := tx.Model(&key).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update key stats in DB: %w", err)
+	}
+
+	if _, err := p.store.HIncrBy(keyHashKey, "failure_count", failures); err != nil {
+		return fmt.Errorf("failed to increment failure count in store: %w", err)
+	}
+	if len(classCounts) > 0 {
+		if err := p.store.HSet(keyHashKey, classCounts); err != nil {
+			return fmt.Errorf("failed to update class failure counts in store: %w", err)
 		}
+	}
 
-		if err := tx.Model(&key).Updates(updates).Error; err != nil {
-			return fmt.Errorf("failed to update key stats in DB: %w", err)
+	if shouldBlacklist {
+		fields := logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}
+		if classBlacklist != nil {
+			fields["statusClass"] = classBlacklist.Class
+		}
+		logrus.WithFields(fields).Warn("Key has reached blacklist threshold, disabling.")
+		if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
+			return fmt.Errorf("failed to LRem key from active list: %w", err)
+		}
+		if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
+			return fmt.Errorf("failed to update key status to invalid in store: %w", err)
 		}
+		p.publishKeyMove(KeyEventBlacklisted, apiKey.GroupID, []models.APIKey{key}, keyDetails["status"], models.KeyStatusInvalid)
+		p.requestReserveRefill(group)
+	}
+
+	return nil
+}
+
+// classFailureCounts 计算各状态码类别新的失败计数（存放在 key hash 的 failure_count:<class> 字段）。
+// failure_count 为 0 时旧的类别计数已随 Key 恢复失效，按 0 处理。
+// 返回需要写入的字段、所有类别计数之和，以及达到阈值的类别（如有）。
+func classFailureCounts(thresholds failover.StatusThresholds, keyDetails map[string]string, failureCount int64, increments map[string]int64) (map[string]any, int64, *failover.StatusThreshold) {
+	counts := make(map[string]any)
+	var classified int64
+	var reached *failover.StatusThreshold
 
-		if _, err := p.store.HIncrBy(keyHashKey, "failure_count", 1); err != nil {
-			return fmt.Errorf("failed to increment failure count in store: %w", err)
+	for i, rule := range thresholds {
+		field := classFailureField(rule.Class)
+		stored, _ := strconv.ParseInt(keyDetails[field], 10, 64)
+		current := stored
+		if failureCount == 0 {
+			current = 0
 		}
 
-		if shouldBlacklist {
-			logrus.WithFields(logrus.Fields{"keyID": apiKey.ID, "threshold": blacklistThreshold}).Warn("Key has reached blacklist threshold, disabling.")
-			if err := p.store.LRem(activeKeysListKey, 0, apiKey.ID); err != nil {
-				return fmt.Errorf("failed to LRem key from active list: %w", err)
-			}
-			if err := p.store.HSet(keyHashKey, map[string]any{"status": models.KeyStatusInvalid}); err != nil {
-				return fmt.Errorf("failed to update key status to invalid in store: %w", err)
-			}
+		count := current + increments[rule.Class]
+		if count != stored {
+			counts[field] = count
 		}
+		classified += count
 
-		return nil
-	})
+		if reached == nil && rule.Threshold > 0 && count >= int64(rule.Threshold) {
+			reached = &thresholds[i]
+		}
+	}
+
+	return counts, classified, reached
+}
+
+func classFailureField(class string) string {
+	return "failure_count:" + class
 }
 
 // LoadKeysFromDB 从数据库加载所有分组和密钥，并填充到 Store 中。
@@ -245,7 +328,7 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 	batchSize := 10000
 	var batchKeys []*models.APIKey
 
-	err := p.db.Model(&models.APIKey{}).FindInBatches(&batchKeys, batchSize, func(tx *gorm.DB, batch int) error {
+	err := p.db.Model(&models.APIKey{}).Where("status <> ?", models.KeyStatusArchived).FindInBatches(&batchKeys, batchSize, func(tx *gorm.DB, batch int) error {
 		logrus.Debugf("Processing batch %d with %d keys...", batch, len(batchKeys))
 
 		var pipeline store.Pipeliner
@@ -294,6 +377,9 @@ func (p *KeyProvider) LoadKeysFromDB() error {
 		}
 	}
 
+	if err := p.markLoaded(); err != nil {
+		logrus.WithError(err).Error("Failed to record that the key pools were loaded")
+	}
 	return nil
 }
 
@@ -311,8 +397,20 @@ func (p *KeyProvider) AddKeys(groupID uint, keys []models.APIKey) error {
 		// 使用批量方法添加到缓存
 		return p.addKeysToCacheBatch(groupID, keys)
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	p.publishKeyStatus(KeyEventAdded, groupID, keys, func(key *models.APIKey) (string, string) {
+		if key.Status == "" {
+			return "", models.KeyStatusActive
+		}
+		return "", key.Status
+	})
+	if slices.ContainsFunc(keys, func(key models.APIKey) bool { return key.Status == models.KeyStatusPending }) {
+		p.requestVerification(groupID)
+	}
+	return nil
 }
 
 // RemoveKeys 批量从池和数据库中移除 Key。
@@ -327,10 +425,7 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		var keyHashes []string
 		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
+			keyHashes = append(keyHashes, encryption.Hashes(p.encryptionSvc, keyValue)...)
 		}
 
 		if len(keyHashes) == 0 {
@@ -362,17 +457,20 @@ func (p *KeyProvider) RemoveKeys(groupID uint, keyValues []string) (int64, error
 
 		return nil
 	})
+	if err == nil {
+		p.publishKeyStatus(KeyEventRemoved, groupID, keysToDelete, removedKeyTransition)
+	}
 
 	return deletedCount, err
 }
 
-// RestoreKeys 恢复组内所有无效的 Key。
-func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
+// RestoreKeys 恢复组内所有无效的 Key，tags 不为空时只恢复带有全部这些标签的 Key。
+func (p *KeyProvider) RestoreKeys(groupID uint, tags []string) (int64, error) {
 	var invalidKeys []models.APIKey
 	var restoredCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Find(&invalidKeys).Error; err != nil {
+		if err := tx.Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Scopes(models.WithKeyTags(tags)).Find(&invalidKeys).Error; err != nil {
 			return err
 		}
 
@@ -384,7 +482,7 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 			"status":        models.KeyStatusActive,
 			"failure_count": 0,
 		}
-		result := tx.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Updates(updates)
+		result := tx.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusInvalid).Scopes(models.WithKeyTags(tags)).Updates(updates)
 		if result.Error != nil {
 			return result.Error
 		}
@@ -400,6 +498,9 @@ func (p *KeyProvider) RestoreKeys(groupID uint) (int64, error) {
 		}
 		return nil
 	})
+	if err == nil {
+		p.publishKeyMove(KeyEventRestored, groupID, invalidKeys, models.KeyStatusInvalid, models.KeyStatusActive)
+	}
 
 	return restoredCount, err
 }
@@ -416,10 +517,7 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 	err := p.db.Transaction(func(tx *gorm.DB) error {
 		var keyHashes []string
 		for _, keyValue := range keyValues {
-			keyHash := p.encryptionSvc.Hash(keyValue)
-			if keyHash != "" {
-				keyHashes = append(keyHashes, keyHash)
-			}
+			keyHashes = append(keyHashes, encryption.Hashes(p.encryptionSvc, keyValue)...)
 		}
 
 		if len(keyHashes) == 0 {
@@ -457,28 +555,32 @@ func (p *KeyProvider) RestoreMultipleKeys(groupID uint, keyValues []string) (int
 
 		return nil
 	})
+	if err == nil {
+		p.publishKeyMove(KeyEventRestored, groupID, keysToRestore, models.KeyStatusInvalid, models.KeyStatusActive)
+	}
 
 	return restoredCount, err
 }
 
 // RemoveInvalidKeys 移除组内所有无效的 Key。
-func (p *KeyProvider) RemoveInvalidKeys(groupID uint) (int64, error) {
-	return p.removeKeysByStatus(groupID, models.KeyStatusInvalid)
+func (p *KeyProvider) RemoveInvalidKeys(groupID uint, tags []string) (int64, error) {
+	return p.removeKeysByStatus(groupID, tags, models.KeyStatusInvalid)
 }
 
 // RemoveAllKeys 移除组内所有的 Key。
-func (p *KeyProvider) RemoveAllKeys(groupID uint) (int64, error) {
-	return p.removeKeysByStatus(groupID)
+func (p *KeyProvider) RemoveAllKeys(groupID uint, tags []string) (int64, error) {
+	return p.removeKeysByStatus(groupID, tags)
 }
 
 // removeKeysByStatus is a generic function to remove keys by status.
-// If no status is provided, it removes all keys in the group.
-func (p *KeyProvider) removeKeysByStatus(groupID uint, status ...string) (int64, error) {
+// If no status is provided, it removes all keys in the group. If tags are provided, only
+// keys carrying all of them are removed.
+func (p *KeyProvider) removeKeysByStatus(groupID uint, tags []string, status ...string) (int64, error) {
 	var keysToRemove []models.APIKey
 	var removedCount int64
 
 	err := p.db.Transaction(func(tx *gorm.DB) error {
-		query := tx.Where("group_id = ?", groupID)
+		query := tx.Where("group_id = ?", groupID).Scopes(models.WithKeyTags(tags))
 		if len(status) > 0 {
 			query = query.Where("status IN ?", status)
 		}
@@ -491,7 +593,7 @@ func (p *KeyProvider) removeKeysByStatus(groupID uint, status ...string) (int64,
 			return nil
 		}
 
-		deleteQuery := tx.Where("group_id = ?", groupID)
+		deleteQuery := tx.Where("group_id = ?", groupID).Scopes(models.WithKeyTags(tags))
 		if len(status) > 0 {
 			deleteQuery = deleteQuery.Where("status IN ?", status)
 		}
@@ -509,10 +611,17 @@ func (p *KeyProvider) removeKeysByStatus(groupID uint, status ...string) (int64,
 		}
 		return nil
 	})
+	if err == nil {
+		p.publishKeyStatus(KeyEventRemoved, groupID, keysToRemove, removedKeyTransition)
+	}
 
 	return removedCount, err
 }
 
+func removedKeyTransition(key *models.APIKey) (string, string) {
+	return key.Status, ""
+}
+
 // RemoveKeysFromStore 直接从内存存储中移除指定的键，不涉及数据库操作
 // 这个方法适用于数据库已经删除但需要清理内存存储的场景
 func (p *KeyProvider) RemoveKeysFromStore(groupID uint, keyIDs []uint) error {
@@ -599,11 +708,16 @@ func (p *KeyProvider) addKeysToCacheBatch(groupID uint, keys []models.APIKey) er
 		}
 	}
 
-	// 2. 收集所有密钥 ID
+	// 2. 收集活跃密钥 ID，备用密钥不进入轮询
 	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
-	activeKeyIDs := make([]any, len(keys))
+	activeKeyIDs := make([]any, 0, len(keys))
 	for i := range keys {
-		activeKeyIDs[i] = keys[i].ID
+		if keys[i].Status == models.KeyStatusActive {
+			activeKeyIDs = append(activeKeyIDs, keys[i].ID)
+		}
+	}
+	if len(activeKeyIDs) == 0 {
+		return nil
 	}
 
 	// 3. 批量 LPush 活跃密钥
@@ -0,0 +1,177 @@
+package keypool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/utils"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const quotaResetCheckInterval = time.Minute
+
+// QuotaResetScheduler restores the keys of groups with a known daily quota window when
+// the window resets, e.g. Gemini free-tier keys at 00:00 Pacific time. It runs on the leading master only.
+type QuotaResetScheduler struct {
+	DB              *gorm.DB
+	Store           store.Store
+	SettingsManager *config.SystemSettingsManager
+	KeyProvider     *KeyProvider
+	Elector         *cluster.Elector
+	stopChan        chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewQuotaResetScheduler creates a new QuotaResetScheduler.
+func NewQuotaResetScheduler(
+	db *gorm.DB,
+	store store.Store,
+	settingsManager *config.SystemSettingsManager,
+	keyProvider *KeyProvider,
+	elector *cluster.Elector,
+) *QuotaResetScheduler {
+	return &QuotaResetScheduler{
+		DB:              db,
+		Store:           store,
+		SettingsManager: settingsManager,
+		KeyProvider:     keyProvider,
+		Elector:         elector,
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// Start begins checking for quota resets.
+func (s *QuotaResetScheduler) Start() {
+	logrus.Debug("Starting QuotaResetScheduler...")
+	s.wg.Add(1)
+	go s.runLoop()
+}
+
+// Stop stops the scheduler, respecting the context for shutdown timeout.
+func (s *QuotaResetScheduler) Stop(ctx context.Context) {
+	close(s.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("QuotaResetScheduler stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("QuotaResetScheduler stop timed out.")
+	}
+}
+
+func (s *QuotaResetScheduler) runLoop() {
+	defer s.wg.Done()
+
+	s.checkGroups()
+
+	ticker := time.NewTicker(quotaResetCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkGroups()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// checkGroups resets every group whose quota window rolled over since its last reset.
+func (s *QuotaResetScheduler) checkGroups() {
+	if !s.Elector.IsLeader() {
+		return
+	}
+
+	var groups []models.Group
+	if err := s.DB.Where("group_type != ? OR group_type IS NULL", "aggregate").Find(&groups).Error; err != nil {
+		logrus.Errorf("QuotaResetScheduler: Failed to get groups: %v", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range groups {
+		group := &groups[i]
+		group.EffectiveConfig = s.SettingsManager.GetEffectiveConfig(group.Config)
+
+		reset, err := utils.ParseDailyReset(group.EffectiveConfig.QuotaResetTime)
+		if err != nil {
+			logrus.Warnf("QuotaResetScheduler: Invalid quota reset time for group %s: %v", group.Name, err)
+			continue
+		}
+		if reset == nil {
+			continue
+		}
+
+		boundary := reset.Last(now)
+		recordKey := fmt.Sprintf("quota_reset:%d", group.ID)
+		lastReset, err := s.lastReset(recordKey)
+		if err != nil {
+			logrus.Errorf("QuotaResetScheduler: Failed to get last quota reset of group %s: %v", group.Name, err)
+			continue
+		}
+
+		// The first check after startup only remembers the current window, keys disabled
+		// since the last boundary were disabled by the current quota.
+		if !lastReset.IsZero() && lastReset.Before(boundary) {
+			if err := s.resetGroup(group); err != nil {
+				logrus.Errorf("QuotaResetScheduler: Failed to reset keys of group %s: %v", group.Name, err)
+				continue
+			}
+		}
+		if lastReset.IsZero() || lastReset.Before(boundary) {
+			if err := s.Store.Set(recordKey, []byte(strconv.FormatInt(boundary.Unix(), 10)), 0); err != nil {
+				logrus.Errorf("QuotaResetScheduler: Failed to record quota reset of group %s: %v", group.Name, err)
+			}
+		}
+	}
+}
+
+func (s *QuotaResetScheduler) lastReset(recordKey string) (time.Time, error) {
+	value, err := s.Store.Get(recordKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// resetGroup restores the invalid keys of a group and ends the cooldown of its rate limited keys.
+func (s *QuotaResetScheduler) resetGroup(group *models.Group) error {
+	restored, err := s.KeyProvider.RestoreKeys(group.ID, nil)
+	if err != nil {
+		return err
+	}
+	released, err := s.KeyProvider.releaseCooledGroupKeys(group.ID)
+	if err != nil {
+		return err
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"group":    group.Name,
+		"restored": restored,
+		"released": released,
+	}).Info("Quota window reset, keys are back in the active pool.")
+	return nil
+}
@@ -0,0 +1,114 @@
+package keypool
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateLimitResetHeaders pairs the reset header of each provider limit with its remaining header.
+var rateLimitResetHeaders = []struct {
+	reset     string
+	remaining string
+}{
+	// OpenAI: durations such as "1s", "6m0s" or "20ms"
+	{reset: "x-ratelimit-reset-requests", remaining: "x-ratelimit-remaining-requests"},
+	{reset: "x-ratelimit-reset-tokens", remaining: "x-ratelimit-remaining-tokens"},
+	// Anthropic: RFC 3339 timestamps
+	{reset: "anthropic-ratelimit-requests-reset", remaining: "anthropic-ratelimit-requests-remaining"},
+	{reset: "anthropic-ratelimit-tokens-reset", remaining: "anthropic-ratelimit-tokens-remaining"},
+	{reset: "anthropic-ratelimit-input-tokens-reset", remaining: "anthropic-ratelimit-input-tokens-remaining"},
+	{reset: "anthropic-ratelimit-output-tokens-reset", remaining: "anthropic-ratelimit-output-tokens-remaining"},
+}
+
+// RateLimitCooldown returns how long a rate limited key should rest according to the
+// upstream response, or 0 when the response does not say. The result is capped at maxCooldown.
+//
+// Sources, in order of precedence:
+//   - Retry-After, in seconds or as an HTTP date
+//   - OpenAI x-ratelimit-reset-* and Anthropic anthropic-ratelimit-*-reset headers of the
+//     exhausted limits (the latest reset wins)
+//   - The retryDelay of a google.rpc.RetryInfo detail in a Gemini error body
+func RateLimitCooldown(header http.Header, body []byte, now time.Time, maxCooldown time.Duration) time.Duration {
+	if maxCooldown <= 0 {
+		return 0
+	}
+
+	cooldown := retryAfter(header.Get("Retry-After"), now)
+	if cooldown <= 0 {
+		cooldown = providerReset(header, now)
+	}
+	if cooldown <= 0 {
+		cooldown = geminiRetryDelay(body)
+	}
+
+	return min(cooldown, maxCooldown)
+}
+
+func retryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+func providerReset(header http.Header, now time.Time) time.Duration {
+	var cooldown time.Duration
+	for _, h := range rateLimitResetHeaders {
+		reset := strings.TrimSpace(header.Get(h.reset))
+		if reset == "" {
+			continue
+		}
+		// Only limits that are used up matter, a missing remaining header counts as used up
+		if remaining := strings.TrimSpace(header.Get(h.remaining)); remaining != "" && remaining != "0" {
+			continue
+		}
+
+		var d time.Duration
+		if at, err := time.Parse(time.RFC3339, reset); err == nil {
+			d = at.Sub(now)
+		} else if parsed, err := time.ParseDuration(reset); err == nil {
+			d = parsed
+		}
+		cooldown = max(cooldown, d)
+	}
+	return cooldown
+}
+
+// geminiRetryDelay reads the retry delay from a Gemini error body:
+// {"error": {"details": [{"@type": "type.googleapis.com/google.rpc.RetryInfo", "retryDelay": "37s"}]}}
+func geminiRetryDelay(body []byte) time.Duration {
+	if len(body) == 0 || !strings.Contains(string(body), "RetryInfo") {
+		return 0
+	}
+
+	var errorBody struct {
+		Error struct {
+			Details []struct {
+				Type       string `json:"@type"`
+				RetryDelay string `json:"retryDelay"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &errorBody); err != nil {
+		return 0
+	}
+
+	for _, detail := range errorBody.Error.Details {
+		if strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+			if d, err := time.ParseDuration(detail.RetryDelay); err == nil {
+				return d
+			}
+		}
+	}
+	return 0
+}
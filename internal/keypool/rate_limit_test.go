@@ -0,0 +1,51 @@
+package keypool
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimitCooldown(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	maxCooldown := time.Hour
+
+	cases := []struct {
+		name   string
+		header map[string]string
+		body   string
+		want   time.Duration
+	}{
+		{name: "retry-after seconds", header: map[string]string{"Retry-After": "30"}, want: 30 * time.Second},
+		{name: "retry-after date", header: map[string]string{"Retry-After": now.Add(2 * time.Minute).Format(http.TimeFormat)}, want: 2 * time.Minute},
+		{name: "openai exhausted limit", header: map[string]string{
+			"x-ratelimit-remaining-requests": "0",
+			"x-ratelimit-reset-requests":     "6m0s",
+			"x-ratelimit-remaining-tokens":   "1500",
+			"x-ratelimit-reset-tokens":       "20m",
+		}, want: 6 * time.Minute},
+		{name: "anthropic reset timestamp", header: map[string]string{
+			"anthropic-ratelimit-tokens-remaining": "0",
+			"anthropic-ratelimit-tokens-reset":     now.Add(45 * time.Second).Format(time.RFC3339),
+		}, want: 45 * time.Second},
+		{name: "gemini retry info", body: `{"error":{"code":429,"details":[{"@type":"type.googleapis.com/google.rpc.RetryInfo","retryDelay":"37s"}]}}`, want: 37 * time.Second},
+		{name: "capped", header: map[string]string{"Retry-After": "86400"}, want: maxCooldown},
+		{name: "no information", body: `{"error":{"message":"rate limited"}}`, want: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			for k, v := range tc.header {
+				header.Set(k, v)
+			}
+			if got := RateLimitCooldown(header, []byte(tc.body), now, maxCooldown); got != tc.want {
+				t.Errorf("RateLimitCooldown() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	if got := RateLimitCooldown(http.Header{"Retry-After": {"30"}}, nil, now, 0); got != 0 {
+		t.Errorf("disabled cooldown should return 0, got %v", got)
+	}
+}
@@ -0,0 +1,55 @@
+package keypool
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"gpt-load/internal/store"
+)
+
+// KeyPoolLoadedKey holds the Unix time at which the key pools were last loaded from the database.
+const KeyPoolLoadedKey = "key_pool_loaded_at"
+
+// markLoaded records that the key pools were loaded from the database.
+func (p *KeyProvider) markLoaded() error {
+	return p.store.Set(KeyPoolLoadedKey, []byte(strconv.FormatInt(time.Now().Unix(), 10)), 0)
+}
+
+// LoadedAt returns when the key pools in the store were last loaded from the database, by this
+// instance or by the master sharing its store, and false when they never were.
+func (p *KeyProvider) LoadedAt() (time.Time, bool, error) {
+	value, err := p.store.Get(KeyPoolLoadedKey)
+	if errors.Is(err, store.ErrNotFound) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	seconds, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(seconds, 0), true, nil
+}
+
+// HasActiveKeys reports whether at least one group has a key in rotation.
+func (p *KeyProvider) HasActiveKeys() (bool, error) {
+	for key, err := range p.store.Scan("group:") {
+		if err != nil {
+			return false, err
+		}
+		if !strings.HasSuffix(key, ":active_keys") {
+			continue
+		}
+		length, err := p.store.LLen(key)
+		if err != nil {
+			return false, err
+		}
+		if length > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,200 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"math"
+	"slices"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PoolDrift lists the keys of a group whose state in the store differs from the database.
+type PoolDrift struct {
+	// MissingHashes are keys in the database without details in the store.
+	MissingHashes []uint `json:"missing_hashes"`
+	// MismatchedHashes have a status, value or group in the store that differs from the database.
+	MismatchedHashes []uint `json:"mismatched_hashes"`
+	// ArchivedHashes are archived keys whose details are still in the store.
+	ArchivedHashes []uint `json:"archived_hashes"`
+	// StaleActive are in rotation but not active in the group in the database.
+	StaleActive []uint `json:"stale_active"`
+	// MissingActive are active in the database but neither in rotation nor cooling down.
+	MissingActive []uint `json:"missing_active"`
+	// DuplicateActive are in rotation more than once.
+	DuplicateActive []uint `json:"duplicate_active"`
+}
+
+// categories returns the drifted keys by category.
+func (d *PoolDrift) categories() map[string]*[]uint {
+	return map[string]*[]uint{
+		"missing_hashes":    &d.MissingHashes,
+		"mismatched_hashes": &d.MismatchedHashes,
+		"archived_hashes":   &d.ArchivedHashes,
+		"stale_active":      &d.StaleActive,
+		"missing_active":    &d.MissingActive,
+		"duplicate_active":  &d.DuplicateActive,
+	}
+}
+
+// Counts returns the number of drifted keys by category.
+func (d *PoolDrift) Counts() map[string]int {
+	counts := make(map[string]int)
+	for category, keyIDs := range d.categories() {
+		if len(*keyIDs) > 0 {
+			counts[category] = len(*keyIDs)
+		}
+	}
+	return counts
+}
+
+// Total returns the number of drifts.
+func (d *PoolDrift) Total() int {
+	total := 0
+	for _, keyIDs := range d.categories() {
+		total += len(*keyIDs)
+	}
+	return total
+}
+
+// Confirm keeps the drifts that are also found in a later check, dropping the transient ones
+// seen while a status update was between its store write and its commit.
+func (d *PoolDrift) Confirm(later *PoolDrift) *PoolDrift {
+	confirmed := &PoolDrift{}
+	laterCategories := later.categories()
+	for category, keyIDs := range confirmed.categories() {
+		for _, keyID := range *d.categories()[category] {
+			if slices.Contains(*laterCategories[category], keyID) {
+				*keyIDs = append(*keyIDs, keyID)
+			}
+		}
+	}
+	return confirmed
+}
+
+// DetectDrift compares the key pool of a group in the store with its keys in the database.
+// Failure counts are not compared, as the store counts failures ahead of the database.
+func (p *KeyProvider) DetectDrift(groupID uint) (*PoolDrift, error) {
+	var keys []models.APIKey
+	if err := p.db.Select("id", "group_id", "key_value", "status").Where("group_id = ?", groupID).Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+	}
+
+	members, err := p.store.LRange(fmt.Sprintf("group:%d:active_keys", groupID), 0, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active keys: %w", err)
+	}
+	cooling, err := p.store.ZRangeByScore(CoolingKeysSet, 0, math.Inf(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cooling keys: %w", err)
+	}
+
+	drift := &PoolDrift{}
+	dbKeys := make(map[uint]*models.APIKey, len(keys))
+	for i := range keys {
+		key := &keys[i]
+		dbKeys[key.ID] = key
+
+		details, err := p.store.HGetAll(fmt.Sprintf("key:%d", key.ID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get details of key %d: %w", key.ID, err)
+		}
+		switch {
+		case key.Status == models.KeyStatusArchived:
+			if len(details) > 0 {
+				drift.ArchivedHashes = append(drift.ArchivedHashes, key.ID)
+			}
+		case len(details) == 0:
+			drift.MissingHashes = append(drift.MissingHashes, key.ID)
+		case details["status"] != key.Status || details["key_string"] != key.KeyValue || details["group_id"] != fmt.Sprint(key.GroupID):
+			drift.MismatchedHashes = append(drift.MismatchedHashes, key.ID)
+		}
+	}
+
+	listed := make(map[uint]int, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member, 10, 64)
+		keyID := uint(id)
+		if err == nil {
+			listed[keyID]++
+		}
+		if key, ok := dbKeys[keyID]; err != nil || !ok || key.Status != models.KeyStatusActive {
+			if err == nil && listed[keyID] == 1 {
+				drift.StaleActive = append(drift.StaleActive, keyID)
+			}
+			continue
+		}
+		if listed[keyID] == 2 {
+			drift.DuplicateActive = append(drift.DuplicateActive, keyID)
+		}
+	}
+	for _, key := range keys {
+		if key.Status == models.KeyStatusActive && listed[key.ID] == 0 && !slices.Contains(cooling, fmt.Sprintf("%d:%d", groupID, key.ID)) {
+			drift.MissingActive = append(drift.MissingActive, key.ID)
+		}
+	}
+	return drift, nil
+}
+
+// RepairDrift brings the key pool of a group back in line with the database for the given
+// drifts and returns how many were repaired.
+func (p *KeyProvider) RepairDrift(groupID uint, drift *PoolDrift) (int, error) {
+	var keys []models.APIKey
+	ids := slices.Concat(drift.MissingHashes, drift.MismatchedHashes, drift.MissingActive, drift.DuplicateActive)
+	if len(ids) > 0 {
+		if err := p.db.Where("group_id = ? AND id IN ?", groupID, ids).Find(&keys).Error; err != nil {
+			return 0, fmt.Errorf("failed to load keys of group %d: %w", groupID, err)
+		}
+	}
+	dbKeys := make(map[uint]*models.APIKey, len(keys))
+	for i := range keys {
+		dbKeys[keys[i].ID] = &keys[i]
+	}
+
+	repaired := 0
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", groupID)
+	repair := func(keyID uint, err error) {
+		if err != nil {
+			logrus.WithFields(logrus.Fields{"groupID": groupID, "keyID": keyID, "error": err}).Warn("Failed to repair key pool drift")
+			return
+		}
+		repaired++
+	}
+
+	for _, keyID := range drift.MissingHashes {
+		if key, ok := dbKeys[keyID]; ok {
+			repair(keyID, p.store.HSet(fmt.Sprintf("key:%d", keyID), p.apiKeyToMap(key)))
+		}
+	}
+	for _, keyID := range drift.MismatchedHashes {
+		if key, ok := dbKeys[keyID]; ok {
+			repair(keyID, p.store.HSet(fmt.Sprintf("key:%d", keyID), map[string]any{
+				"status":     key.Status,
+				"key_string": key.KeyValue,
+				"group_id":   key.GroupID,
+			}))
+		}
+	}
+	for _, keyID := range drift.ArchivedHashes {
+		repair(keyID, p.store.Delete(fmt.Sprintf("key:%d", keyID)))
+	}
+	for _, keyID := range drift.StaleActive {
+		repair(keyID, p.store.LRem(activeKeysListKey, 0, keyID))
+	}
+	for _, keyID := range slices.Concat(drift.MissingActive, drift.DuplicateActive) {
+		if key, ok := dbKeys[keyID]; !ok || key.Status != models.KeyStatusActive {
+			continue
+		}
+		err := p.store.LRem(activeKeysListKey, 0, keyID)
+		if err == nil {
+			err = p.store.LPush(activeKeysListKey, keyID)
+		}
+		repair(keyID, err)
+	}
+
+	if repaired > 0 {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "repaired": repaired, "drift": drift.Counts()}).Info("Key pool drift repaired.")
+	}
+	return repaired, nil
+}
@@ -0,0 +1,179 @@
+package keypool
+
+import (
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// KeyReserveEventChannel carries a ReserveEvent whenever reserve keys are promoted.
+	KeyReserveEventChannel = "key_reserve_events"
+
+	reserveLockKeyPrefix = "key_reserve_lock:"
+	reserveLockTTL       = 30 * time.Second
+	reserveRefillBacklog = 64
+)
+
+// ReserveEvent reports reserve keys promoted into the active pool of a group.
+type ReserveEvent struct {
+	Type    string    `json:"type"`
+	GroupID uint      `json:"group_id"`
+	KeyIDs  []uint    `json:"key_ids"`
+	Active  int64     `json:"active"`
+	Reserve int64     `json:"reserve"`
+	At      time.Time `json:"at"`
+}
+
+// ReserveStatus describes the reserve of a group.
+type ReserveStatus struct {
+	GroupID       uint   `json:"group_id"`
+	MinActiveKeys int    `json:"min_active_keys"`
+	Active        int64  `json:"active"`
+	Reserve       int64  `json:"reserve"`
+	KeyIDs        []uint `json:"key_ids"`
+}
+
+type reserveRefill struct {
+	groupID       uint
+	minActiveKeys int
+}
+
+// ReserveStatus returns the reserve of a group, with minActiveKeys as its promotion threshold.
+func (p *KeyProvider) ReserveStatus(groupID uint, minActiveKeys int) (*ReserveStatus, error) {
+	status := &ReserveStatus{GroupID: groupID, MinActiveKeys: minActiveKeys, KeyIDs: []uint{}}
+	if err := p.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusActive).Count(&status.Active).Error; err != nil {
+		return nil, fmt.Errorf("failed to count active keys: %w", err)
+	}
+	if err := p.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusReserve).Order("id").Pluck("id", &status.KeyIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reserve keys: %w", err)
+	}
+	status.Reserve = int64(len(status.KeyIDs))
+	return status, nil
+}
+
+// DrainReserve removes all keys parked in the reserve of a group.
+func (p *KeyProvider) DrainReserve(groupID uint) (int64, error) {
+	return p.removeKeysByStatus(groupID, nil, models.KeyStatusReserve)
+}
+
+// RefillFromReserve promotes reserve keys until the group has minActiveKeys active keys and
+// returns the promoted keys. Nodes take a lock per group so that a deficit is filled once.
+func (p *KeyProvider) RefillFromReserve(groupID uint, minActiveKeys int) ([]uint, error) {
+	if minActiveKeys <= 0 {
+		return nil, nil
+	}
+
+	lockKey := fmt.Sprintf("%s%d", reserveLockKeyPrefix, groupID)
+	acquired, err := p.store.SetNX(lockKey, []byte("1"), reserveLockTTL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock the reserve: %w", err)
+	}
+	if !acquired {
+		return nil, nil
+	}
+	defer func() {
+		if err := p.store.Delete(lockKey); err != nil {
+			logrus.WithError(err).Warn("Failed to unlock the key reserve")
+		}
+	}()
+
+	var promoted []models.APIKey
+	var active int64
+	err = p.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusActive).Count(&active).Error; err != nil {
+			return err
+		}
+		deficit := minActiveKeys - int(active)
+		if deficit <= 0 {
+			return nil
+		}
+
+		if err := tx.Where("group_id = ? AND status = ?", groupID, models.KeyStatusReserve).Order("id").Limit(deficit).Find(&promoted).Error; err != nil {
+			return err
+		}
+		if len(promoted) == 0 {
+			return nil
+		}
+		if err := tx.Model(&models.APIKey{}).Where("id IN ?", pluckIDs(promoted)).Updates(map[string]any{"status": models.KeyStatusActive, "failure_count": 0}).Error; err != nil {
+			return err
+		}
+
+		for i := range promoted {
+			promoted[i].Status = models.KeyStatusActive
+			promoted[i].FailureCount = 0
+			if err := p.addKeyToStore(&promoted[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to promote reserve keys of group %d: %w", groupID, err)
+	}
+	if len(promoted) == 0 {
+		return nil, nil
+	}
+	p.publishKeyMove(KeyEventPromoted, groupID, promoted, models.KeyStatusReserve, models.KeyStatusActive)
+
+	var reserve int64
+	if err := p.db.Model(&models.APIKey{}).Where("group_id = ? AND status = ?", groupID, models.KeyStatusReserve).Count(&reserve).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to count the remaining reserve keys")
+	}
+
+	event := ReserveEvent{
+		Type:    "reserve_promoted",
+		GroupID: groupID,
+		KeyIDs:  pluckIDs(promoted),
+		Active:  active + int64(len(promoted)),
+		Reserve: reserve,
+		At:      time.Now(),
+	}
+	logrus.WithFields(logrus.Fields{
+		"groupID":  groupID,
+		"promoted": len(promoted),
+		"active":   event.Active,
+		"reserve":  reserve,
+	}).Warn("Active keys fell below the reserve threshold, promoted reserve keys.")
+	if reserve == 0 {
+		logrus.WithField("groupID", groupID).Warn("The key reserve of the group is empty.")
+	}
+
+	if payload, err := json.Marshal(event); err == nil {
+		if err := p.store.Publish(KeyReserveEventChannel, payload); err != nil {
+			logrus.WithError(err).Debug("Failed to publish reserve event")
+		}
+	}
+	return event.KeyIDs, nil
+}
+
+// requestReserveRefill schedules a reserve check of a group after one of its keys was disabled.
+func (p *KeyProvider) requestReserveRefill(group *models.Group) {
+	if group.EffectiveConfig.ReserveMinActiveKeys <= 0 {
+		return
+	}
+	select {
+	case p.reserveRefills <- reserveRefill{groupID: group.ID, minActiveKeys: group.EffectiveConfig.ReserveMinActiveKeys}:
+	default:
+		// The periodic check fills the deficit when the backlog is full
+	}
+}
+
+func (p *KeyProvider) runReserveRefiller() {
+	defer p.statusQueue.wg.Done()
+
+	for {
+		select {
+		case refill := <-p.reserveRefills:
+			if _, err := p.RefillFromReserve(refill.groupID, refill.minActiveKeys); err != nil {
+				logrus.Error(err)
+			}
+		case <-p.statusQueue.stopChan:
+			return
+		}
+	}
+}
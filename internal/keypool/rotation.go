@@ -0,0 +1,25 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+)
+
+// RefreshStoredKeyValues writes the encrypted values of keys to their cached details, after
+// the values were re-encrypted with a new encryption key. Keys not cached are skipped.
+func (p *KeyProvider) RefreshStoredKeyValues(keys []models.APIKey) error {
+	for _, key := range keys {
+		keyHashKey := fmt.Sprintf("key:%d", key.ID)
+		cached, err := p.store.Exists(keyHashKey)
+		if err != nil {
+			return fmt.Errorf("failed to check cached key %d: %w", key.ID, err)
+		}
+		if !cached {
+			continue
+		}
+		if err := p.store.HSet(keyHashKey, map[string]any{"key_string": key.KeyValue}); err != nil {
+			return fmt.Errorf("failed to refresh cached key %d: %w", key.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/failover"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	statusBufferKeyPrefix = "key_status_buffer:"
+	statusBufferDirtySet  = "key_status_buffer_dirty"
+
+	statusBufferStatusFieldPrefix = "status:"
+)
+
+// StatusBufferStats reports the key status updates buffered in the store.
+type StatusBufferStats struct {
+	FlushIntervalSeconds int   `json:"flush_interval_seconds"`
+	FlushBatch           int   `json:"flush_batch"`
+	Pending              int64 `json:"pending"`
+	Buffered             int64 `json:"buffered"`
+	Flushed              int64 `json:"flushed"`
+	Flushes              int64 `json:"flushes"`
+}
+
+// statusBuffer debounces success and failure updates: their deltas are accumulated per key in
+// the store and written to the database every interval, or sooner once batch updates are pending.
+// Deltas kept in Redis survive a crash and are replayed on the next startup.
+type statusBuffer struct {
+	interval time.Duration
+	batch    int
+	flushNow chan struct{}
+
+	pending  atomic.Int64
+	buffered atomic.Int64
+	flushed  atomic.Int64
+	flushes  atomic.Int64
+}
+
+func newStatusBuffer(perfConfig types.PerformanceConfig) *statusBuffer {
+	return &statusBuffer{
+		interval: time.Duration(perfConfig.KeyStatusFlushInterval) * time.Second,
+		batch:    perfConfig.KeyStatusFlushBatch,
+		flushNow: make(chan struct{}, 1),
+	}
+}
+
+func (b *statusBuffer) enabled() bool {
+	return b.interval > 0
+}
+
+func (b *statusBuffer) stats() StatusBufferStats {
+	return StatusBufferStats{
+		FlushIntervalSeconds: int(b.interval.Seconds()),
+		FlushBatch:           b.batch,
+		Pending:              b.pending.Load(),
+		Buffered:             b.buffered.Load(),
+		Flushed:              b.flushed.Load(),
+		Flushes:              b.flushes.Load(),
+	}
+}
+
+// bufferStatus adds a success or failure to the buffered delta of the key.
+func (p *KeyProvider) bufferStatus(apiKey *models.APIKey, group *models.Group, isSuccess bool, statusCode int) error {
+	hashKey := statusBufferKeyPrefix + strconv.FormatUint(uint64(apiKey.ID), 10)
+	fields := map[string]any{
+		"group_id":                group.ID,
+		"blacklist_threshold":     group.EffectiveConfig.BlacklistThreshold,
+		"status_thresholds":       group.EffectiveConfig.BlacklistStatusThresholds,
+		"reserve_min_active_keys": group.EffectiveConfig.ReserveMinActiveKeys,
+	}
+
+	if isSuccess {
+		// A success resets the failures buffered before it. The failures buffered after it
+		// leave its success field in place and are applied after the reset.
+		if err := p.store.Delete(hashKey); err != nil {
+			return err
+		}
+		fields["success"] = 1
+		if err := p.store.HSet(hashKey, fields); err != nil {
+			return err
+		}
+	} else {
+		if err := p.store.HSet(hashKey, fields); err != nil {
+			return err
+		}
+		if _, err := p.store.HIncrBy(hashKey, "failures", 1); err != nil {
+			return err
+		}
+		if statusCode > 0 {
+			if _, err := p.store.HIncrBy(hashKey, statusBufferStatusFieldPrefix+strconv.Itoa(statusCode), 1); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := p.store.SAdd(statusBufferDirtySet, apiKey.ID); err != nil {
+		return err
+	}
+
+	p.statusBuffer.buffered.Add(1)
+	if p.statusBuffer.pending.Add(1) >= int64(p.statusBuffer.batch) {
+		select {
+		case p.statusBuffer.flushNow <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (p *KeyProvider) runStatusFlusher() {
+	defer p.statusQueue.wg.Done()
+
+	ticker := time.NewTicker(p.statusBuffer.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.flushStatusBuffer()
+		case <-p.statusBuffer.flushNow:
+			p.flushStatusBuffer()
+		case <-p.statusQueue.stopChan:
+			p.flushStatusBuffer()
+			return
+		}
+	}
+}
+
+// flushStatusBuffer writes the buffered deltas to the database. While the database is
+// unavailable the deltas stay in the store.
+func (p *KeyProvider) flushStatusBuffer() {
+	if p.dbHealth.IsDegraded() {
+		return
+	}
+	p.statusBuffer.pending.Store(0)
+
+	for {
+		keyIDs, err := p.store.SPopN(statusBufferDirtySet, int64(p.statusBuffer.batch))
+		if err != nil {
+			logrus.Errorf("Failed to pop buffered key status updates: %v", err)
+			return
+		}
+		if len(keyIDs) == 0 {
+			return
+		}
+
+		writes := make([]*statusUpdate, 0, len(keyIDs))
+		for _, id := range keyIDs {
+			update, err := p.takeBufferedStatus(id)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": id, "error": err}).Warn("Failed to read buffered key status update")
+				continue
+			}
+			if update != nil {
+				writes = append(writes, update)
+			}
+		}
+		if len(writes) == 0 {
+			continue
+		}
+
+		p.writeStatusUpdates(writes)
+		p.statusBuffer.flushed.Add(int64(len(writes)))
+		p.statusBuffer.flushes.Add(1)
+	}
+}
+
+// takeBufferedStatus reads and removes the buffered delta of a key in one step, updates
+// buffered afterwards start a new delta.
+func (p *KeyProvider) takeBufferedStatus(id string) (*statusUpdate, error) {
+	keyID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key ID %q", id)
+	}
+	fields, err := p.store.HPopAll(statusBufferKeyPrefix + id)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	groupID, _ := strconv.ParseUint(fields["group_id"], 10, 64)
+	blacklistThreshold, _ := strconv.Atoi(fields["blacklist_threshold"])
+	reserveMinActiveKeys, _ := strconv.Atoi(fields["reserve_min_active_keys"])
+	// The spec was validated with the group, an error leaves no class rules
+	thresholds, _ := failover.ParseStatusThresholds(fields["status_thresholds"])
+
+	update := &statusUpdate{
+		apiKey: &models.APIKey{ID: uint(keyID), GroupID: uint(groupID)},
+		group: &models.Group{
+			ID:                        uint(groupID),
+			EffectiveConfig:           types.SystemSettings{BlacklistThreshold: blacklistThreshold, ReserveMinActiveKeys: reserveMinActiveKeys},
+			BlacklistStatusThresholds: thresholds,
+		},
+		reset: fields["success"] == "1",
+	}
+	update.failures, _ = strconv.ParseInt(fields["failures"], 10, 64)
+	for field, value := range fields {
+		code, ok := strings.CutPrefix(field, statusBufferStatusFieldPrefix)
+		if !ok {
+			continue
+		}
+		statusCode, _ := strconv.Atoi(code)
+		count, _ := strconv.ParseInt(value, 10, 64)
+		if statusCode > 0 && count > 0 {
+			if update.failuresByStatus == nil {
+				update.failuresByStatus = make(map[int]int64)
+			}
+			update.failuresByStatus[statusCode] = count
+		}
+	}
+
+	if !update.reset && update.failures == 0 {
+		return nil, nil
+	}
+	return update, nil
+}
+
+// ReplayPendingStatusUpdates writes the key status updates left in the store by a previous run,
+// both buffered and deferred while the database was unavailable. The master calls it on
+// startup before the store is cleared.
+func (p *KeyProvider) ReplayPendingStatusUpdates() {
+	p.flushStatusBuffer()
+	p.replayDeferredUpdates()
+}
@@ -0,0 +1,482 @@
+package keypool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/failover"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// saturationWarnInterval limits how often dropped updates are reported in the log.
+	saturationWarnInterval = time.Minute
+
+	deferredStatusKeyPrefix = "deferred_key_status:"
+	deferredStatusKeysSet   = "deferred_key_status_updates"
+	deferredReplayBatchSize = 200
+
+	// sqliteWriteBatchSize is the number of key status updates the single SQLite writer
+	// commits in one transaction.
+	sqliteWriteBatchSize = 100
+)
+
+// deferredStatusUpdate is a status update kept in the store while the database is unavailable.
+type deferredStatusUpdate struct {
+	KeyID              uint `json:"key_id"`
+	GroupID            uint `json:"group_id"`
+	BlacklistThreshold int  `json:"blacklist_threshold"`
+	// IsSuccess resets the failures of the key before Failures are added
+	IsSuccess bool  `json:"is_success"`
+	Failures  int64 `json:"failures"`
+
+	StatusThresholds string        `json:"status_thresholds,omitempty"`
+	FailuresByStatus map[int]int64 `json:"failures_by_status,omitempty"`
+}
+
+// statusUpdate is a pending key status change. Updates for the same key are merged
+// while queued: a success resets the failures and cooldown, each failure adds one and
+// a rate limit keeps the latest cooldown. Failures after a success are applied after its
+// reset, so the success still restores the key.
+type statusUpdate struct {
+	apiKey *models.APIKey
+	group  *models.Group
+	// reset is set once a success is queued, it resets the failures of the key before
+	// the failures queued after it are added
+	reset    bool
+	failures int64
+	// failuresByStatus counts the failures with a known upstream status code
+	failuresByStatus map[int]int64
+	// cooldownUntil is when a rate limited key may be used again
+	cooldownUntil time.Time
+}
+
+func (u *statusUpdate) setSuccess() {
+	u.reset = true
+	u.failures = 0
+	u.failuresByStatus = nil
+	u.cooldownUntil = time.Time{}
+}
+
+func (u *statusUpdate) addFailure(statusCode int) {
+	u.failures++
+	if statusCode > 0 {
+		if u.failuresByStatus == nil {
+			u.failuresByStatus = make(map[int]int64)
+		}
+		u.failuresByStatus[statusCode]++
+	}
+}
+
+func (u *statusUpdate) addCooldown(until time.Time) {
+	if until.After(u.cooldownUntil) {
+		u.cooldownUntil = until
+	}
+}
+
+// StatusQueueStats reports the saturation of the key status update worker pool.
+// BatchSize is above 1 when a single writer commits the updates in batches, as it does on SQLite.
+type StatusQueueStats struct {
+	Workers    int     `json:"workers"`
+	BatchSize  int     `json:"batch_size"`
+	Capacity   int     `json:"capacity"`
+	Length     int     `json:"length"`
+	Saturation float64 `json:"saturation"`
+	Enqueued   int64   `json:"enqueued"`
+	Merged     int64   `json:"merged"`
+	Dropped    int64   `json:"dropped"`
+	Processed  int64   `json:"processed"`
+	Batches    int64   `json:"batches"`
+
+	// Buffer is set when success and failure updates are buffered in the store.
+	Buffer *StatusBufferStats `json:"buffer,omitempty"`
+}
+
+// statusQueue is a fixed-size worker pool fed by a bounded channel of key IDs.
+// Each worker takes up to batchSize queued keys at a time and writes them in one transaction.
+type statusQueue struct {
+	workers   int
+	batchSize int
+	queue     chan uint
+
+	mu      sync.Mutex
+	pending map[uint]*statusUpdate
+
+	enqueued     atomic.Int64
+	merged       atomic.Int64
+	dropped      atomic.Int64
+	processed    atomic.Int64
+	batches      atomic.Int64
+	lastWarnUnix atomic.Int64
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+func newStatusQueue(workers, capacity, batchSize int) *statusQueue {
+	return &statusQueue{
+		workers:   workers,
+		batchSize: batchSize,
+		queue:     make(chan uint, capacity),
+		pending:   make(map[uint]*statusUpdate),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// submit queues an update. If the key already has a pending update the two are merged,
+// otherwise the update is dropped when the queue is full.
+func (q *statusQueue) submit(apiKey *models.APIKey, group *models.Group, merge func(*statusUpdate)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if update, ok := q.pending[apiKey.ID]; ok {
+		update.apiKey = apiKey
+		update.group = group
+		merge(update)
+		q.merged.Add(1)
+		return
+	}
+
+	update := &statusUpdate{apiKey: apiKey, group: group}
+	merge(update)
+
+	select {
+	case q.queue <- apiKey.ID:
+		q.pending[apiKey.ID] = update
+		q.enqueued.Add(1)
+	default:
+		q.dropped.Add(1)
+		q.warnSaturated()
+	}
+}
+
+// collect returns first followed by the keys already queued, up to the batch size.
+func (q *statusQueue) collect(first uint) []uint {
+	keyIDs := []uint{first}
+	for len(keyIDs) < q.batchSize {
+		select {
+		case keyID := <-q.queue:
+			keyIDs = append(keyIDs, keyID)
+		default:
+			return keyIDs
+		}
+	}
+	return keyIDs
+}
+
+// take removes and returns the pending update of a dequeued key.
+func (q *statusQueue) take(keyID uint) *statusUpdate {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	update := q.pending[keyID]
+	delete(q.pending, keyID)
+	return update
+}
+
+func (q *statusQueue) warnSaturated() {
+	now := time.Now().Unix()
+	last := q.lastWarnUnix.Load()
+	if now-last < int64(saturationWarnInterval.Seconds()) || !q.lastWarnUnix.CompareAndSwap(last, now) {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"capacity": cap(q.queue),
+		"dropped":  q.dropped.Load(),
+	}).Warn("Key status update queue is full, dropping updates. Consider increasing KEY_STATUS_QUEUE_SIZE or KEY_STATUS_WORKERS.")
+}
+
+func (q *statusQueue) stats() StatusQueueStats {
+	length := len(q.queue)
+	capacity := cap(q.queue)
+	return StatusQueueStats{
+		Workers:    q.workers,
+		BatchSize:  q.batchSize,
+		Capacity:   capacity,
+		Length:     length,
+		Saturation: float64(length) / float64(capacity),
+		Enqueued:   q.enqueued.Load(),
+		Merged:     q.merged.Load(),
+		Dropped:    q.dropped.Load(),
+		Processed:  q.processed.Load(),
+		Batches:    q.batches.Load(),
+	}
+}
+
+// Start launches the status update workers.
+func (p *KeyProvider) Start() {
+	p.dbHealth.OnRecover(p.replayDeferredUpdates)
+	p.startCooldownLoop()
+
+	if p.statusQueue.batchSize > 1 {
+		logrus.Infof("Key status updates are committed by a single writer in batches of up to %d.", p.statusQueue.batchSize)
+	} else {
+		logrus.Debugf("Starting %d key status update workers...", p.statusQueue.workers)
+	}
+	for range p.statusQueue.workers {
+		p.statusQueue.wg.Add(1)
+		go p.runStatusWorker()
+	}
+
+	p.statusQueue.wg.Add(1)
+	go p.runReserveRefiller()
+
+	if p.statusBuffer.enabled() {
+		logrus.Infof("Key status updates are buffered and flushed every %s or every %d updates.", p.statusBuffer.interval, p.statusBuffer.batch)
+		p.statusQueue.wg.Add(1)
+		go p.runStatusFlusher()
+	}
+}
+
+// Stop stops the workers after draining the queued updates, respecting the context for shutdown timeout.
+func (p *KeyProvider) Stop(ctx context.Context) {
+	close(p.statusQueue.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		p.statusQueue.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("KeyProvider status workers stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warnf("KeyProvider stop timed out, %d status updates were not applied.", len(p.statusQueue.queue))
+	}
+}
+
+// StatusQueueStats returns the saturation metrics of the status update worker pool.
+func (p *KeyProvider) StatusQueueStats() StatusQueueStats {
+	stats := p.statusQueue.stats()
+	if p.statusBuffer.enabled() {
+		bufferStats := p.statusBuffer.stats()
+		stats.Buffer = &bufferStats
+	}
+	return stats
+}
+
+func (p *KeyProvider) runStatusWorker() {
+	defer p.statusQueue.wg.Done()
+
+	for {
+		select {
+		case keyID := <-p.statusQueue.queue:
+			p.applyStatusUpdates(p.statusQueue.collect(keyID))
+		case <-p.statusQueue.stopChan:
+			// Drain what is left before exiting
+			for {
+				select {
+				case keyID := <-p.statusQueue.queue:
+					p.applyStatusUpdates(p.statusQueue.collect(keyID))
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// applyStatusUpdates applies the updates of the given keys, writing those that reach the
+// database in one transaction. Each update is written within its own savepoint, so a failed
+// one does not roll back the others.
+func (p *KeyProvider) applyStatusUpdates(keyIDs []uint) {
+	var writes []*statusUpdate
+	for _, keyID := range keyIDs {
+		update := p.statusQueue.take(keyID)
+		if update == nil {
+			continue
+		}
+		if p.prepareStatusUpdate(update) {
+			writes = append(writes, update)
+		} else {
+			p.statusQueue.processed.Add(1)
+		}
+	}
+	if len(writes) == 0 {
+		return
+	}
+	defer p.statusQueue.processed.Add(int64(len(writes)))
+	p.writeStatusUpdates(writes)
+}
+
+// writeStatusUpdates writes updates to the database in one transaction.
+func (p *KeyProvider) writeStatusUpdates(writes []*statusUpdate) {
+	p.statusQueue.batches.Add(1)
+
+	if len(writes) == 1 {
+		if err := p.db.Transaction(func(tx *gorm.DB) error {
+			return p.writeStatusUpdate(tx, writes[0])
+		}); err != nil {
+			logStatusUpdateError(writes[0], err)
+		}
+		return
+	}
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		for _, update := range writes {
+			if err := tx.Transaction(func(sp *gorm.DB) error {
+				return p.writeStatusUpdate(sp, update)
+			}); err != nil {
+				logStatusUpdateError(update, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"updates": len(writes), "error": err}).Error("Failed to commit key status update batch")
+	}
+}
+
+// prepareStatusUpdate applies the parts of an update that do not touch the database and
+// reports whether the rest has to be written to it.
+func (p *KeyProvider) prepareStatusUpdate(update *statusUpdate) bool {
+	keyID := update.apiKey.ID
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", update.group.ID)
+
+	// Cooling only touches the store, so it also applies while the database is down
+	if !update.cooldownUntil.IsZero() {
+		if err := p.coolKey(keyID, update.group.ID, activeKeysListKey, update.cooldownUntil); err != nil {
+			logrus.WithFields(logrus.Fields{"keyID": keyID, "error": err}).Error("Failed to cool down rate limited key")
+		}
+		if update.failures == 0 && !update.reset {
+			return false
+		}
+	}
+
+	if p.dbHealth.IsDegraded() {
+		p.deferStatusUpdate(update)
+		return false
+	}
+	return true
+}
+
+func (p *KeyProvider) writeStatusUpdate(tx *gorm.DB, update *statusUpdate) error {
+	keyID := update.apiKey.ID
+	keyHashKey := fmt.Sprintf("key:%d", keyID)
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", update.group.ID)
+
+	if update.reset {
+		if err := p.applySuccess(tx, keyID, keyHashKey, activeKeysListKey); err != nil {
+			return err
+		}
+	}
+	if update.failures == 0 {
+		return nil
+	}
+	return p.applyFailure(tx, update.apiKey, update.group, keyHashKey, activeKeysListKey, update.failures, update.failuresByStatus)
+}
+
+func logStatusUpdateError(update *statusUpdate, err error) {
+	fields := logrus.Fields{"keyID": update.apiKey.ID, "error": err}
+	if update.failures == 0 {
+		logrus.WithFields(fields).Error("Failed to handle key success")
+	} else {
+		logrus.WithFields(fields).Error("Failed to handle key failure")
+	}
+}
+
+// deferStatusUpdate keeps an update in the store until the database is reachable again.
+func (p *KeyProvider) deferStatusUpdate(update *statusUpdate) {
+	data, err := json.Marshal(deferredStatusUpdate{
+		KeyID:              update.apiKey.ID,
+		GroupID:            update.group.ID,
+		BlacklistThreshold: update.group.EffectiveConfig.BlacklistThreshold,
+		IsSuccess:          update.reset,
+		Failures:           update.failures,
+		StatusThresholds:   update.group.EffectiveConfig.BlacklistStatusThresholds,
+		FailuresByStatus:   update.failuresByStatus,
+	})
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": update.apiKey.ID, "error": err}).Error("Failed to encode deferred key status update")
+		return
+	}
+
+	cacheKey := deferredStatusKeyPrefix + uuid.NewString()
+	if err := p.store.Set(cacheKey, data, 0); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": update.apiKey.ID, "error": err}).Error("Failed to defer key status update")
+		return
+	}
+	if err := p.store.SAdd(deferredStatusKeysSet, cacheKey); err != nil {
+		logrus.WithFields(logrus.Fields{"keyID": update.apiKey.ID, "error": err}).Error("Failed to defer key status update")
+	}
+}
+
+// replayDeferredUpdates applies the updates deferred while the database was unavailable.
+func (p *KeyProvider) replayDeferredUpdates() {
+	replayed := 0
+	for {
+		cacheKeys, err := p.store.SPopN(deferredStatusKeysSet, deferredReplayBatchSize)
+		if err != nil {
+			logrus.Errorf("Failed to pop deferred key status updates: %v", err)
+			return
+		}
+		if len(cacheKeys) == 0 {
+			break
+		}
+
+		for _, cacheKey := range cacheKeys {
+			data, err := p.store.Get(cacheKey)
+			if err != nil {
+				if !errors.Is(err, store.ErrNotFound) {
+					logrus.Warnf("Failed to get deferred key status update %s: %v", cacheKey, err)
+				}
+				continue
+			}
+
+			var deferred deferredStatusUpdate
+			if err := json.Unmarshal(data, &deferred); err != nil {
+				logrus.Warnf("Failed to decode deferred key status update %s: %v", cacheKey, err)
+			} else if err := p.applyDeferredUpdate(&deferred); err != nil {
+				logrus.WithFields(logrus.Fields{"keyID": deferred.KeyID, "error": err}).Error("Failed to replay deferred key status update")
+			} else {
+				replayed++
+			}
+
+			if err := p.store.Delete(cacheKey); err != nil {
+				logrus.Warnf("Failed to delete deferred key status update %s: %v", cacheKey, err)
+			}
+		}
+	}
+
+	if replayed > 0 {
+		logrus.Infof("Replayed %d deferred key status updates.", replayed)
+	}
+}
+
+func (p *KeyProvider) applyDeferredUpdate(deferred *deferredStatusUpdate) error {
+	keyHashKey := fmt.Sprintf("key:%d", deferred.KeyID)
+	activeKeysListKey := fmt.Sprintf("group:%d:active_keys", deferred.GroupID)
+
+	if deferred.Failures == 0 {
+		return p.handleSuccess(deferred.KeyID, keyHashKey, activeKeysListKey)
+	}
+
+	// The spec was validated when the update was deferred, an error leaves no class rules
+	thresholds, _ := failover.ParseStatusThresholds(deferred.StatusThresholds)
+
+	apiKey := &models.APIKey{ID: deferred.KeyID, GroupID: deferred.GroupID}
+	group := &models.Group{
+		ID:                        deferred.GroupID,
+		EffectiveConfig:           types.SystemSettings{BlacklistThreshold: deferred.BlacklistThreshold},
+		BlacklistStatusThresholds: thresholds,
+	}
+	return p.db.Transaction(func(tx *gorm.DB) error {
+		if deferred.IsSuccess {
+			if err := p.applySuccess(tx, deferred.KeyID, keyHashKey, activeKeysListKey); err != nil {
+				return err
+			}
+		}
+		return p.applyFailure(tx, apiKey, group, keyHashKey, activeKeysListKey, deferred.Failures, deferred.FailuresByStatus)
+	})
+}
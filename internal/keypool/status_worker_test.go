@@ -0,0 +1,411 @@
+package keypool
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpt-load/internal/db"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestProvider returns a provider on a SQLite database holding keys 1 to 3, with key 2
+// at two failures. Key 4 is only in the store.
+func newTestProvider(t *testing.T, perfConfig types.PerformanceConfig) (*KeyProvider, []models.APIKey) {
+	t.Helper()
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "keys.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&models.APIKey{}); err != nil {
+		t.Fatal(err)
+	}
+
+	memStore := store.NewMemoryStore(0)
+	keys := []models.APIKey{
+		{ID: 1, GroupID: 1, Status: models.KeyStatusActive},
+		{ID: 2, GroupID: 1, Status: models.KeyStatusActive, FailureCount: 2},
+		{ID: 3, GroupID: 1, Status: models.KeyStatusActive},
+	}
+	if err := database.Create(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range append(keys, models.APIKey{ID: 4, GroupID: 1, Status: models.KeyStatusActive}) {
+		if err := memStore.HSet(fmt.Sprintf("key:%d", key.ID), map[string]any{"status": key.Status, "failure_count": key.FailureCount}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return &KeyProvider{
+		db:           database,
+		store:        memStore,
+		statusQueue:  newStatusQueue(1, 10, sqliteWriteBatchSize),
+		statusBuffer: newStatusBuffer(perfConfig),
+		dbHealth:     &db.HealthMonitor{},
+	}, keys
+}
+
+func loadKeys(t *testing.T, p *KeyProvider) []models.APIKey {
+	t.Helper()
+	var keys []models.APIKey
+	if err := p.db.Order("id").Find(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func TestStatusUpdateBatch(t *testing.T) {
+	p, keys := newTestProvider(t, types.PerformanceConfig{})
+	strict := &models.Group{ID: 1, EffectiveConfig: types.SystemSettings{BlacklistThreshold: 1}}
+	lenient := &models.Group{ID: 1}
+
+	p.UpdateStatus(&keys[0], strict, false, 500, "")
+	p.UpdateStatus(&keys[1], lenient, true, 200, "")
+	// Key 4 is only in the store, its write fails without rolling back the others
+	p.UpdateStatus(&models.APIKey{ID: 4, GroupID: 1}, lenient, false, 500, "")
+	p.UpdateStatus(&keys[2], lenient, false, 500, "")
+
+	p.applyStatusUpdates(p.statusQueue.collect(<-p.statusQueue.queue))
+
+	got := loadKeys(t, p)
+	if got[0].Status != models.KeyStatusInvalid || got[1].FailureCount != 0 || got[2].FailureCount != 1 {
+		t.Errorf("keys after batch = %+v", got)
+	}
+	if stats := p.StatusQueueStats(); stats.Batches != 1 || stats.Processed != 4 {
+		t.Errorf("stats = %+v, want 1 batch of 4 updates", stats)
+	}
+}
+
+func TestStatusUpdateSuccessThenFailure(t *testing.T) {
+	p, keys := newTestProvider(t, types.PerformanceConfig{})
+	group := &models.Group{ID: 1}
+	if err := p.db.Model(&keys[1]).Update("status", models.KeyStatusInvalid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.store.HSet("key:2", map[string]any{"status": models.KeyStatusInvalid}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failure merged after the success counts from the reset and leaves the key recovered
+	p.UpdateStatus(&keys[1], group, true, 200, "")
+	p.UpdateStatus(&keys[1], group, false, 500, "")
+	p.applyStatusUpdates(p.statusQueue.collect(<-p.statusQueue.queue))
+
+	if got := loadKeys(t, p)[1]; got.Status != models.KeyStatusActive || got.FailureCount != 1 {
+		t.Errorf("key after success then failure = %+v, want active at one failure", got)
+	}
+	if details, _ := p.store.HGetAll("key:2"); details["status"] != models.KeyStatusActive || details["failure_count"] != "1" {
+		t.Errorf("key details in store = %v", details)
+	}
+}
+
+func TestStatusBufferFlush(t *testing.T) {
+	p, keys := newTestProvider(t, types.PerformanceConfig{KeyStatusFlushInterval: 60, KeyStatusFlushBatch: 3})
+	group := &models.Group{ID: 1, EffectiveConfig: types.SystemSettings{BlacklistThreshold: 3}}
+
+	p.UpdateStatus(&keys[0], group, false, 500, "")
+	p.UpdateStatus(&keys[0], group, false, 502, "")
+	p.UpdateStatus(&keys[1], group, false, 500, "")
+	p.UpdateStatus(&keys[1], group, true, 200, "")
+	if len(p.statusQueue.queue) != 0 {
+		t.Fatal("buffered updates were queued")
+	}
+	select {
+	case <-p.statusBuffer.flushNow:
+	default:
+		t.Error("reaching the flush batch did not request a flush")
+	}
+	if got := loadKeys(t, p); got[0].FailureCount != 0 {
+		t.Fatalf("updates written before the flush: %+v", got[0])
+	}
+
+	p.flushStatusBuffer()
+	got := loadKeys(t, p)
+	if got[0].FailureCount != 2 || got[0].Status != models.KeyStatusActive || got[1].FailureCount != 0 {
+		t.Errorf("keys after flush = %+v", got)
+	}
+	if stats := p.statusBuffer.stats(); stats.Flushed != 2 || stats.Pending != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestStatusBufferSuccessThenFailure(t *testing.T) {
+	p, keys := newTestProvider(t, types.PerformanceConfig{KeyStatusFlushInterval: 60, KeyStatusFlushBatch: 10})
+	group := &models.Group{ID: 1}
+	if err := p.db.Model(&keys[1]).Update("status", models.KeyStatusInvalid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.store.HSet("key:2", map[string]any{"status": models.KeyStatusInvalid}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The failure buffered after the success keeps its reset
+	p.UpdateStatus(&keys[1], group, true, 200, "")
+	p.UpdateStatus(&keys[1], group, false, 500, "")
+	p.flushStatusBuffer()
+
+	if got := loadKeys(t, p)[1]; got.Status != models.KeyStatusActive || got.FailureCount != 1 {
+		t.Errorf("key after success then failure = %+v, want active at one failure", got)
+	}
+	if exists, _ := p.store.Exists(statusBufferKeyPrefix + "2"); exists {
+		t.Error("the flushed delta was left in the store")
+	}
+}
+
+func TestPoolMembership(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	// Key 4 is not in the database, key 3 is neither rotating nor cooling down
+	for _, keyID := range []uint{1, 4} {
+		if err := p.store.LPush("group:1:active_keys", keyID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.store.ZAdd(CoolingKeysSet, map[string]float64{"1:2": 1, "2:9": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.store.Set(modelCooldownKey(1, "gpt-4o"), []byte("1"), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := p.PoolMembership(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(m.ActiveKeys) != "[1 4]" || fmt.Sprint(m.CoolingKeys) != "[2]" {
+		t.Errorf("active = %v, cooling = %v", m.ActiveKeys, m.CoolingKeys)
+	}
+	if fmt.Sprint(m.MissingKeys) != "[3]" || fmt.Sprint(m.StaleKeys) != "[4]" {
+		t.Errorf("missing = %v, stale = %v", m.MissingKeys, m.StaleKeys)
+	}
+	if fmt.Sprint(m.ModelCooldowns) != "map[1:[gpt-4o]]" {
+		t.Errorf("model cooldowns = %v", m.ModelCooldowns)
+	}
+}
+
+func TestMoveKeyAndRebuildPool(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	if err := p.RebuildPool(1); err != nil {
+		t.Fatal(err)
+	}
+	if m, _ := p.PoolMembership(1); len(m.ActiveKeys) != 3 || len(m.MissingKeys) != 0 {
+		t.Fatalf("after rebuild: active = %v, missing = %v", m.ActiveKeys, m.MissingKeys)
+	}
+
+	if err := p.MoveKey(1, 2, PoolStateCooling, time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.MoveKey(2, 1, PoolStateCooling, time.Now().Add(time.Minute)); !errors.Is(err, ErrKeyNotInGroup) {
+		t.Errorf("moving a key of another group: err = %v", err)
+	}
+	m, _ := p.PoolMembership(1)
+	if len(m.ActiveKeys) != 2 || fmt.Sprint(m.CoolingKeys) != "[2]" {
+		t.Fatalf("after cooling: active = %v, cooling = %v", m.ActiveKeys, m.CoolingKeys)
+	}
+	if recovered, err := p.RecoverCooledKeys(1); err != nil || recovered != 0 {
+		t.Errorf("recovered %d keys still cooling down, err = %v", recovered, err)
+	}
+
+	if err := p.MoveKey(1, 2, PoolStateActive, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = p.PoolMembership(1)
+	if m.ActiveKeys[len(m.ActiveKeys)-1] != 2 || len(m.CoolingKeys) != 0 {
+		t.Errorf("after activating: active = %v, cooling = %v", m.ActiveKeys, m.CoolingKeys)
+	}
+}
+
+func TestDetectAndRepairDrift(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	if err := p.RebuildPool(1); err != nil {
+		t.Fatal(err)
+	}
+	if drift, err := p.DetectDrift(1); err != nil || drift.Total() != 0 {
+		t.Fatalf("drift after rebuild = %+v, %v", drift, err)
+	}
+
+	// Key 3 was blacklisted by a manual edit, key 1 lost its details and key 2 rotates twice
+	if err := p.db.Model(&models.APIKey{}).Where("id = ?", 3).Update("status", models.KeyStatusInvalid).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := p.store.Delete("key:1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.store.LPush("group:1:active_keys", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	drift, err := p.DetectDrift(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(drift.MissingHashes, drift.MismatchedHashes, drift.StaleActive, drift.DuplicateActive) != "[1] [3] [3] [2]" {
+		t.Errorf("drift = %+v", drift)
+	}
+	if repaired, err := p.RepairDrift(1, drift); err != nil || repaired != 4 {
+		t.Errorf("RepairDrift() = %d, %v", repaired, err)
+	}
+	if drift, _ := p.DetectDrift(1); drift.Total() != 0 {
+		t.Errorf("drift after repair = %+v", drift)
+	}
+	if m, _ := p.PoolMembership(1); fmt.Sprint(m.ActiveKeys) != "[1 2]" {
+		t.Errorf("active after repair = %v", m.ActiveKeys)
+	}
+}
+
+func TestRefillFromReserve(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	reserve := []models.APIKey{
+		{ID: 5, GroupID: 1, Status: models.KeyStatusReserve},
+		{ID: 6, GroupID: 1, Status: models.KeyStatusReserve},
+	}
+	if err := p.AddKeys(1, reserve); err != nil {
+		t.Fatal(err)
+	}
+	if length, _ := p.store.LLen("group:1:active_keys"); length != 0 {
+		t.Fatalf("reserve keys were pushed to the active list")
+	}
+	if err := p.db.Model(&models.APIKey{}).Where("id = ?", 1).Update("status", models.KeyStatusInvalid).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	promoted, err := p.RefillFromReserve(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(promoted) != "[5]" {
+		t.Fatalf("promoted = %v, want [5]", promoted)
+	}
+	if promoted, _ := p.RefillFromReserve(1, 3); len(promoted) != 0 {
+		t.Errorf("promoted %v without a deficit", promoted)
+	}
+
+	status, err := p.ReserveStatus(1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Active != 3 || fmt.Sprint(status.KeyIDs) != "[6]" {
+		t.Errorf("reserve status = %+v", status)
+	}
+	if members, _ := p.store.LRange("group:1:active_keys", 0, -1); fmt.Sprint(members) != "[5]" {
+		t.Errorf("active list = %v, want [5]", members)
+	}
+}
+
+func TestRestoreKeysByTag(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	tagged := []models.APIKey{
+		{ID: 5, GroupID: 1, Status: models.KeyStatusInvalid, Tags: models.KeyTags{"team:search", "tier_paid"}},
+		{ID: 6, GroupID: 1, Status: models.KeyStatusInvalid, Tags: models.KeyTags{"team:search", "tierXpaid"}},
+		{ID: 7, GroupID: 1, Status: models.KeyStatusInvalid},
+	}
+	if err := p.db.Create(&tagged).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// '_' must not match any character
+	restored, err := p.RestoreKeys(1, []string{"tier_paid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored != 1 {
+		t.Fatalf("restored %d keys, want 1", restored)
+	}
+	if removed, _ := p.RemoveInvalidKeys(1, []string{"team:search"}); removed != 1 {
+		t.Errorf("removed %d keys, want 1", removed)
+	}
+
+	keys := loadKeys(t, p)
+	if len(keys) != 5 || keys[3].Status != models.KeyStatusActive || keys[4].ID != 7 {
+		t.Fatalf("keys after tag-scoped operations = %+v", keys)
+	}
+	if fmt.Sprint(keys[3].Tags) != "[team:search tier_paid]" {
+		t.Errorf("tags = %v", keys[3].Tags)
+	}
+}
+
+func TestKeyLifecycle(t *testing.T) {
+	p, _ := newTestProvider(t, types.PerformanceConfig{})
+	if err := p.AddKeys(1, []models.APIKey{{ID: 5, GroupID: 1, Status: models.KeyStatusPending}}); err != nil {
+		t.Fatal(err)
+	}
+	if length, _ := p.store.LLen("group:1:active_keys"); length != 0 {
+		t.Fatalf("pending key was pushed to the active list")
+	}
+
+	// A verified key enters rotation
+	if err := p.handleSuccess(5, "key:5", "group:1:active_keys"); err != nil {
+		t.Fatal(err)
+	}
+	if members, _ := p.store.LRange("group:1:active_keys", 0, -1); fmt.Sprint(members) != "[5]" {
+		t.Fatalf("active list after verification = %v, want [5]", members)
+	}
+
+	result, err := p.TransitionKeys(1, []uint{5, 1, 99}, models.KeyStatusRetiring)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(result.Moved) != "[1 5]" || fmt.Sprint(result.Skipped) != "[99]" {
+		t.Fatalf("retiring result = %+v", result)
+	}
+	if length, _ := p.store.LLen("group:1:active_keys"); length != 0 {
+		t.Errorf("retiring key is still in rotation")
+	}
+	// Retiring keys are not reactivated by a late success
+	if err := p.handleSuccess(5, "key:5", "group:1:active_keys"); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, _ := p.TransitionKeys(1, []uint{5, 2}, models.KeyStatusArchived); fmt.Sprint(result.Moved) != "[5]" {
+		t.Fatalf("archived result = %+v, want only key 5 moved", result)
+	}
+	if details, _ := p.store.HGetAll("key:5"); len(details) != 0 {
+		t.Errorf("archived key is still in the store: %v", details)
+	}
+
+	keys := loadKeys(t, p)
+	if keys[0].Status != models.KeyStatusRetiring || keys[1].Status != models.KeyStatusActive || keys[3].Status != models.KeyStatusArchived {
+		t.Errorf("keys after lifecycle moves = %+v", keys)
+	}
+}
+
+func TestRestoreTrashedKeys(t *testing.T) {
+	p, keys := newTestProvider(t, types.PerformanceConfig{})
+	for _, key := range keys {
+		if err := p.db.Model(&key).Update("key_hash", fmt.Sprintf("hash-%d", key.ID)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if removed, err := p.RemoveAllKeys(1, nil); err != nil || removed != 3 {
+		t.Fatalf("RemoveAllKeys = %d, %v", removed, err)
+	}
+	// Key 3 was added again while it was in the trash
+	if err := p.db.Create(&models.APIKey{ID: 5, GroupID: 1, KeyHash: "hash-3", Status: models.KeyStatusActive}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := p.RestoreTrashedKeys(1, nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RestoredCount != 2 || result.DuplicateCount != 1 {
+		t.Fatalf("restore result = %+v, want 2 restored and 1 duplicate", result)
+	}
+
+	if live := loadKeys(t, p); len(live) != 3 || live[0].ID != 1 || live[1].ID != 2 || live[2].ID != 5 {
+		t.Errorf("live keys after restore = %+v", live)
+	}
+	if members, _ := p.store.LRange("group:1:active_keys", 0, -1); len(members) != 2 {
+		t.Errorf("active list after restore = %v, want keys 1 and 2", members)
+	}
+	if details, _ := p.store.HGetAll("key:1"); details["status"] != models.KeyStatusActive {
+		t.Errorf("restored key details = %v", details)
+	}
+}
@@ -0,0 +1,86 @@
+package keypool
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const trashRestoreBatchSize = 1000
+
+// TrashRestoreResult reports the keys taken out of the trash.
+type TrashRestoreResult struct {
+	RestoredCount int `json:"restored_count"`
+	// DuplicateCount are the keys left in the trash because the group holds their value again.
+	DuplicateCount int `json:"duplicate_count"`
+}
+
+// RestoreTrashedKeys takes keys of a group out of the trash and rebuilds their store state.
+// keyIDs limits it to these keys, nil for all, and deletedSince to the keys trashed at or
+// after it, zero for any time.
+func (p *KeyProvider) RestoreTrashedKeys(groupID uint, keyIDs []uint, deletedSince time.Time) (*TrashRestoreResult, error) {
+	result := &TrashRestoreResult{}
+	var restored []models.APIKey
+
+	err := p.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Unscoped().Where("group_id = ? AND deleted_at IS NOT NULL", groupID)
+		if keyIDs != nil {
+			query = query.Where("id IN ?", keyIDs)
+		}
+		if !deletedSince.IsZero() {
+			query = query.Where("deleted_at >= ?", deletedSince)
+		}
+		var trashed []models.APIKey
+		if err := query.Order("id").Find(&trashed).Error; err != nil {
+			return err
+		}
+		if len(trashed) == 0 {
+			return nil
+		}
+
+		var liveHashes []string
+		if err := tx.Model(&models.APIKey{}).Where("group_id = ?", groupID).Pluck("key_hash", &liveHashes).Error; err != nil {
+			return err
+		}
+		seen := make(map[string]bool, len(liveHashes))
+		for _, hash := range liveHashes {
+			seen[hash] = true
+		}
+		restored = slices.DeleteFunc(trashed, func(key models.APIKey) bool {
+			if seen[key.KeyHash] {
+				result.DuplicateCount++
+				return true
+			}
+			seen[key.KeyHash] = true
+			return false
+		})
+
+		for ids := range slices.Chunk(pluckIDs(restored), trashRestoreBatchSize) {
+			if err := tx.Unscoped().Model(&models.APIKey{}).Where("id IN ?", ids).Update("deleted_at", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		// Archived keys are not loaded into the store
+		cached := slices.DeleteFunc(slices.Clone(restored), func(key models.APIKey) bool {
+			return key.Status == models.KeyStatusArchived
+		})
+		return p.addKeysToCacheBatch(groupID, cached)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore trashed keys of group %d: %w", groupID, err)
+	}
+
+	result.RestoredCount = len(restored)
+	if result.RestoredCount > 0 {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "restored": result.RestoredCount, "duplicates": result.DuplicateCount}).Info("Keys restored from the trash.")
+	}
+	if slices.ContainsFunc(restored, func(key models.APIKey) bool { return key.Status == models.KeyStatusPending }) {
+		p.requestVerification(groupID)
+	}
+	return result, nil
+}
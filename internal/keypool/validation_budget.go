@@ -0,0 +1,72 @@
+package keypool
+
+import (
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ValidationBudget bounds the key validations running at once on the instance across all
+// groups and runs, to key_validation_budget. It is read on every acquisition, so a change of
+// the setting applies to the runs in progress.
+type ValidationBudget struct {
+	limit    func() int
+	mu       sync.Mutex
+	inFlight int
+	released chan struct{}
+}
+
+// NewValidationBudget creates the validation budget of the instance.
+func NewValidationBudget(settingsManager *config.SystemSettingsManager) *ValidationBudget {
+	return &ValidationBudget{
+		limit:    func() int { return settingsManager.GetSettings().KeyValidationBudget },
+		released: make(chan struct{}, 1),
+	}
+}
+
+// acquire waits for a free slot of the budget. It returns false if stop is closed first.
+func (b *ValidationBudget) acquire(stop <-chan struct{}) bool {
+	for {
+		b.mu.Lock()
+		limit := b.limit()
+		if limit <= 0 || b.inFlight < limit {
+			b.inFlight++
+			b.mu.Unlock()
+			return true
+		}
+		b.mu.Unlock()
+
+		select {
+		case <-b.released:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+func (b *ValidationBudget) release() {
+	b.mu.Lock()
+	b.inFlight--
+	b.mu.Unlock()
+
+	select {
+	case b.released <- struct{}{}:
+	default:
+	}
+}
+
+// InValidationWindow reports whether the background validation of the invalid keys of a
+// group may run at the given time. Groups without key_validation_windows are always in one.
+func InValidationWindow(group *models.Group, now time.Time) bool {
+	windows, err := utils.ParseTimeWindows(group.EffectiveConfig.KeyValidationWindows)
+	if err != nil {
+		logrus.WithError(err).WithField("group_name", group.Name).Warn("Invalid key_validation_windows, validation is not restricted")
+		return true
+	}
+	return windows == nil || windows.Contains(now)
+}
@@ -0,0 +1,152 @@
+package keypool
+
+import (
+	"errors"
+	"gpt-load/internal/channel"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ValidationLimiter adapts the number of concurrent validations of a run to the upstream:
+// the limit grows by one after a full window of successful validations, and is halved
+// whenever the upstream answers 429 or a server error. It never exceeds the configured
+// concurrency, and starts are spaced to stay under the maximum validations per second.
+// With a budget, each validation also takes a slot of the budget shared with other runs.
+type ValidationLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	max       int
+	inFlight  int
+	successes int
+	backoffs  int
+	released  chan struct{}
+
+	interval  time.Duration
+	nextStart time.Time
+
+	budget *ValidationBudget
+}
+
+// NewValidationLimiter creates a limiter allowing up to maxConcurrency validations at once and
+// maxRPS validation starts per second, 0 for no rate limit.
+func NewValidationLimiter(maxConcurrency, maxRPS int) *ValidationLimiter {
+	maxConcurrency = max(maxConcurrency, 1)
+	l := &ValidationLimiter{
+		limit:    (maxConcurrency + 1) / 2,
+		max:      maxConcurrency,
+		released: make(chan struct{}, 1),
+	}
+	if maxRPS > 0 {
+		l.interval = time.Second / time.Duration(maxRPS)
+	}
+	return l
+}
+
+// WithBudget makes the validations of the limiter take slots of a budget, nil for none.
+func (l *ValidationLimiter) WithBudget(budget *ValidationBudget) *ValidationLimiter {
+	l.budget = budget
+	return l
+}
+
+// Acquire waits until another validation may start. It returns false if stop is closed first.
+func (l *ValidationLimiter) Acquire(stop <-chan struct{}) bool {
+	for {
+		l.mu.Lock()
+		if l.inFlight < l.limit {
+			l.inFlight++
+			wait := l.reserveStart()
+			l.mu.Unlock()
+			if wait > 0 {
+				timer := time.NewTimer(wait)
+				select {
+				case <-timer.C:
+				case <-stop:
+					timer.Stop()
+					l.release(nil)
+					return false
+				}
+			}
+			if l.budget != nil && !l.budget.acquire(stop) {
+				l.release(nil)
+				return false
+			}
+			return true
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-l.released:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// reserveStart reserves the next start slot and returns how long to wait for it.
+func (l *ValidationLimiter) reserveStart() time.Duration {
+	if l.interval == 0 {
+		return 0
+	}
+	now := time.Now()
+	start := l.nextStart
+	if start.Before(now) {
+		start = now
+	}
+	l.nextStart = start.Add(l.interval)
+	return start.Sub(now)
+}
+
+// Release ends a validation and adapts the limit to its outcome.
+func (l *ValidationLimiter) Release(err error) {
+	if l.budget != nil {
+		l.budget.release()
+	}
+	l.release(err)
+}
+
+func (l *ValidationLimiter) release(err error) {
+	l.mu.Lock()
+	l.inFlight--
+	switch {
+	case isValidationOverload(err):
+		l.limit = max(l.limit/2, 1)
+		l.successes = 0
+		l.backoffs++
+	case err == nil || errors.As(err, new(*channel.ValidationError)):
+		// Rejected keys are answered normally and count as successes of the upstream
+		l.successes++
+		if l.successes >= l.limit && l.limit < l.max {
+			l.limit++
+			l.successes = 0
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case l.released <- struct{}{}:
+	default:
+	}
+}
+
+// Concurrency returns the current concurrency limit.
+func (l *ValidationLimiter) Concurrency() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// Backoffs returns how many times the limit was lowered.
+func (l *ValidationLimiter) Backoffs() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.backoffs
+}
+
+func isValidationOverload(err error) bool {
+	var validationErr *channel.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	return validationErr.StatusCode == http.StatusTooManyRequests || validationErr.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,82 @@
+package keypool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gpt-load/internal/channel"
+)
+
+func TestValidationLimiter(t *testing.T) {
+	l := NewValidationLimiter(4, 0)
+	if got := l.Concurrency(); got != 2 {
+		t.Fatalf("initial concurrency = %d, want 2", got)
+	}
+
+	// Rejected keys are normal answers and ramp the limit up to the maximum
+	for range 20 {
+		l.Acquire(nil)
+		l.Release(&channel.ValidationError{StatusCode: 401})
+	}
+	if got := l.Concurrency(); got != 4 {
+		t.Fatalf("concurrency after successes = %d, want 4", got)
+	}
+
+	l.Acquire(nil)
+	l.Release(&channel.ValidationError{StatusCode: 429})
+	l.Acquire(nil)
+	l.Release(&channel.ValidationError{StatusCode: 503})
+	if got := l.Concurrency(); got != 1 || l.Backoffs() != 2 {
+		t.Fatalf("concurrency after overload = %d with %d backoffs, want 1 with 2", got, l.Backoffs())
+	}
+
+	// Network errors leave the limit alone
+	l.Acquire(nil)
+	l.Release(errors.New("connection refused"))
+	if got := l.Concurrency(); got != 1 {
+		t.Fatalf("concurrency after network error = %d, want 1", got)
+	}
+
+	// A full limiter blocks until stopped
+	l.Acquire(nil)
+	stop := make(chan struct{})
+	close(stop)
+	if l.Acquire(stop) {
+		t.Fatal("Acquire succeeded over the limit")
+	}
+}
+
+func TestValidationLimiterRate(t *testing.T) {
+	l := NewValidationLimiter(10, 50)
+	start := time.Now()
+	for range 5 {
+		l.Acquire(nil)
+		l.Release(nil)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("5 starts at 50 per second took %s", elapsed)
+	}
+}
+
+func TestValidationLimiterBudget(t *testing.T) {
+	budget := &ValidationBudget{limit: func() int { return 1 }, released: make(chan struct{}, 1)}
+	first := NewValidationLimiter(2, 0).WithBudget(budget)
+	second := NewValidationLimiter(2, 0).WithBudget(budget)
+
+	if !first.Acquire(nil) {
+		t.Fatal("Acquire failed within the budget")
+	}
+	// The budget is shared: the second run waits although its own limit has room
+	stop := make(chan struct{})
+	close(stop)
+	if second.Acquire(stop) {
+		t.Fatal("Acquire succeeded over the budget")
+	}
+
+	first.Release(nil)
+	if !second.Acquire(nil) {
+		t.Fatal("Acquire failed after the budget was released")
+	}
+	second.Release(nil)
+}
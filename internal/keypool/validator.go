@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
+	"gpt-load/internal/credentials"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
 	"time"
@@ -28,6 +29,7 @@ type KeyValidator struct {
 	SettingsManager *config.SystemSettingsManager
 	keypoolProvider *KeyProvider
 	encryptionSvc   encryption.Service
+	credentials     *credentials.Manager
 }
 
 type KeyValidatorParams struct {
@@ -37,6 +39,7 @@ type KeyValidatorParams struct {
 	SettingsManager *config.SystemSettingsManager
 	KeypoolProvider *KeyProvider
 	EncryptionSvc   encryption.Service
+	Credentials     *credentials.Manager
 }
 
 // NewKeyValidator creates a new KeyValidator.
@@ -47,6 +50,7 @@ func NewKeyValidator(params KeyValidatorParams) *KeyValidator {
 		SettingsManager: params.SettingsManager,
 		keypoolProvider: params.KeypoolProvider,
 		encryptionSvc:   params.EncryptionSvc,
+		credentials:     params.Credentials,
 	}
 }
 
@@ -63,13 +67,18 @@ func (s *KeyValidator) ValidateSingleKey(key *models.APIKey, group *models.Group
 		return false, fmt.Errorf("failed to get channel for group %s: %w", group.Name, err)
 	}
 
-	isValid, validationErr := ch.ValidateKey(ctx, key, group)
+	// Keys holding long-lived credentials are validated with the access token minted from them
+	var isValid bool
+	authKey, validationErr := s.credentials.AuthKey(ctx, ch.GetHTTPClient(), group, key)
+	if validationErr == nil {
+		isValid, validationErr = ch.ValidateKey(ctx, authKey, group)
+	}
 
 	var errorMsg string
 	if !isValid && validationErr != nil {
 		errorMsg = validationErr.Error()
 	}
-	s.keypoolProvider.UpdateStatus(key, group, isValid, errorMsg)
+	s.keypoolProvider.UpdateStatus(key, group, isValid, 0, errorMsg)
 
 	if !isValid {
 		logrus.WithFields(logrus.Fields{
@@ -95,11 +104,7 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	// Generate hashes for all key values
 	var keyHashes []string
 	for _, keyValue := range keyValues {
-		keyHash := s.encryptionSvc.Hash(keyValue)
-		if keyHash == "" {
-			continue
-		}
-		keyHashes = append(keyHashes, keyHash)
+		keyHashes = append(keyHashes, encryption.Hashes(s.encryptionSvc, keyValue)...)
 	}
 
 	// Find which of the provided keys actually exist in the database for this group
@@ -117,8 +122,13 @@ func (s *KeyValidator) TestMultipleKeys(group *models.Group, keyValues []string)
 	}
 
 	for i, kv := range keyValues {
-		keyHash := s.encryptionSvc.Hash(kv)
-		apiKey, exists := existingKeyMap[keyHash]
+		var apiKey models.APIKey
+		exists := false
+		for _, keyHash := range encryption.Hashes(s.encryptionSvc, kv) {
+			if apiKey, exists = existingKeyMap[keyHash]; exists {
+				break
+			}
+		}
 		if !exists {
 			results[i] = KeyTestResult{
 				KeyValue: kv,
@@ -4,13 +4,28 @@ package middleware
 import (
 	"crypto/subtle"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
+	"gpt-load/internal/admission"
+	"gpt-load/internal/config"
+	"gpt-load/internal/cors"
+	"gpt-load/internal/db"
+	"gpt-load/internal/drain"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/grouplimit"
+	"gpt-load/internal/honeypot"
+	"gpt-load/internal/inflight"
+	"gpt-load/internal/models"
+	"gpt-load/internal/netacl"
+	"gpt-load/internal/requestid"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -54,29 +69,38 @@ func Logger(config types.LogConfig) gin.HandlerFunc {
 			retryInfo = fmt.Sprintf(" - Retry[%d]", retryCount)
 		}
 
+		// The context of proxy requests carries their request ID
+		log := logrus.WithContext(c.Request.Context())
+
 		// Filter health check and other monitoring endpoint logs to reduce noise
 		if isMonitoringEndpoint(path) {
 			// Only log errors for monitoring endpoints
 			if statusCode >= 400 {
-				logrus.Warnf("%s %s - %d - %v", method, fullPath, statusCode, latency)
+				log.Warnf("%s %s - %d - %v", method, fullPath, statusCode, latency)
 			}
 			return
 		}
 
 		// Choose log level based on status code
 		if statusCode >= 500 {
-			logrus.Errorf("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
+			log.Errorf("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
 		} else if statusCode >= 400 {
-			logrus.Warnf("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
+			log.Warnf("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
 		} else {
-			logrus.Infof("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
+			log.Infof("%s %s - %d - %v%s%s", method, fullPath, statusCode, latency, keyInfo, retryInfo)
 		}
 	}
 }
 
-// CORS creates a CORS middleware
-func CORS(config types.CORSConfig) gin.HandlerFunc {
+// CORS creates a CORS middleware. Proxy requests of a group with its own CORS config follow
+// that config instead of the global one.
+func CORS(config types.CORSConfig, gm *services.GroupManager) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if group, ok := proxyGroup(c, gm); ok && group.CORS != nil {
+			groupCORS(c, group.CORS)
+			return
+		}
+
 		if !config.Enabled {
 			c.Next()
 			return
@@ -115,8 +139,57 @@ func CORS(config types.CORSConfig) gin.HandlerFunc {
 	}
 }
 
-// Auth creates an authentication middleware
-func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
+// groupCORS applies the CORS config of a group. Preflight requests are answered here, before
+// proxy authentication, so that they need no key and do not consume one.
+func groupCORS(c *gin.Context, config *cors.Config) {
+	origin := c.Request.Header.Get("Origin")
+	allowed := config.AllowsOrigin(origin)
+	if c.Request.Method == http.MethodOptions && origin != "" && c.Request.Header.Get("Access-Control-Request-Method") != "" {
+		c.Header("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+		if !allowed {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Header("Access-Control-Allow-Origin", config.AllowOriginHeader(origin))
+		c.Header("Access-Control-Allow-Methods", config.AllowMethodsHeader())
+		if headers := config.AllowHeadersHeader(c.Request.Header.Get("Access-Control-Request-Headers")); headers != "" {
+			c.Header("Access-Control-Allow-Headers", headers)
+		}
+		if maxAge := config.MaxAgeHeader(); maxAge != "" {
+			c.Header("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+		return
+	}
+
+	if allowed {
+		c.Header("Access-Control-Allow-Origin", config.AllowOriginHeader(origin))
+		c.Header("Vary", "Origin")
+	}
+	c.Next()
+}
+
+// Authentication methods of the management API.
+const (
+	// AuthMethodSession is a login session of the admin account.
+	AuthMethodSession = "session"
+	// AuthMethodKey is AUTH_KEY, accepted while AUTH_KEY_LOGIN is enabled.
+	AuthMethodKey = "key"
+	// AuthMethodSSO is a login session of a user of the OIDC identity provider.
+	AuthMethodSSO = "sso"
+)
+
+const (
+	authMethodKey    = "authMethod"
+	authTokenKey     = "authToken"
+	adminRoleKey     = "adminRole"
+	adminIdentityKey = "adminIdentity"
+)
+
+// Auth creates an authentication middleware accepting the session tokens of the admin
+// account and single sign-on, and AUTH_KEY while AUTH_KEY_LOGIN is enabled. Sessions of the
+// viewer role may only read.
+func Auth(authConfig types.AuthConfig, sessions *services.AdminAuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
@@ -127,14 +200,122 @@ func Auth(authConfig types.AuthConfig) gin.HandlerFunc {
 
 		key := extractAuthKey(c)
 
-		isValid := key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1
+		var method string
+		role := types.AdminRoleAdmin
+		switch {
+		case key == "":
+		case authConfig.KeyLogin && subtle.ConstantTimeCompare([]byte(key), []byte(authConfig.Key)) == 1:
+			method = AuthMethodKey
+		default:
+			if session := sessions.Authenticate(key); session != nil {
+				method = AuthMethodSession
+				if session.UserID == 0 {
+					method = AuthMethodSSO
+					c.Set(adminIdentityKey, session.Identity)
+				}
+				role = session.Role
+			}
+		}
 
-		if !isValid {
+		if method == "" {
+			MarkAuthFailed(c)
 			response.Error(c, app_errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
+		if role != types.AdminRoleAdmin && !isReadOnlyRequest(c) {
+			response.ErrorI18n(c, http.StatusForbidden, "READ_ONLY_ROLE", "auth.read_only_role")
+			c.Abort()
+			return
+		}
+		if role != types.AdminRoleAdmin && exportPaths[path] {
+			response.ErrorI18n(c, http.StatusForbidden, "EXPORT_NOT_ALLOWED", "auth.export_not_allowed")
+			c.Abort()
+			return
+		}
+
+		c.Set(authMethodKey, method)
+		c.Set(authTokenKey, key)
+		c.Set(adminRoleKey, role)
+		c.Next()
+	}
+}
+
+// exportPaths are the downloads of keys and request logs, which only admins may make.
+var exportPaths = map[string]bool{
+	"/api/keys/export":         true,
+	"/api/logs/export":         true,
+	"/api/logs/records/export": true,
+}
+
+// isReadOnlyRequest reports whether a request of the management API changes nothing and
+// reveals no plaintext keys. Logging out counts as read-only.
+func isReadOnlyRequest(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		reveal, _ := strconv.ParseBool(c.Query("reveal"))
+		return !reveal
+	case http.MethodPost:
+		return c.Request.URL.Path == "/api/auth/logout"
+	}
+	return false
+}
+
+// AdminRole returns the role of the caller of the management API.
+func AdminRole(c *gin.Context) string {
+	return c.GetString(adminRoleKey)
+}
+
+// AdminIdentity returns the user of the identity provider signed in with single sign-on,
+// empty for other methods.
+func AdminIdentity(c *gin.Context) string {
+	return c.GetString(adminIdentityKey)
+}
+
+// AuthMethod returns how the request was authenticated.
+func AuthMethod(c *gin.Context) string {
+	return c.GetString(authMethodKey)
+}
+
+// SessionToken returns the session token the request was authenticated with, empty for
+// other methods.
+func SessionToken(c *gin.Context) string {
+	if method := AuthMethod(c); method != AuthMethodSession && method != AuthMethodSSO {
+		return ""
+	}
+	return c.GetString(authTokenKey)
+}
+
+// ReadOnlyWhenDegraded rejects write requests while the database is unavailable
+func ReadOnlyWhenDegraded(monitor *db.HealthMonitor) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || !monitor.IsDegraded() {
+			c.Next()
+			return
+		}
+
+		response.ErrorI18n(c, http.StatusServiceUnavailable, "DATABASE_DEGRADED", "error.database_degraded")
+		c.Abort()
+	}
+}
+
+// drainRetryAfterSeconds is the Retry-After sent with proxy requests rejected while draining,
+// by which time a load balancer should route the client to another instance.
+const drainRetryAfterSeconds = "1"
+
+// RejectWhenDraining rejects new proxy requests once the instance is draining and
+// tracks the requests that are still in flight.
+func RejectWhenDraining(controller *drain.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if controller.IsDraining() {
+			c.Header("Retry-After", drainRetryAfterSeconds)
+			response.Error(c, app_errors.ErrServerDraining)
+			c.Abort()
+			return
+		}
 
+		done := controller.Track()
+		defer done()
 		c.Next()
 	}
 }
@@ -145,6 +326,7 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 		// Check key
 		key := extractAuthKey(c)
 		if key == "" {
+			MarkAuthFailed(c)
 			response.Error(c, app_errors.ErrUnauthorized)
 			c.Abort()
 			return
@@ -166,11 +348,175 @@ func ProxyAuth(gm *services.GroupManager) gin.HandlerFunc {
 			return
 		}
 
+		MarkAuthFailed(c)
 		response.Error(c, app_errors.ErrUnauthorized)
 		c.Abort()
 	}
 }
 
+// Honeypot answers requests from suspected attackers with fabricated LLM responses instead of
+// serving them: requests to the honeypot_paths, proxy requests with one of the
+// honeypot_decoy_keys and, with honeypot_banned_ips, proxy requests from banned client IPs.
+func Honeypot(settingsManager *config.SystemSettingsManager, throttle *services.IPThrottleService, hits *services.HoneypotService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		settings := settingsManager.GetSettings()
+		trigger := honeypotTrigger(c, settings, throttle)
+		if trigger == "" {
+			c.Next()
+			return
+		}
+
+		logrus.WithFields(logrus.Fields{
+			"trigger":    trigger,
+			"ip":         c.ClientIP(),
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
+			"user_agent": c.Request.UserAgent(),
+		}).Warn("Serving a honeypot response")
+		hits.Record(models.HoneypotHit{
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Trigger:   trigger,
+		}, extractAuthKey(c))
+		honeypot.Serve(c, time.Duration(settings.HoneypotDelayMs)*time.Millisecond)
+		c.Abort()
+	}
+}
+
+func honeypotTrigger(c *gin.Context, settings types.SystemSettings, throttle *services.IPThrottleService) string {
+	path := c.Request.URL.Path
+	if honeypot.MatchesPath(settings.HoneypotPathList, path) {
+		return honeypot.TriggerPath
+	}
+	if !strings.HasPrefix(path, "/proxy/") {
+		return ""
+	}
+	if _, ok := settings.HoneypotDecoyKeysMap[extractAuthKey(c)]; ok {
+		return honeypot.TriggerDecoyKey
+	}
+	if settings.HoneypotBannedIPs {
+		if banned, _ := throttle.Banned(c.ClientIP()); banned {
+			return honeypot.TriggerBannedIP
+		}
+	}
+	return ""
+}
+
+// authFailedKey marks requests rejected for invalid credentials, as opposed to upstream 401s
+// passed on to the client.
+const authFailedKey = "authFailed"
+
+// MarkAuthFailed marks a request rejected for invalid credentials, counting towards a ban of
+// the client IP.
+func MarkAuthFailed(c *gin.Context) {
+	c.Set(authFailedKey, true)
+}
+
+// BanRepeatedAuthFailures rejects client IPs banned after repeated authentication failures
+// with 403, and counts the failed authentications of each IP towards a ban.
+func BanRepeatedAuthFailures(throttle *services.IPThrottleService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientIP := c.ClientIP()
+		if banned, remaining := throttle.Banned(clientIP); banned {
+			c.Header("Retry-After", retryAfterSeconds(remaining))
+			response.Error(c, app_errors.ErrIPBanned)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+
+		if c.GetBool(authFailedKey) {
+			throttle.RecordAuthFailure(clientIP)
+		}
+	}
+}
+
+// LimitIPRate rejects requests over the per-minute limit of the client IP in the scope with 429.
+func LimitIPRate(throttle *services.IPThrottleService, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if ok, retryAfter := throttle.Allow(scope, c.ClientIP()); !ok {
+			c.Header("Retry-After", retryAfterSeconds(retryAfter))
+			response.Error(c, app_errors.ErrIPRateLimited)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// retryAfterSeconds formats a Retry-After header, rounding up to whole seconds.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
+
+// NetworkACL rejects proxy requests from client IPs outside the ip_allowlist of the group or
+// in its ip_denylist with 403, recording the rejection for administrators.
+func NetworkACL(gm *services.GroupManager, rejections *netacl.Rejections) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		group, err := gm.GetGroupByName(c.Param("group_name"))
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to retrieve proxy group"))
+			c.Abort()
+			return
+		}
+
+		clientIP := c.ClientIP()
+		if ok, reason := group.NetworkACL.Check(clientIP); !ok {
+			rejections.Record(netacl.Rejection{
+				Group:      group.Name,
+				ClientIP:   clientIP,
+				RemoteAddr: c.RemoteIP(),
+				Method:     c.Request.Method,
+				Path:       c.Param("path"),
+				Reason:     reason,
+			})
+			response.Error(c, app_errors.ErrIPNotAllowed)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequestID identifies a proxy request with the ID supplied by the client, when valid, or a
+// new one. The ID is returned in the response and carried by the request context.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = requestid.New()
+		}
+		c.Header(requestid.Header, id)
+		c.Request = c.Request.WithContext(requestid.WithID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// TrackInFlight registers authenticated proxy requests in the in-flight registry, serving them
+// with a context that an administrator can cancel.
+func TrackInFlight(registry *inflight.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, done := registry.Start(c.Request.Context(), inflight.Request{
+			RequestID: requestid.FromContext(c.Request.Context()),
+			Group:     c.Param("group_name"),
+			Method:    c.Request.Method,
+			Path:      c.Param("path"),
+			ClientIP:  c.ClientIP(),
+			ProxyKey:  utils.MaskAPIKey(extractAuthKey(c)),
+			UserAgent: c.Request.UserAgent(),
+		})
+		defer done()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
 // ProxyRouteDispatcher dispatches special routes before proxy authentication
 func ProxyRouteDispatcher(serverHandler interface{ GetIntegrationInfo(*gin.Context) }) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -193,21 +539,83 @@ func Recovery() gin.HandlerFunc {
 	})
 }
 
-// RateLimiter creates a simple rate limiting middleware
-func RateLimiter(config types.PerformanceConfig) gin.HandlerFunc {
-	// Simple semaphore-based rate limiting
-	semaphore := make(chan struct{}, config.MaxConcurrentRequests)
+// concurrencyRetryAfterSeconds is the Retry-After sent with requests rejected over a
+// concurrency limit, which frees up as soon as requests in flight complete.
+const concurrencyRetryAfterSeconds = "1"
 
+// GroupLimiter caps the requests of each proxy group in flight, queueing those over the cap
+// of the group for a slot before they take one of the global limit.
+func GroupLimiter(gm *services.GroupManager) gin.HandlerFunc {
+	limits := grouplimit.New()
 	return func(c *gin.Context) {
-		select {
-		case semaphore <- struct{}{}:
-			defer func() { <-semaphore }()
+		group, ok := proxyGroup(c, gm)
+		if !ok {
 			c.Next()
-		default:
-			response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Too many concurrent requests"))
+			return
+		}
+		cfg := group.EffectiveConfig
+		release, ok := limits.Acquire(c.Request.Context(), group.ID, cfg.GroupMaxConcurrentRequests, cfg.GroupMaxQueuedRequests, time.Duration(cfg.GroupQueueTimeout)*time.Second)
+		if !ok {
+			c.Header("Retry-After", concurrencyRetryAfterSeconds)
+			response.Error(c, app_errors.ErrGroupConcurrencyLimit)
 			c.Abort()
+			return
 		}
+		defer release()
+		c.Next()
+	}
+}
+
+// RateLimiter creates a concurrency limiting middleware. Requests over the limit wait in a
+// weighted fair queue by the priority of their group, or are rejected when queueing is off.
+func RateLimiter(config types.PerformanceConfig, gm *services.GroupManager) gin.HandlerFunc {
+	queue := admission.NewQueue(config.MaxConcurrentRequests, time.Duration(config.QueueTimeout)*time.Second)
+
+	return func(c *gin.Context) {
+		if !queue.Acquire(c.Request.Context(), requestPriority(c, gm)) {
+			c.Header("Retry-After", concurrencyRetryAfterSeconds)
+			response.Error(c, app_errors.ErrTooManyRequests)
+			c.Abort()
+			return
+		}
+		defer queue.Release()
+		c.Next()
+	}
+}
+
+// requestPriority returns the priority of the group a proxy request targets, normal otherwise
+func requestPriority(c *gin.Context, gm *services.GroupManager) admission.Priority {
+	group, ok := proxyGroup(c, gm)
+	if !ok {
+		return admission.PriorityNormal
+	}
+	return group.Priority
+}
+
+// NativeErrors makes proxy requests answer the errors of gpt-load in the error schema of the
+// channel of their group, so that the SDK of the provider can parse them. It runs before the
+// middleware that can reject proxy requests.
+func NativeErrors(gm *services.GroupManager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if group, ok := proxyGroup(c, gm); ok {
+			response.SetErrorFormat(c, response.ErrorFormatForChannel(group.ChannelType))
+		}
+		c.Next()
+	}
+}
+
+// proxyGroup returns the group a proxy request targets, before the proxy routes are matched.
+func proxyGroup(c *gin.Context, gm *services.GroupManager) (*models.Group, bool) {
+	path, ok := strings.CutPrefix(c.Request.URL.Path, "/proxy/")
+	if !ok {
+		return nil, false
+	}
+	groupName, _, _ := strings.Cut(path, "/")
+	group, err := gm.GetGroupByName(groupName)
+	if err != nil {
+		return nil, false
 	}
+	return group, true
 }
 
 // ErrorHandler creates an error handling middleware
@@ -234,7 +642,7 @@ func ErrorHandler() gin.HandlerFunc {
 
 // isMonitoringEndpoint checks if the path is a monitoring endpoint
 func isMonitoringEndpoint(path string) bool {
-	monitoringPaths := []string{"/health"}
+	monitoringPaths := []string{"/health", "/livez", "/readyz"}
 	for _, monitoringPath := range monitoringPaths {
 		if path == monitoringPath {
 			return true
@@ -0,0 +1,56 @@
+// Package mirror describes the shadow traffic a group copies to another group, to evaluate
+// a new upstream with real requests before switching to it.
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// Config is the mirroring of a group, set as "mirror" in the group config.
+type Config struct {
+	// Group is the name of the group receiving the copies.
+	Group string `json:"group"`
+	// Percentage is the share of requests copied, above 0 and at most 100.
+	Percentage float64 `json:"percentage"`
+	// StoreResponses keeps the responses of the original and mirrored requests as debug
+	// captures for comparison, otherwise the mirrored responses are discarded.
+	StoreResponses bool `json:"store_responses,omitempty"`
+}
+
+// Parse converts the mirror value of a group config into a Config.
+func Parse(value any) (*Config, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("mirror is invalid: %w", err)
+	}
+	config.Group = strings.TrimSpace(config.Group)
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("mirror is invalid: %w", err)
+	}
+	return &config, nil
+}
+
+func (c *Config) validate() error {
+	if c.Group == "" {
+		return fmt.Errorf("group is required")
+	}
+	if c.Percentage <= 0 || c.Percentage > 100 {
+		return fmt.Errorf("percentage must be above 0 and at most 100")
+	}
+	return nil
+}
+
+// Sampled reports whether a request is copied.
+func (c *Config) Sampled() bool {
+	return c.Percentage >= 100 || rand.Float64()*100 < c.Percentage
+}
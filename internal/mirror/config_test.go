@@ -0,0 +1,33 @@
+package mirror
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	config, err := Parse(map[string]any{"group": " candidate ", "percentage": 12.5})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if config.Group != "candidate" || config.Percentage != 12.5 || config.StoreResponses {
+		t.Errorf("Parse() = %+v", config)
+	}
+
+	for _, invalid := range []map[string]any{
+		{"percentage": 10},
+		{"group": "candidate"},
+		{"group": "candidate", "percentage": 101},
+		{"group": "candidate", "percentage": "10"},
+	} {
+		if _, err := Parse(invalid); err == nil {
+			t.Errorf("Parse(%v) succeeded, want an error", invalid)
+		}
+	}
+}
+
+func TestSampled(t *testing.T) {
+	all := &Config{Group: "candidate", Percentage: 100}
+	for range 100 {
+		if !all.Sampled() {
+			t.Fatal("Sampled() = false with a percentage of 100")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package modelpolicy restricts the models a group accepts.
+package modelpolicy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pattern matches a model name, or every name starting with a prefix ending in "*".
+// Matching ignores case.
+type Pattern struct {
+	Name   string
+	Prefix bool
+}
+
+func (p Pattern) matches(model string) bool {
+	model = strings.ToLower(model)
+	if p.Prefix {
+		return strings.HasPrefix(model, p.Name)
+	}
+	return model == p.Name
+}
+
+// List is a list of model patterns.
+type List []Pattern
+
+// Parse parses comma- or newline-separated model names, e.g. "gpt-4o, gpt-4o-mini, o3*".
+func Parse(spec string) (List, error) {
+	var list List
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		name := strings.ToLower(strings.TrimSpace(entry))
+		if name == "" {
+			continue
+		}
+		var pattern Pattern
+		if strings.HasSuffix(name, "*") {
+			pattern.Prefix = true
+			name = strings.TrimSuffix(name, "*")
+		}
+		if strings.Contains(name, "*") {
+			return nil, fmt.Errorf("invalid model %q, * is only allowed at the end", strings.TrimSpace(entry))
+		}
+		pattern.Name = name
+		list = append(list, pattern)
+	}
+	return list, nil
+}
+
+// Contains reports whether a pattern of the list matches the model.
+func (l List) Contains(model string) bool {
+	for _, pattern := range l {
+		if pattern.matches(model) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy decides which models a group accepts. The zero value accepts every model.
+type Policy struct {
+	Allowed List
+	Denied  List
+}
+
+// Allows reports whether the model may be requested: it must not be denied and, when the
+// policy has an allowlist, must be in it. Requests without a model are always allowed.
+func (p Policy) Allows(model string) bool {
+	if model == "" {
+		return true
+	}
+	if p.Denied.Contains(model) {
+		return false
+	}
+	return len(p.Allowed) == 0 || p.Allowed.Contains(model)
+}
@@ -0,0 +1,39 @@
+package modelpolicy
+
+import "testing"
+
+func TestPolicyAllows(t *testing.T) {
+	allowed, err := Parse("gpt-4o, gpt-4o-mini\no3*")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	denied, err := Parse("o3-pro*")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	policy := Policy{Allowed: allowed, Denied: denied}
+
+	tests := []struct {
+		model string
+		want  bool
+	}{
+		{"gpt-4o", true},
+		{"GPT-4o-Mini", true},
+		{"o3-mini", true},
+		{"o3-pro-2025-06-10", false},
+		{"gpt-4", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		if got := policy.Allows(tt.model); got != tt.want {
+			t.Errorf("Allows(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+
+	if !(Policy{}).Allows("anything") {
+		t.Error("empty policy should allow every model")
+	}
+	if _, err := Parse("gpt-*-mini"); err == nil {
+		t.Error("Parse() should reject a * that is not at the end")
+	}
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxKeyTags is the number of tags a key can carry.
+	MaxKeyTags = 16
+	// MaxKeyTagLength is the length of a single tag.
+	MaxKeyTagLength = 50
+)
+
+var keyTagPattern = regexp.MustCompile(`^[A-Za-z0-9:._/-]+$`)
+
+// KeyTags are the free-form tags of a key, e.g. team:search or tier:paid. They are stored
+// as one comma-delimited column (",team:search,tier:paid,") so that a tag can be matched
+// with LIKE on every database.
+type KeyTags []string
+
+// Value implements driver.Valuer.
+func (t KeyTags) Value() (driver.Value, error) {
+	if len(t) == 0 {
+		return "", nil
+	}
+	return "," + strings.Join(t, ",") + ",", nil
+}
+
+// Scan implements sql.Scanner.
+func (t *KeyTags) Scan(value any) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported key tags type %T", value)
+	}
+
+	*t = nil
+	for tag := range strings.SplitSeq(strings.Trim(raw, ","), ",") {
+		if tag != "" {
+			*t = append(*t, tag)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes missing tags as an empty list.
+func (t KeyTags) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(t))
+}
+
+// NormalizeKeyTags trims and deduplicates tags and checks their format.
+func NormalizeKeyTags(tags []string) (KeyTags, error) {
+	var normalized KeyTags
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || slices.Contains(normalized, tag) {
+			continue
+		}
+		if len(tag) > MaxKeyTagLength || !keyTagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to %d letters, digits or :._/-", tag, MaxKeyTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > MaxKeyTags {
+		return nil, fmt.Errorf("a key can carry up to %d tags", MaxKeyTags)
+	}
+	return normalized, nil
+}
+
+// WithKeyTags limits a key query to the keys carrying all of the given tags.
+func WithKeyTags(tags []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, tag := range tags {
+			// '_' is a LIKE wildcard, '!' is not allowed in tags
+			db = db.Where("tags LIKE ? ESCAPE '!'", "%,"+strings.ReplaceAll(tag, "_", "!_")+",%")
+		}
+		return db
+	}
+}
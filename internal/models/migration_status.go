@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// MigrationStatusKey is the store key under which the migrate-keys command publishes its progress.
+const MigrationStatusKey = "migration:status"
+
+// Phases of a migrate-keys run.
+const (
+	MigrationPhasePreCheck = "pre_check"
+	MigrationPhaseDryRun   = "dry_run"
+	MigrationPhaseMigrate  = "migrate"
+	MigrationPhaseSwitch   = "switch"
+	MigrationPhaseDone     = "done"
+	MigrationPhaseFailed   = "failed"
+)
+
+// MigrationStatus is the progress of the last migrate-keys run.
+type MigrationStatus struct {
+	Scenario  string    `json:"scenario"`
+	DryRun    bool      `json:"dry_run"`
+	Phase     string    `json:"phase"`
+	Processed int64     `json:"processed"`
+	Total     int64     `json:"total"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// KeysPerSecond and RemainingSeconds are measured over the current phase.
+	KeysPerSecond    float64 `json:"keys_per_second"`
+	RemainingSeconds int64   `json:"remaining_seconds"`
+	Error            string  `json:"error,omitempty"`
+}
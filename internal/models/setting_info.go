@@ -2,15 +2,17 @@ package models
 
 // SystemSettingInfo 表示系统配置的详细信息（用于API返回）
 type SystemSettingInfo struct {
-	Key          string   `json:"key"`
-	Name         string   `json:"name"`
-	Value        any      `json:"value"`
-	Type         string   `json:"type"` // "int", "bool", "string"
-	DefaultValue any      `json:"default_value"`
-	Description  string   `json:"description"`
-	Category     string   `json:"category"`
-	MinValue     *int     `json:"min_value,omitempty"`
-	Required     bool     `json:"required"`
+	Key             string `json:"key"`
+	Name            string `json:"name"`
+	Value           any    `json:"value"`
+	Type            string `json:"type"` // "int", "bool", "string"
+	DefaultValue    any    `json:"default_value"`
+	Description     string `json:"description"`
+	Category        string `json:"category"`
+	MinValue        *int   `json:"min_value,omitempty"`
+	MaxValue        *int   `json:"max_value,omitempty"`
+	Required        bool   `json:"required"`
+	RequiresRestart bool   `json:"requires_restart"`
 }
 
 // CategorizedSettings a list of settings grouped by category
@@ -18,3 +20,13 @@ type CategorizedSettings struct {
 	CategoryName string              `json:"category_name"`
 	Settings     []SystemSettingInfo `json:"settings"`
 }
+
+// SettingChange describes how a pending settings update would change a single setting.
+type SettingChange struct {
+	Key             string `json:"key"`
+	Name            string `json:"name"`
+	Category        string `json:"category"`
+	OldValue        any    `json:"old_value"`
+	NewValue        any    `json:"new_value"`
+	RequiresRestart bool   `json:"requires_restart"`
+}
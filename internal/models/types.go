@@ -1,19 +1,49 @@
 package models
 
 import (
+	"gpt-load/internal/admission"
+	"gpt-load/internal/anomaly"
+	"gpt-load/internal/balancing"
+	"gpt-load/internal/canary"
+	"gpt-load/internal/cors"
+	"gpt-load/internal/endpoints"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/features"
+	"gpt-load/internal/guardrails"
+	"gpt-load/internal/mirror"
+	"gpt-load/internal/modelpolicy"
+	"gpt-load/internal/moderation"
+	"gpt-load/internal/netacl"
 	"gpt-load/internal/types"
 	"time"
 
 	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 // Key状态
 const (
 	KeyStatusActive  = "active"
 	KeyStatusInvalid = "invalid"
+	// KeyStatusReserve keys are parked in the group's reserve and promoted when active keys run low
+	KeyStatusReserve = "reserve"
+	// KeyStatusPending keys are imported but not verified yet, they enter rotation once verified
+	KeyStatusPending = "pending"
+	// KeyStatusRetiring keys serve no new requests but keep their stats until they are archived
+	KeyStatusRetiring = "retiring"
+	// KeyStatusArchived keys are kept in the database for their stats but are no longer loaded
+	KeyStatusArchived = "archived"
 )
 
+// IsKeyStatus reports whether status is a known key status.
+func IsKeyStatus(status string) bool {
+	switch status {
+	case KeyStatusActive, KeyStatusInvalid, KeyStatusReserve, KeyStatusPending, KeyStatusRetiring, KeyStatusArchived:
+		return true
+	}
+	return false
+}
+
 // SystemSetting 对应 system_settings 表
 type SystemSetting struct {
 	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
@@ -24,22 +54,86 @@ type SystemSetting struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// Key pool modes of a group, see keypool.KeyPool
+const (
+	PoolModeSimple  = "simple"
+	PoolModeLayered = "layered"
+)
+
 // GroupConfig 存储特定于分组的配置
 type GroupConfig struct {
-	RequestTimeout               *int    `json:"request_timeout,omitempty"`
-	IdleConnTimeout              *int    `json:"idle_conn_timeout,omitempty"`
-	ConnectTimeout               *int    `json:"connect_timeout,omitempty"`
-	MaxIdleConns                 *int    `json:"max_idle_conns,omitempty"`
-	MaxIdleConnsPerHost          *int    `json:"max_idle_conns_per_host,omitempty"`
-	ResponseHeaderTimeout        *int    `json:"response_header_timeout,omitempty"`
-	ProxyURL                     *string `json:"proxy_url,omitempty"`
-	MaxRetries                   *int    `json:"max_retries,omitempty"`
-	BlacklistThreshold           *int    `json:"blacklist_threshold,omitempty"`
-	FailoverStatusCodes          *string `json:"failover_status_codes,omitempty"`
-	KeyValidationIntervalMinutes *int    `json:"key_validation_interval_minutes,omitempty"`
-	KeyValidationConcurrency     *int    `json:"key_validation_concurrency,omitempty"`
-	KeyValidationTimeoutSeconds  *int    `json:"key_validation_timeout_seconds,omitempty"`
-	EnableRequestBodyLogging     *bool   `json:"enable_request_body_logging,omitempty"`
+	RequestTimeout                *int    `json:"request_timeout,omitempty"`
+	IdleConnTimeout               *int    `json:"idle_conn_timeout,omitempty"`
+	ConnectTimeout                *int    `json:"connect_timeout,omitempty"`
+	MaxIdleConns                  *int    `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost           *int    `json:"max_idle_conns_per_host,omitempty"`
+	ResponseHeaderTimeout         *int    `json:"response_header_timeout,omitempty"`
+	ProxyURL                      *string `json:"proxy_url,omitempty"`
+	HTTPVersion                   *string `json:"http_version,omitempty"`
+	TCPKeepAlive                  *int    `json:"tcp_keepalive_seconds,omitempty"`
+	DNSCacheTTL                   *int    `json:"dns_cache_ttl_seconds,omitempty"`
+	AllowedEndpoints              *string `json:"allowed_endpoints,omitempty"`
+	AllowedModels                 *string `json:"allowed_models,omitempty"`
+	DeniedModels                  *string `json:"denied_models,omitempty"`
+	IPAllowlist                   *string `json:"ip_allowlist,omitempty"`
+	IPDenylist                    *string `json:"ip_denylist,omitempty"`
+	CircuitBreakerThreshold       *int    `json:"circuit_breaker_threshold,omitempty"`
+	CircuitBreakerCooldownSeconds *int    `json:"circuit_breaker_cooldown_seconds,omitempty"`
+	StreamFirstByteTimeout        *int    `json:"stream_first_byte_timeout_seconds,omitempty"`
+	StreamIdleTimeout             *int    `json:"stream_idle_timeout_seconds,omitempty"`
+	StreamMaxDuration             *int    `json:"stream_max_duration_seconds,omitempty"`
+	StreamRetryOnStall            *bool   `json:"stream_retry_on_stall,omitempty"`
+	CompressResponses             *bool   `json:"compress_responses,omitempty"`
+	SimulateStreaming             *bool   `json:"simulate_streaming,omitempty"`
+	UpstreamHealthCheckPath       *string `json:"upstream_health_check_path,omitempty"`
+	UpstreamHealthCheckInterval   *int    `json:"upstream_health_check_interval_seconds,omitempty"`
+	UpstreamHealthCheckStatus     *int    `json:"upstream_health_check_expected_status,omitempty"`
+	ResponseCacheTTL              *int    `json:"response_cache_ttl_seconds,omitempty"`
+	MaxRequestBodyKB              *int    `json:"max_request_body_kb,omitempty"`
+	GroupMaxConcurrentRequests    *int    `json:"group_max_concurrent_requests,omitempty"`
+	GroupMaxQueuedRequests        *int    `json:"group_max_queued_requests,omitempty"`
+	GroupQueueTimeout             *int    `json:"group_queue_timeout_seconds,omitempty"`
+	RequestPriority               *string `json:"request_priority,omitempty"`
+	RequestIDHeader               *string `json:"request_id_header,omitempty"`
+	RateLimitHeaders              *string `json:"rate_limit_headers,omitempty"`
+	RateLimitHeadersAggregate     *bool   `json:"rate_limit_headers_aggregate,omitempty"`
+	MaxRetries                    *int    `json:"max_retries,omitempty"`
+	RetryBackoffBaseMs            *int    `json:"retry_backoff_base_ms,omitempty"`
+	RetryBackoffMaxMs             *int    `json:"retry_backoff_max_ms,omitempty"`
+	BlacklistThreshold            *int    `json:"blacklist_threshold,omitempty"`
+	BlacklistStatusThresholds     *string `json:"blacklist_status_thresholds,omitempty"`
+	RateLimitMaxCooldown          *int    `json:"rate_limit_max_cooldown_seconds,omitempty"`
+	RateLimitPerModel             *bool   `json:"rate_limit_per_model,omitempty"`
+	QuotaResetTime                *string `json:"quota_reset_time,omitempty"`
+	FailoverStatusCodes           *string `json:"failover_status_codes,omitempty"`
+	KeyValidationIntervalMinutes  *int    `json:"key_validation_interval_minutes,omitempty"`
+	KeyValidationConcurrency      *int    `json:"key_validation_concurrency,omitempty"`
+	KeyValidationTimeoutSeconds   *int    `json:"key_validation_timeout_seconds,omitempty"`
+	KeyValidationMaxRPS           *int    `json:"key_validation_max_rps,omitempty"`
+	KeyValidationWindows          *string `json:"key_validation_windows,omitempty"`
+	ReserveMinActiveKeys          *int    `json:"reserve_min_active_keys,omitempty"`
+	PoolMode                      *string `json:"pool_mode,omitempty"`
+	CredentialRefresher           *string `json:"credential_refresher,omitempty"`
+	EnableRequestBodyLogging      *bool   `json:"enable_request_body_logging,omitempty"`
+	DebugCaptureRate              *int    `json:"debug_capture_rate,omitempty"`
+	DebugCaptureUntil             *string `json:"debug_capture_until,omitempty"`
+	MonthlyRequestBudget          *int    `json:"monthly_request_budget,omitempty"`
+
+	// FeatureFlags switches gated features for the group, unset flags use their defaults
+	FeatureFlags map[string]bool `json:"feature_flags,omitempty"`
+	// RequestGuardrails clamps or rejects request parameters, see guardrails.Policy
+	RequestGuardrails *guardrails.Policy `json:"request_guardrails,omitempty"`
+	// Moderation checks request content with a moderation endpoint, see moderation.Config
+	Moderation *moderation.Config `json:"moderation,omitempty"`
+	// Canary sends synthetic probe requests through the group, see canary.Config
+	Canary *canary.Config `json:"canary,omitempty"`
+	// CapacityBalancing weights the sub-groups of an aggregate group by their live capacity,
+	// see balancing.Config
+	CapacityBalancing *balancing.Config `json:"capacity_balancing,omitempty"`
+	// Mirror copies a share of the requests to another group, see mirror.Config
+	Mirror *mirror.Config `json:"mirror,omitempty"`
+	// CORS lets browser-based clients call the proxy endpoint of the group, see cors.Config
+	CORS *cors.Config `json:"cors,omitempty"`
 }
 
 // HeaderRule defines a single rule for header manipulation.
@@ -47,6 +141,8 @@ type HeaderRule struct {
 	Key    string `json:"key"`
 	Value  string `json:"value"`
 	Action string `json:"action"` // "set" or "remove"
+	// Secret values are encrypted at rest and masked in API responses
+	Secret bool `json:"secret,omitempty"`
 }
 
 // GroupSubGroup 聚合分组和子分组的关联表
@@ -69,6 +165,8 @@ type SubGroupInfo struct {
 	TotalKeys   int64 `json:"total_keys"`
 	ActiveKeys  int64 `json:"active_keys"`
 	InvalidKeys int64 `json:"invalid_keys"`
+	// EffectiveWeight 为启用容量均衡时当前实例实际使用的权重
+	EffectiveWeight int `json:"effective_weight,omitempty"`
 }
 
 // ParentAggregateGroupInfo 用于API响应的父聚合分组信息
@@ -84,6 +182,7 @@ type Group struct {
 	ID                  uint                 `gorm:"primaryKey;autoIncrement" json:"id"`
 	EffectiveConfig     types.SystemSettings `gorm:"-" json:"effective_config,omitempty"`
 	Name                string               `gorm:"type:varchar(255);not null;unique" json:"name"`
+	ExternalID          *string              `gorm:"type:varchar(255);uniqueIndex" json:"external_id,omitempty"` // stable ID set by declarative tools such as Terraform
 	Endpoint            string               `gorm:"-" json:"endpoint"`
 	DisplayName         string               `gorm:"type:varchar(255)" json:"display_name"`
 	ProxyKeys           string               `gorm:"type:text" json:"proxy_keys"`
@@ -93,23 +192,44 @@ type Group struct {
 	ValidationEndpoint  string               `gorm:"type:varchar(255)" json:"validation_endpoint"`
 	ChannelType         string               `gorm:"type:varchar(50);not null" json:"channel_type"`
 	Sort                int                  `gorm:"default:0" json:"sort"`
+	Enabled             bool                 `gorm:"not null;default:true" json:"enabled"`
+	MaintenanceMessage  string               `gorm:"type:varchar(512)" json:"maintenance_message"`
 	TestModel           string               `gorm:"type:varchar(255);not null" json:"test_model"`
 	ParamOverrides      datatypes.JSONMap    `gorm:"type:json" json:"param_overrides"`
 	Config              datatypes.JSONMap    `gorm:"type:json" json:"config"`
 	HeaderRules         datatypes.JSON       `gorm:"type:json" json:"header_rules"`
 	ModelRedirectRules  datatypes.JSONMap    `gorm:"type:json" json:"model_redirect_rules"`
 	ModelRedirectStrict bool                 `gorm:"default:false" json:"model_redirect_strict"`
+	ChannelDefinition   datatypes.JSON       `gorm:"type:json" json:"channel_definition"`
 	APIKeys             []APIKey             `gorm:"foreignKey:GroupID" json:"api_keys"`
 	SubGroups           []GroupSubGroup      `gorm:"-" json:"sub_groups,omitempty"`
 	LastValidatedAt     *time.Time           `json:"last_validated_at"`
 	CreatedAt           time.Time            `json:"created_at"`
 	UpdatedAt           time.Time            `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt       `gorm:"index" json:"-"`
 
 	// For cache
 	ProxyKeysMap              map[string]struct{}        `gorm:"-" json:"-"`
 	HeaderRuleList            []HeaderRule               `gorm:"-" json:"-"`
 	ModelRedirectMap          map[string]string          `gorm:"-" json:"-"`
 	FailoverStatusCodeMatcher failover.StatusCodeMatcher `gorm:"-" json:"-"`
+	BlacklistStatusThresholds failover.StatusThresholds  `gorm:"-" json:"-"`
+	Priority                  admission.Priority         `gorm:"-" json:"-"`
+	EndpointAllowlist         endpoints.Allowlist        `gorm:"-" json:"-"`
+	ModelPolicy               modelpolicy.Policy         `gorm:"-" json:"-"`
+	NetworkACL                netacl.ACL                 `gorm:"-" json:"-"`
+	FeatureFlags              features.Flags             `gorm:"-" json:"-"`
+	Guardrails                *guardrails.Policy         `gorm:"-" json:"-"`
+	Moderation                *moderation.Config         `gorm:"-" json:"-"`
+	Canary                    *canary.Config             `gorm:"-" json:"-"`
+	CapacityBalancing         *balancing.Config          `gorm:"-" json:"-"`
+	Mirror                    *mirror.Config             `gorm:"-" json:"-"`
+	CORS                      *cors.Config               `gorm:"-" json:"-"`
+}
+
+// FeatureEnabled reports whether a feature flag is on for the group.
+func (g *Group) FeatureEnabled(name string) bool {
+	return g.FeatureFlags.Enabled(name)
 }
 
 // APIKey 对应 api_keys 表
@@ -117,14 +237,18 @@ type APIKey struct {
 	ID           uint       `gorm:"primaryKey;autoIncrement;index:idx_api_keys_group_last_used_id,priority:3" json:"id"`
 	KeyValue     string     `gorm:"type:text;not null" json:"key_value"`
 	KeyHash      string     `gorm:"type:varchar(128);index" json:"key_hash"`
+	ExternalID   *string    `gorm:"type:varchar(255);uniqueIndex" json:"external_id,omitempty"`
 	GroupID      uint       `gorm:"not null;index;index:idx_api_keys_group_last_used_id,priority:1" json:"group_id"`
 	Status       string     `gorm:"type:varchar(50);not null;default:'active';index" json:"status"`
 	Notes        string     `gorm:"type:varchar(255);default:''" json:"notes"`
+	Tags         KeyTags    `gorm:"type:varchar(1024);not null;default:''" json:"tags"`
 	RequestCount int64      `gorm:"not null;default:0" json:"request_count"`
 	FailureCount int64      `gorm:"not null;default:0" json:"failure_count"`
 	LastUsedAt   *time.Time `gorm:"index:idx_api_keys_group_last_used_id,priority:2" json:"last_used_at"`
 	CreatedAt    time.Time  `json:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at"`
+	// DeletedAt is set while the key is in the trash
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
 // RequestType 请求类型常量
@@ -133,28 +257,45 @@ const (
 	RequestTypeFinal = "final"
 )
 
+// 过期请求日志的处理方式
+const (
+	LogRetentionDelete  = "delete"
+	LogRetentionArchive = "archive"
+)
+
 // RequestLog 对应 request_logs 表
 type RequestLog struct {
-	ID              string    `gorm:"type:varchar(36);primaryKey" json:"id"`
-	Timestamp       time.Time `gorm:"not null;index" json:"timestamp"`
-	GroupID         uint      `gorm:"not null;index" json:"group_id"`
-	GroupName       string    `gorm:"type:varchar(255);index" json:"group_name"`
-	ParentGroupID   uint      `gorm:"index" json:"parent_group_id"`
-	ParentGroupName string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
-	KeyValue        string    `gorm:"type:text" json:"key_value"`
-	KeyHash         string    `gorm:"type:varchar(128);index" json:"key_hash"`
-	Model           string    `gorm:"type:varchar(255);index" json:"model"`
-	IsSuccess       bool      `gorm:"not null" json:"is_success"`
-	SourceIP        string    `gorm:"type:varchar(64)" json:"source_ip"`
-	StatusCode      int       `gorm:"not null" json:"status_code"`
-	RequestPath     string    `gorm:"type:varchar(500)" json:"request_path"`
-	Duration        int64     `gorm:"not null" json:"duration_ms"`
-	ErrorMessage    string    `gorm:"type:text" json:"error_message"`
-	UserAgent       string    `gorm:"type:varchar(512)" json:"user_agent"`
-	RequestType     string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
-	UpstreamAddr    string    `gorm:"type:varchar(500)" json:"upstream_addr"`
-	IsStream        bool      `gorm:"not null" json:"is_stream"`
-	RequestBody     string    `gorm:"type:text" json:"request_body"`
+	ID               string    `gorm:"type:varchar(36);primaryKey" json:"id"`
+	Timestamp        time.Time `gorm:"not null;index" json:"timestamp"`
+	GroupID          uint      `gorm:"not null;index" json:"group_id"`
+	GroupName        string    `gorm:"type:varchar(255);index" json:"group_name"`
+	ParentGroupID    uint      `gorm:"index" json:"parent_group_id"`
+	ParentGroupName  string    `gorm:"type:varchar(255);index" json:"parent_group_name"`
+	KeyValue         string    `gorm:"type:text" json:"key_value"`
+	KeyHash          string    `gorm:"type:varchar(128);index" json:"key_hash"`
+	Model            string    `gorm:"type:varchar(255);index" json:"model"`
+	IsSuccess        bool      `gorm:"not null" json:"is_success"`
+	SourceIP         string    `gorm:"type:varchar(64)" json:"source_ip"`
+	StatusCode       int       `gorm:"not null" json:"status_code"`
+	RequestPath      string    `gorm:"type:varchar(500)" json:"request_path"`
+	Duration         int64     `gorm:"not null" json:"duration_ms"`
+	ErrorMessage     string    `gorm:"type:text" json:"error_message"`
+	UserAgent        string    `gorm:"type:varchar(512)" json:"user_agent"`
+	RequestType      string    `gorm:"type:varchar(20);not null;default:'final';index" json:"request_type"`
+	UpstreamAddr     string    `gorm:"type:varchar(500)" json:"upstream_addr"`
+	IsStream         bool      `gorm:"not null" json:"is_stream"`
+	RequestBody      string    `gorm:"type:text" json:"request_body"`
+	IsOverflow       bool      `gorm:"not null;default:false;index" json:"is_overflow"`
+	ProviderIncident string    `gorm:"type:varchar(255)" json:"provider_incident"`
+	// RequestedModel is the model the client asked for when a redirect rule rewrote it to Model.
+	RequestedModel string `gorm:"type:varchar(255)" json:"requested_model"`
+	// ModerationVerdict is the verdict of the moderation hook of the group, empty without one.
+	ModerationVerdict string `gorm:"type:varchar(255)" json:"moderation_verdict"`
+	IsFlagged         bool   `gorm:"not null;default:false;index" json:"is_flagged"`
+	// RequestID identifies the client request the entry belongs to, shared by its attempts.
+	RequestID string `gorm:"type:varchar(64);index" json:"request_id"`
+	// MirroredFrom is the group whose request was copied, empty for client requests.
+	MirroredFrom string `gorm:"type:varchar(255);not null;default:'';index" json:"mirrored_from"`
 }
 
 // StatCard 用于仪表盘的单个统计卡片数据
@@ -180,7 +321,20 @@ type DashboardStatsResponse struct {
 	RPM              StatCard          `json:"rpm"`
 	RequestCount     StatCard          `json:"request_count"`
 	ErrorRate        StatCard          `json:"error_rate"`
+	AvgLatency       StatCard          `json:"avg_latency"`
 	SecurityWarnings []SecurityWarning `json:"security_warnings"`
+	// Anomalies are the traffic anomalies found in the last ended hour.
+	Anomalies []anomaly.Anomaly `json:"anomalies"`
+	// MaintenanceGroups are the disabled groups.
+	MaintenanceGroups []MaintenanceGroup `json:"maintenance_groups"`
+}
+
+// MaintenanceGroup 维护中（已停用）的分组
+type MaintenanceGroup struct {
+	ID                 uint   `json:"id"`
+	Name               string `json:"name"`
+	DisplayName        string `json:"display_name"`
+	MaintenanceMessage string `json:"maintenance_message"`
 }
 
 // ChartDataset 用于图表的数据集
@@ -194,15 +348,115 @@ type ChartDataset struct {
 type ChartData struct {
 	Labels   []string       `json:"labels"`
 	Datasets []ChartDataset `json:"datasets"`
+	// BucketHours is the number of hours each point covers.
+	BucketHours int `json:"bucket_hours"`
+	// AvgLatencyMs is the average request duration of each point, 0 without requests.
+	AvgLatencyMs []int64 `json:"avg_latency_ms"`
 }
 
 // GroupHourlyStat 对应 group_hourly_stats 表，用于存储每个分组每小时的请求统计
 type GroupHourlyStat struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	Time         time.Time `gorm:"not null;uniqueIndex:idx_group_time" json:"time"` // 整点时间
-	GroupID      uint      `gorm:"not null;uniqueIndex:idx_group_time" json:"group_id"`
-	SuccessCount int64     `gorm:"not null;default:0" json:"success_count"`
-	FailureCount int64     `gorm:"not null;default:0" json:"failure_count"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Time             time.Time `gorm:"not null;uniqueIndex:idx_group_time" json:"time"` // 整点时间
+	GroupID          uint      `gorm:"not null;uniqueIndex:idx_group_time" json:"group_id"`
+	SuccessCount     int64     `gorm:"not null;default:0" json:"success_count"`
+	FailureCount     int64     `gorm:"not null;default:0" json:"failure_count"`
+	OverflowCount    int64     `gorm:"not null;default:0" json:"overflow_count"`
+	DurationMs       int64     `gorm:"not null;default:0" json:"duration_ms"`        // 请求总耗时，用于计算平均延迟
+	RateLimitedCount int64     `gorm:"not null;default:0" json:"rate_limited_count"` // 429 响应数，包括重试
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// 用量快照的来源
+const (
+	UsageSnapshotSourceSchedule = "schedule"
+	UsageSnapshotSourceManual   = "manual"
+)
+
+// CanaryProbe 对应 canary_probes 表，记录分组的合成探测请求结果
+type CanaryProbe struct {
+	ID         uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	GroupID    uint      `gorm:"not null;index:idx_canary_group_time" json:"group_id"`
+	Time       time.Time `gorm:"not null;index:idx_canary_group_time;index" json:"time"`
+	Success    bool      `gorm:"not null" json:"success"`
+	StatusCode int       `gorm:"not null" json:"status_code"`
+	DurationMs int64     `gorm:"not null" json:"duration_ms"`
+	Error      string    `gorm:"type:varchar(500)" json:"error,omitempty"`
+}
+
+// UsageSnapshot 对应 usage_snapshots 表，记录某一时刻的累计用量，写入后不再修改
+type UsageSnapshot struct {
+	ID           uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	TakenAt      time.Time  `gorm:"not null;index" json:"taken_at"`
+	ScheduledFor *time.Time `gorm:"index" json:"scheduled_for,omitempty"` // 计划快照对应的计划时间
+	Source       string     `gorm:"type:varchar(20);not null" json:"source"`
+	GroupCount   int        `gorm:"not null;default:0" json:"group_count"`
+	KeyCount     int        `gorm:"not null;default:0" json:"key_count"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// UsageSnapshotEntry 对应 usage_snapshot_entries 表，KeyID 为 0 时表示分组的累计计数
+type UsageSnapshotEntry struct {
+	ID            uint   `gorm:"primaryKey;autoIncrement" json:"-"`
+	SnapshotID    uint   `gorm:"not null;index:idx_snapshot_group_key,priority:1" json:"snapshot_id"`
+	GroupID       uint   `gorm:"not null;index:idx_snapshot_group_key,priority:2" json:"group_id"`
+	KeyID         uint   `gorm:"not null;default:0;index:idx_snapshot_group_key,priority:3" json:"key_id,omitempty"`
+	GroupName     string `gorm:"type:varchar(255)" json:"group_name"`
+	KeyHash       string `gorm:"type:varchar(128)" json:"key_hash,omitempty"`
+	RequestCount  int64  `gorm:"not null;default:0" json:"request_count"`
+	SuccessCount  int64  `gorm:"not null;default:0" json:"success_count"`
+	FailureCount  int64  `gorm:"not null;default:0" json:"failure_count"`
+	OverflowCount int64  `gorm:"not null;default:0" json:"overflow_count"`
+}
+
+// KeyRevealLog 对应 key_reveal_logs 表，审计每个返回明文密钥的管理接口响应
+type KeyRevealLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+	Endpoint  string    `gorm:"type:varchar(255);not null" json:"endpoint"`
+	GroupID   uint      `gorm:"not null;default:0;index" json:"group_id,omitempty"` // 0 表示跨分组的响应，如日志
+	KeyCount  int       `gorm:"not null" json:"key_count"`
+	SourceIP  string    `gorm:"type:varchar(64)" json:"source_ip"`
+	UserAgent string    `gorm:"type:varchar(512)" json:"user_agent"`
+}
+
+// HoneypotHit 对应 honeypot_hits 表，记录每个被蜜罐应答的请求
+type HoneypotHit struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+	ClientIP  string    `gorm:"type:varchar(64);not null;index" json:"client_ip"`
+	UserAgent string    `gorm:"type:varchar(512)" json:"user_agent"`
+	Method    string    `gorm:"type:varchar(16)" json:"method"`
+	Path      string    `gorm:"type:varchar(512)" json:"path"`
+	Trigger   string    `gorm:"column:trigger_type;type:varchar(32);not null" json:"trigger"`
+	Token     string    `gorm:"type:varchar(64)" json:"token,omitempty"` // 探测使用的令牌，仅保存脱敏值
+}
+
+// AdminUser 对应 admin_users 表，管理界面的登录账号
+type AdminUser struct {
+	ID             uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Username       string     `gorm:"type:varchar(255);not null;uniqueIndex" json:"username"`
+	PasswordHash   string     `gorm:"type:varchar(255);not null" json:"-"`
+	TOTPSecret     string     `gorm:"type:text" json:"-"` // 加密存储，启用前为待确认的密钥
+	TOTPEnabled    bool       `gorm:"not null;default:false" json:"totp_enabled"`
+	TOTPLastStep   int64      `gorm:"not null;default:0" json:"-"` // 最后一次接受的验证码时间步，防止重放
+	FailedAttempts int        `gorm:"not null;default:0" json:"-"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt    *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// AdminSession 对应 admin_sessions 表，只保存会话令牌的哈希
+// 单点登录的会话 UserID 为 0，Identity 为身份提供方返回的用户
+type AdminSession struct {
+	TokenHash string    `gorm:"type:varchar(64);primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;index" json:"user_id"`
+	Identity  string    `gorm:"type:varchar(255)" json:"identity,omitempty"`
+	Role      string    `gorm:"type:varchar(16);not null;default:'admin'" json:"role"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	SourceIP  string    `gorm:"type:varchar(64)" json:"source_ip"`
+	UserAgent string    `gorm:"type:varchar(512)" json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,257 @@
+// Package moderation checks the content of requests with a moderation endpoint before they
+// are forwarded upstream.
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Actions on flagged content.
+const (
+	ActionBlock = "block"
+	ActionFlag  = "flag"
+)
+
+// Behaviors when the moderation endpoint fails or times out.
+const (
+	FailOpen   = "open"
+	FailClosed = "closed"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	maxBodyBytes   = 1 << 20
+)
+
+// Config is the moderation hook of a group, set as "moderation" in the group config.
+type Config struct {
+	// URL is an OpenAI-compatible moderations endpoint, e.g. https://api.openai.com/v1/moderations.
+	URL    string `json:"url"`
+	APIKey string `json:"api_key,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// Thresholds flags content when the score of a category reaches its threshold. Without
+	// thresholds the verdict of the endpoint is used.
+	Thresholds map[string]float64 `json:"thresholds,omitempty"`
+	// Action is ActionBlock (the default) or ActionFlag to only record the verdict.
+	Action    string `json:"action,omitempty"`
+	TimeoutMs int    `json:"timeout_ms,omitempty"`
+	// FailMode is FailOpen (the default) to forward requests when the endpoint fails, or
+	// FailClosed to reject them.
+	FailMode string `json:"fail_mode,omitempty"`
+}
+
+// Blocks reports whether flagged requests are rejected.
+func (c *Config) Blocks() bool {
+	return c.Action != ActionFlag
+}
+
+// FailsClosed reports whether requests are rejected when the endpoint fails.
+func (c *Config) FailsClosed() bool {
+	return c.FailMode == FailClosed
+}
+
+func (c *Config) timeout() time.Duration {
+	if c.TimeoutMs > 0 {
+		return time.Duration(c.TimeoutMs) * time.Millisecond
+	}
+	return defaultTimeout
+}
+
+// Parse converts the moderation value of a group config into a Config.
+func Parse(value any) (*Config, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("moderation is invalid: %w", err)
+	}
+	if !strings.HasPrefix(config.URL, "http://") && !strings.HasPrefix(config.URL, "https://") {
+		return nil, fmt.Errorf("moderation url must be an http or https URL")
+	}
+	switch config.Action {
+	case "", ActionBlock, ActionFlag:
+	default:
+		return nil, fmt.Errorf("moderation action must be %q or %q", ActionBlock, ActionFlag)
+	}
+	switch config.FailMode {
+	case "", FailOpen, FailClosed:
+	default:
+		return nil, fmt.Errorf("moderation fail_mode must be %q or %q", FailOpen, FailClosed)
+	}
+	if config.TimeoutMs < 0 {
+		return nil, fmt.Errorf("moderation timeout_ms cannot be negative")
+	}
+	for category, threshold := range config.Thresholds {
+		if threshold < 0 || threshold > 1 {
+			return nil, fmt.Errorf("moderation threshold of %q must be between 0 and 1", category)
+		}
+	}
+	return &config, nil
+}
+
+// Verdict is the result of the moderation of a request.
+type Verdict struct {
+	Flagged bool `json:"flagged"`
+	// Categories are the flagged categories with their scores.
+	Categories map[string]float64 `json:"categories,omitempty"`
+}
+
+// String describes the verdict for request logs, e.g. "flagged: violence=0.92".
+func (v *Verdict) String() string {
+	if !v.Flagged {
+		return "passed"
+	}
+	names := make([]string, 0, len(v.Categories))
+	for name := range v.Categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", name, v.Categories[name]))
+	}
+	return "flagged: " + strings.Join(parts, ", ")
+}
+
+// Moderator calls moderation endpoints.
+type Moderator struct {
+	client *http.Client
+}
+
+// NewModerator creates a new Moderator.
+func NewModerator() *Moderator {
+	return &Moderator{client: &http.Client{}}
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// Check moderates the text of a request body. Bodies without text pass without a call.
+func (m *Moderator) Check(ctx context.Context, config *Config, bodyBytes []byte) (*Verdict, error) {
+	text := ExtractText(bodyBytes)
+	if text == "" {
+		return &Verdict{}, nil
+	}
+
+	payload := map[string]any{"input": text}
+	if config.Model != "" {
+		payload["model"] = config.Model
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.timeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+config.APIKey)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("moderation endpoint returned status %d", resp.StatusCode)
+	}
+
+	var result moderationResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return nil, fmt.Errorf("moderation response has no results")
+	}
+
+	verdict := &Verdict{Categories: make(map[string]float64)}
+	for _, r := range result.Results {
+		if len(config.Thresholds) > 0 {
+			for category, threshold := range config.Thresholds {
+				if score, ok := r.CategoryScores[category]; ok && score >= threshold {
+					verdict.Categories[category] = max(verdict.Categories[category], score)
+				}
+			}
+			continue
+		}
+		if !r.Flagged {
+			continue
+		}
+		for category, flagged := range r.Categories {
+			if flagged {
+				verdict.Categories[category] = max(verdict.Categories[category], r.CategoryScores[category])
+			}
+		}
+		// Classifiers may flag content without naming a category
+		verdict.Flagged = true
+	}
+	verdict.Flagged = verdict.Flagged || len(verdict.Categories) > 0
+	return verdict, nil
+}
+
+// textKeys are the fields holding the text of a request in the OpenAI, Anthropic and Gemini
+// formats: messages[].content, system, contents[].parts[].text, input, prompt...
+var textKeys = map[string]bool{
+	"content": true, "text": true, "system": true, "input": true, "prompt": true, "instructions": true,
+}
+
+// ExtractText returns the text of a JSON request body, one piece per line.
+func ExtractText(bodyBytes []byte) string {
+	var body any
+	if err := json.Unmarshal(bodyBytes, &body); err != nil {
+		return ""
+	}
+	var parts []string
+	collectText(body, false, &parts)
+	return strings.Join(parts, "\n")
+}
+
+func collectText(value any, inText bool, parts *[]string) {
+	switch v := value.(type) {
+	case string:
+		if inText && v != "" {
+			*parts = append(*parts, v)
+		}
+	case []any:
+		for _, item := range v {
+			collectText(item, inText, parts)
+		}
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			collectText(v[key], textKeys[key], parts)
+		}
+	}
+}
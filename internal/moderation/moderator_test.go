@@ -0,0 +1,47 @@
+package moderation
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModeratorCheck(t *testing.T) {
+	var input string
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Input string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&payload)
+		input = payload.Input
+		w.Write([]byte(`{"results":[{"flagged":false,"categories":{"violence":false},"category_scores":{"violence":0.42,"hate":0.01}}]}`))
+	}))
+	defer endpoint.Close()
+
+	body := []byte(`{"model":"gpt-4o","messages":[{"role":"system","content":"be nice"},{"role":"user","content":[{"type":"text","text":"hello"}]}]}`)
+	m := NewModerator()
+
+	verdict, err := m.Check(t.Context(), &Config{URL: endpoint.URL}, body)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if verdict.Flagged || input != "be nice\nhello" {
+		t.Errorf("Check() = %+v with input %q, want passed with the message text", verdict, input)
+	}
+
+	verdict, err = m.Check(t.Context(), &Config{URL: endpoint.URL, Thresholds: map[string]float64{"violence": 0.4}}, body)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if !verdict.Flagged || verdict.String() != "flagged: violence=0.42" {
+		t.Errorf("Check() = %s, want violence over its threshold", verdict)
+	}
+
+	if _, err := m.Check(t.Context(), &Config{URL: "http://127.0.0.1:1"}, body); err == nil {
+		t.Error("Check() should fail when the endpoint is unreachable")
+	}
+	if _, err := Parse(map[string]any{"url": endpoint.URL, "fail_mode": "sometimes"}); err == nil {
+		t.Error("Parse() should reject an unknown fail_mode")
+	}
+}
@@ -0,0 +1,128 @@
+// Package netacl restricts the client IPs allowed to use the proxy and keeps the recent
+// rejections for administrators.
+package netacl
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// List is a list of IP networks.
+type List []netip.Prefix
+
+// Parse parses comma- or newline-separated IP addresses and CIDRs, e.g. "10.0.0.0/8, 203.0.113.7".
+func Parse(spec string) (List, error) {
+	var list List
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q", entry)
+			}
+			list = append(list, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+		list = append(list, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return list, nil
+}
+
+// Contains reports whether an address is in a network of the list.
+func (l List) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range l {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ACL decides which client IPs may use a group. The zero value allows every client.
+type ACL struct {
+	Allowed List
+	Denied  List
+}
+
+// Check reports whether the client IP is allowed, and why not when it is rejected.
+func (a ACL) Check(clientIP string) (bool, string) {
+	if len(a.Allowed) == 0 && len(a.Denied) == 0 {
+		return true, ""
+	}
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false, "invalid client IP"
+	}
+	if a.Denied.Contains(addr) {
+		return false, "denied"
+	}
+	if len(a.Allowed) > 0 && !a.Allowed.Contains(addr) {
+		return false, "not allowed"
+	}
+	return true, ""
+}
+
+// Rejection is a proxy request rejected by an ACL.
+type Rejection struct {
+	Time  time.Time `json:"time"`
+	Group string    `json:"group"`
+	// ClientIP is the IP the ACL was checked against, RemoteAddr the peer of the connection,
+	// which differs behind a trusted proxy.
+	ClientIP   string `json:"client_ip"`
+	RemoteAddr string `json:"remote_addr"`
+	Method     string `json:"method"`
+	Path       string `json:"path"`
+	Reason     string `json:"reason"`
+}
+
+// maxRejections is the number of recent rejections kept in memory.
+const maxRejections = 200
+
+// Rejections keeps the recent rejections of this instance.
+type Rejections struct {
+	mu      sync.Mutex
+	entries []Rejection
+	next    int
+}
+
+// NewRejections creates a new Rejections.
+func NewRejections() *Rejections {
+	return &Rejections{entries: make([]Rejection, 0, maxRejections)}
+}
+
+// Record adds a rejection, replacing the oldest one when full.
+func (r *Rejections) Record(rejection Rejection) {
+	if rejection.Time.IsZero() {
+		rejection.Time = time.Now()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.entries) < maxRejections {
+		r.entries = append(r.entries, rejection)
+		return
+	}
+	r.entries[r.next] = rejection
+	r.next = (r.next + 1) % maxRejections
+}
+
+// Recent returns the recent rejections, newest first.
+func (r *Rejections) Recent() []Rejection {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	recent := make([]Rejection, 0, len(r.entries))
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		recent = append(recent, r.entries[(r.next+i)%len(r.entries)])
+	}
+	return recent
+}
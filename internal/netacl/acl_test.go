@@ -0,0 +1,54 @@
+package netacl
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestACLCheck(t *testing.T) {
+	allowed, err := Parse("10.0.0.0/8, 203.0.113.7\n2001:db8::/32")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	denied, err := Parse("10.0.0.66")
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	acl := ACL{Allowed: allowed, Denied: denied}
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"203.0.113.7", true},
+		{"2001:db8::1", true},
+		{"10.0.0.66", false},
+		{"192.168.1.1", false},
+		{"not-an-ip", false},
+	}
+	for _, tt := range tests {
+		if got, _ := acl.Check(tt.ip); got != tt.want {
+			t.Errorf("Check(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+
+	if ok, _ := (ACL{}).Check("192.168.1.1"); !ok {
+		t.Error("empty ACL should allow every client")
+	}
+	if _, err := Parse("10.0.0.0/33"); err == nil {
+		t.Error("Parse() should reject an invalid CIDR")
+	}
+}
+
+func TestRejectionsRecent(t *testing.T) {
+	r := NewRejections()
+	for i := range maxRejections + 5 {
+		r.Record(Rejection{ClientIP: fmt.Sprint(i)})
+	}
+	recent := r.Recent()
+	if len(recent) != maxRejections || recent[0].ClientIP != fmt.Sprint(maxRejections+4) || recent[len(recent)-1].ClientIP != "5" {
+		t.Fatalf("Recent() = %d entries from %s to %s, want the last %d newest first", len(recent), recent[0].ClientIP, recent[len(recent)-1].ClientIP, maxRejections)
+	}
+}
@@ -0,0 +1,181 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testProvider struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	idToken  string
+	verifier string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	tp := &testProvider{}
+	tp.rsaKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	tp.ecKey, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 tp.server.URL,
+			"authorization_endpoint": tp.server.URL + "/authorize",
+			"token_endpoint":         tp.server.URL + "/token",
+			"jwks_uri":               tp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		b64 := base64.RawURLEncoding.EncodeToString
+		point, _ := tp.ecKey.PublicKey.Bytes()
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(tp.rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(tp.rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(point[1:33]), "y": b64(point[33:])},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, _ := r.BasicAuth()
+		challenge := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if id != "client" || secret != "secret" || r.FormValue("code") != "code" ||
+			base64.RawURLEncoding.EncodeToString(challenge[:]) != tp.verifier {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": tp.idToken})
+	})
+	tp.server = httptest.NewServer(mux)
+	t.Cleanup(tp.server.Close)
+	return tp
+}
+
+func (tp *testProvider) sign(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	if alg == "ES256" {
+		r, s, err := ecdsa.Sign(rand.Reader, tp.ecKey, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	} else {
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, tp.rsaKey, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestLoginFlow(t *testing.T) {
+	tp := newTestProvider(t)
+	ctx := context.Background()
+	config := Config{ClientID: "client", ClientSecret: "secret", RedirectURL: "https://gpt-load.test/callback", Scopes: []string{"openid", "email"}}
+
+	provider, err := Discover(ctx, tp.server.Client(), tp.server.URL+"/")
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	verifier, _ := RandomString()
+	authURL, err := url.Parse(provider.AuthCodeURL(config, "state", "nonce", verifier))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := authURL.Query()
+	if query.Get("client_id") != "client" || query.Get("scope") != "openid email" || query.Get("code_challenge_method") != "S256" {
+		t.Errorf("AuthCodeURL() = %s", authURL)
+	}
+	tp.verifier = query.Get("code_challenge")
+
+	now := time.Now()
+	tp.idToken = tp.sign(t, "RS256", "rsa", map[string]any{
+		"iss": tp.server.URL, "aud": "client", "exp": now.Add(time.Hour).Unix(), "iat": now.Unix(),
+		"nonce": "nonce", "email": "alice@example.com", "email_verified": true, "groups": []string{"ops", "dev"},
+	})
+	rawToken, err := provider.Exchange(ctx, config, "code", verifier)
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if _, err := provider.Exchange(ctx, config, "code", "another verifier"); err == nil {
+		t.Error("Exchange() with a wrong verifier succeeded")
+	}
+
+	claims, err := provider.Verify(ctx, config, rawToken, "nonce", now)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if verified, _ := claims.Bool("email_verified"); claims.String("email") != "alice@example.com" || !verified {
+		t.Errorf("claims = %v", claims)
+	}
+	if groups := claims.Strings("groups"); len(groups) != 2 || groups[1] != "dev" {
+		t.Errorf("Strings(groups) = %v", groups)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tp := newTestProvider(t)
+	ctx := context.Background()
+	config := Config{ClientID: "client"}
+	provider, err := Discover(ctx, tp.server.Client(), tp.server.URL)
+	if err != nil {
+		t.Fatalf("Discover() error = %v", err)
+	}
+
+	now := time.Now()
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{"iss": tp.server.URL, "aud": []string{"client", "other"}, "azp": "client", "exp": now.Add(time.Hour).Unix(), "nonce": "nonce"}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	if _, err := provider.Verify(ctx, config, tp.sign(t, "ES256", "ec", claims(nil)), "nonce", now); err != nil {
+		t.Errorf("Verify() of an ES256 token error = %v", err)
+	}
+
+	valid := tp.sign(t, "RS256", "rsa", claims(nil))
+	parts := strings.Split(valid, ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"x"}`)) + "." + parts[2]
+
+	for name, token := range map[string]string{
+		"expired":        tp.sign(t, "RS256", "rsa", claims(map[string]any{"exp": now.Add(-time.Hour).Unix()})),
+		"other audience": tp.sign(t, "RS256", "rsa", claims(map[string]any{"aud": "other", "azp": nil})),
+		"other issuer":   tp.sign(t, "RS256", "rsa", claims(map[string]any{"iss": "https://evil.test"})),
+		"other nonce":    tp.sign(t, "RS256", "rsa", claims(map[string]any{"nonce": "replayed"})),
+		"unknown key":    tp.sign(t, "RS256", "gone", claims(nil)),
+		"wrong key type": tp.sign(t, "RS256", "ec", claims(nil)),
+		"tampered":       tampered,
+		"unsigned":       parts[0] + "." + parts[1] + ".",
+	} {
+		if _, err := provider.Verify(ctx, config, token, "nonce", now); err == nil {
+			t.Errorf("Verify() of a token %s succeeded", name)
+		}
+	}
+}
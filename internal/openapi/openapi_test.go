@@ -0,0 +1,79 @@
+package openapi
+
+import (
+	"testing"
+	"time"
+)
+
+type testItem struct {
+	ID        uint     `json:"id"`
+	Name      string   `json:"name" binding:"required"`
+	Note      *string  `json:"note,omitempty"`
+	Tags      []string `json:"tags"`
+	Meta      map[string]any
+	CreatedAt time.Time  `json:"created_at"`
+	Parent    *testItem  `json:"parent,omitempty"`
+	Ignored   string     `json:"-"`
+	Children  []testItem `json:"children"`
+}
+
+type testEmbedding struct {
+	testItem
+	Extra bool `json:"extra"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	builder := NewBuilder(Info{Title: "test", Version: "1"})
+	schema := builder.SchemaOf(testEmbedding{})
+	if schema.Ref != "#/components/schemas/TestEmbedding" {
+		t.Fatalf("unexpected schema %+v", schema)
+	}
+
+	components := builder.Document().Components.Schemas
+	embedding := components["TestEmbedding"]
+	if embedding.Properties["extra"].Type != "boolean" || embedding.Properties["name"].Type != "string" {
+		t.Errorf("embedded fields are not promoted: %+v", embedding.Properties)
+	}
+
+	item := components["TestItem"]
+	if item == nil {
+		t.Fatal("TestItem is not a component")
+	}
+	cases := map[string]Schema{
+		"id":         {Type: "integer", Format: "int64"},
+		"note":       {Type: "string", Nullable: true},
+		"created_at": {Type: "string", Format: "date-time"},
+		"parent":     {Ref: "#/components/schemas/TestItem"},
+	}
+	for name, want := range cases {
+		got := item.Properties[name]
+		if got == nil || got.Type != want.Type || got.Format != want.Format || got.Nullable != want.Nullable || got.Ref != want.Ref {
+			t.Errorf("property %s = %+v, want %+v", name, got, want)
+		}
+	}
+	if item.Properties["Meta"].AdditionalProperties == nil || item.Properties["children"].Items.Ref == "" {
+		t.Errorf("unexpected map or slice schema %+v", item.Properties)
+	}
+	if _, ok := item.Properties["Ignored"]; ok {
+		t.Error("fields tagged - are documented")
+	}
+	if len(item.Required) != 1 || item.Required[0] != "name" {
+		t.Errorf("required = %v, want [name]", item.Required)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	cases := map[string]string{
+		"CreateGroup":          "Create group",
+		"GetGroupByExternalID": "Get group by external ID",
+		"ListKeysInGroup":      "List keys in group",
+	}
+	for name, want := range cases {
+		if got := Summary(name); got != want {
+			t.Errorf("Summary(%q) = %q, want %q", name, got, want)
+		}
+	}
+	if got := OperationID("CreateGroup"); got != "createGroup" {
+		t.Errorf("OperationID = %q", got)
+	}
+}
@@ -0,0 +1,263 @@
+// Package providerstatus polls the public status pages of the upstream providers, so that
+// failures during a provider-wide outage are not blamed on the keys.
+package providerstatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	pollInterval = 2 * time.Minute
+	fetchTimeout = 10 * time.Second
+	maxBodyBytes = 4 << 20
+)
+
+// Incident is an unresolved incident reported by the status page of a provider.
+type Incident struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	// Impact is "none", "minor", "major" or "critical".
+	Impact    string    `json:"impact"`
+	Status    string    `json:"status"`
+	URL       string    `json:"url"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// IsOutage reports whether the incident is severe enough to be treated as a provider-wide outage.
+func (i Incident) IsOutage() bool {
+	return i.Impact == "major" || i.Impact == "critical"
+}
+
+// Reference identifies the incident in request logs.
+func (i Incident) Reference() string {
+	if i.URL == "" {
+		return fmt.Sprintf("%s:%s", i.Provider, i.ID)
+	}
+	return fmt.Sprintf("%s:%s %s", i.Provider, i.ID, i.URL)
+}
+
+// Status is the state of the status page polling.
+type Status struct {
+	Enabled   bool                 `json:"enabled"`
+	CheckedAt map[string]time.Time `json:"checked_at"`
+	Incidents []Incident           `json:"incidents"`
+}
+
+// Monitor polls the status pages while provider status polling is enabled in the system settings.
+type Monitor struct {
+	settingsManager *config.SystemSettingsManager
+	client          *http.Client
+	providers       []provider
+
+	mu        sync.RWMutex
+	incidents map[string][]Incident
+	checkedAt map[string]time.Time
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewMonitor creates a new Monitor.
+func NewMonitor(settingsManager *config.SystemSettingsManager) *Monitor {
+	return &Monitor{
+		settingsManager: settingsManager,
+		client:          &http.Client{Timeout: fetchTimeout},
+		providers:       providers,
+		incidents:       make(map[string][]Incident),
+		checkedAt:       make(map[string]time.Time),
+		stopChan:        make(chan struct{}),
+	}
+}
+
+// Start begins polling in the background.
+func (m *Monitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		m.poll()
+		for {
+			select {
+			case <-ticker.C:
+				m.poll()
+			case <-m.stopChan:
+				return
+			}
+		}
+	}()
+	logrus.Debug("Provider status monitor started.")
+}
+
+// Stop stops polling.
+func (m *Monitor) Stop(ctx context.Context) {
+	close(m.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("Provider status monitor stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("Provider status monitor stop timed out.")
+	}
+}
+
+// IncidentFor returns the most severe unresolved incident of the provider serving upstreamURL,
+// or nil when there is none or polling is disabled.
+func (m *Monitor) IncidentFor(upstreamURL string) *Incident {
+	if !m.settingsManager.GetSettings().ProviderStatusPolling {
+		return nil
+	}
+	u, err := url.Parse(upstreamURL)
+	if err != nil {
+		return nil
+	}
+	p := providerForHost(m.providers, u.Hostname())
+	if p == nil {
+		return nil
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var worst *Incident
+	for i := range m.incidents[p.name] {
+		incident := m.incidents[p.name][i]
+		if worst == nil || impactRank(incident.Impact) > impactRank(worst.Impact) {
+			worst = &incident
+		}
+	}
+	return worst
+}
+
+// Status returns the unresolved incidents and when each provider was last checked.
+func (m *Monitor) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	status := Status{
+		Enabled:   m.settingsManager.GetSettings().ProviderStatusPolling,
+		CheckedAt: make(map[string]time.Time, len(m.checkedAt)),
+		Incidents: []Incident{},
+	}
+	for name, at := range m.checkedAt {
+		status.CheckedAt[name] = at
+	}
+	for _, incidents := range m.incidents {
+		status.Incidents = append(status.Incidents, incidents...)
+	}
+	sort.Slice(status.Incidents, func(i, j int) bool {
+		return status.Incidents[i].StartedAt.After(status.Incidents[j].StartedAt)
+	})
+	return status
+}
+
+func (m *Monitor) poll() {
+	if !m.settingsManager.GetSettings().ProviderStatusPolling {
+		m.mu.Lock()
+		clear(m.incidents)
+		clear(m.checkedAt)
+		m.mu.Unlock()
+		return
+	}
+
+	for _, p := range m.providers {
+		incidents, err := m.fetch(p)
+		if err != nil {
+			// Keep the last known incidents, a status page that cannot be reached says nothing new.
+			logrus.WithError(err).Warnf("Failed to poll the %s status page", p.name)
+			continue
+		}
+		m.update(p.name, incidents)
+	}
+}
+
+func (m *Monitor) fetch(p provider) ([]Incident, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.statusURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status page answered %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodyBytes))
+	if err != nil {
+		return nil, err
+	}
+	incidents, err := p.parse(body)
+	if err != nil {
+		return nil, err
+	}
+	for i := range incidents {
+		incidents[i].Provider = p.name
+	}
+	return incidents, nil
+}
+
+// update replaces the incidents of a provider and logs the ones that started or ended.
+func (m *Monitor) update(name string, incidents []Incident) {
+	m.mu.Lock()
+	previous := m.incidents[name]
+	m.incidents[name] = incidents
+	m.checkedAt[name] = time.Now()
+	m.mu.Unlock()
+
+	known := make(map[string]bool, len(previous))
+	for _, incident := range previous {
+		known[incident.ID] = true
+	}
+	for _, incident := range incidents {
+		if known[incident.ID] {
+			delete(known, incident.ID)
+			continue
+		}
+		logrus.WithFields(logrus.Fields{
+			"provider": name,
+			"impact":   incident.Impact,
+			"incident": incident.Reference(),
+		}).Warnf("Provider incident detected: %s", incident.Name)
+	}
+	for id := range known {
+		logrus.WithFields(logrus.Fields{"provider": name, "incident": id}).Info("Provider incident resolved")
+	}
+}
+
+func impactRank(impact string) int {
+	switch strings.ToLower(impact) {
+	case "critical":
+		return 3
+	case "major":
+		return 2
+	case "minor":
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,147 @@
+package providerstatus
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// provider is an upstream provider with a public status page.
+type provider struct {
+	name      string
+	statusURL string
+	// hosts are the API hosts of the provider. An entry starting with "." matches any subdomain.
+	hosts []string
+	parse func(body []byte) ([]Incident, error)
+}
+
+var providers = []provider{
+	{
+		name:      "openai",
+		statusURL: "https://status.openai.com/api/v2/incidents/unresolved.json",
+		hosts:     []string{"api.openai.com"},
+		parse:     parseStatuspage,
+	},
+	{
+		name:      "anthropic",
+		statusURL: "https://status.anthropic.com/api/v2/incidents/unresolved.json",
+		hosts:     []string{"api.anthropic.com"},
+		parse:     parseStatuspage,
+	},
+	{
+		name:      "google",
+		statusURL: "https://status.cloud.google.com/incidents.json",
+		hosts:     []string{"generativelanguage.googleapis.com", "aiplatform.googleapis.com", "-aiplatform.googleapis.com"},
+		parse:     parseGoogleCloud,
+	},
+}
+
+// providerForHost returns the provider serving the API host, or nil for any other upstream,
+// such as a relay, whose availability the status pages say nothing about.
+func providerForHost(providers []provider, host string) *provider {
+	host = strings.ToLower(host)
+	for i := range providers {
+		for _, h := range providers[i].hosts {
+			if host == h || (strings.HasPrefix(h, "-") && strings.HasSuffix(host, h)) {
+				return &providers[i]
+			}
+		}
+	}
+	return nil
+}
+
+// parseStatuspage parses the unresolved incidents of an Atlassian Statuspage.
+func parseStatuspage(body []byte) ([]Incident, error) {
+	var page struct {
+		Incidents []struct {
+			ID        string    `json:"id"`
+			Name      string    `json:"name"`
+			Status    string    `json:"status"`
+			Impact    string    `json:"impact"`
+			Shortlink string    `json:"shortlink"`
+			StartedAt time.Time `json:"started_at"`
+		} `json:"incidents"`
+	}
+	if err := json.Unmarshal(body, &page); err != nil {
+		return nil, err
+	}
+
+	incidents := make([]Incident, 0, len(page.Incidents))
+	for _, i := range page.Incidents {
+		incidents = append(incidents, Incident{
+			ID:        i.ID,
+			Name:      i.Name,
+			Impact:    i.Impact,
+			Status:    i.Status,
+			URL:       i.Shortlink,
+			StartedAt: i.StartedAt,
+		})
+	}
+	return incidents, nil
+}
+
+// googleProducts are the Google Cloud products serving the Gemini API.
+var googleProducts = []string{"gemini", "vertex ai"}
+
+// parseGoogleCloud parses the Google Cloud incident history, keeping the ongoing incidents
+// that affect the Gemini API.
+func parseGoogleCloud(body []byte) ([]Incident, error) {
+	var history []struct {
+		ID               string     `json:"id"`
+		ExternalDesc     string     `json:"external_desc"`
+		Begin            time.Time  `json:"begin"`
+		End              *time.Time `json:"end"`
+		Severity         string     `json:"severity"`
+		StatusImpact     string     `json:"status_impact"`
+		URI              string     `json:"uri"`
+		AffectedProducts []struct {
+			Title string `json:"title"`
+		} `json:"affected_products"`
+	}
+	if err := json.Unmarshal(body, &history); err != nil {
+		return nil, err
+	}
+
+	var incidents []Incident
+	for _, i := range history {
+		if i.End != nil {
+			continue
+		}
+		affected := false
+		for _, product := range i.AffectedProducts {
+			title := strings.ToLower(product.Title)
+			for _, name := range googleProducts {
+				if strings.Contains(title, name) {
+					affected = true
+				}
+			}
+		}
+		if !affected {
+			continue
+		}
+
+		incident := Incident{
+			ID:        i.ID,
+			Name:      i.ExternalDesc,
+			Impact:    googleImpact(i.Severity),
+			Status:    i.StatusImpact,
+			StartedAt: i.Begin,
+		}
+		if i.URI != "" {
+			incident.URL = "https://status.cloud.google.com/" + strings.TrimPrefix(i.URI, "/")
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// googleImpact maps the severity of a Google Cloud incident to a Statuspage impact.
+func googleImpact(severity string) string {
+	switch strings.ToLower(severity) {
+	case "high":
+		return "major"
+	case "medium":
+		return "minor"
+	}
+	return "none"
+}
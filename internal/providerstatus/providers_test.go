@@ -0,0 +1,48 @@
+package providerstatus
+
+import "testing"
+
+func TestParseStatuspage(t *testing.T) {
+	body := []byte(`{"page":{"id":"p"},"incidents":[{"id":"abc","name":"Elevated errors","status":"investigating","impact":"major","shortlink":"https://stspg.io/x","started_at":"2026-01-02T03:04:05.000Z"}]}`)
+	incidents, err := parseStatuspage(body)
+	if err != nil {
+		t.Fatalf("parseStatuspage() error = %v", err)
+	}
+	if len(incidents) != 1 || incidents[0].ID != "abc" || !incidents[0].IsOutage() || incidents[0].URL != "https://stspg.io/x" {
+		t.Fatalf("parseStatuspage() = %+v", incidents)
+	}
+}
+
+func TestParseGoogleCloud(t *testing.T) {
+	body := []byte(`[
+		{"id":"ongoing","external_desc":"Gemini API errors","begin":"2026-01-02T03:04:05+00:00","severity":"high","status_impact":"SERVICE_OUTAGE","uri":"incidents/ongoing","affected_products":[{"title":"Vertex Gemini API"}]},
+		{"id":"other","external_desc":"BigQuery latency","begin":"2026-01-02T03:04:05+00:00","severity":"high","affected_products":[{"title":"BigQuery"}]},
+		{"id":"resolved","external_desc":"Gemini API errors","begin":"2026-01-01T03:04:05+00:00","end":"2026-01-01T05:04:05+00:00","severity":"high","affected_products":[{"title":"Gemini API"}]}
+	]`)
+	incidents, err := parseGoogleCloud(body)
+	if err != nil {
+		t.Fatalf("parseGoogleCloud() error = %v", err)
+	}
+	if len(incidents) != 1 || incidents[0].ID != "ongoing" || incidents[0].Impact != "major" || incidents[0].URL != "https://status.cloud.google.com/incidents/ongoing" {
+		t.Fatalf("parseGoogleCloud() = %+v", incidents)
+	}
+}
+
+func TestProviderForHost(t *testing.T) {
+	tests := map[string]string{
+		"api.openai.com":                        "openai",
+		"API.Anthropic.com":                     "anthropic",
+		"generativelanguage.googleapis.com":     "google",
+		"us-central1-aiplatform.googleapis.com": "google",
+		"relay.example.com":                     "",
+	}
+	for host, want := range tests {
+		got := ""
+		if p := providerForHost(providers, host); p != nil {
+			got = p.name
+		}
+		if got != want {
+			t.Errorf("providerForHost(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
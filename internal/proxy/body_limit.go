@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"net/http"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// limitRequestBody rejects a request whose body is over the size limit of its group with 413.
+// A body without a declared length is cut off at the limit while it is read.
+func limitRequestBody(c *gin.Context, group *models.Group) bool {
+	limitKB := group.EffectiveConfig.MaxRequestBodyKB
+	if limitKB <= 0 {
+		return true
+	}
+	limit := int64(limitKB) * 1024
+	if c.Request.ContentLength > limit {
+		response.Error(c, app_errors.ErrRequestTooLarge)
+		return false
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	return true
+}
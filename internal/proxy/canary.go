@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	canaryTickInterval  = 30 * time.Second
+	canaryRetention     = 7 * 24 * time.Hour
+	canaryPruneInterval = time.Hour
+	canaryUserAgent     = "gpt-load-canary"
+	canaryErrorLength   = 500
+	// MaxCanaryHistoryHours bounds the probe history of a group.
+	MaxCanaryHistoryHours = 168
+)
+
+// CanaryHistory is the probe history of a group over the last hours.
+type CanaryHistory struct {
+	GroupID             uint                 `json:"group_id"`
+	Hours               int                  `json:"hours"`
+	Probes              []models.CanaryProbe `json:"probes"`
+	SuccessRate         float64              `json:"success_rate"`
+	AvgLatencyMs        int64                `json:"avg_latency_ms"`
+	P95LatencyMs        int64                `json:"p95_latency_ms"`
+	ConsecutiveFailures int                  `json:"consecutive_failures"`
+}
+
+// canaryState is the in-memory state of the canary of a group on the leader.
+type canaryState struct {
+	lastRun  time.Time
+	running  bool
+	failures int
+	alerted  bool
+}
+
+// CanaryRunner sends the canary probe of every group with a canary config through the proxy
+// pipeline on its interval, records the outcome as a probe series and alerts once a group
+// fails its threshold of consecutive probes, then again when it recovers. Probes run on the
+// leading master only and are logged like any other request.
+type CanaryRunner struct {
+	db           *gorm.DB
+	proxyServer  *ProxyServer
+	groupManager *services.GroupManager
+	elector      *cluster.Elector
+	notifier     *services.AlertNotifier
+	mu           sync.Mutex
+	states       map[uint]*canaryState
+	lastPrune    time.Time
+	ctx          context.Context
+	cancel       context.CancelFunc
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewCanaryRunner creates a new CanaryRunner.
+func NewCanaryRunner(
+	db *gorm.DB,
+	proxyServer *ProxyServer,
+	groupManager *services.GroupManager,
+	elector *cluster.Elector,
+	notifier *services.AlertNotifier,
+) *CanaryRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &CanaryRunner{
+		db:           db,
+		proxyServer:  proxyServer,
+		groupManager: groupManager,
+		elector:      elector,
+		notifier:     notifier,
+		states:       make(map[uint]*canaryState),
+		ctx:          ctx,
+		cancel:       cancel,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start begins probing the groups.
+func (r *CanaryRunner) Start() {
+	r.wg.Add(1)
+	go r.run()
+	logrus.Debug("Canary runner started")
+}
+
+// Stop stops the probes, respecting the context for shutdown timeout.
+func (r *CanaryRunner) Stop(ctx context.Context) {
+	close(r.stopCh)
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("CanaryRunner stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("CanaryRunner stop timed out.")
+	}
+}
+
+// History returns the probes of a group over the last hours with their summary.
+func (r *CanaryRunner) History(groupID uint, hours int) (*CanaryHistory, error) {
+	history := &CanaryHistory{GroupID: groupID, Hours: hours, Probes: []models.CanaryProbe{}}
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	if err := r.db.Where("group_id = ? AND time >= ?", groupID, since).Order("time ASC").Find(&history.Probes).Error; err != nil {
+		return nil, err
+	}
+	if len(history.Probes) == 0 {
+		return history, nil
+	}
+
+	var successes int
+	var total int64
+	durations := make([]int64, 0, len(history.Probes))
+	for _, probe := range history.Probes {
+		if probe.Success {
+			successes++
+		}
+		total += probe.DurationMs
+		durations = append(durations, probe.DurationMs)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+
+	history.SuccessRate = float64(successes) / float64(len(history.Probes)) * 100
+	history.AvgLatencyMs = total / int64(len(history.Probes))
+	history.P95LatencyMs = durations[(len(durations)*95-1)/100]
+	for i := len(history.Probes) - 1; i >= 0 && !history.Probes[i].Success; i-- {
+		history.ConsecutiveFailures++
+	}
+	return history, nil
+}
+
+func (r *CanaryRunner) run() {
+	defer r.wg.Done()
+
+	ticker := time.NewTicker(canaryTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.tick()
+		case <-r.stopCh:
+			return
+		}
+	}
+}
+
+// tick starts the probes that are due.
+func (r *CanaryRunner) tick() {
+	if !r.elector.IsLeader() {
+		return
+	}
+
+	var names []string
+	if err := r.db.Model(&models.Group{}).Pluck("name", &names).Error; err != nil {
+		logrus.WithError(err).Error("CanaryRunner: Failed to list the groups")
+		return
+	}
+
+	now := time.Now()
+	for _, name := range names {
+		group, err := r.groupManager.GetGroupByName(name)
+		if err != nil || group.Canary == nil || !group.Enabled {
+			continue
+		}
+
+		r.mu.Lock()
+		state, ok := r.states[group.ID]
+		if !ok {
+			state = &canaryState{}
+			r.states[group.ID] = state
+		}
+		due := !state.running && now.Sub(state.lastRun) >= group.Canary.Interval()
+		if due {
+			state.running = true
+			state.lastRun = now
+		}
+		r.mu.Unlock()
+
+		if due {
+			r.wg.Add(1)
+			go r.probe(group, state)
+		}
+	}
+
+	if now.Sub(r.lastPrune) >= canaryPruneInterval {
+		r.lastPrune = now
+		if err := r.db.Where("time < ?", now.Add(-canaryRetention)).Delete(&models.CanaryProbe{}).Error; err != nil {
+			logrus.WithError(err).Warn("CanaryRunner: Failed to prune old probes")
+		}
+	}
+}
+
+// probe sends the canary probe of a group once and records its outcome.
+func (r *CanaryRunner) probe(group *models.Group, state *canaryState) {
+	defer r.wg.Done()
+
+	result := r.proxyServer.Probe(r.ctx, group)
+	if r.ctx.Err() != nil {
+		r.mu.Lock()
+		state.running = false
+		r.mu.Unlock()
+		return
+	}
+	if err := r.db.Create(result).Error; err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Error("CanaryRunner: Failed to record the probe")
+	}
+
+	r.mu.Lock()
+	state.running = false
+	var event, text string
+	if result.Success {
+		if state.alerted {
+			event = "canary_recovered"
+			text = fmt.Sprintf("GPT-Load: canary of group %s recovered after %d failed probes", group.Name, state.failures)
+		}
+		state.failures = 0
+		state.alerted = false
+	} else {
+		state.failures++
+		if state.failures == group.Canary.Threshold() {
+			state.alerted = true
+			event = "canary_failure"
+			text = fmt.Sprintf("GPT-Load: canary of group %s failed %d times in a row, last status %d: %s",
+				group.Name, state.failures, result.StatusCode, result.Error)
+		}
+	}
+	failures := state.failures
+	r.mu.Unlock()
+
+	if event == "" {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"group":    group.Name,
+		"failures": failures,
+		"status":   result.StatusCode,
+	}).Warn(text)
+	r.notifier.Send(event, text, map[string]any{
+		"group":                group.Name,
+		"consecutive_failures": failures,
+		"probe":                result,
+	})
+}
+
+// Probe sends the canary probe of a group through its proxy pipeline without proxy
+// authentication and response cache, and returns its outcome.
+func (ps *ProxyServer) Probe(ctx context.Context, group *models.Group) *models.CanaryProbe {
+	ctx, cancel := context.WithTimeout(ctx, group.Canary.Timeout())
+	defer cancel()
+
+	result := &models.CanaryProbe{GroupID: group.ID, Time: time.Now()}
+	method, path, body := group.Canary.Request(group.ChannelType, group.TestModel)
+	req, err := http.NewRequestWithContext(ctx, method, "/proxy/"+group.Name+path, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.RemoteAddr = "127.0.0.1:0"
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", canaryUserAgent)
+
+	recorder := ps.serveInProcess(req, group.Name, nil)
+	result.DurationMs = time.Since(result.Time).Milliseconds()
+	result.StatusCode = recorder.status
+	result.Success = recorder.status < http.StatusBadRequest
+	if !result.Success {
+		result.Error = utils.TruncateString(string(decodeCapturedBody(recorder.Header(), recorder.body.Bytes())), canaryErrorLength)
+	}
+	return result
+}
@@ -0,0 +1,271 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/requestid"
+	"gpt-load/internal/utils"
+)
+
+const (
+	compareUserAgent = "gpt-load-compare"
+	// MaxCompareTargets bounds the groups and models one prompt is sent to.
+	MaxCompareTargets = 10
+	// MaxCompareTimeout bounds the time a target is given to answer.
+	MaxCompareTimeout       = 5 * time.Minute
+	defaultCompareTimeout   = 60 * time.Second
+	defaultCompareMaxTokens = 256
+	compareErrorLength      = 2000
+)
+
+// CompareTarget is a group, and optionally a model, a comparison prompt is sent to.
+type CompareTarget struct {
+	Group *models.Group
+	// Model defaults to the test model of the group.
+	Model string
+}
+
+// CompareOptions describes a prompt sent to several groups and models side by side.
+type CompareOptions struct {
+	Prompt    string
+	System    string
+	MaxTokens int
+	Timeout   time.Duration
+	Targets   []CompareTarget
+	// RemoteAddr is the address of the administrator, logged as the source of the requests.
+	RemoteAddr string
+}
+
+// CompareUsage is the token usage reported by the upstream.
+type CompareUsage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// CompareResult is the outcome of the prompt for one target.
+type CompareResult struct {
+	Group       string        `json:"group"`
+	ChannelType string        `json:"channel_type"`
+	Model       string        `json:"model"`
+	RequestID   string        `json:"request_id"`
+	StatusCode  int           `json:"status_code"`
+	DurationMs  int64         `json:"duration_ms"`
+	Response    string        `json:"response"`
+	Usage       *CompareUsage `json:"usage,omitempty"`
+	Error       string        `json:"error,omitempty"`
+}
+
+// Compare sends one prompt to every target at once through the proxy pipeline of its group,
+// without proxy authentication and response cache, and returns the results in the order of
+// the targets. The requests are logged like any other request.
+func (ps *ProxyServer) Compare(ctx context.Context, opts CompareOptions) []CompareResult {
+	if opts.MaxTokens <= 0 {
+		opts.MaxTokens = defaultCompareMaxTokens
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultCompareTimeout
+	}
+
+	results := make([]CompareResult, len(opts.Targets))
+	var wg sync.WaitGroup
+	for i, target := range opts.Targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = ps.compareOne(ctx, opts, target)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func (ps *ProxyServer) compareOne(ctx context.Context, opts CompareOptions, target CompareTarget) CompareResult {
+	group := target.Group
+	model := target.Model
+	if model == "" {
+		model = group.TestModel
+	}
+	result := CompareResult{
+		Group:       group.Name,
+		ChannelType: group.ChannelType,
+		Model:       model,
+		RequestID:   requestid.New(),
+	}
+
+	ctx, cancel := context.WithTimeout(requestid.WithID(ctx, result.RequestID), opts.Timeout)
+	defer cancel()
+
+	path, body := comparePrompt(group.ChannelType, model, opts)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "/proxy/"+group.Name+path, bytes.NewReader(body))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	req.RemoteAddr = opts.RemoteAddr
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", compareUserAgent)
+
+	start := time.Now()
+	recorder := ps.serveInProcess(req, group.Name, nil)
+	result.DurationMs = time.Since(start).Milliseconds()
+	result.StatusCode = recorder.status
+
+	respBody := decodeCapturedBody(recorder.Header(), recorder.body.Bytes())
+	if recorder.status >= http.StatusBadRequest {
+		result.Error = utils.TruncateString(string(respBody), compareErrorLength)
+		return result
+	}
+	result.Response, result.Usage = parseCompareResponse(group.ChannelType, respBody)
+	return result
+}
+
+// comparePrompt returns the path and body of a completion of the prompt in the API of a
+// channel.
+func comparePrompt(channelType, model string, opts CompareOptions) (string, []byte) {
+	var path string
+	var payload map[string]any
+	switch channelType {
+	case "anthropic":
+		path = "/v1/messages"
+		payload = map[string]any{
+			"model":      model,
+			"max_tokens": opts.MaxTokens,
+			"messages":   []map[string]string{{"role": "user", "content": opts.Prompt}},
+		}
+		if opts.System != "" {
+			payload["system"] = opts.System
+		}
+	case "gemini":
+		path = "/v1beta/models/" + model + ":generateContent"
+		payload = map[string]any{
+			"contents":         []map[string]any{{"role": "user", "parts": []map[string]string{{"text": opts.Prompt}}}},
+			"generationConfig": map[string]any{"maxOutputTokens": opts.MaxTokens},
+		}
+		if opts.System != "" {
+			payload["systemInstruction"] = map[string]any{"parts": []map[string]string{{"text": opts.System}}}
+		}
+	case "openai-response":
+		path = "/v1/responses"
+		payload = map[string]any{"model": model, "input": opts.Prompt, "max_output_tokens": opts.MaxTokens}
+		if opts.System != "" {
+			payload["instructions"] = opts.System
+		}
+	default:
+		path = "/v1/chat/completions"
+		messages := []map[string]string{{"role": "user", "content": opts.Prompt}}
+		if opts.System != "" {
+			messages = append([]map[string]string{{"role": "system", "content": opts.System}}, messages...)
+		}
+		payload = map[string]any{"model": model, "max_tokens": opts.MaxTokens, "messages": messages}
+	}
+	body, _ := json.Marshal(payload)
+	return path, body
+}
+
+// compareResponse holds the fields of the response formats of all channels that carry the
+// text and usage.
+type compareResponse struct {
+	// OpenAI chat completions
+	Choices []struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	} `json:"choices"`
+	// OpenAI responses
+	Output []struct {
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"output"`
+	// Anthropic messages
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	// Gemini
+	Candidates []struct {
+		Content struct {
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"content"`
+	} `json:"candidates"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		InputTokens      int `json:"input_tokens"`
+		OutputTokens     int `json:"output_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+	UsageMetadata *struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+// parseCompareResponse extracts the generated text and token usage of a response. A body
+// that cannot be parsed is returned as it is.
+func parseCompareResponse(channelType string, body []byte) (string, *CompareUsage) {
+	var resp compareResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return utils.TruncateString(string(body), compareErrorLength), nil
+	}
+
+	var text strings.Builder
+	switch channelType {
+	case "anthropic":
+		for _, block := range resp.Content {
+			if block.Type == "text" {
+				text.WriteString(block.Text)
+			}
+		}
+	case "gemini":
+		if len(resp.Candidates) > 0 {
+			for _, part := range resp.Candidates[0].Content.Parts {
+				text.WriteString(part.Text)
+			}
+		}
+	case "openai-response":
+		for _, item := range resp.Output {
+			for _, part := range item.Content {
+				if part.Type == "output_text" {
+					text.WriteString(part.Text)
+				}
+			}
+		}
+	default:
+		if len(resp.Choices) > 0 {
+			text.WriteString(resp.Choices[0].Message.Content)
+		}
+	}
+
+	var usage *CompareUsage
+	switch {
+	case resp.UsageMetadata != nil:
+		usage = &CompareUsage{
+			InputTokens:  resp.UsageMetadata.PromptTokenCount,
+			OutputTokens: resp.UsageMetadata.CandidatesTokenCount,
+			TotalTokens:  resp.UsageMetadata.TotalTokenCount,
+		}
+	case resp.Usage != nil:
+		usage = &CompareUsage{
+			InputTokens:  max(resp.Usage.PromptTokens, resp.Usage.InputTokens),
+			OutputTokens: max(resp.Usage.CompletionTokens, resp.Usage.OutputTokens),
+			TotalTokens:  resp.Usage.TotalTokens,
+		}
+		if usage.TotalTokens == 0 {
+			usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+		}
+	}
+	return text.String(), usage
+}
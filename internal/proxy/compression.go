@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"net/http"
+
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// compressingResponseWriter compresses the response toward the client with the encoding it
+// accepts. The decision is taken at the first write, once the upstream headers are copied:
+// a body that is already encoded, e.g. passed through as the upstream sent it, is left alone.
+type compressingResponseWriter struct {
+	gin.ResponseWriter
+	encoding string
+	encoder  utils.CompressWriter
+	decided  bool
+}
+
+// startResponseCompression swaps the gin writer for one compressing the response, when the
+// group compresses responses and the client accepts an encoding. It returns nil otherwise.
+func startResponseCompression(c *gin.Context, enabled bool) *compressingResponseWriter {
+	if !enabled {
+		return nil
+	}
+	encoding := utils.NegotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return nil
+	}
+	writer := &compressingResponseWriter{ResponseWriter: c.Writer, encoding: encoding}
+	c.Writer = writer
+	return writer
+}
+
+// decide sets up compression before the headers are sent, if the body is not encoded yet.
+func (w *compressingResponseWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" || !bodyAllowedForStatus(w.Status()) {
+		return
+	}
+	encoder, err := utils.NewCompressWriter(w.encoding, w.ResponseWriter)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to compress the response, sending it uncompressed")
+		return
+	}
+	w.encoder = encoder
+	header.Set("Content-Encoding", w.encoding)
+	header.Del("Content-Length")
+	header.Add("Vary", "Accept-Encoding")
+}
+
+func (w *compressingResponseWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.encoder == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	return w.encoder.Write(data)
+}
+
+func (w *compressingResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends the data compressed so far, so that streamed chunks reach the client as they come.
+func (w *compressingResponseWriter) Flush() {
+	if w.encoder != nil {
+		if err := w.encoder.Flush(); err != nil {
+			logUpstreamError("flushing compressed response", err)
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// finish ends the compressed body and puts the writer it wraps back in place. It is a no-op on
+// a nil writer, for requests that are not compressed.
+func (w *compressingResponseWriter) finish(c *gin.Context) {
+	if w == nil {
+		return
+	}
+	if w.encoder != nil {
+		if err := w.encoder.Close(); err != nil {
+			logUpstreamError("finishing compressed response", err)
+		}
+		w.encoder = nil
+	}
+	if c.Writer == w {
+		c.Writer = w.ResponseWriter
+	}
+}
+
+// bodyAllowedForStatus reports whether a response with status may have a body.
+func bodyAllowedForStatus(status int) bool {
+	return status >= http.StatusOK && status != http.StatusNoContent && status != http.StatusNotModified
+}
@@ -0,0 +1,110 @@
+package proxy
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// requestDeadlineHeader carries the client's latency budget for the whole request, retries included.
+	requestDeadlineHeader = "X-Request-Deadline"
+	// requestTimeoutParam is the query parameter alternative for clients that cannot set headers.
+	requestTimeoutParam = "timeout"
+	requestDeadlineKey  = "requestDeadline"
+	// maxRequestBudget caps the latency budget a client can ask for.
+	maxRequestBudget = 24 * time.Hour
+)
+
+// applyRequestDeadline reads the client's latency budget, stores its deadline in the context and
+// removes it from the request so it is not forwarded upstream.
+func applyRequestDeadline(c *gin.Context, now time.Time) error {
+	deadline, err := parseRequestDeadline(c.Request, now)
+	if err != nil {
+		return err
+	}
+
+	c.Request.Header.Del(requestDeadlineHeader)
+	if query := c.Request.URL.Query(); query.Has(requestTimeoutParam) {
+		query.Del(requestTimeoutParam)
+		c.Request.URL.RawQuery = query.Encode()
+	}
+
+	if !deadline.IsZero() {
+		c.Set(requestDeadlineKey, deadline)
+	}
+	return nil
+}
+
+// parseRequestDeadline returns the deadline requested by the client, or the zero time if there is none.
+// The header holds an RFC 3339 timestamp, a number of seconds or a duration such as "1500ms";
+// the query parameter holds seconds or a duration. The header wins when both are set. Deadlines
+// further away than maxRequestBudget are brought back to it.
+func parseRequestDeadline(r *http.Request, now time.Time) (time.Time, error) {
+	if value := strings.TrimSpace(r.Header.Get(requestDeadlineHeader)); value != "" {
+		if at, err := time.Parse(time.RFC3339, value); err == nil {
+			if latest := now.Add(maxRequestBudget); at.After(latest) {
+				return latest, nil
+			}
+			return at, nil
+		}
+		budget, err := parseBudget(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s header %q: expected an RFC 3339 time, seconds or a duration", requestDeadlineHeader, value)
+		}
+		return now.Add(budget), nil
+	}
+
+	if value := strings.TrimSpace(r.URL.Query().Get(requestTimeoutParam)); value != "" {
+		budget, err := parseBudget(value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid %s parameter %q: expected seconds or a duration", requestTimeoutParam, value)
+		}
+		return now.Add(budget), nil
+	}
+
+	return time.Time{}, nil
+}
+
+// parseBudget parses a budget in seconds or as a duration, capped at maxRequestBudget. Seconds
+// are compared with the cap before their conversion, which would overflow for huge values.
+func parseBudget(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if !(seconds > 0) || math.IsInf(seconds, 1) {
+			return 0, fmt.Errorf("budget must be a positive number of seconds")
+		}
+		if seconds >= maxRequestBudget.Seconds() {
+			return maxRequestBudget, nil
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	budget, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if budget <= 0 {
+		return 0, fmt.Errorf("budget must be positive")
+	}
+	return min(budget, maxRequestBudget), nil
+}
+
+// requestDeadline returns the client's deadline set by applyRequestDeadline.
+func requestDeadline(c *gin.Context) (time.Time, bool) {
+	value, ok := c.Get(requestDeadlineKey)
+	if !ok {
+		return time.Time{}, false
+	}
+	deadline, ok := value.(time.Time)
+	return deadline, ok
+}
+
+// withinBudget reports whether work expected to take estimate can finish before the client's deadline.
+func withinBudget(c *gin.Context, estimate time.Duration) bool {
+	deadline, ok := requestDeadline(c)
+	return !ok || time.Now().Add(estimate).Before(deadline)
+}
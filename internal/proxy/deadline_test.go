@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseRequestDeadline(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		header  string
+		query   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "seconds", header: "2.5", want: 2500 * time.Millisecond},
+		{name: "duration", header: "1500ms", want: 1500 * time.Millisecond},
+		{name: "timestamp", header: now.Add(time.Minute).Format(time.RFC3339), want: time.Minute},
+		{name: "query seconds", query: "30", want: 30 * time.Second},
+		{name: "huge seconds", header: "1e300", want: maxRequestBudget},
+		{name: "overflowing seconds", query: "9300000000", want: maxRequestBudget},
+		{name: "long duration", header: "100000h", want: maxRequestBudget},
+		{name: "far timestamp", header: now.AddDate(10, 0, 0).Format(time.RFC3339), want: maxRequestBudget},
+		{name: "zero", header: "0", wantErr: true},
+		{name: "negative", query: "-5", wantErr: true},
+		{name: "infinite", header: "+Inf", wantErr: true},
+		{name: "not a number", header: "soon", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/chat/completions", nil)
+			if tc.header != "" {
+				r.Header.Set(requestDeadlineHeader, tc.header)
+			}
+			if tc.query != "" {
+				r.URL.RawQuery = requestTimeoutParam + "=" + tc.query
+			}
+
+			deadline, err := parseRequestDeadline(r, now)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("parseRequestDeadline() = %v, want an error", deadline)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := deadline.Sub(now); got != tc.want {
+				t.Errorf("budget = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package proxy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	debugCaptureSampledKey = "debugCaptureSampled"
+	debugCaptureKey        = "debugCapture"
+	redactedHeaderValue    = "[REDACTED]"
+)
+
+// sensitiveHeaderWords mark headers whose values are never captured, such as the
+// channel auth header and upstream-level credentials.
+var sensitiveHeaderWords = []string{"auth", "key", "token", "secret", "cookie", "signature"}
+
+// debugCaptureWriter keeps the beginning of the response body written to the client.
+type debugCaptureWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	truncated bool
+}
+
+func (w *debugCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *debugCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *debugCaptureWriter) capture(data []byte) {
+	if remaining := services.DebugCaptureMaxBodySize - w.body.Len(); len(data) > remaining {
+		w.body.Write(data[:remaining])
+		w.truncated = true
+		return
+	}
+	w.body.Write(data)
+}
+
+// startDebugCapture records the upstream request of an attempt if the request was sampled
+// for debug capture. The capture is saved with the request log of the attempt.
+func startDebugCapture(c *gin.Context, req *http.Request, body []byte, apiKey *models.APIKey) *services.DebugCapture {
+	if !c.GetBool(debugCaptureSampledKey) {
+		return nil
+	}
+
+	capture := &services.DebugCapture{
+		Method:         req.Method,
+		UpstreamURL:    utils.RedactSecret(req.URL.String(), apiKey.KeyValue),
+		RequestHeaders: redactHeaders(req.Header, apiKey),
+	}
+	capture.RequestBody, capture.RequestTruncated = truncateCapturedBody(utils.RedactSecret(string(body), apiKey.KeyValue))
+	c.Set(debugCaptureKey, capture)
+	return capture
+}
+
+// captureResponse fills the response of a capture from the upstream response and body.
+func captureResponse(capture *services.DebugCapture, resp *http.Response, body []byte, truncated bool, apiKey *models.APIKey) {
+	if capture == nil {
+		return
+	}
+	if resp != nil {
+		capture.ResponseHeaders = redactHeaders(resp.Header, apiKey)
+		body = decodeCapturedBody(resp.Header, body)
+	}
+	var cut bool
+	capture.ResponseBody, cut = truncateCapturedBody(utils.RedactSecret(string(body), apiKey.KeyValue))
+	capture.ResponseTruncated = truncated || cut
+}
+
+// takeDebugCapture returns the pending capture of the current attempt and clears it.
+func takeDebugCapture(c *gin.Context) *services.DebugCapture {
+	value, ok := c.Get(debugCaptureKey)
+	if !ok {
+		return nil
+	}
+	capture, _ := value.(*services.DebugCapture)
+	if capture != nil {
+		c.Set(debugCaptureKey, (*services.DebugCapture)(nil))
+	}
+	return capture
+}
+
+func (ps *ProxyServer) saveDebugCapture(c *gin.Context, logEntry *models.RequestLog) {
+	capture := takeDebugCapture(c)
+	if capture == nil || logEntry.ID == "" {
+		return
+	}
+	capture.RequestLogID = logEntry.ID
+	capture.GroupID = logEntry.GroupID
+	capture.CapturedAt = time.Now()
+	capture.StatusCode = logEntry.StatusCode
+	ps.debugCaptureService.Save(capture)
+}
+
+func redactHeaders(header http.Header, apiKey *models.APIKey) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		lower := strings.ToLower(name)
+		for _, word := range sensitiveHeaderWords {
+			if strings.Contains(lower, word) {
+				value = redactedHeaderValue
+				break
+			}
+		}
+		redacted[name] = utils.RedactSecret(value, apiKey.KeyValue)
+	}
+	return redacted
+}
+
+func truncateCapturedBody(body string) (string, bool) {
+	if len(body) > services.DebugCaptureMaxBodySize {
+		return body[:services.DebugCaptureMaxBodySize], true
+	}
+	return body, false
+}
+
+// decodeCapturedBody decompresses a gzip body, keeping what can be read of a truncated one.
+func decodeCapturedBody(header http.Header, body []byte) []byte {
+	if header.Get("Content-Encoding") != "gzip" {
+		return body
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return body
+	}
+	defer reader.Close()
+
+	decoded, err := io.ReadAll(reader)
+	if err != nil && len(decoded) == 0 {
+		return body
+	}
+	return decoded
+}
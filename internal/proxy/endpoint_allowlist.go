@@ -0,0 +1,29 @@
+package proxy
+
+import (
+	"strings"
+
+	"gpt-load/internal/endpoints"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkEndpointAllowed rejects requests outside the group's allowed_endpoints, with 404 for
+// a path that is not allowed and 405 when only the method is not.
+func checkEndpointAllowed(c *gin.Context, group *models.Group) bool {
+	path := strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+c.Param("group_name"))
+	decision, methods := group.EndpointAllowlist.Check(c.Request.Method, path)
+	switch decision {
+	case endpoints.PathNotAllowed:
+		response.Error(c, app_errors.ErrEndpointNotAllowed)
+		return false
+	case endpoints.MethodNotAllowed:
+		c.Header("Allow", strings.Join(methods, ", "))
+		response.Error(c, app_errors.ErrMethodNotAllowed)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"net/url"
+	"strings"
+
+	"gpt-load/internal/features"
+	"gpt-load/internal/models"
+	"gpt-load/internal/translate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// formatTranslationKey holds the translation between the client format of a request and the
+// format of its upstream, for groups with format translation on.
+const formatTranslationKey = "format_translation"
+
+// selectFormatTranslation picks the translation a request to group needs: OpenAI chat completions
+// sent to an Anthropic group, or Anthropic messages sent to an OpenAI group.
+func selectFormatTranslation(c *gin.Context, originalGroup, group *models.Group) {
+	if !group.FeatureEnabled(features.FormatTranslation) {
+		return
+	}
+	path := strings.TrimPrefix(c.Request.URL.Path, "/proxy/"+originalGroup.Name)
+	var translation *translate.Translation
+	switch {
+	case path == translate.ChatCompletionsPath && group.ChannelType == "anthropic":
+		translation = translate.ChatToMessages
+	case path == translate.MessagesPath && group.ChannelType == "openai":
+		translation = translate.MessagesToChat
+	default:
+		return
+	}
+	c.Set(formatTranslationKey, translation)
+}
+
+// formatTranslation returns the translation of the request, nil when it is not translated.
+func formatTranslation(c *gin.Context) *translate.Translation {
+	value, ok := c.Get(formatTranslationKey)
+	if !ok {
+		return nil
+	}
+	return value.(*translate.Translation)
+}
+
+// translateUpstreamURL points an upstream URL built from the client path at the endpoint of the
+// upstream format.
+func translateUpstreamURL(upstreamURL string, translation *translate.Translation) (string, error) {
+	u, err := url.Parse(upstreamURL)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(u.Path, translation.ClientPath) {
+		u.Path = strings.TrimSuffix(u.Path, translation.ClientPath) + translation.UpstreamPath
+	}
+	return u.String(), nil
+}
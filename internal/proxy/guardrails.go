@@ -0,0 +1,23 @@
+package proxy
+
+import (
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// applyGuardrails applies the request_guardrails of the group to the body. A rejected request
+// gets 400 with the violated limit in the details of the error, and ok is false.
+func applyGuardrails(c *gin.Context, group *models.Group, bodyBytes []byte) ([]byte, bool) {
+	if group.Guardrails == nil {
+		return bodyBytes, true
+	}
+	finalBodyBytes, violation := group.Guardrails.Apply(bodyBytes)
+	if violation != nil {
+		response.ErrorWithDetails(c, app_errors.NewAPIError(app_errors.ErrRequestPolicyViolation, violation.Error()), violation)
+		return nil, false
+	}
+	return finalBodyBytes, true
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/requestid"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// mirroredFromKey marks a mirrored request with the name of the group it was copied from.
+	mirroredFromKey = "mirroredFrom"
+	// maxInFlightMirrors bounds the mirrored requests in flight on an instance, requests
+	// beyond it are not mirrored.
+	maxInFlightMirrors = 64
+	mirrorTimeout      = 5 * time.Minute
+)
+
+// mirrorRequest copies a sampled request of a group with a mirror config to the mirror group
+// in the background, so that the client does not wait for it. The copy runs through the proxy
+// pipeline of the mirror group like a replay, is logged with the request ID of the original
+// request, and its response is discarded or, with store_responses, kept as a debug capture.
+// Requests sent by gpt-load itself are not mirrored.
+func (ps *ProxyServer) mirrorRequest(c *gin.Context, group *models.Group, body []byte) {
+	cfg := group.Mirror
+	if cfg == nil || c.GetBool(inProcessKey) || !cfg.Sampled() {
+		return
+	}
+	log := logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"group_name":   group.Name,
+		"mirror_group": cfg.Group,
+	})
+
+	select {
+	case ps.mirrorSlots <- struct{}{}:
+	default:
+		log.Debug("Too many mirrored requests in flight, request not mirrored")
+		return
+	}
+
+	path := "/proxy/" + cfg.Group + c.Param("path")
+	if c.Request.URL.RawQuery != "" {
+		path += "?" + c.Request.URL.RawQuery
+	}
+	ctx, cancel := context.WithTimeout(requestid.WithID(context.Background(), requestid.FromContext(c.Request.Context())), mirrorTimeout)
+	req, err := http.NewRequestWithContext(ctx, c.Request.Method, path, bytes.NewReader(bytes.Clone(body)))
+	if err != nil {
+		cancel()
+		<-ps.mirrorSlots
+		log.WithError(err).Warn("Failed to create mirrored request")
+		return
+	}
+	req.Header = c.Request.Header.Clone()
+	req.RemoteAddr = c.Request.RemoteAddr
+
+	keys := map[string]any{mirroredFromKey: group.Name}
+	if cfg.StoreResponses {
+		keys[debugCaptureSampledKey] = true
+		c.Set(debugCaptureSampledKey, true)
+	}
+
+	go func() {
+		defer func() {
+			cancel()
+			<-ps.mirrorSlots
+		}()
+		start := time.Now()
+		recorder := ps.serveInProcess(req, cfg.Group, keys)
+		log.WithFields(logrus.Fields{
+			"status_code": recorder.status,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}).Debug("Mirrored request completed")
+	}()
+}
@@ -1,9 +1,11 @@
 package proxy
 
 import (
+	"errors"
 	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
-	"gpt-load/internal/utils"
+	"gpt-load/internal/response"
 	"io"
 	"net/http"
 	"strings"
@@ -30,25 +32,41 @@ func (ps *ProxyServer) handleModelListResponse(c *gin.Context, resp *http.Respon
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to read model list response body")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read response"})
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to read response"))
 		return
 	}
 
 	// Decompress response data based on Content-Encoding
-	contentEncoding := resp.Header.Get("Content-Encoding")
-	decompressed, err := utils.DecompressResponse(contentEncoding, bodyBytes)
-	if err != nil {
-		logrus.WithError(err).Warn("Decompression failed, using original data")
-		decompressed = bodyBytes
-	}
+	decompressed, _ := decodeResponseBody(resp, bodyBytes)
 
 	// Transform model list (returns map[string]any directly, no marshaling)
-	response, err := channelHandler.TransformModelList(c.Request, decompressed, group)
+	modelList, err := channelHandler.TransformModelList(c.Request, decompressed, group)
 	if err != nil {
 		logrus.WithError(err).Error("Failed to transform model list")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process response"})
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, "Failed to process response"))
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, modelList)
+}
+
+// isDiscoveredModelList checks if this is a request for the OpenAI model list of the group,
+// which is served by model discovery instead of being proxied.
+func isDiscoveredModelList(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet && c.Param("path") == "/v1/models"
+}
+
+// serveDiscoveredModelList answers with the models discovered for the group.
+func (ps *ProxyServer) serveDiscoveredModelList(c *gin.Context, group *models.Group) {
+	list, err := ps.modelDiscovery.ModelList(c.Request.Context(), c.Request, group)
+	if err != nil {
+		logrus.WithError(err).WithField("group", group.Name).Warn("Failed to list the models of the group")
+		if errors.Is(err, app_errors.ErrNoActiveKeys) {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		return
+	}
+	c.JSON(http.StatusOK, list)
 }
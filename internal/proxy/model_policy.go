@@ -0,0 +1,21 @@
+package proxy
+
+import (
+	"fmt"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// checkModelAllowed rejects requests for a model outside the group's allowed_models or in its
+// denied_models with 403.
+func checkModelAllowed(c *gin.Context, group *models.Group, model string) bool {
+	if group.ModelPolicy.Allows(model) {
+		return true
+	}
+	response.Error(c, app_errors.NewAPIError(app_errors.ErrModelNotAllowed, fmt.Sprintf("Model '%s' is not allowed for group '%s'", model, group.Name)))
+	return false
+}
@@ -0,0 +1,80 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/response"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	moderationVerdictKey = "moderationVerdict"
+	moderationFlaggedKey = "moderationFlagged"
+)
+
+// moderateRequest runs the moderation hooks of the aggregate and of the selected sub-group
+// before the request is forwarded. Their verdicts are recorded in the request log. It returns
+// false when the request was rejected, because its content was blocked or because a hook
+// failing closed could not reach its endpoint.
+func (ps *ProxyServer) moderateRequest(
+	c *gin.Context,
+	originalGroup *models.Group,
+	group *models.Group,
+	channelHandler channel.ChannelProxy,
+	bodyBytes []byte,
+	isStream bool,
+	startTime time.Time,
+) bool {
+	groups := []*models.Group{originalGroup}
+	if group.ID != originalGroup.ID {
+		groups = append(groups, group)
+	}
+
+	var verdicts []string
+	for _, g := range groups {
+		if g.Moderation == nil {
+			continue
+		}
+		record := func(verdict string) {
+			if len(groups) > 1 {
+				verdict = g.Name + ": " + verdict
+			}
+			verdicts = append(verdicts, verdict)
+			c.Set(moderationVerdictKey, strings.Join(verdicts, "; "))
+		}
+
+		verdict, err := ps.moderator.Check(c.Request.Context(), g.Moderation, bodyBytes)
+		if err != nil {
+			logrus.WithError(err).WithField("group", g.Name).Warn("Content moderation failed")
+			if !g.Moderation.FailsClosed() {
+				record(fmt.Sprintf("error (fail open): %v", err))
+				continue
+			}
+			record(fmt.Sprintf("error (fail closed): %v", err))
+			apiErr := app_errors.NewAPIError(app_errors.ErrModerationUnavailable, fmt.Sprintf("Content moderation for group '%s' is unavailable", g.Name))
+			response.Error(c, apiErr)
+			ps.logRequest(c, originalGroup, group, nil, startTime, apiErr.HTTPStatus, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return false
+		}
+
+		record(verdict.String())
+		if !verdict.Flagged {
+			continue
+		}
+		c.Set(moderationFlaggedKey, true)
+		if g.Moderation.Blocks() {
+			response.ErrorWithDetails(c, app_errors.ErrContentBlocked, verdict)
+			ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusBadRequest, app_errors.ErrContentBlocked, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"gpt-load/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// rateLimitHeaderPrefixes start the names of the upstream headers reporting the rate limits
+// of the key a request used, next to Retry-After.
+var rateLimitHeaderPrefixes = []string{"x-ratelimit-", "anthropic-ratelimit-"}
+
+func isRateLimitHeader(name string) bool {
+	name = strings.ToLower(name)
+	if name == "retry-after" {
+		return true
+	}
+	for _, prefix := range rateLimitHeaderPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitHeaderAllowed reports whether a comma separated allowlist of header names, where
+// a trailing * matches a prefix, contains a header.
+func rateLimitHeaderAllowed(allowlist, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range strings.Split(allowlist, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if pattern != "" && pattern == name {
+			return true
+		}
+	}
+	return false
+}
+
+// isRateLimitCount reports whether a rate limit header holds a limit or a remaining count,
+// e.g. x-ratelimit-remaining-tokens or anthropic-ratelimit-requests-limit, rather than a
+// reset time.
+func isRateLimitCount(name string) bool {
+	for _, part := range strings.Split(strings.ToLower(name), "-") {
+		if part == "limit" || part == "remaining" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRateLimitHeaders drops the upstream rate limit headers missing from the allowlist of
+// the group. With rate_limit_headers_aggregate, the limits and remaining counts of the key
+// are scaled to the keys of the group in rotation, an estimate of the capacity of the pool.
+func (ps *ProxyServer) applyRateLimitHeaders(header http.Header, group *models.Group) {
+	cfg := group.EffectiveConfig
+	var activeKeys int64
+	for name, values := range header {
+		if !isRateLimitHeader(name) {
+			continue
+		}
+		if !rateLimitHeaderAllowed(cfg.RateLimitHeaders, name) {
+			header.Del(name)
+			continue
+		}
+		if !cfg.RateLimitHeadersAggregate || !isRateLimitCount(name) {
+			continue
+		}
+		if activeKeys == 0 {
+			count, err := ps.keyPool.ActiveKeyCount(group.ID)
+			if err != nil {
+				logrus.WithError(err).WithField("group", group.Name).Debug("Failed to count active keys for rate limit headers")
+				return
+			}
+			activeKeys = max(count, 1)
+		}
+		for i, value := range values {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				values[i] = strconv.FormatInt(n*activeKeys, 10)
+			}
+		}
+	}
+}
+
+// forwardRateLimitHeaders sends the allowed rate limit headers of a failed upstream response
+// along with the error returned to the client.
+func (ps *ProxyServer) forwardRateLimitHeaders(c *gin.Context, resp *http.Response, group *models.Group) {
+	if resp == nil {
+		return
+	}
+	ps.applyRateLimitHeaders(resp.Header, group)
+	for name, values := range resp.Header {
+		if !isRateLimitHeader(name) {
+			continue
+		}
+		for _, value := range values {
+			c.Writer.Header().Add(name, value)
+		}
+	}
+}
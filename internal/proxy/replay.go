@@ -0,0 +1,213 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/requestid"
+	"gpt-load/internal/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	replayKeyKey = "replayKey"
+	// inProcessKey marks requests sent by gpt-load itself, they bypass the response cache.
+	inProcessKey = "inProcessRequest"
+	// replayUserAgent is sent when the replayed request did not log its user agent.
+	replayUserAgent = "gpt-load-replay"
+)
+
+// Sources of the body of a replayed request.
+const (
+	ReplayBodyFromLog     = "request_log"
+	ReplayBodyFromCapture = "debug_capture"
+)
+
+// ReplayExchange is one side of a replay: the logged outcome or the replayed one.
+type ReplayExchange struct {
+	StatusCode    int    `json:"status_code"`
+	DurationMs    int64  `json:"duration_ms"`
+	Body          string `json:"body"`
+	BodyTruncated bool   `json:"body_truncated,omitempty"`
+}
+
+// ReplayResult puts the logged outcome of a request next to the outcome of its replay.
+type ReplayResult struct {
+	RequestLogID string `json:"request_log_id"`
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	Group        string `json:"group"`
+	// KeyID is the key the replay was forced to use, 0 when a key was selected from the pool.
+	KeyID      uint           `json:"key_id,omitempty"`
+	BodySource string         `json:"body_source"`
+	Original   ReplayExchange `json:"original"`
+	Replay     ReplayExchange `json:"replay"`
+	// Resolved is set when the original request failed and the replay succeeded.
+	Resolved bool `json:"resolved"`
+}
+
+// ReplayOptions describes a replay of a logged request.
+type ReplayOptions struct {
+	Log *models.RequestLog
+	// Capture, when set, provides the method and body of the request, otherwise the body
+	// logged with request body logging is sent.
+	Capture *services.DebugCapture
+	Group   *models.Group
+	// Key forces the key of the replay, a single attempt is made with it.
+	Key *models.APIKey
+	// RemoteAddr is the address of the administrator, logged as the source of the replay.
+	RemoteAddr string
+}
+
+// Replay sends a logged request through the proxy pipeline of a group again, without proxy
+// authentication and response cache, and returns its outcome next to the logged one. The
+// replay is logged like any other request.
+func (ps *ProxyServer) Replay(ctx context.Context, opts ReplayOptions) (*ReplayResult, error) {
+	path, err := replayPath(opts.Log.RequestPath, opts.Group.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ReplayResult{
+		RequestLogID: opts.Log.ID,
+		Path:         path,
+		Group:        opts.Group.Name,
+		BodySource:   ReplayBodyFromLog,
+		Original: ReplayExchange{
+			StatusCode: opts.Log.StatusCode,
+			DurationMs: opts.Log.Duration,
+			Body:       opts.Log.ErrorMessage,
+		},
+	}
+
+	body := []byte(opts.Log.RequestBody)
+	result.Method = http.MethodPost
+	if len(body) == 0 {
+		result.Method = http.MethodGet
+	}
+	if opts.Capture != nil {
+		if opts.Capture.RequestTruncated {
+			return nil, fmt.Errorf("the captured request body was truncated, it cannot be replayed")
+		}
+		body = []byte(opts.Capture.RequestBody)
+		result.Method = opts.Capture.Method
+		result.BodySource = ReplayBodyFromCapture
+		result.Original.Body = opts.Capture.ResponseBody
+		result.Original.BodyTruncated = opts.Capture.ResponseTruncated
+	}
+	var key *models.APIKey
+	if opts.Key != nil {
+		result.KeyID = opts.Key.ID
+		decrypted, err := ps.encryptionSvc.Decrypt(opts.Key.KeyValue)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt key %d: %w", opts.Key.ID, err)
+		}
+		forced := *opts.Key
+		forced.KeyValue = decrypted
+		key = &forced
+	}
+
+	req, err := http.NewRequestWithContext(ctx, result.Method, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.RemoteAddr = opts.RemoteAddr
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("User-Agent", replayUserAgent)
+	if opts.Log.UserAgent != "" {
+		req.Header.Set("User-Agent", opts.Log.UserAgent)
+	}
+
+	start := time.Now()
+	var keys map[string]any
+	if key != nil {
+		keys = map[string]any{replayKeyKey: key}
+	}
+	recorder := ps.serveInProcess(req, opts.Group.Name, keys)
+
+	replayBody, truncated := truncateCapturedBody(string(decodeCapturedBody(recorder.Header(), recorder.body.Bytes())))
+	result.Replay = ReplayExchange{
+		StatusCode:    recorder.status,
+		DurationMs:    time.Since(start).Milliseconds(),
+		Body:          replayBody,
+		BodyTruncated: truncated,
+	}
+	result.Resolved = result.Original.StatusCode >= http.StatusBadRequest && result.Replay.StatusCode < http.StatusBadRequest
+	return result, nil
+}
+
+// serveInProcess runs a request through the proxy pipeline of a group without proxy
+// authentication and response cache, and returns the collected response. The keys are set
+// on the request context, e.g. the key a replay is forced to use. The request keeps the
+// request ID of its context, or gets a new one.
+func (ps *ProxyServer) serveInProcess(req *http.Request, groupName string, keys map[string]any) *replayRecorder {
+	recorder := newReplayRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = req
+	if requestid.FromContext(req.Context()) == "" {
+		c.Request = req.WithContext(requestid.WithID(req.Context(), requestid.New()))
+	}
+	c.Params = gin.Params{
+		{Key: "group_name", Value: groupName},
+		{Key: "path", Value: strings.TrimPrefix(req.URL.Path, "/proxy/"+groupName)},
+	}
+	c.Set(inProcessKey, true)
+	for name, value := range keys {
+		c.Set(name, value)
+	}
+
+	ps.HandleProxy(c)
+	c.Writer.WriteHeaderNow()
+	return recorder
+}
+
+// replayPath points a logged proxy path, e.g. /proxy/openai/v1/chat/completions, at a group.
+func replayPath(loggedPath, groupName string) (string, error) {
+	rest, ok := strings.CutPrefix(loggedPath, "/proxy/")
+	if !ok {
+		return "", fmt.Errorf("request path %q is not a proxy path", loggedPath)
+	}
+	_, rest, _ = strings.Cut(rest, "/")
+	return "/proxy/" + groupName + "/" + rest, nil
+}
+
+// forcedReplayKey returns the key a replay must use with the group, if any.
+func forcedReplayKey(c *gin.Context, group *models.Group) (*models.APIKey, bool) {
+	value, ok := c.Get(replayKeyKey)
+	if !ok {
+		return nil, false
+	}
+	key, ok := value.(*models.APIKey)
+	if !ok || key.GroupID != group.ID {
+		return nil, false
+	}
+	return key, true
+}
+
+// replayRecorder collects the response of a replay. Flush is a no-op so that streamed
+// responses are collected whole.
+type replayRecorder struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func newReplayRecorder() *replayRecorder {
+	return &replayRecorder{header: make(http.Header), status: http.StatusOK}
+}
+
+func (r *replayRecorder) Header() http.Header { return r.header }
+
+func (r *replayRecorder) Write(data []byte) (int, error) { return r.body.Write(data) }
+
+func (r *replayRecorder) WriteHeader(status int) { r.status = status }
+
+func (r *replayRecorder) Flush() {}
@@ -1,13 +1,13 @@
 package proxy
 
 import (
-	"bytes"
-	"compress/gzip"
 	"encoding/json"
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
-	"io"
+	"gpt-load/internal/utils"
+	"math/rand/v2"
 	"net/http"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -42,22 +42,31 @@ func logUpstreamError(context string, err error) {
 	}
 }
 
-// handleGzipCompression checks for gzip encoding and decompresses the body if necessary.
-func handleGzipCompression(resp *http.Response, bodyBytes []byte) []byte {
-	if resp.Header.Get("Content-Encoding") == "gzip" {
-		reader, gzipErr := gzip.NewReader(bytes.NewReader(bodyBytes))
-		if gzipErr != nil {
-			logrus.Warnf("Failed to create gzip reader for error body: %v", gzipErr)
-			return bodyBytes
-		}
-		defer reader.Close()
-
-		decompressedBody, readAllErr := io.ReadAll(reader)
-		if readAllErr != nil {
-			logrus.Warnf("Failed to decompress gzip error body: %v", readAllErr)
-			return bodyBytes
-		}
-		return decompressedBody
-	}
-	return bodyBytes
+// decodeResponseBody decompresses an upstream body in any encoding utils can decode. ok is
+// false when the body is kept as received, still encoded, so that its Content-Encoding stays.
+func decodeResponseBody(resp *http.Response, bodyBytes []byte) (decoded []byte, ok bool) {
+	contentEncoding := resp.Header.Get("Content-Encoding")
+	if contentEncoding == "" {
+		return bodyBytes, true
+	}
+	decoded, err := utils.Decompress(contentEncoding, bodyBytes)
+	if err != nil {
+		logrus.Warnf("Failed to decompress %s response body: %v", contentEncoding, err)
+		return bodyBytes, false
+	}
+	return decoded, true
+}
+
+// retryBackoff returns a random delay in [0, min(base*2^retryCount, max)] milliseconds.
+func retryBackoff(baseMs, maxMs, retryCount int) time.Duration {
+	if baseMs <= 0 {
+		return 0
+	}
+
+	ceiling := int64(baseMs) << min(retryCount, 30)
+	if maxMs > 0 && ceiling > int64(maxMs) {
+		ceiling = int64(maxMs)
+	}
+
+	return time.Duration(rand.Int64N(ceiling+1)) * time.Millisecond
 }
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/services"
+	"gpt-load/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// cachedResponseHeaders lists the response headers that are replayed on cache hits.
+var cachedResponseHeaders = []string{"Content-Type", "Content-Encoding"}
+
+// cachingResponseWriter records the body written to the client so it can be cached afterwards.
+type cachingResponseWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	oversized bool
+}
+
+func (w *cachingResponseWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *cachingResponseWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture buffers data until the body grows beyond what can be cached.
+func (w *cachingResponseWriter) capture(data []byte) {
+	if w.oversized {
+		return
+	}
+	if w.body.Len()+len(data) > services.ResponseCacheMaxBodySize {
+		w.oversized = true
+		w.body.Reset()
+		return
+	}
+	w.body.Write(data)
+}
+
+// serveFromResponseCache writes a cached response if one exists for the request.
+// On a miss it returns the cache key the response should be stored under.
+func (ps *ProxyServer) serveFromResponseCache(c *gin.Context, group *models.Group, bodyBytes []byte) (bool, string) {
+	cacheKey, err := ps.responseCacheService.BuildKey(group, c.Request.Method, c.Request.URL.Path, c.Request.URL.RawQuery, bodyBytes)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"group": group.Name, "error": err}).Warn("Failed to build response cache key")
+		return false, ""
+	}
+
+	cached, ok := ps.responseCacheService.Get(group, cacheKey)
+	if !ok {
+		return false, cacheKey
+	}
+
+	// A body cached compressed for another client is decoded for one that does not accept it
+	body := cached.Body
+	contentEncoding := cached.Header["Content-Encoding"]
+	if contentEncoding != "" && !utils.AcceptsEncoding(c.Request.Header.Get("Accept-Encoding"), contentEncoding) {
+		if decoded, err := utils.Decompress(contentEncoding, body); err == nil {
+			body, contentEncoding = decoded, ""
+		}
+	}
+	for key, value := range cached.Header {
+		if key != "Content-Encoding" {
+			c.Header(key, value)
+		}
+	}
+	if contentEncoding != "" {
+		c.Header("Content-Encoding", contentEncoding)
+	}
+	c.Header("Vary", "Accept-Encoding")
+	c.Header("X-Cache", "HIT")
+	c.Status(cached.StatusCode)
+	if _, err := c.Writer.Write(body); err != nil {
+		logUpstreamError("writing cached response", err)
+	}
+	return true, ""
+}
+
+// captureResponseForCache swaps the gin writer for one that records the response body.
+func captureResponseForCache(c *gin.Context) *cachingResponseWriter {
+	writer := &cachingResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	return writer
+}
+
+// storeResponseInCache caches the captured response if it was a complete success.
+func (ps *ProxyServer) storeResponseInCache(group *models.Group, cacheKey string, writer *cachingResponseWriter) {
+	if writer.oversized || writer.Status() != http.StatusOK {
+		return
+	}
+
+	header := make(map[string]string, len(cachedResponseHeaders))
+	for _, key := range cachedResponseHeaders {
+		if value := writer.Header().Get(key); value != "" {
+			header[key] = value
+		}
+	}
+
+	ps.responseCacheService.Set(group, cacheKey, &services.CachedResponse{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       writer.body.Bytes(),
+	})
+}
@@ -3,42 +3,102 @@ package proxy
 import (
 	"io"
 	"net/http"
+	"strings"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/translate"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
-func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response) {
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("X-Accel-Buffering", "no")
+// groupCORSKey marks requests to a group with its own CORS config, whose CORS headers are
+// set by the proxy rather than copied from the upstream.
+const groupCORSKey = "group_cors"
+
+// writeResponseHeaders copies the upstream status and headers to the client response.
+func writeResponseHeaders(c *gin.Context, resp *http.Response) {
+	_, ownCORS := c.Get(groupCORSKey)
+	for key, values := range resp.Header {
+		if ownCORS && strings.HasPrefix(key, "Access-Control-") {
+			continue
+		}
+		for _, value := range values {
+			c.Header(key, value)
+		}
+	}
+	c.Status(resp.StatusCode)
+}
+
+// handleStreamingResponse relays the upstream stream to the client. Headers are only
+// sent with the first chunk, so a stream that stalls before any data can still be retried.
+// started reports whether anything was sent to the client, err is set when the watchdog aborted the stream.
+// A stream translated to the client format goes through converter, which is nil otherwise.
+func (ps *ProxyServer) handleStreamingResponse(c *gin.Context, resp *http.Response, watchdog *streamWatchdog, converter *translate.StreamConverter) (started bool, err error) {
+	sendHeaders := func() {
+		writeResponseHeaders(c, resp)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		// A translated stream is not the length of the upstream one
+		if converter != nil {
+			c.Writer.Header().Del("Content-Length")
+		}
+	}
 
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
 		logrus.Error("Streaming unsupported by the writer, falling back to normal response")
+		sendHeaders()
 		ps.handleNormalResponse(c, resp)
-		return
+		return true, nil
 	}
 
+	watchdog.start()
+	defer watchdog.stop()
+
 	buf := make([]byte, 4*1024)
 	for {
-		n, err := resp.Body.Read(buf)
+		n, readErr := resp.Body.Read(buf)
 		if n > 0 {
-			if _, writeErr := c.Writer.Write(buf[:n]); writeErr != nil {
+			watchdog.chunk()
+			if !started {
+				sendHeaders()
+				started = true
+			}
+			chunk := buf[:n]
+			if converter != nil {
+				chunk = converter.Write(chunk)
+			}
+			if _, writeErr := c.Writer.Write(chunk); writeErr != nil {
 				logUpstreamError("writing stream to client", writeErr)
-				return
+				return started, nil
 			}
 			flusher.Flush()
 		}
-		if err == io.EOF {
+		if readErr == io.EOF {
 			break
 		}
-		if err != nil {
-			logUpstreamError("reading from upstream", err)
-			return
+		if readErr != nil {
+			if stallErr := watchdog.err(); stallErr != nil {
+				return started, stallErr
+			}
+			logUpstreamError("reading from upstream", readErr)
+			return started, nil
 		}
 	}
+
+	if !started {
+		sendHeaders()
+		c.Writer.WriteHeaderNow()
+	}
+	if converter != nil {
+		if _, err := c.Writer.Write(converter.Close()); err != nil {
+			logUpstreamError("writing stream to client", err)
+		}
+	}
+	return true, nil
 }
 
 func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response) {
@@ -46,3 +106,75 @@ func (ps *ProxyServer) handleNormalResponse(c *gin.Context, resp *http.Response)
 		logUpstreamError("copying response body", err)
 	}
 }
+
+// handleTransformedResponse buffers the upstream body and rewrites it with the channel's transformer,
+// the translation to the client format or both, applied in order.
+func (ps *ProxyServer) handleTransformedResponse(c *gin.Context, resp *http.Response, transforms ...func([]byte) ([]byte, error)) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return
+	}
+
+	// A body that cannot be decoded cannot be transformed either, it is sent as received
+	bodyBytes, decoded := decodeResponseBody(resp, bodyBytes)
+	if !decoded {
+		if _, err := c.Writer.Write(bodyBytes); err != nil {
+			logUpstreamError("writing response body", err)
+		}
+		return
+	}
+	transformed := bodyBytes
+	for _, transform := range transforms {
+		if transformed, err = transform(transformed); err != nil {
+			logrus.Warnf("Failed to transform response body, returning it unchanged: %v", err)
+			transformed = bodyBytes
+			break
+		}
+	}
+
+	// The body is sent decompressed and its length may have changed
+	c.Writer.Header().Del("Content-Encoding")
+	c.Writer.Header().Del("Content-Length")
+	if _, err := c.Writer.Write(transformed); err != nil {
+		logUpstreamError("writing response body", err)
+	}
+}
+
+// handleSimulatedStream buffers the complete upstream response and sends it to the client as the
+// stream it would have been, translated to the client format by converter unless it is nil.
+// A response that cannot be converted is sent unchanged.
+func (ps *ProxyServer) handleSimulatedStream(c *gin.Context, resp *http.Response, simulator channel.StreamSimulator, converter *translate.StreamConverter) {
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logUpstreamError("reading response body", err)
+		return
+	}
+
+	writeResponseHeaders(c, resp)
+	bodyBytes, decoded := decodeResponseBody(resp, bodyBytes)
+	if !decoded {
+		if _, err := c.Writer.Write(bodyBytes); err != nil {
+			logUpstreamError("writing response body", err)
+		}
+		return
+	}
+	c.Writer.Header().Del("Content-Encoding")
+	c.Writer.Header().Del("Content-Length")
+
+	stream, contentType, err := simulator.SimulateStream(c.Request, bodyBytes)
+	if err != nil {
+		logrus.Warnf("Failed to simulate a stream from the response, returning it unchanged: %v", err)
+		stream = bodyBytes
+	} else {
+		if converter != nil {
+			stream = append(converter.Write(stream), converter.Close()...)
+		}
+		c.Header("Content-Type", contentType)
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+	}
+	if _, err := c.Writer.Write(stream); err != nil {
+		logUpstreamError("writing response body", err)
+	}
+}
@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const upstreamRetryAfterKey = "upstreamRetryAfter"
+
+// recordRetryAfter remembers the earliest time at which one of the keys attempted for this
+// request is expected to be accepted again by its upstream.
+func recordRetryAfter(c *gin.Context, until time.Time) {
+	if earliest, ok := c.Get(upstreamRetryAfterKey); ok && !until.Before(earliest.(time.Time)) {
+		return
+	}
+	c.Set(upstreamRetryAfterKey, until)
+}
+
+// applyRetryAfter sets a Retry-After header on a final rate limit failure, in whole seconds
+// until the earliest recorded reset, so that clients back off instead of retrying at once.
+func applyRetryAfter(c *gin.Context, now time.Time) {
+	earliest, ok := c.Get(upstreamRetryAfterKey)
+	if !ok {
+		return
+	}
+	seconds := max(int(math.Ceil(earliest.(time.Time).Sub(now).Seconds())), 1)
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
@@ -13,12 +13,20 @@ import (
 
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
+	"gpt-load/internal/credentials"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/features"
+	"gpt-load/internal/inflight"
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
+	"gpt-load/internal/moderation"
+	"gpt-load/internal/providerstatus"
+	"gpt-load/internal/requestid"
 	"gpt-load/internal/response"
 	"gpt-load/internal/services"
+	"gpt-load/internal/translate"
+	"gpt-load/internal/upstreamstats"
 	"gpt-load/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -27,33 +35,62 @@ import (
 
 // ProxyServer represents the proxy server
 type ProxyServer struct {
-	keyProvider       *keypool.KeyProvider
-	groupManager      *services.GroupManager
-	subGroupManager   *services.SubGroupManager
-	settingsManager   *config.SystemSettingsManager
-	channelFactory    *channel.Factory
-	requestLogService *services.RequestLogService
-	encryptionSvc     encryption.Service
+	keyPool              keypool.KeyPool
+	groupManager         *services.GroupManager
+	subGroupManager      *services.SubGroupManager
+	settingsManager      *config.SystemSettingsManager
+	channelFactory       *channel.Factory
+	requestLogService    *services.RequestLogService
+	budgetService        *services.BudgetService
+	responseCacheService *services.ResponseCacheService
+	debugCaptureService  *services.DebugCaptureService
+	providerStatus       *providerstatus.Monitor
+	inFlight             *inflight.Registry
+	encryptionSvc        encryption.Service
+	credentials          *credentials.Manager
+	modelDiscovery       *services.ModelDiscoveryService
+	moderator            *moderation.Moderator
+	upstreamStats        *upstreamstats.Tracker
+	mirrorSlots          chan struct{}
 }
 
 // NewProxyServer creates a new proxy server
 func NewProxyServer(
-	keyProvider *keypool.KeyProvider,
+	keyPool keypool.KeyPool,
 	groupManager *services.GroupManager,
 	subGroupManager *services.SubGroupManager,
 	settingsManager *config.SystemSettingsManager,
 	channelFactory *channel.Factory,
 	requestLogService *services.RequestLogService,
+	budgetService *services.BudgetService,
+	responseCacheService *services.ResponseCacheService,
+	debugCaptureService *services.DebugCaptureService,
+	providerStatus *providerstatus.Monitor,
+	inFlight *inflight.Registry,
 	encryptionSvc encryption.Service,
+	credentials *credentials.Manager,
+	modelDiscovery *services.ModelDiscoveryService,
+	moderator *moderation.Moderator,
+	upstreamStats *upstreamstats.Tracker,
 ) (*ProxyServer, error) {
 	return &ProxyServer{
-		keyProvider:       keyProvider,
-		groupManager:      groupManager,
-		subGroupManager:   subGroupManager,
-		settingsManager:   settingsManager,
-		channelFactory:    channelFactory,
-		requestLogService: requestLogService,
-		encryptionSvc:     encryptionSvc,
+		keyPool:              keyPool,
+		groupManager:         groupManager,
+		subGroupManager:      subGroupManager,
+		settingsManager:      settingsManager,
+		channelFactory:       channelFactory,
+		requestLogService:    requestLogService,
+		budgetService:        budgetService,
+		responseCacheService: responseCacheService,
+		debugCaptureService:  debugCaptureService,
+		providerStatus:       providerStatus,
+		inFlight:             inFlight,
+		encryptionSvc:        encryptionSvc,
+		credentials:          credentials,
+		modelDiscovery:       modelDiscovery,
+		moderator:            moderator,
+		upstreamStats:        upstreamStats,
+		mirrorSlots:          make(chan struct{}, maxInFlightMirrors),
 	}, nil
 }
 
@@ -67,11 +104,36 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		response.Error(c, app_errors.ParseDBError(err))
 		return
 	}
+	// A disabled group is in maintenance, it answers without touching its keys
+	if !originalGroup.Enabled {
+		apiErr := app_errors.ErrGroupDisabled
+		if originalGroup.MaintenanceMessage != "" {
+			apiErr = app_errors.NewAPIError(app_errors.ErrGroupDisabled, originalGroup.MaintenanceMessage)
+		}
+		response.Error(c, apiErr)
+		return
+	}
+	if !checkEndpointAllowed(c, originalGroup) {
+		return
+	}
+	if originalGroup.CORS != nil {
+		c.Set(groupCORSKey, true)
+	}
+
+	// /v1/models is answered from the discovered models of the group, merged across sub-groups
+	if isDiscoveredModelList(c) {
+		ps.serveDiscoveredModelList(c, originalGroup)
+		return
+	}
+
+	if !limitRequestBody(c, originalGroup) {
+		return
+	}
 
 	// Select sub-group if this is an aggregate group
 	subGroupName, err := ps.subGroupManager.SelectSubGroup(originalGroup)
 	if err != nil {
-		logrus.WithFields(logrus.Fields{
+		logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
 			"aggregate_group": originalGroup.Name,
 			"error":           err,
 		}).Error("Failed to select sub-group from aggregate")
@@ -86,6 +148,10 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 			response.Error(c, app_errors.ParseDBError(err))
 			return
 		}
+		// 子分组的端点限制同样生效，避免通过聚合分组绕过
+		if !checkEndpointAllowed(c, group) {
+			return
+		}
 	}
 
 	channelHandler, err := ps.channelFactory.GetChannel(group)
@@ -93,10 +159,20 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to get channel for group '%s': %v", groupName, err)))
 		return
 	}
+	selectFormatTranslation(c, originalGroup, group)
+
+	if err := applyRequestDeadline(c, startTime); err != nil {
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+		return
+	}
 
 	bodyBytes, err := io.ReadAll(c.Request.Body)
+	if maxBytesErr := (*http.MaxBytesError)(nil); errors.As(err, &maxBytesErr) {
+		response.Error(c, app_errors.ErrRequestTooLarge)
+		return
+	}
 	if err != nil {
-		logrus.Errorf("Failed to read request body: %v", err)
+		logrus.WithContext(c.Request.Context()).Errorf("Failed to read request body: %v", err)
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, "Failed to read request body"))
 		return
 	}
@@ -108,9 +184,65 @@ func (ps *ProxyServer) HandleProxy(c *gin.Context) {
 		return
 	}
 
+	// The model policies of both the aggregate and the selected sub-group apply
+	model := channelHandler.ExtractModel(c, bodyBytes)
+	if !checkModelAllowed(c, originalGroup, model) || (group.ID != originalGroup.ID && !checkModelAllowed(c, group, model)) {
+		return
+	}
+
+	// Like model policies, the guardrails of the aggregate apply before those of the sub-group
+	var ok bool
+	if finalBodyBytes, ok = applyGuardrails(c, originalGroup, finalBodyBytes); !ok {
+		return
+	}
+	if group.ID != originalGroup.ID {
+		if finalBodyBytes, ok = applyGuardrails(c, group, finalBodyBytes); !ok {
+			return
+		}
+	}
+
 	isStream := channelHandler.IsStreamRequest(c, bodyBytes)
+	ps.inFlight.Update(c.Request.Context(), func(r *inflight.Request) {
+		if group.ID != originalGroup.ID {
+			r.SubGroup = group.Name
+		}
+		r.Model = model
+		r.IsStream = isStream
+	})
+
+	if !ps.moderateRequest(c, originalGroup, group, channelHandler, finalBodyBytes, isStream, startTime) {
+		return
+	}
+	ps.mirrorRequest(c, originalGroup, bodyBytes)
+
+	// Identical non-streaming requests are answered from the cache without consuming a key
+	var cacheKey string
+	if !isStream && !c.GetBool(inProcessKey) && ps.responseCacheService.Enabled(originalGroup) {
+		var hit bool
+		if hit, cacheKey = ps.serveFromResponseCache(c, originalGroup, bodyBytes); hit {
+			return
+		}
+	}
+
+	// Soft budget: overflow requests are still served, only tagged for chargeback attribution
+	if ps.budgetService.Consume(originalGroup) {
+		c.Set("budgetOverflow", true)
+	}
+
+	if ps.debugCaptureService.ShouldCapture(group) {
+		c.Set(debugCaptureSampledKey, true)
+	}
+
+	var cacheWriter *cachingResponseWriter
+	if cacheKey != "" {
+		cacheWriter = captureResponseForCache(c)
+	}
 
 	ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, finalBodyBytes, isStream, startTime, 0)
+
+	if cacheWriter != nil {
+		ps.storeResponseInCache(originalGroup, cacheKey, cacheWriter)
+	}
 }
 
 // executeRequestWithRetry is the core recursive function for handling requests and retries.
@@ -126,33 +258,84 @@ func (ps *ProxyServer) executeRequestWithRetry(
 ) {
 	cfg := group.EffectiveConfig
 
-	apiKey, err := ps.keyProvider.SelectKey(group.ID)
+	// 客户端的请求预算已用完时不再发起新的尝试
+	deadline, hasDeadline := requestDeadline(c)
+	if hasDeadline && !time.Now().Before(deadline) {
+		ps.rejectExhaustedDeadline(c, channelHandler, originalGroup, group, nil, bodyBytes, "", isStream, startTime)
+		return
+	}
+
+	// 按模型限流时，Key 的冷却以 (Key, 模型) 为维度，选 Key 时跳过该模型冷却中的 Key
+	var rateLimitModel string
+	if cfg.RateLimitPerModel {
+		rateLimitModel = channelHandler.ExtractModel(c, bodyBytes)
+	}
+
+	// A replay forced to a key makes a single attempt with it
+	var err error
+	apiKey, forcedKey := forcedReplayKey(c, group)
+	if !forcedKey {
+		apiKey, err = ps.keyPool.SelectKey(group, rateLimitModel)
+	}
 	if err != nil {
-		logrus.Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
+		logrus.WithContext(c.Request.Context()).Errorf("Failed to select a key for group %s on attempt %d: %v", group.Name, retryCount+1, err)
+		applyRetryAfter(c, time.Now())
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrNoKeysAvailable, err.Error()))
 		ps.logRequest(c, originalGroup, group, nil, startTime, http.StatusServiceUnavailable, err, isStream, "", channelHandler, bodyBytes, models.RequestTypeFinal)
 		return
 	}
 
+	ps.inFlight.Update(c.Request.Context(), func(r *inflight.Request) {
+		r.KeyHash = ps.encryptionSvc.Hash(apiKey.KeyValue)
+		r.Attempt = retryCount + 1
+	})
+
+	translation := formatTranslation(c)
 	upstreamURL, err := channelHandler.BuildUpstreamURL(c.Request.URL, originalGroup.Name)
+	if err == nil && translation != nil {
+		upstreamURL, err = translateUpstreamURL(upstreamURL, translation)
+	}
 	if err != nil {
 		response.Error(c, app_errors.NewAPIError(app_errors.ErrInternalServer, fmt.Sprintf("Failed to build upstream URL: %v", err)))
 		return
 	}
 
+	// A simulated stream is a non-streaming upstream call
+	simulator, simulateStream := ps.streamSimulator(channelHandler, group, isStream)
+	upstreamStream := isStream && !simulateStream
+
 	var ctx context.Context
 	var cancel context.CancelFunc
-	if isStream {
+	if upstreamStream {
 		ctx, cancel = context.WithCancel(c.Request.Context())
 	} else {
 		timeout := time.Duration(cfg.RequestTimeout) * time.Second
 		ctx, cancel = context.WithTimeout(c.Request.Context(), timeout)
 	}
 	defer cancel()
+	if hasDeadline {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		defer cancelDeadline()
+	}
+
+	// Keys holding long-lived credentials authenticate with an access token minted from them
+	authKey, err := ps.credentials.AuthKey(ctx, channelHandler.GetHTTPClient(), group, apiKey)
+	if err != nil {
+		logrus.WithContext(c.Request.Context()).WithError(err).Warnf("Failed to get an access token for key %s in group %s", utils.MaskAPIKey(apiKey.KeyValue), group.Name)
+		ps.keyPool.UpdateStatus(apiKey, group, false, 0, err.Error())
+		if retryCount < cfg.MaxRetries {
+			ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
+			return
+		}
+		response.Error(c, app_errors.NewAPIError(app_errors.ErrBadGateway, err.Error()))
+		ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadGateway, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+		return
+	}
 
 	req, err := http.NewRequestWithContext(ctx, c.Request.Method, upstreamURL, bytes.NewReader(bodyBytes))
 	if err != nil {
-		logrus.Errorf("Failed to create upstream request: %v", err)
+		logrus.WithContext(c.Request.Context()).Errorf("Failed to create upstream request: %v", err)
 		response.Error(c, app_errors.ErrInternalServer)
 		return
 	}
@@ -164,6 +347,13 @@ func (ps *ProxyServer) executeRequestWithRetry(
 	req.Header.Del("Authorization")
 	req.Header.Del("X-Api-Key")
 	req.Header.Del("X-Goog-Api-Key")
+	req.Header.Del(requestDeadlineHeader)
+
+	// Pass the request ID on, for correlating the logs of the upstream with ours
+	req.Header.Del(requestid.Header)
+	if header := group.EffectiveConfig.RequestIDHeader; header != "" {
+		req.Header.Set(header, requestid.FromContext(c.Request.Context()))
+	}
 
 	// Apply model redirection
 	finalBodyBytes, err := channelHandler.ApplyModelRedirect(req, bodyBytes, group)
@@ -173,39 +363,101 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		return
 	}
 
-	// Update request body if it was modified by redirection
+	// Translate the request to the upstream format, which the channel's own transformations expect
+	if translation != nil {
+		finalBodyBytes, err = translation.Request(finalBodyBytes)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	// Apply channel-defined request transformations
+	if transformer, ok := channelHandler.(channel.RequestBodyTransformer); ok && group.FeatureEnabled(features.BodyTransforms) {
+		finalBodyBytes, err = transformer.TransformRequestBody(finalBodyBytes)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+	}
+
+	if simulateStream {
+		finalBodyBytes, err = simulator.DisableStream(req, finalBodyBytes)
+		if err != nil {
+			response.Error(c, app_errors.NewAPIError(app_errors.ErrBadRequest, err.Error()))
+			ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusBadRequest, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+			return
+		}
+		req.Header.Set("Accept", "application/json")
+	}
+
+	// Responses that are rewritten must come in an encoding that can be decoded, translated
+	// streams unencoded as they are converted chunk by chunk
+	if translation != nil && upstreamStream {
+		req.Header.Del("Accept-Encoding")
+	} else if responseRewritten(c, channelHandler, group, simulateStream) {
+		req.Header.Set("Accept-Encoding", utils.DecodableEncodings)
+	}
+
+	// Update request body if it was modified by redirection, transformation or stream simulation
 	if !bytes.Equal(finalBodyBytes, bodyBytes) {
 		req.Body = io.NopCloser(bytes.NewReader(finalBodyBytes))
 		req.ContentLength = int64(len(finalBodyBytes))
 	}
 
-	channelHandler.ModifyRequest(req, apiKey, group)
+	channelHandler.ModifyRequest(req, authKey, group)
+	channelHandler.ApplyUpstreamAuth(req)
 
-	// Apply custom header rules
+	// Apply custom header rules, after the client and channel headers so that rules take precedence
 	if len(group.HeaderRuleList) > 0 {
+		if apiKey.KeyHash == "" {
+			apiKey.KeyHash = ps.encryptionSvc.Hash(apiKey.KeyValue)
+		}
 		headerCtx := utils.NewHeaderVariableContextFromGin(c, group, apiKey)
 		utils.ApplyHeaderRules(req, group.HeaderRuleList, headerCtx)
 	}
 
 	var client *http.Client
-	if isStream {
+	if upstreamStream {
 		client = channelHandler.GetStreamClient()
 		req.Header.Set("X-Accel-Buffering", "no")
 	} else {
 		client = channelHandler.GetHTTPClient()
 	}
 
+	capture := startDebugCapture(c, req, finalBodyBytes, apiKey)
+
+	req, timing := upstreamstats.Trace(req)
+	upstreamStart := time.Now()
 	resp, err := client.Do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 
+	// The client's budget ran out, which says nothing about the key or the upstream
+	if err != nil && hasDeadline && errors.Is(err, context.DeadlineExceeded) && !time.Now().Before(deadline) {
+		ps.rejectExhaustedDeadline(c, channelHandler, originalGroup, group, apiKey, bodyBytes, upstreamURL, isStream, startTime)
+		return
+	}
+
+	// Feed the upstream circuit breaker and health score; client-side aborts say nothing about upstream health
+	if !app_errors.IsIgnorableError(err) {
+		channelHandler.ReportUpstreamResult(upstreamURL, err == nil && resp.StatusCode < http.StatusInternalServerError, time.Since(upstreamStart))
+	}
+
 	// Unified error handling for retries.
 	// Retry policy is fully defined by group.FailoverStatusCodeMatcher (derived from EffectiveConfig).
-	shouldRetryByStatus := resp != nil && shouldFailoverOnStatusCode(resp.StatusCode, group)
+	shouldRetryByStatus := resp != nil && shouldFailover(resp, group, channelHandler)
 	if err != nil || shouldRetryByStatus {
 		if err != nil && app_errors.IsIgnorableError(err) {
-			logrus.Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+			if inflight.IsCancelled(c.Request.Context()) {
+				logrus.WithContext(c.Request.Context()).Infof("Request to group %s cancelled by an administrator", group.Name)
+				response.Error(c, app_errors.ErrRequestCancelled)
+			} else {
+				logrus.WithContext(c.Request.Context()).Debugf("Client-side ignorable error for key %s, aborting retries: %v", utils.MaskAPIKey(apiKey.KeyValue), err)
+			}
 			ps.logRequest(c, originalGroup, group, apiKey, startTime, 499, err, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 			return
 		}
@@ -213,37 +465,68 @@ func (ps *ProxyServer) executeRequestWithRetry(
 		var statusCode int
 		var errorMessage string
 		var parsedError string
+		var rateLimitedUntil time.Time
 
 		if err != nil {
+			ps.upstreamStats.Record(group.Name, channelHandler.UpstreamBaseURL(upstreamURL), timing, 0)
 			statusCode = 500
 			errorMessage = err.Error()
 			parsedError = errorMessage
-			logrus.Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
+			logrus.WithContext(c.Request.Context()).Debugf("Request failed (attempt %d/%d) for key %s: %v", retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), err)
 		} else {
 			// Retryable upstream response (HTTP status code matched failover policy)
 			statusCode = resp.StatusCode
 			errorBody, readErr := io.ReadAll(resp.Body)
 			if readErr != nil {
-				logrus.Errorf("Failed to read error body: %v", readErr)
+				logrus.WithContext(c.Request.Context()).Errorf("Failed to read error body: %v", readErr)
 				errorBody = []byte("Failed to read error body")
 			}
 
-			errorBody = handleGzipCompression(resp, errorBody)
+			errorBody, _ = decodeResponseBody(resp, errorBody)
+			ps.upstreamStats.Record(group.Name, channelHandler.UpstreamBaseURL(upstreamURL), timing, statusCode)
+			if statusCode == http.StatusTooManyRequests {
+				maxCooldown := time.Duration(cfg.RateLimitMaxCooldown) * time.Second
+				if cooldown := keypool.RateLimitCooldown(resp.Header, errorBody, time.Now(), maxCooldown); cooldown > 0 {
+					until := time.Now().Add(cooldown)
+					recordRetryAfter(c, until)
+					if group.FeatureEnabled(features.RateLimitCooldown) {
+						rateLimitedUntil = until
+					}
+				}
+			}
 			errorMessage = string(errorBody)
 			parsedError = app_errors.ParseUpstreamError(errorBody)
-			logrus.Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
+			logrus.WithContext(c.Request.Context()).Debugf("Request failed with status %d (attempt %d/%d) for key %s. Parsed Error: %s", statusCode, retryCount+1, cfg.MaxRetries, utils.MaskAPIKey(apiKey.KeyValue), parsedError)
 		}
 
 		// 上游 key 可能出现在错误文本中（如 Gemini 通道将 key 放入 URL query，
 		// 传输层错误会把完整 URL 带入 err.Error()），返回客户端和落库前先脱敏
 		errorMessage = utils.RedactSecret(errorMessage, apiKey.KeyValue)
 		parsedError = utils.RedactSecret(parsedError, apiKey.KeyValue)
+		captureResponse(capture, resp, []byte(errorMessage), false, apiKey)
+
+		// 上游给出了限流重置时间时，Key 冷却到配额恢复为止，不计入失败次数；否则使用解析后的错误信息更新密钥状态
+		switch {
+		case !rateLimitedUntil.IsZero() && rateLimitModel != "":
+			ps.keyPool.CoolDownModel(apiKey, rateLimitModel, rateLimitedUntil)
+		case !rateLimitedUntil.IsZero():
+			ps.keyPool.CoolDown(apiKey, group, rateLimitedUntil)
+		case (err != nil || statusCode >= http.StatusInternalServerError) && ps.providerOutage(upstreamURL) != nil:
+			logrus.WithContext(c.Request.Context()).Debugf("Provider outage in progress, not counting the failure against key %s", utils.MaskAPIKey(apiKey.KeyValue))
+		default:
+			ps.keyPool.UpdateStatus(apiKey, group, false, statusCode, parsedError)
+		}
 
-		// 使用解析后的错误信息更新密钥状态
-		ps.keyProvider.UpdateStatus(apiKey, group, false, parsedError)
-
-		// 判断是否为最后一次尝试
-		isLastAttempt := retryCount >= cfg.MaxRetries
+		// 判断是否为最后一次尝试；剩余的请求预算不足以完成下一次尝试（按本次耗时估算）时也不再重试
+		isLastAttempt := retryCount >= cfg.MaxRetries || forcedKey
+		var delay time.Duration
+		if !isLastAttempt {
+			delay = retryBackoff(cfg.RetryBackoffBaseMs, cfg.RetryBackoffMaxMs, retryCount)
+			if !withinBudget(c, delay+time.Since(upstreamStart)) {
+				logrus.WithContext(c.Request.Context()).Debugf("Request deadline leaves no time for another attempt in group %s, skipping retries", group.Name)
+				isLastAttempt = true
+			}
+		}
 		requestType := models.RequestTypeRetry
 		if isLastAttempt {
 			requestType = models.RequestTypeFinal
@@ -253,6 +536,10 @@ func (ps *ProxyServer) executeRequestWithRetry(
 
 		// 如果是最后一次尝试，直接返回错误，不再递归
 		if isLastAttempt {
+			ps.forwardRateLimitHeaders(c, resp, group)
+			if statusCode == http.StatusTooManyRequests {
+				applyRetryAfter(c, time.Now())
+			}
 			var errorJSON map[string]any
 			if err := json.Unmarshal([]byte(errorMessage), &errorJSON); err == nil {
 				c.JSON(statusCode, errorJSON)
@@ -262,34 +549,197 @@ func (ps *ProxyServer) executeRequestWithRetry(
 			return
 		}
 
+		// Exponential backoff with full jitter before the next attempt
+		if delay > 0 {
+			select {
+			case <-time.After(delay):
+			case <-c.Request.Context().Done():
+				logrus.WithContext(c.Request.Context()).Debugf("Client canceled during retry backoff for group %s", group.Name)
+				return
+			}
+		}
+
 		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, isStream, startTime, retryCount+1)
 		return
 	}
 
-	// ps.keyProvider.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
-	logrus.Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	// ps.keyPool.UpdateStatus(apiKey, group, true) // 请求成功不再重置成功次数，减少IO消耗
+	logrus.WithContext(c.Request.Context()).Debugf("Request for group %s succeeded on attempt %d with key %s", group.Name, retryCount+1, utils.MaskAPIKey(apiKey.KeyValue))
+	ps.applyRateLimitHeaders(resp.Header, group)
+
+	compressor := startResponseCompression(c, cfg.CompressResponses)
+	var captureWriter *debugCaptureWriter
+	if capture != nil {
+		captureWriter = &debugCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = captureWriter
+	}
+	finishCapture := func() {
+		if captureWriter != nil {
+			c.Writer = captureWriter.ResponseWriter
+			captureResponse(capture, resp, captureWriter.body.Bytes(), captureWriter.truncated, apiKey)
+			captureWriter = nil
+		}
+		compressor.finish(c)
+		compressor = nil
+	}
 
 	// Check if this is a model list request (needs special handling)
 	if shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
 		ps.handleModelListResponse(c, resp, group, channelHandler)
-	} else {
-		for key, values := range resp.Header {
-			for _, value := range values {
-				c.Header(key, value)
-			}
+	} else if simulateStream {
+		ps.handleSimulatedStream(c, resp, simulator, streamConverter(translation, resp, bodyBytes))
+	} else if isStream {
+		started, stallErr := ps.handleStreamingResponse(c, resp, newStreamWatchdog(cfg, cancel), streamConverter(translation, resp, bodyBytes))
+		finishCapture()
+		if stallErr != nil {
+			ps.upstreamStats.Record(group.Name, channelHandler.UpstreamBaseURL(upstreamURL), timing, http.StatusGatewayTimeout)
+			ps.handleStalledStream(c, channelHandler, originalGroup, group, apiKey, bodyBytes, upstreamURL, startTime, retryCount, started, stallErr)
+			return
+		}
+		if !started && hasDeadline && errors.Is(ctx.Err(), context.DeadlineExceeded) && !time.Now().Before(deadline) {
+			ps.rejectExhaustedDeadline(c, channelHandler, originalGroup, group, apiKey, bodyBytes, upstreamURL, isStream, startTime)
+			return
 		}
-		c.Status(resp.StatusCode)
+	} else {
+		writeResponseHeaders(c, resp)
 
-		if isStream {
-			ps.handleStreamingResponse(c, resp)
+		// Error responses are passed through as the upstream sent them
+		var transforms []func([]byte) ([]byte, error)
+		if transformer, ok := channelHandler.(channel.ResponseBodyTransformer); ok && resp.StatusCode < 400 && group.FeatureEnabled(features.BodyTransforms) {
+			transforms = append(transforms, transformer.TransformResponseBody)
+		}
+		if translation != nil && resp.StatusCode < 400 {
+			transforms = append(transforms, translation.Response)
+		}
+		if len(transforms) > 0 {
+			ps.handleTransformedResponse(c, resp, transforms...)
 		} else {
 			ps.handleNormalResponse(c, resp)
 		}
 	}
 
+	finishCapture()
+	ps.upstreamStats.Record(group.Name, channelHandler.UpstreamBaseURL(upstreamURL), timing, resp.StatusCode)
 	ps.logRequest(c, originalGroup, group, apiKey, startTime, resp.StatusCode, nil, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
 }
 
+// responseRewritten reports whether the response body of a request is decoded and rewritten
+// by the proxy rather than passed through as the upstream sent it.
+func responseRewritten(c *gin.Context, channelHandler channel.ChannelProxy, group *models.Group, simulateStream bool) bool {
+	if simulateStream || formatTranslation(c) != nil || shouldInterceptModelList(c.Request.URL.Path, c.Request.Method) {
+		return true
+	}
+	_, ok := channelHandler.(channel.ResponseBodyTransformer)
+	return ok && group.FeatureEnabled(features.BodyTransforms)
+}
+
+// streamConverter returns the converter of a successful upstream stream to the client format of a
+// translated request, nil when there is nothing to convert.
+func streamConverter(translation *translate.Translation, resp *http.Response, clientRequest []byte) *translate.StreamConverter {
+	if translation == nil || resp.StatusCode >= 400 {
+		return nil
+	}
+	return translation.NewStream(clientRequest)
+}
+
+// streamSimulator returns the stream simulator of the channel when a streaming request is served
+// from a non-streaming upstream call, as set for the group or by the global kill switch.
+func (ps *ProxyServer) streamSimulator(channelHandler channel.ChannelProxy, group *models.Group, isStream bool) (channel.StreamSimulator, bool) {
+	if !isStream || !(group.EffectiveConfig.SimulateStreaming || ps.settingsManager.GetSettings().StreamingKillSwitch) {
+		return nil, false
+	}
+	simulator, ok := channelHandler.(channel.StreamSimulator)
+	return simulator, ok
+}
+
+// handleStalledStream records a stream aborted by the watchdog as a failed attempt and
+// retries on another key when enabled and nothing was sent to the client yet.
+func (ps *ProxyServer) handleStalledStream(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	apiKey *models.APIKey,
+	bodyBytes []byte,
+	upstreamURL string,
+	startTime time.Time,
+	retryCount int,
+	started bool,
+	stallErr error,
+) {
+	cfg := group.EffectiveConfig
+
+	logrus.WithContext(c.Request.Context()).WithFields(logrus.Fields{
+		"group":   group.Name,
+		"key":     utils.MaskAPIKey(apiKey.KeyValue),
+		"started": started,
+		"error":   stallErr,
+	}).Warn("Upstream stream aborted by watchdog")
+
+	channelHandler.ReportUpstreamResult(upstreamURL, false, time.Since(startTime))
+	ps.keyPool.UpdateStatus(apiKey, group, false, 0, stallErr.Error())
+
+	canRetry := !started && cfg.StreamRetryOnStall && retryCount < cfg.MaxRetries && c.Request.Context().Err() == nil && withinBudget(c, 0)
+	requestType := models.RequestTypeFinal
+	if canRetry {
+		requestType = models.RequestTypeRetry
+	}
+	ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusGatewayTimeout, stallErr, true, upstreamURL, channelHandler, bodyBytes, requestType)
+
+	if canRetry {
+		ps.executeRequestWithRetry(c, channelHandler, originalGroup, group, bodyBytes, true, startTime, retryCount+1)
+		return
+	}
+
+	if !started {
+		response.Error(c, app_errors.NewAPIErrorWithUpstream(http.StatusGatewayTimeout, "UPSTREAM_STREAM_TIMEOUT", stallErr.Error()))
+	}
+}
+
+// rejectExhaustedDeadline answers a request whose client deadline has passed with a distinct 504.
+func (ps *ProxyServer) rejectExhaustedDeadline(
+	c *gin.Context,
+	channelHandler channel.ChannelProxy,
+	originalGroup *models.Group,
+	group *models.Group,
+	apiKey *models.APIKey,
+	bodyBytes []byte,
+	upstreamURL string,
+	isStream bool,
+	startTime time.Time,
+) {
+	logrus.WithContext(c.Request.Context()).Debugf("Request deadline exceeded for group %s after %v", group.Name, time.Since(startTime))
+	response.Error(c, app_errors.ErrRequestDeadlineExceeded)
+	ps.logRequest(c, originalGroup, group, apiKey, startTime, http.StatusGatewayTimeout, app_errors.ErrRequestDeadlineExceeded, isStream, upstreamURL, channelHandler, bodyBytes, models.RequestTypeFinal)
+}
+
+// shouldFailover lets channels that classify errors themselves decide first,
+// falling back to the group's failover status codes.
+func shouldFailover(resp *http.Response, group *models.Group, channelHandler channel.ChannelProxy) bool {
+	classifier, ok := channelHandler.(channel.ErrorClassifier)
+	if !ok || resp.StatusCode < 400 {
+		return shouldFailoverOnStatusCode(resp.StatusCode, group)
+	}
+
+	// Buffer the body so it can still be returned to the client or parsed for logging
+	errorBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logrus.Errorf("Failed to read error body for classification: %v", err)
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(errorBody))
+
+	decodedBody, _ := decodeResponseBody(resp, errorBody)
+	switch classifier.ClassifyError(resp.StatusCode, decodedBody) {
+	case channel.ErrorActionFailover:
+		return true
+	case channel.ErrorActionPassthrough:
+		return false
+	default:
+		return shouldFailoverOnStatusCode(resp.StatusCode, group)
+	}
+}
+
 func shouldFailoverOnStatusCode(statusCode int, group *models.Group) bool {
 	if group == nil {
 		return false
@@ -297,6 +747,18 @@ func shouldFailoverOnStatusCode(statusCode int, group *models.Group) bool {
 	return group.FailoverStatusCodeMatcher.Match(statusCode)
 }
 
+// providerOutage returns the confirmed outage of the provider serving upstreamURL, if any.
+// Server-side failures during an outage are not the fault of the key.
+func (ps *ProxyServer) providerOutage(upstreamURL string) *providerstatus.Incident {
+	if ps.providerStatus == nil {
+		return nil
+	}
+	if incident := ps.providerStatus.IncidentFor(upstreamURL); incident != nil && incident.IsOutage() {
+		return incident
+	}
+	return nil
+}
+
 // logRequest is a helper function to create and record a request log.
 func (ps *ProxyServer) logRequest(
 	c *gin.Context,
@@ -338,6 +800,20 @@ func (ps *ProxyServer) logRequest(
 		IsStream:     isStream,
 		UpstreamAddr: utils.TruncateString(upstreamAddr, 500),
 		RequestBody:  requestBodyToLog,
+		IsOverflow:   c.GetBool("budgetOverflow"),
+		RequestID:    requestid.FromContext(c.Request.Context()),
+		MirroredFrom: c.GetString(mirroredFromKey),
+	}
+
+	if verdict := c.GetString(moderationVerdictKey); verdict != "" {
+		logEntry.ModerationVerdict = utils.TruncateString(verdict, 255)
+		logEntry.IsFlagged = c.GetBool(moderationFlaggedKey)
+	}
+
+	if !logEntry.IsSuccess && ps.providerStatus != nil {
+		if incident := ps.providerStatus.IncidentFor(upstreamAddr); incident != nil {
+			logEntry.ProviderIncident = utils.TruncateString(incident.Reference(), 255)
+		}
 	}
 
 	// Set parent group
@@ -348,13 +824,18 @@ func (ps *ProxyServer) logRequest(
 
 	if channelHandler != nil && bodyBytes != nil {
 		logEntry.Model = channelHandler.ExtractModel(c, bodyBytes)
+		// Log the model the upstream was asked for, as rewritten by the redirect rules
+		if target, ok := group.ModelRedirectMap[logEntry.Model]; ok {
+			logEntry.RequestedModel = logEntry.Model
+			logEntry.Model = target
+		}
 	}
 
 	if apiKey != nil {
 		// 加密密钥值用于日志存储
 		encryptedKeyValue, err := ps.encryptionSvc.Encrypt(apiKey.KeyValue)
 		if err != nil {
-			logrus.WithError(err).Error("Failed to encrypt key value for logging")
+			logrus.WithContext(c.Request.Context()).WithError(err).Error("Failed to encrypt key value for logging")
 			logEntry.KeyValue = "failed-to-encryption"
 		} else {
 			logEntry.KeyValue = encryptedKeyValue
@@ -368,6 +849,7 @@ func (ps *ProxyServer) logRequest(
 	}
 
 	if err := ps.requestLogService.Record(logEntry); err != nil {
-		logrus.Errorf("Failed to record request log: %v", err)
+		logrus.WithContext(c.Request.Context()).Errorf("Failed to record request log: %v", err)
 	}
+	ps.saveDebugCapture(c, logEntry)
 }
@@ -0,0 +1,118 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gpt-load/internal/types"
+)
+
+// streamWatchdog aborts a proxied stream that stalls. It cancels the upstream request
+// context when the first chunk, the next chunk or the whole stream takes too long.
+type streamWatchdog struct {
+	firstByte time.Duration
+	idle      time.Duration
+	total     time.Duration
+	cancel    context.CancelFunc
+
+	mu         sync.Mutex
+	chunkTimer *time.Timer
+	totalTimer *time.Timer
+	stallErr   error
+}
+
+// newStreamWatchdog returns nil when all stream timeouts are disabled.
+func newStreamWatchdog(cfg types.SystemSettings, cancel context.CancelFunc) *streamWatchdog {
+	if cfg.StreamFirstByteTimeout <= 0 && cfg.StreamIdleTimeout <= 0 && cfg.StreamMaxDuration <= 0 {
+		return nil
+	}
+	return &streamWatchdog{
+		firstByte: time.Duration(cfg.StreamFirstByteTimeout) * time.Second,
+		idle:      time.Duration(cfg.StreamIdleTimeout) * time.Second,
+		total:     time.Duration(cfg.StreamMaxDuration) * time.Second,
+		cancel:    cancel,
+	}
+}
+
+// start arms the first byte and total duration timers.
+func (w *streamWatchdog) start() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.firstByte > 0 {
+		w.chunkTimer = time.AfterFunc(w.firstByte, func() {
+			w.fire(fmt.Errorf("stream stalled: no data received within %s", w.firstByte))
+		})
+	} else if w.idle > 0 {
+		w.chunkTimer = time.AfterFunc(w.idle, w.idleFired)
+	}
+	if w.total > 0 {
+		w.totalTimer = time.AfterFunc(w.total, func() {
+			w.fire(fmt.Errorf("stream exceeded the maximum duration of %s", w.total))
+		})
+	}
+}
+
+// chunk re-arms the idle timer after data was received.
+func (w *streamWatchdog) chunk() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stallErr != nil {
+		return
+	}
+	if w.chunkTimer != nil {
+		w.chunkTimer.Stop()
+		w.chunkTimer = nil
+	}
+	if w.idle > 0 {
+		w.chunkTimer = time.AfterFunc(w.idle, w.idleFired)
+	}
+}
+
+// stop disarms all timers once the stream is finished.
+func (w *streamWatchdog) stop() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.chunkTimer != nil {
+		w.chunkTimer.Stop()
+	}
+	if w.totalTimer != nil {
+		w.totalTimer.Stop()
+	}
+}
+
+// err returns why the watchdog aborted the stream, or nil if it did not.
+func (w *streamWatchdog) err() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stallErr
+}
+
+func (w *streamWatchdog) idleFired() {
+	w.fire(fmt.Errorf("stream stalled: no data received for %s", w.idle))
+}
+
+func (w *streamWatchdog) fire(err error) {
+	w.mu.Lock()
+	if w.stallErr == nil {
+		w.stallErr = err
+	}
+	w.mu.Unlock()
+	w.cancel()
+}
@@ -0,0 +1,77 @@
+// Package requestid identifies proxy requests, so that a client complaint can be matched with
+// the request logs, log lines and upstream requests of the request.
+package requestid
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// Header carries the request ID in proxy responses. A client can set it on a request to
+// use its own ID.
+const Header = "X-GPT-Load-Request-ID"
+
+// maxLength bounds the length of an ID supplied by a client.
+const maxLength = 64
+
+// LogField is the field of log lines holding the request ID.
+const LogField = "request_id"
+
+type contextKey struct{}
+
+// New returns a new request ID.
+func New() string {
+	return uuid.NewString()
+}
+
+// Valid reports whether an ID supplied by a client can be used: at most 64 printable ASCII
+// characters without spaces.
+func Valid(id string) bool {
+	return id != "" && len(id) <= maxLength && !strings.ContainsFunc(id, func(r rune) bool {
+		return r > unicode.MaxASCII || !unicode.IsPrint(r) || unicode.IsSpace(r)
+	})
+}
+
+// ValidHeaderName reports whether name can be used as the name of an HTTP header.
+func ValidHeaderName(name string) bool {
+	return name != "" && !strings.ContainsFunc(name, func(r rune) bool {
+		return !strings.ContainsRune(tokenChars, r)
+	})
+}
+
+// tokenChars are the characters allowed in HTTP header names (RFC 9110 tokens).
+const tokenChars = "!#$%&'*+-.^_`|~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// WithID returns a context carrying the request ID.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// LogHook adds the request ID to the log lines written with logrus.WithContext.
+type LogHook struct{}
+
+// Levels implements logrus.Hook.
+func (LogHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook.
+func (LogHook) Fire(entry *logrus.Entry) error {
+	if id := FromContext(entry.Context); id != "" {
+		entry.Data[LogField] = id
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestValid(t *testing.T) {
+	cases := map[string]bool{
+		"":                      false,
+		"req-123_abc.DEF:9":     true,
+		New():                   true,
+		strings.Repeat("a", 64): true,
+		strings.Repeat("a", 65): false,
+		"with space":            false,
+		"new\nline":             false,
+		"ünicode":               false,
+	}
+	for id, want := range cases {
+		if got := Valid(id); got != want {
+			t.Errorf("Valid(%q) = %t, want %t", id, got, want)
+		}
+	}
+}
+
+func TestValidHeaderName(t *testing.T) {
+	for _, name := range []string{"X-Request-ID", "x_trace.id"} {
+		if !ValidHeaderName(name) {
+			t.Errorf("ValidHeaderName(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"", "X Request", "X-Request:", "X-Réquest"} {
+		if ValidHeaderName(name) {
+			t.Errorf("ValidHeaderName(%q) = true, want false", name)
+		}
+	}
+}
+
+func TestLogHook(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.AddHook(LogHook{})
+
+	logger.WithContext(WithID(context.Background(), "req-1")).Info("with id")
+	logger.WithContext(context.Background()).Info("without id")
+	logger.Info("no context")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d log lines, want 3", len(lines))
+	}
+	if !strings.Contains(lines[0], "request_id=req-1") {
+		t.Errorf("request ID missing from %q", lines[0])
+	}
+	for _, line := range lines[1:] {
+		if strings.Contains(line, "request_id") {
+			t.Errorf("unexpected request ID in %q", line)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error formats of proxy requests. Errors raised by gpt-load itself on a proxy route are
+// written in the error schema of the API the group proxies, so that provider SDKs parse
+// them like upstream errors.
+const (
+	ErrorFormatOpenAI    = "openai"
+	ErrorFormatAnthropic = "anthropic"
+	ErrorFormatGemini    = "gemini"
+)
+
+const errorFormatKey = "errorFormat"
+
+// ErrorFormatForChannel returns the error format of a channel type, empty for channels
+// answered with the standard error response.
+func ErrorFormatForChannel(channelType string) string {
+	switch channelType {
+	case "openai", "openai-response":
+		return ErrorFormatOpenAI
+	case "anthropic":
+		return ErrorFormatAnthropic
+	case "gemini":
+		return ErrorFormatGemini
+	default:
+		return ""
+	}
+}
+
+// SetErrorFormat makes the error responses of the request use format.
+func SetErrorFormat(c *gin.Context, format string) {
+	if format != "" {
+		c.Set(errorFormatKey, format)
+	}
+}
+
+// writeError writes an error in the format of the request.
+func writeError(c *gin.Context, httpStatus int, code, message string, details any) {
+	switch c.GetString(errorFormatKey) {
+	case ErrorFormatOpenAI:
+		c.JSON(httpStatus, gin.H{"error": nativeErrorBody(gin.H{
+			"message": message,
+			"type":    openAIErrorType(httpStatus),
+			"param":   nil,
+			"code":    strings.ToLower(code),
+		}, details)})
+	case ErrorFormatAnthropic:
+		c.JSON(httpStatus, gin.H{"type": "error", "error": nativeErrorBody(gin.H{
+			"type":    anthropicErrorType(httpStatus),
+			"message": message,
+		}, details)})
+	case ErrorFormatGemini:
+		c.JSON(httpStatus, gin.H{"error": gin.H{
+			"code":    httpStatus,
+			"message": message,
+			"status":  googleStatus(httpStatus),
+		}})
+	default:
+		c.JSON(httpStatus, ErrorResponse{
+			Code:    code,
+			Message: message,
+			Details: details,
+		})
+	}
+}
+
+// nativeErrorBody adds the details of an error, which SDKs ignore, to its error object.
+func nativeErrorBody(body gin.H, details any) gin.H {
+	if details != nil {
+		body["details"] = details
+	}
+	return body
+}
+
+// openAIErrorType returns the error type OpenAI uses for a status.
+func openAIErrorType(httpStatus int) string {
+	switch {
+	case httpStatus == http.StatusUnauthorized:
+		return "authentication_error"
+	case httpStatus == http.StatusForbidden:
+		return "permission_error"
+	case httpStatus == http.StatusNotFound:
+		return "not_found_error"
+	case httpStatus == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case httpStatus >= http.StatusInternalServerError:
+		return "server_error"
+	default:
+		return "invalid_request_error"
+	}
+}
+
+// anthropicErrorType returns the error type Anthropic uses for a status.
+func anthropicErrorType(httpStatus int) string {
+	switch {
+	case httpStatus == http.StatusUnauthorized:
+		return "authentication_error"
+	case httpStatus == http.StatusForbidden:
+		return "permission_error"
+	case httpStatus == http.StatusNotFound:
+		return "not_found_error"
+	case httpStatus == http.StatusRequestEntityTooLarge:
+		return "request_too_large"
+	case httpStatus == http.StatusTooManyRequests:
+		return "rate_limit_error"
+	case httpStatus == http.StatusServiceUnavailable:
+		return "overloaded_error"
+	case httpStatus >= http.StatusInternalServerError:
+		return "api_error"
+	default:
+		return "invalid_request_error"
+	}
+}
+
+// googleStatus returns the canonical Google API status of an HTTP status.
+func googleStatus(httpStatus int) string {
+	switch httpStatus {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return "INVALID_ARGUMENT"
+	case http.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case http.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case http.StatusNotFound:
+		return "NOT_FOUND"
+	case http.StatusConflict:
+		return "ABORTED"
+	case http.StatusTooManyRequests:
+		return "RESOURCE_EXHAUSTED"
+	case http.StatusNotImplemented, http.StatusMethodNotAllowed:
+		return "UNIMPLEMENTED"
+	case http.StatusServiceUnavailable:
+		return "UNAVAILABLE"
+	case http.StatusGatewayTimeout:
+		return "DEADLINE_EXCEEDED"
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		return "INTERNAL"
+	}
+	return "FAILED_PRECONDITION"
+}
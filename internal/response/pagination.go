@@ -27,10 +27,8 @@ type PaginatedResponse struct {
 	Pagination Pagination `json:"pagination"`
 }
 
-// Paginate performs pagination on a GORM query and returns a standardized response.
-// It takes a Gin context, a GORM query builder, and a destination slice for the results.
-func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, error) {
-	// 1. Get page and page size from query parameters
+// pageParams reads the page and page size from the query parameters.
+func pageParams(c *gin.Context) (int, int) {
 	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
 	if err != nil || page < 1 {
 		page = 1
@@ -43,6 +41,14 @@ func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, err
 	if pageSize > MaxPageSize {
 		pageSize = MaxPageSize
 	}
+	return page, pageSize
+}
+
+// Paginate performs pagination on a GORM query and returns a standardized response.
+// It takes a Gin context, a GORM query builder, and a destination slice for the results.
+func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, error) {
+	// 1. Get page and page size from query parameters
+	page, pageSize := pageParams(c)
 
 	// 2. Get total count of items
 	var totalItems int64
@@ -72,3 +78,22 @@ func Paginate(c *gin.Context, query *gorm.DB, dest any) (*PaginatedResponse, err
 
 	return paginatedData, nil
 }
+
+// PaginateSlice paginates an in-memory slice using the same query parameters as Paginate.
+func PaginateSlice[T any](c *gin.Context, items []T) *PaginatedResponse {
+	page, pageSize := pageParams(c)
+
+	totalItems := len(items)
+	start := min((page-1)*pageSize, totalItems)
+	end := min(start+pageSize, totalItems)
+
+	return &PaginatedResponse{
+		Items: items[start:end],
+		Pagination: Pagination{
+			Page:       page,
+			PageSize:   pageSize,
+			TotalItems: int64(totalItems),
+			TotalPages: int(math.Ceil(float64(totalItems) / float64(pageSize))),
+		},
+	}
+}
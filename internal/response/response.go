@@ -16,10 +16,12 @@ type SuccessResponse struct {
 	Data    any    `json:"data,omitempty"`
 }
 
-// ErrorResponse defines the standard JSON error response structure.
+// ErrorResponse defines the standard JSON error response structure. Proxy requests use the
+// error schema of their channel instead, see SetErrorFormat.
 type ErrorResponse struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 }
 
 // Success sends a standardized success response.
@@ -34,10 +36,13 @@ func Success(c *gin.Context, data any) {
 
 // Error sends a standardized error response using an APIError.
 func Error(c *gin.Context, apiErr *app_errors.APIError) {
-	c.JSON(apiErr.HTTPStatus, ErrorResponse{
-		Code:    apiErr.Code,
-		Message: apiErr.Message,
-	})
+	writeError(c, apiErr.HTTPStatus, apiErr.Code, apiErr.Message, nil)
+}
+
+// ErrorWithDetails sends a standardized error response using an APIError, with details
+// describing the error for clients.
+func ErrorWithDetails(c *gin.Context, apiErr *app_errors.APIError, details any) {
+	writeError(c, apiErr.HTTPStatus, apiErr.Code, apiErr.Message, details)
 }
 
 // SuccessI18n sends a standardized success response with i18n message.
@@ -53,17 +58,11 @@ func SuccessI18n(c *gin.Context, msgID string, data any, templateData ...map[str
 // ErrorI18n sends a standardized error response with i18n message.
 func ErrorI18n(c *gin.Context, httpStatus int, code string, msgID string, templateData ...map[string]any) {
 	message := i18n.Message(c, msgID, templateData...)
-	c.JSON(httpStatus, ErrorResponse{
-		Code:    code,
-		Message: message,
-	})
+	writeError(c, httpStatus, code, message, nil)
 }
 
 // ErrorI18nFromAPIError sends a standardized error response using an APIError with i18n message.
 func ErrorI18nFromAPIError(c *gin.Context, apiErr *app_errors.APIError, msgID string, templateData ...map[string]any) {
 	message := i18n.Message(c, msgID, templateData...)
-	c.JSON(apiErr.HTTPStatus, ErrorResponse{
-		Code:    apiErr.Code,
-		Message: message,
-	})
+	writeError(c, apiErr.HTTPStatus, apiErr.Code, message, nil)
 }
@@ -0,0 +1,436 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"gpt-load/internal/channel"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/drain"
+	"gpt-load/internal/handler"
+	"gpt-load/internal/inflight"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/netacl"
+	"gpt-load/internal/openapi"
+	"gpt-load/internal/providerstatus"
+	"gpt-load/internal/proxy"
+	"gpt-load/internal/response"
+	"gpt-load/internal/services"
+	"gpt-load/internal/store"
+	"gpt-load/internal/templates"
+	"gpt-load/internal/upstreamstats"
+	"gpt-load/internal/version"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	openAPIPath = "/api/openapi.json"
+	apiDocsPath = "/api/docs"
+)
+
+// apiOperation documents the body and responses of an admin API route. The operation ID and
+// summary are taken from the name of its handler.
+type apiOperation struct {
+	// request is the JSON body of the request
+	request any
+	// response is the data of the success envelope, nil when the envelope carries none
+	response any
+	query    []openapi.Parameter
+	// content is the content type of a response sent without the envelope, such as an export
+	content string
+	// stringID marks an :id path parameter that is not numeric
+	stringID bool
+}
+
+// page is the typed form of response.PaginatedResponse.
+type page[T any] struct {
+	Items      []T                 `json:"items"`
+	Pagination response.Pagination `json:"pagination"`
+}
+
+func queryParam(name, schemaType, description string) openapi.Parameter {
+	return openapi.Parameter{Name: name, In: "query", Description: description, Schema: &openapi.Schema{Type: schemaType}}
+}
+
+var (
+	pageQuery = []openapi.Parameter{
+		queryParam("page", "integer", "Page number, from 1"),
+		queryParam("page_size", "integer", "Items per page"),
+	}
+	keyTagsQuery   = queryParam("tags", "string", "Comma separated tags a key must all carry")
+	revealQuery    = queryParam("reveal", "boolean", "Show plaintext keys whatever key_masking is, audited")
+	logFilterQuery = []openapi.Parameter{
+		queryParam("group_id", "integer", ""),
+		queryParam("group_name", "string", ""),
+		queryParam("parent_group_name", "string", ""),
+		queryParam("key_value", "string", ""),
+		queryParam("model", "string", ""),
+		queryParam("is_success", "boolean", ""),
+		queryParam("is_overflow", "boolean", ""),
+		queryParam("is_flagged", "boolean", ""),
+		queryParam("request_id", "string", ""),
+		queryParam("is_mirror", "boolean", "Only mirrored requests, or only client requests"),
+		queryParam("request_type", "string", ""),
+		queryParam("status_code", "integer", ""),
+		queryParam("source_ip", "string", ""),
+		queryParam("error_contains", "string", ""),
+		queryParam("start_time", "string", "RFC 3339 time"),
+		queryParam("end_time", "string", "RFC 3339 time"),
+		revealQuery,
+	}
+	statsRangeQuery = []openapi.Parameter{
+		queryParam("range", "string", "24h, 7d, 30d or custom"),
+		queryParam("start", "string", "RFC 3339 start of a custom range"),
+		queryParam("end", "string", "RFC 3339 end of a custom range"),
+	}
+	usageSnapshotQuery = []openapi.Parameter{
+		queryParam("group_id", "integer", "Limits the snapshot to a group"),
+		queryParam("include_keys", "boolean", "Includes the usage of every key"),
+	}
+)
+
+// apiOperations documents every admin API route, keyed by method and gin path. The router
+// test fails when a route is added without an entry here.
+var apiOperations = map[string]apiOperation{
+	"POST /api/auth/login":        {request: handler.LoginRequest{}, response: handler.LoginResponse{}, content: "application/json"},
+	"POST /api/auth/logout":       {},
+	"GET /api/auth/account":       {response: handler.AccountResponse{}},
+	"PUT /api/auth/password":      {request: handler.SetPasswordRequest{}},
+	"POST /api/auth/totp/setup":   {response: services.TOTPSetup{}},
+	"POST /api/auth/totp/enable":  {request: handler.TOTPCodeRequest{}},
+	"POST /api/auth/totp/disable": {request: handler.TOTPCodeRequest{}},
+	"GET /api/auth/options":       {response: handler.AuthOptions{}},
+	"GET /api/auth/oidc/login":    {},
+	"GET /api/auth/oidc/callback": {query: []openapi.Parameter{queryParam("code", "string", ""), queryParam("state", "string", "")}},
+
+	"GET /api/integration/info": {response: []handler.IntegrationGroupInfo{}, query: []openapi.Parameter{queryParam("key", "string", "Proxy key")}},
+	"GET /api/channel-types":    {response: []string{}},
+	"POST /api/channel-types/custom/dry-run": {request: handler.CustomChannelDryRunRequest{}, response: struct {
+		Definition *channel.CustomDefinition   `json:"definition"`
+		Result     *channel.CustomDryRunResult `json:"result"`
+	}{}},
+
+	"POST /api/groups":                              {request: handler.GroupCreateRequest{}, response: handler.GroupResponse{}},
+	"POST /api/groups/validate":                     {request: handler.GroupValidateRequest{}, response: handler.GroupValidationResponse{}},
+	"GET /api/groups":                               {response: []handler.GroupResponse{}},
+	"GET /api/groups/list":                          {response: []models.Group{}},
+	"GET /api/groups/config-options":                {response: []handler.ConfigOption{}},
+	"GET /api/groups/feature-flags":                 {response: []handler.FeatureFlagInfo{}, query: []openapi.Parameter{queryParam("group_id", "integer", "Resolves the flags for a group")}},
+	"GET /api/groups/templates":                     {response: []templates.Template{}},
+	"PUT /api/groups/reorder":                       {request: handler.GroupReorderRequest{}},
+	"PUT /api/groups/bulk/enabled":                  {request: handler.GroupBulkEnabledRequest{}, response: services.GroupBulkResult{}},
+	"PUT /api/groups/bulk/config":                   {request: handler.GroupBulkConfigRequest{}, response: services.GroupBulkResult{}},
+	"GET /api/groups/by-external-id/:externalId":    {response: handler.GroupResponse{}},
+	"PUT /api/groups/by-external-id/:externalId":    {request: handler.GroupUpsertRequest{}, response: handler.GroupResponse{}},
+	"DELETE /api/groups/by-external-id/:externalId": {},
+	"PUT /api/groups/:id":                           {request: handler.GroupUpdateRequest{}, response: handler.GroupResponse{}},
+	"DELETE /api/groups/:id":                        {},
+	"GET /api/groups/:id/stats":                     {response: services.GroupStats{}},
+	"GET /api/groups/:id/canary":                    {response: proxy.CanaryHistory{}, query: []openapi.Parameter{queryParam("hours", "integer", "")}},
+	"GET /api/groups/:id/pool":                      {response: keypool.PoolMembership{}},
+	"GET /api/groups/:id/models":                    {response: []channel.DiscoveredModel{}, query: []openapi.Parameter{queryParam("refresh", "boolean", "Bypasses the cached model list")}},
+	"POST /api/groups/:id/pool/rebuild":             {response: keypool.PoolMembership{}},
+	"POST /api/groups/:id/pool/recover-cooled": {response: struct {
+		Recovered int `json:"recovered"`
+	}{}},
+	"POST /api/groups/:id/pool/keys/:keyId/move":        {request: handler.PoolMoveRequest{}, response: keypool.PoolMembership{}},
+	"GET /api/groups/:id/reserve":                       {response: keypool.ReserveStatus{}},
+	"POST /api/groups/:id/reserve":                      {request: handler.ReserveStockRequest{}, response: services.AddKeysResult{}},
+	"DELETE /api/groups/:id/reserve":                    {},
+	"DELETE /api/groups/:id/response-cache":             {},
+	"POST /api/groups/:id/copy":                         {request: handler.GroupCopyRequest{}, response: handler.GroupCopyResponse{}},
+	"POST /api/groups/:id/test-connectivity":            {response: []channel.UpstreamConnectivity{}},
+	"GET /api/groups/:id/sub-groups":                    {response: []models.SubGroupInfo{}},
+	"POST /api/groups/:id/sub-groups":                   {request: handler.AddSubGroupsRequest{}},
+	"PUT /api/groups/:id/sub-groups/:subGroupId/weight": {request: handler.UpdateSubGroupWeightRequest{}},
+	"DELETE /api/groups/:id/sub-groups/:subGroupId":     {},
+	"GET /api/groups/:id/parent-aggregate-groups":       {response: []models.ParentAggregateGroupInfo{}},
+
+	"GET /api/keys": {response: page[models.APIKey]{}, query: append([]openapi.Parameter{
+		queryParam("group_id", "integer", ""),
+		queryParam("status", "string", "Key status, all when empty"),
+		queryParam("key_value", "string", "Exact key value"),
+		keyTagsQuery,
+		revealQuery,
+	}, pageQuery...)},
+	"GET /api/keys/export": {content: "text/plain", query: []openapi.Parameter{
+		queryParam("group_id", "integer", ""),
+		queryParam("status", "string", "all, active or invalid"),
+		keyTagsQuery,
+		revealQuery,
+	}},
+	"GET /api/keys/reveal-logs": {response: page[models.KeyRevealLog]{}, query: append([]openapi.Parameter{queryParam("group_id", "integer", "")}, pageQuery...)},
+	"GET /api/keys/watch": {content: "text/event-stream", query: []openapi.Parameter{
+		queryParam("group_id", "integer", "Only the events of this group"),
+	}},
+	"POST /api/keys/add-multiple":        {request: handler.KeyTextRequest{}, response: services.AddKeysResult{}},
+	"POST /api/keys/add-async":           {request: handler.KeyTextRequest{}, response: services.TaskStatus{}},
+	"POST /api/keys/delete-multiple":     {request: handler.KeyTextRequest{}, response: services.DeleteKeysResult{}},
+	"POST /api/keys/delete-async":        {request: handler.KeyTextRequest{}, response: services.TaskStatus{}},
+	"POST /api/keys/restore-multiple":    {request: handler.KeyTextRequest{}, response: services.RestoreKeysResult{}},
+	"POST /api/keys/restore-all-invalid": {request: handler.GroupIDRequest{}},
+	"POST /api/keys/clear-all-invalid":   {request: handler.GroupIDRequest{}},
+	"POST /api/keys/clear-all":           {request: handler.GroupIDRequest{}},
+	"POST /api/keys/validate-group":      {request: handler.ValidateGroupKeysRequest{}, response: services.TaskStatus{}},
+	"POST /api/keys/test-multiple": {request: handler.KeyTextRequest{}, response: struct {
+		Results       []keypool.KeyTestResult `json:"results"`
+		TotalDuration int64                   `json:"total_duration"`
+	}{}},
+	"GET /api/keys/by-external-id/:externalId":    {response: models.APIKey{}, query: []openapi.Parameter{revealQuery}},
+	"PUT /api/keys/by-external-id/:externalId":    {request: handler.KeyUpsertRequest{}, response: models.APIKey{}, query: []openapi.Parameter{revealQuery}},
+	"DELETE /api/keys/by-external-id/:externalId": {},
+	"PUT /api/keys/:id/notes":                     {request: handler.UpdateKeyNotesRequest{}},
+	"GET /api/keys/:id/stats":                     {response: services.KeyUsageStats{}, query: []openapi.Parameter{queryParam("hours", "integer", "")}},
+	"POST /api/keys/tags/add":                     {request: handler.KeyTagsRequest{}, response: services.KeyTagsResult{}},
+	"POST /api/keys/tags/remove":                  {request: handler.KeyTagsRequest{}, response: services.KeyTagsResult{}},
+	"POST /api/keys/lifecycle":                    {request: handler.KeyLifecycleRequest{}, response: keypool.LifecycleResult{}},
+
+	"GET /api/tasks/status":                {response: services.TaskStatus{}},
+	"GET /api/migrations/status":           {response: models.MigrationStatus{}},
+	"GET /api/encryption/rotation":         {response: services.KeyRotationStatus{}},
+	"POST /api/encryption/rotation/finish": {response: services.KeyRotationStatus{}},
+
+	"GET /api/dashboard/stats": {response: models.DashboardStatsResponse{}, query: statsRangeQuery},
+	"GET /api/dashboard/chart": {response: models.ChartData{}, query: append([]openapi.Parameter{queryParam("groupId", "integer", "")}, statsRangeQuery...)},
+	"GET /api/dashboard/encryption-status": {response: struct {
+		HasMismatch  bool   `json:"has_mismatch"`
+		ScenarioType string `json:"scenario_type"`
+		Message      string `json:"message"`
+		Suggestion   string `json:"suggestion"`
+	}{}},
+	"GET /api/dashboard/key-status-queue":   {response: keypool.StatusQueueStats{}},
+	"GET /api/dashboard/request-log-writer": {response: services.RequestLogWriterStats{}},
+	"GET /api/dashboard/memory-store": {response: struct {
+		Backend  string                `json:"backend"`
+		Stats    *store.MemoryStats    `json:"stats,omitempty"`
+		Failover *store.FailoverStatus `json:"failover,omitempty"`
+	}{}},
+	"POST /api/dashboard/store-hygiene":  {response: services.StoreHygieneReport{}},
+	"GET /api/dashboard/pool-reconcile":  {response: services.PoolReconcileReport{}},
+	"POST /api/dashboard/pool-reconcile": {response: services.PoolReconcileReport{}, query: []openapi.Parameter{queryParam("dry_run", "boolean", "Reports the differences without fixing them")}},
+	"POST /api/dashboard/store-flush":    {request: handler.StoreFlushRequest{}, response: services.StoreFlushReport{}},
+	"GET /api/dashboard/drain":           {response: drain.Status{}},
+	"POST /api/dashboard/drain":          {response: drain.Status{}},
+	"GET /api/dashboard/cluster":         {response: cluster.Status{}},
+	"GET /api/dashboard/provider-status": {response: providerstatus.Status{}},
+	"GET /api/dashboard/upstreams":       {response: []upstreamstats.Stats{}, query: []openapi.Parameter{queryParam("group", "string", "Group name")}},
+
+	"GET /api/inflight":                  {response: []inflight.Request{}, query: []openapi.Parameter{queryParam("group", "string", "Group name")}},
+	"DELETE /api/inflight/:id":           {stringID: true},
+	"POST /api/compare":                  {request: handler.CompareRequest{}, response: []proxy.CompareResult{}},
+	"GET /api/network-acl/rejections":    {response: []netacl.Rejection{}},
+	"GET /api/honeypot/hits":             {response: page[models.HoneypotHit]{}, query: append([]openapi.Parameter{queryParam("client_ip", "string", "")}, pageQuery...)},
+	"GET /api/honeypot/report":           {response: services.HoneypotReport{}, query: []openapi.Parameter{queryParam("hours", "integer", "")}},
+	"GET /api/trash/groups":              {response: []services.TrashedGroup{}},
+	"POST /api/trash/groups/:id/restore": {response: keypool.TrashRestoreResult{}},
+	"DELETE /api/trash/groups/:id":       {},
+	"GET /api/trash/keys":                {response: page[services.TrashedKey]{}, query: append([]openapi.Parameter{queryParam("group_id", "integer", ""), revealQuery}, pageQuery...)},
+	"POST /api/trash/keys/restore":       {request: handler.TrashKeysRequest{}, response: keypool.TrashRestoreResult{}},
+	"POST /api/trash/keys/purge":         {request: handler.TrashKeysRequest{}},
+
+	"GET /api/usage-snapshots": {response: []models.UsageSnapshot{}, query: []openapi.Parameter{
+		queryParam("as_of", "string", "RFC 3339 time, lists the snapshots taken before it"),
+		queryParam("limit", "integer", ""),
+	}},
+	"POST /api/usage-snapshots":      {response: models.UsageSnapshot{}},
+	"GET /api/usage-snapshots/as-of": {response: services.UsageSnapshotView{}, query: append([]openapi.Parameter{queryParam("as_of", "string", "RFC 3339 time")}, usageSnapshotQuery...)},
+	"GET /api/usage-snapshots/:id":   {response: services.UsageSnapshotView{}, query: usageSnapshotQuery},
+
+	"GET /api/logs":                {response: page[models.RequestLog]{}, query: append(logFilterQuery, pageQuery...)},
+	"GET /api/logs/export":         {content: "text/csv", query: logFilterQuery},
+	"GET /api/logs/records/export": {content: "application/x-ndjson", query: append([]openapi.Parameter{queryParam("format", "string", "ndjson or csv")}, logFilterQuery...)},
+	"GET /api/logs/stream": {content: "text/event-stream", query: []openapi.Parameter{
+		queryParam("group_id", "integer", ""),
+		queryParam("key_value", "string", ""),
+		queryParam("key_hash", "string", ""),
+		queryParam("status_code", "string", "Comma separated status codes"),
+		revealQuery,
+	}},
+	"GET /api/logs/requests/:id": {response: []models.RequestLog{}, query: []openapi.Parameter{revealQuery}, stringID: true},
+	"GET /api/logs/:id/capture":  {response: services.DebugCapture{}, stringID: true},
+	"POST /api/logs/:id/replay":  {request: handler.ReplayLogRequest{}, response: proxy.ReplayResult{}, stringID: true},
+
+	"GET /api/settings": {response: []models.CategorizedSettings{}},
+	"GET /api/settings/search": {response: page[models.SystemSettingInfo]{}, query: append([]openapi.Parameter{
+		queryParam("search", "string", ""),
+		queryParam("category", "string", ""),
+	}, pageQuery...)},
+	"PUT /api/settings":          {request: map[string]any{}},
+	"POST /api/settings/preview": {request: map[string]any{}, response: []models.SettingChange{}},
+}
+
+// publicAPIRoutes are the admin API routes served without the AUTH_KEY.
+var publicAPIRoutes = map[string]bool{
+	"POST /api/auth/login":        true,
+	"GET /api/auth/options":       true,
+	"GET /api/auth/oidc/login":    true,
+	"GET /api/auth/oidc/callback": true,
+	"GET /api/integration/info":   true,
+	"GET " + openAPIPath:          true,
+	"GET " + apiDocsPath:          true,
+}
+
+// newOpenAPIDocument documents the admin API routes of an engine.
+func newOpenAPIDocument(routes gin.RoutesInfo) *openapi.Document {
+	builder := openapi.NewBuilder(openapi.Info{
+		Title:       "GPT-Load Admin API",
+		Description: "Management API of GPT-Load. Requests are authenticated with the AUTH_KEY as a bearer token.",
+		Version:     version.Version,
+	})
+	builder.Define(reflect.TypeFor[datatypes.JSON](), &openapi.Schema{})
+	builder.Define(reflect.TypeFor[gorm.DeletedAt](), &openapi.Schema{Type: "string", Format: "date-time", Nullable: true})
+
+	doc := builder.Document()
+	doc.Components.SecuritySchemes = map[string]openapi.SecurityScheme{
+		"bearerAuth": {Type: "http", Scheme: "bearer"},
+	}
+	doc.Security = []map[string][]string{{"bearerAuth": {}}}
+	errorSchema := builder.SchemaOf(response.ErrorResponse{})
+
+	tags := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		if !strings.HasPrefix(route.Path, "/api/") || route.Path == openAPIPath || route.Path == apiDocsPath {
+			continue
+		}
+		spec := apiOperations[key]
+
+		name := handlerName(route.Handler)
+		tag := strings.SplitN(strings.TrimPrefix(route.Path, "/api/"), "/", 2)[0]
+		tags[tag] = true
+		op := &openapi.Operation{
+			OperationID: openapi.OperationID(name),
+			Summary:     openapi.Summary(name),
+			Tags:        []string{tag},
+			Parameters:  append(pathParams(route.Path, spec.stringID), spec.query...),
+			Responses:   map[string]openapi.Response{"default": jsonResponse("Error", errorSchema)},
+		}
+		if publicAPIRoutes[key] {
+			op.Security = &[]map[string][]string{}
+		}
+		if spec.request != nil {
+			op.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{"application/json": {Schema: builder.SchemaOf(spec.request)}},
+			}
+		}
+		switch spec.content {
+		case "":
+			op.Responses["200"] = jsonResponse("Success", envelopeSchema(builder, spec.response))
+		case "application/json":
+			op.Responses["200"] = jsonResponse("Success", builder.SchemaOf(spec.response))
+		default:
+			op.Responses["200"] = openapi.Response{
+				Description: "Success",
+				Content:     map[string]openapi.MediaType{spec.content: {Schema: &openapi.Schema{Type: "string"}}},
+			}
+		}
+		builder.AddOperation(route.Method, openAPIPathOf(route.Path), op)
+	}
+
+	for tag := range tags {
+		doc.Tags = append(doc.Tags, openapi.Tag{Name: tag})
+	}
+	sort.Slice(doc.Tags, func(i, j int) bool { return doc.Tags[i].Name < doc.Tags[j].Name })
+	return doc
+}
+
+// envelopeSchema is the schema of the success envelope carrying data.
+func envelopeSchema(builder *openapi.Builder, data any) *openapi.Schema {
+	schema := &openapi.Schema{
+		Type: "object",
+		Properties: map[string]*openapi.Schema{
+			"code":    {Type: "integer", Format: "int32"},
+			"message": {Type: "string"},
+		},
+		Required: []string{"code", "message"},
+	}
+	if data != nil {
+		schema.Properties["data"] = builder.SchemaOf(data)
+	}
+	return schema
+}
+
+func jsonResponse(description string, schema *openapi.Schema) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     map[string]openapi.MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// pathParams documents the parameters of a gin path, numeric IDs unless stringID is set.
+func pathParams(path string, stringID bool) []openapi.Parameter {
+	var params []openapi.Parameter
+	for segment := range strings.SplitSeq(path, "/") {
+		name, ok := strings.CutPrefix(segment, ":")
+		if !ok {
+			continue
+		}
+		schema := &openapi.Schema{Type: "integer", Format: "int64"}
+		if name == "externalId" || (name == "id" && stringID) {
+			schema = &openapi.Schema{Type: "string"}
+		}
+		params = append(params, openapi.Parameter{Name: name, In: "path", Required: true, Schema: schema})
+	}
+	return params
+}
+
+// openAPIPathOf turns a gin path such as /api/groups/:id into /api/groups/{id}.
+func openAPIPathOf(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if name, ok := strings.CutPrefix(segment, ":"); ok {
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// handlerName extracts the method name from a handler name such as
+// gpt-load/internal/handler.(*Server).CreateGroup-fm.
+func handlerName(name string) string {
+	name = strings.TrimSuffix(name, "-fm")
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// registerOpenAPIRoutes serves the OpenAPI document of the admin API, built on first request
+// from the routes of the engine, and a Swagger UI rendering it.
+func registerOpenAPIRoutes(router *gin.Engine, api *gin.RouterGroup) {
+	var (
+		once sync.Once
+		doc  *openapi.Document
+	)
+	api.GET(strings.TrimPrefix(openAPIPath, "/api"), func(c *gin.Context) {
+		once.Do(func() { doc = newOpenAPIDocument(router.Routes()) })
+		c.JSON(http.StatusOK, doc)
+	})
+	api.GET(strings.TrimPrefix(apiDocsPath, "/api"), func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+	})
+}
+
+// swaggerUIPage loads Swagger UI from a CDN, so that the binary does not embed it.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>GPT-Load Admin API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "` + openAPIPath + `", dom_id: "#swagger-ui", persistAuthorization: true });
+  </script>
+</body>
+</html>
+`
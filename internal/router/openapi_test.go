@@ -0,0 +1,75 @@
+package router
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gpt-load/internal/handler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestAPIRoutes() gin.RoutesInfo {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	serverHandler := &handler.Server{}
+	api := engine.Group("/api")
+	registerPublicAPIRoutes(api, serverHandler)
+	registerOpenAPIRoutes(engine, api)
+	registerProtectedAPIRoutes(api, serverHandler)
+	return engine.Routes()
+}
+
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	routes := newTestAPIRoutes()
+	registered := make(map[string]bool)
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if route.Path == openAPIPath || route.Path == apiDocsPath {
+			continue
+		}
+		if _, ok := apiOperations[key]; !ok {
+			t.Errorf("route %s is missing from apiOperations", key)
+		}
+	}
+	for key := range apiOperations {
+		if !registered[key] {
+			t.Errorf("apiOperations documents %s, which is not a route", key)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	doc := newOpenAPIDocument(newTestAPIRoutes())
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("failed to encode the document: %v", err)
+	}
+
+	op := doc.Paths["/api/groups/{id}"]["put"]
+	if op == nil {
+		t.Fatal("PUT /api/groups/{id} is not documented")
+	}
+	if op.OperationID != "updateGroup" || op.Summary != "Update group" {
+		t.Errorf("unexpected operation %q %q", op.OperationID, op.Summary)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Name != "id" || op.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("unexpected parameters %+v", op.Parameters)
+	}
+	if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/GroupUpdateRequest" {
+		t.Errorf("unexpected request body %+v", op.RequestBody)
+	}
+	if doc.Paths["/api/auth/login"]["post"].Security == nil {
+		t.Error("login should not require the auth key")
+	}
+
+	// Every reference resolves to a component
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("component %s is referenced but not defined", name)
+		}
+	}
+}
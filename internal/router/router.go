@@ -17,6 +17,7 @@ import (
 	"github.com/gin-contrib/static"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 type embedFileSystem struct {
@@ -50,12 +51,24 @@ func NewRouter(
 
 	router := gin.New()
 
+	// The client IP of the logs and network ACLs is taken from these headers only when the
+	// connection comes from a trusted proxy
+	serverConfig := configManager.GetEffectiveServerConfig()
+	if err := router.SetTrustedProxies(serverConfig.TrustedProxies); err != nil {
+		logrus.WithError(err).Error("Invalid trusted proxies, trusting none")
+		_ = router.SetTrustedProxies(nil)
+	}
+	router.RemoteIPHeaders = serverConfig.TrustedProxyHeaders
+
 	// 注册全局中间件
 	router.Use(middleware.Recovery())
 	router.Use(middleware.ErrorHandler())
 	router.Use(middleware.Logger(configManager.GetLogConfig()))
-	router.Use(middleware.CORS(configManager.GetCORSConfig()))
-	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig()))
+	router.Use(middleware.CORS(configManager.GetCORSConfig(), groupManager))
+	router.Use(middleware.NativeErrors(groupManager))
+	router.Use(middleware.Honeypot(serverHandler.SettingsManager, serverHandler.IPThrottle, serverHandler.HoneypotService))
+	router.Use(middleware.GroupLimiter(groupManager))
+	router.Use(middleware.RateLimiter(configManager.GetPerformanceConfig(), groupManager))
 	router.Use(middleware.SecurityHeaders())
 	startTime := time.Now()
 	router.Use(func(c *gin.Context) {
@@ -75,6 +88,8 @@ func NewRouter(
 // registerSystemRoutes 注册系统级路由
 func registerSystemRoutes(router *gin.Engine, serverHandler *handler.Server) {
 	router.GET("/health", serverHandler.Health)
+	router.GET("/livez", serverHandler.Livez)
+	router.GET("/readyz", serverHandler.Readyz)
 }
 
 // registerAPIRoutes 注册API路由
@@ -85,39 +100,75 @@ func registerAPIRoutes(
 ) {
 	api := router.Group("/api")
 	api.Use(i18n.Middleware())
+	api.Use(middleware.BanRepeatedAuthFailures(serverHandler.IPThrottle))
 
 	authConfig := configManager.GetAuthConfig()
 
 	// 公开
 	registerPublicAPIRoutes(api, serverHandler)
+	registerOpenAPIRoutes(router, api)
 
 	// 认证
 	protectedAPI := api.Group("")
-	protectedAPI.Use(middleware.Auth(authConfig))
+	protectedAPI.Use(middleware.Auth(authConfig, serverHandler.AdminAuth))
+	protectedAPI.Use(middleware.ReadOnlyWhenDegraded(serverHandler.DBHealth))
 	registerProtectedAPIRoutes(protectedAPI, serverHandler)
 }
 
 // registerPublicAPIRoutes 公开API路由
 func registerPublicAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
-	api.POST("/auth/login", serverHandler.Login)
+	api.POST("/auth/login", middleware.LimitIPRate(serverHandler.IPThrottle, services.IPThrottleScopeAuth), serverHandler.Login)
+	api.GET("/auth/options", serverHandler.GetAuthOptions)
+	api.GET("/auth/oidc/login", middleware.LimitIPRate(serverHandler.IPThrottle, services.IPThrottleScopeAuth), serverHandler.OIDCLogin)
+	api.GET("/auth/oidc/callback", middleware.LimitIPRate(serverHandler.IPThrottle, services.IPThrottleScopeAuth), serverHandler.OIDCCallback)
 	api.GET("/integration/info", serverHandler.GetIntegrationInfo)
 }
 
 // registerProtectedAPIRoutes 认证API路由
 func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Server) {
 	api.GET("/channel-types", serverHandler.CommonHandler.GetChannelTypes)
+	api.POST("/channel-types/custom/dry-run", serverHandler.CommonHandler.DryRunCustomChannel)
+
+	auth := api.Group("/auth")
+	{
+		auth.POST("/logout", serverHandler.Logout)
+		auth.GET("/account", serverHandler.GetAccount)
+		auth.PUT("/password", serverHandler.SetPassword)
+		auth.POST("/totp/setup", serverHandler.SetupTOTP)
+		auth.POST("/totp/enable", serverHandler.EnableTOTP)
+		auth.POST("/totp/disable", serverHandler.DisableTOTP)
+	}
 
 	groups := api.Group("/groups")
 	{
 		groups.POST("", serverHandler.CreateGroup)
+		groups.POST("/validate", serverHandler.ValidateGroup)
 		groups.GET("", serverHandler.ListGroups)
 		groups.GET("/list", serverHandler.List)
 		groups.GET("/config-options", serverHandler.GetGroupConfigOptions)
+		groups.GET("/feature-flags", serverHandler.ListFeatureFlags)
+		groups.GET("/templates", serverHandler.ListGroupTemplates)
 		groups.PUT("/reorder", serverHandler.ReorderGroups)
+		groups.PUT("/bulk/enabled", serverHandler.BulkSetGroupsEnabled)
+		groups.PUT("/bulk/config", serverHandler.BulkUpdateGroupsConfig)
+		groups.GET("/by-external-id/:externalId", serverHandler.GetGroupByExternalID)
+		groups.PUT("/by-external-id/:externalId", serverHandler.UpsertGroupByExternalID)
+		groups.DELETE("/by-external-id/:externalId", serverHandler.DeleteGroupByExternalID)
 		groups.PUT("/:id", serverHandler.UpdateGroup)
 		groups.DELETE("/:id", serverHandler.DeleteGroup)
 		groups.GET("/:id/stats", serverHandler.GetGroupStats)
+		groups.GET("/:id/canary", serverHandler.GetGroupCanary)
+		groups.GET("/:id/pool", serverHandler.GetGroupPool)
+		groups.GET("/:id/models", serverHandler.DiscoverGroupModels)
+		groups.POST("/:id/pool/rebuild", serverHandler.RebuildGroupPool)
+		groups.POST("/:id/pool/recover-cooled", serverHandler.RecoverGroupCooledKeys)
+		groups.POST("/:id/pool/keys/:keyId/move", serverHandler.MoveGroupPoolKey)
+		groups.GET("/:id/reserve", serverHandler.GetGroupReserve)
+		groups.POST("/:id/reserve", serverHandler.StockGroupReserve)
+		groups.DELETE("/:id/reserve", serverHandler.DrainGroupReserve)
+		groups.DELETE("/:id/response-cache", serverHandler.PurgeGroupResponseCache)
 		groups.POST("/:id/copy", serverHandler.CopyGroup)
+		groups.POST("/:id/test-connectivity", serverHandler.TestGroupConnectivity)
 
 		groups.GET("/:id/sub-groups", serverHandler.GetSubGroups)
 		groups.POST("/:id/sub-groups", serverHandler.AddSubGroups)
@@ -131,6 +182,8 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	{
 		keys.GET("", serverHandler.ListKeysInGroup)
 		keys.GET("/export", serverHandler.ExportKeys)
+		keys.GET("/reveal-logs", serverHandler.ListKeyRevealLogs)
+		keys.GET("/watch", serverHandler.WatchKeys)
 		keys.POST("/add-multiple", serverHandler.AddMultipleKeys)
 		keys.POST("/add-async", serverHandler.AddMultipleKeysAsync)
 		keys.POST("/delete-multiple", serverHandler.DeleteMultipleKeys)
@@ -141,11 +194,21 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		keys.POST("/clear-all", serverHandler.ClearAllKeys)
 		keys.POST("/validate-group", serverHandler.ValidateGroupKeys)
 		keys.POST("/test-multiple", serverHandler.TestMultipleKeys)
+		keys.GET("/by-external-id/:externalId", serverHandler.GetKeyByExternalID)
+		keys.PUT("/by-external-id/:externalId", serverHandler.UpsertKeyByExternalID)
+		keys.DELETE("/by-external-id/:externalId", serverHandler.DeleteKeyByExternalID)
 		keys.PUT("/:id/notes", serverHandler.UpdateKeyNotes)
+		keys.GET("/:id/stats", serverHandler.GetKeyStats)
+		keys.POST("/tags/add", serverHandler.TagKeys)
+		keys.POST("/tags/remove", serverHandler.UntagKeys)
+		keys.POST("/lifecycle", serverHandler.TransitionKeys)
 	}
 
 	// Tasks
 	api.GET("/tasks/status", serverHandler.GetTaskStatus)
+	api.GET("/migrations/status", serverHandler.GetMigrationStatus)
+	api.GET("/encryption/rotation", serverHandler.GetKeyRotationStatus)
+	api.POST("/encryption/rotation/finish", serverHandler.FinishKeyRotation)
 
 	// 仪表板和日志
 	dashboard := api.Group("/dashboard")
@@ -153,6 +216,58 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 		dashboard.GET("/stats", serverHandler.Stats)
 		dashboard.GET("/chart", serverHandler.Chart)
 		dashboard.GET("/encryption-status", serverHandler.EncryptionStatus)
+		dashboard.GET("/key-status-queue", serverHandler.KeyStatusQueue)
+		dashboard.GET("/request-log-writer", serverHandler.RequestLogWriterStats)
+		dashboard.GET("/memory-store", serverHandler.MemoryStore)
+		dashboard.POST("/store-hygiene", serverHandler.RunStoreHygiene)
+		dashboard.GET("/pool-reconcile", serverHandler.PoolReconcileStatus)
+		dashboard.POST("/pool-reconcile", serverHandler.RunPoolReconcile)
+		dashboard.POST("/store-flush", serverHandler.FlushStore)
+		dashboard.GET("/drain", serverHandler.DrainStatus)
+		dashboard.POST("/drain", serverHandler.StartDrain)
+		dashboard.GET("/cluster", serverHandler.ClusterStatus)
+		dashboard.GET("/provider-status", serverHandler.ProviderStatus)
+		dashboard.GET("/upstreams", serverHandler.ListUpstreamStats)
+	}
+
+	// 进行中的代理请求
+	inFlight := api.Group("/inflight")
+	{
+		inFlight.GET("", serverHandler.ListInFlightRequests)
+		inFlight.DELETE("/:id", serverHandler.CancelInFlightRequest)
+	}
+
+	// 对比多个分组和模型对同一提示词的响应
+	api.POST("/compare", serverHandler.Compare)
+
+	// 网络访问控制
+	api.GET("/network-acl/rejections", serverHandler.ListNetworkRejections)
+
+	// 蜜罐命中记录与攻击者画像
+	honeypotAPI := api.Group("/honeypot")
+	{
+		honeypotAPI.GET("/hits", serverHandler.ListHoneypotHits)
+		honeypotAPI.GET("/report", serverHandler.GetHoneypotReport)
+	}
+
+	// 回收站
+	trash := api.Group("/trash")
+	{
+		trash.GET("/groups", serverHandler.ListTrashedGroups)
+		trash.POST("/groups/:id/restore", serverHandler.RestoreTrashedGroup)
+		trash.DELETE("/groups/:id", serverHandler.PurgeTrashedGroup)
+		trash.GET("/keys", serverHandler.ListTrashedKeys)
+		trash.POST("/keys/restore", serverHandler.RestoreTrashedKeys)
+		trash.POST("/keys/purge", serverHandler.PurgeTrashedKeys)
+	}
+
+	// 用量快照
+	usageSnapshots := api.Group("/usage-snapshots")
+	{
+		usageSnapshots.GET("", serverHandler.ListUsageSnapshots)
+		usageSnapshots.POST("", serverHandler.TakeUsageSnapshot)
+		usageSnapshots.GET("/as-of", serverHandler.GetUsageSnapshotAsOf)
+		usageSnapshots.GET("/:id", serverHandler.GetUsageSnapshot)
 	}
 
 	// 日志
@@ -160,13 +275,20 @@ func registerProtectedAPIRoutes(api *gin.RouterGroup, serverHandler *handler.Ser
 	{
 		logs.GET("", serverHandler.GetLogs)
 		logs.GET("/export", serverHandler.ExportLogs)
+		logs.GET("/records/export", serverHandler.ExportLogRecords)
+		logs.GET("/stream", serverHandler.StreamLogs)
+		logs.GET("/requests/:id", serverHandler.GetRequestLogs)
+		logs.GET("/:id/capture", serverHandler.GetLogCapture)
+		logs.POST("/:id/replay", serverHandler.ReplayLog)
 	}
 
 	// 设置
 	settings := api.Group("/settings")
 	{
 		settings.GET("", serverHandler.GetSettings)
+		settings.GET("/search", serverHandler.SearchSettings)
 		settings.PUT("", serverHandler.UpdateSettings)
+		settings.POST("/preview", serverHandler.PreviewSettings)
 	}
 }
 
@@ -179,8 +301,14 @@ func registerProxyRoutes(
 ) {
 	proxyGroup := router.Group("/proxy/:group_name")
 
+	proxyGroup.Use(middleware.RequestID())
+	proxyGroup.Use(middleware.RejectWhenDraining(serverHandler.Drain))
 	proxyGroup.Use(middleware.ProxyRouteDispatcher(serverHandler))
+	proxyGroup.Use(middleware.BanRepeatedAuthFailures(serverHandler.IPThrottle))
+	proxyGroup.Use(middleware.LimitIPRate(serverHandler.IPThrottle, services.IPThrottleScopeProxy))
+	proxyGroup.Use(middleware.NetworkACL(groupManager, serverHandler.NetworkRejections))
 	proxyGroup.Use(middleware.ProxyAuth(groupManager))
+	proxyGroup.Use(middleware.TrackInFlight(serverHandler.InFlight))
 
 	proxyGroup.Any("/*path", proxyServer.HandleProxy)
 }
@@ -0,0 +1,83 @@
+package selftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	mockModel   = "selftest-model"
+	mockReply   = "gpt-load selftest ok"
+	mockOKKey   = "sk-selftest-ok"
+	mockFailKey = "sk-selftest-fail"
+)
+
+// mockUpstream is an OpenAI compatible upstream that fails every request made with the failing key.
+type mockUpstream struct {
+	server   *http.Server
+	listener net.Listener
+	failures atomic.Int64
+	requests atomic.Int64
+}
+
+func startMockUpstream(addr string) (*mockUpstream, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for the mock upstream on %s: %w", addr, err)
+	}
+	m := &mockUpstream{listener: listener}
+	m.server = &http.Server{Handler: m, ReadHeaderTimeout: 10 * time.Second}
+	go m.server.Serve(listener)
+	return m, nil
+}
+
+// url is the address of the mock upstream as seen from this host.
+func (m *mockUpstream) url() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+func (m *mockUpstream) close() {
+	m.server.Close()
+}
+
+func (m *mockUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.requests.Add(1)
+	if r.Header.Get("Authorization") == "Bearer "+mockFailKey {
+		m.failures.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, `{"error":{"message":"selftest induced failure","type":"server_error"}}`)
+		return
+	}
+
+	if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/models") {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"object":"list","data":[{"id":%q,"object":"model"}]}`, mockModel)
+		return
+	}
+
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	if !body.Stream {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"selftest","object":"chat.completion","model":%q,"choices":[{"index":0,"message":{"role":"assistant","content":%q},"finish_reason":"stop"}]}`, mockModel, mockReply)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	flusher, _ := w.(http.Flusher)
+	for _, word := range strings.Fields(mockReply) {
+		fmt.Fprintf(w, "data: {\"id\":\"selftest\",\"object\":\"chat.completion.chunk\",\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", word+" ")
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
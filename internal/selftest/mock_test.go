@@ -0,0 +1,55 @@
+package selftest
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMockUpstream(t *testing.T) {
+	mock, err := startMockUpstream("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.close()
+
+	send := func(key, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPost, mock.url()+"/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+key)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp, string(data)
+	}
+
+	if resp, _ := send(mockFailKey, `{}`); resp.StatusCode != http.StatusInternalServerError || mock.failures.Load() != 1 {
+		t.Errorf("failing key: status = %d, failures = %d", resp.StatusCode, mock.failures.Load())
+	}
+	if resp, body := send(mockOKKey, `{}`); resp.StatusCode != http.StatusOK || !strings.Contains(body, mockReply) {
+		t.Errorf("completion: status = %d, body = %s", resp.StatusCode, body)
+	}
+	resp, body := send(mockOKKey, `{"stream":true}`)
+	if resp.Header.Get("Content-Type") != "text/event-stream" || !strings.HasSuffix(body, "data: [DONE]\n\n") {
+		t.Errorf("stream: content type = %q, body = %s", resp.Header.Get("Content-Type"), body)
+	}
+}
+
+func TestSuiteValidate(t *testing.T) {
+	if err := DefaultSuite().Validate(); err != nil {
+		t.Errorf("DefaultSuite().Validate() = %v", err)
+	}
+	invalid := []Suite{
+		{},
+		{Checks: []Check{{Name: "unknown", Type: "latency"}}},
+		{Checks: []Check{{Name: "retry on a group", Type: CheckRetry, Group: "openai"}}},
+	}
+	for _, suite := range invalid {
+		if suite.Validate() == nil {
+			t.Errorf("Validate() of %+v = nil", suite)
+		}
+	}
+}
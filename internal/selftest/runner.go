@@ -0,0 +1,393 @@
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"gpt-load/internal/client"
+	"gpt-load/internal/handler"
+)
+
+// Result statuses.
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// groupReadyTimeout bounds the wait for a new group to be served by the instance.
+const groupReadyTimeout = 10 * time.Second
+
+// Options configures a run.
+type Options struct {
+	// BaseURL is the address of the instance, e.g. "http://localhost:3001".
+	BaseURL string
+	AuthKey string
+	// ProxyKey authenticates proxy checks that target an existing group.
+	ProxyKey string
+	// MockListen is the address the mock upstream listens on.
+	MockListen string
+	// MockURL is the address of the mock upstream as seen from the instance. It defaults to
+	// the listen address, which suits an instance running on the same host.
+	MockURL string
+	// DisableMock skips the checks that need the mock upstream.
+	DisableMock bool
+	// Timeout bounds each check.
+	Timeout time.Duration
+}
+
+// Result is the outcome of one check.
+type Result struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Report is the outcome of a run.
+type Report struct {
+	BaseURL    string    `json:"base_url"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Passed     int       `json:"passed"`
+	Failed     int       `json:"failed"`
+	Skipped    int       `json:"skipped"`
+	Results    []Result  `json:"results"`
+}
+
+// OK reports whether no check failed.
+func (r *Report) OK() bool {
+	return r.Failed == 0
+}
+
+type runner struct {
+	opts   Options
+	client *client.Client
+	http   *http.Client
+
+	mock         *mockUpstream
+	mockGroup    *client.Group
+	mockProxyKey string
+	mockSetupErr error
+}
+
+// Run runs the suite against the instance and reports the outcome of every check.
+func Run(ctx context.Context, suite Suite, opts Options) (*Report, error) {
+	if err := suite.Validate(); err != nil {
+		return nil, err
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	c, err := client.New(opts.BaseURL, opts.AuthKey, client.WithRetries(0))
+	if err != nil {
+		return nil, err
+	}
+	r := &runner{opts: opts, client: c, http: &http.Client{}}
+	defer r.teardownMock()
+
+	report := &Report{BaseURL: opts.BaseURL, StartedAt: time.Now()}
+	if !opts.DisableMock && slices.ContainsFunc(suite.Checks, Check.needsMock) {
+		r.mockSetupErr = r.setupMock(ctx)
+	}
+
+	for _, check := range suite.Checks {
+		start := time.Now()
+		result := Result{Name: check.Name, Type: check.Type}
+		switch {
+		case check.needsMock() && opts.DisableMock:
+			result.Status, result.Message = StatusSkip, "needs the mock upstream"
+		case check.needsMock() && r.mockSetupErr != nil:
+			result.Status, result.Message = StatusFail, "mock upstream setup failed: "+r.mockSetupErr.Error()
+		case check.Type == CheckProxy && check.Group != "" && opts.ProxyKey == "":
+			result.Status, result.Message = StatusSkip, "needs a proxy key for group "+check.Group
+		default:
+			checkCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+			message, err := r.run(checkCtx, check)
+			cancel()
+			if err != nil {
+				result.Status, result.Message = StatusFail, err.Error()
+			} else {
+				result.Status, result.Message = StatusPass, message
+			}
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+
+		switch result.Status {
+		case StatusPass:
+			report.Passed++
+		case StatusFail:
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+		report.Results = append(report.Results, result)
+	}
+	report.DurationMs = time.Since(report.StartedAt).Milliseconds()
+	return report, nil
+}
+
+func (r *runner) run(ctx context.Context, check Check) (string, error) {
+	switch check.Type {
+	case CheckHealth:
+		return r.checkHealth(ctx)
+	case CheckAuth:
+		return r.checkAuth(ctx)
+	case CheckProxy:
+		return r.checkProxy(ctx, check)
+	case CheckRetry:
+		return r.checkRetry(ctx)
+	case CheckMetrics:
+		return r.checkMetrics(ctx)
+	}
+	return "", fmt.Errorf("unknown check type %q", check.Type)
+}
+
+// setupMock starts the mock upstream and creates a temporary group with a working and a
+// failing key, which retries failed attempts and never blacklists keys.
+func (r *runner) setupMock(ctx context.Context) error {
+	listen := r.opts.MockListen
+	if listen == "" {
+		listen = "127.0.0.1:0"
+	}
+	mock, err := startMockUpstream(listen)
+	if err != nil {
+		return err
+	}
+	r.mock = mock
+
+	mockURL := r.opts.MockURL
+	if mockURL == "" {
+		mockURL = mock.url()
+	}
+	upstreams, _ := json.Marshal([]map[string]any{{"url": mockURL, "weight": 1}})
+	suffix := randomHex(4)
+	r.mockProxyKey = "sk-selftest-proxy-" + randomHex(12)
+
+	group, err := r.client.CreateGroup(ctx, handler.GroupCreateRequest{
+		Name:        "selftest-" + suffix,
+		DisplayName: "Selftest " + suffix,
+		Description: "Temporary group of gpt-load selftest, safe to delete",
+		GroupType:   "standard",
+		Upstreams:   upstreams,
+		ChannelType: "openai",
+		TestModel:   mockModel,
+		Config: map[string]any{
+			"max_retries":         2,
+			"blacklist_threshold": 0,
+		},
+		ProxyKeys: r.mockProxyKey,
+	})
+	if err != nil {
+		return fmt.Errorf("create group: %w", err)
+	}
+	r.mockGroup = group
+
+	if _, err := r.client.AddKeys(ctx, group.ID, mockOKKey+"\n"+mockFailKey); err != nil {
+		return fmt.Errorf("add keys: %w", err)
+	}
+	return r.waitForGroup(ctx)
+}
+
+// waitForGroup waits until the instance serves the new group, which it learns about asynchronously.
+func (r *runner) waitForGroup(ctx context.Context) error {
+	deadline := time.Now().Add(groupReadyTimeout)
+	for {
+		status, _, err := r.proxy(ctx, r.mockGroup.Name, r.mockProxyKey, Check{Method: http.MethodGet, Path: "/v1/models"})
+		if err == nil && status == http.StatusOK {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if err == nil {
+				err = fmt.Errorf("HTTP %d", status)
+			}
+			return fmt.Errorf("group %s not served within %s, is the mock upstream at %s reachable from the instance? last answer: %w",
+				r.mockGroup.Name, groupReadyTimeout, r.mockURL(), err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+func (r *runner) mockURL() string {
+	if r.opts.MockURL != "" {
+		return r.opts.MockURL
+	}
+	return r.mock.url()
+}
+
+func (r *runner) teardownMock() {
+	if r.mockGroup != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), r.opts.Timeout)
+		defer cancel()
+		_ = r.client.DeleteGroup(ctx, r.mockGroup.ID)
+	}
+	if r.mock != nil {
+		r.mock.close()
+	}
+}
+
+func (r *runner) checkHealth(ctx context.Context) (string, error) {
+	health, err := r.client.Health(ctx)
+	if err != nil {
+		return "", err
+	}
+	if health.Status != "healthy" {
+		return "", fmt.Errorf("status is %q, database %q", health.Status, health.Database)
+	}
+	return fmt.Sprintf("database %s, uptime %s", health.Database, health.Uptime), nil
+}
+
+func (r *runner) checkAuth(ctx context.Context) (string, error) {
+	wrong, err := client.New(r.opts.BaseURL, "selftest-wrong-key-"+randomHex(8), client.WithRetries(0))
+	if err != nil {
+		return "", err
+	}
+	if ok, err := wrong.Login(ctx); err != nil || ok {
+		return "", fmt.Errorf("login with a wrong auth key: accepted = %t, error = %v", ok, err)
+	}
+	if ok, err := r.client.Login(ctx); err != nil || !ok {
+		return "", fmt.Errorf("login with the auth key: accepted = %t, error = %v", ok, err)
+	}
+	if _, err := r.client.GetChannelTypes(ctx); err != nil {
+		return "", fmt.Errorf("admin API with the auth key: %w", err)
+	}
+
+	if r.mockGroup == nil {
+		return "admin API checked, proxy authentication needs the mock group", nil
+	}
+	status, _, err := r.proxy(ctx, r.mockGroup.Name, "sk-selftest-wrong", Check{Method: http.MethodGet, Path: "/v1/models"})
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusUnauthorized {
+		return "", fmt.Errorf("proxy request with a wrong proxy key answered %d, want 401", status)
+	}
+	return "admin API and proxy reject wrong keys", nil
+}
+
+func (r *runner) checkProxy(ctx context.Context, check Check) (string, error) {
+	group, proxyKey := check.Group, r.opts.ProxyKey
+	if group == "" {
+		group, proxyKey = r.mockGroup.Name, r.mockProxyKey
+	}
+
+	status, body, err := r.proxy(ctx, group, proxyKey, check)
+	if err != nil {
+		return "", err
+	}
+	want := check.ExpectStatus
+	if want == 0 {
+		want = http.StatusOK
+	}
+	if status != want {
+		return "", fmt.Errorf("HTTP %d, want %d: %s", status, want, truncate(body, 200))
+	}
+	if check.ExpectBodyContains != "" && !strings.Contains(body, check.ExpectBodyContains) {
+		return "", fmt.Errorf("body does not contain %q: %s", check.ExpectBodyContains, truncate(body, 200))
+	}
+	return fmt.Sprintf("HTTP %d from group %s", status, group), nil
+}
+
+func (r *runner) checkRetry(ctx context.Context) (string, error) {
+	failuresBefore := r.mock.failures.Load()
+	// Keys are rotated, so one of two requests starts on the failing key.
+	const requests = 2
+	for i := range requests {
+		status, body, err := r.proxy(ctx, r.mockGroup.Name, r.mockProxyKey, Check{})
+		if err != nil {
+			return "", err
+		}
+		if status != http.StatusOK {
+			return "", fmt.Errorf("request %d answered %d: %s", i+1, status, truncate(body, 200))
+		}
+	}
+	failed := r.mock.failures.Load() - failuresBefore
+	if failed == 0 {
+		return "", errors.New("no request reached the failing key, retries were not exercised")
+	}
+	return fmt.Sprintf("%d requests succeeded after %d failed attempts", requests, failed), nil
+}
+
+func (r *runner) checkMetrics(ctx context.Context) (string, error) {
+	if _, err := r.client.GetDashboardStats(ctx, client.StatsRange{}); err != nil {
+		return "", fmt.Errorf("dashboard statistics: %w", err)
+	}
+	if r.mockGroup == nil {
+		return "dashboard statistics served", nil
+	}
+	if _, err := r.client.GetGroupStats(ctx, r.mockGroup.ID); err != nil {
+		return "", fmt.Errorf("group statistics: %w", err)
+	}
+	return "dashboard and group statistics served", nil
+}
+
+// proxy sends a request through a group and returns the status and body of the answer.
+// Without a method and path it sends a chat completion, streamed if the check says so.
+func (r *runner) proxy(ctx context.Context, group, proxyKey string, check Check) (int, string, error) {
+	method, path, body := check.Method, check.Path, []byte(check.Body)
+	if method == "" {
+		method = http.MethodPost
+	}
+	if path == "" {
+		path = "/v1/chat/completions"
+	}
+	if len(body) == 0 && method == http.MethodPost {
+		body, _ = json.Marshal(map[string]any{
+			"model":    mockModel,
+			"messages": []map[string]string{{"role": "user", "content": "ping"}},
+			"stream":   check.Stream,
+		})
+	}
+
+	url := strings.TrimRight(r.opts.BaseURL, "/") + "/proxy/" + group + path
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+proxyKey)
+
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, "", err
+	}
+	if check.Stream && resp.StatusCode == http.StatusOK && !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		return 0, "", fmt.Errorf("streamed response has Content-Type %q", resp.Header.Get("Content-Type"))
+	}
+	return resp.StatusCode, string(data), nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
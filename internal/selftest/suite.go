@@ -0,0 +1,103 @@
+// Package selftest runs end-to-end checks against a live gpt-load instance, for post-deploy
+// verification. Checks that need an upstream use a temporary group pointing to a mock upstream
+// served by the test run itself.
+package selftest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Check types.
+const (
+	// CheckHealth expects the instance to report itself healthy.
+	CheckHealth = "health"
+	// CheckAuth expects the admin API and the proxy to reject wrong keys and accept the right one.
+	CheckAuth = "auth"
+	// CheckProxy sends a request through a group and checks the answer, streamed if Stream is set.
+	CheckProxy = "proxy"
+	// CheckRetry expects a request to succeed on another key when the first one fails upstream.
+	CheckRetry = "retry"
+	// CheckMetrics expects the dashboard and group statistics to be served.
+	CheckMetrics = "metrics"
+)
+
+// Check is one entry of a suite.
+type Check struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	// Group is an existing group to send a proxy check to, with the proxy key of the run.
+	// When empty, the check uses the temporary group of the mock upstream.
+	Group  string          `json:"group,omitempty"`
+	Method string          `json:"method,omitempty"`
+	Path   string          `json:"path,omitempty"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Stream bool            `json:"stream,omitempty"`
+	// ExpectStatus defaults to 200.
+	ExpectStatus       int    `json:"expect_status,omitempty"`
+	ExpectBodyContains string `json:"expect_body_contains,omitempty"`
+}
+
+// Suite is an ordered list of checks.
+type Suite struct {
+	Checks []Check `json:"checks"`
+}
+
+// DefaultSuite covers health, authentication, routing, streaming, retries and statistics
+// using the mock upstream.
+func DefaultSuite() Suite {
+	return Suite{Checks: []Check{
+		{Name: "instance is healthy", Type: CheckHealth},
+		{Name: "wrong keys are rejected", Type: CheckAuth},
+		{Name: "request is routed to the group upstream", Type: CheckProxy, ExpectBodyContains: mockReply},
+		{Name: "response is streamed", Type: CheckProxy, Stream: true, ExpectBodyContains: "[DONE]"},
+		{Name: "failed attempt is retried on another key", Type: CheckRetry},
+		{Name: "statistics are served", Type: CheckMetrics},
+	}}
+}
+
+// LoadSuite reads a suite from a JSON file.
+func LoadSuite(path string) (Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Suite{}, err
+	}
+	var suite Suite
+	if err := json.Unmarshal(data, &suite); err != nil {
+		return Suite{}, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	if err := suite.Validate(); err != nil {
+		return Suite{}, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return suite, nil
+}
+
+// Validate checks that the suite only holds known check types.
+func (s Suite) Validate() error {
+	if len(s.Checks) == 0 {
+		return fmt.Errorf("no checks")
+	}
+	for i, check := range s.Checks {
+		switch check.Type {
+		case CheckHealth, CheckAuth, CheckProxy, CheckRetry, CheckMetrics:
+		default:
+			return fmt.Errorf("check %d (%q): unknown type %q", i+1, check.Name, check.Type)
+		}
+		if check.Group != "" && check.Type != CheckProxy {
+			return fmt.Errorf("check %d (%q): only proxy checks can target a group", i+1, check.Name)
+		}
+	}
+	return nil
+}
+
+// needsMock reports whether the check runs against the mock upstream.
+func (c Check) needsMock() bool {
+	switch c.Type {
+	case CheckProxy:
+		return c.Group == ""
+	case CheckRetry:
+		return true
+	}
+	return false
+}
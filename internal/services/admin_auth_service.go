@@ -0,0 +1,391 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"gpt-load/internal/totp"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	// AdminSessionTokenPrefix starts every session token, which tells them apart from AUTH_KEY
+	// without a database lookup.
+	AdminSessionTokenPrefix = "gls_"
+	maxLoginFailures        = 5
+	loginLockout            = 15 * time.Minute
+	minAdminPasswordLength  = 8
+	totpIssuer              = "GPT-Load"
+)
+
+// AdminSessionToken is the token of a login session, sent as the bearer token of the
+// management API.
+type AdminSessionToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AdminAccount describes the admin account and how the management API can be signed in to.
+type AdminAccount struct {
+	Configured  bool       `json:"configured"`
+	Username    string     `json:"username,omitempty"`
+	TOTPEnabled bool       `json:"totp_enabled"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+	KeyLogin    bool       `json:"key_login"`
+}
+
+// TOTPSetup is a new two-factor secret waiting to be confirmed with a code.
+type TOTPSetup struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+// AdminAuthService signs in to the admin account with a password and an optional TOTP code,
+// and keeps the login sessions in the database so that every instance accepts them. An
+// account is locked for a while after repeated failed logins.
+type AdminAuthService struct {
+	db            *gorm.DB
+	configManager types.ConfigManager
+	encryptionSvc encryption.Service
+	// dummyHash is compared for unknown usernames so that they take as long as wrong passwords.
+	dummyHash []byte
+}
+
+// NewAdminAuthService creates a new AdminAuthService.
+func NewAdminAuthService(db *gorm.DB, configManager types.ConfigManager, encryptionSvc encryption.Service) *AdminAuthService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("gpt-load"), bcrypt.DefaultCost)
+	return &AdminAuthService{
+		db:            db,
+		configManager: configManager,
+		encryptionSvc: encryptionSvc,
+		dummyHash:     dummyHash,
+	}
+}
+
+// EnsureAccount creates the admin account from ADMIN_USERNAME and ADMIN_PASSWORD when
+// there is none yet.
+func (s *AdminAuthService) EnsureAccount() error {
+	authConfig := s.configManager.GetAuthConfig()
+	if authConfig.AdminPassword == "" {
+		return nil
+	}
+	var count int64
+	if err := s.db.Model(&models.AdminUser{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(authConfig.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Create(&models.AdminUser{Username: authConfig.AdminUsername, PasswordHash: string(hash)}).Error; err != nil {
+		return err
+	}
+	logrus.Infof("Admin account %q created from ADMIN_PASSWORD", authConfig.AdminUsername)
+	return nil
+}
+
+// Login checks a username, password and, with two-factor authentication enabled, a TOTP
+// code, and starts a session.
+func (s *AdminAuthService) Login(username, password, totpCode, sourceIP, userAgent string) (*AdminSessionToken, error) {
+	var user models.AdminUser
+	if err := s.db.Where("username = ?", username).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, app_errors.ParseDBError(err)
+		}
+		_ = bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		return nil, NewI18nError(app_errors.ErrUnauthorized, "auth.invalid_credentials", nil)
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return nil, lockedError(*user.LockedUntil, now)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, s.recordFailure(&user, now, "auth.invalid_credentials")
+	}
+
+	updates := map[string]any{"failed_attempts": 0, "locked_until": nil, "last_login_at": now}
+	if user.TOTPEnabled {
+		if strings.TrimSpace(totpCode) == "" {
+			return nil, NewI18nError(app_errors.ErrTOTPRequired, "auth.totp_required", nil)
+		}
+		counter, ok := s.checkTOTP(&user, totpCode, now)
+		if !ok {
+			return nil, s.recordFailure(&user, now, "auth.invalid_totp")
+		}
+		updates["totp_last_step"] = counter
+	}
+	if err := s.db.Model(&user).Updates(updates).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	return s.startSession(models.AdminSession{UserID: user.ID, Role: types.AdminRoleAdmin}, sourceIP, userAgent, now)
+}
+
+// recordFailure counts a failed login of an account and locks it once the failures reach
+// the limit.
+func (s *AdminAuthService) recordFailure(user *models.AdminUser, now time.Time, messageID string) error {
+	updates := map[string]any{"failed_attempts": gorm.Expr("failed_attempts + 1")}
+	locked := user.FailedAttempts+1 >= maxLoginFailures
+	if locked {
+		updates = map[string]any{"failed_attempts": 0, "locked_until": now.Add(loginLockout)}
+	}
+	if err := s.db.Model(user).Updates(updates).Error; err != nil {
+		logrus.WithError(err).Error("Failed to record a failed admin login")
+	}
+	if locked {
+		logrus.Warnf("Admin account %q locked for %s after %d failed logins", user.Username, loginLockout, maxLoginFailures)
+		return lockedError(now.Add(loginLockout), now)
+	}
+	return NewI18nError(app_errors.ErrUnauthorized, messageID, nil)
+}
+
+func lockedError(until, now time.Time) error {
+	minutes := int(math.Ceil(until.Sub(now).Minutes()))
+	return NewI18nError(app_errors.ErrAccountLocked, "auth.account_locked", map[string]any{"minutes": minutes})
+}
+
+// checkTOTP validates a code against the secret of an account, rejecting codes of a time step
+// already used.
+func (s *AdminAuthService) checkTOTP(user *models.AdminUser, code string, now time.Time) (int64, bool) {
+	secret, err := s.encryptionSvc.Decrypt(user.TOTPSecret)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to decrypt the TOTP secret of the admin account")
+		return 0, false
+	}
+	counter, ok := totp.Validate(secret, code, now)
+	if !ok || counter <= user.TOTPLastStep {
+		return 0, false
+	}
+	return counter, true
+}
+
+// StartSSOSession starts a session of a user signed in with single sign-on.
+func (s *AdminAuthService) StartSSOSession(identity, role, sourceIP, userAgent string) (*AdminSessionToken, error) {
+	session := models.AdminSession{Identity: utils.TruncateString(identity, 255), Role: role}
+	return s.startSession(session, sourceIP, userAgent, time.Now())
+}
+
+func (s *AdminAuthService) startSession(session models.AdminSession, sourceIP, userAgent string, now time.Time) (*AdminSessionToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	token := AdminSessionTokenPrefix + hex.EncodeToString(raw)
+	ttl := time.Duration(s.configManager.GetAuthConfig().SessionTTLHours) * time.Hour
+	session.TokenHash = hashSessionToken(token)
+	session.ExpiresAt = now.Add(ttl)
+	session.SourceIP = utils.TruncateString(sourceIP, 64)
+	session.UserAgent = utils.TruncateString(userAgent, 512)
+	if err := s.db.Create(&session).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if err := s.db.Where("expires_at < ?", now).Delete(&models.AdminSession{}).Error; err != nil {
+		logrus.WithError(err).Warn("Failed to delete expired admin sessions")
+	}
+	return &AdminSessionToken{Token: token, ExpiresAt: session.ExpiresAt}, nil
+}
+
+func hashSessionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate returns the session of a token, nil when there is none or it has expired.
+func (s *AdminAuthService) Authenticate(token string) *models.AdminSession {
+	if !strings.HasPrefix(token, AdminSessionTokenPrefix) {
+		return nil
+	}
+	var session models.AdminSession
+	err := s.db.Where("token_hash = ? AND expires_at > ?", hashSessionToken(token), time.Now()).
+		First(&session).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logrus.WithError(err).Error("Failed to look up an admin session")
+		}
+		return nil
+	}
+	return &session
+}
+
+// Logout ends the session of a token.
+func (s *AdminAuthService) Logout(token string) error {
+	return s.db.Where("token_hash = ?", hashSessionToken(token)).Delete(&models.AdminSession{}).Error
+}
+
+// account returns the admin account, nil when there is none.
+func (s *AdminAuthService) account() (*models.AdminUser, error) {
+	var user models.AdminUser
+	if err := s.db.Order("id").First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &user, nil
+}
+
+// Account describes the admin account.
+func (s *AdminAuthService) Account() (*AdminAccount, error) {
+	user, err := s.account()
+	if err != nil {
+		return nil, err
+	}
+	result := &AdminAccount{KeyLogin: s.configManager.GetAuthConfig().KeyLogin}
+	if user != nil {
+		result.Configured = true
+		result.Username = user.Username
+		result.TOTPEnabled = user.TOTPEnabled
+		result.LastLoginAt = user.LastLoginAt
+		if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+			result.LockedUntil = user.LockedUntil
+		}
+	}
+	return result, nil
+}
+
+// SetPassword changes the password of the admin account and ends its other sessions than
+// keepToken. The current password is checked unless breakGlass is set, for callers
+// authenticated with AUTH_KEY, which also creates the account with username when there is
+// none and lifts a lockout.
+func (s *AdminAuthService) SetPassword(username, currentPassword, newPassword, keepToken string, breakGlass bool) error {
+	if len(newPassword) < minAdminPasswordLength {
+		return NewI18nError(app_errors.ErrValidation, "auth.password_too_short", map[string]any{"min": minAdminPasswordLength})
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash the password: %w", err)
+	}
+
+	user, err := s.account()
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		if !breakGlass {
+			return NewI18nError(app_errors.ErrResourceNotFound, "auth.account_not_configured", nil)
+		}
+		username = strings.TrimSpace(username)
+		if username == "" {
+			username = s.configManager.GetAuthConfig().AdminUsername
+		}
+		if err := s.db.Create(&models.AdminUser{Username: username, PasswordHash: string(hash)}).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		logrus.Infof("Admin account %q created", username)
+		return nil
+	}
+
+	if !breakGlass && bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)) != nil {
+		return NewI18nError(app_errors.ErrValidation, "auth.invalid_current_password", nil)
+	}
+	updates := map[string]any{"password_hash": string(hash), "failed_attempts": 0, "locked_until": nil}
+	if breakGlass && strings.TrimSpace(username) != "" {
+		updates["username"] = strings.TrimSpace(username)
+	}
+	if err := s.db.Model(user).Updates(updates).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return s.endOtherSessions(user.ID, keepToken)
+}
+
+func (s *AdminAuthService) endOtherSessions(userID uint, keepToken string) error {
+	query := s.db.Where("user_id = ?", userID)
+	if keepToken != "" {
+		query = query.Where("token_hash <> ?", hashSessionToken(keepToken))
+	}
+	if err := query.Delete(&models.AdminSession{}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
+
+// SetupTOTP generates a two-factor secret for the admin account, which takes effect once
+// EnableTOTP confirms a code of it. A new setup replaces a pending one.
+func (s *AdminAuthService) SetupTOTP() (*TOTPSetup, error) {
+	user, err := s.account()
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, NewI18nError(app_errors.ErrResourceNotFound, "auth.account_not_configured", nil)
+	}
+	if user.TOTPEnabled {
+		return nil, NewI18nError(app_errors.ErrValidation, "auth.totp_already_enabled", nil)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := s.encryptionSvc.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(user).Update("totp_secret", encrypted).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &TOTPSetup{Secret: secret, URL: totp.URL(totpIssuer, user.Username, secret)}, nil
+}
+
+// EnableTOTP turns two-factor authentication on with a code of the pending secret.
+func (s *AdminAuthService) EnableTOTP(code string) error {
+	user, err := s.account()
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return NewI18nError(app_errors.ErrResourceNotFound, "auth.account_not_configured", nil)
+	}
+	if user.TOTPEnabled {
+		return NewI18nError(app_errors.ErrValidation, "auth.totp_already_enabled", nil)
+	}
+	if user.TOTPSecret == "" {
+		return NewI18nError(app_errors.ErrValidation, "auth.totp_not_set_up", nil)
+	}
+	counter, ok := s.checkTOTP(user, code, time.Now())
+	if !ok {
+		return NewI18nError(app_errors.ErrValidation, "auth.invalid_totp", nil)
+	}
+	if err := s.db.Model(user).Updates(map[string]any{"totp_enabled": true, "totp_last_step": counter}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
+
+// DisableTOTP turns two-factor authentication off. A current code is required unless
+// breakGlass is set, for callers authenticated with AUTH_KEY.
+func (s *AdminAuthService) DisableTOTP(code string, breakGlass bool) error {
+	user, err := s.account()
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return NewI18nError(app_errors.ErrResourceNotFound, "auth.account_not_configured", nil)
+	}
+	if !breakGlass && user.TOTPEnabled {
+		if _, ok := s.checkTOTP(user, code, time.Now()); !ok {
+			return NewI18nError(app_errors.ErrValidation, "auth.invalid_totp", nil)
+		}
+	}
+	if err := s.db.Model(user).Updates(map[string]any{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	app_errors "gpt-load/internal/errors"
 	"gpt-load/internal/models"
+	"gpt-load/internal/syncer"
 	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -146,6 +147,7 @@ func (s *AggregateGroupService) GetSubGroups(ctx context.Context, groupID uint)
 	}
 
 	keyStatsMap := s.fetchSubGroupsKeyStats(ctx, subGroupIDs)
+	effectiveWeights := s.groupManager.subGroupManager.EffectiveWeights(groupID)
 
 	subGroups := make([]models.SubGroupInfo, 0, len(subGroupModels))
 	for _, subGroup := range subGroupModels {
@@ -163,6 +165,8 @@ func (s *AggregateGroupService) GetSubGroups(ctx context.Context, groupID uint)
 			TotalKeys:   stats.TotalKeys,
 			ActiveKeys:  stats.ActiveKeys,
 			InvalidKeys: stats.InvalidKeys,
+
+			EffectiveWeight: effectiveWeights[subGroup.ID],
 		})
 	}
 
@@ -233,7 +237,7 @@ func (s *AggregateGroupService) AddSubGroups(ctx context.Context, groupID uint,
 	}
 
 	// 触发缓存更新
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventSubGroupsChanged, groupID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after adding sub groups")
 	}
 
@@ -285,7 +289,7 @@ func (s *AggregateGroupService) UpdateSubGroupWeight(ctx context.Context, groupI
 	}
 
 	// 触发缓存更新
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventSubGroupsChanged, groupID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after updating sub group weight")
 	}
 
@@ -319,7 +323,7 @@ func (s *AggregateGroupService) DeleteSubGroup(ctx context.Context, groupID, sub
 	}
 
 	// 触发缓存更新
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventSubGroupsChanged, groupID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache after deleting sub group")
 	}
 
@@ -401,9 +405,9 @@ func (s *AggregateGroupService) fetchSubGroupsKeyStats(ctx context.Context, grou
 			var totalKeys, activeKeys int64
 			result := keyStatsResult{GroupID: gid}
 
-			// Query total keys
+			// Query total keys, keys parked in the reserve or outside their active lifecycle are not part of the pool
 			if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
-				Where("group_id = ?", gid).
+				Where("group_id = ? AND status IN ?", gid, []string{models.KeyStatusActive, models.KeyStatusInvalid}).
 				Count(&totalKeys).Error; err != nil {
 				result.Err = err
 				mu.Lock()
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"gpt-load/internal/config"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const alertWebhookTimeout = 10 * time.Second
+
+// AlertNotifier posts alerts to the alert_webhook_url setting.
+type AlertNotifier struct {
+	settingsManager *config.SystemSettingsManager
+	httpClient      *http.Client
+}
+
+// NewAlertNotifier creates a new AlertNotifier.
+func NewAlertNotifier(settingsManager *config.SystemSettingsManager) *AlertNotifier {
+	return &AlertNotifier{
+		settingsManager: settingsManager,
+		httpClient:      &http.Client{Timeout: alertWebhookTimeout},
+	}
+}
+
+// Send posts an alert event with a text summary for chat integrations next to the given
+// fields. It does nothing when no webhook is set.
+func (n *AlertNotifier) Send(event, text string, fields map[string]any) {
+	webhookURL := n.settingsManager.GetSettings().AlertWebhookURL
+	if webhookURL == "" {
+		return
+	}
+
+	payload := map[string]any{"event": event, "text": text}
+	for name, value := range fields {
+		payload[name] = value
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	resp, err := n.httpClient.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logrus.WithError(err).WithField("event", event).Warn("Failed to send the alert webhook")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		logrus.WithFields(logrus.Fields{"event": event, "status": resp.StatusCode}).Warn("Alert webhook rejected the alert")
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"gpt-load/internal/anomaly"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	anomalyCheckInterval = 5 * time.Minute
+	// anomalyCheckDelay leaves time for the request logs of an hour to reach the hourly stats.
+	anomalyCheckDelay     = 5 * time.Minute
+	activeAnomaliesKey    = "anomalies:active"
+	anomalyCheckedHourKey = "anomalies:checked_hour"
+)
+
+// AnomalyService compares the hourly stats of every group with the same hour of the previous
+// days once an hour ends, keeps the anomalies of the last hour in the store for the dashboard
+// and sends them to the alert webhook. The check runs on the leading master only.
+type AnomalyService struct {
+	db              *gorm.DB
+	store           store.Store
+	settingsManager *config.SystemSettingsManager
+	elector         *cluster.Elector
+	notifier        *AlertNotifier
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewAnomalyService creates a new AnomalyService.
+func NewAnomalyService(db *gorm.DB, store store.Store, settingsManager *config.SystemSettingsManager, elector *cluster.Elector, notifier *AlertNotifier) *AnomalyService {
+	return &AnomalyService{
+		db:              db,
+		store:           store,
+		settingsManager: settingsManager,
+		elector:         elector,
+		notifier:        notifier,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins checking every ended hour.
+func (s *AnomalyService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Anomaly service started")
+}
+
+// Stop stops the checks, respecting the context for shutdown timeout.
+func (s *AnomalyService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("AnomalyService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("AnomalyService stop timed out.")
+	}
+}
+
+// Active returns the anomalies found in the last checked hour.
+func (s *AnomalyService) Active() []anomaly.Anomaly {
+	data, err := s.store.Get(activeAnomaliesKey)
+	if err != nil {
+		return []anomaly.Anomaly{}
+	}
+	var anomalies []anomaly.Anomaly
+	if err := json.Unmarshal(data, &anomalies); err != nil {
+		return []anomaly.Anomaly{}
+	}
+	return anomalies
+}
+
+func (s *AnomalyService) run() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(anomalyCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// check judges the last ended hour once.
+func (s *AnomalyService) check() {
+	if !s.elector.IsLeader() {
+		return
+	}
+	settings := s.settingsManager.GetSettings()
+	if settings.AnomalySigma <= 0 {
+		if err := s.store.Delete(activeAnomaliesKey); err != nil {
+			logrus.WithError(err).Debug("AnomalyService: Failed to clear the active anomalies")
+		}
+		return
+	}
+
+	hour := time.Now().Add(-anomalyCheckDelay).Truncate(time.Hour).Add(-time.Hour)
+	if checked, err := s.store.Get(anomalyCheckedHourKey); err == nil && string(checked) == fmt.Sprint(hour.Unix()) {
+		return
+	}
+
+	anomalies, err := s.detect(hour, float64(settings.AnomalySigma), int64(settings.AnomalyMinRequests))
+	if err != nil {
+		logrus.WithError(err).Error("AnomalyService: Failed to check the hourly stats")
+		return
+	}
+
+	data, err := json.Marshal(anomalies)
+	if err != nil {
+		return
+	}
+	// Anomalies stay active until the next hour is checked
+	if err := s.store.Set(activeAnomaliesKey, data, 2*time.Hour); err != nil {
+		logrus.WithError(err).Error("AnomalyService: Failed to store the active anomalies")
+		return
+	}
+	if err := s.store.Set(anomalyCheckedHourKey, []byte(fmt.Sprint(hour.Unix())), 2*time.Hour); err != nil {
+		logrus.WithError(err).Warn("AnomalyService: Failed to store the checked hour")
+	}
+
+	for _, a := range anomalies {
+		logrus.WithFields(logrus.Fields{
+			"group":    a.GroupName,
+			"metric":   a.Metric,
+			"value":    a.Value,
+			"baseline": a.Baseline,
+			"sigma":    a.Sigma,
+		}).Warn("Traffic anomaly detected")
+	}
+	if len(anomalies) > 0 {
+		s.sendAlert(hour, anomalies)
+	}
+}
+
+// detect compares the stats of every group at hour with the same hour of the previous days.
+func (s *AnomalyService) detect(hour time.Time, sigma float64, minRequests int64) ([]anomaly.Anomaly, error) {
+	hours := []time.Time{hour}
+	for day := 1; day <= anomaly.BaselineDays; day++ {
+		hours = append(hours, hour.AddDate(0, 0, -day))
+	}
+
+	var stats []models.GroupHourlyStat
+	if err := s.db.Where("time IN ?", hours).Find(&stats).Error; err != nil {
+		return nil, err
+	}
+
+	current := make(map[uint]anomaly.Sample)
+	baselines := make(map[uint][]anomaly.Sample)
+	for _, stat := range stats {
+		sample := anomaly.Sample{
+			Requests:    stat.SuccessCount + stat.FailureCount,
+			Failures:    stat.FailureCount,
+			RateLimited: stat.RateLimitedCount,
+			DurationMs:  stat.DurationMs,
+		}
+		if stat.Time.Equal(hour) {
+			current[stat.GroupID] = sample
+		} else {
+			baselines[stat.GroupID] = append(baselines[stat.GroupID], sample)
+		}
+	}
+	if len(current) == 0 {
+		return []anomaly.Anomaly{}, nil
+	}
+
+	var groups []models.Group
+	if err := s.db.Select("id", "name").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		names[group.ID] = group.Name
+	}
+
+	anomalies := []anomaly.Anomaly{}
+	for groupID, sample := range current {
+		name, ok := names[groupID]
+		if !ok {
+			continue
+		}
+		for _, a := range anomaly.Detect(sample, baselines[groupID], sigma, minRequests) {
+			a.GroupID = groupID
+			a.GroupName = name
+			a.Hour = hour
+			anomalies = append(anomalies, a)
+		}
+	}
+	return anomalies, nil
+}
+
+// sendAlert sends the anomalies of an hour to the alert webhook.
+func (s *AnomalyService) sendAlert(hour time.Time, anomalies []anomaly.Anomaly) {
+	text := fmt.Sprintf("GPT-Load: %d traffic anomalies in the hour of %s", len(anomalies), hour.Format(time.RFC3339))
+	for _, a := range anomalies {
+		text += "\n" + a.String()
+	}
+	s.notifier.Send("traffic_anomaly", text, map[string]any{"hour": hour, "anomalies": anomalies})
+}
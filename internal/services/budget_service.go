@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	BudgetUsageKeyPrefix = "budget_usage:"
+	budgetPeriodLayout   = "2006-01"
+	budgetSeedLockTTL    = 30 * time.Second
+
+	// budgetSeededSuffix marks the field of a group in the usage hash as seeded, in a field
+	// of its own next to the counter
+	budgetSeededSuffix = ":seeded"
+)
+
+// BudgetUsage describes a group's consumption of its monthly soft budget.
+type BudgetUsage struct {
+	Period   string `json:"period"`
+	Budget   int    `json:"budget"`
+	Used     int64  `json:"used"`
+	Overflow int64  `json:"overflow"`
+}
+
+// BudgetService tracks per-group monthly request usage against soft budgets.
+// Requests beyond the budget are never blocked, they are only tagged as overflow
+// so that they can be attributed separately in stats and reports.
+type BudgetService struct {
+	db        *gorm.DB
+	store     store.Store
+	logWriter *RequestLogWriter
+	seeded    sync.Map
+}
+
+// NewBudgetService creates a new BudgetService.
+func NewBudgetService(db *gorm.DB, store store.Store, logWriter *RequestLogWriter) *BudgetService {
+	return &BudgetService{
+		db:        db,
+		store:     store,
+		logWriter: logWriter,
+	}
+}
+
+// Consume records one request against the group's budget for the current month
+// and reports whether the request falls into overflow usage.
+func (s *BudgetService) Consume(group *models.Group) bool {
+	budget := group.EffectiveConfig.MonthlyRequestBudget
+	if budget <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	usageKey := BudgetUsageKeyPrefix + now.Format(budgetPeriodLayout)
+	field := strconv.FormatUint(uint64(group.ID), 10)
+
+	seeded, err := s.ensureSeeded(usageKey, field, group.ID, now)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to seed budget usage from database")
+	}
+	if !seeded {
+		// The request is counted through its log by the seed
+		return false
+	}
+
+	used, err := s.store.HIncrBy(usageKey, field, 1)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Error("Failed to increment budget usage")
+		return false
+	}
+
+	return used > int64(budget)
+}
+
+// GetUsage returns the current month's budget usage for a group, or nil if the group has no budget.
+func (s *BudgetService) GetUsage(group *models.Group) (*BudgetUsage, error) {
+	budget := group.EffectiveConfig.MonthlyRequestBudget
+	if budget <= 0 {
+		return nil, nil
+	}
+
+	now := time.Now()
+	period := now.Format(budgetPeriodLayout)
+	usageKey := BudgetUsageKeyPrefix + period
+	field := strconv.FormatUint(uint64(group.ID), 10)
+
+	seeded, err := s.ensureSeeded(usageKey, field, group.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var used int64
+	if seeded {
+		usage, err := s.store.HGetAll(usageKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get budget usage from store: %w", err)
+		}
+		used, _ = strconv.ParseInt(usage[field], 10, 64)
+	} else if used, err = s.monthlyUsage(group.ID, now); err != nil {
+		return nil, err
+	}
+
+	return &BudgetUsage{
+		Period:   period,
+		Budget:   budget,
+		Used:     used,
+		Overflow: max(used-int64(budget), 0),
+	}, nil
+}
+
+// Recount replaces the current month's usage counter of a group, or of every group with a
+// counter when groupID is 0, with the count from the hourly stats table. Counters of past
+// months are removed. It returns the number of counters rewritten or removed.
+func (s *BudgetService) Recount(groupID uint) (int, error) {
+	now := time.Now()
+	usageKey := BudgetUsageKeyPrefix + now.Format(budgetPeriodLayout)
+
+	fields := []string{strconv.FormatUint(uint64(groupID), 10)}
+	changed := 0
+	if groupID == 0 {
+		usage, err := s.store.HGetAll(usageKey)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get budget usage from store: %w", err)
+		}
+		fields = fields[:0]
+		for field := range usage {
+			fields = append(fields, field)
+		}
+
+		keys, err := s.store.Keys(BudgetUsageKeyPrefix)
+		if err != nil {
+			return 0, fmt.Errorf("failed to list budget usage: %w", err)
+		}
+		for _, key := range keys {
+			// 只删除往月的计数，种子锁等辅助键保持不变
+			if key < usageKey && !strings.Contains(strings.TrimPrefix(key, BudgetUsageKeyPrefix), ":") {
+				if err := s.store.Delete(key); err != nil {
+					return changed, fmt.Errorf("failed to remove budget usage %s: %w", key, err)
+				}
+				changed++
+			}
+		}
+	}
+
+	for _, field := range fields {
+		id, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		total, err := s.monthlyUsage(uint(id), now)
+		if err != nil {
+			return changed, err
+		}
+		if err := s.store.HSet(usageKey, map[string]any{field: total, field + budgetSeededSuffix: 1}); err != nil {
+			return changed, fmt.Errorf("failed to reset budget usage: %w", err)
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// ensureSeeded initializes the usage counter of a group the first time it is seen in a period,
+// so that counters survive restarts and store resets, and reports whether it is seeded. Until
+// then requests are not counted, the seed counts them through their logs. The seed is added
+// in one step with the mark, so a counter is seeded once even by concurrent seeders.
+func (s *BudgetService) ensureSeeded(usageKey, field string, groupID uint, now time.Time) (bool, error) {
+	seededKey := usageKey + ":" + field
+	if _, ok := s.seeded.Load(seededKey); ok {
+		return true, nil
+	}
+
+	usage, err := s.store.HGetAll(usageKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to get budget usage from store: %w", err)
+	}
+	if _, exists := usage[field+budgetSeededSuffix]; exists {
+		s.seeded.Store(seededKey, struct{}{})
+		return true, nil
+	}
+
+	// The lock only spares the database concurrent seeds, a failed seed is retried once it expires
+	acquired, err := s.store.SetNX(seededKey+":seed_lock", []byte("1"), budgetSeedLockTTL)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire budget seed lock: %w", err)
+	}
+	if !acquired {
+		return false, nil
+	}
+
+	total, err := s.monthlyUsage(groupID, now)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := s.store.HIncrByOnce(usageKey, field+budgetSeededSuffix, field, total); err != nil {
+		return false, fmt.Errorf("failed to seed budget usage: %w", err)
+	}
+
+	s.seeded.Store(seededKey, struct{}{})
+	return true, nil
+}
+
+// monthlyUsage counts the group's requests of the current month: those in the hourly stats
+// table and those whose logs are not written to it yet, buffered by this instance or cached
+// in the store. The buffered logs are counted first, so that a log written meanwhile is
+// counted twice rather than missed.
+func (s *BudgetService) monthlyUsage(groupID uint, now time.Time) (int64, error) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	// Counted like the hourly stats count them
+	match := func(log *models.RequestLog) bool {
+		return log.RequestType != models.RequestTypeRetry && !log.Timestamp.Before(monthStart) &&
+			(log.GroupID == groupID || log.ParentGroupID == groupID)
+	}
+	var pending int64
+	if s.logWriter != nil {
+		pending = s.logWriter.Count(match)
+	}
+	cached, err := countCachedRequestLogs(s.store, match)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count cached request logs: %w", err)
+	}
+
+	var total int64
+	if err := s.db.Model(&models.GroupHourlyStat{}).
+		Select("COALESCE(SUM(success_count + failure_count), 0)").
+		Where("group_id = ? AND time >= ?", groupID, monthStart).
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to load monthly usage: %w", err)
+	}
+	return total + pending + cached, nil
+}
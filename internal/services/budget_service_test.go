@@ -0,0 +1,62 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBudgetSeed(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "budget.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, memStore := newTestLogWriter(types.RequestLogOverflowDrop)
+	budgets := NewBudgetService(database, memStore, writer)
+	group := &models.Group{ID: 1}
+	group.EffectiveConfig.MonthlyRequestBudget = 10
+	now := time.Now()
+
+	// Without the stats table the seed fails and the request is left to its log
+	if budgets.Consume(group) {
+		t.Error("a request was counted as overflow before the counter was seeded")
+	}
+	usageKey := BudgetUsageKeyPrefix + now.Format(budgetPeriodLayout)
+	if usage, _ := memStore.HGetAll(usageKey); len(usage) != 0 {
+		t.Fatalf("usage after a failed seed = %v, want no counter", usage)
+	}
+
+	if err := database.AutoMigrate(&models.GroupHourlyStat{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&models.GroupHourlyStat{Time: now.Truncate(time.Hour), GroupID: 1, SuccessCount: 4, FailureCount: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+	// Logs not written to the stats yet: buffered, cached in the store, and a retry that is not counted
+	writer.Enqueue(&models.RequestLog{ID: "buffered", GroupID: 2, ParentGroupID: 1, Timestamp: now})
+	writer.Enqueue(&models.RequestLog{ID: "retry", GroupID: 1, Timestamp: now, RequestType: models.RequestTypeRetry})
+	if err := cacheRequestLog(memStore, &models.RequestLog{ID: "cached", GroupID: 1, Timestamp: now}, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := memStore.Delete(usageKey + ":1:seed_lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second instance finds the counter seeded and does not add the seed again
+	budgets.Consume(group)
+	NewBudgetService(database, memStore, writer).Consume(group)
+	usage, err := budgets.GetUsage(group)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.Used != 9 {
+		t.Errorf("used = %d, want 7 seeded and 2 counted", usage.Used)
+	}
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	debugCaptureKeyPrefix = "debug_capture:"
+	// DebugCaptureTTL is how long a captured exchange stays retrievable.
+	DebugCaptureTTL = 24 * time.Hour
+	// DebugCaptureMaxBodySize is the number of bytes kept of each captured body.
+	DebugCaptureMaxBodySize = 32 * 1024
+)
+
+// ErrDebugCaptureNotFound is returned when no capture exists for a request log, or it has expired.
+var ErrDebugCaptureNotFound = errors.New("debug capture not found")
+
+// DebugCapture is one proxied exchange as seen by the upstream, with key material redacted.
+type DebugCapture struct {
+	RequestLogID      string            `json:"request_log_id"`
+	GroupID           uint              `json:"group_id"`
+	CapturedAt        time.Time         `json:"captured_at"`
+	Method            string            `json:"method"`
+	UpstreamURL       string            `json:"upstream_url"`
+	RequestHeaders    map[string]string `json:"request_headers"`
+	RequestBody       string            `json:"request_body"`
+	RequestTruncated  bool              `json:"request_truncated"`
+	StatusCode        int               `json:"status_code"`
+	ResponseHeaders   map[string]string `json:"response_headers,omitempty"`
+	ResponseBody      string            `json:"response_body"`
+	ResponseTruncated bool              `json:"response_truncated"`
+}
+
+// DebugCaptureService keeps sampled request and response bodies of groups in debug capture mode.
+type DebugCaptureService struct {
+	store store.Store
+}
+
+// NewDebugCaptureService creates a new DebugCaptureService.
+func NewDebugCaptureService(store store.Store) *DebugCaptureService {
+	return &DebugCaptureService{store: store}
+}
+
+// ShouldCapture decides whether a request of the group is captured. Capture is active while
+// debug_capture_rate is above 0 and debug_capture_until, if set, has not passed.
+func (s *DebugCaptureService) ShouldCapture(group *models.Group) bool {
+	cfg := group.EffectiveConfig
+	if cfg.DebugCaptureRate <= 0 {
+		return false
+	}
+	if cfg.DebugCaptureUntil != "" {
+		until, err := time.Parse(time.RFC3339, cfg.DebugCaptureUntil)
+		if err != nil || !time.Now().Before(until) {
+			return false
+		}
+	}
+	return cfg.DebugCaptureRate >= 100 || rand.IntN(100) < cfg.DebugCaptureRate
+}
+
+// Save stores the capture of a request log.
+func (s *DebugCaptureService) Save(capture *DebugCapture) {
+	data, err := json.Marshal(capture)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to marshal debug capture")
+		return
+	}
+	if err := s.store.Set(debugCaptureKeyPrefix+capture.RequestLogID, data, DebugCaptureTTL); err != nil {
+		logrus.WithError(err).Warn("Failed to store debug capture")
+	}
+}
+
+// Flush removes the captures of a group, or all captures when groupID is 0.
+func (s *DebugCaptureService) Flush(groupID uint) (int, error) {
+	keys, err := s.store.Keys(debugCaptureKeyPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list debug captures: %w", err)
+	}
+
+	removed := 0
+	for _, key := range keys {
+		if groupID != 0 {
+			capture, err := s.Get(strings.TrimPrefix(key, debugCaptureKeyPrefix))
+			if err != nil || capture.GroupID != groupID {
+				continue
+			}
+		}
+		if err := s.store.Delete(key); err != nil {
+			return removed, fmt.Errorf("failed to remove debug capture: %w", err)
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// Get returns the capture of a request log.
+func (s *DebugCaptureService) Get(requestLogID string) (*DebugCapture, error) {
+	data, err := s.store.Get(debugCaptureKeyPrefix + requestLogID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, ErrDebugCaptureNotFound
+		}
+		return nil, fmt.Errorf("failed to load debug capture: %w", err)
+	}
+
+	var capture DebugCapture
+	if err := json.Unmarshal(data, &capture); err != nil {
+		return nil, fmt.Errorf("failed to decode debug capture: %w", err)
+	}
+	return &capture, nil
+}
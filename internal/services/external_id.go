@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const maxExternalIDLength = 255
+
+// validateExternalID checks an external ID, which is used as a path segment.
+func validateExternalID(externalID string) error {
+	if externalID == "" || len(externalID) > maxExternalIDLength || strings.ContainsFunc(externalID, func(r rune) bool {
+		return r == '/' || unicode.IsSpace(r) || unicode.IsControl(r)
+	}) {
+		return NewI18nError(app_errors.ErrValidation, "validation.invalid_external_id", map[string]any{"max": maxExternalIDLength})
+	}
+	return nil
+}
+
+// releaseTrashedExternalID frees an external ID held by a group or key in the trash, so that
+// a resource deleted by a declarative tool can be created again. The trashed one keeps the
+// rest of its fields and can still be restored.
+func releaseTrashedExternalID(tx *gorm.DB, model any, externalID string) error {
+	return tx.Unscoped().Model(model).
+		Where("external_id = ? AND deleted_at IS NOT NULL", externalID).
+		Update("external_id", nil).Error
+}
+
+// FindGroupByExternalID returns the group carrying the external ID.
+func (s *GroupService) FindGroupByExternalID(ctx context.Context, externalID string) (*models.Group, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Where("external_id = ?", externalID).First(&group).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &group, nil
+}
+
+// UpsertGroupByExternalID creates the group carrying the external ID, or brings it in line
+// with params, and reports whether it was created. Enabled and maintenanceMessage are left
+// unchanged when nil. The group type of an existing group cannot change.
+func (s *GroupService) UpsertGroupByExternalID(ctx context.Context, externalID string, params GroupCreateParams, enabled *bool, maintenanceMessage *string) (*models.Group, bool, error) {
+	if err := validateExternalID(externalID); err != nil {
+		return nil, false, err
+	}
+
+	group, err := s.FindGroupByExternalID(ctx, externalID)
+	if err != nil && !errors.Is(err, app_errors.ErrResourceNotFound) {
+		return nil, false, err
+	}
+
+	if group == nil {
+		if err := releaseTrashedExternalID(s.db.WithContext(ctx), &models.Group{}, externalID); err != nil {
+			return nil, false, app_errors.ParseDBError(err)
+		}
+		params.ExternalID = externalID
+		created, err := s.CreateGroup(ctx, params)
+		if err != nil {
+			return nil, false, err
+		}
+		if (enabled == nil || *enabled) && maintenanceMessage == nil {
+			return created, true, nil
+		}
+		created, err = s.UpdateGroup(ctx, created.ID, GroupUpdateParams{Enabled: enabled, MaintenanceMessage: maintenanceMessage})
+		return created, true, err
+	}
+
+	groupType := strings.TrimSpace(params.GroupType)
+	if groupType == "" {
+		groupType = "standard"
+	}
+	if groupType != group.GroupType {
+		return nil, false, NewI18nError(app_errors.ErrValidation, "validation.group_type_immutable", nil)
+	}
+
+	update := GroupUpdateParams{
+		Name:                &params.Name,
+		DisplayName:         &params.DisplayName,
+		Description:         &params.Description,
+		ChannelType:         &params.ChannelType,
+		Sort:                &params.Sort,
+		Enabled:             enabled,
+		MaintenanceMessage:  maintenanceMessage,
+		ParamOverrides:      params.ParamOverrides,
+		ModelRedirectRules:  params.ModelRedirectRules,
+		ModelRedirectStrict: &params.ModelRedirectStrict,
+		Config:              params.Config,
+		HeaderRules:         &params.HeaderRules,
+		ProxyKeys:           &params.ProxyKeys,
+	}
+	// Omitted maps are cleared rather than kept, so that the group matches the request
+	if update.ParamOverrides == nil {
+		update.ParamOverrides = map[string]any{}
+	}
+	if update.ModelRedirectRules == nil {
+		update.ModelRedirectRules = map[string]string{}
+	}
+	if update.Config == nil {
+		update.Config = map[string]any{}
+	}
+	if group.GroupType != "aggregate" {
+		update.Upstreams, update.HasUpstreams = params.Upstreams, true
+		update.ChannelDefinition, update.HasChannelDefinition = params.ChannelDefinition, true
+		update.TestModel, update.HasTestModel = params.TestModel, true
+		update.ValidationEndpoint = &params.ValidationEndpoint
+	}
+
+	group, err = s.UpdateGroup(ctx, group.ID, update)
+	return group, false, err
+}
+
+// KeyUpsertParams is the desired state of a key managed by external ID. Notes and tags are
+// left unchanged when nil.
+type KeyUpsertParams struct {
+	GroupID  uint
+	KeyValue string
+	Notes    *string
+	Tags     *models.KeyTags
+}
+
+// FindKeyByExternalID returns the key carrying the external ID.
+func (s *KeyService) FindKeyByExternalID(externalID string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.DB.Where("external_id = ?", externalID).First(&key).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &key, nil
+}
+
+// UpsertKeyByExternalID creates the key carrying the external ID, or updates its notes and
+// tags, and reports whether it was created. A key of the group with the same value but no
+// external ID is adopted instead of duplicated. The group and value of a key cannot change.
+func (s *KeyService) UpsertKeyByExternalID(externalID string, params KeyUpsertParams) (*models.APIKey, bool, error) {
+	if err := validateExternalID(externalID); err != nil {
+		return nil, false, err
+	}
+	keyValue := strings.TrimSpace(params.KeyValue)
+	if !s.isValidKeyFormat(keyValue) {
+		return nil, false, NewI18nError(app_errors.ErrValidation, "validation.keys_text_empty", nil)
+	}
+	if params.Notes != nil {
+		notes := strings.TrimSpace(*params.Notes)
+		if utf8.RuneCountInString(notes) > 255 {
+			return nil, false, app_errors.NewAPIError(app_errors.ErrValidation, "notes length must be <= 255 characters")
+		}
+		params.Notes = &notes
+	}
+	keyHashes := encryption.Hashes(s.EncryptionSvc, keyValue)
+
+	key, err := s.FindKeyByExternalID(externalID)
+	if err != nil && !errors.Is(err, app_errors.ErrResourceNotFound) {
+		return nil, false, err
+	}
+	if key != nil {
+		if key.GroupID != params.GroupID || !slices.Contains(keyHashes, key.KeyHash) {
+			return nil, false, NewI18nError(app_errors.ErrValidation, "validation.key_external_id_immutable", nil)
+		}
+		return key, false, s.updateManagedKey(key, params)
+	}
+
+	if err := releaseTrashedExternalID(s.DB, &models.APIKey{}, externalID); err != nil {
+		return nil, false, app_errors.ParseDBError(err)
+	}
+
+	// A key added before it was managed by external ID is adopted
+	var existing models.APIKey
+	err = s.DB.Where("group_id = ? AND key_hash IN ?", params.GroupID, keyHashes).First(&existing).Error
+	switch {
+	case err == nil:
+		if existing.ExternalID != nil {
+			return nil, false, NewI18nError(app_errors.ErrValidation, "validation.key_external_id_conflict", map[string]any{"external_id": *existing.ExternalID})
+		}
+		existing.ExternalID = &externalID
+		if err := s.DB.Model(&existing).Update("external_id", externalID).Error; err != nil {
+			return nil, false, app_errors.ParseDBError(err)
+		}
+		return &existing, false, s.updateManagedKey(&existing, params)
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, false, app_errors.ParseDBError(err)
+	}
+
+	encryptedKey, err := s.EncryptionSvc.Encrypt(keyValue)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encrypt key: %w", err)
+	}
+	key = &models.APIKey{
+		GroupID:    params.GroupID,
+		KeyValue:   encryptedKey,
+		KeyHash:    s.EncryptionSvc.Hash(keyValue),
+		ExternalID: &externalID,
+		Status:     models.KeyStatusPending,
+	}
+	if params.Notes != nil {
+		key.Notes = *params.Notes
+	}
+	if params.Tags != nil {
+		key.Tags = *params.Tags
+	}
+	keys := []models.APIKey{*key}
+	if err := s.KeyProvider.AddKeys(params.GroupID, keys); err != nil {
+		return nil, false, app_errors.ParseDBError(err)
+	}
+	return &keys[0], true, nil
+}
+
+// updateManagedKey applies the notes and tags of params that differ from the key.
+func (s *KeyService) updateManagedKey(key *models.APIKey, params KeyUpsertParams) error {
+	updates := make(map[string]any)
+	if params.Notes != nil && *params.Notes != key.Notes {
+		key.Notes = *params.Notes
+		updates["notes"] = key.Notes
+	}
+	if params.Tags != nil && !slices.Equal(*params.Tags, key.Tags) {
+		key.Tags = *params.Tags
+		updates["tags"] = key.Tags
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	if err := s.DB.Model(key).Updates(updates).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
+
+// DeleteKeyByExternalID moves the key carrying the external ID to the trash.
+func (s *KeyService) DeleteKeyByExternalID(externalID string) error {
+	key, err := s.FindKeyByExternalID(externalID)
+	if err != nil {
+		return err
+	}
+	keyValue, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt key %d: %w", key.ID, err)
+	}
+	if _, err := s.KeyProvider.RemoveKeys(key.GroupID, []string{keyValue}); err != nil {
+		return app_errors.ParseDBError(err)
+	}
+	return nil
+}
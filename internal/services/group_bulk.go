@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// GroupSelector selects the groups of a bulk operation: the listed groups, the groups of a
+// channel type, or the listed groups of that channel type when both are set.
+type GroupSelector struct {
+	GroupIDs    []uint
+	ChannelType string
+}
+
+// find returns the selected groups.
+func (sel GroupSelector) find(db *gorm.DB) ([]models.Group, error) {
+	channelType := strings.TrimSpace(sel.ChannelType)
+	if len(sel.GroupIDs) == 0 && channelType == "" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.bulk_group_selector_required", nil)
+	}
+
+	query := db.Model(&models.Group{})
+	if len(sel.GroupIDs) > 0 {
+		query = query.Where("id IN ?", sel.GroupIDs)
+	}
+	if channelType != "" {
+		query = query.Where("channel_type = ? AND group_type <> ?", channelType, "aggregate")
+	}
+
+	var groups []models.Group
+	if err := query.Order("id asc").Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return groups, nil
+}
+
+// GroupBulkResult lists the groups changed by a bulk operation.
+type GroupBulkResult struct {
+	Updated  int    `json:"updated"`
+	GroupIDs []uint `json:"group_ids"`
+}
+
+func newGroupBulkResult(groups []models.Group) *GroupBulkResult {
+	result := &GroupBulkResult{Updated: len(groups), GroupIDs: make([]uint, 0, len(groups))}
+	for _, group := range groups {
+		result.GroupIDs = append(result.GroupIDs, group.ID)
+	}
+	return result
+}
+
+// maxMaintenanceMessageLength is the size of the maintenance_message column.
+const maxMaintenanceMessageLength = 512
+
+// SetGroupsEnabled enables or disables the selected groups, and sets their maintenance message
+// when message is not nil. Disabled groups are in maintenance: they answer proxy requests with
+// their maintenance message, receive no traffic from their aggregate groups and their keys are
+// not validated in the background.
+func (s *GroupService) SetGroupsEnabled(ctx context.Context, sel GroupSelector, enabled bool, message *string) (*GroupBulkResult, error) {
+	updates := map[string]any{"enabled": enabled}
+	if message != nil {
+		trimmed := strings.TrimSpace(*message)
+		if len(trimmed) > maxMaintenanceMessageLength {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.maintenance_message_too_long", map[string]any{"max": maxMaintenanceMessageLength})
+		}
+		updates["maintenance_message"] = trimmed
+	}
+
+	groups, err := sel.find(s.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	result := newGroupBulkResult(groups)
+	if len(groups) == 0 {
+		return result, nil
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("id IN ?", result.GroupIDs).Updates(updates).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	if err := s.groupManager.Invalidate(); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+	}
+	return result, nil
+}
+
+// UpdateGroupsConfig sets config fields on the selected groups, keeping their other fields.
+// A nil value removes the field, so that the group follows the system setting again. The
+// groups are validated first and none is changed when one of them would become invalid.
+func (s *GroupService) UpdateGroupsConfig(ctx context.Context, sel GroupSelector, patch map[string]any) (*GroupBulkResult, error) {
+	if len(patch) == 0 {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.bulk_config_empty", nil)
+	}
+
+	groups, err := sel.find(s.db.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	configs := make([]map[string]any, len(groups))
+	for i, group := range groups {
+		config := make(map[string]any, len(group.Config)+len(patch))
+		maps.Copy(config, group.Config)
+		for key, value := range patch {
+			if value == nil {
+				delete(config, key)
+			} else {
+				config[key] = value
+			}
+		}
+
+		cleaned, err := s.validateAndCleanConfig(config, s.groupSecrets(&group))
+		if err != nil {
+			if i18nErr, ok := err.(*I18nError); ok && i18nErr.Template != nil {
+				i18nErr.Template["error"] = fmt.Sprintf("group %s: %v", group.Name, i18nErr.Template["error"])
+			}
+			return nil, err
+		}
+		configs[i] = cleaned
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, group := range groups {
+			if err := tx.Model(&models.Group{}).Where("id = ?", group.ID).Update("config", datatypes.JSONMap(configs[i])).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	if len(groups) > 0 {
+		if err := s.groupManager.Invalidate(); err != nil {
+			logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+		}
+	}
+	return newGroupBulkResult(groups), nil
+}
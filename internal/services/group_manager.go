@@ -4,11 +4,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/admission"
+	"gpt-load/internal/balancing"
+	"gpt-load/internal/canary"
 	"gpt-load/internal/config"
+	"gpt-load/internal/cors"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/endpoints"
 	"gpt-load/internal/failover"
+	"gpt-load/internal/features"
+	"gpt-load/internal/guardrails"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/mirror"
+	"gpt-load/internal/modelpolicy"
 	"gpt-load/internal/models"
+	"gpt-load/internal/moderation"
+	"gpt-load/internal/netacl"
 	"gpt-load/internal/store"
 	"gpt-load/internal/syncer"
+	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -24,6 +38,8 @@ type GroupManager struct {
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
 	subGroupManager *SubGroupManager
+	encryptionSvc   encryption.Service
+	keyPools        *keypool.Pools
 }
 
 // NewGroupManager creates a new, uninitialized GroupManager.
@@ -32,12 +48,16 @@ func NewGroupManager(
 	store store.Store,
 	settingsManager *config.SystemSettingsManager,
 	subGroupManager *SubGroupManager,
+	encryptionSvc encryption.Service,
+	keyPools *keypool.Pools,
 ) *GroupManager {
 	return &GroupManager{
 		db:              db,
 		store:           store,
 		settingsManager: settingsManager,
 		subGroupManager: subGroupManager,
+		encryptionSvc:   encryptionSvc,
+		keyPools:        keyPools,
 	}
 }
 
@@ -84,12 +104,160 @@ func (gm *GroupManager) Initialize() error {
 				g.FailoverStatusCodeMatcher = matcher
 			}
 
+			thresholds, err := failover.ParseStatusThresholds(g.EffectiveConfig.BlacklistStatusThresholds)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.BlacklistStatusThresholds,
+					"error":      err,
+				}).Warn("Invalid blacklist status thresholds spec, ignoring")
+			} else {
+				g.BlacklistStatusThresholds = thresholds
+			}
+
+			allowlist, err := endpoints.Parse(g.EffectiveConfig.AllowedEndpoints)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.AllowedEndpoints,
+					"error":      err,
+				}).Warn("Invalid allowed endpoints spec, ignoring")
+			} else {
+				g.EndpointAllowlist = allowlist
+			}
+
+			allowedModels, err := modelpolicy.Parse(g.EffectiveConfig.AllowedModels)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.AllowedModels,
+					"error":      err,
+				}).Warn("Invalid allowed models spec, ignoring")
+			} else {
+				g.ModelPolicy.Allowed = allowedModels
+			}
+
+			deniedModels, err := modelpolicy.Parse(g.EffectiveConfig.DeniedModels)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.DeniedModels,
+					"error":      err,
+				}).Warn("Invalid denied models spec, ignoring")
+			} else {
+				g.ModelPolicy.Denied = deniedModels
+			}
+
+			ipAllowlist, err := netacl.Parse(g.EffectiveConfig.IPAllowlist)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.IPAllowlist,
+					"error":      err,
+				}).Warn("Invalid IP allowlist spec, ignoring")
+			} else {
+				g.NetworkACL.Allowed = ipAllowlist
+			}
+
+			// The global denylist applies even to groups with their own
+			ipDenylist, err := netacl.Parse(gm.settingsManager.GetSettings().IPDenylist + "," + g.EffectiveConfig.IPDenylist)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"spec":       g.EffectiveConfig.IPDenylist,
+					"error":      err,
+				}).Warn("Invalid IP denylist spec, ignoring")
+			} else {
+				g.NetworkACL.Denied = ipDenylist
+			}
+
+			priority, err := admission.ParsePriority(g.EffectiveConfig.RequestPriority)
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"priority":   g.EffectiveConfig.RequestPriority,
+					"error":      err,
+				}).Warn("Invalid request priority, using normal")
+			}
+			g.Priority = priority
+
+			flags, err := features.Parse(g.Config["feature_flags"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid feature flags, using defaults")
+			}
+			g.FeatureFlags = flags
+
+			policy, err := guardrails.Parse(g.Config["request_guardrails"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid request guardrails, ignoring them")
+			}
+			g.Guardrails = policy
+
+			moderationConfig, err := moderation.Parse(g.Config["moderation"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid moderation config, requests are not moderated")
+			}
+			if moderationConfig != nil && moderationConfig.APIKey != "" {
+				moderationConfig.APIKey = decryptStoredSecret(gm.encryptionSvc, moderationConfig.APIKey)
+			}
+			g.Moderation = moderationConfig
+
+			canaryConfig, err := canary.Parse(g.Config["canary"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid canary config, the group is not probed")
+			}
+			g.Canary = canaryConfig
+
+			mirrorConfig, err := mirror.Parse(g.Config["mirror"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid mirror config, requests are not mirrored")
+			}
+			g.Mirror = mirrorConfig
+
+			corsConfig, err := cors.Parse(g.Config["cors"])
+			if err != nil {
+				logrus.WithFields(logrus.Fields{
+					"group_name": g.Name,
+					"error":      err,
+				}).Warn("Invalid CORS config, preflight requests are not answered")
+			}
+			g.CORS = corsConfig
+
+			if g.GroupType == "aggregate" {
+				balancingConfig, err := balancing.Parse(g.Config["capacity_balancing"])
+				if err != nil {
+					logrus.WithFields(logrus.Fields{
+						"group_name": g.Name,
+						"error":      err,
+					}).Warn("Invalid capacity balancing config, static weights are used")
+				}
+				g.CapacityBalancing = balancingConfig
+			}
+
 			// Parse header rules with error handling
 			if len(group.HeaderRules) > 0 {
 				if err := json.Unmarshal(group.HeaderRules, &g.HeaderRuleList); err != nil {
 					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to parse header rules for group")
 					g.HeaderRuleList = []models.HeaderRule{}
 				}
+				if g.HeaderRuleList, err = revealHeaderRules(gm.encryptionSvc, g.HeaderRuleList); err != nil {
+					logrus.WithError(err).WithField("group_name", g.Name).Warn("Failed to decrypt a secret header rule, it is not applied")
+				}
 			} else {
 				g.HeaderRuleList = []models.HeaderRule{}
 			}
@@ -119,12 +287,16 @@ func (gm *GroupManager) Initialize() error {
 			// Load sub-groups for aggregate groups
 			if g.GroupType == "aggregate" {
 				if subGroups, ok := subGroupsByAggregateID[g.ID]; ok {
-					g.SubGroups = make([]models.GroupSubGroup, len(subGroups))
-					for i, sg := range subGroups {
-						g.SubGroups[i] = sg
+					g.SubGroups = make([]models.GroupSubGroup, 0, len(subGroups))
+					for _, sg := range subGroups {
 						if subGroup, exists := groupByID[sg.SubGroupID]; exists {
-							g.SubGroups[i].SubGroupName = subGroup.Name
+							// Disabled sub-groups receive no traffic from their aggregates
+							if !subGroup.Enabled {
+								continue
+							}
+							sg.SubGroupName = subGroup.Name
 						}
+						g.SubGroups = append(g.SubGroups, sg)
 					}
 				}
 			}
@@ -145,6 +317,7 @@ func (gm *GroupManager) Initialize() error {
 
 	afterReload := func(newCache map[string]*models.Group) {
 		gm.subGroupManager.RebuildSelectors(newCache)
+		gm.keyPools.SyncPoolModes(newCache)
 	}
 
 	syncer, err := syncer.NewCacheSyncer(
@@ -158,6 +331,14 @@ func (gm *GroupManager) Initialize() error {
 		return fmt.Errorf("failed to create group syncer: %w", err)
 	}
 	gm.syncer = syncer
+
+	// Every instance reloads its settings, so each one recomputes the effective config of its
+	// groups right away instead of waiting for a broadcast that may overtake its own reload.
+	gm.settingsManager.OnChange(func(_, _ types.SystemSettings) {
+		if err := gm.syncer.Reload(); err != nil {
+			logrus.WithError(err).Error("Failed to reload groups after a settings change")
+		}
+	})
 	return nil
 }
 
@@ -183,6 +364,14 @@ func (gm *GroupManager) Invalidate() error {
 	return gm.syncer.Invalidate()
 }
 
+// InvalidateFor triggers a cache reload across all instances for the given change.
+func (gm *GroupManager) InvalidateFor(eventType string, groupID uint) error {
+	if gm.syncer == nil {
+		return fmt.Errorf("GroupManager is not initialized")
+	}
+	return gm.syncer.InvalidateFor(eventType, groupID)
+}
+
 // Stop gracefully stops the GroupManager's background syncer.
 func (gm *GroupManager) Stop(ctx context.Context) {
 	if gm.syncer != nil {
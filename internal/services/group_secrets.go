@@ -0,0 +1,209 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/datatypes"
+)
+
+// TransformGroupSecrets replaces the stored value of every secret of a group with the result
+// of fn. The secrets are the auth tokens and proxy of the upstreams, the values of secret
+// header rules, and the proxy and moderation API key of the config, all encrypted at rest.
+// The group gets new upstreams, config and header rules when they change, so that groups
+// sharing them, as cached ones do, are not modified. It reports whether any value changed.
+func TransformGroupSecrets(group *models.Group, fn func(stored string) (string, error)) (bool, error) {
+	changed := false
+	replace := func(value any) (any, error) {
+		stored, ok := value.(string)
+		if !ok || stored == "" {
+			return value, nil
+		}
+		result, err := fn(stored)
+		if err != nil {
+			return nil, err
+		}
+		if result != stored {
+			changed = true
+		}
+		return result, nil
+	}
+
+	if len(group.Upstreams) > 0 {
+		var upstreams []map[string]any
+		if err := json.Unmarshal(group.Upstreams, &upstreams); err != nil {
+			return false, fmt.Errorf("group ID %d has invalid upstreams: %w", group.ID, err)
+		}
+		upstreamsChanged := false
+		for _, upstream := range upstreams {
+			tokens, _ := upstream["auth_tokens"].([]any)
+			for i, token := range tokens {
+				value, err := replace(token)
+				if err != nil {
+					return false, fmt.Errorf("group ID %d upstream auth token: %w", group.ID, err)
+				}
+				upstreamsChanged = upstreamsChanged || value != token
+				tokens[i] = value
+			}
+			if proxyURL, ok := upstream["proxy_url"]; ok {
+				value, err := replace(proxyURL)
+				if err != nil {
+					return false, fmt.Errorf("group ID %d upstream proxy_url: %w", group.ID, err)
+				}
+				upstreamsChanged = upstreamsChanged || value != proxyURL
+				upstream["proxy_url"] = value
+			}
+		}
+		if upstreamsChanged {
+			data, err := json.Marshal(upstreams)
+			if err != nil {
+				return false, fmt.Errorf("group ID %d upstreams marshal failed: %w", group.ID, err)
+			}
+			group.Upstreams = datatypes.JSON(data)
+		}
+	}
+
+	if len(group.HeaderRules) > 0 {
+		var rules []models.HeaderRule
+		if err := json.Unmarshal(group.HeaderRules, &rules); err != nil {
+			return false, fmt.Errorf("group ID %d has invalid header rules: %w", group.ID, err)
+		}
+		rulesChanged := false
+		for i := range rules {
+			if !rules[i].Secret {
+				continue
+			}
+			value, err := replace(rules[i].Value)
+			if err != nil {
+				return false, fmt.Errorf("group ID %d header %s: %w", group.ID, rules[i].Key, err)
+			}
+			rulesChanged = rulesChanged || value != rules[i].Value
+			rules[i].Value = value.(string)
+		}
+		if rulesChanged {
+			data, err := json.Marshal(rules)
+			if err != nil {
+				return false, fmt.Errorf("group ID %d header rules marshal failed: %w", group.ID, err)
+			}
+			group.HeaderRules = datatypes.JSON(data)
+		}
+	}
+
+	if len(group.Config) > 0 {
+		config := maps.Clone(group.Config)
+		configChanged := false
+		if proxyURL, ok := config["proxy_url"]; ok {
+			value, err := replace(proxyURL)
+			if err != nil {
+				return false, fmt.Errorf("group ID %d proxy_url: %w", group.ID, err)
+			}
+			configChanged = configChanged || value != proxyURL
+			config["proxy_url"] = value
+		}
+		if moderationConfig, ok := config["moderation"].(map[string]any); ok {
+			if apiKey, ok := moderationConfig["api_key"]; ok {
+				value, err := replace(apiKey)
+				if err != nil {
+					return false, fmt.Errorf("group ID %d moderation api_key: %w", group.ID, err)
+				}
+				if value != apiKey {
+					moderationConfig = maps.Clone(moderationConfig)
+					moderationConfig["api_key"] = value
+					config["moderation"] = moderationConfig
+					configChanged = true
+				}
+			}
+		}
+		if configChanged {
+			group.Config = config
+		}
+	}
+
+	return changed, nil
+}
+
+// decryptStoredSecret decrypts a stored secret. The config proxy and moderation API key were
+// stored in plaintext before they were encrypted, so a value that does not decrypt is taken
+// as such a plaintext, encrypted on the next save of its group.
+func decryptStoredSecret(svc encryption.Service, stored string) string {
+	if plaintext, err := svc.Decrypt(stored); err == nil {
+		return plaintext
+	}
+	return stored
+}
+
+// MaskGroupSecrets returns a copy of a group with its secrets masked, for API responses.
+func MaskGroupSecrets(svc encryption.Service, group *models.Group) *models.Group {
+	masked := *group
+	if _, err := TransformGroupSecrets(&masked, func(stored string) (string, error) {
+		return encryption.MaskSecret(svc, decryptStoredSecret(svc, stored)), nil
+	}); err != nil {
+		// Malformed fields are not returned, rather than risking a secret in them
+		logrus.WithError(err).Warn("Failed to mask the secrets of a group")
+		masked.Upstreams, masked.HeaderRules, masked.Config = nil, nil, nil
+	}
+	return &masked
+}
+
+// groupSecrets maps the masked form of every secret of a group to its stored value, so that
+// a masked secret sent back by a client keeps the secret it stands for.
+func (s *GroupService) groupSecrets(group *models.Group) map[string]string {
+	secrets := make(map[string]string)
+	if group == nil {
+		return secrets
+	}
+	copied := *group
+	TransformGroupSecrets(&copied, func(stored string) (string, error) {
+		secrets[encryption.MaskSecret(s.encryptionSvc, decryptStoredSecret(s.encryptionSvc, stored))] = stored
+		return stored, nil
+	})
+	return secrets
+}
+
+// sealSecret returns the plaintext of a secret sent by a client, to validate it, and its value
+// to store. A masked secret resolves to the stored secret it stands for, a secret that already
+// decrypts, as stored, is kept as is, and any other value is encrypted.
+func (s *GroupService) sealSecret(value string, stored map[string]string) (plaintext, sealed string, err error) {
+	if encryption.IsMaskedSecret(value) {
+		sealed, ok := stored[value]
+		if !ok {
+			return "", "", fmt.Errorf("the masked secret %s does not match a stored secret, enter it again", value)
+		}
+		if plaintext, err := s.encryptionSvc.Decrypt(sealed); err == nil {
+			return plaintext, sealed, nil
+		}
+		value = sealed
+	}
+	if plaintext, err := s.encryptionSvc.Decrypt(value); err == nil {
+		return plaintext, value, nil
+	}
+	sealed, err = s.encryptionSvc.Encrypt(value)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	return value, sealed, nil
+}
+
+// revealHeaderRules decrypts the values of secret header rules for use in requests. Rules that
+// cannot be decrypted are dropped, rather than sending the ciphertext upstream.
+func revealHeaderRules(svc encryption.Service, rules []models.HeaderRule) ([]models.HeaderRule, error) {
+	var failed error
+	revealed := make([]models.HeaderRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Secret && rule.Value != "" {
+			plaintext, err := svc.Decrypt(rule.Value)
+			if err != nil {
+				failed = fmt.Errorf("header %s: %w", rule.Key, err)
+				continue
+			}
+			rule.Value = plaintext
+		}
+		revealed = append(revealed, rule)
+	}
+	return revealed, failed
+}
@@ -3,7 +3,9 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
 	"math"
 	"net/http"
 	"reflect"
@@ -12,11 +14,21 @@ import (
 	"sync"
 	"time"
 
+	"gpt-load/internal/balancing"
+	"gpt-load/internal/canary"
 	"gpt-load/internal/channel"
 	"gpt-load/internal/config"
+	"gpt-load/internal/cors"
 	"gpt-load/internal/encryption"
 	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/features"
+	"gpt-load/internal/guardrails"
+	"gpt-load/internal/httpclient"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/mirror"
 	"gpt-load/internal/models"
+	"gpt-load/internal/moderation"
+	"gpt-load/internal/syncer"
 	"gpt-load/internal/utils"
 
 	"github.com/sirupsen/logrus"
@@ -55,9 +67,13 @@ type GroupService struct {
 	settingsManager       *config.SystemSettingsManager
 	groupManager          *GroupManager
 	keyService            *KeyService
+	keyPool               keypool.KeyPool
 	keyImportSvc          *KeyImportService
 	encryptionSvc         encryption.Service
 	aggregateGroupService *AggregateGroupService
+	budgetService         *BudgetService
+	responseCacheService  *ResponseCacheService
+	channelFactory        *channel.Factory
 	channelRegistry       []string
 }
 
@@ -67,18 +83,26 @@ func NewGroupService(
 	settingsManager *config.SystemSettingsManager,
 	groupManager *GroupManager,
 	keyService *KeyService,
+	keyPool keypool.KeyPool,
 	keyImportSvc *KeyImportService,
 	encryptionSvc encryption.Service,
 	aggregateGroupService *AggregateGroupService,
+	budgetService *BudgetService,
+	responseCacheService *ResponseCacheService,
+	channelFactory *channel.Factory,
 ) *GroupService {
 	return &GroupService{
 		db:                    db,
 		settingsManager:       settingsManager,
 		groupManager:          groupManager,
 		keyService:            keyService,
+		keyPool:               keyPool,
 		keyImportSvc:          keyImportSvc,
 		encryptionSvc:         encryptionSvc,
 		aggregateGroupService: aggregateGroupService,
+		budgetService:         budgetService,
+		responseCacheService:  responseCacheService,
+		channelFactory:        channelFactory,
 		channelRegistry:       channel.GetChannels(),
 	}
 }
@@ -91,6 +115,7 @@ type GroupCreateParams struct {
 	GroupType           string
 	Upstreams           json.RawMessage
 	ChannelType         string
+	ChannelDefinition   json.RawMessage
 	Sort                int
 	TestModel           string
 	ValidationEndpoint  string
@@ -101,28 +126,34 @@ type GroupCreateParams struct {
 	HeaderRules         []models.HeaderRule
 	ProxyKeys           string
 	SubGroups           []SubGroupInput
+	// ExternalID is the stable ID of a group managed by a declarative tool, if any.
+	ExternalID string
 }
 
 // GroupUpdateParams captures updatable fields for a group.
 type GroupUpdateParams struct {
-	Name                *string
-	DisplayName         *string
-	Description         *string
-	GroupType           *string
-	Upstreams           json.RawMessage
-	HasUpstreams        bool
-	ChannelType         *string
-	Sort                *int
-	TestModel           string
-	HasTestModel        bool
-	ValidationEndpoint  *string
-	ParamOverrides      map[string]any
-	ModelRedirectRules  map[string]string
-	ModelRedirectStrict *bool
-	Config              map[string]any
-	HeaderRules         *[]models.HeaderRule
-	ProxyKeys           *string
-	SubGroups           *[]SubGroupInput
+	Name                 *string
+	DisplayName          *string
+	Description          *string
+	GroupType            *string
+	Upstreams            json.RawMessage
+	HasUpstreams         bool
+	ChannelType          *string
+	ChannelDefinition    json.RawMessage
+	HasChannelDefinition bool
+	Sort                 *int
+	Enabled              *bool
+	MaintenanceMessage   *string
+	TestModel            string
+	HasTestModel         bool
+	ValidationEndpoint   *string
+	ParamOverrides       map[string]any
+	ModelRedirectRules   map[string]string
+	ModelRedirectStrict  *bool
+	Config               map[string]any
+	HeaderRules          *[]models.HeaderRule
+	ProxyKeys            *string
+	SubGroups            *[]SubGroupInput
 }
 
 // GroupReorderItem captures a group ID and target sort value.
@@ -136,21 +167,30 @@ type KeyStats struct {
 	TotalKeys   int64 `json:"total_keys"`
 	ActiveKeys  int64 `json:"active_keys"`
 	InvalidKeys int64 `json:"invalid_keys"`
+	ReserveKeys int64 `json:"reserve_keys"`
+	// PendingKeys await verification, RetiringKeys serve no new requests.
+	PendingKeys  int64 `json:"pending_keys"`
+	RetiringKeys int64 `json:"retiring_keys"`
+	// ArchivedKeys are kept for their stats and not counted in TotalKeys.
+	ArchivedKeys int64 `json:"archived_keys"`
 }
 
 // RequestStats captures request success and failure ratios over a time window.
 type RequestStats struct {
-	TotalRequests  int64   `json:"total_requests"`
-	FailedRequests int64   `json:"failed_requests"`
-	FailureRate    float64 `json:"failure_rate"`
+	TotalRequests    int64   `json:"total_requests"`
+	FailedRequests   int64   `json:"failed_requests"`
+	FailureRate      float64 `json:"failure_rate"`
+	OverflowRequests int64   `json:"overflow_requests"`
 }
 
 // GroupStats aggregates all per-group metrics for dashboard usage.
 type GroupStats struct {
-	KeyStats    KeyStats     `json:"key_stats"`
-	Stats24Hour RequestStats `json:"stats_24_hour"`
-	Stats7Day   RequestStats `json:"stats_7_day"`
-	Stats30Day  RequestStats `json:"stats_30_day"`
+	KeyStats      KeyStats            `json:"key_stats"`
+	Stats24Hour   RequestStats        `json:"stats_24_hour"`
+	Stats7Day     RequestStats        `json:"stats_7_day"`
+	Stats30Day    RequestStats        `json:"stats_30_day"`
+	BudgetUsage   *BudgetUsage        `json:"budget_usage,omitempty"`
+	ResponseCache *ResponseCacheStats `json:"response_cache,omitempty"`
 }
 
 // ConfigOption describes a configurable override exposed to clients.
@@ -168,6 +208,14 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_group_name", nil)
 	}
 
+	var externalID *string
+	if params.ExternalID != "" {
+		if err := validateExternalID(params.ExternalID); err != nil {
+			return nil, err
+		}
+		externalID = &params.ExternalID
+	}
+
 	channelType := strings.TrimSpace(params.ChannelType)
 	if !s.isValidChannelType(channelType) {
 		supported := strings.Join(s.channelRegistry, ", ")
@@ -183,6 +231,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 	}
 
 	var cleanedUpstreams datatypes.JSON
+	var channelDefinition datatypes.JSON
 	var testModel string
 	var validationEndpoint string
 
@@ -196,7 +245,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		if testModel == "" {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.test_model_required", nil)
 		}
-		cleaned, err := s.validateAndCleanUpstreams(params.Upstreams)
+		cleaned, err := s.validateAndCleanUpstreams(params.Upstreams, nil)
 		if err != nil {
 			return nil, err
 		}
@@ -206,14 +255,20 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		if !isValidValidationEndpoint(validationEndpoint) {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_test_path", nil)
 		}
+
+		definition, err := s.validateChannelDefinition(channelType, params.ChannelDefinition)
+		if err != nil {
+			return nil, err
+		}
+		channelDefinition = definition
 	}
 
-	cleanedConfig, err := s.validateAndCleanConfig(params.Config)
+	cleanedConfig, err := s.validateAndCleanConfig(params.Config, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	headerRulesJSON, err := s.normalizeHeaderRules(params.HeaderRules)
+	headerRulesJSON, err := s.normalizeHeaderRules(params.HeaderRules, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -233,11 +288,13 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 
 	group := models.Group{
 		Name:                name,
+		ExternalID:          externalID,
 		DisplayName:         strings.TrimSpace(params.DisplayName),
 		Description:         strings.TrimSpace(params.Description),
 		GroupType:           groupType,
 		Upstreams:           cleanedUpstreams,
 		ChannelType:         channelType,
+		ChannelDefinition:   channelDefinition,
 		Sort:                params.Sort,
 		TestModel:           testModel,
 		ValidationEndpoint:  validationEndpoint,
@@ -263,7 +320,7 @@ func (s *GroupService) CreateGroup(ctx context.Context, params GroupCreateParams
 		return nil, app_errors.ParseDBError(err)
 	}
 
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupCreated, group.ID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
@@ -339,7 +396,7 @@ func (s *GroupService) ReorderGroups(ctx context.Context, items []GroupReorderIt
 	}
 	tx = nil
 
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupsReordered, 0); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
@@ -352,6 +409,7 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	if err := s.db.WithContext(ctx).First(&group, id).Error; err != nil {
 		return nil, app_errors.ParseDBError(err)
 	}
+	secrets := s.groupSecrets(&group)
 
 	tx := s.db.WithContext(ctx).Begin()
 	if err := tx.Error; err != nil {
@@ -376,7 +434,7 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 
 	if params.HasUpstreams {
-		cleanedUpstreams, err := s.validateAndCleanUpstreams(params.Upstreams)
+		cleanedUpstreams, err := s.validateAndCleanUpstreams(params.Upstreams, secrets)
 		if err != nil {
 			return nil, err
 		}
@@ -420,10 +478,35 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		group.ChannelType = cleanedChannelType
 	}
 
+	// Re-validate the definition when either it or the channel type changes
+	if group.GroupType != "aggregate" && (params.HasChannelDefinition || params.ChannelType != nil) {
+		definition := json.RawMessage(group.ChannelDefinition)
+		if params.HasChannelDefinition {
+			definition = params.ChannelDefinition
+		}
+		cleanedDefinition, err := s.validateChannelDefinition(group.ChannelType, definition)
+		if err != nil {
+			return nil, err
+		}
+		group.ChannelDefinition = cleanedDefinition
+	}
+
 	if params.Sort != nil {
 		group.Sort = *params.Sort
 	}
 
+	if params.Enabled != nil {
+		group.Enabled = *params.Enabled
+	}
+
+	if params.MaintenanceMessage != nil {
+		message := strings.TrimSpace(*params.MaintenanceMessage)
+		if len(message) > maxMaintenanceMessageLength {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.maintenance_message_too_long", map[string]any{"max": maxMaintenanceMessageLength})
+		}
+		group.MaintenanceMessage = message
+	}
+
 	if params.HasTestModel {
 		cleanedTestModel := strings.TrimSpace(params.TestModel)
 		if cleanedTestModel == "" {
@@ -462,7 +545,7 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 
 	if params.Config != nil {
-		cleanedConfig, err := s.validateAndCleanConfig(params.Config)
+		cleanedConfig, err := s.validateAndCleanConfig(params.Config, secrets)
 		if err != nil {
 			return nil, err
 		}
@@ -474,7 +557,7 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 	}
 
 	if params.HeaderRules != nil {
-		headerRulesJSON, err := s.normalizeHeaderRules(*params.HeaderRules)
+		headerRulesJSON, err := s.normalizeHeaderRules(*params.HeaderRules, secrets)
 		if err != nil {
 			return nil, err
 		}
@@ -492,14 +575,14 @@ func (s *GroupService) UpdateGroup(ctx context.Context, id uint, params GroupUpd
 		return nil, app_errors.ErrDatabase
 	}
 
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupUpdated, group.ID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
 	return &group, nil
 }
 
-// DeleteGroup removes a group and associated resources.
+// DeleteGroup moves a group and its keys to the trash and removes its other resources.
 func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 	var apiKeys []models.APIKey
 	if err := s.db.WithContext(ctx).Where("group_id = ?", id).Find(&apiKeys).Error; err != nil {
@@ -530,14 +613,16 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 		return app_errors.ParseDBError(err)
 	}
 
-	if err := tx.Where("group_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
-		return app_errors.ErrDatabase
-	}
-
+	// The group and its keys go to the trash, keys are trashed after the group so that
+	// restoring it brings back the keys deleted with it but not the ones cleared before
 	if err := tx.Delete(&models.Group{}, id).Error; err != nil {
 		return app_errors.ParseDBError(err)
 	}
 
+	if err := tx.Where("group_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
+		return app_errors.ErrDatabase
+	}
+
 	if len(keyIDs) > 0 {
 		if err := s.keyService.KeyProvider.RemoveKeysFromStore(id, keyIDs); err != nil {
 			logrus.WithContext(ctx).WithFields(logrus.Fields{
@@ -553,7 +638,7 @@ func (s *GroupService) DeleteGroup(ctx context.Context, id uint) error {
 	}
 	tx = nil
 
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupDeleted, id); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
@@ -588,6 +673,7 @@ func (s *GroupService) CopyGroup(ctx context.Context, sourceGroupID uint, copyKe
 	newGroup := sourceGroup
 	newGroup.ID = 0
 	newGroup.Name = s.generateUniqueGroupName(ctx, sourceGroup.Name)
+	newGroup.ExternalID = nil
 	if sourceGroup.DisplayName != "" {
 		newGroup.DisplayName = sourceGroup.DisplayName + " Copy"
 	}
@@ -625,7 +711,7 @@ func (s *GroupService) CopyGroup(ctx context.Context, sourceGroupID uint, copyKe
 	}
 	tx = nil
 
-	if err := s.groupManager.Invalidate(); err != nil {
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupCreated, newGroup.ID); err != nil {
 		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
 	}
 
@@ -655,18 +741,169 @@ func (s *GroupService) GetGroupStats(ctx context.Context, groupID uint) (*GroupS
 	}
 
 	// 根据分组类型选择不同的统计逻辑
+	var stats *GroupStats
+	var err error
 	if group.GroupType == "aggregate" {
-		return s.getAggregateGroupStats(ctx, groupID)
+		stats, err = s.getAggregateGroupStats(ctx, groupID)
+	} else {
+		stats, err = s.getStandardGroupStats(ctx, groupID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 预算使用情况基于缓存中的有效配置
+	if cachedGroup, cacheErr := s.groupManager.GetGroupByName(group.Name); cacheErr == nil {
+		usage, usageErr := s.budgetService.GetUsage(cachedGroup)
+		if usageErr != nil {
+			logrus.WithContext(ctx).WithError(usageErr).Warn("failed to fetch budget usage")
+		} else {
+			stats.BudgetUsage = usage
+		}
+
+		cacheStats, cacheErr := s.responseCacheService.GetStats(cachedGroup)
+		if cacheErr != nil {
+			logrus.WithContext(ctx).WithError(cacheErr).Warn("failed to fetch response cache stats")
+		} else {
+			stats.ResponseCache = cacheStats
+		}
+	}
+
+	return stats, nil
+}
+
+// GetKeyReserve returns the reserve of a group.
+func (s *GroupService) GetKeyReserve(ctx context.Context, groupID uint) (*keypool.ReserveStatus, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id", "config").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
 	}
 
-	return s.getStandardGroupStats(ctx, groupID)
+	minActiveKeys := s.settingsManager.GetEffectiveConfig(group.Config).ReserveMinActiveKeys
+	return s.keyService.KeyProvider.ReserveStatus(group.ID, minActiveKeys)
+}
+
+// StockKeyReserve parks new keys in the reserve of a group. Keys are promoted right away
+// if the group is already below its reserve threshold.
+func (s *GroupService) StockKeyReserve(ctx context.Context, groupID uint, keysText string) (*AddKeysResult, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id", "config").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	result, err := s.keyService.AddReserveKeys(group.ID, keysText)
+	if err != nil {
+		return nil, err
+	}
+
+	minActiveKeys := s.settingsManager.GetEffectiveConfig(group.Config).ReserveMinActiveKeys
+	if _, err := s.keyService.KeyProvider.RefillFromReserve(group.ID, minActiveKeys); err != nil {
+		logrus.WithContext(ctx).WithError(err).Warn("failed to promote stocked reserve keys")
+	}
+	return result, nil
+}
+
+// DrainKeyReserve removes the keys parked in the reserve of a group.
+func (s *GroupService) DrainKeyReserve(ctx context.Context, groupID uint) (int64, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id").First(&group, groupID).Error; err != nil {
+		return 0, app_errors.ParseDBError(err)
+	}
+
+	return s.keyService.KeyProvider.DrainReserve(group.ID)
+}
+
+// PurgeResponseCache drops all cached responses of a group.
+func (s *GroupService) PurgeResponseCache(ctx context.Context, groupID uint) error {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id").First(&group, groupID).Error; err != nil {
+		return app_errors.ParseDBError(err)
+	}
+
+	if err := s.responseCacheService.Purge(group.ID); err != nil {
+		return NewI18nError(app_errors.ErrInternalServer, "error.purge_response_cache", map[string]any{"error": err.Error()})
+	}
+
+	return nil
+}
+
+// poolGroup loads a group for the operations on its key pool, with the effective config of
+// the group cache that selects its pool mode.
+func (s *GroupService) poolGroup(ctx context.Context, groupID uint) (*models.Group, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id", "name").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if cachedGroup, err := s.groupManager.GetGroupByName(group.Name); err == nil {
+		return cachedGroup, nil
+	}
+	return &group, nil
+}
+
+// GetPoolMembership returns which keys of a group are in its key pool.
+func (s *GroupService) GetPoolMembership(ctx context.Context, groupID uint) (*keypool.PoolMembership, error) {
+	group, err := s.poolGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.keyPool.PoolMembership(group)
+}
+
+// MovePoolKey puts a key of a group back into rotation, or takes it out of rotation for
+// cooldownSeconds, and returns the resulting pool membership.
+func (s *GroupService) MovePoolKey(ctx context.Context, groupID, keyID uint, to string, cooldownSeconds int) (*keypool.PoolMembership, error) {
+	if to != keypool.PoolStateActive && (to != keypool.PoolStateCooling || cooldownSeconds <= 0) {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_pool_move", nil)
+	}
+
+	group, err := s.poolGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	until := time.Now().Add(time.Duration(cooldownSeconds) * time.Second)
+	switch err := s.keyPool.MoveKey(group, keyID, to, until); {
+	case errors.Is(err, keypool.ErrKeyNotInGroup):
+		return nil, app_errors.ErrResourceNotFound
+	case errors.Is(err, keypool.ErrKeyNotActive):
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.pool_key_not_active", nil)
+	case err != nil:
+		return nil, err
+	}
+
+	return s.keyPool.PoolMembership(group)
+}
+
+// RebuildKeyPool rebuilds the key pool of a group from the database and returns the result.
+func (s *GroupService) RebuildKeyPool(ctx context.Context, groupID uint) (*keypool.PoolMembership, error) {
+	group, err := s.poolGroup(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.keyPool.RebuildPool(group); err != nil {
+		return nil, err
+	}
+	return s.keyPool.PoolMembership(group)
+}
+
+// RecoverCooledKeys returns the keys of a group whose cooldown is over to its pool right away.
+func (s *GroupService) RecoverCooledKeys(ctx context.Context, groupID uint) (int, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id").First(&group, groupID).Error; err != nil {
+		return 0, app_errors.ParseDBError(err)
+	}
+
+	return s.keyService.KeyProvider.RecoverCooledKeys(group.ID)
 }
 
 // queryGroupHourlyStats queries aggregated hourly statistics from group_hourly_stats table
 func (s *GroupService) queryGroupHourlyStats(ctx context.Context, groupID uint, hours int) (RequestStats, error) {
 	var result struct {
-		SuccessCount int64
-		FailureCount int64
+		SuccessCount  int64
+		FailureCount  int64
+		OverflowCount int64
 	}
 
 	now := time.Now()
@@ -675,36 +912,51 @@ func (s *GroupService) queryGroupHourlyStats(ctx context.Context, groupID uint,
 	startTime := endTime.Add(-time.Duration(hours) * time.Hour)
 
 	if err := s.db.WithContext(ctx).Model(&models.GroupHourlyStat{}).
-		Select("SUM(success_count) as success_count, SUM(failure_count) as failure_count").
+		Select("SUM(success_count) as success_count, SUM(failure_count) as failure_count, SUM(overflow_count) as overflow_count").
 		Where("group_id = ? AND time >= ? AND time < ?", groupID, startTime, endTime).
 		Scan(&result).Error; err != nil {
 		return RequestStats{}, err
 	}
 
-	return calculateRequestStats(result.SuccessCount+result.FailureCount, result.FailureCount), nil
+	stats := calculateRequestStats(result.SuccessCount+result.FailureCount, result.FailureCount)
+	stats.OverflowRequests = result.OverflowCount
+	return stats, nil
 }
 
 // fetchKeyStats retrieves API key statistics for a group
 func (s *GroupService) fetchKeyStats(ctx context.Context, groupID uint) (KeyStats, error) {
-	var totalKeys, activeKeys int64
-
-	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
-		Where("group_id = ?", groupID).
-		Count(&totalKeys).Error; err != nil {
-		return KeyStats{}, fmt.Errorf("failed to get total keys: %w", err)
+	var counts []struct {
+		Status string
+		Count  int64
 	}
-
 	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
-		Where("group_id = ? AND status = ?", groupID, models.KeyStatusActive).
-		Count(&activeKeys).Error; err != nil {
-		return KeyStats{}, fmt.Errorf("failed to get active keys: %w", err)
+		Select("status, COUNT(*) as count").
+		Where("group_id = ?", groupID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		return KeyStats{}, fmt.Errorf("failed to count keys by status: %w", err)
+	}
+
+	var stats KeyStats
+	for _, c := range counts {
+		switch c.Status {
+		case models.KeyStatusActive:
+			stats.ActiveKeys = c.Count
+		case models.KeyStatusInvalid:
+			stats.InvalidKeys = c.Count
+		case models.KeyStatusReserve:
+			stats.ReserveKeys = c.Count
+		case models.KeyStatusPending:
+			stats.PendingKeys = c.Count
+		case models.KeyStatusRetiring:
+			stats.RetiringKeys = c.Count
+		case models.KeyStatusArchived:
+			stats.ArchivedKeys = c.Count
+			continue
+		}
+		stats.TotalKeys += c.Count
 	}
-
-	return KeyStats{
-		TotalKeys:   totalKeys,
-		ActiveKeys:  activeKeys,
-		InvalidKeys: totalKeys - activeKeys,
-	}, nil
+	return stats, nil
 }
 
 // fetchRequestStats retrieves request statistics for multiple time periods
@@ -848,12 +1100,35 @@ func (s *GroupService) GetGroupConfigOptions() ([]ConfigOption, error) {
 	return options, nil
 }
 
-// validateAndCleanConfig verifies GroupConfig overrides.
-func (s *GroupService) validateAndCleanConfig(configMap map[string]any) (map[string]any, error) {
+// validateAndCleanConfig verifies GroupConfig overrides. Its proxy and moderation API key
+// are validated in plaintext and encrypted for storage, masked ones resolving to the stored
+// secrets of the group.
+func (s *GroupService) validateAndCleanConfig(configMap map[string]any, stored map[string]string) (map[string]any, error) {
 	if configMap == nil {
 		return nil, nil
 	}
 
+	configMap = maps.Clone(configMap)
+	var sealedProxyURL, sealedAPIKey string
+	if proxyURL, ok := configMap["proxy_url"].(string); ok && proxyURL != "" {
+		plaintext, sealed, err := s.sealSecret(proxyURL, stored)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": fmt.Sprintf("proxy_url: %v", err)})
+		}
+		configMap["proxy_url"], sealedProxyURL = plaintext, sealed
+	}
+	if moderationConfig, ok := configMap["moderation"].(map[string]any); ok {
+		if apiKey, ok := moderationConfig["api_key"].(string); ok && apiKey != "" {
+			plaintext, sealed, err := s.sealSecret(apiKey, stored)
+			if err != nil {
+				return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": fmt.Sprintf("moderation api_key: %v", err)})
+			}
+			moderationConfig = maps.Clone(moderationConfig)
+			moderationConfig["api_key"], sealedAPIKey = plaintext, sealed
+			configMap["moderation"] = moderationConfig
+		}
+	}
+
 	var tempGroupConfig models.GroupConfig
 	groupConfigType := reflect.TypeOf(tempGroupConfig)
 	validFields := make(map[string]bool)
@@ -872,7 +1147,35 @@ func (s *GroupService) validateAndCleanConfig(configMap map[string]any) (map[str
 		}
 	}
 
-	if err := s.settingsManager.ValidateGroupConfigOverrides(configMap); err != nil {
+	if _, err := features.Parse(configMap["feature_flags"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := guardrails.Parse(configMap["request_guardrails"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := moderation.Parse(configMap["moderation"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := canary.Parse(configMap["canary"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := balancing.Parse(configMap["capacity_balancing"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := mirror.Parse(configMap["mirror"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	if _, err := cors.Parse(configMap["cors"]); err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+	}
+	overrides := make(map[string]any, len(configMap))
+	for key, value := range configMap {
+		if key != "feature_flags" && key != "request_guardrails" && key != "moderation" && key != "canary" && key != "capacity_balancing" && key != "mirror" && key != "cors" {
+			overrides[key] = value
+		}
+	}
+
+	if err := s.settingsManager.ValidateGroupConfigOverrides(overrides); err != nil {
 		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
 	}
 
@@ -896,11 +1199,19 @@ func (s *GroupService) validateAndCleanConfig(configMap map[string]any) (map[str
 		return nil, NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
 	}
 
+	if sealedProxyURL != "" {
+		finalMap["proxy_url"] = sealedProxyURL
+	}
+	if moderationConfig, ok := finalMap["moderation"].(map[string]any); ok && sealedAPIKey != "" {
+		moderationConfig["api_key"] = sealedAPIKey
+	}
+
 	return finalMap, nil
 }
 
-// normalizeHeaderRules deduplicates and normalises header rules.
-func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatypes.JSON, error) {
+// normalizeHeaderRules deduplicates and normalises header rules. The values of secret rules
+// are encrypted for storage, masked ones resolving to the stored secrets of the group.
+func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule, stored map[string]string) (datatypes.JSON, error) {
 	if len(rules) == 0 {
 		return nil, nil
 	}
@@ -914,11 +1225,25 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 			continue
 		}
 		canonicalKey := http.CanonicalHeaderKey(key)
+		if rule.Action == "" {
+			rule.Action = "set"
+		}
+		if rule.Action != "set" && rule.Action != "remove" {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_header_action", map[string]any{"key": canonicalKey})
+		}
 		if seenKeys[canonicalKey] {
 			return nil, NewI18nError(app_errors.ErrValidation, "validation.duplicate_header", map[string]any{"key": canonicalKey})
 		}
 		seenKeys[canonicalKey] = true
-		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action})
+		secret := rule.Secret && rule.Action == "set" && rule.Value != ""
+		if secret {
+			_, sealed, err := s.sealSecret(rule.Value, stored)
+			if err != nil {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_header_secret", map[string]any{"key": canonicalKey, "error": err.Error()})
+			}
+			rule.Value = sealed
+		}
+		normalized = append(normalized, models.HeaderRule{Key: canonicalKey, Value: rule.Value, Action: rule.Action, Secret: secret})
 	}
 
 	if len(normalized) == 0 {
@@ -933,15 +1258,45 @@ func (s *GroupService) normalizeHeaderRules(rules []models.HeaderRule) (datatype
 	return datatypes.JSON(headerRulesBytes), nil
 }
 
-// validateAndCleanUpstreams validates upstream definitions.
-func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (datatypes.JSON, error) {
+// validateChannelDefinition validates the definition of custom channel groups.
+// Other channel types do not use a definition, so it is cleared for them.
+func (s *GroupService) validateChannelDefinition(channelType string, definition json.RawMessage) (datatypes.JSON, error) {
+	if channelType != channel.CustomChannelType {
+		return nil, nil
+	}
+
+	if len(definition) == 0 || string(definition) == "null" {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.channel_definition_required", nil)
+	}
+
+	parsed, err := channel.ParseCustomDefinition(definition)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_channel_definition", map[string]any{"error": err.Error()})
+	}
+
+	cleaned, err := json.Marshal(parsed)
+	if err != nil {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_channel_definition", map[string]any{"error": err.Error()})
+	}
+
+	return datatypes.JSON(cleaned), nil
+}
+
+// validateAndCleanUpstreams validates upstream definitions. Their credentials and proxies are
+// encrypted for storage, masked ones resolving to the stored secrets of the group.
+func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage, stored map[string]string) (datatypes.JSON, error) {
 	if len(upstreams) == 0 {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": "upstreams field is required"})
 	}
 
 	var defs []struct {
-		URL    string `json:"url"`
-		Weight int    `json:"weight"`
+		URL        string   `json:"url"`
+		Weight     int      `json:"weight"`
+		AuthHeader string   `json:"auth_header,omitempty"`
+		AuthTokens []string `json:"auth_tokens,omitempty"`
+		ProxyURL   string   `json:"proxy_url,omitempty"`
+
+		Transport *httpclient.UpstreamTransport `json:"transport,omitempty"`
 	}
 	if err := json.Unmarshal(upstreams, &defs); err != nil {
 		return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": err.Error()})
@@ -966,6 +1321,30 @@ func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (dat
 		if defs[i].Weight > 0 {
 			hasActiveUpstream = true
 		}
+
+		authTokens, err := s.encryptAuthTokens(defs[i].AuthHeader, defs[i].AuthTokens, stored)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": fmt.Sprintf("invalid auth credentials for upstream %s: %v", defs[i].URL, err)})
+		}
+		if defs[i].AuthHeader = strings.TrimSpace(defs[i].AuthHeader); defs[i].AuthHeader != "" {
+			defs[i].AuthHeader = http.CanonicalHeaderKey(defs[i].AuthHeader)
+		}
+		defs[i].AuthTokens = authTokens
+
+		proxyURL, err := s.encryptProxyURL(defs[i].ProxyURL, stored)
+		if err != nil {
+			return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": fmt.Sprintf("invalid proxy_url for upstream %s: %v", defs[i].URL, err)})
+		}
+		defs[i].ProxyURL = proxyURL
+
+		if defs[i].Transport != nil {
+			if err := defs[i].Transport.Normalize(); err != nil {
+				return nil, NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": fmt.Sprintf("invalid transport for upstream %s: %v", defs[i].URL, err)})
+			}
+			if defs[i].Transport.IsZero() {
+				defs[i].Transport = nil
+			}
+		}
 	}
 
 	if !hasActiveUpstream {
@@ -980,6 +1359,55 @@ func (s *GroupService) validateAndCleanUpstreams(upstreams json.RawMessage) (dat
 	return datatypes.JSON(cleanedUpstreams), nil
 }
 
+// encryptAuthTokens validates the upstream-level credentials of an upstream and encrypts them
+// for storage. Tokens that are already encrypted are kept as is.
+func (s *GroupService) encryptAuthTokens(header string, tokens []string, stored map[string]string) ([]string, error) {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		if len(tokens) > 0 {
+			return nil, fmt.Errorf("auth_header is required when auth_tokens are set")
+		}
+		return nil, nil
+	}
+	if strings.ContainsAny(header, " \t\r\n:") {
+		return nil, fmt.Errorf("invalid auth_header %q", header)
+	}
+
+	encrypted := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		_, sealed, err := s.sealSecret(token, stored)
+		if err != nil {
+			return nil, fmt.Errorf("auth token: %w", err)
+		}
+		encrypted = append(encrypted, sealed)
+	}
+	if len(encrypted) == 0 {
+		return nil, fmt.Errorf("auth_tokens are required when auth_header is set")
+	}
+	return encrypted, nil
+}
+
+// encryptProxyURL validates the outbound proxy of an upstream and encrypts it for storage, since it
+// may hold proxy credentials. A proxy URL that is already encrypted is kept as is.
+func (s *GroupService) encryptProxyURL(proxyURL string, stored map[string]string) (string, error) {
+	proxyURL = strings.TrimSpace(proxyURL)
+	if proxyURL == "" {
+		return "", nil
+	}
+	plaintext, sealed, err := s.sealSecret(proxyURL, stored)
+	if err != nil {
+		return "", err
+	}
+	if err := httpclient.ValidateProxyURL(plaintext); err != nil {
+		return "", err
+	}
+	return sealed, nil
+}
+
 func calculateRequestStats(total, failed int64) RequestStats {
 	stats := RequestStats{
 		TotalRequests:  total,
@@ -993,8 +1421,9 @@ func calculateRequestStats(total, failed int64) RequestStats {
 }
 
 func (s *GroupService) generateUniqueGroupName(ctx context.Context, baseName string) string {
+	// Names of groups in the trash stay taken until they are purged
 	var groups []models.Group
-	if err := s.db.WithContext(ctx).Select("name").Find(&groups).Error; err != nil {
+	if err := s.db.WithContext(ctx).Unscoped().Select("name").Find(&groups).Error; err != nil {
 		return baseName + "_copy"
 	}
 
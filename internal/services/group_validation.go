@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+	"unicode"
+
+	"gpt-load/internal/channel"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/modelpolicy"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"gorm.io/datatypes"
+)
+
+// groupLiveTestTimeout bounds the live test call of a group validation.
+const groupLiveTestTimeout = 30 * time.Second
+
+// GroupValidationParams is a group payload checked without saving it.
+type GroupValidationParams struct {
+	GroupCreateParams
+	// GroupID is the group being edited, if any, so that its own name is not reported as taken
+	// and its masked secrets resolve to the stored ones.
+	GroupID uint
+	// SkipConnectivity skips sending a request to every upstream.
+	SkipConnectivity bool
+	// TestKey, when set, is used for a live test call of the test model.
+	TestKey string
+}
+
+// GroupFieldError is a problem found in a field of a group payload.
+type GroupFieldError struct {
+	Field string
+	Err   *I18nError
+}
+
+// GroupLiveTest is the outcome of the live test call of a group validation.
+type GroupLiveTest struct {
+	Success    bool   `json:"success"`
+	StatusCode int    `json:"status_code,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// GroupValidation is the outcome of a dry-run check of a group payload.
+type GroupValidation struct {
+	Errors []GroupFieldError
+	// Connectivity and LiveTest are only set when the fields are valid.
+	Connectivity []channel.UpstreamConnectivity
+	LiveTest     *GroupLiveTest
+}
+
+// ValidateGroup runs the checks of CreateGroup on a group payload without saving it, reporting
+// every invalid field instead of the first one. When the fields are valid, it also checks that
+// the upstreams can be reached and, with a test key, makes a live test call of the test model.
+func (s *GroupService) ValidateGroup(ctx context.Context, params GroupValidationParams) (*GroupValidation, error) {
+	result := &GroupValidation{Errors: []GroupFieldError{}}
+	report := func(field string, err error) {
+		var i18nErr *I18nError
+		if !errors.As(err, &i18nErr) {
+			i18nErr = NewI18nError(app_errors.ErrValidation, "error.invalid_config_format", map[string]any{"error": err.Error()})
+		}
+		result.Errors = append(result.Errors, GroupFieldError{Field: field, Err: i18nErr})
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if !isValidGroupName(name) {
+		report("name", NewI18nError(app_errors.ErrValidation, "validation.invalid_group_name", nil))
+	} else {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Group{}).Where("name = ? AND id <> ?", name, params.GroupID).Count(&count).Error; err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+		if count > 0 {
+			report("name", NewI18nError(app_errors.ErrValidation, "group.name_exists", nil))
+		}
+	}
+
+	channelType := strings.TrimSpace(params.ChannelType)
+	if !s.isValidChannelType(channelType) {
+		report("channel_type", NewI18nError(app_errors.ErrValidation, "validation.invalid_channel_type", map[string]any{"types": strings.Join(s.channelRegistry, ", ")}))
+	}
+
+	groupType := strings.TrimSpace(params.GroupType)
+	if groupType == "" {
+		groupType = "standard"
+	}
+	if groupType != "standard" && groupType != "aggregate" {
+		report("group_type", NewI18nError(app_errors.ErrValidation, "validation.invalid_group_type", nil))
+	}
+
+	var secrets map[string]string
+	if params.GroupID != 0 {
+		var existing models.Group
+		if err := s.db.WithContext(ctx).First(&existing, params.GroupID).Error; err == nil {
+			secrets = s.groupSecrets(&existing)
+		}
+	}
+
+	cleanedConfig, err := s.validateAndCleanConfig(params.Config, secrets)
+	if err != nil {
+		report("config", err)
+	}
+
+	headerRulesJSON, err := s.normalizeHeaderRules(params.HeaderRules, secrets)
+	if err != nil {
+		report("header_rules", err)
+	}
+	for _, rule := range params.HeaderRules {
+		for _, variable := range utils.UnknownHeaderVariables(rule.Value) {
+			report("header_rules", NewI18nError(app_errors.ErrValidation, "validation.unknown_header_variable", map[string]any{"key": rule.Key, "variable": variable}))
+		}
+	}
+
+	if err := validateModelRedirectRules(params.ModelRedirectRules); err != nil {
+		report("model_redirect_rules", NewI18nError(app_errors.ErrValidation, "validation.invalid_model_redirect", map[string]any{"error": err.Error()}))
+	}
+
+	if groupType != "standard" {
+		if groupType == "aggregate" && len(params.ModelRedirectRules) > 0 {
+			report("model_redirect_rules", NewI18nError(app_errors.ErrValidation, "validation.aggregate_no_model_redirect", nil))
+		}
+		return result, nil
+	}
+
+	upstreams, err := s.validateAndCleanUpstreams(params.Upstreams, secrets)
+	if err != nil {
+		report("upstreams", err)
+	}
+
+	validationEndpoint := strings.TrimSpace(params.ValidationEndpoint)
+	if !isValidValidationEndpoint(validationEndpoint) {
+		report("validation_endpoint", NewI18nError(app_errors.ErrValidation, "validation.invalid_test_path", nil))
+	}
+
+	channelDefinition, err := s.validateChannelDefinition(channelType, params.ChannelDefinition)
+	if err != nil {
+		report("channel_definition", err)
+	}
+
+	effectiveConfig := s.settingsManager.GetEffectiveConfig(cleanedConfig)
+	testModel := strings.TrimSpace(params.TestModel)
+	if testModel == "" {
+		report("test_model", NewI18nError(app_errors.ErrValidation, "validation.test_model_required", nil))
+	} else if strings.IndexFunc(testModel, unicode.IsSpace) >= 0 {
+		report("test_model", NewI18nError(app_errors.ErrValidation, "validation.test_model_whitespace", nil))
+	} else {
+		// A malformed policy is reported with the config
+		allowed, allowedErr := modelpolicy.Parse(effectiveConfig.AllowedModels)
+		denied, deniedErr := modelpolicy.Parse(effectiveConfig.DeniedModels)
+		if allowedErr == nil && deniedErr == nil && !(modelpolicy.Policy{Allowed: allowed, Denied: denied}).Allows(testModel) {
+			report("test_model", NewI18nError(app_errors.ErrValidation, "validation.test_model_not_allowed", map[string]any{"model": testModel}))
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	group := &models.Group{
+		Name:                name,
+		GroupType:           groupType,
+		Upstreams:           upstreams,
+		ChannelType:         channelType,
+		ChannelDefinition:   channelDefinition,
+		TestModel:           testModel,
+		ValidationEndpoint:  validationEndpoint,
+		ParamOverrides:      params.ParamOverrides,
+		ModelRedirectRules:  convertToJSONMap(params.ModelRedirectRules),
+		ModelRedirectStrict: params.ModelRedirectStrict,
+		Config:              cleanedConfig,
+		HeaderRules:         headerRulesJSON,
+		EffectiveConfig:     effectiveConfig,
+		HeaderRuleList:      s.normalizedHeaderRuleList(headerRulesJSON),
+	}
+	channelHandler, err := s.channelFactory.NewChannel(group)
+	if err != nil {
+		report("upstreams", NewI18nError(app_errors.ErrValidation, "validation.invalid_upstreams", map[string]any{"error": err.Error()}))
+		return result, nil
+	}
+
+	if !params.SkipConnectivity {
+		result.Connectivity = channelHandler.TestConnectivity(ctx)
+	}
+
+	if testKey := strings.TrimSpace(params.TestKey); testKey != "" {
+		testCtx, cancel := context.WithTimeout(ctx, groupLiveTestTimeout)
+		defer cancel()
+
+		start := time.Now()
+		valid, err := channelHandler.ValidateKey(testCtx, &models.APIKey{KeyValue: testKey}, group)
+		result.LiveTest = &GroupLiveTest{Success: valid && err == nil, DurationMs: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.LiveTest.Error = utils.RedactSecret(err.Error(), testKey)
+			var validationErr *channel.ValidationError
+			if errors.As(err, &validationErr) {
+				result.LiveTest.StatusCode = validationErr.StatusCode
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// normalizedHeaderRuleList decodes header rules returned by normalizeHeaderRules, with their
+// secret values decrypted.
+func (s *GroupService) normalizedHeaderRuleList(headerRules datatypes.JSON) []models.HeaderRule {
+	rules := []models.HeaderRule{}
+	if len(headerRules) > 0 {
+		_ = json.Unmarshal(headerRules, &rules)
+	}
+	rules, _ = revealHeaderRules(s.encryptionSvc, rules)
+	return rules
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// honeypotAutoDenyWindow is the period over which the hits of an IP count towards
+	// honeypot_auto_deny_hits.
+	honeypotAutoDenyWindow = 24 * time.Hour
+	// MaxHoneypotReportHours is the longest period a honeypot report covers.
+	MaxHoneypotReportHours = 720
+	// honeypotReportProfiles is the number of attacker profiles in a honeypot report.
+	honeypotReportProfiles = 100
+)
+
+// HoneypotProfile aggregates the honeypot hits of one client IP.
+type HoneypotProfile struct {
+	ClientIP  string    `json:"client_ip"`
+	Hits      int64     `json:"hits"`
+	Paths     int64     `json:"paths"`
+	Tokens    int64     `json:"tokens"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+	UserAgent string    `json:"user_agent"`
+	Denied    bool      `json:"denied"`
+}
+
+// HoneypotReport summarizes the honeypot hits of a period, with the most active attackers first.
+type HoneypotReport struct {
+	Since     time.Time         `json:"since"`
+	Hits      int64             `json:"hits"`
+	ClientIPs int64             `json:"client_ips"`
+	Triggers  map[string]int64  `json:"triggers"`
+	Profiles  []HoneypotProfile `json:"profiles"`
+}
+
+// HoneypotService records the requests answered by the honeypot, profiles the IPs sending
+// them and, with honeypot_auto_deny_hits set, adds the most persistent ones to ip_denylist.
+type HoneypotService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	// denyMu serializes the updates of ip_denylist, so that an IP is added once
+	denyMu sync.Mutex
+}
+
+// NewHoneypotService creates a new HoneypotService.
+func NewHoneypotService(db *gorm.DB, settingsManager *config.SystemSettingsManager) *HoneypotService {
+	return &HoneypotService{
+		db:              db,
+		settingsManager: settingsManager,
+	}
+}
+
+// Record logs a request answered by the honeypot. token is the credential it probed, which
+// is only stored masked.
+func (s *HoneypotService) Record(hit models.HoneypotHit, token string) {
+	hit.ClientIP = utils.TruncateString(hit.ClientIP, 64)
+	hit.UserAgent = utils.TruncateString(hit.UserAgent, 512)
+	hit.Method = utils.TruncateString(hit.Method, 16)
+	hit.Path = utils.TruncateString(hit.Path, 512)
+	if token != "" {
+		hit.Token = utils.TruncateString(utils.MaskAPIKey(token), 64)
+	}
+	if err := s.db.Create(&hit).Error; err != nil {
+		logrus.WithError(err).WithField("ip", hit.ClientIP).Error("Failed to record a honeypot hit")
+		return
+	}
+
+	if threshold := s.settingsManager.GetSettings().HoneypotAutoDenyHits; threshold > 0 {
+		s.denyIfPersistent(hit.ClientIP, threshold)
+	}
+}
+
+// denyIfPersistent adds an IP to ip_denylist once its hits within honeypotAutoDenyWindow reach
+// the threshold.
+func (s *HoneypotService) denyIfPersistent(clientIP string, threshold int) {
+	var hits int64
+	err := s.db.Model(&models.HoneypotHit{}).
+		Where("client_ip = ? AND created_at >= ?", clientIP, time.Now().Add(-honeypotAutoDenyWindow)).
+		Count(&hits).Error
+	if err != nil {
+		logrus.WithError(err).WithField("ip", clientIP).Error("Failed to count honeypot hits")
+		return
+	}
+	if hits < int64(threshold) {
+		return
+	}
+
+	s.denyMu.Lock()
+	defer s.denyMu.Unlock()
+	denylist := s.settingsManager.GetSettings().IPDenylist
+	if denylistContains(denylist, clientIP) {
+		return
+	}
+	if denylist = strings.TrimSpace(denylist); denylist != "" {
+		denylist += ","
+	}
+	if err := s.settingsManager.UpdateSettings(map[string]any{"ip_denylist": denylist + clientIP}); err != nil {
+		logrus.WithError(err).WithField("ip", clientIP).Error("Failed to add a honeypot attacker to the IP denylist")
+		return
+	}
+	logrus.WithFields(logrus.Fields{"ip": clientIP, "hits": hits}).Warn("Added a honeypot attacker to the IP denylist")
+}
+
+// denylistContains reports whether the comma-separated denylist lists the IP itself.
+func denylistContains(denylist, clientIP string) bool {
+	for _, entry := range strings.Split(denylist, ",") {
+		if strings.TrimSpace(entry) == clientIP {
+			return true
+		}
+	}
+	return false
+}
+
+// HitsQuery returns the honeypot hits, newest first, optionally of one client IP.
+func (s *HoneypotService) HitsQuery(clientIP string) *gorm.DB {
+	query := s.db.Model(&models.HoneypotHit{}).Order("id desc")
+	if clientIP != "" {
+		query = query.Where("client_ip = ?", clientIP)
+	}
+	return query
+}
+
+// Report summarizes the honeypot hits of the last hours.
+func (s *HoneypotService) Report(hours int) (*HoneypotReport, error) {
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	report := &HoneypotReport{Since: since, Triggers: map[string]int64{}, Profiles: []HoneypotProfile{}}
+	inPeriod := func() *gorm.DB {
+		return s.db.Model(&models.HoneypotHit{}).Where("created_at >= ?", since)
+	}
+
+	var triggers []struct {
+		TriggerType string
+		Hits        int64
+	}
+	if err := inPeriod().Select("trigger_type, COUNT(*) AS hits").Group("trigger_type").Scan(&triggers).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range triggers {
+		report.Triggers[t.TriggerType] = t.Hits
+		report.Hits += t.Hits
+	}
+	if err := inPeriod().Distinct("client_ip").Count(&report.ClientIPs).Error; err != nil {
+		return nil, err
+	}
+
+	// The first and last hits are found by ID, as databases differ in the type of MIN(created_at)
+	var rows []struct {
+		ClientIP string
+		Hits     int64
+		Paths    int64
+		Tokens   int64
+		FirstID  uint
+		LastID   uint
+	}
+	err := inPeriod().
+		Select("client_ip, COUNT(*) AS hits, COUNT(DISTINCT path) AS paths, COUNT(DISTINCT token) AS tokens, MIN(id) AS first_id, MAX(id) AS last_id").
+		Group("client_ip").Order("hits DESC").Limit(honeypotReportProfiles).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, 2*len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.FirstID, row.LastID)
+	}
+	var hits []models.HoneypotHit
+	if len(ids) > 0 {
+		if err := s.db.Select("id", "created_at", "user_agent").Where("id IN ?", ids).Find(&hits).Error; err != nil {
+			return nil, err
+		}
+	}
+	hitsByID := make(map[uint]models.HoneypotHit, len(hits))
+	for _, hit := range hits {
+		hitsByID[hit.ID] = hit
+	}
+
+	denylist := s.settingsManager.GetSettings().IPDenylist
+	for _, row := range rows {
+		report.Profiles = append(report.Profiles, HoneypotProfile{
+			ClientIP:  row.ClientIP,
+			Hits:      row.Hits,
+			Paths:     row.Paths,
+			Tokens:    row.Tokens,
+			FirstSeen: hitsByID[row.FirstID].CreatedAt,
+			LastSeen:  hitsByID[row.LastID].CreatedAt,
+			UserAgent: hitsByID[row.LastID].UserAgent,
+			Denied:    denylistContains(denylist, row.ClientIP),
+		})
+	}
+	return report, nil
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/config"
+	"gpt-load/internal/store"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ipRateKeyPrefix     = "ip_rate:"
+	ipAuthFailKeyPrefix = "ip_auth_fail:"
+	ipBanKeyPrefix      = "ip_ban:"
+
+	// IPThrottleScopeProxy and IPThrottleScopeAuth are the request limits of a client IP.
+	IPThrottleScopeProxy = "proxy"
+	IPThrottleScopeAuth  = "auth"
+
+	ipRateWindow = time.Minute
+	// ipAuthFailWindow is the window in which ip_ban_threshold authentication failures ban an IP.
+	ipAuthFailWindow = 10 * time.Minute
+)
+
+// IPThrottleService limits the requests of each client IP independently of proxy keys, and
+// bans an IP for a while after repeated authentication failures. Counters are sliding windows
+// kept in the store, so that they are shared by the instances of a cluster.
+type IPThrottleService struct {
+	store           store.Store
+	settingsManager *config.SystemSettingsManager
+}
+
+// NewIPThrottleService creates a new IPThrottleService.
+func NewIPThrottleService(store store.Store, settingsManager *config.SystemSettingsManager) *IPThrottleService {
+	return &IPThrottleService{
+		store:           store,
+		settingsManager: settingsManager,
+	}
+}
+
+// Allow counts a request of the client IP in the scope and reports whether it is within the
+// per-minute limit of the scope, with the time to wait before retrying when it is not.
+func (s *IPThrottleService) Allow(scope, ip string) (bool, time.Duration) {
+	settings := s.settingsManager.GetSettings()
+	limit := settings.IPRateLimitPerMinute
+	if scope == IPThrottleScopeAuth {
+		limit = settings.AuthRateLimitPerMinute
+	}
+	if limit <= 0 {
+		return true, 0
+	}
+
+	count, err := s.hit(ipRateKeyPrefix+scope+":"+ip, ipRateWindow)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to count the requests of a client IP, allowing it")
+		return true, 0
+	}
+	if count <= float64(limit) {
+		return true, 0
+	}
+	now := time.Now()
+	return false, now.Truncate(ipRateWindow).Add(ipRateWindow).Sub(now)
+}
+
+// Banned reports whether the client IP is banned, and for how long.
+func (s *IPThrottleService) Banned(ip string) (bool, time.Duration) {
+	data, err := s.store.Get(ipBanKeyPrefix + ip)
+	if err != nil {
+		return false, 0
+	}
+	until, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return false, 0
+	}
+	remaining := time.Until(time.Unix(until, 0))
+	return remaining > 0, remaining
+}
+
+// RecordAuthFailure counts a failed authentication of the client IP and bans the IP for
+// ip_ban_duration_seconds once it reaches ip_ban_threshold failures in ten minutes.
+func (s *IPThrottleService) RecordAuthFailure(ip string) {
+	settings := s.settingsManager.GetSettings()
+	if settings.IPBanThreshold <= 0 {
+		return
+	}
+
+	count, err := s.hit(ipAuthFailKeyPrefix+ip, ipAuthFailWindow)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to count the authentication failures of a client IP")
+		return
+	}
+	if count < float64(settings.IPBanThreshold) {
+		return
+	}
+
+	duration := time.Duration(settings.IPBanDurationSeconds) * time.Second
+	until := time.Now().Add(duration).Unix()
+	created, err := s.store.SetNX(ipBanKeyPrefix+ip, []byte(strconv.FormatInt(until, 10)), duration)
+	if err != nil {
+		logrus.WithError(err).WithField("ip", ip).Warn("Failed to ban a client IP")
+		return
+	}
+	if created {
+		logrus.WithFields(logrus.Fields{
+			"ip":       ip,
+			"failures": int(count),
+			"duration": duration,
+		}).Warn("Banned a client IP after repeated authentication failures")
+	}
+}
+
+// hit counts an event and returns the count over the sliding window: the count of the current
+// fixed window plus the count of the previous one, weighted by the part of it that is still
+// in the sliding window.
+func (s *IPThrottleService) hit(key string, window time.Duration) (float64, error) {
+	now := time.Now()
+	current := now.Truncate(window)
+	count, err := s.store.IncrBy(fmt.Sprintf("%s:%d", key, current.Unix()), 1, 2*window)
+	if err != nil {
+		return 0, err
+	}
+
+	var previous int64
+	if data, err := s.store.Get(fmt.Sprintf("%s:%d", key, current.Add(-window).Unix())); err == nil {
+		previous, _ = strconv.ParseInt(string(data), 10, 64)
+	}
+	weight := 1 - float64(now.Sub(current))/float64(window)
+	return float64(count) + float64(previous)*weight, nil
+}
@@ -51,7 +51,7 @@ func (s *KeyImportService) runImport(group *models.Group, keys []string) {
 		}
 	}
 
-	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeys(group.ID, keys, progressCallback)
+	result, err := s.ImportKeys(group.ID, keys, progressCallback)
 	if err != nil {
 		if endErr := s.TaskService.EndTask(nil, err); endErr != nil {
 			logrus.Errorf("Failed to end task with error for group %d: %v (original error: %v)", group.ID, endErr, err)
@@ -59,12 +59,20 @@ func (s *KeyImportService) runImport(group *models.Group, keys []string) {
 		return
 	}
 
-	result := KeyImportResult{
-		AddedCount:   addedCount,
-		IgnoredCount: ignoredCount,
+	if endErr := s.TaskService.EndTask(*result, nil); endErr != nil {
+		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
 	}
+}
 
-	if endErr := s.TaskService.EndTask(result, nil); endErr != nil {
-		logrus.Errorf("Failed to end task with success result for group %d: %v", group.ID, endErr)
+// ImportKeys adds keys to a group synchronously, without the batch limit of AddMultipleKeys.
+// New keys are pending until they are verified.
+func (s *KeyImportService) ImportKeys(groupID uint, keys []string, progressCallback func(processed int)) (*KeyImportResult, error) {
+	addedCount, ignoredCount, err := s.KeyService.processAndCreateKeys(groupID, keys, models.KeyStatusPending, progressCallback)
+	if err != nil {
+		return nil, err
 	}
+	return &KeyImportResult{
+		AddedCount:   addedCount,
+		IgnoredCount: ignoredCount,
+	}, nil
 }
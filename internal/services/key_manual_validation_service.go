@@ -7,6 +7,7 @@ import (
 	"gpt-load/internal/keypool"
 	"gpt-load/internal/models"
 	"gpt-load/internal/types"
+	"math/rand/v2"
 	"sync"
 	"time"
 
@@ -29,10 +30,11 @@ type KeyManualValidationService struct {
 	SettingsManager *config.SystemSettingsManager
 	ConfigManager   types.ConfigManager
 	EncryptionSvc   encryption.Service
+	Budget          *keypool.ValidationBudget
 }
 
 // NewKeyManualValidationService creates a new KeyManualValidationService.
-func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator, taskService *TaskService, settingsManager *config.SystemSettingsManager, configManager types.ConfigManager, encryptionSvc encryption.Service) *KeyManualValidationService {
+func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator, taskService *TaskService, settingsManager *config.SystemSettingsManager, configManager types.ConfigManager, encryptionSvc encryption.Service, budget *keypool.ValidationBudget) *KeyManualValidationService {
 	return &KeyManualValidationService{
 		DB:              db,
 		Validator:       validator,
@@ -40,13 +42,44 @@ func NewKeyManualValidationService(db *gorm.DB, validator *keypool.KeyValidator,
 		SettingsManager: settingsManager,
 		ConfigManager:   configManager,
 		EncryptionSvc:   encryptionSvc,
+		Budget:          budget,
 	}
 }
 
 // StartValidationTask starts a new manual validation task for a given group.
-func (s *KeyManualValidationService) StartValidationTask(group *models.Group, status string) (*TaskStatus, error) {
+// If tags are given, only keys carrying all of them are validated. An override run is
+// urgent and does not wait for the validation budget.
+func (s *KeyManualValidationService) StartValidationTask(group *models.Group, status string, tags []string, override bool) (*TaskStatus, error) {
+	keys, err := s.loadKeys(group, status, tags)
+	if err != nil {
+		return nil, err
+	}
+
+	taskStatus, err := s.TaskService.StartTask(TaskTypeKeyValidation, group.Name, len(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the validation in a separate goroutine
+	go s.runValidation(group, keys, status, override)
+
+	return taskStatus, nil
+}
+
+// ValidateKeys validates the keys of a group synchronously, reporting progress to the
+// callback if set. Status and tags filter the keys as in StartValidationTask.
+func (s *KeyManualValidationService) ValidateKeys(group *models.Group, status string, tags []string, progressCallback func(processed int)) (*ManualValidationResult, error) {
+	keys, err := s.loadKeys(group, status, tags)
+	if err != nil {
+		return nil, err
+	}
+	result := s.validateAll(group, keys, status, false, progressCallback)
+	return &result, nil
+}
+
+func (s *KeyManualValidationService) loadKeys(group *models.Group, status string, tags []string) ([]models.APIKey, error) {
 	var keys []models.APIKey
-	query := s.DB.Where("group_id = ?", group.ID)
+	query := s.DB.Where("group_id = ?", group.ID).Scopes(models.WithKeyTags(tags))
 	if status != "" {
 		query = query.Where("status = ?", status)
 	}
@@ -57,19 +90,23 @@ func (s *KeyManualValidationService) StartValidationTask(group *models.Group, st
 	if len(keys) == 0 {
 		return nil, fmt.Errorf("no keys to validate in group %s", group.Name)
 	}
+	return keys, nil
+}
 
-	taskStatus, err := s.TaskService.StartTask(TaskTypeKeyValidation, group.Name, len(keys))
-	if err != nil {
-		return nil, err
-	}
-
-	// Run the validation in a separate goroutine
-	go s.runValidation(group, keys, status)
+func (s *KeyManualValidationService) runValidation(group *models.Group, keys []models.APIKey, status string, override bool) {
+	result := s.validateAll(group, keys, status, override, func(processed int) {
+		if err := s.TaskService.UpdateProgress(processed); err != nil {
+			logrus.Warnf("Failed to update task progress: %v", err)
+		}
+	})
 
-	return taskStatus, nil
+	// End the task and store the final result
+	if err := s.TaskService.EndTask(result, nil); err != nil {
+		logrus.Errorf("Failed to end task for group %s: %v", group.Name, err)
+	}
 }
 
-func (s *KeyManualValidationService) runValidation(group *models.Group, keys []models.APIKey, status string) {
+func (s *KeyManualValidationService) validateAll(group *models.Group, keys []models.APIKey, status string, override bool, progressCallback func(processed int)) ManualValidationResult {
 	logFields := logrus.Fields{
 		"group":  group.Name,
 		"status": status,
@@ -79,23 +116,26 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 	}
 	logrus.WithFields(logFields).Info("Starting manual validation")
 
-	jobs := make(chan models.APIKey, len(keys))
-	results := make(chan bool, len(keys))
-
-	concurrency := group.EffectiveConfig.KeyValidationConcurrency
-
-	var wg sync.WaitGroup
-	for range concurrency {
-		wg.Add(1)
-		go s.validationWorker(&wg, group, jobs, results)
-	}
-
-	for _, key := range keys {
-		jobs <- key
+	// Validate in random order so that no key is always checked first
+	rand.Shuffle(len(keys), func(i, j int) { keys[i], keys[j] = keys[j], keys[i] })
+	limiter := keypool.NewValidationLimiter(group.EffectiveConfig.KeyValidationConcurrency, group.EffectiveConfig.KeyValidationMaxRPS)
+	if !override {
+		limiter.WithBudget(s.Budget)
 	}
-	close(jobs)
+	results := make(chan bool, len(keys))
 
 	go func() {
+		var wg sync.WaitGroup
+		for _, key := range keys {
+			limiter.Acquire(nil)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				isValid, err := s.validateKey(group, key)
+				limiter.Release(err)
+				results <- isValid
+			}()
+		}
 		wg.Wait()
 		close(results)
 	}()
@@ -111,17 +151,15 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 		}
 
 		// Throttle progress updates to once per second
-		if time.Since(lastUpdateTime) > time.Second {
-			if err := s.TaskService.UpdateProgress(processedCount); err != nil {
-				logrus.Warnf("Failed to update task progress: %v", err)
-			}
+		if progressCallback != nil && time.Since(lastUpdateTime) > time.Second {
+			progressCallback(processedCount)
 			lastUpdateTime = time.Now()
 		}
 	}
 
 	// Ensure the final progress is always updated
-	if err := s.TaskService.UpdateProgress(processedCount); err != nil {
-		logrus.Warnf("Failed to update final task progress: %v", err)
+	if progressCallback != nil {
+		progressCallback(processedCount)
 	}
 
 	result := ManualValidationResult{
@@ -129,31 +167,19 @@ func (s *KeyManualValidationService) runValidation(group *models.Group, keys []m
 		ValidKeys:   validCount,
 		InvalidKeys: len(keys) - validCount,
 	}
-
-	// End the task and store the final result
-	if err := s.TaskService.EndTask(result, nil); err != nil {
-		logrus.Errorf("Failed to end task for group %s: %v", group.Name, err)
-	}
-	logrus.Infof("Manual validation finished for group %s: %+v", group.Name, result)
+	logrus.Infof("Manual validation finished for group %s: %+v, final concurrency %d after %d backoffs", group.Name, result, limiter.Concurrency(), limiter.Backoffs())
+	return result
 }
 
-// validationResult 包含验证结果信息
-func (s *KeyManualValidationService) validationWorker(wg *sync.WaitGroup, group *models.Group, jobs <-chan models.APIKey, results chan<- bool) {
-	defer wg.Done()
-	for key := range jobs {
-		// Decrypt the key before validation
-		decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
-		if err != nil {
-			logrus.WithError(err).WithField("key_id", key.ID).Error("Manual validation: Failed to decrypt key for validation, marking as invalid")
-			results <- false
-			continue
-		}
-
-		// Create a copy with decrypted value for validation
-		keyForValidation := key
-		keyForValidation.KeyValue = decryptedKey
-
-		isValid, _ := s.Validator.ValidateSingleKey(&keyForValidation, group)
-		results <- isValid
+// validateKey decrypts and validates a single key.
+func (s *KeyManualValidationService) validateKey(group *models.Group, key models.APIKey) (bool, error) {
+	decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
+	if err != nil {
+		logrus.WithError(err).WithField("key_id", key.ID).Error("Manual validation: Failed to decrypt key for validation, marking as invalid")
+		return false, err
 	}
+
+	// Create a copy with decrypted value for validation
+	key.KeyValue = decryptedKey
+	return s.Validator.ValidateSingleKey(&key, group)
 }
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/keymask"
+	"gpt-load/internal/models"
+	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// keyDecryptFailed is shown in place of a key that cannot be decrypted.
+const keyDecryptFailed = "failed-to-decrypt"
+
+// KeyMaskingService applies the key_masking setting to every key value the management API
+// returns, and audits the plaintext keys revealed with reveal=true or exported.
+type KeyMaskingService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	encryptionSvc   encryption.Service
+}
+
+// NewKeyMaskingService creates a new KeyMaskingService.
+func NewKeyMaskingService(db *gorm.DB, settingsManager *config.SystemSettingsManager, encryptionSvc encryption.Service) *KeyMaskingService {
+	return &KeyMaskingService{
+		db:              db,
+		settingsManager: settingsManager,
+		encryptionSvc:   encryptionSvc,
+	}
+}
+
+// KeyRevealSource is the caller a response with plaintext keys is audited for.
+type KeyRevealSource struct {
+	Endpoint  string
+	SourceIP  string
+	UserAgent string
+}
+
+// KeyView shows the key values of one response at a masking level and, for audited
+// responses, counts the keys it shows in plaintext.
+type KeyView struct {
+	service  *KeyMaskingService
+	level    string
+	source   KeyRevealSource
+	audited  bool
+	revealed int
+}
+
+// View returns the view of a response to a caller of role at the configured masking level,
+// or in plaintext when reveal is set, which is audited. Callers other than admins never see
+// plaintext keys: a plaintext level shows them the last four characters instead.
+func (s *KeyMaskingService) View(source KeyRevealSource, reveal bool, role string) *KeyView {
+	level := s.settingsManager.GetSettings().KeyMasking
+	if reveal {
+		level = keymask.Plaintext
+	}
+	if role != types.AdminRoleAdmin && level == keymask.Plaintext {
+		level = keymask.Last4
+	}
+	return &KeyView{service: s, level: level, source: source, audited: reveal}
+}
+
+// MaskProxyKeys returns comma separated proxy keys, of a group or of the proxy_keys setting, as
+// a caller of role sees them. Admins see them as they are, other callers see the last four
+// characters of each key.
+func MaskProxyKeys(proxyKeys, role string) string {
+	if role == types.AdminRoleAdmin || proxyKeys == "" {
+		return proxyKeys
+	}
+	keys := utils.SplitAndTrim(proxyKeys, ",")
+	for i, key := range keys {
+		keys[i] = keymask.Mask(keymask.Last4, key, "")
+	}
+	return strings.Join(keys, ",")
+}
+
+// ViewForRequest returns the view of the response to an API request by a caller of role.
+// The query parameter reveal=true shows admins plaintext keys whatever the setting.
+func (s *KeyMaskingService) ViewForRequest(c *gin.Context, role string) *KeyView {
+	reveal, _ := strconv.ParseBool(c.Query("reveal"))
+	return s.View(KeyRevealSource{
+		Endpoint:  c.Request.Method + " " + c.FullPath(),
+		SourceIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}, reveal, role)
+}
+
+// ViewForExport returns the view of an export by a caller of role, like ViewForRequest, but
+// audits the plaintext keys it carries whether or not reveal is set.
+func (s *KeyMaskingService) ViewForExport(c *gin.Context, role string) *KeyView {
+	view := s.ViewForRequest(c, role)
+	view.audited = true
+	return view
+}
+
+// Show returns an encrypted key value with the stored hash of the key as the view shows it.
+// The key is only decrypted when the level needs it.
+func (v *KeyView) Show(encrypted, hash string) string {
+	if encrypted == "" {
+		return ""
+	}
+	plaintext := ""
+	if v.level == keymask.Plaintext || v.level == keymask.Last4 {
+		decrypted, err := v.service.encryptionSvc.Decrypt(encrypted)
+		if err != nil {
+			logrus.WithError(err).WithField("key_hash", hash).Error("Failed to decrypt key value for display")
+			return keyDecryptFailed
+		}
+		plaintext = decrypted
+	}
+	if v.audited && v.level == keymask.Plaintext {
+		v.revealed++
+	}
+	return keymask.Mask(v.level, plaintext, hash)
+}
+
+// Audit records the audited plaintext keys the view has shown since the last call, if any. groupID
+// is 0 for responses spanning groups.
+func (v *KeyView) Audit(groupID uint) {
+	if v.revealed == 0 {
+		return
+	}
+	entry := models.KeyRevealLog{
+		Endpoint:  utils.TruncateString(v.source.Endpoint, 255),
+		GroupID:   groupID,
+		KeyCount:  v.revealed,
+		SourceIP:  utils.TruncateString(v.source.SourceIP, 64),
+		UserAgent: utils.TruncateString(v.source.UserAgent, 512),
+	}
+	v.revealed = 0
+	if err := v.service.db.Create(&entry).Error; err != nil {
+		logrus.WithError(err).WithField("endpoint", entry.Endpoint).Error("Failed to audit revealed keys")
+	}
+}
+
+// RevealLogsQuery returns the audit entries of plaintext keys, newest first, optionally of
+// one group.
+func (s *KeyMaskingService) RevealLogsQuery(groupID uint) *gorm.DB {
+	query := s.db.Model(&models.KeyRevealLog{}).Order("id desc")
+	if groupID > 0 {
+		query = query.Where("group_id = ?", groupID)
+	}
+	return query
+}
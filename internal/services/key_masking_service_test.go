@@ -0,0 +1,21 @@
+package services
+
+import (
+	"testing"
+
+	"gpt-load/internal/types"
+)
+
+func TestMaskProxyKeys(t *testing.T) {
+	const keys = "sk-proxy-aaaa1111, sk-proxy-bbbb2222,short"
+
+	if got := MaskProxyKeys(keys, types.AdminRoleAdmin); got != keys {
+		t.Errorf("MaskProxyKeys() for an admin = %q, want the keys as they are", got)
+	}
+	if got := MaskProxyKeys(keys, types.AdminRoleViewer); got != "****1111,****2222,********" {
+		t.Errorf("MaskProxyKeys() for a viewer = %q", got)
+	}
+	if got := MaskProxyKeys("", types.AdminRoleViewer); got != "" {
+		t.Errorf("MaskProxyKeys() of no keys = %q", got)
+	}
+}
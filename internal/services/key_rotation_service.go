@@ -0,0 +1,363 @@
+package services
+
+import (
+	"context"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/config"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	keyRotationBatchSize = 500
+	// keyRotationInterval paces the batches so that the proxy keeps serving while rotating.
+	keyRotationInterval     = 2 * time.Second
+	keyRotationIdleInterval = time.Minute
+)
+
+// KeyRotationStatus reports the progress of an online encryption key rotation.
+type KeyRotationStatus struct {
+	// Rotating is true while data written with ENCRYPTION_KEY_SECONDARY can still be read.
+	Rotating bool `json:"rotating"`
+	// Completed is true once every key and upstream credential was checked.
+	Completed     bool  `json:"completed"`
+	RotatedKeys   int64 `json:"rotated_keys"`
+	RotatedGroups int64 `json:"rotated_groups"`
+	// FailedKeys are the keys that cannot be decrypted with either key.
+	FailedKeys []uint `json:"failed_keys"`
+}
+
+// KeyRotationService re-encrypts the keys and group secrets written with the secondary
+// encryption key in the background while the proxy keeps serving, and drops the secondary key
+// once an administrator finishes the rotation. It runs on the leading master only.
+type KeyRotationService struct {
+	db           *gorm.DB
+	encryption   *encryption.RotatingService
+	keyProvider  *keypool.KeyProvider
+	groupManager *GroupManager
+	elector      *cluster.Elector
+	mu           sync.Mutex
+	status       KeyRotationStatus
+	lastKeyID    uint
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewKeyRotationService creates a new KeyRotationService.
+func NewKeyRotationService(db *gorm.DB, encryptionSvc encryption.Service, keyProvider *keypool.KeyProvider, groupManager *GroupManager, elector *cluster.Elector) *KeyRotationService {
+	rotating, _ := encryptionSvc.(*encryption.RotatingService)
+	return &KeyRotationService{
+		// Keys and groups in the trash are rotated too
+		db:           db.Unscoped().Session(&gorm.Session{}),
+		encryption:   rotating,
+		keyProvider:  keyProvider,
+		groupManager: groupManager,
+		elector:      elector,
+		status:       KeyRotationStatus{Rotating: rotating != nil, FailedKeys: []uint{}},
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start begins the rotation when ENCRYPTION_KEY_SECONDARY is set.
+func (s *KeyRotationService) Start() {
+	if s.encryption == nil {
+		return
+	}
+	s.wg.Add(1)
+	go s.run()
+	logrus.Info("Key rotation service started, re-encrypting data written with ENCRYPTION_KEY_SECONDARY")
+}
+
+// Stop stops the rotation, respecting the context for shutdown timeout.
+func (s *KeyRotationService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("KeyRotationService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("KeyRotationService stop timed out.")
+	}
+}
+
+func (s *KeyRotationService) run() {
+	defer s.wg.Done()
+	for {
+		wait := keyRotationIdleInterval
+		if s.step() {
+			wait = keyRotationInterval
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// step rotates one batch of keys and reports whether more remain.
+func (s *KeyRotationService) step() bool {
+	if !s.encryption.Rotating() || !s.elector.IsLeader() {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.status.Completed {
+		return false
+	}
+
+	more, err := s.rotateKeyBatch()
+	if err != nil {
+		logrus.WithError(err).Error("Failed to rotate a batch of keys, retrying later")
+		return false
+	}
+	if more {
+		return true
+	}
+
+	if err := s.rotateGroupSecrets(); err != nil {
+		logrus.WithError(err).Error("Failed to rotate group secrets, retrying later")
+		return false
+	}
+	if err := s.rotateAdminSecrets(); err != nil {
+		logrus.WithError(err).Error("Failed to rotate admin secrets, retrying later")
+		return false
+	}
+	if err := s.rotateSettingSecrets(); err != nil {
+		logrus.WithError(err).Error("Failed to rotate secret settings, retrying later")
+		return false
+	}
+	s.status.Completed = true
+	logrus.WithFields(logrus.Fields{
+		"keys":   s.status.RotatedKeys,
+		"groups": s.status.RotatedGroups,
+		"failed": len(s.status.FailedKeys),
+	}).Info("Key rotation completed, finish it to drop ENCRYPTION_KEY_SECONDARY")
+	return false
+}
+
+// rotateKeyBatch re-encrypts the next batch of keys and reports whether more remain.
+func (s *KeyRotationService) rotateKeyBatch() (bool, error) {
+	if s.lastKeyID == 0 {
+		s.status.FailedKeys = []uint{}
+	}
+
+	var keys []models.APIKey
+	if err := s.db.Select("id", "group_id", "key_value").Where("id > ?", s.lastKeyID).Order("id").Limit(keyRotationBatchSize).Find(&keys).Error; err != nil {
+		return false, err
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+
+	var rotated []models.APIKey
+	for _, key := range keys {
+		encrypted, hash, ok, err := s.encryption.Reencrypt(key.KeyValue)
+		if err != nil {
+			logrus.WithError(err).WithField("key_id", key.ID).Warn("Key cannot be rotated")
+			s.status.FailedKeys = append(s.status.FailedKeys, key.ID)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		// The key may have changed since it was read, it is then picked up by the next pass
+		result := s.db.Model(&models.APIKey{}).
+			Where("id = ? AND key_value = ?", key.ID, key.KeyValue).
+			UpdateColumns(map[string]any{"key_value": encrypted, "key_hash": hash})
+		if result.Error != nil {
+			return false, result.Error
+		}
+		if result.RowsAffected > 0 {
+			key.KeyValue = encrypted
+			rotated = append(rotated, key)
+		}
+	}
+
+	if err := s.keyProvider.RefreshStoredKeyValues(rotated); err != nil {
+		return false, err
+	}
+	s.status.RotatedKeys += int64(len(rotated))
+	s.lastKeyID = keys[len(keys)-1].ID
+	return true, nil
+}
+
+// rotateGroupSecrets re-encrypts the secrets stored in the group upstreams, config and
+// header rules.
+func (s *KeyRotationService) rotateGroupSecrets() error {
+	var groups []models.Group
+	if err := s.db.Select("id", "upstreams", "config", "header_rules").Find(&groups).Error; err != nil {
+		return err
+	}
+
+	rotatedGroups := int64(0)
+	for _, group := range groups {
+		changed, err := TransformGroupSecrets(&group, func(stored string) (string, error) {
+			encrypted, _, ok, err := s.encryption.Reencrypt(stored)
+			if err != nil {
+				// Neither key reads it, as a config secret stored in plaintext before it
+				// was encrypted, so it is left for the next save of the group
+				logrus.WithError(err).WithField("group_id", group.ID).Warn("Group secret cannot be rotated")
+				return stored, nil
+			}
+			if !ok {
+				return stored, nil
+			}
+			return encrypted, nil
+		})
+		if err != nil {
+			return err
+		}
+		if !changed {
+			continue
+		}
+
+		columns := map[string]any{"upstreams": group.Upstreams, "config": group.Config, "header_rules": group.HeaderRules}
+		if err := s.db.Model(&models.Group{}).Where("id = ?", group.ID).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		rotatedGroups++
+	}
+
+	if rotatedGroups > 0 {
+		s.status.RotatedGroups += rotatedGroups
+		if err := s.groupManager.Invalidate(); err != nil {
+			logrus.WithError(err).Error("failed to invalidate group cache")
+		}
+	}
+	return nil
+}
+
+// rotateAdminSecrets re-encrypts the two-factor secrets of the admin accounts, without which
+// their logins fail once the secondary key is dropped.
+func (s *KeyRotationService) rotateAdminSecrets() error {
+	var users []models.AdminUser
+	if err := s.db.Select("id", "totp_secret").Where("totp_secret <> ''").Find(&users).Error; err != nil {
+		return err
+	}
+	for _, user := range users {
+		encrypted, _, ok, err := s.encryption.Reencrypt(user.TOTPSecret)
+		if err != nil {
+			logrus.WithError(err).WithField("admin_user_id", user.ID).Warn("Admin TOTP secret cannot be rotated")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		err = s.db.Model(&models.AdminUser{}).
+			Where("id = ? AND totp_secret = ?", user.ID, user.TOTPSecret).
+			UpdateColumn("totp_secret", encrypted).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rotateSettingSecrets re-encrypts the secret system settings. The settings manager holds them
+// in plaintext, so the running instances need no reload.
+func (s *KeyRotationService) rotateSettingSecrets() error {
+	var settings []models.SystemSetting
+	if err := s.db.Where("setting_key IN ? AND setting_value <> ''", config.SecretSettings).Find(&settings).Error; err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		encrypted, _, ok, err := s.encryption.Reencrypt(setting.SettingValue)
+		if err != nil {
+			// Neither key reads it, as a setting stored in plaintext before it was
+			// encrypted, so it is left for its next update
+			logrus.WithError(err).WithField("setting", setting.SettingKey).Warn("Secret setting cannot be rotated")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		err = s.db.Model(&models.SystemSetting{}).
+			Where("setting_key = ? AND setting_value = ?", setting.SettingKey, setting.SettingValue).
+			UpdateColumn("setting_value", encrypted).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status returns the progress of the rotation.
+func (s *KeyRotationService) Status() KeyRotationStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	status.Rotating = s.encryption != nil && s.encryption.Rotating()
+	return status
+}
+
+// Finish runs a last full pass over the keys and group secrets, refreshes the keys
+// cached in the store and drops the secondary key, after which data written with it can no
+// longer be read. It fails while any key cannot be decrypted with either key.
+func (s *KeyRotationService) Finish(ctx context.Context) (*KeyRotationStatus, error) {
+	if s.encryption == nil || !s.encryption.Rotating() {
+		return nil, NewI18nError(app_errors.ErrBadRequest, "validation.no_key_rotation", nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastKeyID = 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		more, err := s.rotateKeyBatch()
+		if err != nil {
+			return nil, app_errors.ParseDBError(err)
+		}
+		if !more {
+			break
+		}
+	}
+	if err := s.rotateGroupSecrets(); err != nil {
+		return nil, err
+	}
+	if err := s.rotateAdminSecrets(); err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	if err := s.rotateSettingSecrets(); err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	s.status.Completed = true
+	if len(s.status.FailedKeys) > 0 {
+		return nil, NewI18nError(app_errors.ErrValidation, "validation.key_rotation_failed_keys", map[string]any{"count": len(s.status.FailedKeys)})
+	}
+
+	// Cached details may have been written from rows read before they were rotated
+	var keys []models.APIKey
+	err := s.db.Select("id", "key_value").Where("status <> ?", models.KeyStatusArchived).
+		FindInBatches(&keys, keyRotationBatchSize, func(tx *gorm.DB, batch int) error {
+			return s.keyProvider.RefreshStoredKeyValues(keys)
+		}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	s.encryption.DropSecondary()
+	logrus.Info("Key rotation finished, ENCRYPTION_KEY_SECONDARY is no longer used and can be removed")
+
+	status := s.status
+	status.Rotating = false
+	return &status, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpt-load/internal/encryption"
+	"gpt-load/internal/models"
+	"gpt-load/internal/totp"
+	"gpt-load/internal/types"
+
+	"github.com/glebarez/sqlite"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testAuthConfig serves the auth config of an AdminAuthService under test.
+type testAuthConfig struct {
+	types.ConfigManager
+}
+
+func (testAuthConfig) GetAuthConfig() types.AuthConfig {
+	return types.AuthConfig{SessionTTLHours: 1}
+}
+
+func TestKeyRotationKeepsTOTPLogin(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "rotation.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&models.APIKey{}, &models.Group{}, &models.AdminUser{}, &models.AdminSession{}, &models.SystemSetting{}); err != nil {
+		t.Fatal(err)
+	}
+
+	oldService, err := encryption.NewService("old-encryption-key-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedSecret, err := oldService.Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := models.AdminUser{Username: "admin", PasswordHash: string(passwordHash), TOTPEnabled: true, TOTPSecret: encryptedSecret}
+	if err := database.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	encryptedS3Secret, err := oldService.Encrypt("s3-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.Create(&models.SystemSetting{SettingKey: "archive_s3_secret_key", SettingValue: encryptedS3Secret}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	rotating, err := encryption.NewRotatingService("new-encryption-key-123456", "old-encryption-key-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotation := NewKeyRotationService(database, rotating, nil, nil, nil)
+	if _, err := rotation.Finish(context.Background()); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	code, err := totp.Code(secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth := NewAdminAuthService(database, testAuthConfig{}, rotating)
+	if _, err := auth.Login("admin", "password", code, "127.0.0.1", "test"); err != nil {
+		t.Fatalf("Login() after rotation error = %v", err)
+	}
+
+	newService, err := encryption.NewService("new-encryption-key-123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var setting models.SystemSetting
+	if err := database.First(&setting, "setting_key = ?", "archive_s3_secret_key").Error; err != nil {
+		t.Fatal(err)
+	}
+	if got, err := newService.Decrypt(setting.SettingValue); err != nil || got != "s3-secret" {
+		t.Errorf("archive_s3_secret_key after rotation = %q, %v", got, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"gpt-load/internal/models"
 	"io"
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/sirupsen/logrus"
@@ -59,8 +60,18 @@ func NewKeyService(db *gorm.DB, keyProvider *keypool.KeyProvider, keyValidator *
 }
 
 // AddMultipleKeys handles the business logic of creating new keys from a text block.
+// New keys are pending until they are verified.
 // deprecated: use KeyImportService for large imports
 func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysResult, error) {
+	return s.addKeysWithStatus(groupID, keysText, models.KeyStatusPending)
+}
+
+// AddReserveKeys parks new keys from a text block in the reserve of a group.
+func (s *KeyService) AddReserveKeys(groupID uint, keysText string) (*AddKeysResult, error) {
+	return s.addKeysWithStatus(groupID, keysText, models.KeyStatusReserve)
+}
+
+func (s *KeyService) addKeysWithStatus(groupID uint, keysText, status string) (*AddKeysResult, error) {
 	keys := s.ParseKeysFromText(keysText)
 	if len(keys) > maxRequestKeys {
 		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keys))
@@ -69,7 +80,7 @@ func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysRes
 		return nil, fmt.Errorf("no valid keys found in the input text")
 	}
 
-	addedCount, ignoredCount, err := s.processAndCreateKeys(groupID, keys, nil)
+	addedCount, ignoredCount, err := s.processAndCreateKeys(groupID, keys, status, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +101,7 @@ func (s *KeyService) AddMultipleKeys(groupID uint, keysText string) (*AddKeysRes
 func (s *KeyService) processAndCreateKeys(
 	groupID uint,
 	keys []string,
+	status string,
 	progressCallback func(processed int),
 ) (addedCount int, ignoredCount int, err error) {
 	// 1. Get existing key hashes in the group for deduplication
@@ -112,11 +124,12 @@ func (s *KeyService) processAndCreateKeys(
 			continue
 		}
 
-		// Generate hash for deduplication check
-		keyHash := s.EncryptionSvc.Hash(trimmedKey)
-		if existingHashMap[keyHash] {
+		// Generate hash for deduplication check, also under the key being rotated out
+		keyHashes := encryption.Hashes(s.EncryptionSvc, trimmedKey)
+		if slices.ContainsFunc(keyHashes, func(hash string) bool { return existingHashMap[hash] }) {
 			continue
 		}
+		keyHash := s.EncryptionSvc.Hash(trimmedKey)
 
 		encryptedKey, err := s.EncryptionSvc.Encrypt(trimmedKey)
 		if err != nil {
@@ -129,7 +142,7 @@ func (s *KeyService) processAndCreateKeys(
 			GroupID:  groupID,
 			KeyValue: encryptedKey,
 			KeyHash:  keyHash,
-			Status:   models.KeyStatusActive,
+			Status:   status,
 		})
 	}
 
@@ -237,18 +250,29 @@ func (s *KeyService) RestoreMultipleKeys(groupID uint, keysText string) (*Restor
 }
 
 // RestoreAllInvalidKeys sets the status of all 'inactive' keys in a group to 'active'.
-func (s *KeyService) RestoreAllInvalidKeys(groupID uint) (int64, error) {
-	return s.KeyProvider.RestoreKeys(groupID)
+// If tags are given, only keys carrying all of them are restored.
+func (s *KeyService) RestoreAllInvalidKeys(groupID uint, tags []string) (int64, error) {
+	return s.KeyProvider.RestoreKeys(groupID, tags)
 }
 
 // ClearAllInvalidKeys deletes all 'inactive' keys from a group.
-func (s *KeyService) ClearAllInvalidKeys(groupID uint) (int64, error) {
-	return s.KeyProvider.RemoveInvalidKeys(groupID)
+// If tags are given, only keys carrying all of them are deleted.
+func (s *KeyService) ClearAllInvalidKeys(groupID uint, tags []string) (int64, error) {
+	return s.KeyProvider.RemoveInvalidKeys(groupID, tags)
 }
 
 // ClearAllKeys deletes all keys from a group.
-func (s *KeyService) ClearAllKeys(groupID uint) (int64, error) {
-	return s.KeyProvider.RemoveAllKeys(groupID)
+// If tags are given, only keys carrying all of them are deleted.
+func (s *KeyService) ClearAllKeys(groupID uint, tags []string) (int64, error) {
+	return s.KeyProvider.RemoveAllKeys(groupID, tags)
+}
+
+// TransitionKeys moves keys of a group along their lifecycle.
+func (s *KeyService) TransitionKeys(groupID uint, keyIDs []uint, to string) (*keypool.LifecycleResult, error) {
+	if len(keyIDs) > maxRequestKeys {
+		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keyIDs))
+	}
+	return s.KeyProvider.TransitionKeys(groupID, keyIDs, to)
 }
 
 // DeleteMultipleKeys handles the business logic of deleting keys from a text block.
@@ -290,15 +314,15 @@ func (s *KeyService) DeleteMultipleKeys(groupID uint, keysText string) (*DeleteK
 }
 
 // ListKeysInGroupQuery builds a query to list all keys within a specific group, filtered by status.
-func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHash string) *gorm.DB {
-	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID)
+func (s *KeyService) ListKeysInGroupQuery(groupID uint, statusFilter string, searchHashes []string, tags []string) *gorm.DB {
+	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Scopes(models.WithKeyTags(tags))
 
 	if statusFilter != "" {
 		query = query.Where("status = ?", statusFilter)
 	}
 
-	if searchHash != "" {
-		query = query.Where("key_hash = ?", searchHash)
+	if len(searchHashes) > 0 {
+		query = query.Where("key_hash IN ?", searchHashes)
 	}
 
 	orderBy := "last_used_at desc, id desc"
@@ -338,14 +362,15 @@ func (s *KeyService) TestMultipleKeys(group *models.Group, keysText string) ([]k
 	return allResults, nil
 }
 
-// StreamKeysToWriter fetches keys from the database in batches and writes them to the provided writer.
-func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, writer io.Writer) error {
-	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Select("id, key_value")
+// StreamKeysToWriter fetches keys from the database in batches and writes them, as view shows
+// them, to the provided writer.
+func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, tags []string, writer io.Writer, view *KeyView) error {
+	query := s.DB.Model(&models.APIKey{}).Where("group_id = ?", groupID).Scopes(models.WithKeyTags(tags)).Select("id, key_value, key_hash")
 
-	switch statusFilter {
-	case models.KeyStatusActive, models.KeyStatusInvalid:
+	switch {
+	case statusFilter == "all":
+	case models.IsKeyStatus(statusFilter):
 		query = query.Where("status = ?", statusFilter)
-	case "all":
 	default:
 		return fmt.Errorf("invalid status filter: %s", statusFilter)
 	}
@@ -353,12 +378,11 @@ func (s *KeyService) StreamKeysToWriter(groupID uint, statusFilter string, write
 	var keys []models.APIKey
 	err := query.FindInBatches(&keys, chunkSize, func(tx *gorm.DB, batch int) error {
 		for _, key := range keys {
-			decryptedKey, err := s.EncryptionSvc.Decrypt(key.KeyValue)
-			if err != nil {
-				logrus.WithError(err).WithField("key_id", key.ID).Error("Failed to decrypt key for streaming, skipping")
+			value := view.Show(key.KeyValue, key.KeyHash)
+			if value == keyDecryptFailed {
 				continue
 			}
-			if _, err := writer.Write([]byte(decryptedKey + "\n")); err != nil {
+			if _, err := writer.Write([]byte(value + "\n")); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/encryption"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/models"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// KeyTagsResult holds the result of tagging or untagging keys.
+type KeyTagsResult struct {
+	MatchedCount int `json:"matched_count"`
+	UpdatedCount int `json:"updated_count"`
+}
+
+// UpdateKeyTags adds tags to, or removes them from, the keys of a group selected by ID or
+// found in keysText.
+func (s *KeyService) UpdateKeyTags(groupID uint, keyIDs []uint, keysText string, tags models.KeyTags, add bool) (*KeyTagsResult, error) {
+	keyValues := s.ParseKeysFromText(keysText)
+	var keyHashes []string
+	for _, keyValue := range keyValues {
+		keyHashes = append(keyHashes, encryption.Hashes(s.EncryptionSvc, keyValue)...)
+	}
+	if len(keyIDs)+len(keyValues) > maxRequestKeys {
+		return nil, fmt.Errorf("batch size exceeds the limit of %d keys, got %d", maxRequestKeys, len(keyIDs)+len(keyValues))
+	}
+	if len(keyIDs)+len(keyHashes) == 0 {
+		return nil, fmt.Errorf("no valid keys found in the input text")
+	}
+
+	result := &KeyTagsResult{}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var keys []models.APIKey
+		if err := tx.Select("id", "tags").
+			Where("group_id = ?", groupID).
+			Where(tx.Where("id IN ?", keyIDs).Or("key_hash IN ?", keyHashes)).
+			Find(&keys).Error; err != nil {
+			return err
+		}
+		result.MatchedCount = len(keys)
+
+		// Keys ending up with the same tags are updated together
+		updates := make(map[string][]uint)
+		for _, key := range keys {
+			newTags := slices.Clone(key.Tags)
+			for _, tag := range tags {
+				switch {
+				case add && !slices.Contains(newTags, tag):
+					newTags = append(newTags, tag)
+				case !add:
+					newTags = slices.DeleteFunc(newTags, func(t string) bool { return t == tag })
+				}
+			}
+			if slices.Equal(newTags, key.Tags) {
+				continue
+			}
+			if len(newTags) > models.MaxKeyTags {
+				return NewI18nError(app_errors.ErrValidation, "validation.too_many_key_tags", map[string]any{"max": models.MaxKeyTags})
+			}
+			value, _ := models.KeyTags(newTags).Value()
+			updates[value.(string)] = append(updates[value.(string)], key.ID)
+		}
+
+		for value, ids := range updates {
+			if err := tx.Model(&models.APIKey{}).Where("id IN ?", ids).Update("tags", value).Error; err != nil {
+				return err
+			}
+			result.UpdatedCount += len(ids)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"gpt-load/internal/models"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxKeyUsageStatsHours is the longest window of the statistics of a key.
+	MaxKeyUsageStatsHours = 30 * 24
+	// keyErrorCodesWindow is the period of the error code histogram, whatever the window.
+	keyErrorCodesWindow = 24 * time.Hour
+)
+
+// KeyUsageStats summarizes the requests made with one key, from the request logs of a window.
+// Every attempt counts, including the ones retried with another key.
+type KeyUsageStats struct {
+	KeyID        uint      `json:"key_id"`
+	GroupID      uint      `json:"group_id"`
+	Since        time.Time `json:"since"`
+	RequestCount int64     `json:"request_count"`
+	SuccessCount int64     `json:"success_count"`
+	// SuccessRate is the percentage of successful requests, 0 without requests.
+	SuccessRate  float64 `json:"success_rate"`
+	P50LatencyMs int64   `json:"p50_latency_ms"`
+	P95LatencyMs int64   `json:"p95_latency_ms"`
+	// ErrorCodes counts the failed requests of the last 24 hours by status code, 0 for
+	// network errors.
+	ErrorCodes map[int]int64 `json:"error_codes"`
+	// RateLimitedCount is the number of 429 responses, RateLimitedPerHour their hourly average.
+	RateLimitedCount   int64   `json:"rate_limited_count"`
+	RateLimitedPerHour float64 `json:"rate_limited_per_hour"`
+	// Lifetime counters of the key.
+	TotalRequestCount int64      `json:"total_request_count"`
+	TotalFailureCount int64      `json:"total_failure_count"`
+	LastUsedAt        *time.Time `json:"last_used_at"`
+}
+
+// UsageStats computes the statistics of a key over the last hours.
+func (s *KeyService) UsageStats(key *models.APIKey, hours int) (*KeyUsageStats, error) {
+	since := time.Now().Add(-time.Duration(hours) * time.Hour)
+	stats := &KeyUsageStats{
+		KeyID:             key.ID,
+		GroupID:           key.GroupID,
+		Since:             since,
+		ErrorCodes:        make(map[int]int64),
+		TotalRequestCount: key.RequestCount,
+		TotalFailureCount: key.FailureCount,
+		LastUsedAt:        key.LastUsedAt,
+	}
+	if key.KeyHash == "" {
+		return stats, nil
+	}
+
+	logs := func() *gorm.DB {
+		return s.DB.Model(&models.RequestLog{}).
+			Where("group_id = ? AND key_hash = ? AND timestamp >= ?", key.GroupID, key.KeyHash, since)
+	}
+
+	var counts struct {
+		Requests    int64
+		Successes   int64
+		RateLimited int64
+	}
+	if err := logs().Select(
+		"COUNT(*) AS requests, COALESCE(SUM(CASE WHEN is_success = ? THEN 1 ELSE 0 END), 0) AS successes, COALESCE(SUM(CASE WHEN status_code = ? THEN 1 ELSE 0 END), 0) AS rate_limited",
+		true, http.StatusTooManyRequests,
+	).Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to query key requests: %w", err)
+	}
+	stats.RequestCount = counts.Requests
+	stats.SuccessCount = counts.Successes
+	if stats.RequestCount > 0 {
+		stats.SuccessRate = float64(stats.SuccessCount) / float64(stats.RequestCount) * 100
+	}
+	stats.RateLimitedCount = counts.RateLimited
+	stats.RateLimitedPerHour = float64(stats.RateLimitedCount) / float64(hours)
+
+	var err error
+	if stats.P50LatencyMs, err = percentileDuration(logs, stats.RequestCount, 50); err != nil {
+		return nil, fmt.Errorf("failed to query key latencies: %w", err)
+	}
+	if stats.P95LatencyMs, err = percentileDuration(logs, stats.RequestCount, 95); err != nil {
+		return nil, fmt.Errorf("failed to query key latencies: %w", err)
+	}
+
+	var failures []struct {
+		StatusCode int
+		Count      int64
+	}
+	if err := s.DB.Model(&models.RequestLog{}).
+		Select("status_code, COUNT(*) as count").
+		Where("group_id = ? AND key_hash = ? AND timestamp >= ? AND is_success = ?", key.GroupID, key.KeyHash, time.Now().Add(-keyErrorCodesWindow), false).
+		Group("status_code").
+		Scan(&failures).Error; err != nil {
+		return nil, fmt.Errorf("failed to query key errors: %w", err)
+	}
+	for _, failure := range failures {
+		stats.ErrorCodes[failure.StatusCode] = failure.Count
+	}
+
+	return stats, nil
+}
+
+// percentileDuration returns the nearest-rank percentile of the durations of count logs, 0 when
+// there are none. The database sorts the durations and returns only the one at the rank.
+func percentileDuration(logs func() *gorm.DB, count int64, p int64) (int64, error) {
+	if count == 0 {
+		return 0, nil
+	}
+	rank := max((count*p+99)/100, 1)
+	var durations []int64
+	if err := logs().Order("duration asc").Offset(int(rank-1)).Limit(1).Pluck("duration", &durations).Error; err != nil {
+		return 0, err
+	}
+	if len(durations) == 0 {
+		return 0, nil
+	}
+	return durations[0], nil
+}
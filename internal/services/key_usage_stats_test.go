@@ -0,0 +1,64 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gpt-load/internal/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestKeyUsageStats(t *testing.T) {
+	database, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "stats.db")), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.AutoMigrate(&models.RequestLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := &models.APIKey{GroupID: 1, KeyHash: "hash"}
+	now := time.Now()
+	var logs []models.RequestLog
+	for i := 1; i <= 20; i++ {
+		logs = append(logs, models.RequestLog{ID: fmt.Sprintf("ok-%d", i), Timestamp: now.Add(-time.Hour), GroupID: 1, KeyHash: "hash", IsSuccess: true, StatusCode: http.StatusOK, Duration: int64(i * 10)})
+	}
+	logs = append(logs,
+		models.RequestLog{ID: "recent-429", Timestamp: now.Add(-time.Hour), GroupID: 1, KeyHash: "hash", StatusCode: http.StatusTooManyRequests, Duration: 1000},
+		models.RequestLog{ID: "old-429", Timestamp: now.Add(-48 * time.Hour), GroupID: 1, KeyHash: "hash", StatusCode: http.StatusTooManyRequests, Duration: 1000},
+		models.RequestLog{ID: "old-500", Timestamp: now.Add(-48 * time.Hour), GroupID: 1, KeyHash: "hash", StatusCode: http.StatusInternalServerError, Duration: 1000},
+		models.RequestLog{ID: "other-key", Timestamp: now.Add(-time.Hour), GroupID: 1, KeyHash: "other", StatusCode: http.StatusInternalServerError, Duration: 5},
+	)
+	if err := database.Create(&logs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := (&KeyService{DB: database}).UsageStats(key, 72)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.RequestCount != 23 || stats.SuccessCount != 20 || stats.RateLimitedCount != 2 {
+		t.Errorf("counts = %d requests, %d successes, %d rate limited", stats.RequestCount, stats.SuccessCount, stats.RateLimitedCount)
+	}
+	if stats.P50LatencyMs != 120 || stats.P95LatencyMs != 1000 {
+		t.Errorf("latency p50 = %d, p95 = %d, want 120 and 1000", stats.P50LatencyMs, stats.P95LatencyMs)
+	}
+	// The histogram only covers the last 24 hours, whatever the window
+	if len(stats.ErrorCodes) != 1 || stats.ErrorCodes[http.StatusTooManyRequests] != 1 {
+		t.Errorf("ErrorCodes = %v, want one 429", stats.ErrorCodes)
+	}
+
+	empty, err := (&KeyService{DB: database}).UsageStats(&models.APIKey{GroupID: 2, KeyHash: "unused"}, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.RequestCount != 0 || empty.P95LatencyMs != 0 {
+		t.Errorf("stats without requests = %+v", empty)
+	}
+}
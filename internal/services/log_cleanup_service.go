@@ -2,8 +2,12 @@ package services
 
 import (
 	"context"
+	"fmt"
+	"gpt-load/internal/archive"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
 	"gpt-load/internal/models"
+	"gpt-load/internal/types"
 	"sync"
 	"time"
 
@@ -11,19 +15,28 @@ import (
 	"gorm.io/gorm"
 )
 
-// LogCleanupService 负责清理过期的请求日志
+const (
+	logArchiveBatchSize     = 5000
+	logArchiveUploadTimeout = 5 * time.Minute
+)
+
+// LogCleanupService 负责清理过期的请求日志和小时统计
 type LogCleanupService struct {
 	db              *gorm.DB
 	settingsManager *config.SystemSettingsManager
+	archiveConfig   types.LogArchiveConfig
+	elector         *cluster.Elector
 	stopCh          chan struct{}
 	wg              sync.WaitGroup
 }
 
 // NewLogCleanupService 创建新的日志清理服务
-func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager) *LogCleanupService {
+func NewLogCleanupService(db *gorm.DB, settingsManager *config.SystemSettingsManager, configManager types.ConfigManager, elector *cluster.Elector) *LogCleanupService {
 	return &LogCleanupService{
 		db:              db,
 		settingsManager: settingsManager,
+		archiveConfig:   configManager.GetLogArchiveConfig(),
+		elector:         elector,
 		stopCh:          make(chan struct{}),
 	}
 }
@@ -72,24 +85,57 @@ func (s *LogCleanupService) run() {
 	}
 }
 
-// cleanupExpiredLogs 清理过期的请求日志
+// cleanupExpiredLogs 清理过期的请求日志和小时统计
 func (s *LogCleanupService) cleanupExpiredLogs() {
-	// 获取日志保留天数配置
+	if !s.elector.IsLeader() {
+		return
+	}
+
 	settings := s.settingsManager.GetSettings()
-	retentionDays := settings.RequestLogRetentionDays
+	target := archive.NewTarget(archive.SettingsConfig(settings, s.archiveConfig))
+	archiving := settings.RequestLogRetentionAction == models.LogRetentionArchive
 
-	if retentionDays <= 0 {
+	if settings.RequestLogRetentionDays <= 0 {
 		logrus.Debug("Log retention is disabled (retention_days <= 0)")
-		return
+	} else {
+		cutoffTime := time.Now().AddDate(0, 0, -settings.RequestLogRetentionDays).UTC()
+		if archiving {
+			s.archiveExpired(target, "request logs", cutoffTime, func(target archive.Target) (int, error) {
+				return archiveBatch(s.db, target, archive.KindRequestLogs, "timestamp", cutoffTime, func(log *models.RequestLog) (time.Time, any) {
+					return log.Timestamp, log.ID
+				})
+			})
+		} else {
+			s.deleteExpired(&models.RequestLog{}, "request logs", "timestamp", cutoffTime, settings.RequestLogRetentionDays)
+		}
 	}
 
-	// 计算过期时间点
-	cutoffTime := time.Now().AddDate(0, 0, -retentionDays).UTC()
+	// 蜜罐命中记录和密钥明文审计记录与请求日志保留相同天数，不归档
+	if settings.RequestLogRetentionDays > 0 {
+		cutoffTime := time.Now().AddDate(0, 0, -settings.RequestLogRetentionDays).UTC()
+		s.deleteExpired(&models.HoneypotHit{}, "honeypot hits", "created_at", cutoffTime, settings.RequestLogRetentionDays)
+		s.deleteExpired(&models.KeyRevealLog{}, "key reveal logs", "created_at", cutoffTime, settings.RequestLogRetentionDays)
+	}
+
+	if settings.HourlyStatsRetentionDays > 0 {
+		cutoffTime := time.Now().AddDate(0, 0, -settings.HourlyStatsRetentionDays).UTC()
+		if archiving {
+			s.archiveExpired(target, "hourly stats", cutoffTime, func(target archive.Target) (int, error) {
+				return archiveBatch(s.db, target, archive.KindHourlyStats, "time", cutoffTime, func(stat *models.GroupHourlyStat) (time.Time, any) {
+					return stat.Time, stat.ID
+				})
+			})
+		} else {
+			s.deleteExpired(&models.GroupHourlyStat{}, "hourly stats", "time", cutoffTime, settings.HourlyStatsRetentionDays)
+		}
+	}
+}
 
-	// 执行删除操作
-	result := s.db.Where("timestamp < ?", cutoffTime).Delete(&models.RequestLog{})
+// deleteExpired 删除指定表中早于截止时间的记录
+func (s *LogCleanupService) deleteExpired(model any, what, timeColumn string, cutoffTime time.Time, retentionDays int) {
+	result := s.db.Where(timeColumn+" < ?", cutoffTime).Delete(model)
 	if result.Error != nil {
-		logrus.WithError(result.Error).Error("Failed to cleanup expired request logs")
+		logrus.WithError(result.Error).Errorf("Failed to cleanup expired %s", what)
 		return
 	}
 
@@ -98,8 +144,81 @@ func (s *LogCleanupService) cleanupExpiredLogs() {
 			"deleted_count":  result.RowsAffected,
 			"cutoff_time":    cutoffTime.Format(time.RFC3339),
 			"retention_days": retentionDays,
-		}).Info("Successfully cleaned up expired request logs")
+		}).Infof("Successfully cleaned up expired %s", what)
+	} else {
+		logrus.Debugf("No expired %s found to cleanup", what)
+	}
+}
+
+// archiveExpired 将过期记录分批归档后删除，每批一个 gzip 压缩的 NDJSON 对象。
+// 只有上传成功的批次才会被删除，未配置归档位置时保留所有记录。
+func (s *LogCleanupService) archiveExpired(target archive.Target, what string, cutoffTime time.Time, batch func(archive.Target) (int, error)) {
+	if target == nil {
+		logrus.Warnf("Log retention action is archive but no archive bucket or LOG_ARCHIVE_DIR is set, expired %s are kept", what)
+		return
+	}
+
+	archived := 0
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		default:
+		}
+
+		n, err := batch(target)
+		if err != nil {
+			logrus.WithError(err).WithField("target", target.String()).Errorf("Failed to archive expired %s, they are kept until the next run", what)
+			return
+		}
+		archived += n
+
+		if n < logArchiveBatchSize {
+			break
+		}
+	}
+
+	if archived > 0 {
+		logrus.WithFields(logrus.Fields{
+			"archived_count": archived,
+			"cutoff_time":    cutoffTime.Format(time.RFC3339),
+			"target":         target.String(),
+		}).Infof("Successfully archived expired %s", what)
 	} else {
-		logrus.Debug("No expired request logs found to cleanup")
+		logrus.Debugf("No expired %s found to archive", what)
+	}
+}
+
+// archiveBatch uploads the oldest batch of records before the cutoff to the target, then
+// deletes them, and returns how many were archived. key returns the time and the primary
+// key of a record.
+func archiveBatch[T any](db *gorm.DB, target archive.Target, kind, timeColumn string, cutoffTime time.Time, key func(*T) (time.Time, any)) (int, error) {
+	var records []T
+	if err := db.Where(timeColumn+" < ?", cutoffTime).Order(timeColumn + ", id").Limit(logArchiveBatchSize).Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired records: %w", err)
+	}
+	if len(records) == 0 {
+		return 0, nil
+	}
+
+	data, err := archive.Encode(records)
+	if err != nil {
+		return 0, err
+	}
+	firstTime, firstID := key(&records[0])
+	ctx, cancel := context.WithTimeout(context.Background(), logArchiveUploadTimeout)
+	defer cancel()
+	if err := target.Put(ctx, archive.Name(kind, firstTime, fmt.Sprint(firstID)), data); err != nil {
+		return 0, err
+	}
+
+	ids := make([]any, len(records))
+	for i := range records {
+		_, ids[i] = key(&records[i])
+	}
+	var model T
+	if err := db.Where("id IN ?", ids).Delete(&model).Error; err != nil {
+		return 0, fmt.Errorf("failed to delete archived records: %w", err)
 	}
+	return len(records), nil
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"gpt-load/internal/encryption"
 	"gpt-load/internal/models"
@@ -10,17 +11,33 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 // ExportableLogKey defines the structure for the data to be exported to CSV.
 type ExportableLogKey struct {
 	KeyValue   string `gorm:"column:key_value"`
+	KeyHash    string `gorm:"column:key_hash"`
 	GroupName  string `gorm:"column:group_name"`
 	StatusCode int    `gorm:"column:status_code"`
 }
 
+// Request log export formats
+const (
+	LogExportNDJSON = "ndjson"
+	LogExportCSV    = "csv"
+)
+
+const logExportBatchSize = 1000
+
+// logExportColumns are the CSV columns of a request log export, named like its JSON fields.
+var logExportColumns = []string{
+	"id", "timestamp", "group_id", "group_name", "parent_group_id", "parent_group_name", "key_value",
+	"model", "is_success", "source_ip", "status_code", "request_path", "duration_ms", "error_message",
+	"user_agent", "request_type", "upstream_addr", "is_stream", "is_overflow", "provider_incident",
+	"requested_model", "moderation_verdict", "is_flagged", "request_id", "mirrored_from", "request_body",
+}
+
 // LogService provides services related to request logs.
 type LogService struct {
 	DB            *gorm.DB
@@ -41,6 +58,9 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 		if parentGroupName := c.Query("parent_group_name"); parentGroupName != "" {
 			db = db.Where("parent_group_name LIKE ?", "%"+parentGroupName+"%")
 		}
+		if groupID, err := strconv.Atoi(c.Query("group_id")); err == nil && groupID > 0 {
+			db = db.Where("group_id = ? OR parent_group_id = ?", groupID, groupID)
+		}
 		if groupName := c.Query("group_name"); groupName != "" {
 			db = db.Where("group_name LIKE ?", "%"+groupName+"%")
 		}
@@ -49,13 +69,35 @@ func (s *LogService) logFiltersScope(c *gin.Context) func(db *gorm.DB) *gorm.DB
 			db = db.Where("key_hash = ?", keyHash)
 		}
 		if model := c.Query("model"); model != "" {
-			db = db.Where("model LIKE ?", "%"+model+"%")
+			db = db.Where("model LIKE ? OR requested_model LIKE ?", "%"+model+"%", "%"+model+"%")
 		}
 		if isSuccessStr := c.Query("is_success"); isSuccessStr != "" {
 			if isSuccess, err := strconv.ParseBool(isSuccessStr); err == nil {
 				db = db.Where("is_success = ?", isSuccess)
 			}
 		}
+		if isOverflowStr := c.Query("is_overflow"); isOverflowStr != "" {
+			if isOverflow, err := strconv.ParseBool(isOverflowStr); err == nil {
+				db = db.Where("is_overflow = ?", isOverflow)
+			}
+		}
+		if isFlaggedStr := c.Query("is_flagged"); isFlaggedStr != "" {
+			if isFlagged, err := strconv.ParseBool(isFlaggedStr); err == nil {
+				db = db.Where("is_flagged = ?", isFlagged)
+			}
+		}
+		if requestID := c.Query("request_id"); requestID != "" {
+			db = db.Where("request_id = ?", requestID)
+		}
+		if isMirrorStr := c.Query("is_mirror"); isMirrorStr != "" {
+			if isMirror, err := strconv.ParseBool(isMirrorStr); err == nil {
+				if isMirror {
+					db = db.Where("mirrored_from <> ''")
+				} else {
+					db = db.Where("mirrored_from = ''")
+				}
+			}
+		}
 		if requestType := c.Query("request_type"); requestType != "" {
 			db = db.Where("request_type = ?", requestType)
 		}
@@ -89,8 +131,18 @@ func (s *LogService) GetLogsQuery(c *gin.Context) *gorm.DB {
 	return s.DB.Model(&models.RequestLog{}).Scopes(s.logFiltersScope(c))
 }
 
-// StreamLogKeysToCSV fetches unique keys from logs based on filters and streams them as a CSV.
-func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error {
+// GetRequestLogs returns the log entries of a client request, one per attempt, oldest first.
+func (s *LogService) GetRequestLogs(requestID string) ([]models.RequestLog, error) {
+	var logs []models.RequestLog
+	if err := s.DB.Where("request_id = ?", requestID).Order("timestamp asc").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// StreamLogKeysToCSV fetches unique keys from logs based on filters and streams them as a CSV,
+// with the keys shown by view.
+func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer, view *KeyView) error {
 	// Create a CSV writer
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
@@ -109,6 +161,7 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 	err := s.DB.Raw(`
 		SELECT
 			key_value,
+			key_hash,
 			group_name,
 			status_code
 		FROM (
@@ -128,21 +181,10 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 		return fmt.Errorf("failed to fetch log keys: %w", err)
 	}
 
-	// 解密并写入CSV数据
+	// 按脱敏级别写入CSV数据
 	for _, record := range results {
-		// 解密密钥用于CSV导出
-		decryptedKey := record.KeyValue
-		if record.KeyValue != "" {
-			if decrypted, err := s.EncryptionSvc.Decrypt(record.KeyValue); err != nil {
-				logrus.WithError(err).WithField("key_value", record.KeyValue).Error("Failed to decrypt key for CSV export")
-				decryptedKey = "failed-to-decrypt"
-			} else {
-				decryptedKey = decrypted
-			}
-		}
-
 		csvRecord := []string{
-			decryptedKey,
+			view.Show(record.KeyValue, record.KeyHash),
 			record.GroupName,
 			strconv.Itoa(record.StatusCode),
 		}
@@ -153,3 +195,94 @@ func (s *LogService) StreamLogKeysToCSV(c *gin.Context, writer io.Writer) error
 
 	return nil
 }
+
+// StreamLogRecords streams the request logs matching the filters in chronological order,
+// one JSON object per line or as CSV, with the keys shown by view.
+func (s *LogService) StreamLogRecords(c *gin.Context, writer io.Writer, format string, view *KeyView) error {
+	var csvWriter *csv.Writer
+	var encoder *json.Encoder
+	if format == LogExportCSV {
+		csvWriter = csv.NewWriter(writer)
+		if err := csvWriter.Write(logExportColumns); err != nil {
+			return fmt.Errorf("failed to write CSV header: %w", err)
+		}
+	} else {
+		encoder = json.NewEncoder(writer)
+	}
+
+	// 以 (timestamp, id) 作为游标分批读取，避免一次性加载全部日志
+	var lastTimestamp time.Time
+	var lastID string
+	for {
+		query := s.GetLogsQuery(c)
+		if lastID != "" {
+			query = query.Where("timestamp > ? OR (timestamp = ? AND id > ?)", lastTimestamp, lastTimestamp, lastID)
+		}
+
+		var logs []models.RequestLog
+		if err := query.Order("timestamp, id").Limit(logExportBatchSize).Find(&logs).Error; err != nil {
+			return fmt.Errorf("failed to fetch request logs: %w", err)
+		}
+
+		for i := range logs {
+			log := &logs[i]
+			log.KeyValue = view.Show(log.KeyValue, log.KeyHash)
+
+			var err error
+			if csvWriter != nil {
+				err = csvWriter.Write(logExportRecord(log))
+			} else {
+				err = encoder.Encode(log)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to write request log: %w", err)
+			}
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return fmt.Errorf("failed to write request logs: %w", err)
+			}
+		}
+		if flusher, ok := writer.(interface{ Flush() }); ok {
+			flusher.Flush()
+		}
+
+		if len(logs) < logExportBatchSize {
+			return nil
+		}
+		lastTimestamp, lastID = logs[len(logs)-1].Timestamp, logs[len(logs)-1].ID
+	}
+}
+
+func logExportRecord(log *models.RequestLog) []string {
+	return []string{
+		log.ID,
+		log.Timestamp.UTC().Format(time.RFC3339Nano),
+		strconv.FormatUint(uint64(log.GroupID), 10),
+		log.GroupName,
+		strconv.FormatUint(uint64(log.ParentGroupID), 10),
+		log.ParentGroupName,
+		log.KeyValue,
+		log.Model,
+		strconv.FormatBool(log.IsSuccess),
+		log.SourceIP,
+		strconv.Itoa(log.StatusCode),
+		log.RequestPath,
+		strconv.FormatInt(log.Duration, 10),
+		log.ErrorMessage,
+		log.UserAgent,
+		log.RequestType,
+		log.UpstreamAddr,
+		strconv.FormatBool(log.IsStream),
+		strconv.FormatBool(log.IsOverflow),
+		log.ProviderIncident,
+		log.RequestedModel,
+		log.ModerationVerdict,
+		strconv.FormatBool(log.IsFlagged),
+		log.RequestID,
+		log.MirroredFrom,
+		log.RequestBody,
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"encoding/json"
+	"gpt-load/internal/failover"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// RequestLogStreamChannel carries new request logs to the live log streams of every node.
+	RequestLogStreamChannel = "request_log_stream"
+
+	// logStreamListenersKey exists while at least one live log stream is open on any node.
+	logStreamListenersKey = "log_stream_listeners"
+	// LogStreamListenerTTL is how long an open stream keeps publishing enabled without refreshing it.
+	LogStreamListenerTTL   = 30 * time.Second
+	logStreamCheckInterval = 5 * time.Second
+)
+
+// LogStreamFilter selects the request logs sent to a live log stream.
+type LogStreamFilter struct {
+	// GroupID matches the group or the aggregate parent group, 0 matches all groups.
+	GroupID     uint
+	StatusCodes failover.StatusCodeMatcher
+	KeyHash     string
+}
+
+// Match reports whether a request log passes the filter.
+func (f *LogStreamFilter) Match(log *models.RequestLog) bool {
+	if f.GroupID != 0 && log.GroupID != f.GroupID && log.ParentGroupID != f.GroupID {
+		return false
+	}
+	if !f.StatusCodes.IsEmpty() && !f.StatusCodes.Match(log.StatusCode) {
+		return false
+	}
+	if f.KeyHash != "" && log.KeyHash != f.KeyHash {
+		return false
+	}
+	return true
+}
+
+// LogStreamService fans new request logs out to the live log streams of the admin API.
+// Logs are only published while a stream is open somewhere, so idle clusters pay nothing.
+type LogStreamService struct {
+	store     store.Store
+	active    atomic.Bool
+	checkedAt atomic.Int64
+}
+
+// NewLogStreamService creates a new LogStreamService.
+func NewLogStreamService(store store.Store) *LogStreamService {
+	return &LogStreamService{store: store}
+}
+
+// Publish sends a request log to the open live log streams.
+func (s *LogStreamService) Publish(log *models.RequestLog) {
+	if !s.hasListeners() {
+		return
+	}
+
+	payload, err := json.Marshal(log)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to marshal request log for the live log stream")
+		return
+	}
+	if err := s.store.Publish(RequestLogStreamChannel, payload); err != nil {
+		logrus.WithError(err).Debug("Failed to publish request log to the live log stream")
+	}
+}
+
+// Subscribe opens a subscription to new request logs and marks a listener as present.
+// The caller must call KeepAlive at least every LogStreamListenerTTL while it is open.
+func (s *LogStreamService) Subscribe() (store.Subscription, error) {
+	subscription, err := s.store.Subscribe(RequestLogStreamChannel)
+	if err != nil {
+		return nil, err
+	}
+	s.KeepAlive()
+	return subscription, nil
+}
+
+// KeepAlive refreshes the listener marker of an open stream.
+func (s *LogStreamService) KeepAlive() {
+	if err := s.store.Set(logStreamListenersKey, []byte("1"), LogStreamListenerTTL); err != nil {
+		logrus.WithError(err).Warn("Failed to refresh the live log stream listener marker")
+		return
+	}
+	s.active.Store(true)
+	s.checkedAt.Store(time.Now().UnixNano())
+}
+
+// hasListeners checks the listener marker at most once per logStreamCheckInterval.
+func (s *LogStreamService) hasListeners() bool {
+	now := time.Now().UnixNano()
+	checkedAt := s.checkedAt.Load()
+	if now-checkedAt < logStreamCheckInterval.Nanoseconds() || !s.checkedAt.CompareAndSwap(checkedAt, now) {
+		return s.active.Load()
+	}
+
+	exists, err := s.store.Exists(logStreamListenersKey)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to check the live log stream listener marker")
+	}
+	s.active.Store(exists)
+	return exists
+}
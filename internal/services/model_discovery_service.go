@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/channel"
+	"gpt-load/internal/credentials"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	modelListKeyPrefix = "model_list:"
+	// modelListTTL is how long the model list of an upstream is served from the cache.
+	modelListTTL = 10 * time.Minute
+)
+
+// ErrNoModelsDiscovered is returned when no sub-group of an aggregate group could list its models.
+var ErrNoModelsDiscovered = errors.New("no sub-group could list its models")
+
+// ModelDiscoveryService lists the models of the upstreams of a group with one of its active
+// keys and caches the list, so that clients can query /v1/models from any group, including
+// aggregate groups and upstreams that do not list models at /v1/models.
+type ModelDiscoveryService struct {
+	groupManager   *GroupManager
+	channelFactory *channel.Factory
+	keyPool        keypool.KeyPool
+	credentials    *credentials.Manager
+	store          store.Store
+	// locks holds a mutex per group so that concurrent requests query the upstream once.
+	locks sync.Map
+}
+
+// NewModelDiscoveryService creates a new ModelDiscoveryService.
+func NewModelDiscoveryService(groupManager *GroupManager, channelFactory *channel.Factory, keyPool keypool.KeyPool, credentials *credentials.Manager, store store.Store) *ModelDiscoveryService {
+	return &ModelDiscoveryService{
+		groupManager:   groupManager,
+		channelFactory: channelFactory,
+		keyPool:        keyPool,
+		credentials:    credentials,
+		store:          store,
+	}
+}
+
+// Discover returns the models the upstreams of the group serve, from the cache unless refresh
+// is set. The models of an aggregate group are the models of its sub-groups, deduplicated.
+func (s *ModelDiscoveryService) Discover(ctx context.Context, group *models.Group, refresh bool) ([]channel.DiscoveredModel, error) {
+	if group.GroupType != "aggregate" {
+		return s.discoverGroup(ctx, group, refresh)
+	}
+
+	var merged []channel.DiscoveredModel
+	index := make(map[string]int)
+	err := s.eachSubGroup(group, func(subGroup *models.Group) error {
+		discovered, err := s.discoverGroup(ctx, subGroup, refresh)
+		if err != nil {
+			return err
+		}
+		for _, model := range discovered {
+			i, ok := index[model.ID]
+			if !ok {
+				i = len(merged)
+				index[model.ID] = i
+				merged = append(merged, channel.DiscoveredModel{ID: model.ID, OwnedBy: model.OwnedBy})
+			}
+			merged[i].Groups = append(merged[i].Groups, subGroup.Name)
+		}
+		return nil
+	})
+	return merged, err
+}
+
+// ModelList returns the model list served at /v1/models in the OpenAI format, with the model
+// redirect rules and model policy of the group, or of each sub-group of an aggregate group,
+// applied.
+func (s *ModelDiscoveryService) ModelList(ctx context.Context, req *http.Request, group *models.Group) (map[string]any, error) {
+	if group.GroupType != "aggregate" {
+		return s.groupModelList(ctx, req, group)
+	}
+
+	merged := []any{}
+	seen := make(map[string]bool)
+	err := s.eachSubGroup(group, func(subGroup *models.Group) error {
+		list, err := s.groupModelList(ctx, req, subGroup)
+		if err != nil {
+			return err
+		}
+		data, _ := list["data"].([]any)
+		for _, item := range data {
+			model, _ := item.(map[string]any)
+			id, _ := model["id"].(string)
+			if id == "" || seen[id] || !group.ModelPolicy.Allows(id) {
+				continue
+			}
+			seen[id] = true
+			merged = append(merged, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"object": "list", "data": merged}, nil
+}
+
+// eachSubGroup calls fn for each sub-group of an aggregate group. It fails only when fn
+// fails for every sub-group, the others are logged and skipped.
+func (s *ModelDiscoveryService) eachSubGroup(group *models.Group, fn func(subGroup *models.Group) error) error {
+	succeeded := false
+	var lastErr error
+	for _, sg := range group.SubGroups {
+		subGroup, err := s.groupManager.GetGroupByName(sg.SubGroupName)
+		if err == nil {
+			err = fn(subGroup)
+		}
+		if err != nil {
+			logrus.WithError(err).WithFields(logrus.Fields{
+				"aggregate_group": group.Name,
+				"sub_group":       sg.SubGroupName,
+			}).Warn("Failed to list the models of a sub-group")
+			lastErr = err
+			continue
+		}
+		succeeded = true
+	}
+	if !succeeded {
+		if lastErr == nil {
+			return ErrNoModelsDiscovered
+		}
+		return fmt.Errorf("%w: %v", ErrNoModelsDiscovered, lastErr)
+	}
+	return nil
+}
+
+func (s *ModelDiscoveryService) groupModelList(ctx context.Context, req *http.Request, group *models.Group) (map[string]any, error) {
+	discovered, err := s.discoverGroup(ctx, group, false)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]map[string]any, 0, len(discovered))
+	for _, model := range discovered {
+		ownedBy := model.OwnedBy
+		if ownedBy == "" {
+			ownedBy = group.ChannelType
+		}
+		data = append(data, map[string]any{"id": model.ID, "object": "model", "created": 0, "owned_by": ownedBy})
+	}
+	body, err := json.Marshal(map[string]any{"object": "list", "data": data})
+	if err != nil {
+		return nil, err
+	}
+
+	channelHandler, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, err
+	}
+	list, err := channelHandler.TransformModelList(req, body, group)
+	if err != nil {
+		return nil, err
+	}
+
+	items, _ := list["data"].([]any)
+	allowed := make([]any, 0, len(items))
+	for _, item := range items {
+		model, _ := item.(map[string]any)
+		if id, _ := model["id"].(string); group.ModelPolicy.Allows(id) {
+			allowed = append(allowed, item)
+		}
+	}
+	list["data"] = allowed
+	return list, nil
+}
+
+// discoverGroup returns the models of a standard group. The cache entry is tied to the last
+// update of the group, so that changing its upstreams or channel lists them again.
+func (s *ModelDiscoveryService) discoverGroup(ctx context.Context, group *models.Group, refresh bool) ([]channel.DiscoveredModel, error) {
+	cacheKey := fmt.Sprintf("%s%d:%d", modelListKeyPrefix, group.ID, group.UpdatedAt.UnixNano())
+	if !refresh {
+		if cached := s.cached(cacheKey); cached != nil {
+			return cached, nil
+		}
+	}
+
+	lock, _ := s.locks.LoadOrStore(group.ID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	// Another request may have listed the models meanwhile
+	if !refresh {
+		if cached := s.cached(cacheKey); cached != nil {
+			return cached, nil
+		}
+	}
+
+	channelHandler, err := s.channelFactory.GetChannel(group)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like proxied requests, a failing key is retried with the next one up to max_retries times
+	var discovered []channel.DiscoveredModel
+	for attempt := 0; ; attempt++ {
+		discovered, err = s.fetch(ctx, channelHandler, group)
+		if err == nil {
+			break
+		}
+		if errors.Is(err, app_errors.ErrNoActiveKeys) || attempt >= group.EffectiveConfig.MaxRetries {
+			return nil, fmt.Errorf("group %s: %w", group.Name, err)
+		}
+	}
+
+	if data, err := json.Marshal(discovered); err == nil {
+		if err := s.store.Set(cacheKey, data, modelListTTL); err != nil {
+			logrus.WithError(err).WithField("group", group.Name).Warn("Failed to cache the model list")
+		}
+	}
+	return discovered, nil
+}
+
+func (s *ModelDiscoveryService) fetch(ctx context.Context, channelHandler channel.ChannelProxy, group *models.Group) ([]channel.DiscoveredModel, error) {
+	apiKey, err := s.keyPool.SelectKey(group, "")
+	if err != nil {
+		return nil, err
+	}
+	authKey, err := s.credentials.AuthKey(ctx, channelHandler.GetHTTPClient(), group, apiKey)
+	if err != nil {
+		return nil, err
+	}
+	return channel.FetchModels(ctx, channelHandler, authKey, group)
+}
+
+// cached returns the model list cached under cacheKey, nil if there is none.
+func (s *ModelDiscoveryService) cached(cacheKey string) []channel.DiscoveredModel {
+	data, err := s.store.Get(cacheKey)
+	if err != nil {
+		return nil
+	}
+	var discovered []channel.DiscoveredModel
+	if err := json.Unmarshal(data, &discovered); err != nil {
+		return nil
+	}
+	return discovered
+}
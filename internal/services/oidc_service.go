@@ -0,0 +1,196 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/oidc"
+	"gpt-load/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+const oidcTimeout = 15 * time.Second
+
+// OIDCFlow is a login with the identity provider in progress, kept in a cookie of the browser
+// between the redirect to the provider and the callback.
+type OIDCFlow struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// Encode returns the cookie value of a flow.
+func (f OIDCFlow) Encode() string {
+	return f.State + "." + f.Nonce + "." + f.Verifier
+}
+
+// ParseOIDCFlow parses the cookie value of a flow.
+func ParseOIDCFlow(value string) (OIDCFlow, bool) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return OIDCFlow{}, false
+	}
+	return OIDCFlow{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, true
+}
+
+// OIDCService signs in to the management UI with an OpenID Connect identity provider. Users
+// get the role mapped to their groups, and sessions like the admin account.
+type OIDCService struct {
+	configManager types.ConfigManager
+	adminAuth     *AdminAuthService
+	client        *http.Client
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+}
+
+// NewOIDCService creates a new OIDCService.
+func NewOIDCService(configManager types.ConfigManager, adminAuth *AdminAuthService) *OIDCService {
+	return &OIDCService{
+		configManager: configManager,
+		adminAuth:     adminAuth,
+		client:        &http.Client{Timeout: oidcTimeout},
+	}
+}
+
+// Enabled reports whether single sign-on is configured.
+func (s *OIDCService) Enabled() bool {
+	return s.configManager.GetAuthConfig().OIDC.Enabled()
+}
+
+// discover returns the identity provider, fetching its discovery document on first use and
+// again after a failure.
+func (s *OIDCService) discover(ctx context.Context) (*oidc.Provider, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+	provider, err := oidc.Discover(ctx, s.client, s.configManager.GetAuthConfig().OIDC.Issuer)
+	if err != nil {
+		return nil, err
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+func (s *OIDCService) clientConfig() oidc.Config {
+	config := s.configManager.GetAuthConfig().OIDC
+	scopes := config.Scopes
+	if !slices.Contains(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+	return oidc.Config{
+		ClientID:     config.ClientID,
+		ClientSecret: config.ClientSecret,
+		RedirectURL:  config.RedirectURL,
+		Scopes:       scopes,
+	}
+}
+
+// Begin starts a login and returns the URL of the identity provider to send the browser to.
+func (s *OIDCService) Begin(ctx context.Context) (string, OIDCFlow, error) {
+	if !s.Enabled() {
+		return "", OIDCFlow{}, NewI18nError(app_errors.ErrResourceNotFound, "auth.sso_not_configured", nil)
+	}
+	provider, err := s.discover(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to discover the OIDC identity provider")
+		return "", OIDCFlow{}, NewI18nError(app_errors.ErrBadGateway, "auth.sso_failed", nil)
+	}
+
+	var flow OIDCFlow
+	for _, value := range []*string{&flow.State, &flow.Nonce, &flow.Verifier} {
+		if *value, err = oidc.RandomString(); err != nil {
+			return "", OIDCFlow{}, err
+		}
+	}
+	return provider.AuthCodeURL(s.clientConfig(), flow.State, flow.Nonce, flow.Verifier), flow, nil
+}
+
+// Finish completes a login with the code the identity provider returned, checks the email
+// domain and role of the user, and starts a session.
+func (s *OIDCService) Finish(ctx context.Context, flow OIDCFlow, state, code, sourceIP, userAgent string) (*AdminSessionToken, error) {
+	if !s.Enabled() {
+		return nil, NewI18nError(app_errors.ErrResourceNotFound, "auth.sso_not_configured", nil)
+	}
+	if flow.State == "" || state != flow.State || code == "" {
+		return nil, NewI18nError(app_errors.ErrUnauthorized, "auth.sso_failed", nil)
+	}
+	provider, err := s.discover(ctx)
+	if err != nil {
+		logrus.WithError(err).Error("Failed to discover the OIDC identity provider")
+		return nil, NewI18nError(app_errors.ErrBadGateway, "auth.sso_failed", nil)
+	}
+
+	config := s.clientConfig()
+	rawToken, err := provider.Exchange(ctx, config, code, flow.Verifier)
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to redeem an OIDC authorization code")
+		return nil, NewI18nError(app_errors.ErrUnauthorized, "auth.sso_failed", nil)
+	}
+	claims, err := provider.Verify(ctx, config, rawToken, flow.Nonce, time.Now())
+	if err != nil {
+		logrus.WithError(err).Warn("Rejected an OIDC ID token")
+		return nil, NewI18nError(app_errors.ErrUnauthorized, "auth.sso_failed", nil)
+	}
+
+	identity := cmp.Or(claims.String("email"), claims.String("preferred_username"), claims.String("sub"))
+	if !s.emailAllowed(claims) {
+		logrus.Warnf("Rejected the SSO login of %q: email domain not allowed", identity)
+		return nil, NewI18nError(app_errors.ErrForbidden, "auth.sso_email_not_allowed", nil)
+	}
+	role := s.role(claims)
+	if role == "" {
+		logrus.Warnf("Rejected the SSO login of %q: no role mapped to its groups", identity)
+		return nil, NewI18nError(app_errors.ErrForbidden, "auth.sso_no_role", nil)
+	}
+
+	logrus.Infof("SSO login of %q as %s", identity, role)
+	return s.adminAuth.StartSSOSession(identity, role, sourceIP, userAgent)
+}
+
+// emailAllowed reports whether the email of a user is in an allowed domain, and verified when
+// the provider says whether it is.
+func (s *OIDCService) emailAllowed(claims oidc.Claims) bool {
+	domains := s.configManager.GetAuthConfig().OIDC.AllowedEmailDomains
+	if len(domains) == 0 {
+		return true
+	}
+	email := claims.String("email")
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	if verified, ok := claims.Bool("email_verified"); ok && !verified {
+		return false
+	}
+	return slices.ContainsFunc(domains, func(domain string) bool {
+		return strings.EqualFold(email[at+1:], strings.TrimPrefix(domain, "@"))
+	})
+}
+
+// role returns the highest role mapped to the groups of a user, the default role when none is.
+func (s *OIDCService) role(claims oidc.Claims) string {
+	config := s.configManager.GetAuthConfig().OIDC
+	role := ""
+	for _, group := range claims.Strings(config.GroupsClaim) {
+		switch config.RoleMapping[group] {
+		case types.AdminRoleAdmin:
+			return types.AdminRoleAdmin
+		case types.AdminRoleViewer:
+			role = types.AdminRoleViewer
+		}
+	}
+	if role == "" {
+		role = config.DefaultRole
+	}
+	return role
+}
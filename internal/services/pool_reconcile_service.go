@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/db"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	poolReconcileInterval = 30 * time.Minute
+	// poolDriftConfirmDelay is the wait before drifts are checked again, longer than a key
+	// status update takes between its store write and its commit.
+	poolDriftConfirmDelay = 2 * time.Second
+)
+
+// ErrPoolReconcileRunning is returned when a reconciliation is requested while another one is in progress.
+var ErrPoolReconcileRunning = errors.New("pool reconciliation is already running")
+
+// PoolReconcileReport summarizes the drift a reconciliation found between the store and the database.
+type PoolReconcileReport struct {
+	CheckedGroups int `json:"checked_groups"`
+	// Drift counts the confirmed drifts by category.
+	Drift map[string]int `json:"drift"`
+	// Groups lists the drifts of each group that had some, by group ID.
+	Groups     map[uint]*keypool.PoolDrift `json:"groups"`
+	Repaired   int                         `json:"repaired"`
+	DryRun     bool                        `json:"dry_run"`
+	DurationMs int64                       `json:"duration_ms"`
+	FinishedAt time.Time                   `json:"finished_at"`
+}
+
+// PoolReconcileService periodically compares the key pools in the store with the database
+// and repairs the drift, e.g. after manual database edits or partially failed updates.
+type PoolReconcileService struct {
+	db           *gorm.DB
+	keyProvider  *keypool.KeyProvider
+	dbHealth     *db.HealthMonitor
+	elector      *cluster.Elector
+	running      sync.Mutex
+	reportMu     sync.RWMutex
+	lastReport   *PoolReconcileReport
+	confirmDelay time.Duration
+	stopCh       chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewPoolReconcileService creates a new PoolReconcileService.
+func NewPoolReconcileService(db *gorm.DB, keyProvider *keypool.KeyProvider, dbHealth *db.HealthMonitor, elector *cluster.Elector) *PoolReconcileService {
+	return &PoolReconcileService{
+		db:           db,
+		keyProvider:  keyProvider,
+		dbHealth:     dbHealth,
+		elector:      elector,
+		confirmDelay: poolDriftConfirmDelay,
+		stopCh:       make(chan struct{}),
+	}
+}
+
+// Start 启动定期的密钥池校对
+func (s *PoolReconcileService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Pool reconcile service started")
+}
+
+// Stop 停止密钥池校对服务
+func (s *PoolReconcileService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("PoolReconcileService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("PoolReconcileService stop timed out.")
+	}
+}
+
+func (s *PoolReconcileService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(poolReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.elector.IsLeader() {
+				continue
+			}
+			if _, err := s.Run(false); err != nil {
+				logrus.WithError(err).Warn("Pool reconciliation failed")
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// LastReport returns the report of the last reconciliation run by this instance, or nil.
+func (s *PoolReconcileService) LastReport() *PoolReconcileReport {
+	s.reportMu.RLock()
+	defer s.reportMu.RUnlock()
+	return s.lastReport
+}
+
+// Run compares the key pool of every group with the database and, unless dryRun is set,
+// repairs the drift. A drift is only reported once a second check confirms it.
+func (s *PoolReconcileService) Run(dryRun bool) (*PoolReconcileReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrPoolReconcileRunning
+	}
+	defer s.running.Unlock()
+
+	if s.dbHealth.IsDegraded() {
+		return nil, fmt.Errorf("database is degraded, pool reconciliation skipped")
+	}
+
+	start := time.Now()
+	var groupIDs []uint
+	if err := s.db.Model(&models.Group{}).Where("group_type <> ?", "aggregate").Pluck("id", &groupIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+
+	report := &PoolReconcileReport{
+		CheckedGroups: len(groupIDs),
+		Drift:         make(map[string]int),
+		Groups:        make(map[uint]*keypool.PoolDrift),
+		DryRun:        dryRun,
+	}
+
+	suspects := make(map[uint]*keypool.PoolDrift)
+	for _, groupID := range groupIDs {
+		drift, err := s.keyProvider.DetectDrift(groupID)
+		if err != nil {
+			return nil, err
+		}
+		if drift.Total() > 0 {
+			suspects[groupID] = drift
+		}
+	}
+
+	if len(suspects) > 0 {
+		time.Sleep(s.confirmDelay)
+	}
+	for groupID, suspect := range suspects {
+		later, err := s.keyProvider.DetectDrift(groupID)
+		if err != nil {
+			return nil, err
+		}
+		drift := suspect.Confirm(later)
+		if drift.Total() == 0 {
+			continue
+		}
+		report.Groups[groupID] = drift
+		for category, count := range drift.Counts() {
+			report.Drift[category] += count
+		}
+		if dryRun {
+			continue
+		}
+		repaired, err := s.keyProvider.RepairDrift(groupID, drift)
+		if err != nil {
+			return nil, err
+		}
+		report.Repaired += repaired
+	}
+
+	report.DurationMs = time.Since(start).Milliseconds()
+	report.FinishedAt = time.Now()
+	if len(report.Groups) > 0 {
+		logrus.WithFields(logrus.Fields{
+			"groups":   len(report.Groups),
+			"drift":    report.Drift,
+			"repaired": report.Repaired,
+			"dry_run":  dryRun,
+		}).Warn("Key pools drifted from the database")
+	} else {
+		logrus.Debug("Pool reconciliation found no drift")
+	}
+
+	s.reportMu.Lock()
+	s.lastReport = report
+	s.reportMu.Unlock()
+	return report, nil
+}
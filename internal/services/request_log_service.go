@@ -4,9 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"gpt-load/internal/cluster"
 	"gpt-load/internal/config"
+	"gpt-load/internal/db"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -21,6 +24,9 @@ const (
 	RequestLogCachePrefix    = "request_log:"
 	PendingLogKeysSet        = "pending_log_keys"
 	DefaultLogFlushBatchSize = 200
+
+	// degradedLogTTL keeps logs recorded in degraded sync mode until the database is back
+	degradedLogTTL = 24 * time.Hour
 )
 
 // RequestLogService is responsible for managing request logs.
@@ -28,23 +34,34 @@ type RequestLogService struct {
 	db              *gorm.DB
 	store           store.Store
 	settingsManager *config.SystemSettingsManager
+	dbHealth        *db.HealthMonitor
+	logStream       *LogStreamService
+	elector         *cluster.Elector
+	writer          *RequestLogWriter
 	stopChan        chan struct{}
 	wg              sync.WaitGroup
 	ticker          *time.Ticker
 }
 
 // NewRequestLogService creates a new RequestLogService instance
-func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager) *RequestLogService {
+func NewRequestLogService(db *gorm.DB, store store.Store, sm *config.SystemSettingsManager, dbHealth *db.HealthMonitor, logStream *LogStreamService, elector *cluster.Elector, writer *RequestLogWriter) *RequestLogService {
 	return &RequestLogService{
 		db:              db,
 		store:           store,
 		settingsManager: sm,
+		dbHealth:        dbHealth,
+		logStream:       logStream,
+		elector:         elector,
+		writer:          writer,
 		stopChan:        make(chan struct{}),
 	}
 }
 
 // Start initializes the service and starts the periodic flush routine
 func (s *RequestLogService) Start() {
+	// Logs cached while the database was down are written as soon as it is back
+	s.dbHealth.OnRecover(s.flushPending)
+
 	s.wg.Add(1)
 	go s.runLoop()
 }
@@ -74,7 +91,9 @@ func (s *RequestLogService) runLoop() {
 				interval = newInterval
 				logrus.Debugf("Request log write interval updated to: %v", interval)
 			}
-			s.flush()
+			if s.elector.IsLeader() {
+				s.flush()
+			}
 		case <-s.stopChan:
 			return
 		}
@@ -100,15 +119,28 @@ func (s *RequestLogService) Stop(ctx context.Context) {
 	}
 }
 
-// Record logs a request to the database and cache
+// Record logs a request. In sync mode the log is handed to the request log writer,
+// otherwise it is cached in the store until the next scheduled flush.
 func (s *RequestLogService) Record(log *models.RequestLog) error {
 	log.ID = uuid.NewString()
 	log.Timestamp = time.Now()
+	s.logStream.Publish(log)
 
-	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
-		return s.writeLogsToDB([]*models.RequestLog{log})
+	interval := s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes
+	if interval == 0 && !s.dbHealth.IsDegraded() {
+		s.writer.Enqueue(log)
+		return nil
 	}
 
+	ttl := time.Duration(interval*5) * time.Minute
+	if interval == 0 {
+		ttl = degradedLogTTL
+	}
+	return cacheRequestLog(s.store, log, ttl)
+}
+
+// cacheRequestLog caches a log in the store and adds it to the pending set.
+func cacheRequestLog(st store.Store, log *models.RequestLog, ttl time.Duration) error {
 	cacheKey := RequestLogCachePrefix + log.ID
 
 	logBytes, err := json.Marshal(log)
@@ -116,21 +148,52 @@ func (s *RequestLogService) Record(log *models.RequestLog) error {
 		return fmt.Errorf("failed to marshal request log: %w", err)
 	}
 
-	ttl := time.Duration(s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes*5) * time.Minute
-	if err := s.store.Set(cacheKey, logBytes, ttl); err != nil {
+	if err := st.Set(cacheKey, logBytes, ttl); err != nil {
 		return err
 	}
 
-	return s.store.SAdd(PendingLogKeysSet, cacheKey)
+	return st.SAdd(PendingLogKeysSet, cacheKey)
 }
 
-// flush data from cache to database
+// countCachedRequestLogs returns the number of logs cached in the store that match. Logs are
+// cached until the master has written them, so this includes the batch being written.
+func countCachedRequestLogs(st store.Store, match func(*models.RequestLog) bool) (int64, error) {
+	var count int64
+	for cacheKey, err := range st.Scan(RequestLogCachePrefix) {
+		if err != nil {
+			return count, err
+		}
+		logBytes, err := st.Get(cacheKey)
+		if err != nil {
+			if err == store.ErrNotFound {
+				continue
+			}
+			return count, err
+		}
+		var log models.RequestLog
+		if err := json.Unmarshal(logBytes, &log); err != nil {
+			continue
+		}
+		if match(&log) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// flush data from cache to database. In sync mode only logs spilled by the request log
+// writer are pending.
 func (s *RequestLogService) flush() {
-	if s.settingsManager.GetSettings().RequestLogWriteIntervalMinutes == 0 {
-		logrus.Debug("Sync mode enabled, skipping scheduled log flush.")
+	if s.dbHealth.IsDegraded() {
+		logrus.Debug("Database is degraded, postponing request log flush.")
 		return
 	}
 
+	s.flushPending()
+}
+
+// flushPending writes all cached logs to the database in batches.
+func (s *RequestLogService) flushPending() {
 	logrus.Debug("Master starting to flush request logs...")
 
 	for {
@@ -171,7 +234,7 @@ func (s *RequestLogService) flush() {
 			continue
 		}
 
-		err = s.writeLogsToDB(logs)
+		err = writeRequestLogs(s.db, logs)
 
 		if err != nil {
 			logrus.Errorf("Failed to flush request logs batch, will retry next time. Error: %v", err)
@@ -196,13 +259,13 @@ func (s *RequestLogService) flush() {
 	}
 }
 
-// writeLogsToDB writes a batch of request logs to the database
-func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
+// writeRequestLogs inserts request logs and updates the key usage and hourly statistics they count for.
+func writeRequestLogs(database *gorm.DB, logs []*models.RequestLog) error {
 	if len(logs) == 0 {
 		return nil
 	}
 
-	return s.db.Transaction(func(tx *gorm.DB) error {
+	return database.Transaction(func(tx *gorm.DB) error {
 		if err := tx.CreateInBatches(logs, len(logs)).Error; err != nil {
 			return fmt.Errorf("failed to batch insert request logs: %w", err)
 		}
@@ -266,42 +329,39 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 			}
 		}
 
-		// 更新统计表
-		hourlyStats := make(map[struct {
+		// 更新统计表，重试只计入 429 响应数
+		type hourlyKey struct {
 			Time    time.Time
 			GroupID uint
-		}]struct{ Success, Failure int64 })
-		for _, log := range logs {
-			if log.RequestType == models.RequestTypeRetry {
-				continue
+		}
+		type hourlyCounts struct{ Success, Failure, Overflow, DurationMs, RateLimited int64 }
+		hourlyStats := make(map[hourlyKey]hourlyCounts)
+		addToHour := func(groupID uint, log *models.RequestLog) {
+			if log.RequestType == models.RequestTypeRetry && log.StatusCode != http.StatusTooManyRequests {
+				return
 			}
-			hourlyTime := log.Timestamp.Truncate(time.Hour)
-			key := struct {
-				Time    time.Time
-				GroupID uint
-			}{Time: hourlyTime, GroupID: log.GroupID}
-
+			key := hourlyKey{Time: log.Timestamp.Truncate(time.Hour), GroupID: groupID}
 			counts := hourlyStats[key]
-			if log.IsSuccess {
-				counts.Success++
-			} else {
-				counts.Failure++
+			if log.StatusCode == http.StatusTooManyRequests {
+				counts.RateLimited++
 			}
-			hourlyStats[key] = counts
-
-			if log.ParentGroupID > 0 {
-				parentKey := struct {
-					Time    time.Time
-					GroupID uint
-				}{Time: hourlyTime, GroupID: log.ParentGroupID}
-
-				parentCounts := hourlyStats[parentKey]
+			if log.RequestType != models.RequestTypeRetry {
 				if log.IsSuccess {
-					parentCounts.Success++
+					counts.Success++
 				} else {
-					parentCounts.Failure++
+					counts.Failure++
 				}
-				hourlyStats[parentKey] = parentCounts
+				if log.IsOverflow {
+					counts.Overflow++
+				}
+				counts.DurationMs += log.Duration
+			}
+			hourlyStats[key] = counts
+		}
+		for _, log := range logs {
+			addToHour(log.GroupID, log)
+			if log.ParentGroupID > 0 {
+				addToHour(log.ParentGroupID, log)
 			}
 		}
 
@@ -310,15 +370,21 @@ func (s *RequestLogService) writeLogsToDB(logs []*models.RequestLog) error {
 				err := tx.Clauses(clause.OnConflict{
 					Columns: []clause.Column{{Name: "time"}, {Name: "group_id"}},
 					DoUpdates: clause.Assignments(map[string]any{
-						"success_count": gorm.Expr("group_hourly_stats.success_count + ?", counts.Success),
-						"failure_count": gorm.Expr("group_hourly_stats.failure_count + ?", counts.Failure),
-						"updated_at":    time.Now(),
+						"success_count":      gorm.Expr("group_hourly_stats.success_count + ?", counts.Success),
+						"failure_count":      gorm.Expr("group_hourly_stats.failure_count + ?", counts.Failure),
+						"overflow_count":     gorm.Expr("group_hourly_stats.overflow_count + ?", counts.Overflow),
+						"duration_ms":        gorm.Expr("group_hourly_stats.duration_ms + ?", counts.DurationMs),
+						"rate_limited_count": gorm.Expr("group_hourly_stats.rate_limited_count + ?", counts.RateLimited),
+						"updated_at":         time.Now(),
 					}),
 				}).Create(&models.GroupHourlyStat{
-					Time:         key.Time,
-					GroupID:      key.GroupID,
-					SuccessCount: counts.Success,
-					FailureCount: counts.Failure,
+					Time:             key.Time,
+					GroupID:          key.GroupID,
+					SuccessCount:     counts.Success,
+					FailureCount:     counts.Failure,
+					OverflowCount:    counts.Overflow,
+					DurationMs:       counts.DurationMs,
+					RateLimitedCount: counts.RateLimited,
 				}).Error
 
 				if err != nil {
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"context"
+	"gpt-load/internal/db"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// overflowWarnInterval limits how often dropped logs are reported in the log.
+const overflowWarnInterval = time.Minute
+
+// RequestLogWriterStats reports the buffer of the request log writer.
+type RequestLogWriterStats struct {
+	Capacity        int     `json:"capacity"`
+	Length          int     `json:"length"`
+	Saturation      float64 `json:"saturation"`
+	BatchSize       int     `json:"batch_size"`
+	FlushIntervalMs int     `json:"flush_interval_ms"`
+	Overflow        string  `json:"overflow"`
+	Enqueued        int64   `json:"enqueued"`
+	Written         int64   `json:"written"`
+	Batches         int64   `json:"batches"`
+	Failed          int64   `json:"failed"`
+	Spilled         int64   `json:"spilled"`
+	Dropped         int64   `json:"dropped"`
+}
+
+// RequestLogWriter persists the request logs recorded in sync mode. Logs are kept in an
+// in-memory ring buffer and inserted in batches every flush interval, or as soon as a batch
+// is full. When the buffer is full, new logs are dropped or spilled to the store, from which
+// the master writes them like logs recorded in async mode. Batches that cannot be written
+// are spilled as well.
+type RequestLogWriter struct {
+	db       *gorm.DB
+	store    store.Store
+	dbHealth *db.HealthMonitor
+	config   types.RequestLogWriterConfig
+
+	mu   sync.Mutex
+	ring []*models.RequestLog
+	head int
+	size int
+
+	flushNow chan struct{}
+
+	enqueued     atomic.Int64
+	written      atomic.Int64
+	batches      atomic.Int64
+	failed       atomic.Int64
+	spilled      atomic.Int64
+	dropped      atomic.Int64
+	lastWarnUnix atomic.Int64
+
+	stopChan chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewRequestLogWriter creates a new RequestLogWriter.
+func NewRequestLogWriter(db *gorm.DB, store store.Store, configManager types.ConfigManager, dbHealth *db.HealthMonitor) *RequestLogWriter {
+	config := configManager.GetRequestLogWriterConfig()
+	return &RequestLogWriter{
+		db:       db,
+		store:    store,
+		dbHealth: dbHealth,
+		config:   config,
+		ring:     make([]*models.RequestLog, config.BufferSize),
+		flushNow: make(chan struct{}, 1),
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start starts the flush loop.
+func (w *RequestLogWriter) Start() {
+	w.wg.Add(1)
+	go w.runLoop()
+}
+
+// Stop stops the flush loop after writing the buffered logs.
+func (w *RequestLogWriter) Stop(ctx context.Context) {
+	close(w.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("RequestLogWriter stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warnf("RequestLogWriter stop timed out, %d request logs were not written.", w.Stats().Length)
+	}
+}
+
+// Enqueue adds a log to the buffer.
+func (w *RequestLogWriter) Enqueue(log *models.RequestLog) {
+	w.mu.Lock()
+	if w.size == len(w.ring) {
+		w.mu.Unlock()
+		w.overflow(log)
+		return
+	}
+	w.ring[(w.head+w.size)%len(w.ring)] = log
+	w.size++
+	full := w.size >= w.config.BatchSize
+	w.mu.Unlock()
+
+	w.enqueued.Add(1)
+	if full {
+		select {
+		case w.flushNow <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Stats returns the buffer metrics of the writer.
+func (w *RequestLogWriter) Stats() RequestLogWriterStats {
+	w.mu.Lock()
+	length := w.size
+	w.mu.Unlock()
+
+	return RequestLogWriterStats{
+		Capacity:        len(w.ring),
+		Length:          length,
+		Saturation:      float64(length) / float64(len(w.ring)),
+		BatchSize:       w.config.BatchSize,
+		FlushIntervalMs: w.config.FlushIntervalMs,
+		Overflow:        w.config.Overflow,
+		Enqueued:        w.enqueued.Load(),
+		Written:         w.written.Load(),
+		Batches:         w.batches.Load(),
+		Failed:          w.failed.Load(),
+		Spilled:         w.spilled.Load(),
+		Dropped:         w.dropped.Load(),
+	}
+}
+
+// Count returns the number of buffered logs that match.
+func (w *RequestLogWriter) Count(match func(*models.RequestLog) bool) int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var count int64
+	for i := range w.size {
+		if match(w.ring[(w.head+i)%len(w.ring)]) {
+			count++
+		}
+	}
+	return count
+}
+
+func (w *RequestLogWriter) runLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(w.config.FlushIntervalMs) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.flushNow:
+			w.flush()
+		case <-w.stopChan:
+			w.flush()
+			return
+		}
+	}
+}
+
+// flush writes the buffered logs in batches.
+func (w *RequestLogWriter) flush() {
+	for {
+		batch := w.take(w.config.BatchSize)
+		if len(batch) == 0 {
+			return
+		}
+		w.write(batch)
+	}
+}
+
+// take removes and returns up to n logs from the buffer, oldest first.
+func (w *RequestLogWriter) take(n int) []*models.RequestLog {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	n = min(n, w.size)
+	batch := make([]*models.RequestLog, n)
+	for i := range n {
+		index := (w.head + i) % len(w.ring)
+		batch[i] = w.ring[index]
+		w.ring[index] = nil
+	}
+	w.head = (w.head + n) % len(w.ring)
+	w.size -= n
+	return batch
+}
+
+func (w *RequestLogWriter) write(batch []*models.RequestLog) {
+	if w.dbHealth.IsDegraded() {
+		w.spill(batch)
+		return
+	}
+
+	w.batches.Add(1)
+	if err := writeRequestLogs(w.db, batch); err != nil {
+		w.failed.Add(int64(len(batch)))
+		logrus.Errorf("Failed to write %d request logs, spilling them to the store: %v", len(batch), err)
+		w.spill(batch)
+		return
+	}
+	w.written.Add(int64(len(batch)))
+}
+
+func (w *RequestLogWriter) overflow(log *models.RequestLog) {
+	if w.config.Overflow == types.RequestLogOverflowSpill {
+		w.spill([]*models.RequestLog{log})
+		return
+	}
+	w.dropped.Add(1)
+	w.warnOverflow()
+}
+
+// spill caches logs in the store for the master to write them.
+func (w *RequestLogWriter) spill(logs []*models.RequestLog) {
+	for _, log := range logs {
+		if err := cacheRequestLog(w.store, log, degradedLogTTL); err != nil {
+			w.dropped.Add(1)
+			logrus.WithError(err).Error("Failed to spill request log to the store, dropping it")
+			continue
+		}
+		w.spilled.Add(1)
+	}
+}
+
+func (w *RequestLogWriter) warnOverflow() {
+	now := time.Now().Unix()
+	last := w.lastWarnUnix.Load()
+	if now-last < int64(overflowWarnInterval.Seconds()) || !w.lastWarnUnix.CompareAndSwap(last, now) {
+		return
+	}
+	logrus.WithFields(logrus.Fields{
+		"capacity": len(w.ring),
+		"dropped":  w.dropped.Load(),
+	}).Warn("Request log buffer is full, dropping logs. Consider increasing REQUEST_LOG_BUFFER_SIZE or setting REQUEST_LOG_OVERFLOW=spill.")
+}
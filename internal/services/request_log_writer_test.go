@@ -0,0 +1,72 @@
+package services
+
+import (
+	"strconv"
+	"testing"
+
+	"gpt-load/internal/db"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"gpt-load/internal/types"
+)
+
+func newTestLogWriter(overflow string) (*RequestLogWriter, store.Store) {
+	memStore := store.NewMemoryStore(0)
+	config := types.RequestLogWriterConfig{BufferSize: 3, BatchSize: 2, FlushIntervalMs: 1000, Overflow: overflow}
+	return &RequestLogWriter{
+		store:    memStore,
+		dbHealth: &db.HealthMonitor{},
+		config:   config,
+		ring:     make([]*models.RequestLog, config.BufferSize),
+		flushNow: make(chan struct{}, 1),
+	}, memStore
+}
+
+func TestRequestLogWriterRing(t *testing.T) {
+	w, _ := newTestLogWriter(types.RequestLogOverflowDrop)
+	for i := range 5 {
+		w.Enqueue(&models.RequestLog{ID: strconv.Itoa(i)})
+		if i == 2 {
+			// Wrap around the ring
+			if batch := w.take(2); len(batch) != 2 || batch[0].ID != "0" || batch[1].ID != "1" {
+				t.Fatalf("take(2) = %v", batch)
+			}
+		}
+	}
+
+	batch := w.take(10)
+	var ids []string
+	for _, log := range batch {
+		ids = append(ids, log.ID)
+	}
+	if len(ids) != 3 || ids[0] != "2" || ids[2] != "4" {
+		t.Errorf("take(10) = %v, want [2 3 4]", ids)
+	}
+
+	select {
+	case <-w.flushNow:
+	default:
+		t.Error("a full batch did not request a flush")
+	}
+}
+
+func TestRequestLogWriterOverflow(t *testing.T) {
+	for _, overflow := range []string{types.RequestLogOverflowDrop, types.RequestLogOverflowSpill} {
+		w, memStore := newTestLogWriter(overflow)
+		for i := range 5 {
+			w.Enqueue(&models.RequestLog{ID: strconv.Itoa(i)})
+		}
+
+		stats := w.Stats()
+		pending, _ := memStore.SPopN(PendingLogKeysSet, 10)
+		if overflow == types.RequestLogOverflowDrop && (stats.Dropped != 2 || len(pending) != 0) {
+			t.Errorf("drop: stats = %+v, pending = %v", stats, pending)
+		}
+		if overflow == types.RequestLogOverflowSpill && (stats.Spilled != 2 || stats.Dropped != 0 || len(pending) != 2) {
+			t.Errorf("spill: stats = %+v, pending = %v", stats, pending)
+		}
+		if stats.Length != 3 || stats.Enqueued != 3 {
+			t.Errorf("%s: stats = %+v, want 3 buffered logs", overflow, stats)
+		}
+	}
+}
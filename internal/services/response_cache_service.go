@@ -0,0 +1,202 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gpt-load/internal/features"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	responseCacheKeyPrefix        = "response_cache:"
+	responseCacheGenerationKey    = "response_cache_generation"
+	responseCacheStatsKeyPrefix   = "response_cache_stats:"
+	responseCacheStatsFieldHits   = "hits"
+	responseCacheStatsFieldMisses = "misses"
+)
+
+// ResponseCacheMaxBodySize is the largest response body that will be cached.
+const ResponseCacheMaxBodySize = 1 << 20
+
+// CachedResponse is a successful upstream response stored for replay.
+type CachedResponse struct {
+	StatusCode int               `json:"status_code"`
+	Header     map[string]string `json:"header"`
+	Body       []byte            `json:"body"`
+}
+
+// ResponseCacheStats reports the cache effectiveness of a group.
+type ResponseCacheStats struct {
+	TTLSeconds int     `json:"ttl_seconds"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	HitRate    float64 `json:"hit_rate"`
+}
+
+// ResponseCacheService caches non-streaming responses of identical requests per group.
+// Purging bumps a per-group generation instead of scanning keys, old entries simply expire.
+type ResponseCacheService struct {
+	store store.Store
+}
+
+// NewResponseCacheService creates a new ResponseCacheService.
+func NewResponseCacheService(store store.Store) *ResponseCacheService {
+	return &ResponseCacheService{store: store}
+}
+
+// Enabled reports whether response caching is enabled for the group.
+func (s *ResponseCacheService) Enabled(group *models.Group) bool {
+	return group.EffectiveConfig.ResponseCacheTTL > 0 && group.FeatureEnabled(features.ResponseCache)
+}
+
+// BuildKey returns the cache key for a request. The body is normalized so that
+// key order and whitespace differences do not produce different keys.
+func (s *ResponseCacheService) BuildKey(group *models.Group, method, path, query string, bodyBytes []byte) (string, error) {
+	generation, err := s.generation(group.ID)
+	if err != nil {
+		return "", err
+	}
+
+	normalized := bodyBytes
+	var body any
+	if err := json.Unmarshal(bodyBytes, &body); err == nil {
+		if encoded, err := json.Marshal(body); err == nil {
+			normalized = encoded
+		}
+	}
+
+	h := sha256.New()
+	h.Write([]byte(method + "\n" + path + "\n" + query + "\n"))
+	h.Write(normalized)
+
+	return fmt.Sprintf("%s%d:%d:%s", responseCacheKeyPrefix, group.ID, generation, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// Get returns the cached response for the key and records a hit or miss.
+func (s *ResponseCacheService) Get(group *models.Group, key string) (*CachedResponse, bool) {
+	data, err := s.store.Get(key)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to read response cache")
+		}
+		s.recordStat(group.ID, responseCacheStatsFieldMisses)
+		return nil, false
+	}
+
+	var cached CachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to decode cached response")
+		s.recordStat(group.ID, responseCacheStatsFieldMisses)
+		return nil, false
+	}
+
+	s.recordStat(group.ID, responseCacheStatsFieldHits)
+	return &cached, true
+}
+
+// Set stores a response under the key using the group's TTL. Oversized bodies are skipped.
+func (s *ResponseCacheService) Set(group *models.Group, key string, cached *CachedResponse) {
+	if len(cached.Body) == 0 || len(cached.Body) > ResponseCacheMaxBodySize {
+		return
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to encode response for cache")
+		return
+	}
+
+	ttl := time.Duration(group.EffectiveConfig.ResponseCacheTTL) * time.Second
+	if err := s.store.Set(key, data, ttl); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": group.ID, "error": err}).Warn("Failed to write response cache")
+	}
+}
+
+// Purge invalidates all cached responses of a group.
+func (s *ResponseCacheService) Purge(groupID uint) error {
+	if _, err := s.store.HIncrBy(responseCacheGenerationKey, strconv.FormatUint(uint64(groupID), 10), 1); err != nil {
+		return fmt.Errorf("failed to purge response cache: %w", err)
+	}
+	return nil
+}
+
+// Flush removes the cached responses and hit metrics of a group, or of all groups when groupID is 0.
+// Entries of a single group cannot be told apart by key, they are purged and left to expire.
+func (s *ResponseCacheService) Flush(groupID uint) (int, error) {
+	if groupID != 0 {
+		if err := s.Purge(groupID); err != nil {
+			return 0, err
+		}
+		if err := s.store.Delete(responseCacheStatsKeyPrefix + strconv.FormatUint(uint64(groupID), 10)); err != nil {
+			return 0, fmt.Errorf("failed to remove response cache stats: %w", err)
+		}
+		return 1, nil
+	}
+
+	entries, err := s.store.Keys(responseCacheKeyPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list response cache entries: %w", err)
+	}
+	stats, err := s.store.Keys(responseCacheStatsKeyPrefix)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list response cache stats: %w", err)
+	}
+	keys := append(entries, stats...)
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	if err := s.store.Del(keys...); err != nil {
+		return 0, fmt.Errorf("failed to remove response cache entries: %w", err)
+	}
+	return len(keys), nil
+}
+
+// GetStats returns the cache hit metrics of a group, or nil if caching is disabled.
+func (s *ResponseCacheService) GetStats(group *models.Group) (*ResponseCacheStats, error) {
+	if !s.Enabled(group) {
+		return nil, nil
+	}
+
+	values, err := s.store.HGetAll(responseCacheStatsKeyPrefix + strconv.FormatUint(uint64(group.ID), 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get response cache stats: %w", err)
+	}
+
+	hits, _ := strconv.ParseInt(values[responseCacheStatsFieldHits], 10, 64)
+	misses, _ := strconv.ParseInt(values[responseCacheStatsFieldMisses], 10, 64)
+
+	stats := &ResponseCacheStats{
+		TTLSeconds: group.EffectiveConfig.ResponseCacheTTL,
+		Hits:       hits,
+		Misses:     misses,
+	}
+	if total := hits + misses; total > 0 {
+		stats.HitRate = math.Round(float64(hits)/float64(total)*10000) / 10000
+	}
+	return stats, nil
+}
+
+func (s *ResponseCacheService) generation(groupID uint) (int64, error) {
+	values, err := s.store.HGetAll(responseCacheGenerationKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get response cache generation: %w", err)
+	}
+	generation, _ := strconv.ParseInt(values[strconv.FormatUint(uint64(groupID), 10)], 10, 64)
+	return generation, nil
+}
+
+func (s *ResponseCacheService) recordStat(groupID uint, field string) {
+	key := responseCacheStatsKeyPrefix + strconv.FormatUint(uint64(groupID), 10)
+	if _, err := s.store.HIncrBy(key, field, 1); err != nil {
+		logrus.WithFields(logrus.Fields{"groupID": groupID, "error": err}).Debug("Failed to record response cache stat")
+	}
+}
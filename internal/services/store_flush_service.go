@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Store flush scopes
+const (
+	FlushScopeResponseCache = "response_cache"
+	FlushScopeBudgets       = "budgets"
+	FlushScopeCooldowns     = "cooldowns"
+	FlushScopeDebugCaptures = "debug_captures"
+	// FlushScopeGroup flushes every subsystem above for a single group.
+	FlushScopeGroup = "group"
+)
+
+var (
+	// ErrInvalidFlushScope is returned for an unknown flush scope.
+	ErrInvalidFlushScope = errors.New("invalid flush scope")
+	// ErrFlushScopeNeedsGroup is returned when the group scope is used without a group.
+	ErrFlushScopeNeedsGroup = errors.New("the group flush scope requires a group_id")
+)
+
+// StoreFlushReport counts what a scoped flush removed or reset, per subsystem.
+type StoreFlushReport struct {
+	Scope   string         `json:"scope"`
+	GroupID uint           `json:"group_id,omitempty"`
+	Flushed map[string]int `json:"flushed"`
+}
+
+// StoreFlushService clears the store data of one subsystem or one group, leaving key pools,
+// pending request logs and other groups untouched.
+type StoreFlushService struct {
+	store                store.Store
+	keyProvider          *keypool.KeyProvider
+	responseCacheService *ResponseCacheService
+	budgetService        *BudgetService
+	debugCaptureService  *DebugCaptureService
+}
+
+// NewStoreFlushService creates a new StoreFlushService.
+func NewStoreFlushService(
+	store store.Store,
+	keyProvider *keypool.KeyProvider,
+	responseCacheService *ResponseCacheService,
+	budgetService *BudgetService,
+	debugCaptureService *DebugCaptureService,
+) *StoreFlushService {
+	return &StoreFlushService{
+		store:                store,
+		keyProvider:          keyProvider,
+		responseCacheService: responseCacheService,
+		budgetService:        budgetService,
+		debugCaptureService:  debugCaptureService,
+	}
+}
+
+// Flush clears a subsystem for one group, or for all groups when groupID is 0.
+// Budgets are recounted from the hourly stats instead of being dropped, so that
+// nodes that already seeded a counter keep counting from the right value.
+func (s *StoreFlushService) Flush(scope string, groupID uint) (*StoreFlushReport, error) {
+	var scopes []string
+	switch scope {
+	case FlushScopeResponseCache, FlushScopeBudgets, FlushScopeCooldowns, FlushScopeDebugCaptures:
+		scopes = []string{scope}
+	case FlushScopeGroup:
+		if groupID == 0 {
+			return nil, ErrFlushScopeNeedsGroup
+		}
+		scopes = []string{FlushScopeResponseCache, FlushScopeBudgets, FlushScopeCooldowns, FlushScopeDebugCaptures}
+	default:
+		return nil, ErrInvalidFlushScope
+	}
+
+	report := &StoreFlushReport{Scope: scope, GroupID: groupID, Flushed: make(map[string]int, len(scopes))}
+	for _, sub := range scopes {
+		var count int
+		var err error
+		switch sub {
+		case FlushScopeResponseCache:
+			count, err = s.responseCacheService.Flush(groupID)
+		case FlushScopeBudgets:
+			count, err = s.budgetService.Recount(groupID)
+		case FlushScopeCooldowns:
+			count, err = s.keyProvider.FlushCooldowns(groupID)
+		case FlushScopeDebugCaptures:
+			count, err = s.debugCaptureService.Flush(groupID)
+		}
+		report.Flushed[sub] = count
+		if err != nil {
+			return report, fmt.Errorf("failed to flush %s: %w", sub, err)
+		}
+	}
+
+	// 分组的配额重置记录，下次检查时重新记录
+	if scope == FlushScopeGroup {
+		if err := s.store.Delete(fmt.Sprintf("quota_reset:%d", groupID)); err != nil {
+			return report, fmt.Errorf("failed to flush quota reset record: %w", err)
+		}
+	}
+
+	logrus.WithFields(logrus.Fields{"scope": scope, "groupID": groupID, "flushed": report.Flushed}).Info("Flushed store data")
+	return report, nil
+}
@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/db"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/store"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const storeHygieneInterval = 6 * time.Hour
+
+// ErrStoreHygieneRunning is returned when a hygiene run is requested while another one is in progress.
+var ErrStoreHygieneRunning = errors.New("store hygiene is already running")
+
+// StoreHygieneReport summarizes what a hygiene run removed from the store.
+type StoreHygieneReport struct {
+	ScannedKeys    int            `json:"scanned_keys"`
+	RemovedKeys    int            `json:"removed_keys"`
+	RemovedEntries int            `json:"removed_entries"`
+	ReclaimedBytes int64          `json:"reclaimed_bytes"`
+	Categories     map[string]int `json:"categories"`
+	DurationMs     int64          `json:"duration_ms"`
+}
+
+// StoreHygieneService removes store structures that are no longer backed by database rows,
+// such as key hashes and active lists left behind by bulk deletes and group removals.
+type StoreHygieneService struct {
+	db       *gorm.DB
+	store    store.Store
+	dbHealth *db.HealthMonitor
+	elector  *cluster.Elector
+	running  sync.Mutex
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// NewStoreHygieneService creates a new StoreHygieneService.
+func NewStoreHygieneService(db *gorm.DB, store store.Store, dbHealth *db.HealthMonitor, elector *cluster.Elector) *StoreHygieneService {
+	return &StoreHygieneService{
+		db:       db,
+		store:    store,
+		dbHealth: dbHealth,
+		elector:  elector,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// Start 启动定期存储清理
+func (s *StoreHygieneService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Store hygiene service started")
+}
+
+// Stop 停止存储清理服务
+func (s *StoreHygieneService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("StoreHygieneService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("StoreHygieneService stop timed out.")
+	}
+}
+
+func (s *StoreHygieneService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(storeHygieneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !s.elector.IsLeader() {
+				continue
+			}
+			if _, err := s.Run(); err != nil {
+				logrus.WithError(err).Warn("Store hygiene run failed")
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// Run scans the store once and removes orphaned structures.
+//
+// The store is listed before the database is read, so a structure created while the run
+// is in progress is backed by a row the run can see. Keys are written to the store before
+// their insert commits, so key IDs above the highest committed ID are never treated as orphans.
+func (s *StoreHygieneService) Run() (*StoreHygieneReport, error) {
+	if !s.running.TryLock() {
+		return nil, ErrStoreHygieneRunning
+	}
+	defer s.running.Unlock()
+
+	if s.dbHealth.IsDegraded() {
+		return nil, fmt.Errorf("database is degraded, store hygiene skipped")
+	}
+
+	start := time.Now()
+	report := &StoreHygieneReport{Categories: make(map[string]int)}
+
+	keyHashes, err := s.store.Keys("key:")
+	if err != nil {
+		return nil, err
+	}
+	groupKeys, err := s.store.Keys("group:")
+	if err != nil {
+		return nil, err
+	}
+	cacheStats, err := s.store.Keys(responseCacheStatsKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	quotaResets, err := s.store.Keys("quota_reset:")
+	if err != nil {
+		return nil, err
+	}
+	budgetUsages, err := s.store.Keys(BudgetUsageKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	coolingMembers, err := s.store.ZRangeByScore(keypool.CoolingKeysSet, math.Inf(-1), math.Inf(1))
+	if err != nil {
+		return nil, err
+	}
+	report.ScannedKeys = len(keyHashes) + len(groupKeys) + len(cacheStats) + len(quotaResets) + len(budgetUsages)
+
+	keys, groupIDs, err := s.loadRows()
+	if err != nil {
+		return nil, err
+	}
+
+	// 不存在于数据库中的 Key 哈希
+	for _, key := range keyHashes {
+		if id, ok := parseStoreID(strings.TrimPrefix(key, "key:")); ok {
+			if keys.orphaned(id) {
+				s.remove(report, "key_hashes", key)
+			}
+		}
+	}
+
+	// 已删除分组的活跃列表和备选层，以及列表中已删除或不属于该分组的 Key
+	for _, key := range groupKeys {
+		if groupIDStr, ok := strings.CutSuffix(strings.TrimPrefix(key, "group:"), ":fallback_keys"); ok {
+			if groupID, ok := parseStoreID(groupIDStr); ok {
+				if _, exists := groupIDs[groupID]; !exists {
+					s.remove(report, "fallback_layers", key)
+				}
+			}
+			continue
+		}
+		groupIDStr, ok := strings.CutSuffix(strings.TrimPrefix(key, "group:"), ":active_keys")
+		if !ok {
+			continue
+		}
+		groupID, ok := parseStoreID(groupIDStr)
+		if !ok {
+			continue
+		}
+		if _, exists := groupIDs[groupID]; !exists {
+			s.remove(report, "active_key_lists", key)
+			continue
+		}
+		s.compactActiveList(report, key, groupID, keys)
+	}
+
+	// 已删除分组的统计和调度记录
+	for _, key := range append(cacheStats, quotaResets...) {
+		idStr := strings.TrimPrefix(strings.TrimPrefix(key, responseCacheStatsKeyPrefix), "quota_reset:")
+		if groupID, ok := parseStoreID(idStr); ok {
+			if _, exists := groupIDs[groupID]; !exists {
+				s.remove(report, "group_records", key)
+			}
+		}
+	}
+
+	// 往月的预算用量，需要时会从数据库重新统计
+	currentPeriod := BudgetUsageKeyPrefix + time.Now().Format(budgetPeriodLayout)
+	for _, key := range budgetUsages {
+		if key < currentPeriod {
+			s.remove(report, "budget_usage", key)
+		}
+	}
+
+	// 冷却集合中已删除的 Key
+	for _, member := range coolingMembers {
+		_, keyIDStr, _ := strings.Cut(member, ":")
+		if id, ok := parseStoreID(keyIDStr); ok && !keys.orphaned(id) {
+			continue
+		}
+		if err := s.store.ZRem(keypool.CoolingKeysSet, member); err != nil {
+			logrus.WithError(err).Warnf("Failed to remove cooling entry %s", member)
+			continue
+		}
+		report.RemovedEntries++
+		report.Categories["cooling_entries"]++
+	}
+
+	report.DurationMs = time.Since(start).Milliseconds()
+	if report.RemovedKeys > 0 || report.RemovedEntries > 0 {
+		logrus.WithFields(logrus.Fields{
+			"scanned_keys":    report.ScannedKeys,
+			"removed_keys":    report.RemovedKeys,
+			"removed_entries": report.RemovedEntries,
+			"reclaimed_bytes": report.ReclaimedBytes,
+		}).Info("Store hygiene removed orphaned data")
+	} else {
+		logrus.Debug("Store hygiene found no orphaned data")
+	}
+	return report, nil
+}
+
+// hygieneKeyRows holds the group of every key in the database.
+type hygieneKeyRows struct {
+	groups map[uint]uint
+	maxID  uint
+}
+
+func (r hygieneKeyRows) orphaned(id uint) bool {
+	_, exists := r.groups[id]
+	return !exists && id <= r.maxID
+}
+
+// loadRows returns the keys and the set of group IDs in the database.
+func (s *StoreHygieneService) loadRows() (hygieneKeyRows, map[uint]struct{}, error) {
+	var keys []models.APIKey
+	if err := s.db.Select("id", "group_id").Find(&keys).Error; err != nil {
+		return hygieneKeyRows{}, nil, fmt.Errorf("failed to load keys: %w", err)
+	}
+	rows := hygieneKeyRows{groups: make(map[uint]uint, len(keys))}
+	for _, key := range keys {
+		rows.groups[key.ID] = key.GroupID
+		rows.maxID = max(rows.maxID, key.ID)
+	}
+
+	var ids []uint
+	if err := s.db.Model(&models.Group{}).Pluck("id", &ids).Error; err != nil {
+		return hygieneKeyRows{}, nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	groupIDs := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		groupIDs[id] = struct{}{}
+	}
+
+	return rows, groupIDs, nil
+}
+
+// compactActiveList removes the entries of keys that were deleted or moved to another group.
+func (s *StoreHygieneService) compactActiveList(report *StoreHygieneReport, listKey string, groupID uint, keys hygieneKeyRows) {
+	members, err := s.store.LRange(listKey, 0, -1)
+	if err != nil {
+		logrus.WithError(err).Warnf("Failed to read active list %s", listKey)
+		return
+	}
+
+	seen := make(map[string]struct{}, len(members))
+	for _, member := range members {
+		if _, ok := seen[member]; ok {
+			continue
+		}
+		seen[member] = struct{}{}
+
+		if id, ok := parseStoreID(member); ok {
+			owner, exists := keys.groups[id]
+			if owner == groupID || (!exists && !keys.orphaned(id)) {
+				continue
+			}
+		}
+		if err := s.store.LRem(listKey, 0, member); err != nil {
+			logrus.WithError(err).Warnf("Failed to remove %s from active list %s", member, listKey)
+			continue
+		}
+		report.RemovedEntries++
+		report.Categories["active_list_entries"]++
+	}
+}
+
+func (s *StoreHygieneService) remove(report *StoreHygieneReport, category, key string) {
+	var size int64
+	if sizer, ok := s.store.(store.KeySizer); ok {
+		size, _ = sizer.KeySize(key)
+	}
+	if err := s.store.Delete(key); err != nil {
+		logrus.WithError(err).Warnf("Failed to remove orphaned store key %s", key)
+		return
+	}
+	report.RemovedKeys++
+	report.ReclaimedBytes += size
+	report.Categories[category]++
+}
+
+func parseStoreID(value string) (uint, bool) {
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil || id == 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
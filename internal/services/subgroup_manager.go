@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"gpt-load/internal/balancing"
 	"gpt-load/internal/models"
 	"gpt-load/internal/store"
+	"gpt-load/internal/upstreamstats"
 	"sync"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -12,23 +16,105 @@ import (
 // SubGroupManager manages weighted round-robin selection for all aggregate groups
 type SubGroupManager struct {
 	store     store.Store
+	stats     *upstreamstats.Tracker
 	selectors map[uint]*selector
 	mu        sync.RWMutex
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
 }
 
 // subGroupItem represents a sub-group with its weight and current weight for round-robin
 type subGroupItem struct {
-	name          string
-	subGroupID    uint
+	name       string
+	subGroupID uint
+	// staticWeight is the configured weight, weight the one used for selection, which
+	// capacity balancing derives from the live capacity of the sub-group
+	staticWeight  int
 	weight        int
 	currentWeight int
 }
 
 // NewSubGroupManager creates a new sub-group manager service
-func NewSubGroupManager(store store.Store) *SubGroupManager {
+func NewSubGroupManager(store store.Store, stats *upstreamstats.Tracker) *SubGroupManager {
 	return &SubGroupManager{
 		store:     store,
+		stats:     stats,
 		selectors: make(map[uint]*selector),
+		stopChan:  make(chan struct{}),
+	}
+}
+
+// Start re-evaluates the weights of the aggregate groups with capacity balancing as they
+// become due.
+func (m *SubGroupManager) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(balancing.MinInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.rebalance()
+			case <-m.stopChan:
+				return
+			}
+		}
+	}()
+	logrus.Debug("Sub-group capacity balancing started.")
+}
+
+// Stop stops the re-evaluation of the weights.
+func (m *SubGroupManager) Stop(ctx context.Context) {
+	close(m.stopChan)
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("Sub-group capacity balancing stopped.")
+	case <-ctx.Done():
+		logrus.Warn("Sub-group capacity balancing stop timed out.")
+	}
+}
+
+// EffectiveWeights returns the weights used for the sub-groups of an aggregate group with
+// capacity balancing by sub-group ID, nil for other groups.
+func (m *SubGroupManager) EffectiveWeights(groupID uint) map[uint]int {
+	m.mu.RLock()
+	sel, ok := m.selectors[groupID]
+	m.mu.RUnlock()
+	if !ok || sel.balancing == nil {
+		return nil
+	}
+
+	sel.mu.Lock()
+	defer sel.mu.Unlock()
+	weights := make(map[uint]int, len(sel.subGroups))
+	for _, item := range sel.subGroups {
+		weights[item.subGroupID] = item.weight
+	}
+	return weights
+}
+
+// rebalance re-evaluates the selectors due for it.
+func (m *SubGroupManager) rebalance() {
+	m.mu.RLock()
+	due := make([]*selector, 0, len(m.selectors))
+	for _, sel := range m.selectors {
+		if sel.balancing != nil && time.Since(sel.evaluatedAt) >= sel.balancing.Interval() {
+			due = append(due, sel)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, sel := range due {
+		sel.rebalance(m.stats)
 	}
 }
 
@@ -115,6 +201,7 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 		items = append(items, subGroupItem{
 			name:          sg.SubGroupName,
 			subGroupID:    sg.SubGroupID,
+			staticWeight:  sg.Weight,
 			weight:        sg.Weight,
 			currentWeight: 0,
 		})
@@ -129,6 +216,7 @@ func (m *SubGroupManager) createSelector(group *models.Group) *selector {
 		groupName: group.Name,
 		subGroups: items,
 		store:     m.store,
+		balancing: group.CapacityBalancing,
 	}
 }
 
@@ -139,6 +227,52 @@ type selector struct {
 	subGroups []subGroupItem
 	store     store.Store
 	mu        sync.Mutex
+
+	// balancing is set for an aggregate group with capacity balancing, evaluatedAt is the
+	// time of the last evaluation of its weights
+	balancing   *balancing.Config
+	evaluatedAt time.Time
+}
+
+// rebalance derives the weights of the sub-groups from their active keys and, over the
+// window of the config, the 429 rate and latency seen by this instance.
+func (s *selector) rebalance(stats *upstreamstats.Tracker) {
+	s.mu.Lock()
+	items := make([]subGroupItem, len(s.subGroups))
+	copy(items, s.subGroups)
+	s.mu.Unlock()
+
+	staticWeights := make([]int, len(items))
+	signals := make([]balancing.Signals, len(items))
+	for i, item := range items {
+		staticWeights[i] = item.staticWeight
+		activeKeys, err := s.store.LLen(fmt.Sprintf("group:%d:active_keys", item.subGroupID))
+		if err != nil {
+			logrus.WithError(err).WithField("group_name", item.name).Debug("Failed to count active keys for capacity balancing")
+			return
+		}
+		load := stats.Load(item.name, s.balancing.Window())
+		signals[i] = balancing.Signals{
+			ActiveKeys:   activeKeys,
+			Requests:     load.Requests,
+			RateLimited:  load.RateLimited,
+			AvgLatencyMs: load.AvgLatencyMs,
+		}
+	}
+	weights := s.balancing.Weights(staticWeights, signals)
+
+	s.mu.Lock()
+	for i := range s.subGroups {
+		s.subGroups[i].weight = weights[i]
+	}
+	s.evaluatedAt = time.Now()
+	s.mu.Unlock()
+
+	fields := logrus.Fields{"aggregate_group": s.groupName}
+	for i, item := range items {
+		fields[item.name] = weights[i]
+	}
+	logrus.WithFields(fields).Debug("Re-evaluated sub-group weights by capacity")
 }
 
 // selectNext uses weighted round-robin algorithm to select a sub-group with active keys
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/config"
+	app_errors "gpt-load/internal/errors"
+	"gpt-load/internal/keypool"
+	"gpt-load/internal/models"
+	"gpt-load/internal/syncer"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const trashPurgeInterval = time.Hour
+
+// TrashedGroup is a deleted group waiting in the trash.
+type TrashedGroup struct {
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	DisplayName string     `json:"display_name"`
+	GroupType   string     `json:"group_type"`
+	KeyCount    int64      `json:"key_count"`
+	DeletedAt   time.Time  `json:"deleted_at"`
+	PurgeAt     *time.Time `json:"purge_at"`
+}
+
+// TrashedKey is a deleted key waiting in the trash.
+type TrashedKey struct {
+	models.APIKey
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at"`
+}
+
+// TrashService keeps deleted groups and keys for trash_retention_days so that they can be
+// restored, and purges them afterwards. The purge runs on the leading master only.
+type TrashService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	groupManager    *GroupManager
+	keyProvider     *keypool.KeyProvider
+	elector         *cluster.Elector
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewTrashService creates a new TrashService.
+func NewTrashService(db *gorm.DB, settingsManager *config.SystemSettingsManager, groupManager *GroupManager, keyProvider *keypool.KeyProvider, elector *cluster.Elector) *TrashService {
+	return &TrashService{
+		db:              db,
+		settingsManager: settingsManager,
+		groupManager:    groupManager,
+		keyProvider:     keyProvider,
+		elector:         elector,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins purging the trash.
+func (s *TrashService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Trash service started")
+}
+
+// Stop stops the purge, respecting the context for shutdown timeout.
+func (s *TrashService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("TrashService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("TrashService stop timed out.")
+	}
+}
+
+func (s *TrashService) run() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	s.purgeExpired()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.purgeExpired()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// purgeExpired permanently deletes the groups and keys kept in the trash for longer than
+// the retention window.
+func (s *TrashService) purgeExpired() {
+	if !s.elector.IsLeader() {
+		return
+	}
+	retentionDays := s.settingsManager.GetSettings().TrashRetentionDays
+	if retentionDays <= 0 {
+		return
+	}
+	cutoff := time.Now().AddDate(0, 0, -retentionDays)
+
+	expiredGroups := s.db.Unscoped().Model(&models.Group{}).Select("id").Where("deleted_at < ?", cutoff)
+	keys := s.db.Unscoped().Where("deleted_at < ? OR group_id IN (?)", cutoff, expiredGroups).Delete(&models.APIKey{})
+	if keys.Error != nil {
+		logrus.WithError(keys.Error).Error("Failed to purge expired keys from the trash")
+		return
+	}
+	groups := s.db.Unscoped().Where("deleted_at < ?", cutoff).Delete(&models.Group{})
+	if groups.Error != nil {
+		logrus.WithError(groups.Error).Error("Failed to purge expired groups from the trash")
+		return
+	}
+
+	if keys.RowsAffected > 0 || groups.RowsAffected > 0 {
+		logrus.WithFields(logrus.Fields{
+			"keys":           keys.RowsAffected,
+			"groups":         groups.RowsAffected,
+			"retention_days": retentionDays,
+		}).Info("Purged expired items from the trash")
+	}
+}
+
+// purgeAt returns when an item deleted at deletedAt is purged, nil if it is kept until purged explicitly.
+func (s *TrashService) purgeAt(deletedAt time.Time) *time.Time {
+	retentionDays := s.settingsManager.GetSettings().TrashRetentionDays
+	if retentionDays <= 0 {
+		return nil
+	}
+	at := deletedAt.AddDate(0, 0, retentionDays)
+	return &at
+}
+
+// ListGroups lists the groups in the trash, most recently deleted first.
+func (s *TrashService) ListGroups(ctx context.Context) ([]TrashedGroup, error) {
+	var groups []models.Group
+	if err := s.db.WithContext(ctx).Unscoped().
+		Select("id", "name", "display_name", "group_type", "deleted_at").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Find(&groups).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	var counts []struct {
+		GroupID uint
+		Count   int64
+	}
+	if err := s.db.WithContext(ctx).Unscoped().Model(&models.APIKey{}).
+		Select("group_id, COUNT(*) as count").
+		Where("deleted_at IS NOT NULL AND group_id IN (?)", s.db.Unscoped().Model(&models.Group{}).Select("id").Where("deleted_at IS NOT NULL")).
+		Group("group_id").
+		Scan(&counts).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	keyCounts := make(map[uint]int64, len(counts))
+	for _, c := range counts {
+		keyCounts[c.GroupID] = c.Count
+	}
+
+	trashed := make([]TrashedGroup, 0, len(groups))
+	for _, group := range groups {
+		trashed = append(trashed, TrashedGroup{
+			ID:          group.ID,
+			Name:        group.Name,
+			DisplayName: group.DisplayName,
+			GroupType:   group.GroupType,
+			KeyCount:    keyCounts[group.ID],
+			DeletedAt:   group.DeletedAt.Time,
+			PurgeAt:     s.purgeAt(group.DeletedAt.Time),
+		})
+	}
+	return trashed, nil
+}
+
+// KeysQuery returns the query for the trashed keys of a group, most recently deleted first.
+func (s *TrashService) KeysQuery(groupID uint) *gorm.DB {
+	return s.db.Unscoped().Model(&models.APIKey{}).
+		Where("group_id = ? AND deleted_at IS NOT NULL", groupID).
+		Order("deleted_at DESC, id")
+}
+
+// TrashedKeys adds the deletion and purge times to trashed keys.
+func (s *TrashService) TrashedKeys(keys []models.APIKey) []TrashedKey {
+	trashed := make([]TrashedKey, 0, len(keys))
+	for _, key := range keys {
+		trashed = append(trashed, TrashedKey{
+			APIKey:    key,
+			DeletedAt: key.DeletedAt.Time,
+			PurgeAt:   s.purgeAt(key.DeletedAt.Time),
+		})
+	}
+	return trashed
+}
+
+// RestoreGroup takes a group out of the trash together with the keys deleted with it.
+// The sub-groups of an aggregate group are not restored.
+func (s *TrashService) RestoreGroup(ctx context.Context, id uint) (*keypool.TrashRestoreResult, error) {
+	group, err := s.findTrashedGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Unscoped().Model(group).Update("deleted_at", nil).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+
+	result, err := s.keyProvider.RestoreTrashedKeys(id, nil, group.DeletedAt.Time)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.groupManager.InvalidateFor(syncer.EventGroupCreated, id); err != nil {
+		logrus.WithContext(ctx).WithError(err).Error("failed to invalidate group cache")
+	}
+	return result, nil
+}
+
+// RestoreKeys takes keys of a group out of the trash.
+func (s *TrashService) RestoreKeys(ctx context.Context, groupID uint, keyIDs []uint) (*keypool.TrashRestoreResult, error) {
+	if len(keyIDs) == 0 {
+		return &keypool.TrashRestoreResult{}, nil
+	}
+	var group models.Group
+	if err := s.db.WithContext(ctx).Select("id").First(&group, groupID).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return s.keyProvider.RestoreTrashedKeys(groupID, keyIDs, time.Time{})
+}
+
+// PurgeGroup permanently deletes a group in the trash and its keys.
+func (s *TrashService) PurgeGroup(ctx context.Context, id uint) error {
+	if _, err := s.findTrashedGroup(ctx, id); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("group_id = ?", id).Delete(&models.APIKey{}).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		if err := tx.Unscoped().Delete(&models.Group{}, id).Error; err != nil {
+			return app_errors.ParseDBError(err)
+		}
+		return nil
+	})
+}
+
+// PurgeKeys permanently deletes keys of a group that are in the trash.
+func (s *TrashService) PurgeKeys(ctx context.Context, groupID uint, keyIDs []uint) (int64, error) {
+	if len(keyIDs) == 0 {
+		return 0, nil
+	}
+	result := s.db.WithContext(ctx).Unscoped().
+		Where("group_id = ? AND id IN ? AND deleted_at IS NOT NULL", groupID, keyIDs).
+		Delete(&models.APIKey{})
+	if result.Error != nil {
+		return 0, app_errors.ParseDBError(result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *TrashService) findTrashedGroup(ctx context.Context, id uint) (*models.Group, error) {
+	var group models.Group
+	if err := s.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&group, id).Error; err != nil {
+		return nil, app_errors.ParseDBError(err)
+	}
+	return &group, nil
+}
@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gpt-load/internal/cluster"
+	"gpt-load/internal/config"
+	"gpt-load/internal/models"
+	"gpt-load/internal/utils"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	usageSnapshotCheckInterval = time.Minute
+	usageSnapshotBatchSize     = 1000
+)
+
+// ErrUsageSnapshotNotFound is returned when no snapshot matches a lookup.
+var ErrUsageSnapshotNotFound = errors.New("usage snapshot not found")
+
+// UsageSnapshotView is a snapshot together with its counters.
+type UsageSnapshotView struct {
+	models.UsageSnapshot
+	Entries []models.UsageSnapshotEntry `json:"entries"`
+}
+
+// UsageSnapshotService records the cumulative counters of every group and key into
+// append-only snapshot tables on the usage_snapshot_schedule, so that reports for a period
+// can be computed from two snapshots regardless of later log pruning or key deletion.
+// The schedule runs on the leading master only.
+type UsageSnapshotService struct {
+	db              *gorm.DB
+	settingsManager *config.SystemSettingsManager
+	elector         *cluster.Elector
+	mu              sync.Mutex
+	stopCh          chan struct{}
+	wg              sync.WaitGroup
+}
+
+// NewUsageSnapshotService creates a new UsageSnapshotService.
+func NewUsageSnapshotService(db *gorm.DB, settingsManager *config.SystemSettingsManager, elector *cluster.Elector) *UsageSnapshotService {
+	return &UsageSnapshotService{
+		db:              db,
+		settingsManager: settingsManager,
+		elector:         elector,
+		stopCh:          make(chan struct{}),
+	}
+}
+
+// Start begins checking the snapshot schedule.
+func (s *UsageSnapshotService) Start() {
+	s.wg.Add(1)
+	go s.run()
+	logrus.Debug("Usage snapshot service started")
+}
+
+// Stop stops the scheduler, respecting the context for shutdown timeout.
+func (s *UsageSnapshotService) Stop(ctx context.Context) {
+	close(s.stopCh)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		logrus.Info("UsageSnapshotService stopped gracefully.")
+	case <-ctx.Done():
+		logrus.Warn("UsageSnapshotService stop timed out.")
+	}
+}
+
+func (s *UsageSnapshotService) run() {
+	defer s.wg.Done()
+
+	s.checkSchedule()
+
+	ticker := time.NewTicker(usageSnapshotCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.checkSchedule()
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// checkSchedule takes the snapshot of the latest scheduled time if it has not been taken yet.
+// A snapshot missed while the master was down is taken as soon as it is back.
+func (s *UsageSnapshotService) checkSchedule() {
+	if !s.elector.IsLeader() {
+		return
+	}
+
+	schedule, err := utils.ParseSnapshotSchedule(s.settingsManager.GetSettings().UsageSnapshotSchedule)
+	if err != nil {
+		logrus.Warnf("UsageSnapshotService: Invalid usage snapshot schedule: %v", err)
+		return
+	}
+	if schedule == nil {
+		return
+	}
+
+	scheduledFor := schedule.Last(time.Now())
+	var count int64
+	if err := s.db.Model(&models.UsageSnapshot{}).
+		Where("source = ? AND scheduled_for >= ?", models.UsageSnapshotSourceSchedule, scheduledFor).
+		Count(&count).Error; err != nil {
+		logrus.Errorf("UsageSnapshotService: Failed to check the last snapshot: %v", err)
+		return
+	}
+	if count > 0 {
+		return
+	}
+
+	if _, err := s.Take(&scheduledFor); err != nil {
+		logrus.Errorf("UsageSnapshotService: Failed to take scheduled snapshot: %v", err)
+	}
+}
+
+// Take records a snapshot of the current counters. scheduledFor is nil for a manual snapshot.
+func (s *UsageSnapshotService) Take(scheduledFor *time.Time) (*models.UsageSnapshot, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := &models.UsageSnapshot{
+		TakenAt:      time.Now(),
+		ScheduledFor: scheduledFor,
+		Source:       models.UsageSnapshotSourceManual,
+	}
+	if scheduledFor != nil {
+		snapshot.Source = models.UsageSnapshotSourceSchedule
+	}
+
+	var groups []models.Group
+	if err := s.db.Select("id", "name").Find(&groups).Error; err != nil {
+		return nil, fmt.Errorf("failed to load groups: %w", err)
+	}
+	groupNames := make(map[uint]string, len(groups))
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	var groupStats []struct {
+		GroupID       uint
+		SuccessCount  int64
+		FailureCount  int64
+		OverflowCount int64
+	}
+	if err := s.db.Model(&models.GroupHourlyStat{}).
+		Select("group_id, SUM(success_count) AS success_count, SUM(failure_count) AS failure_count, SUM(overflow_count) AS overflow_count").
+		Group("group_id").
+		Scan(&groupStats).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum group stats: %w", err)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		entries := make([]models.UsageSnapshotEntry, 0, len(groupStats))
+		for _, stat := range groupStats {
+			entries = append(entries, models.UsageSnapshotEntry{
+				SnapshotID:    snapshot.ID,
+				GroupID:       stat.GroupID,
+				GroupName:     groupNames[stat.GroupID],
+				RequestCount:  stat.SuccessCount + stat.FailureCount,
+				SuccessCount:  stat.SuccessCount,
+				FailureCount:  stat.FailureCount,
+				OverflowCount: stat.OverflowCount,
+			})
+		}
+		if len(entries) > 0 {
+			if err := tx.CreateInBatches(entries, usageSnapshotBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		snapshot.GroupCount = len(entries)
+
+		var keys []models.APIKey
+		if err := tx.Model(&models.APIKey{}).
+			Select("id", "group_id", "key_hash", "request_count", "failure_count").
+			Where("request_count > 0").
+			FindInBatches(&keys, usageSnapshotBatchSize, func(_ *gorm.DB, _ int) error {
+				keyEntries := make([]models.UsageSnapshotEntry, len(keys))
+				for i, key := range keys {
+					keyEntries[i] = models.UsageSnapshotEntry{
+						SnapshotID:   snapshot.ID,
+						GroupID:      key.GroupID,
+						KeyID:        key.ID,
+						GroupName:    groupNames[key.GroupID],
+						KeyHash:      key.KeyHash,
+						RequestCount: key.RequestCount,
+						SuccessCount: key.RequestCount - key.FailureCount,
+						FailureCount: key.FailureCount,
+					}
+				}
+				snapshot.KeyCount += len(keyEntries)
+				return tx.Create(&keyEntries).Error
+			}).Error; err != nil {
+			return err
+		}
+
+		// 条目数在写入后才确定，在同一事务内补写，快照提交后不再修改
+		return tx.Model(snapshot).Updates(map[string]any{
+			"group_count": snapshot.GroupCount,
+			"key_count":   snapshot.KeyCount,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save usage snapshot: %w", err)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"snapshotID": snapshot.ID,
+		"source":     snapshot.Source,
+		"groups":     snapshot.GroupCount,
+		"keys":       snapshot.KeyCount,
+	}).Info("Usage snapshot taken")
+	return snapshot, nil
+}
+
+// List returns the snapshots taken at or before asOf, newest first.
+func (s *UsageSnapshotService) List(asOf time.Time, limit int) ([]models.UsageSnapshot, error) {
+	var snapshots []models.UsageSnapshot
+	if err := s.db.Where("taken_at <= ?", asOf).Order("taken_at desc, id desc").Limit(limit).Find(&snapshots).Error; err != nil {
+		return nil, fmt.Errorf("failed to list usage snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Get returns a snapshot with the counters of one group, or of all groups when groupID is 0.
+// Key counters are only included when includeKeys is set.
+func (s *UsageSnapshotService) Get(id uint, groupID uint, includeKeys bool) (*UsageSnapshotView, error) {
+	var snapshot models.UsageSnapshot
+	if err := s.db.First(&snapshot, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUsageSnapshotNotFound
+		}
+		return nil, fmt.Errorf("failed to load usage snapshot: %w", err)
+	}
+	return s.view(snapshot, groupID, includeKeys)
+}
+
+// AsOf returns the latest snapshot taken at or before asOf, with its counters.
+func (s *UsageSnapshotService) AsOf(asOf time.Time, groupID uint, includeKeys bool) (*UsageSnapshotView, error) {
+	snapshots, err := s.List(asOf, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, ErrUsageSnapshotNotFound
+	}
+	return s.view(snapshots[0], groupID, includeKeys)
+}
+
+func (s *UsageSnapshotService) view(snapshot models.UsageSnapshot, groupID uint, includeKeys bool) (*UsageSnapshotView, error) {
+	query := s.db.Where("snapshot_id = ?", snapshot.ID)
+	if groupID != 0 {
+		query = query.Where("group_id = ?", groupID)
+	}
+	if !includeKeys {
+		query = query.Where("key_id = 0")
+	}
+
+	view := &UsageSnapshotView{UsageSnapshot: snapshot, Entries: []models.UsageSnapshotEntry{}}
+	if err := query.Order("group_id, key_id").Find(&view.Entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load usage snapshot entries: %w", err)
+	}
+	return view, nil
+}
@@ -3,7 +3,10 @@ package store
 import (
 	"context"
 	"fmt"
+	"gpt-load/internal/db"
 	"gpt-load/internal/types"
+	"gpt-load/internal/utils"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/sirupsen/logrus"
@@ -12,6 +15,7 @@ import (
 // NewStore creates a new store based on the application configuration.
 func NewStore(cfg types.ConfigManager) (Store, error) {
 	redisDSN := cfg.GetRedisDSN()
+	maxBytes := int64(cfg.GetPerformanceConfig().MemoryStoreMaxMB) * 1024 * 1024
 	if redisDSN != "" {
 		opts, err := redis.ParseURL(redisDSN)
 		if err != nil {
@@ -19,14 +23,46 @@ func NewStore(cfg types.ConfigManager) (Store, error) {
 		}
 
 		client := redis.NewClient(opts)
-		if err := client.Ping(context.Background()).Err(); err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.GetDatabaseConfig().StartupWait)*time.Second)
+		defer cancel()
+		err = utils.RetryWithBackoff(ctx, "Connecting to Redis", func() error {
+			return client.Ping(context.Background()).Err()
+		})
+		if err != nil {
+			client.Close()
 			return nil, fmt.Errorf("failed to connect to redis: %w", err)
 		}
 
 		logrus.Debug("Successfully connected to Redis.")
+		if cfg.GetDatabaseConfig().RedisFailover {
+			return NewFailoverStore(NewRedisStore(client), NewMemoryStore(maxBytes), failoverPrefixes), nil
+		}
 		return NewRedisStore(client), nil
 	}
 
 	logrus.Info("Redis DSN not configured, falling back to in-memory store.")
-	return NewMemoryStore(), nil
+	memoryStore := NewMemoryStore(maxBytes)
+	if UsesPostgresPubSub(cfg) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.GetDatabaseConfig().StartupWait)*time.Second)
+		defer cancel()
+		var relay *PostgresRelay
+		err := utils.RetryWithBackoff(ctx, "Connecting the postgres pub/sub relay", func() error {
+			var err error
+			relay, err = NewPostgresRelay(context.Background(), cfg.GetDatabaseConfig().DSN, memoryStore.Deliver)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		memoryStore.SetRelay(relay)
+		logrus.Info("Using PostgreSQL LISTEN/NOTIFY for pub/sub between instances.")
+	}
+	return memoryStore, nil
+}
+
+// UsesPostgresPubSub reports whether the in-memory store relays its pub/sub messages through
+// PostgreSQL, which is the case without Redis on a PostgreSQL database unless POSTGRES_PUBSUB is off.
+func UsesPostgresPubSub(cfg types.ConfigManager) bool {
+	dbConfig := cfg.GetDatabaseConfig()
+	return cfg.GetRedisDSN() == "" && dbConfig.PGPubSub && db.IsPostgresDSN(dbConfig.DSN)
 }
@@ -0,0 +1,570 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"iter"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	failoverCheckInterval = 5 * time.Second
+	failoverSyncInterval  = time.Minute
+	failoverPingTimeout   = 2 * time.Second
+	// maxPendingMutations bounds the writes kept for replay while Redis is unreachable.
+	maxPendingMutations = 100000
+)
+
+// failoverPrefixes are the keys mirrored into memory while Redis is healthy: the key pools,
+// key hashes and cooldowns needed to keep selecting keys during an outage, and the time the
+// pools were loaded.
+var failoverPrefixes = []string{"group:", "key:", "cooling_keys", "model_cooldown:", "key_pool_loaded_at"}
+
+// redisUnavailablePrefixes are Redis error replies meaning the server cannot serve commands.
+var redisUnavailablePrefixes = []string{"LOADING", "READONLY", "MASTERDOWN", "CLUSTERDOWN", "TRYAGAIN"}
+
+// FailoverStatus reports whether the store is running on its in-memory backup.
+type FailoverStatus struct {
+	Degraded         bool       `json:"degraded"`
+	DegradedSince    *time.Time `json:"degraded_since,omitempty"`
+	LastSync         *time.Time `json:"last_sync,omitempty"`
+	PendingMutations int        `json:"pending_mutations"`
+	DroppedMutations int64      `json:"dropped_mutations"`
+}
+
+// mutation is a write applied to the backup during an outage and replayed on Redis afterwards.
+type mutation func(Store) error
+
+// FailoverStore serves from Redis and falls back to an in-memory backup when Redis becomes
+// unreachable. While Redis is healthy the key pools are copied into the backup every minute.
+// During an outage reads and writes go to the backup and writes are queued; once Redis
+// answers again the queued writes are replayed in order and the backup is synced again.
+// Pub/sub always goes through Redis.
+type FailoverStore struct {
+	primary  *RedisStore
+	backup   *MemoryStore
+	prefixes []string
+
+	degradedSince atomic.Int64
+	lastSync      atomic.Int64
+	dropped       atomic.Int64
+
+	// mu guards the pending writes and the switch back to Redis.
+	mu      sync.Mutex
+	pending []mutation
+	syncMu  sync.Mutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewFailoverStore creates a FailoverStore mirroring the keys starting with the prefixes, and
+// starts its health checks.
+func NewFailoverStore(primary *RedisStore, backup *MemoryStore, prefixes []string) *FailoverStore {
+	s := &FailoverStore{
+		primary:  primary,
+		backup:   backup,
+		prefixes: prefixes,
+		stopCh:   make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.run()
+	return s
+}
+
+// IsDegraded reports whether the store is serving from its backup.
+func (s *FailoverStore) IsDegraded() bool {
+	return s.degradedSince.Load() != 0
+}
+
+// Status returns the current failover status.
+func (s *FailoverStore) Status() FailoverStatus {
+	s.mu.Lock()
+	status := FailoverStatus{PendingMutations: len(s.pending), DroppedMutations: s.dropped.Load()}
+	s.mu.Unlock()
+
+	if since := s.degradedSince.Load(); since != 0 {
+		t := time.Unix(0, since)
+		status.Degraded = true
+		status.DegradedSince = &t
+	}
+	if synced := s.lastSync.Load(); synced != 0 {
+		t := time.Unix(0, synced)
+		status.LastSync = &t
+	}
+	return status
+}
+
+func (s *FailoverStore) run() {
+	defer s.wg.Done()
+
+	s.sync()
+	ticker := time.NewTicker(failoverCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if s.IsDegraded() {
+				s.recover()
+			} else if time.Since(time.Unix(0, s.lastSync.Load())) >= failoverSyncInterval {
+				s.sync()
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// sync copies the mirrored keys from Redis into the backup.
+func (s *FailoverStore) sync() {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	snapshot, err := s.primary.Snapshot(s.prefixes)
+	if err != nil {
+		if isUnavailable(err) {
+			s.markDegraded(err)
+		} else {
+			logrus.WithError(err).Warn("Failed to sync the in-memory store backup from Redis")
+		}
+		return
+	}
+
+	// Writes made to the backup since the outage began must not be overwritten
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.IsDegraded() {
+		return
+	}
+	s.backup.replacePrefixes(s.prefixes, snapshot)
+	s.lastSync.Store(time.Now().UnixNano())
+}
+
+// markDegraded switches to the backup after a failed Redis command.
+func (s *FailoverStore) markDegraded(err error) {
+	if s.degradedSince.CompareAndSwap(0, time.Now().UnixNano()) {
+		logrus.WithError(err).Error("Redis is unreachable, serving from the in-memory store backup and queueing writes for replay")
+	}
+}
+
+// recover replays the queued writes once Redis answers again, then switches back to it.
+func (s *FailoverStore) recover() {
+	ctx, cancel := context.WithTimeout(context.Background(), failoverPingTimeout)
+	err := s.primary.Ping(ctx)
+	cancel()
+	if err != nil {
+		return
+	}
+
+	replayed := 0
+	for {
+		s.mu.Lock()
+		if len(s.pending) == 0 {
+			s.degradedSince.Store(0)
+			s.mu.Unlock()
+			break
+		}
+		batch := s.pending
+		s.pending = nil
+		s.mu.Unlock()
+
+		for i, op := range batch {
+			err := op(s.primary)
+			switch {
+			case err == nil:
+				replayed++
+			case isUnavailable(err):
+				// Redis went away again, keep the rest for the next attempt
+				s.mu.Lock()
+				s.pending = append(batch[i:], s.pending...)
+				s.mu.Unlock()
+				return
+			default:
+				logrus.WithError(err).Warn("Failed to replay a store write on Redis, dropping it")
+			}
+		}
+	}
+
+	logrus.Infof("Redis is reachable again, replayed %d queued writes and left the in-memory store backup", replayed)
+	s.sync()
+}
+
+// enqueue keeps a write applied to the backup for replay, or applies it to Redis when the
+// store recovered in the meantime.
+func (s *FailoverStore) enqueue(op mutation) {
+	s.mu.Lock()
+	if !s.IsDegraded() {
+		s.mu.Unlock()
+		if err := op(s.primary); err != nil {
+			logrus.WithError(err).Warn("Failed to apply a store write on Redis after recovery")
+		}
+		return
+	}
+	defer s.mu.Unlock()
+
+	if len(s.pending) >= maxPendingMutations {
+		if s.dropped.Add(1) == 1 {
+			logrus.Warnf("More than %d store writes queued during the Redis outage, dropping new ones", maxPendingMutations)
+		}
+		return
+	}
+	s.pending = append(s.pending, op)
+}
+
+// isUnavailable reports whether a Redis error means that Redis cannot be reached, as opposed
+// to a missing key or a rejected command.
+func isUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, redis.ErrClosed) || errors.Is(err, redis.ErrPoolTimeout) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var redisErr redis.Error
+	if errors.As(err, &redisErr) {
+		for _, prefix := range redisUnavailablePrefixes {
+			if strings.HasPrefix(redisErr.Error(), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// read runs a read on Redis, or on the backup when Redis is unreachable.
+func read[T any](s *FailoverStore, op func(Store) (T, error)) (T, error) {
+	if !s.IsDegraded() {
+		result, err := op(s.primary)
+		if !isUnavailable(err) {
+			return result, err
+		}
+		s.markDegraded(err)
+	}
+	return op(s.backup)
+}
+
+// write runs a write whose result is not needed for its replay.
+func (s *FailoverStore) write(op mutation) error {
+	_, err := writeResult(s, func(st Store) (struct{}, error) { return struct{}{}, op(st) }, func(struct{}) mutation { return op })
+	return err
+}
+
+// writeResult runs a write on Redis, or on the backup when Redis is unreachable, queueing the
+// mutation that replays it given its result on the backup. A nil replay is not queued.
+func writeResult[T any](s *FailoverStore, op func(Store) (T, error), replay func(T) mutation) (T, error) {
+	if !s.IsDegraded() {
+		result, err := op(s.primary)
+		if !isUnavailable(err) {
+			return result, err
+		}
+		s.markDegraded(err)
+	}
+
+	result, err := op(s.backup)
+	if err == nil {
+		if m := replay(result); m != nil {
+			s.enqueue(m)
+		}
+	}
+	return result, err
+}
+
+// Set stores a key-value pair with an optional TTL.
+func (s *FailoverStore) Set(key string, value []byte, ttl time.Duration) error {
+	return s.write(func(st Store) error { return st.Set(key, value, ttl) })
+}
+
+// Get retrieves a value by its key.
+func (s *FailoverStore) Get(key string) ([]byte, error) {
+	return read(s, func(st Store) ([]byte, error) { return st.Get(key) })
+}
+
+// Delete removes a value by its key.
+func (s *FailoverStore) Delete(key string) error {
+	return s.write(func(st Store) error { return st.Delete(key) })
+}
+
+// Del deletes multiple keys.
+func (s *FailoverStore) Del(keys ...string) error {
+	return s.write(func(st Store) error { return st.Del(keys...) })
+}
+
+// Exists checks if a key exists in the store.
+func (s *FailoverStore) Exists(key string) (bool, error) {
+	return read(s, func(st Store) (bool, error) { return st.Exists(key) })
+}
+
+// SetNX sets a key-value pair if the key does not already exist. During an outage the key
+// is only checked against the backup of this instance.
+func (s *FailoverStore) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	return writeResult(s, func(st Store) (bool, error) { return st.SetNX(key, value, ttl) }, func(set bool) mutation {
+		if !set {
+			return nil
+		}
+		return func(st Store) error {
+			_, err := st.SetNX(key, value, ttl)
+			return err
+		}
+	})
+}
+
+// CompareAndExpire resets the TTL of a key only while it holds value. During an outage the
+// value is only compared against the backup of this instance.
+func (s *FailoverStore) CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error) {
+	return writeResult(s, func(st Store) (bool, error) { return st.CompareAndExpire(key, value, ttl) }, func(renewed bool) mutation {
+		if !renewed {
+			return nil
+		}
+		return func(st Store) error {
+			_, err := st.CompareAndExpire(key, value, ttl)
+			return err
+		}
+	})
+}
+
+// CompareAndDelete removes a key only while it holds value. During an outage the value is
+// only compared against the backup of this instance.
+func (s *FailoverStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	return writeResult(s, func(st Store) (bool, error) { return st.CompareAndDelete(key, value) }, func(deleted bool) mutation {
+		if !deleted {
+			return nil
+		}
+		return func(st Store) error {
+			_, err := st.CompareAndDelete(key, value)
+			return err
+		}
+	})
+}
+
+// IncrBy increments the integer value of a key and returns the new value.
+func (s *FailoverStore) IncrBy(key string, incr int64, ttl time.Duration) (int64, error) {
+	return writeResult(s, func(st Store) (int64, error) { return st.IncrBy(key, incr, ttl) }, func(int64) mutation {
+		return func(st Store) error {
+			_, err := st.IncrBy(key, incr, ttl)
+			return err
+		}
+	})
+}
+
+// Keys returns the names of all keys starting with prefix.
+func (s *FailoverStore) Keys(prefix string) ([]string, error) {
+	return read(s, func(st Store) ([]string, error) { return st.Keys(prefix) })
+}
+
+// Scan iterates over the names of the keys starting with prefix.
+func (s *FailoverStore) Scan(prefix string) iter.Seq2[string, error] {
+	if s.IsDegraded() {
+		return s.backup.Scan(prefix)
+	}
+	return s.primary.Scan(prefix)
+}
+
+// KeySize returns the approximate memory used by a key.
+func (s *FailoverStore) KeySize(key string) (int64, error) {
+	return read(s, func(st Store) (int64, error) { return st.(KeySizer).KeySize(key) })
+}
+
+// HSet sets fields of a hash.
+func (s *FailoverStore) HSet(key string, values map[string]any) error {
+	return s.write(func(st Store) error { return st.HSet(key, values) })
+}
+
+// HGetAll returns all fields of a hash.
+func (s *FailoverStore) HGetAll(key string) (map[string]string, error) {
+	return read(s, func(st Store) (map[string]string, error) { return st.HGetAll(key) })
+}
+
+// HIncrBy increments a field of a hash and returns its new value.
+func (s *FailoverStore) HIncrBy(key, field string, incr int64) (int64, error) {
+	return writeResult(s, func(st Store) (int64, error) { return st.HIncrBy(key, field, incr) }, func(int64) mutation {
+		return func(st Store) error {
+			_, err := st.HIncrBy(key, field, incr)
+			return err
+		}
+	})
+}
+
+// HIncrByOnce increments a field of a hash while its marker field is not set yet. During an
+// outage the marker is only checked against the backup of this instance.
+func (s *FailoverStore) HIncrByOnce(key, marker, field string, incr int64) (bool, error) {
+	return writeResult(s, func(st Store) (bool, error) { return st.HIncrByOnce(key, marker, field, incr) }, func(incremented bool) mutation {
+		if !incremented {
+			return nil
+		}
+		return func(st Store) error {
+			_, err := st.HIncrByOnce(key, marker, field, incr)
+			return err
+		}
+	})
+}
+
+// HPopAll returns all fields of a hash and removes it. A hash popped from the backup during
+// an outage is removed from Redis on replay.
+func (s *FailoverStore) HPopAll(key string) (map[string]string, error) {
+	return writeResult(s, func(st Store) (map[string]string, error) { return st.HPopAll(key) }, func(fields map[string]string) mutation {
+		if len(fields) == 0 {
+			return nil
+		}
+		return func(st Store) error { return st.Delete(key) }
+	})
+}
+
+// LPush prepends values to a list.
+func (s *FailoverStore) LPush(key string, values ...any) error {
+	return s.write(func(st Store) error { return st.LPush(key, values...) })
+}
+
+// LRem removes occurrences of a value from a list.
+func (s *FailoverStore) LRem(key string, count int64, value any) error {
+	return s.write(func(st Store) error { return st.LRem(key, count, value) })
+}
+
+// Rotate moves the last element of a list to its head and returns it. Rotations made during
+// an outage only change the order of the backup list and are not replayed.
+func (s *FailoverStore) Rotate(key string) (string, error) {
+	return writeResult(s, func(st Store) (string, error) { return st.Rotate(key) }, func(string) mutation { return nil })
+}
+
+// LLen returns the length of a list.
+func (s *FailoverStore) LLen(key string) (int64, error) {
+	return read(s, func(st Store) (int64, error) { return st.LLen(key) })
+}
+
+// LRange returns a range of elements of a list.
+func (s *FailoverStore) LRange(key string, start, stop int64) ([]string, error) {
+	return read(s, func(st Store) ([]string, error) { return st.LRange(key, start, stop) })
+}
+
+// SAdd adds members to a set.
+func (s *FailoverStore) SAdd(key string, members ...any) error {
+	return s.write(func(st Store) error { return st.SAdd(key, members...) })
+}
+
+// SPopN removes and returns up to count random members of a set. The members popped from the
+// backup during an outage are removed from Redis on replay.
+func (s *FailoverStore) SPopN(key string, count int64) ([]string, error) {
+	return writeResult(s, func(st Store) ([]string, error) { return st.SPopN(key, count) }, func(popped []string) mutation {
+		if len(popped) == 0 {
+			return nil
+		}
+		members := make([]any, len(popped))
+		for i, member := range popped {
+			members[i] = member
+		}
+		return func(st Store) error { return st.SRem(key, members...) }
+	})
+}
+
+// SRem removes members from a set.
+func (s *FailoverStore) SRem(key string, members ...any) error {
+	return s.write(func(st Store) error { return st.SRem(key, members...) })
+}
+
+// ZAdd adds members with their scores to a sorted set.
+func (s *FailoverStore) ZAdd(key string, members map[string]float64) error {
+	return s.write(func(st Store) error { return st.ZAdd(key, members) })
+}
+
+// ZRangeByScore returns the members of a sorted set with a score between min and max.
+func (s *FailoverStore) ZRangeByScore(key string, min, max float64) ([]string, error) {
+	return read(s, func(st Store) ([]string, error) { return st.ZRangeByScore(key, min, max) })
+}
+
+// ZRem removes members from a sorted set.
+func (s *FailoverStore) ZRem(key string, members ...any) error {
+	return s.write(func(st Store) error { return st.ZRem(key, members...) })
+}
+
+// Publish sends a message to a given channel through Redis.
+func (s *FailoverStore) Publish(channel string, message []byte) error {
+	err := s.primary.Publish(channel, message)
+	if isUnavailable(err) {
+		s.markDegraded(err)
+	}
+	return err
+}
+
+// Ping checks that Redis is reachable.
+func (s *FailoverStore) Ping(ctx context.Context) error {
+	return s.primary.Ping(ctx)
+}
+
+// Subscribe listens for messages on a given channel through Redis.
+func (s *FailoverStore) Subscribe(channel string) (Subscription, error) {
+	return s.primary.Subscribe(channel)
+}
+
+// Clear clears all data.
+func (s *FailoverStore) Clear() error {
+	return s.write(func(st Store) error { return st.Clear() })
+}
+
+// Snapshot exports the keys of Redis, which must be reachable.
+func (s *FailoverStore) Snapshot(prefixes []string) (*Snapshot, error) {
+	return s.primary.Snapshot(prefixes)
+}
+
+// Restore writes the snapshot into Redis, which must be reachable.
+func (s *FailoverStore) Restore(snapshot *Snapshot) error {
+	return s.primary.Restore(snapshot)
+}
+
+// Pipeline returns a pipeliner that falls back to the backup like single commands do.
+func (s *FailoverStore) Pipeline() Pipeliner {
+	return &failoverPipeliner{store: s}
+}
+
+// Close stops the health checks and closes Redis.
+func (s *FailoverStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+	s.wg.Wait()
+	return s.primary.Close()
+}
+
+type failoverHSet struct {
+	key    string
+	values map[string]any
+}
+
+type failoverPipeliner struct {
+	store *FailoverStore
+	hsets []failoverHSet
+}
+
+func (p *failoverPipeliner) HSet(key string, values map[string]any) {
+	p.hsets = append(p.hsets, failoverHSet{key: key, values: values})
+}
+
+func (p *failoverPipeliner) Exec() error {
+	if !p.store.IsDegraded() {
+		pipe := p.store.primary.Pipeline()
+		for _, hset := range p.hsets {
+			pipe.HSet(hset.key, hset.values)
+		}
+		err := pipe.Exec()
+		if !isUnavailable(err) {
+			return err
+		}
+		p.store.markDegraded(err)
+	}
+
+	// HSet is idempotent, the commands Redis may have applied can be written again
+	for _, hset := range p.hsets {
+		if err := p.store.HSet(hset.key, hset.values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
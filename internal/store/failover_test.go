@@ -0,0 +1,48 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// TestFailoverStoreUsesBackup checks that an unreachable Redis switches the store to its
+// in-memory backup and that writes are queued for replay, except rotations.
+func TestFailoverStoreUsesBackup(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	backup := NewMemoryStore(0)
+	if err := backup.LPush("group:1:active_keys", "1", "2"); err != nil {
+		t.Fatal(err)
+	}
+	s := NewFailoverStore(NewRedisStore(client), backup, failoverPrefixes)
+	defer s.Close()
+
+	if keyID, err := s.Rotate("group:1:active_keys"); err != nil || keyID != "2" {
+		t.Fatalf("Rotate() = %q, %v, want 2 from the backup", keyID, err)
+	}
+	if !s.IsDegraded() {
+		t.Fatal("store should be degraded")
+	}
+	if err := s.HSet("key:2", map[string]any{"status": "active"}); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := s.HIncrBy("key:2", "failure_count", 1); err != nil || count != 1 {
+		t.Fatalf("HIncrBy() = %d, %v", count, err)
+	}
+	if _, err := s.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	status := s.Status()
+	if !status.Degraded || status.DegradedSince == nil || status.PendingMutations != 2 {
+		t.Errorf("Status() = %+v, want degraded with 2 pending writes", status)
+	}
+
+	for _, err := range []error{ErrNotFound, redis.Nil, redis.ErrCrossSlot} {
+		if isUnavailable(err) {
+			t.Errorf("isUnavailable(%v) = true", err)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const benchListSize = 100_000
+
+// BenchmarkLRem compares LRem on a 100k element list in the memory store and, when
+// BENCH_REDIS_DSN is set, in Redis:
+//
+//	BENCH_REDIS_DSN=redis://localhost:6379/15 go test ./internal/store -run '^$' -bench LRem
+//
+// The removed element sits at the head, so count=1 stops at the first element while
+// count=0 and count=-1 scan the whole list.
+func BenchmarkLRem(b *testing.B) {
+	stores := map[string]Store{"memory": NewMemoryStore(0)}
+	if dsn := os.Getenv("BENCH_REDIS_DSN"); dsn != "" {
+		opts, err := redis.ParseURL(dsn)
+		if err != nil {
+			b.Fatalf("invalid BENCH_REDIS_DSN: %v", err)
+		}
+		client := redis.NewClient(opts)
+		if err := client.Ping(context.Background()).Err(); err != nil {
+			b.Fatalf("failed to connect to redis: %v", err)
+		}
+		defer client.Close()
+		stores["redis"] = NewRedisStore(client)
+	}
+
+	for name, s := range stores {
+		key := "bench:lrem"
+		fillBenchList(b, s, key)
+
+		for _, count := range []int64{1, 0, -1} {
+			b.Run(fmt.Sprintf("%s/count=%d", name, count), func(b *testing.B) {
+				for b.Loop() {
+					if err := s.LRem(key, count, "target"); err != nil {
+						b.Fatal(err)
+					}
+					b.StopTimer()
+					if err := s.LPush(key, "target"); err != nil {
+						b.Fatal(err)
+					}
+					b.StartTimer()
+				}
+			})
+		}
+
+		if err := s.Del(key); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func fillBenchList(b *testing.B, s Store, key string) {
+	b.Helper()
+	if err := s.Del(key); err != nil {
+		b.Fatal(err)
+	}
+	values := make([]any, 0, 1000)
+	for i := range benchListSize {
+		values = append(values, fmt.Sprintf("key-%d", i))
+		if len(values) == cap(values) {
+			if err := s.LPush(key, values...); err != nil {
+				b.Fatal(err)
+			}
+			values = values[:0]
+		}
+	}
+	if err := s.LPush(key, "target"); err != nil {
+		b.Fatal(err)
+	}
+}
@@ -1,37 +1,92 @@
 package store
 
 import (
+	"bytes"
 	"fmt"
+	"iter"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// memoryItemOverhead approximates the bookkeeping cost of a K/V item in bytes.
+	memoryItemOverhead = 64
+	// evictionSampleSize is the number of K/V items compared to pick an eviction victim.
+	evictionSampleSize = 16
+)
+
+// evictablePrefixes are the cached values a MemoryStore may evict under its ceiling, which
+// are rebuilt on a miss. Other K/V items hold state, such as pending request logs, deferred key
+// status updates and the key pool readiness marker, and are never evicted.
+var evictablePrefixes = []string{"response_cache:", "model_list:", "debug_capture:", "credential_token:"}
+
 // memoryStoreItem holds the value and expiration timestamp for a key.
 type memoryStoreItem struct {
 	value     []byte
 	expiresAt int64 // Unix-nano timestamp. 0 for no expiry.
+	storedAt  int64
+}
+
+// MemoryStats reports the memory usage and evictions of a MemoryStore.
+type MemoryStats struct {
+	MaxBytes  int64 `json:"max_bytes"`
+	UsedBytes int64 `json:"used_bytes"`
+	Keys      int   `json:"keys"`
+	Evictions int64 `json:"evictions"`
+	Rejected  int64 `json:"rejected"`
 }
 
 // MemoryStore is an in-memory key-value store that is safe for concurrent use.
+//
+// With a memory ceiling, cached K/V items are evicted once the approximate size of all K/V
+// items exceeds it: expired items first, then items with the nearest expiry, then the oldest
+// items without a TTL. A cached item that does not fit is rejected, while other K/V items are
+// always stored. Hashes, lists and sets hold the key pools and are never evicted or counted.
 type MemoryStore struct {
 	mu            sync.RWMutex
 	data          map[string]any
+	maxBytes      int64
+	usedBytes     int64
+	evictions     atomic.Int64
+	rejected      atomic.Int64
 	muSubscribers sync.RWMutex
-	subscribers   map[string]map[chan *Message]struct{}
+	subscribers   map[string]map[*memorySubscription]struct{}
+	relay         Relay
 }
 
-// NewMemoryStore creates and returns a new MemoryStore instance.
-func NewMemoryStore() *MemoryStore {
+// Relay carries the pub/sub messages of a MemoryStore between instances. Published messages
+// are handed to the relay, which delivers every message it carries, this instance's included,
+// to the local subscribers.
+type Relay interface {
+	Publish(channel string, message []byte) error
+	Close() error
+}
+
+// NewMemoryStore creates and returns a new MemoryStore instance. A maxBytes of 0 means no ceiling.
+func NewMemoryStore(maxBytes int64) *MemoryStore {
 	s := &MemoryStore{
 		data:        make(map[string]any),
-		subscribers: make(map[string]map[chan *Message]struct{}),
+		maxBytes:    maxBytes,
+		subscribers: make(map[string]map[*memorySubscription]struct{}),
 	}
 	return s
 }
 
+// SetRelay makes the store publish its messages through relay instead of delivering them locally.
+func (s *MemoryStore) SetRelay(relay Relay) {
+	s.relay = relay
+}
+
 // Close cleans up resources.
 func (s *MemoryStore) Close() error {
+	if s.relay != nil {
+		return s.relay.Close()
+	}
 	return nil
 }
 
@@ -40,16 +95,7 @@ func (s *MemoryStore) Set(key string, value []byte, ttl time.Duration) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var expiresAt int64
-	if ttl > 0 {
-		expiresAt = time.Now().UnixNano() + ttl.Nanoseconds()
-	}
-
-	s.data[key] = memoryStoreItem{
-		value:     value,
-		expiresAt: expiresAt,
-	}
-	return nil
+	return s.setItemLocked(key, value, ttl)
 }
 
 // Get retrieves a value by its key.
@@ -69,7 +115,7 @@ func (s *MemoryStore) Get(key string) ([]byte, error) {
 
 	if item.expiresAt > 0 && time.Now().UnixNano() > item.expiresAt {
 		s.mu.Lock()
-		delete(s.data, key)
+		s.removeLocked(key)
 		s.mu.Unlock()
 		return nil, ErrNotFound
 	}
@@ -81,7 +127,7 @@ func (s *MemoryStore) Get(key string) ([]byte, error) {
 func (s *MemoryStore) Delete(key string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	delete(s.data, key)
+	s.removeLocked(key)
 	return nil
 }
 
@@ -90,7 +136,7 @@ func (s *MemoryStore) Del(keys ...string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, key := range keys {
-		delete(s.data, key)
+		s.removeLocked(key)
 	}
 	return nil
 }
@@ -108,7 +154,7 @@ func (s *MemoryStore) Exists(key string) (bool, error) {
 	if item, ok := rawItem.(memoryStoreItem); ok {
 		if item.expiresAt > 0 && time.Now().UnixNano() > item.expiresAt {
 			s.mu.Lock()
-			delete(s.data, key)
+			s.removeLocked(key)
 			s.mu.Unlock()
 			return false, nil
 		}
@@ -135,17 +181,141 @@ func (s *MemoryStore) SetNX(key string, value []byte, ttl time.Duration) (bool,
 	}
 
 	// Key does not exist or is expired, so we can set it.
-	var expiresAt int64
-	if ttl > 0 {
-		expiresAt = time.Now().UnixNano() + ttl.Nanoseconds()
+	if err := s.setItemLocked(key, value, ttl); err != nil {
+		return false, err
 	}
-	s.data[key] = memoryStoreItem{
-		value:     value,
-		expiresAt: expiresAt,
+	return true, nil
+}
+
+// CompareAndExpire resets the TTL of a key only while it holds value.
+func (s *MemoryStore) CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.holdsLocked(key, value) {
+		return false, nil
+	}
+	if err := s.setItemLocked(key, value, ttl); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CompareAndDelete removes a key only while it holds value.
+func (s *MemoryStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.holdsLocked(key, value) {
+		return false, nil
 	}
+	s.removeLocked(key)
 	return true, nil
 }
 
+// holdsLocked reports whether key is an unexpired K/V item holding value.
+func (s *MemoryStore) holdsLocked(key string, value []byte) bool {
+	item, ok := s.data[key].(memoryStoreItem)
+	if !ok || (item.expiresAt != 0 && time.Now().UnixNano() >= item.expiresAt) {
+		return false
+	}
+	return bytes.Equal(item.value, value)
+}
+
+// IncrBy increments the integer value of a key, creating it with the ttl when it does not exist.
+func (s *MemoryStore) IncrBy(key string, incr int64, ttl time.Duration) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	var current int64
+	if rawItem, exists := s.data[key]; exists {
+		item, ok := rawItem.(memoryStoreItem)
+		if !ok {
+			return 0, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+		}
+		if item.expiresAt == 0 || now < item.expiresAt {
+			var err error
+			if current, err = strconv.ParseInt(string(item.value), 10, 64); err != nil {
+				return 0, fmt.Errorf("value of key '%s' is not an integer", key)
+			}
+			ttl = 0
+			if item.expiresAt > 0 {
+				ttl = time.Duration(item.expiresAt - now)
+			}
+		}
+	}
+
+	newVal := current + incr
+	if err := s.setItemLocked(key, []byte(strconv.FormatInt(newVal, 10)), ttl); err != nil {
+		return 0, err
+	}
+	return newVal, nil
+}
+
+// Keys returns the names of all unexpired keys starting with prefix.
+func (s *MemoryStore) Keys(prefix string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UnixNano()
+	var keys []string
+	for key, rawItem := range s.data {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		if item, ok := rawItem.(memoryStoreItem); ok && item.expiresAt > 0 && now > item.expiresAt {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Scan iterates over the unexpired keys starting with prefix. The names are collected under
+// the lock first, so the store may be modified during the iteration.
+func (s *MemoryStore) Scan(prefix string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		keys, _ := s.Keys(prefix)
+		for _, key := range keys {
+			if !yield(key, nil) {
+				return
+			}
+		}
+	}
+}
+
+// KeySize returns the approximate memory used by a key of any type.
+func (s *MemoryStore) KeySize(key string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	size := int64(len(key)) + memoryItemOverhead
+	switch value := s.data[key].(type) {
+	case nil:
+		return 0, nil
+	case memoryStoreItem:
+		size += int64(len(value.value))
+	case map[string]string:
+		for field, v := range value {
+			size += int64(len(field) + len(v))
+		}
+	case []string:
+		for _, v := range value {
+			size += int64(len(v))
+		}
+	case map[string]struct{}:
+		for member := range value {
+			size += int64(len(member))
+		}
+	case memorySortedSet:
+		for member := range value {
+			size += int64(len(member)) + 8
+		}
+	}
+	return size, nil
+}
+
 // --- HASH operations ---
 
 func (s *MemoryStore) HSet(key string, values map[string]any) error {
@@ -193,6 +363,53 @@ func (s *MemoryStore) HGetAll(key string) (map[string]string, error) {
 	return result, nil
 }
 
+// HIncrByOnce increments a field of a hash while its marker field is not set yet.
+func (s *MemoryStore) HIncrByOnce(key, marker, field string, incr int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var hash map[string]string
+	rawHash, exists := s.data[key]
+	if !exists {
+		hash = make(map[string]string)
+		s.data[key] = hash
+	} else {
+		var ok bool
+		hash, ok = rawHash.(map[string]string)
+		if !ok {
+			return false, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+		}
+	}
+
+	if _, marked := hash[marker]; marked {
+		return false, nil
+	}
+	hash[marker] = "1"
+	currentVal, _ := strconv.ParseInt(hash[field], 10, 64)
+	hash[field] = strconv.FormatInt(currentVal+incr, 10)
+
+	return true, nil
+}
+
+// HPopAll returns all fields of a hash and removes it.
+func (s *MemoryStore) HPopAll(key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawHash, exists := s.data[key]
+	if !exists {
+		return make(map[string]string), nil
+	}
+
+	hash, ok := rawHash.(map[string]string)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+	s.removeLocked(key)
+
+	return hash, nil
+}
+
 func (s *MemoryStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -258,20 +475,59 @@ func (s *MemoryStore) LRem(key string, count int64, value any) error {
 		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
 	}
 
-	strValue := fmt.Sprint(value)
-	newList := make([]string, 0, len(list))
+	s.data[key] = removeListElements(list, fmt.Sprint(value), count)
+	return nil
+}
 
-	if count != 0 {
-		return fmt.Errorf("LRem with non-zero count is not implemented in MemoryStore")
+// removeListElements removes occurrences of value with Redis LREM semantics: the first count
+// from the head when count > 0, the last -count from the tail when count < 0, all when 0.
+// Scanning stops once enough matches are found and the list is compacted in place.
+func removeListElements(list []string, value string, count int64) []string {
+	var indexes []int
+	switch {
+	case count > 0:
+		for i := 0; i < len(list) && int64(len(indexes)) < count; i++ {
+			if list[i] == value {
+				indexes = append(indexes, i)
+			}
+		}
+	case count < 0:
+		for i := len(list) - 1; i >= 0 && int64(len(indexes)) < -count; i-- {
+			if list[i] == value {
+				indexes = append(indexes, i)
+			}
+		}
+		slices.Reverse(indexes)
+	default:
+		for i, item := range list {
+			if item == value {
+				indexes = append(indexes, i)
+			}
+		}
+	}
+	if len(indexes) == 0 {
+		return list
 	}
 
-	for _, item := range list {
-		if item != strValue {
-			newList = append(newList, item)
+	// A single element in the first half is removed by shifting the shorter head segment right
+	if len(indexes) == 1 && indexes[0] < len(list)/2 {
+		index := indexes[0]
+		copy(list[1:index+1], list[:index])
+		list[0] = ""
+		return list[1:]
+	}
+
+	// Shift the segments between removed elements left
+	write := indexes[0]
+	for n, index := range indexes {
+		end := len(list)
+		if n+1 < len(indexes) {
+			end = indexes[n+1]
 		}
+		write += copy(list[write:], list[index+1:end])
 	}
-	s.data[key] = newList
-	return nil
+	clear(list[write:])
+	return list[:write]
 }
 
 func (s *MemoryStore) Rotate(key string) (string, error) {
@@ -320,6 +576,41 @@ func (s *MemoryStore) LLen(key string) (int64, error) {
 	return int64(len(list)), nil
 }
 
+// LRange returns the elements of a list between start and stop, inclusive.
+// Negative indexes count from the end of the list, as in Redis.
+func (s *MemoryStore) LRange(key string, start, stop int64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawList, exists := s.data[key]
+	if !exists {
+		return []string{}, nil
+	}
+
+	list, ok := rawList.([]string)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	length := int64(len(list))
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+
+	result := make([]string, stop-start+1)
+	copy(result, list[start:stop+1])
+	return result, nil
+}
+
 // --- SET operations ---
 
 // SAdd adds members to a set.
@@ -377,6 +668,108 @@ func (s *MemoryStore) SPopN(key string, count int64) ([]string, error) {
 	return popped, nil
 }
 
+// SRem removes members from a set.
+func (s *MemoryStore) SRem(key string, members ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawSet, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+
+	set, ok := rawSet.(map[string]struct{})
+	if !ok {
+		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	for _, member := range members {
+		delete(set, fmt.Sprint(member))
+	}
+	return nil
+}
+
+// --- SORTED SET operations ---
+
+// memorySortedSet maps members to their scores.
+type memorySortedSet map[string]float64
+
+// ZAdd adds members with their scores to a sorted set, updating the score of existing members.
+func (s *MemoryStore) ZAdd(key string, members map[string]float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zset memorySortedSet
+	rawSet, exists := s.data[key]
+	if !exists {
+		zset = make(memorySortedSet)
+		s.data[key] = zset
+	} else {
+		var ok bool
+		zset, ok = rawSet.(memorySortedSet)
+		if !ok {
+			return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+		}
+	}
+
+	for member, score := range members {
+		zset[member] = score
+	}
+	return nil
+}
+
+// ZRangeByScore returns the members with a score between min and max, inclusive, ordered by score.
+func (s *MemoryStore) ZRangeByScore(key string, min, max float64) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rawSet, exists := s.data[key]
+	if !exists {
+		return []string{}, nil
+	}
+
+	zset, ok := rawSet.(memorySortedSet)
+	if !ok {
+		return nil, fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	result := make([]string, 0)
+	for member, score := range zset {
+		if score >= min && score <= max {
+			result = append(result, member)
+		}
+	}
+	// Same order as Redis: by score, then lexicographically by member
+	sort.Slice(result, func(i, j int) bool {
+		if zset[result[i]] != zset[result[j]] {
+			return zset[result[i]] < zset[result[j]]
+		}
+		return result[i] < result[j]
+	})
+	return result, nil
+}
+
+// ZRem removes members from a sorted set.
+func (s *MemoryStore) ZRem(key string, members ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rawSet, exists := s.data[key]
+	if !exists {
+		return nil
+	}
+
+	zset, ok := rawSet.(memorySortedSet)
+	if !ok {
+		return fmt.Errorf("type mismatch: key '%s' holds a different data type", key)
+	}
+
+	for _, member := range members {
+		delete(zset, fmt.Sprint(member))
+	}
+	return nil
+}
+
 // --- Pub/Sub operations ---
 
 // memorySubscription implements the Subscription interface for the in-memory store.
@@ -384,6 +777,23 @@ type memorySubscription struct {
 	store   *MemoryStore
 	channel string
 	msgChan chan *Message
+
+	// mu keeps Close from closing msgChan while a message is being sent on it
+	mu     sync.Mutex
+	closed bool
+}
+
+// send hands a message to the subscriber, giving up after a second.
+func (ms *memorySubscription) send(msg *Message) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.closed {
+		return
+	}
+	select {
+	case ms.msgChan <- msg:
+	case <-time.After(1 * time.Second):
+	}
 }
 
 // Channel returns the message channel for the subscription.
@@ -397,17 +807,32 @@ func (ms *memorySubscription) Close() error {
 	defer ms.store.muSubscribers.Unlock()
 
 	if subs, ok := ms.store.subscribers[ms.channel]; ok {
-		delete(subs, ms.msgChan)
+		delete(subs, ms)
 		if len(subs) == 0 {
 			delete(ms.store.subscribers, ms.channel)
 		}
 	}
-	close(ms.msgChan)
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if !ms.closed {
+		ms.closed = true
+		close(ms.msgChan)
+	}
 	return nil
 }
 
-// Publish sends a message to all subscribers of a channel.
+// Publish sends a message to all subscribers of a channel, through the relay when there is one.
 func (s *MemoryStore) Publish(channel string, message []byte) error {
+	if s.relay != nil {
+		return s.relay.Publish(channel, message)
+	}
+	s.Deliver(channel, message)
+	return nil
+}
+
+// Deliver sends a message to the local subscribers of a channel.
+func (s *MemoryStore) Deliver(channel string, message []byte) {
 	s.muSubscribers.RLock()
 	defer s.muSubscribers.RUnlock()
 
@@ -416,17 +841,9 @@ func (s *MemoryStore) Publish(channel string, message []byte) error {
 		Payload: message,
 	}
 
-	if subs, ok := s.subscribers[channel]; ok {
-		for subCh := range subs {
-			go func(c chan *Message) {
-				select {
-				case c <- msg:
-				case <-time.After(1 * time.Second):
-				}
-			}(subCh)
-		}
+	for sub := range s.subscribers[channel] {
+		go sub.send(msg)
 	}
-	return nil
 }
 
 // Subscribe listens for messages on a given channel.
@@ -434,19 +851,17 @@ func (s *MemoryStore) Subscribe(channel string) (Subscription, error) {
 	s.muSubscribers.Lock()
 	defer s.muSubscribers.Unlock()
 
-	msgChan := make(chan *Message, 10) // Buffered channel
-
-	if _, ok := s.subscribers[channel]; !ok {
-		s.subscribers[channel] = make(map[chan *Message]struct{})
-	}
-	s.subscribers[channel][msgChan] = struct{}{}
-
 	sub := &memorySubscription{
 		store:   s,
 		channel: channel,
-		msgChan: msgChan,
+		msgChan: make(chan *Message, 10), // Buffered channel
 	}
 
+	if _, ok := s.subscribers[channel]; !ok {
+		s.subscribers[channel] = make(map[*memorySubscription]struct{})
+	}
+	s.subscribers[channel][sub] = struct{}{}
+
 	return sub, nil
 }
 
@@ -457,6 +872,173 @@ func (s *MemoryStore) Clear() error {
 
 	// Clear all data
 	s.data = make(map[string]any)
+	s.usedBytes = 0
 
 	return nil
 }
+
+// replacePrefixes replaces all keys starting with one of the prefixes with the snapshot entries
+// in one step. Snapshot TTLs are only kept for plain K/V items.
+func (s *MemoryStore) replacePrefixes(prefixes []string, snapshot *Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key := range s.data {
+		if matchesAnyPrefix(key, prefixes) {
+			s.removeLocked(key)
+		}
+	}
+
+	for _, entry := range snapshot.Entries {
+		switch entry.Type {
+		case SnapshotTypeString:
+			_ = s.setItemLocked(entry.Key, entry.String, time.Duration(entry.TTLMillis)*time.Millisecond)
+		case SnapshotTypeHash:
+			if len(entry.Hash) > 0 {
+				s.data[entry.Key] = entry.Hash
+			}
+		case SnapshotTypeList:
+			if len(entry.List) > 0 {
+				s.data[entry.Key] = entry.List
+			}
+		case SnapshotTypeSet:
+			set := make(map[string]struct{}, len(entry.Set))
+			for _, member := range entry.Set {
+				set[member] = struct{}{}
+			}
+			s.data[entry.Key] = set
+		case SnapshotTypeZSet:
+			zset := make(memorySortedSet, len(entry.ZSet))
+			for _, member := range entry.ZSet {
+				zset[member.Member] = member.Score
+			}
+			s.data[entry.Key] = zset
+		}
+	}
+}
+
+// MemoryStats returns the approximate memory usage and eviction counters.
+func (s *MemoryStore) MemoryStats() MemoryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return MemoryStats{
+		MaxBytes:  s.maxBytes,
+		UsedBytes: s.usedBytes,
+		Keys:      len(s.data),
+		Evictions: s.evictions.Load(),
+		Rejected:  s.rejected.Load(),
+	}
+}
+
+// --- Memory accounting ---
+
+func memoryItemSize(key string, value []byte) int64 {
+	return int64(len(key)+len(value)) + memoryItemOverhead
+}
+
+// setItemLocked stores a K/V item, evicting other items first if it would exceed the ceiling.
+func (s *MemoryStore) setItemLocked(key string, value []byte, ttl time.Duration) error {
+	now := time.Now().UnixNano()
+	var expiresAt int64
+	if ttl > 0 {
+		expiresAt = now + ttl.Nanoseconds()
+	}
+
+	size := memoryItemSize(key, value)
+	if s.maxBytes > 0 {
+		var previous int64
+		if item, ok := s.data[key].(memoryStoreItem); ok {
+			previous = memoryItemSize(key, item.value)
+		}
+		if excess := s.usedBytes - previous + size - s.maxBytes; excess > 0 {
+			s.evictLocked(excess, key, now)
+		}
+		if s.usedBytes-previous+size > s.maxBytes && isEvictable(key) {
+			s.rejected.Add(1)
+			return fmt.Errorf("%w: storing %q needs %d bytes", ErrMemoryLimit, key, size)
+		}
+	}
+
+	s.removeLocked(key)
+	s.data[key] = memoryStoreItem{
+		value:     value,
+		expiresAt: expiresAt,
+		storedAt:  now,
+	}
+	s.usedBytes += size
+	return nil
+}
+
+// removeLocked deletes a key and releases the memory accounted for it.
+func (s *MemoryStore) removeLocked(key string) {
+	if item, ok := s.data[key].(memoryStoreItem); ok {
+		s.usedBytes -= memoryItemSize(key, item.value)
+	}
+	delete(s.data, key)
+}
+
+// isEvictable reports whether a K/V item is a cached value the ceiling may evict.
+func isEvictable(key string) bool {
+	for _, prefix := range evictablePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// evictLocked frees at least the given number of bytes from cached items, never touching the
+// key being written.
+func (s *MemoryStore) evictLocked(bytes int64, keep string, now int64) {
+	for bytes > 0 {
+		var victim string
+		var victimItem memoryStoreItem
+		sampled := 0
+		// Map iteration starts at a random position, which makes this a random sample.
+		for key, rawItem := range s.data {
+			item, ok := rawItem.(memoryStoreItem)
+			if !ok || key == keep || !isEvictable(key) {
+				continue
+			}
+			if sampled == 0 || evictBefore(item, victimItem, now) {
+				victim, victimItem = key, item
+			}
+			sampled++
+			if sampled >= evictionSampleSize {
+				break
+			}
+		}
+		if sampled == 0 {
+			return
+		}
+
+		bytes -= memoryItemSize(victim, victimItem.value)
+		s.removeLocked(victim)
+		s.evictions.Add(1)
+	}
+}
+
+// evictBefore reports whether a should be evicted before b.
+func evictBefore(a, b memoryStoreItem, now int64) bool {
+	rankA, rankB := evictionRank(a, now), evictionRank(b, now)
+	if rankA != rankB {
+		return rankA < rankB
+	}
+	if rankA == 1 {
+		return a.expiresAt < b.expiresAt
+	}
+	return a.storedAt < b.storedAt
+}
+
+// evictionRank orders items by eviction priority: expired, then with a TTL, then without one.
+func evictionRank(item memoryStoreItem, now int64) int {
+	switch {
+	case item.expiresAt > 0 && now > item.expiresAt:
+		return 0
+	case item.expiresAt > 0:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,344 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEvictsUnderCeiling(t *testing.T) {
+	value := make([]byte, 100)
+	itemSize := memoryItemSize("response_cache:0", value)
+	s := NewMemoryStore(itemSize*4 + 10)
+
+	if err := s.HSet("key:1", map[string]any{"status": "active"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("response_cache:old", value, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("response_cache:0", value, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("response_cache:1", value, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("response_cache:2", value, 2*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	// The entry closest to expiry goes first, then the one with a later expiry.
+	if err := s.Set("response_cache:3", value, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("response_cache:1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("response_cache:1 should have been evicted, got %v", err)
+	}
+	if _, err := s.Get("response_cache:old"); err != nil {
+		t.Errorf("an entry without TTL should outlive entries with one, got %v", err)
+	}
+	if fields, _ := s.HGetAll("key:1"); fields["status"] != "active" {
+		t.Error("hashes must never be evicted")
+	}
+
+	stats := s.MemoryStats()
+	if stats.Evictions != 1 || stats.UsedBytes > stats.MaxBytes {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if err := s.Set("response_cache:huge", make([]byte, itemSize*5), 0); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("value larger than the ceiling should be rejected, got %v", err)
+	}
+
+	if err := s.Del("response_cache:old", "response_cache:0", "response_cache:2", "response_cache:3"); err != nil {
+		t.Fatal(err)
+	}
+	if used := s.MemoryStats().UsedBytes; used != 0 {
+		t.Errorf("used bytes should return to 0 after deleting everything, got %d", used)
+	}
+}
+
+func TestMemoryStoreKeepsStateUnderCeiling(t *testing.T) {
+	value := make([]byte, 100)
+	itemSize := memoryItemSize("response_cache:0", value)
+	s := NewMemoryStore(itemSize*3 + 10)
+
+	state := []string{"request_log:1", "deferred_key_status:1", "key_pool_loaded_at"}
+	for _, key := range state {
+		if err := s.Set(key, value, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Cached entries make room for state and are rejected once only state is left.
+	if err := s.Set("response_cache:0", value, time.Hour); !errors.Is(err, ErrMemoryLimit) {
+		t.Errorf("cached entry over the ceiling should be rejected, got %v", err)
+	}
+	if err := s.Del("key_pool_loaded_at"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("response_cache:0", value, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"key_pool_loaded_at", "request_log:2"} {
+		if err := s.Set(key, value, 0); err != nil {
+			t.Errorf("state %s should be stored over the ceiling, got %v", key, err)
+		}
+	}
+
+	if _, err := s.Get("response_cache:0"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("cached entry should have been evicted for state, got %v", err)
+	}
+	for _, key := range append(state, "request_log:2") {
+		if _, err := s.Get(key); err != nil {
+			t.Errorf("%s should survive memory pressure, got %v", key, err)
+		}
+	}
+}
+
+func TestMemoryStoreRangeOperations(t *testing.T) {
+	s := NewMemoryStore(0)
+
+	if err := s.LPush("list", "c", "b", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.LRange("list", 1, -1); len(got) != 2 || got[0] != "b" || got[1] != "a" {
+		t.Errorf("LRange(1, -1) = %v", got)
+	}
+
+	if err := s.ZAdd("cooling", map[string]float64{"k3": 30, "k1": 10, "k2": 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ZRem("cooling", "k2"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.ZRangeByScore("cooling", 0, 30); len(got) != 2 || got[0] != "k1" || got[1] != "k3" {
+		t.Errorf("ZRangeByScore(0, 30) = %v", got)
+	}
+
+	if err := s.SAdd("set", "a", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SRem("set", "a"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.SPopN("set", 10); len(got) != 1 || got[0] != "b" {
+		t.Errorf("set after SRem = %v", got)
+	}
+}
+
+func TestMemoryStoreLRemCount(t *testing.T) {
+	cases := []struct {
+		count int64
+		want  string
+	}{
+		{count: 0, want: "b,c"},
+		{count: 1, want: "b,a,c,a"},
+		{count: 2, want: "b,c,a"},
+		{count: -1, want: "a,b,a,c"},
+		{count: -5, want: "b,c"},
+	}
+	for _, tc := range cases {
+		s := NewMemoryStore(0)
+		if err := s.LPush("list", "a", "b", "a", "c", "a"); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.LRem("list", tc.count, "a"); err != nil {
+			t.Fatalf("LRem(%d): %v", tc.count, err)
+		}
+		got, _ := s.LRange("list", 0, -1)
+		if strings.Join(got, ",") != tc.want {
+			t.Errorf("LRem(%d) left %v, want %s", tc.count, got, tc.want)
+		}
+		if length, _ := s.LLen("list"); length != int64(len(got)) {
+			t.Errorf("LLen after LRem(%d) = %d, want %d", tc.count, length, len(got))
+		}
+	}
+}
+
+func TestMemoryStoreKeys(t *testing.T) {
+	s := NewMemoryStore(0)
+	if err := s.HSet("key:1", map[string]any{"status": "active"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LPush("group:1:active_keys", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("key:expired", []byte("x"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	keys, err := s.Keys("key:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0] != "key:1" {
+		t.Errorf("Keys(key:) = %v, want [key:1]", keys)
+	}
+
+	if size, _ := s.KeySize("group:1:active_keys"); size <= 0 {
+		t.Errorf("KeySize() = %d, want a positive size", size)
+	}
+	if size, _ := s.KeySize("missing"); size != 0 {
+		t.Errorf("KeySize(missing) = %d, want 0", size)
+	}
+}
+
+func TestMemoryStoreScan(t *testing.T) {
+	s := NewMemoryStore(0)
+	for _, key := range []string{"model_cooldown:1:a", "model_cooldown:2:b", "key:1"} {
+		if err := s.Set(key, []byte("1"), 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Set("model_cooldown:3:c", []byte("1"), time.Nanosecond); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+
+	var seen []string
+	for key, err := range s.Scan("model_cooldown:") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The store may be modified while iterating
+		if err := s.Delete(key); err != nil {
+			t.Fatal(err)
+		}
+		seen = append(seen, key)
+	}
+	if len(seen) != 2 || !strings.HasPrefix(seen[0], "model_cooldown:") {
+		t.Errorf("Scan() = %v, want the 2 unexpired model cooldowns", seen)
+	}
+
+	for range s.Scan("") {
+		break
+	}
+}
+
+func TestMemoryStoreIncrBy(t *testing.T) {
+	s := NewMemoryStore(0)
+	for want := int64(1); want <= 3; want++ {
+		if got, err := s.IncrBy("counter", 1, 20*time.Millisecond); err != nil || got != want {
+			t.Fatalf("IncrBy() = %d, %v, want %d", got, err, want)
+		}
+	}
+
+	// The expiry is set when the counter is created and not extended by increments
+	time.Sleep(25 * time.Millisecond)
+	if got, _ := s.IncrBy("counter", 5, time.Minute); got != 5 {
+		t.Errorf("IncrBy() after expiry = %d, want a new counter at 5", got)
+	}
+}
+
+func TestMemoryStoreHIncrByOnce(t *testing.T) {
+	s := NewMemoryStore(0)
+	if _, err := s.HIncrBy("hash", "count", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	if incremented, err := s.HIncrByOnce("hash", "seeded", "count", 5); err != nil || !incremented {
+		t.Fatalf("first HIncrByOnce() = %t, %v, want true", incremented, err)
+	}
+	if incremented, err := s.HIncrByOnce("hash", "seeded", "count", 5); err != nil || incremented {
+		t.Errorf("second HIncrByOnce() = %t, %v, want false", incremented, err)
+	}
+	if fields, _ := s.HGetAll("hash"); fields["count"] != "7" {
+		t.Errorf("count = %s, want 7", fields["count"])
+	}
+}
+
+func TestMemoryStoreHPopAll(t *testing.T) {
+	s := NewMemoryStore(0)
+	if err := s.HSet("hash", map[string]any{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.HIncrBy("hash", "b", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := s.HPopAll("hash")
+	if err != nil || len(fields) != 2 || fields["a"] != "1" || fields["b"] != "2" {
+		t.Fatalf("HPopAll() = %v, %v", fields, err)
+	}
+	if exists, _ := s.Exists("hash"); exists {
+		t.Error("HPopAll() should remove the hash")
+	}
+	if fields, err := s.HPopAll("hash"); err != nil || len(fields) != 0 {
+		t.Errorf("HPopAll() of a missing hash = %v, %v", fields, err)
+	}
+}
+
+func TestMemoryStoreCompareAndSwapLease(t *testing.T) {
+	s := NewMemoryStore(0)
+	if err := s.Set("lease", []byte("first"), 20*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if renewed, err := s.CompareAndExpire("lease", []byte("second"), time.Minute); err != nil || renewed {
+		t.Errorf("CompareAndExpire() by another holder = %t, %v, want false", renewed, err)
+	}
+	if renewed, err := s.CompareAndExpire("lease", []byte("first"), time.Minute); err != nil || !renewed {
+		t.Fatalf("CompareAndExpire() by the holder = %t, %v, want true", renewed, err)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if _, err := s.Get("lease"); err != nil {
+		t.Errorf("the renewed lease should not have expired, got %v", err)
+	}
+
+	if deleted, err := s.CompareAndDelete("lease", []byte("second")); err != nil || deleted {
+		t.Errorf("CompareAndDelete() by another holder = %t, %v, want false", deleted, err)
+	}
+	if deleted, err := s.CompareAndDelete("lease", []byte("first")); err != nil || !deleted {
+		t.Errorf("CompareAndDelete() by the holder = %t, %v, want true", deleted, err)
+	}
+	if renewed, _ := s.CompareAndExpire("lease", []byte("first"), time.Minute); renewed {
+		t.Error("CompareAndExpire() should not recreate a released lease")
+	}
+}
+
+// loopbackRelay delivers published messages back to the store, as the postgres relay does.
+type loopbackRelay struct {
+	store     *MemoryStore
+	published []string
+}
+
+func (r *loopbackRelay) Publish(channel string, message []byte) error {
+	r.published = append(r.published, channel)
+	r.store.Deliver(channel, message)
+	return nil
+}
+
+func (r *loopbackRelay) Close() error { return nil }
+
+func TestMemoryStoreRelay(t *testing.T) {
+	s := NewMemoryStore(0)
+	relay := &loopbackRelay{store: s}
+	s.SetRelay(relay)
+
+	sub, err := s.Subscribe("events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Publish("events", []byte("reload")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-sub.Channel():
+		if string(msg.Payload) != "reload" || len(relay.published) != 1 {
+			t.Errorf("received %q, relay published %v", msg.Payload, relay.published)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	// Closing while deliveries are pending must not send on the closed channel
+	for range 20 {
+		s.Deliver("events", []byte("late"))
+	}
+	if err := sub.Close(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+}
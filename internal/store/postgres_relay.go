@@ -0,0 +1,142 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// postgresRelayChannel is the PostgreSQL notification channel carrying all store channels.
+	postgresRelayChannel = "gpt_load_events"
+	// maxNotifyPayload is the largest payload PostgreSQL accepts for a notification.
+	maxNotifyPayload = 7999
+
+	relayReconnectMin = time.Second
+	relayReconnectMax = 30 * time.Second
+)
+
+// relayEnvelope wraps a message with its store channel in a notification payload.
+type relayEnvelope struct {
+	Channel string `json:"c"`
+	Payload []byte `json:"p"`
+}
+
+// PostgresRelay carries the pub/sub messages of a MemoryStore between instances with
+// PostgreSQL LISTEN/NOTIFY, for deployments sharing a PostgreSQL database without Redis.
+// Like Redis pub/sub, messages sent while the listener reconnects are lost.
+type PostgresRelay struct {
+	dsn     string
+	pool    *pgxpool.Pool
+	deliver func(channel string, message []byte)
+
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// NewPostgresRelay connects to PostgreSQL and starts listening for notifications, which are
+// handed to deliver.
+func NewPostgresRelay(ctx context.Context, dsn string, deliver func(channel string, message []byte)) (*PostgresRelay, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse postgres DSN: %w", err)
+	}
+	config.MaxConns = 2
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to postgres: %w", err)
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	r := &PostgresRelay{dsn: dsn, pool: pool, deliver: deliver, cancel: cancel}
+	r.wg.Add(1)
+	go r.listen(listenCtx)
+	return r, nil
+}
+
+// Publish sends a message to the subscribers of a channel on every instance.
+func (r *PostgresRelay) Publish(channel string, message []byte) error {
+	payload, err := json.Marshal(relayEnvelope{Channel: channel, Payload: message})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("message of %d bytes on %s is too large for a postgres notification", len(payload), channel)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := r.pool.Exec(ctx, "SELECT pg_notify($1, $2)", postgresRelayChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+	return nil
+}
+
+// Close stops listening and closes the connections.
+func (r *PostgresRelay) Close() error {
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.wg.Wait()
+		r.pool.Close()
+	})
+	return nil
+}
+
+// listen holds a dedicated connection listening for notifications, reconnecting with a
+// growing backoff when it is lost.
+func (r *PostgresRelay) listen(ctx context.Context) {
+	defer r.wg.Done()
+	backoff := relayReconnectMin
+	for {
+		err := r.listenOnce(ctx, func() { backoff = relayReconnectMin })
+		if ctx.Err() != nil {
+			return
+		}
+		logrus.WithError(err).Warnf("Postgres notification listener disconnected, reconnecting in %s", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(backoff*2, relayReconnectMax)
+	}
+}
+
+func (r *PostgresRelay) listenOnce(ctx context.Context, listening func()) error {
+	conn, err := pgx.Connect(ctx, r.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresRelayChannel); err != nil {
+		return err
+	}
+	listening()
+	logrus.Debug("Listening for store messages on postgres notifications.")
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var envelope relayEnvelope
+		if err := json.Unmarshal([]byte(notification.Payload), &envelope); err != nil {
+			logrus.WithError(err).Warn("Ignoring an invalid postgres store notification")
+			continue
+		}
+		r.deliver(envelope.Channel, envelope.Payload)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -37,6 +40,51 @@ func (s *RedisStore) prefixKeys(keys []string) []string {
 	return prefixed
 }
 
+// Keys returns the names of all keys starting with prefix, scanning incrementally.
+func (s *RedisStore) Keys(prefix string) ([]string, error) {
+	var result []string
+	for key, err := range s.Scan(prefix) {
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, key)
+	}
+	return result, nil
+}
+
+// Scan iterates over the keys starting with prefix, fetching them with SCAN one page at a time.
+func (s *RedisStore) Scan(prefix string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		ctx := context.Background()
+		var cursor uint64
+		for {
+			keys, nextCursor, err := s.client.Scan(ctx, cursor, s.prefixKey(prefix)+"*", 1000).Result()
+			if err != nil {
+				yield("", fmt.Errorf("failed to scan keys: %w", err))
+				return
+			}
+			for _, key := range keys {
+				if !yield(strings.TrimPrefix(key, RedisKeyPrefix), nil) {
+					return
+				}
+			}
+			cursor = nextCursor
+			if cursor == 0 {
+				return
+			}
+		}
+	}
+}
+
+// KeySize returns the memory used by a key as reported by MEMORY USAGE.
+func (s *RedisStore) KeySize(key string) (int64, error) {
+	size, err := s.client.MemoryUsage(context.Background(), s.prefixKey(key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return size, err
+}
+
 // Set stores a key-value pair in Redis.
 func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
 	return s.client.Set(context.Background(), s.prefixKey(key), value, ttl).Err()
@@ -81,6 +129,54 @@ func (s *RedisStore) SetNX(key string, value []byte, ttl time.Duration) (bool, e
 	return s.client.SetNX(context.Background(), s.prefixKey(key), value, ttl).Result()
 }
 
+// compareAndExpireScript renews the expiry of a key only while it still holds the expected value.
+var compareAndExpireScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// CompareAndExpire resets the TTL of a key in Redis only while it holds value.
+func (s *RedisStore) CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error) {
+	renewed, err := compareAndExpireScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, value, ttl.Milliseconds()).Int64()
+	return renewed == 1, err
+}
+
+// compareAndDeleteScript deletes a key only while it still holds the expected value.
+var compareAndDeleteScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// CompareAndDelete removes a key from Redis only while it holds value.
+func (s *RedisStore) CompareAndDelete(key string, value []byte) (bool, error) {
+	deleted, err := compareAndDeleteScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, value).Int64()
+	return deleted == 1, err
+}
+
+// incrByScript increments a key and sets its expiry only when it has none, so that the window
+// of a counter is not extended by each increment.
+var incrByScript = redis.NewScript(`
+local value = redis.call('INCRBY', KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// IncrBy increments the integer value of a key, creating it with the ttl when it does not exist.
+func (s *RedisStore) IncrBy(key string, incr int64, ttl time.Duration) (int64, error) {
+	return incrByScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, incr, ttl.Milliseconds()).Int64()
+}
+
+// Ping checks that Redis is reachable.
+func (s *RedisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
 // Close closes the Redis client connection.
 func (s *RedisStore) Close() error {
 	return s.client.Close()
@@ -100,6 +196,41 @@ func (s *RedisStore) HIncrBy(key, field string, incr int64) (int64, error) {
 	return s.client.HIncrBy(context.Background(), s.prefixKey(key), field, incr).Result()
 }
 
+// hIncrByOnceScript increments a field of a hash only the first time its marker field is set.
+var hIncrByOnceScript = redis.NewScript(`
+if redis.call('HSETNX', KEYS[1], ARGV[1], 1) == 0 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[2], ARGV[3])
+return 1
+`)
+
+// HIncrByOnce increments a field of a hash in Redis while its marker field is not set yet.
+func (s *RedisStore) HIncrByOnce(key, marker, field string, incr int64) (bool, error) {
+	incremented, err := hIncrByOnceScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}, marker, field, incr).Int64()
+	return incremented == 1, err
+}
+
+// hPopAllScript reads a hash and deletes it atomically.
+var hPopAllScript = redis.NewScript(`
+local fields = redis.call('HGETALL', KEYS[1])
+redis.call('DEL', KEYS[1])
+return fields
+`)
+
+// HPopAll returns all fields of a hash in Redis and removes it.
+func (s *RedisStore) HPopAll(key string) (map[string]string, error) {
+	pairs, err := hPopAllScript.Run(context.Background(), s.client, []string{s.prefixKey(key)}).StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		fields[pairs[i]] = pairs[i+1]
+	}
+	return fields, nil
+}
+
 // --- LIST operations ---
 
 func (s *RedisStore) LPush(key string, values ...any) error {
@@ -127,6 +258,11 @@ func (s *RedisStore) LLen(key string) (int64, error) {
 	return s.client.LLen(context.Background(), s.prefixKey(key)).Result()
 }
 
+// LRange returns the elements of a list between start and stop, inclusive.
+func (s *RedisStore) LRange(key string, start, stop int64) ([]string, error) {
+	return s.client.LRange(context.Background(), s.prefixKey(key), start, stop).Result()
+}
+
 // --- SET operations ---
 
 func (s *RedisStore) SAdd(key string, members ...any) error {
@@ -137,6 +273,35 @@ func (s *RedisStore) SPopN(key string, count int64) ([]string, error) {
 	return s.client.SPopN(context.Background(), s.prefixKey(key), count).Result()
 }
 
+func (s *RedisStore) SRem(key string, members ...any) error {
+	return s.client.SRem(context.Background(), s.prefixKey(key), members...).Err()
+}
+
+// --- SORTED SET operations ---
+
+func (s *RedisStore) ZAdd(key string, members map[string]float64) error {
+	if len(members) == 0 {
+		return nil
+	}
+	zMembers := make([]redis.Z, 0, len(members))
+	for member, score := range members {
+		zMembers = append(zMembers, redis.Z{Score: score, Member: member})
+	}
+	return s.client.ZAdd(context.Background(), s.prefixKey(key), zMembers...).Err()
+}
+
+// ZRangeByScore returns the members with a score between min and max, inclusive, ordered by score.
+func (s *RedisStore) ZRangeByScore(key string, min, max float64) ([]string, error) {
+	return s.client.ZRangeByScore(context.Background(), s.prefixKey(key), &redis.ZRangeBy{
+		Min: strconv.FormatFloat(min, 'f', -1, 64),
+		Max: strconv.FormatFloat(max, 'f', -1, 64),
+	}).Result()
+}
+
+func (s *RedisStore) ZRem(key string, members ...any) error {
+	return s.client.ZRem(context.Background(), s.prefixKey(key), members...).Err()
+}
+
 // --- Pipeliner implementation ---
 
 type redisPipeliner struct {
@@ -0,0 +1,171 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Snapshot exports the GPT-Load keys of the current Redis database.
+func (s *RedisStore) Snapshot(prefixes []string) (*Snapshot, error) {
+	ctx := context.Background()
+	snapshot := &Snapshot{
+		Version:   SnapshotVersion,
+		CreatedAt: time.Now(),
+		Entries:   make([]SnapshotEntry, 0),
+	}
+
+	var cursor uint64
+	for {
+		keys, nextCursor, err := s.client.Scan(ctx, cursor, RedisKeyPrefix+"*", 1000).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan keys: %w", err)
+		}
+
+		for _, fullKey := range keys {
+			key := strings.TrimPrefix(fullKey, RedisKeyPrefix)
+			if !matchesAnyPrefix(key, prefixes) {
+				continue
+			}
+
+			entry, err := s.snapshotKey(ctx, fullKey)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil {
+				continue
+			}
+			entry.Key = key
+			snapshot.Entries = append(snapshot.Entries, *entry)
+		}
+
+		cursor = nextCursor
+		if cursor == 0 {
+			break
+		}
+	}
+
+	return snapshot, nil
+}
+
+// snapshotKey reads a single key, returning nil if it expired in the meantime.
+func (s *RedisStore) snapshotKey(ctx context.Context, fullKey string) (*SnapshotEntry, error) {
+	keyType, err := s.client.Type(ctx, fullKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get type of key %s: %w", fullKey, err)
+	}
+
+	entry := &SnapshotEntry{Type: keyType}
+	switch keyType {
+	case "none":
+		return nil, nil
+	case SnapshotTypeString:
+		entry.String, err = s.client.Get(ctx, fullKey).Bytes()
+	case SnapshotTypeHash:
+		entry.Hash, err = s.client.HGetAll(ctx, fullKey).Result()
+	case SnapshotTypeList:
+		entry.List, err = s.client.LRange(ctx, fullKey, 0, -1).Result()
+	case SnapshotTypeSet:
+		entry.Set, err = s.client.SMembers(ctx, fullKey).Result()
+	case SnapshotTypeZSet:
+		var members []redis.Z
+		members, err = s.client.ZRangeWithScores(ctx, fullKey, 0, -1).Result()
+		for _, m := range members {
+			entry.ZSet = append(entry.ZSet, ZMember{Member: fmt.Sprint(m.Member), Score: m.Score})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported type %s for key %s", keyType, fullKey)
+	}
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key %s: %w", fullKey, err)
+	}
+
+	ttl, err := s.client.PTTL(ctx, fullKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ttl of key %s: %w", fullKey, err)
+	}
+	if ttl > 0 {
+		entry.TTLMillis = ttl.Milliseconds()
+	}
+
+	return entry, nil
+}
+
+// Restore writes the snapshot into Redis, replacing keys that already exist.
+func (s *RedisStore) Restore(snapshot *Snapshot) error {
+	if snapshot.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", snapshot.Version)
+	}
+
+	ctx := context.Background()
+	const batchSize = 500
+	for i := 0; i < len(snapshot.Entries); i += batchSize {
+		end := min(i+batchSize, len(snapshot.Entries))
+
+		pipe := s.client.TxPipeline()
+		for _, entry := range snapshot.Entries[i:end] {
+			if err := queueRestore(ctx, pipe, s.prefixKey(entry.Key), entry); err != nil {
+				return err
+			}
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func queueRestore(ctx context.Context, pipe redis.Pipeliner, fullKey string, entry SnapshotEntry) error {
+	pipe.Del(ctx, fullKey)
+
+	switch entry.Type {
+	case SnapshotTypeString:
+		pipe.Set(ctx, fullKey, entry.String, 0)
+	case SnapshotTypeHash:
+		if len(entry.Hash) == 0 {
+			return nil
+		}
+		pipe.HSet(ctx, fullKey, entry.Hash)
+	case SnapshotTypeList:
+		if len(entry.List) == 0 {
+			return nil
+		}
+		values := make([]any, len(entry.List))
+		for i, v := range entry.List {
+			values[i] = v
+		}
+		pipe.RPush(ctx, fullKey, values...)
+	case SnapshotTypeSet:
+		if len(entry.Set) == 0 {
+			return nil
+		}
+		members := make([]any, len(entry.Set))
+		for i, m := range entry.Set {
+			members[i] = m
+		}
+		pipe.SAdd(ctx, fullKey, members...)
+	case SnapshotTypeZSet:
+		if len(entry.ZSet) == 0 {
+			return nil
+		}
+		members := make([]redis.Z, len(entry.ZSet))
+		for i, m := range entry.ZSet {
+			members[i] = redis.Z{Member: m.Member, Score: m.Score}
+		}
+		pipe.ZAdd(ctx, fullKey, members...)
+	default:
+		return fmt.Errorf("unsupported type %s for key %s", entry.Type, entry.Key)
+	}
+
+	if entry.TTLMillis > 0 {
+		pipe.PExpire(ctx, fullKey, time.Duration(entry.TTLMillis)*time.Millisecond)
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package store
+
+import (
+	"strings"
+	"time"
+)
+
+// SnapshotVersion is the format version written into snapshots.
+const SnapshotVersion = 1
+
+// Snapshot entry types.
+const (
+	SnapshotTypeString = "string"
+	SnapshotTypeHash   = "hash"
+	SnapshotTypeList   = "list"
+	SnapshotTypeSet    = "set"
+	SnapshotTypeZSet   = "zset"
+)
+
+// Snapshot is a portable copy of the runtime store state.
+type Snapshot struct {
+	Version   int             `json:"version"`
+	CreatedAt time.Time       `json:"created_at"`
+	Entries   []SnapshotEntry `json:"entries"`
+}
+
+// SnapshotEntry holds a single key. Only the field matching Type is set.
+// TTLMillis is the remaining time to live when the snapshot was taken, 0 for no expiry.
+type SnapshotEntry struct {
+	Key       string            `json:"key"`
+	Type      string            `json:"type"`
+	TTLMillis int64             `json:"ttl_ms,omitempty"`
+	String    []byte            `json:"string,omitempty"`
+	Hash      map[string]string `json:"hash,omitempty"`
+	List      []string          `json:"list,omitempty"`
+	Set       []string          `json:"set,omitempty"`
+	ZSet      []ZMember         `json:"zset,omitempty"`
+}
+
+// ZMember is a sorted set member with its score.
+type ZMember struct {
+	Member string  `json:"member"`
+	Score  float64 `json:"score"`
+}
+
+// Snapshotter is implemented by stores whose state can be exported and restored.
+type Snapshotter interface {
+	// Snapshot exports all keys whose name starts with one of the prefixes, or all keys if none are given.
+	Snapshot(prefixes []string) (*Snapshot, error)
+
+	// Restore writes the snapshot entries, replacing existing keys with the same name.
+	Restore(snapshot *Snapshot) error
+}
+
+func matchesAnyPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
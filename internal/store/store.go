@@ -1,13 +1,18 @@
 package store
 
 import (
+	"context"
 	"errors"
+	"iter"
 	"time"
 )
 
 // ErrNotFound is the error returned when a key is not found in the store.
 var ErrNotFound = errors.New("store: key not found")
 
+// ErrMemoryLimit is the error returned when a value does not fit under the memory store ceiling.
+var ErrMemoryLimit = errors.New("store: memory limit exceeded")
+
 // Message is the struct for received pub/sub messages.
 type Message struct {
 	Channel string
@@ -40,20 +45,51 @@ type Store interface {
 	// SetNX sets a key-value pair if the key does not already exist.
 	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
 
+	// CompareAndExpire resets the TTL of a key only while it holds value, and reports whether
+	// it did.
+	CompareAndExpire(key string, value []byte, ttl time.Duration) (bool, error)
+
+	// CompareAndDelete removes a key only while it holds value, and reports whether it did.
+	CompareAndDelete(key string, value []byte) (bool, error)
+
+	// IncrBy increments the integer value of a key and returns the new value. A missing key
+	// starts at 0 and expires after ttl, an existing key keeps its expiry.
+	IncrBy(key string, incr int64, ttl time.Duration) (int64, error)
+
+	// Keys returns the names of all keys starting with prefix.
+	Keys(prefix string) ([]string, error)
+
+	// Scan iterates over the names of the keys starting with prefix without collecting them
+	// first. Keys added or removed during the iteration may or may not be returned.
+	Scan(prefix string) iter.Seq2[string, error]
+
 	// HASH operations
 	HSet(key string, values map[string]any) error
 	HGetAll(key string) (map[string]string, error)
 	HIncrBy(key, field string, incr int64) (int64, error)
+	// HIncrByOnce increments a field of a hash by incr and sets the marker field, in one step
+	// and only while the marker is not set yet. It reports whether it incremented the field.
+	HIncrByOnce(key, marker, field string, incr int64) (bool, error)
+	// HPopAll returns all fields of a hash and removes it in one step, so that no field
+	// written meanwhile is lost.
+	HPopAll(key string) (map[string]string, error)
 
 	// LIST operations
 	LPush(key string, values ...any) error
 	LRem(key string, count int64, value any) error
 	Rotate(key string) (string, error)
 	LLen(key string) (int64, error)
+	LRange(key string, start, stop int64) ([]string, error)
 
 	// SET operations
 	SAdd(key string, members ...any) error
 	SPopN(key string, count int64) ([]string, error)
+	SRem(key string, members ...any) error
+
+	// SORTED SET operations
+	ZAdd(key string, members map[string]float64) error
+	ZRangeByScore(key string, min, max float64) ([]string, error)
+	ZRem(key string, members ...any) error
 
 	// Close closes the store and releases any underlying resources.
 	Close() error
@@ -68,6 +104,16 @@ type Store interface {
 	Clear() error
 }
 
+// Pinger is implemented by stores backed by a server whose connectivity can be checked.
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// KeySizer is implemented by stores that can report the approximate memory used by a key.
+type KeySizer interface {
+	KeySize(key string) (int64, error)
+}
+
 // Pipeliner defines an interface for executing a batch of commands.
 type Pipeliner interface {
 	HSet(key string, values map[string]any)
@@ -1,7 +1,9 @@
 package syncer
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
@@ -10,6 +12,30 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// Event types describe the change behind an invalidation.
+const (
+	EventReload           = "reload"
+	EventGroupCreated     = "group_created"
+	EventGroupUpdated     = "group_updated"
+	EventGroupDeleted     = "group_deleted"
+	EventGroupsReordered  = "groups_reordered"
+	EventSubGroupsChanged = "sub_groups_changed"
+	EventSettingsChanged  = "settings_changed"
+)
+
+// Event is the payload broadcast to all instances when a cached value changes.
+type Event struct {
+	Type    string `json:"type"`
+	GroupID uint   `json:"group_id,omitempty"`
+	Source  string `json:"source"`
+}
+
+// instanceID identifies this process as the source of the events it publishes.
+var instanceID = func() string {
+	hostname, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", hostname, os.Getpid())
+}()
+
 // LoaderFunc defines a generic function signature for loading data from the source of truth (e.g., database).
 type LoaderFunc[T any] func() (T, error)
 
@@ -62,8 +88,23 @@ func (s *CacheSyncer[T]) Get() T {
 
 // Invalidate publishes a notification to all instances to reload their cache.
 func (s *CacheSyncer[T]) Invalidate() error {
-	s.logger.Debug("publishing invalidation notification")
-	return s.store.Publish(s.channelName, []byte("reload"))
+	return s.InvalidateFor(EventReload, 0)
+}
+
+// InvalidateFor publishes a notification of the given change to all instances, this one
+// included, to reload their cache.
+func (s *CacheSyncer[T]) InvalidateFor(eventType string, groupID uint) error {
+	payload, err := json.Marshal(Event{Type: eventType, GroupID: groupID, Source: instanceID})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+	s.logger.WithField("event", eventType).Debug("publishing invalidation notification")
+	return s.store.Publish(s.channelName, payload)
+}
+
+// Reload reloads the cache of this instance only, without notifying the others.
+func (s *CacheSyncer[T]) Reload() error {
+	return s.reload()
 }
 
 // Stop gracefully shuts down the syncer's background goroutine.
@@ -99,6 +140,7 @@ func (s *CacheSyncer[T]) reload() error {
 func (s *CacheSyncer[T]) listenForUpdates() {
 	defer s.wg.Done()
 
+	resubscribing := false
 	for {
 		select {
 		case <-s.stopChan:
@@ -125,6 +167,14 @@ func (s *CacheSyncer[T]) listenForUpdates() {
 
 		s.logger.Debugf("subscribed to channel: %s", s.channelName)
 
+		// Notifications published while the subscription was down are lost, so catch up with a reload.
+		if resubscribing {
+			if err := s.reload(); err != nil {
+				s.logger.Errorf("failed to reload cache after re-subscribing: %v", err)
+			}
+		}
+		resubscribing = true
+
 	subscriberLoop:
 		for {
 			select {
@@ -133,7 +183,12 @@ func (s *CacheSyncer[T]) listenForUpdates() {
 					s.logger.Warn("subscription channel closed, attempting to re-subscribe...")
 					break subscriberLoop
 				}
-				s.logger.Debugf("received invalidation notification, payload: %s", string(msg.Payload))
+				event := decodeEvent(msg.Payload)
+				s.logger.WithFields(logrus.Fields{
+					"event":    event.Type,
+					"group_id": event.GroupID,
+					"source":   event.Source,
+				}).Debug("received invalidation notification")
 				if err := s.reload(); err != nil {
 					s.logger.Errorf("failed to reload cache after notification: %v", err)
 				}
@@ -158,3 +213,13 @@ func (s *CacheSyncer[T]) listenForUpdates() {
 		}
 	}
 }
+
+// decodeEvent parses an invalidation payload. Payloads of older instances are plain
+// "reload" strings and are treated as such.
+func decodeEvent(payload []byte) Event {
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil || event.Type == "" {
+		return Event{Type: EventReload}
+	}
+	return event
+}
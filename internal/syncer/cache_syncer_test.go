@@ -0,0 +1,57 @@
+package syncer
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gpt-load/internal/store"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestCacheSyncerReloadsOnEvent(t *testing.T) {
+	var loads atomic.Int32
+	reloaded := make(chan int32, 4)
+	loader := func() (int32, error) {
+		return loads.Add(1), nil
+	}
+
+	s, err := NewCacheSyncer(loader, store.NewMemoryStore(0), "test:updated", logrus.WithField("syncer", "test"), func(v int32) {
+		reloaded <- v
+	})
+	if err != nil {
+		t.Fatalf("NewCacheSyncer() error = %v", err)
+	}
+	defer s.Stop()
+	<-reloaded
+
+	// Wait for the listener to subscribe before publishing.
+	deadline := time.Now().Add(time.Second)
+	for {
+		if err := s.InvalidateFor(EventGroupUpdated, 3); err != nil {
+			t.Fatalf("InvalidateFor() error = %v", err)
+		}
+		select {
+		case v := <-reloaded:
+			if s.Get() != v {
+				t.Errorf("Get() = %d, want %d", s.Get(), v)
+			}
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("cache was not reloaded after the event")
+		}
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	if event := decodeEvent([]byte("reload")); event.Type != EventReload {
+		t.Errorf("decodeEvent(plain) = %+v", event)
+	}
+	event := decodeEvent([]byte(`{"type":"group_deleted","group_id":7,"source":"a-1"}`))
+	if event.Type != EventGroupDeleted || event.GroupID != 7 || event.Source != "a-1" {
+		t.Errorf("decodeEvent(json) = %+v", event)
+	}
+}
@@ -0,0 +1,129 @@
+// Package templates holds the catalog of group templates, presets that pre-fill a new group
+// for a well-known provider.
+package templates
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"gpt-load/internal/types"
+)
+
+//go:embed builtin.json
+var builtinTemplates []byte
+
+// Upstream is an upstream URL of a template.
+type Upstream struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// Template pre-fills the fields of a group created from it.
+type Template struct {
+	ID                 string     `json:"id"`
+	Name               string     `json:"name"`
+	Description        string     `json:"description"`
+	ChannelType        string     `json:"channel_type"`
+	Upstreams          []Upstream `json:"upstreams"`
+	TestModel          string     `json:"test_model"`
+	ValidationEndpoint string     `json:"validation_endpoint,omitempty"`
+	// Config is the recommended group config.
+	Config map[string]any `json:"config,omitempty"`
+}
+
+// Catalog holds the built-in templates and the ones loaded from GROUP_TEMPLATES_FILE.
+type Catalog struct {
+	templates []Template
+}
+
+// NewCatalog creates the catalog, failing when GROUP_TEMPLATES_FILE cannot be loaded.
+func NewCatalog(configManager types.ConfigManager) (*Catalog, error) {
+	return Load(configManager.GetGroupTemplatesFile())
+}
+
+// Load creates a catalog of the built-in templates extended with the templates of the JSON
+// file at path, if any. A template of the file replaces the built-in template with its ID.
+func Load(path string) (*Catalog, error) {
+	builtin, err := parse(builtinTemplates)
+	if err != nil {
+		return nil, fmt.Errorf("invalid built-in group templates: %w", err)
+	}
+	c := &Catalog{templates: builtin}
+	if path == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GROUP_TEMPLATES_FILE: %w", err)
+	}
+	custom, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GROUP_TEMPLATES_FILE %s: %w", path, err)
+	}
+	for _, template := range custom {
+		if i := c.index(template.ID); i >= 0 {
+			c.templates[i] = template
+		} else {
+			c.templates = append(c.templates, template)
+		}
+	}
+	return c, nil
+}
+
+func parse(data []byte) ([]Template, error) {
+	var templates []Template
+	if err := json.Unmarshal(data, &templates); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(templates))
+	for i := range templates {
+		template := &templates[i]
+		template.ID = strings.TrimSpace(template.ID)
+		if template.ID == "" {
+			return nil, fmt.Errorf("template #%d has no id", i+1)
+		}
+		if seen[template.ID] {
+			return nil, fmt.Errorf("duplicate template id %q", template.ID)
+		}
+		seen[template.ID] = true
+		if template.Name == "" || template.ChannelType == "" {
+			return nil, fmt.Errorf("template %q requires a name and a channel_type", template.ID)
+		}
+		if len(template.Upstreams) == 0 {
+			return nil, fmt.Errorf("template %q requires at least one upstream", template.ID)
+		}
+		for j := range template.Upstreams {
+			if template.Upstreams[j].Weight == 0 {
+				template.Upstreams[j].Weight = 1
+			}
+		}
+	}
+	return templates, nil
+}
+
+func (c *Catalog) index(id string) int {
+	for i := range c.templates {
+		if c.templates[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// All returns every template, the built-in ones first.
+func (c *Catalog) All() []Template {
+	return c.templates
+}
+
+// Get returns the template with the given ID.
+func (c *Catalog) Get(id string) (Template, bool) {
+	if i := c.index(id); i >= 0 {
+		return c.templates[i], true
+	}
+	return Template{}, false
+}
@@ -0,0 +1,46 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCustomTemplates(t *testing.T) {
+	builtin, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := builtin.Get("openai"); !ok {
+		t.Fatal("built-in catalog has no openai template")
+	}
+
+	path := filepath.Join(t.TempDir(), "templates.json")
+	custom := `[
+		{"id": "openai", "name": "OpenAI relay", "channel_type": "openai", "upstreams": [{"url": "https://relay.example.com"}]},
+		{"id": "local", "name": "Local vLLM", "channel_type": "openai", "upstreams": [{"url": "http://localhost:8000"}], "test_model": "qwen"}
+	]`
+	if err := os.WriteFile(path, []byte(custom), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	catalog, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog.All()) != len(builtin.All())+1 {
+		t.Fatalf("catalog has %d templates, want the built-in ones plus local", len(catalog.All()))
+	}
+	if openai, _ := catalog.Get("openai"); openai.Upstreams[0].URL != "https://relay.example.com" || openai.Upstreams[0].Weight != 1 {
+		t.Fatalf("openai template = %+v, want the one of the file with a default weight", openai)
+	}
+	if builtinOpenAI, _ := builtin.Get("openai"); builtinOpenAI.Upstreams[0].URL != "https://api.openai.com" {
+		t.Fatal("loading a file changed the built-in templates of another catalog")
+	}
+
+	if err := os.WriteFile(path, []byte(`[{"id": "broken", "name": "Broken", "channel_type": "openai"}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatal("Load accepted a template without upstreams")
+	}
+}
@@ -0,0 +1,392 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultMaxTokens is sent to Anthropic, which requires max_tokens, when the client sets none.
+const defaultMaxTokens = 4096
+
+// chatRequestToMessages converts an OpenAI chat completion request into an Anthropic messages
+// request: system and developer messages become the system prompt, tool calls tool_use blocks
+// and tool messages tool_result blocks.
+func chatRequestToMessages(body []byte) ([]byte, error) {
+	req, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]any{"max_tokens": defaultMaxTokens}
+	copyFields(out, req, map[string]string{"model": "model", "temperature": "temperature", "top_p": "top_p", "stream": "stream"})
+	if value, ok := req["max_completion_tokens"]; ok && value != nil {
+		out["max_tokens"] = value
+	} else if value, ok := req["max_tokens"]; ok && value != nil {
+		out["max_tokens"] = value
+	}
+	switch stop := req["stop"].(type) {
+	case string:
+		out["stop_sequences"] = []any{stop}
+	case []any:
+		out["stop_sequences"] = stop
+	}
+	if user, ok := req["user"].(string); ok && user != "" {
+		out["metadata"] = map[string]any{"user_id": user}
+	}
+
+	var system []string
+	var messages []map[string]any
+	appendBlocks := func(role string, blocks []any) {
+		if len(blocks) == 0 {
+			return
+		}
+		// Anthropic expects the roles to alternate, consecutive messages of a role are merged
+		if n := len(messages); n > 0 && messages[n-1]["role"] == role {
+			messages[n-1]["content"] = append(messages[n-1]["content"].([]any), blocks...)
+			return
+		}
+		messages = append(messages, map[string]any{"role": role, "content": blocks})
+	}
+
+	rawMessages, _ := req["messages"].([]any)
+	for i, item := range rawMessages {
+		message, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("messages[%d] is not an object", i)
+		}
+		switch role, _ := message["role"].(string); role {
+		case "system", "developer":
+			if text := textOf(message["content"]); text != "" {
+				system = append(system, text)
+			}
+		case "user":
+			blocks, err := chatContentToBlocks(message["content"])
+			if err != nil {
+				return nil, fmt.Errorf("messages[%d]: %w", i, err)
+			}
+			appendBlocks("user", blocks)
+		case "assistant":
+			blocks, err := chatContentToBlocks(message["content"])
+			if err != nil {
+				return nil, fmt.Errorf("messages[%d]: %w", i, err)
+			}
+			toolCalls, _ := message["tool_calls"].([]any)
+			for j, item := range toolCalls {
+				call, _ := item.(map[string]any)
+				function, _ := call["function"].(map[string]any)
+				id, _ := call["id"].(string)
+				name, _ := function["name"].(string)
+				arguments, _ := function["arguments"].(string)
+				input, err := decodeArguments(arguments)
+				if err != nil {
+					return nil, fmt.Errorf("messages[%d].tool_calls[%d]: invalid arguments: %w", i, j, err)
+				}
+				blocks = append(blocks, map[string]any{"type": "tool_use", "id": anthropicToolID(id), "name": name, "input": input})
+			}
+			appendBlocks("assistant", blocks)
+		case "tool":
+			id, _ := message["tool_call_id"].(string)
+			appendBlocks("user", []any{map[string]any{"type": "tool_result", "tool_use_id": anthropicToolID(id), "content": textOf(message["content"])}})
+		default:
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, role)
+		}
+	}
+	if len(system) > 0 {
+		out["system"] = strings.Join(system, "\n\n")
+	}
+	out["messages"] = messages
+
+	if tools, ok := req["tools"].([]any); ok && len(tools) > 0 {
+		converted := make([]any, 0, len(tools))
+		for i, item := range tools {
+			tool, _ := item.(map[string]any)
+			function, ok := tool["function"].(map[string]any)
+			if !ok || (tool["type"] != nil && tool["type"] != "function") {
+				return nil, fmt.Errorf("tools[%d]: only function tools are supported", i)
+			}
+			schema := function["parameters"]
+			if schema == nil {
+				schema = map[string]any{"type": "object", "properties": map[string]any{}}
+			}
+			tool = map[string]any{"name": function["name"], "input_schema": schema}
+			if description, ok := function["description"]; ok {
+				tool["description"] = description
+			}
+			converted = append(converted, tool)
+		}
+		out["tools"] = converted
+	}
+
+	var toolChoice map[string]any
+	switch choice := req["tool_choice"].(type) {
+	case string:
+		switch choice {
+		case "auto":
+			toolChoice = map[string]any{"type": "auto"}
+		case "none":
+			toolChoice = map[string]any{"type": "none"}
+		case "required":
+			toolChoice = map[string]any{"type": "any"}
+		}
+	case map[string]any:
+		function, _ := choice["function"].(map[string]any)
+		if name, ok := function["name"].(string); ok {
+			toolChoice = map[string]any{"type": "tool", "name": name}
+		}
+	}
+	if parallel, ok := req["parallel_tool_calls"].(bool); ok && !parallel && out["tools"] != nil {
+		if toolChoice == nil {
+			toolChoice = map[string]any{"type": "auto"}
+		}
+		if toolChoice["type"] != "none" {
+			toolChoice["disable_parallel_tool_use"] = true
+		}
+	}
+	if toolChoice != nil {
+		out["tool_choice"] = toolChoice
+	}
+
+	return json.Marshal(out)
+}
+
+// chatContentToBlocks converts the content of an OpenAI message, a string or a list of parts,
+// into Anthropic content blocks.
+func chatContentToBlocks(content any) ([]any, error) {
+	switch v := content.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		if v == "" {
+			return nil, nil
+		}
+		return []any{map[string]any{"type": "text", "text": v}}, nil
+	case []any:
+		blocks := make([]any, 0, len(v))
+		for i, item := range v {
+			part, _ := item.(map[string]any)
+			switch partType, _ := part["type"].(string); partType {
+			case "text":
+				if text, _ := part["text"].(string); text != "" {
+					blocks = append(blocks, map[string]any{"type": "text", "text": text})
+				}
+			case "refusal":
+				if text, _ := part["refusal"].(string); text != "" {
+					blocks = append(blocks, map[string]any{"type": "text", "text": text})
+				}
+			case "image_url":
+				image, _ := part["image_url"].(map[string]any)
+				url, _ := image["url"].(string)
+				if mediaType, data, ok := parseDataURL(url); ok {
+					blocks = append(blocks, map[string]any{"type": "image", "source": map[string]any{"type": "base64", "media_type": mediaType, "data": data}})
+				} else {
+					blocks = append(blocks, map[string]any{"type": "image", "source": map[string]any{"type": "url", "url": url}})
+				}
+			default:
+				return nil, fmt.Errorf("content[%d]: unsupported part type %q", i, partType)
+			}
+		}
+		return blocks, nil
+	}
+	return nil, fmt.Errorf("unsupported content")
+}
+
+// chatFinishReasons maps the stop reasons of Anthropic to OpenAI finish reasons.
+var chatFinishReasons = map[string]string{
+	"end_turn":      "stop",
+	"stop_sequence": "stop",
+	"pause_turn":    "stop",
+	"refusal":       "content_filter",
+	"max_tokens":    "length",
+	"tool_use":      "tool_calls",
+}
+
+func chatFinishReason(stopReason any) any {
+	reason, ok := stopReason.(string)
+	if !ok {
+		return nil
+	}
+	if mapped, ok := chatFinishReasons[reason]; ok {
+		return mapped
+	}
+	return "stop"
+}
+
+// chatUsage converts Anthropic usage, whose input tokens exclude those read from or written to
+// the prompt cache, into OpenAI usage.
+func chatUsage(usage map[string]any) map[string]any {
+	prompt := intOf(usage["input_tokens"]) + intOf(usage["cache_read_input_tokens"]) + intOf(usage["cache_creation_input_tokens"])
+	completion := intOf(usage["output_tokens"])
+	return map[string]any{"prompt_tokens": prompt, "completion_tokens": completion, "total_tokens": prompt + completion}
+}
+
+// messagesResponseToChat converts an Anthropic message into an OpenAI chat completion.
+func messagesResponseToChat(body []byte) ([]byte, error) {
+	message, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+	if message["type"] != "message" {
+		return nil, fmt.Errorf("response is not a message")
+	}
+
+	var texts, thinking []string
+	var toolCalls []any
+	content, _ := message["content"].([]any)
+	for _, item := range content {
+		block, _ := item.(map[string]any)
+		switch block["type"] {
+		case "text":
+			text, _ := block["text"].(string)
+			texts = append(texts, text)
+		case "thinking":
+			text, _ := block["thinking"].(string)
+			thinking = append(thinking, text)
+		case "tool_use":
+			arguments, err := encodeArguments(block["input"])
+			if err != nil {
+				return nil, err
+			}
+			toolCalls = append(toolCalls, map[string]any{"id": block["id"], "type": "function", "function": map[string]any{"name": block["name"], "arguments": arguments}})
+		}
+	}
+
+	result := map[string]any{"role": "assistant", "content": nil}
+	if len(texts) > 0 {
+		result["content"] = strings.Join(texts, "")
+	}
+	if len(thinking) > 0 {
+		result["reasoning_content"] = strings.Join(thinking, "")
+	}
+	if len(toolCalls) > 0 {
+		result["tool_calls"] = toolCalls
+	}
+
+	completion := map[string]any{
+		"id":      message["id"],
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   message["model"],
+		"choices": []any{map[string]any{"index": 0, "message": result, "finish_reason": chatFinishReason(message["stop_reason"])}},
+	}
+	if usage, ok := message["usage"].(map[string]any); ok {
+		completion["usage"] = chatUsage(usage)
+	}
+	return json.Marshal(completion)
+}
+
+// messagesToChatStream converts Anthropic stream events into OpenAI chat completion chunks.
+// Tool use blocks become tool calls numbered in order, their partial JSON input the deltas of
+// their arguments.
+type messagesToChatStream struct {
+	includeUsage bool
+	id           any
+	model        any
+	created      int64
+	toolCalls    map[int64]int
+	usage        map[string]any
+	stopReason   any
+	done         bool
+}
+
+func newMessagesToChatStream(clientRequest []byte) streamHandler {
+	s := &messagesToChatStream{created: time.Now().Unix(), toolCalls: make(map[int64]int), usage: make(map[string]any)}
+	if req, err := decodeObject(clientRequest); err == nil {
+		options, _ := req["stream_options"].(map[string]any)
+		s.includeUsage, _ = options["include_usage"].(bool)
+	}
+	return s
+}
+
+func (s *messagesToChatStream) chunk(delta map[string]any, finishReason any) map[string]any {
+	return map[string]any{
+		"id":      s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   s.model,
+		"choices": []any{map[string]any{"index": 0, "delta": delta, "finish_reason": finishReason}},
+	}
+}
+
+func (s *messagesToChatStream) event(_ string, data []byte, w *eventWriter) {
+	event, err := decodeObject(data)
+	if err != nil || s.done {
+		return
+	}
+	switch event["type"] {
+	case "message_start":
+		message, _ := event["message"].(map[string]any)
+		s.id, s.model = message["id"], message["model"]
+		if usage, ok := message["usage"].(map[string]any); ok {
+			for key, value := range usage {
+				s.usage[key] = value
+			}
+		}
+		w.event("", s.chunk(map[string]any{"role": "assistant", "content": ""}, nil))
+	case "content_block_start":
+		block, _ := event["content_block"].(map[string]any)
+		switch block["type"] {
+		case "tool_use":
+			index := len(s.toolCalls)
+			s.toolCalls[intOf(event["index"])] = index
+			w.event("", s.chunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index": index, "id": block["id"], "type": "function",
+				"function": map[string]any{"name": block["name"], "arguments": ""},
+			}}}, nil))
+		case "text":
+			if text, _ := block["text"].(string); text != "" {
+				w.event("", s.chunk(map[string]any{"content": text}, nil))
+			}
+		}
+	case "content_block_delta":
+		delta, _ := event["delta"].(map[string]any)
+		switch delta["type"] {
+		case "text_delta":
+			w.event("", s.chunk(map[string]any{"content": delta["text"]}, nil))
+		case "thinking_delta":
+			w.event("", s.chunk(map[string]any{"reasoning_content": delta["thinking"]}, nil))
+		case "input_json_delta":
+			index, ok := s.toolCalls[intOf(event["index"])]
+			if !ok {
+				return
+			}
+			w.event("", s.chunk(map[string]any{"tool_calls": []any{map[string]any{
+				"index": index, "function": map[string]any{"arguments": delta["partial_json"]},
+			}}}, nil))
+		}
+	case "message_delta":
+		delta, _ := event["delta"].(map[string]any)
+		s.stopReason = delta["stop_reason"]
+		if usage, ok := event["usage"].(map[string]any); ok {
+			for key, value := range usage {
+				s.usage[key] = value
+			}
+		}
+		w.event("", s.chunk(map[string]any{}, chatFinishReason(s.stopReason)))
+	case "message_stop":
+		s.end(w)
+	case "error":
+		upstreamError, _ := event["error"].(map[string]any)
+		w.event("", map[string]any{"error": map[string]any{"message": upstreamError["message"], "type": upstreamError["type"]}})
+	}
+}
+
+// end sends the usage, if the client asked for it, and the end of the stream.
+func (s *messagesToChatStream) end(w *eventWriter) {
+	if s.includeUsage {
+		chunk := s.chunk(nil, nil)
+		chunk["choices"] = []any{}
+		chunk["usage"] = chatUsage(s.usage)
+		w.event("", chunk)
+	}
+	w.raw("", []byte("[DONE]"))
+	s.done = true
+}
+
+// finish ends a stream cut off after its stop reason, which is complete but for message_stop.
+func (s *messagesToChatStream) finish(w *eventWriter) {
+	if !s.done && s.stopReason != nil {
+		s.end(w)
+	}
+}
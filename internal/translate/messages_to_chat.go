@@ -0,0 +1,352 @@
+package translate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// messagesRequestToChat converts an Anthropic messages request into an OpenAI chat completion
+// request: the system prompt becomes a system message, tool_use blocks tool calls and
+// tool_result blocks tool messages. Thinking blocks are dropped.
+func messagesRequestToChat(body []byte) ([]byte, error) {
+	req, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]any{}
+	copyFields(out, req, map[string]string{"model": "model", "max_tokens": "max_tokens", "temperature": "temperature", "top_p": "top_p", "stop_sequences": "stop"})
+	if stream, _ := req["stream"].(bool); stream {
+		out["stream"] = true
+		out["stream_options"] = map[string]any{"include_usage": true}
+	}
+	if metadata, ok := req["metadata"].(map[string]any); ok {
+		if user, ok := metadata["user_id"].(string); ok && user != "" {
+			out["user"] = user
+		}
+	}
+
+	var messages []any
+	if system := textOf(req["system"]); system != "" {
+		messages = append(messages, map[string]any{"role": "system", "content": system})
+	}
+
+	rawMessages, _ := req["messages"].([]any)
+	for i, item := range rawMessages {
+		message, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("messages[%d] is not an object", i)
+		}
+		role, _ := message["role"].(string)
+		if role != "user" && role != "assistant" {
+			return nil, fmt.Errorf("messages[%d]: unsupported role %q", i, role)
+		}
+		if text, ok := message["content"].(string); ok {
+			messages = append(messages, map[string]any{"role": role, "content": text})
+			continue
+		}
+		blocks, _ := message["content"].([]any)
+
+		if role == "assistant" {
+			var texts []string
+			var toolCalls []any
+			for _, item := range blocks {
+				block, _ := item.(map[string]any)
+				switch block["type"] {
+				case "text":
+					text, _ := block["text"].(string)
+					texts = append(texts, text)
+				case "tool_use":
+					arguments, err := encodeArguments(block["input"])
+					if err != nil {
+						return nil, fmt.Errorf("messages[%d]: %w", i, err)
+					}
+					toolCalls = append(toolCalls, map[string]any{"id": block["id"], "type": "function", "function": map[string]any{"name": block["name"], "arguments": arguments}})
+				case "thinking", "redacted_thinking":
+				default:
+					return nil, fmt.Errorf("messages[%d]: unsupported block type %q", i, block["type"])
+				}
+			}
+			converted := map[string]any{"role": "assistant", "content": nil}
+			if len(texts) > 0 {
+				converted["content"] = strings.Join(texts, "")
+			}
+			if len(toolCalls) > 0 {
+				converted["tool_calls"] = toolCalls
+			}
+			messages = append(messages, converted)
+			continue
+		}
+
+		// Tool results answer the tool calls of the previous assistant message, so they come
+		// first, as tool messages, followed by the rest of the user content
+		var parts []any
+		for _, item := range blocks {
+			block, _ := item.(map[string]any)
+			switch block["type"] {
+			case "tool_result":
+				content := textOf(block["content"])
+				if isError, _ := block["is_error"].(bool); isError && content == "" {
+					content = "error"
+				}
+				messages = append(messages, map[string]any{"role": "tool", "tool_call_id": block["tool_use_id"], "content": content})
+			case "text":
+				parts = append(parts, map[string]any{"type": "text", "text": block["text"]})
+			case "image":
+				source, _ := block["source"].(map[string]any)
+				url, _ := source["url"].(string)
+				if source["type"] == "base64" {
+					url = fmt.Sprintf("data:%s;base64,%s", source["media_type"], source["data"])
+				}
+				parts = append(parts, map[string]any{"type": "image_url", "image_url": map[string]any{"url": url}})
+			default:
+				return nil, fmt.Errorf("messages[%d]: unsupported block type %q", i, block["type"])
+			}
+		}
+		if len(parts) > 0 {
+			messages = append(messages, map[string]any{"role": "user", "content": parts})
+		}
+	}
+	out["messages"] = messages
+
+	if tools, ok := req["tools"].([]any); ok && len(tools) > 0 {
+		converted := make([]any, 0, len(tools))
+		for i, item := range tools {
+			tool, _ := item.(map[string]any)
+			if toolType, _ := tool["type"].(string); toolType != "" && toolType != "custom" {
+				return nil, fmt.Errorf("tools[%d]: server tool %q is not supported", i, toolType)
+			}
+			function := map[string]any{"name": tool["name"], "parameters": tool["input_schema"]}
+			if description, ok := tool["description"]; ok {
+				function["description"] = description
+			}
+			converted = append(converted, map[string]any{"type": "function", "function": function})
+		}
+		out["tools"] = converted
+	}
+
+	if choice, ok := req["tool_choice"].(map[string]any); ok {
+		switch choice["type"] {
+		case "auto":
+			out["tool_choice"] = "auto"
+		case "any":
+			out["tool_choice"] = "required"
+		case "none":
+			out["tool_choice"] = "none"
+		case "tool":
+			out["tool_choice"] = map[string]any{"type": "function", "function": map[string]any{"name": choice["name"]}}
+		}
+		if disable, _ := choice["disable_parallel_tool_use"].(bool); disable && out["tools"] != nil {
+			out["parallel_tool_calls"] = false
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// messagesStopReasons maps OpenAI finish reasons to the stop reasons of Anthropic.
+var messagesStopReasons = map[string]string{
+	"stop":           "end_turn",
+	"length":         "max_tokens",
+	"tool_calls":     "tool_use",
+	"function_call":  "tool_use",
+	"content_filter": "refusal",
+}
+
+func messagesStopReason(finishReason any) any {
+	reason, ok := finishReason.(string)
+	if !ok {
+		return nil
+	}
+	if mapped, ok := messagesStopReasons[reason]; ok {
+		return mapped
+	}
+	return "end_turn"
+}
+
+// messagesUsage converts OpenAI usage into Anthropic usage.
+func messagesUsage(usage map[string]any) map[string]any {
+	return map[string]any{"input_tokens": intOf(usage["prompt_tokens"]), "output_tokens": intOf(usage["completion_tokens"])}
+}
+
+// chatResponseToMessages converts an OpenAI chat completion into an Anthropic message, from its
+// first choice.
+func chatResponseToMessages(body []byte) ([]byte, error) {
+	completion, err := decodeObject(body)
+	if err != nil {
+		return nil, err
+	}
+	choices, _ := completion["choices"].([]any)
+	if completion["object"] != "chat.completion" || len(choices) == 0 {
+		return nil, fmt.Errorf("response is not a chat completion")
+	}
+	choice, _ := choices[0].(map[string]any)
+	message, _ := choice["message"].(map[string]any)
+
+	content := []any{}
+	if text := textOf(message["content"]); text != "" {
+		content = append(content, map[string]any{"type": "text", "text": text})
+	}
+	toolCalls, _ := message["tool_calls"].([]any)
+	for _, item := range toolCalls {
+		call, _ := item.(map[string]any)
+		function, _ := call["function"].(map[string]any)
+		arguments, _ := function["arguments"].(string)
+		input, err := decodeArguments(arguments)
+		if err != nil {
+			return nil, fmt.Errorf("tool call arguments are not JSON: %w", err)
+		}
+		id, _ := call["id"].(string)
+		content = append(content, map[string]any{"type": "tool_use", "id": anthropicToolID(id), "name": function["name"], "input": input})
+	}
+
+	result := map[string]any{
+		"id":            completion["id"],
+		"type":          "message",
+		"role":          "assistant",
+		"model":         completion["model"],
+		"content":       content,
+		"stop_reason":   messagesStopReason(choice["finish_reason"]),
+		"stop_sequence": nil,
+		"usage":         map[string]any{"input_tokens": 0, "output_tokens": 0},
+	}
+	if usage, ok := completion["usage"].(map[string]any); ok {
+		result["usage"] = messagesUsage(usage)
+	}
+	return json.Marshal(result)
+}
+
+// chatToMessagesStream converts OpenAI chat completion chunks into Anthropic stream events.
+// Text, reasoning and each tool call get their own content block, opened when their first
+// delta arrives; the deltas of a tool call's arguments become partial JSON input.
+type chatToMessagesStream struct {
+	started    bool
+	done       bool
+	nextBlock  int
+	openBlock  int
+	openType   string
+	toolBlocks map[int64]int
+	stopReason any
+	usage      map[string]any
+}
+
+func newChatToMessagesStream([]byte) streamHandler {
+	return &chatToMessagesStream{openBlock: -1, toolBlocks: make(map[int64]int), usage: map[string]any{}}
+}
+
+func (s *chatToMessagesStream) start(chunk map[string]any, w *eventWriter) {
+	if s.started {
+		return
+	}
+	s.started = true
+	w.event("message_start", map[string]any{"type": "message_start", "message": map[string]any{
+		"id": chunk["id"], "type": "message", "role": "assistant", "model": chunk["model"],
+		"content": []any{}, "stop_reason": nil, "stop_sequence": nil,
+		"usage": map[string]any{"input_tokens": 0, "output_tokens": 0},
+	}})
+}
+
+// openContent opens a block for text or reasoning, unless one of that type is open.
+func (s *chatToMessagesStream) openContent(blockType string, w *eventWriter) {
+	if s.openType == blockType {
+		return
+	}
+	s.closeBlock(w)
+	block := map[string]any{"type": blockType, blockType: ""}
+	s.openBlock, s.openType = s.nextBlock, blockType
+	s.nextBlock++
+	w.event("content_block_start", map[string]any{"type": "content_block_start", "index": s.openBlock, "content_block": block})
+}
+
+func (s *chatToMessagesStream) closeBlock(w *eventWriter) {
+	if s.openBlock < 0 {
+		return
+	}
+	w.event("content_block_stop", map[string]any{"type": "content_block_stop", "index": s.openBlock})
+	s.openBlock, s.openType = -1, ""
+}
+
+func (s *chatToMessagesStream) event(_ string, data []byte, w *eventWriter) {
+	if s.done {
+		return
+	}
+	if strings.TrimSpace(string(data)) == "[DONE]" {
+		s.end(w)
+		return
+	}
+	chunk, err := decodeObject(data)
+	if err != nil {
+		return
+	}
+	if upstreamError, ok := chunk["error"].(map[string]any); ok {
+		w.event("error", map[string]any{"type": "error", "error": map[string]any{"type": "api_error", "message": upstreamError["message"]}})
+		return
+	}
+	s.start(chunk, w)
+	if usage, ok := chunk["usage"].(map[string]any); ok {
+		s.usage = messagesUsage(usage)
+	}
+
+	choices, _ := chunk["choices"].([]any)
+	if len(choices) == 0 {
+		return
+	}
+	choice, _ := choices[0].(map[string]any)
+	delta, _ := choice["delta"].(map[string]any)
+
+	if reasoning, _ := delta["reasoning_content"].(string); reasoning != "" {
+		s.openContent("thinking", w)
+		w.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": s.openBlock, "delta": map[string]any{"type": "thinking_delta", "thinking": reasoning}})
+	}
+	if text, _ := delta["content"].(string); text != "" {
+		s.openContent("text", w)
+		w.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": s.openBlock, "delta": map[string]any{"type": "text_delta", "text": text}})
+	}
+	toolCalls, _ := delta["tool_calls"].([]any)
+	for _, item := range toolCalls {
+		call, _ := item.(map[string]any)
+		function, _ := call["function"].(map[string]any)
+		toolIndex := intOf(call["index"])
+		block, ok := s.toolBlocks[toolIndex]
+		if !ok {
+			s.closeBlock(w)
+			id, _ := call["id"].(string)
+			block = s.nextBlock
+			s.nextBlock++
+			s.toolBlocks[toolIndex] = block
+			s.openBlock, s.openType = block, "tool_use"
+			w.event("content_block_start", map[string]any{"type": "content_block_start", "index": block, "content_block": map[string]any{
+				"type": "tool_use", "id": anthropicToolID(id), "name": function["name"], "input": map[string]any{},
+			}})
+		}
+		if arguments, _ := function["arguments"].(string); arguments != "" {
+			w.event("content_block_delta", map[string]any{"type": "content_block_delta", "index": block, "delta": map[string]any{"type": "input_json_delta", "partial_json": arguments}})
+		}
+	}
+	if reason := choice["finish_reason"]; reason != nil {
+		s.stopReason = messagesStopReason(reason)
+	}
+}
+
+// end closes the open block and sends the stop reason, the usage and the end of the message.
+func (s *chatToMessagesStream) end(w *eventWriter) {
+	if !s.started {
+		s.start(map[string]any{}, w)
+	}
+	s.closeBlock(w)
+	stopReason := s.stopReason
+	if stopReason == nil {
+		stopReason = "end_turn"
+	}
+	w.event("message_delta", map[string]any{"type": "message_delta", "delta": map[string]any{"stop_reason": stopReason, "stop_sequence": nil}, "usage": s.usage})
+	w.event("message_stop", map[string]any{"type": "message_stop"})
+	s.done = true
+}
+
+// finish ends a stream whose upstream stopped without [DONE] after its finish reason.
+func (s *chatToMessagesStream) finish(w *eventWriter) {
+	if !s.done && s.stopReason != nil {
+		s.end(w)
+	}
+}
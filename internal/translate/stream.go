@@ -0,0 +1,105 @@
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// StreamConverter converts a server-sent event stream as it arrives. Events may be split
+// across writes: incomplete lines are kept until the rest of them comes.
+type StreamConverter struct {
+	handler streamHandler
+	pending []byte
+	event   string
+	data    []string
+	out     eventWriter
+}
+
+// streamHandler converts the events of one format into those of the other.
+type streamHandler interface {
+	// event handles an upstream event, named unless name is empty
+	event(name string, data []byte, w *eventWriter)
+	// finish ends the client stream once the upstream stream is over
+	finish(w *eventWriter)
+}
+
+// Write feeds upstream stream data to the converter and returns the client events it
+// completes, possibly none.
+func (s *StreamConverter) Write(chunk []byte) []byte {
+	s.pending = append(s.pending, chunk...)
+	for {
+		end := bytes.IndexByte(s.pending, '\n')
+		if end < 0 {
+			break
+		}
+		line := strings.TrimSuffix(string(s.pending[:end]), "\r")
+		s.pending = s.pending[end+1:]
+		s.line(line)
+	}
+	return s.out.take()
+}
+
+// Close ends the stream and returns the last client events.
+func (s *StreamConverter) Close() []byte {
+	if len(s.pending) > 0 {
+		s.line(strings.TrimSuffix(string(s.pending), "\r"))
+		s.pending = nil
+	}
+	s.dispatch()
+	s.handler.finish(&s.out)
+	return s.out.take()
+}
+
+func (s *StreamConverter) line(line string) {
+	switch {
+	case line == "":
+		s.dispatch()
+	case strings.HasPrefix(line, ":"):
+		// Comments keep connections alive and carry nothing
+	case strings.HasPrefix(line, "event:"):
+		s.event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+	case strings.HasPrefix(line, "data:"):
+		s.data = append(s.data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+	}
+}
+
+func (s *StreamConverter) dispatch() {
+	if len(s.data) > 0 {
+		s.handler.event(s.event, []byte(strings.Join(s.data, "\n")), &s.out)
+	}
+	s.event, s.data = "", nil
+}
+
+// eventWriter builds the events of the client stream.
+type eventWriter struct {
+	buf bytes.Buffer
+}
+
+// event appends an event with a JSON payload, named unless name is empty.
+func (w *eventWriter) event(name string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	w.raw(name, data)
+}
+
+// raw appends an event with data as it is.
+func (w *eventWriter) raw(name string, data []byte) {
+	if name != "" {
+		w.buf.WriteString("event: " + name + "\n")
+	}
+	w.buf.WriteString("data: ")
+	w.buf.Write(data)
+	w.buf.WriteString("\n\n")
+}
+
+func (w *eventWriter) take() []byte {
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	out := bytes.Clone(w.buf.Bytes())
+	w.buf.Reset()
+	return out
+}
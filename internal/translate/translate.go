@@ -0,0 +1,175 @@
+// Package translate converts requests, responses and streams between the OpenAI chat
+// completions and the Anthropic messages formats, tool calls included, so that clients of
+// either format can use a group whose upstream speaks the other.
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Endpoints of the formats, relative to the group.
+const (
+	ChatCompletionsPath = "/v1/chat/completions"
+	MessagesPath        = "/v1/messages"
+)
+
+// Translation converts the exchanges of clients of one format with upstreams of another.
+type Translation struct {
+	// Name identifies the translation in logs
+	Name string
+	// ClientPath is the endpoint the client calls, UpstreamPath the one it is sent to
+	ClientPath   string
+	UpstreamPath string
+
+	request   func(body []byte) ([]byte, error)
+	response  func(body []byte) ([]byte, error)
+	newStream func(clientRequest []byte) streamHandler
+}
+
+// ChatToMessages serves OpenAI chat completion clients from Anthropic messages upstreams.
+var ChatToMessages = &Translation{
+	Name:         "chat_to_messages",
+	ClientPath:   ChatCompletionsPath,
+	UpstreamPath: MessagesPath,
+	request:      chatRequestToMessages,
+	response:     messagesResponseToChat,
+	newStream:    newMessagesToChatStream,
+}
+
+// MessagesToChat serves Anthropic messages clients from OpenAI chat completion upstreams.
+var MessagesToChat = &Translation{
+	Name:         "messages_to_chat",
+	ClientPath:   MessagesPath,
+	UpstreamPath: ChatCompletionsPath,
+	request:      messagesRequestToChat,
+	response:     chatResponseToMessages,
+	newStream:    newChatToMessagesStream,
+}
+
+// Request converts a client request body into the upstream format.
+func (t *Translation) Request(body []byte) ([]byte, error) {
+	return t.request(body)
+}
+
+// Response converts a complete upstream response body into the client format.
+func (t *Translation) Response(body []byte) ([]byte, error) {
+	return t.response(body)
+}
+
+// NewStream returns a converter of the upstream event stream of a request into the events of
+// the client format. clientRequest is the request body the client sent.
+func (t *Translation) NewStream(clientRequest []byte) *StreamConverter {
+	return &StreamConverter{handler: t.newStream(clientRequest)}
+}
+
+// decodeObject decodes a JSON object keeping its numbers as they are.
+func decodeObject(body []byte) (map[string]any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var data map[string]any
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("invalid JSON body: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("body is not a JSON object")
+	}
+	return data, nil
+}
+
+// decodeArguments decodes the JSON arguments of a tool call, empty meaning no arguments.
+func decodeArguments(arguments string) (any, error) {
+	if strings.TrimSpace(arguments) == "" {
+		return map[string]any{}, nil
+	}
+	decoder := json.NewDecoder(strings.NewReader(arguments))
+	decoder.UseNumber()
+	var input any
+	if err := decoder.Decode(&input); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// encodeArguments encodes the input of a tool use as the JSON arguments of a tool call.
+func encodeArguments(input any) (string, error) {
+	if input == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// invalidToolIDChars are the characters Anthropic does not accept in tool use IDs.
+var invalidToolIDChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// anthropicToolID maps a tool call ID to one Anthropic accepts. The mapping is the same for a
+// tool call and the result referencing it, so that they keep matching.
+func anthropicToolID(id string) string {
+	return invalidToolIDChars.ReplaceAllString(id, "_")
+}
+
+// copyFields copies the fields present in from to to, renamed as mapped.
+func copyFields(to, from map[string]any, fields map[string]string) {
+	for source, target := range fields {
+		if value, ok := from[source]; ok && value != nil {
+			to[target] = value
+		}
+	}
+}
+
+// textOf joins the text of a content that is a string or a list of text blocks or parts.
+func textOf(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		var texts []string
+		for _, item := range v {
+			block, _ := item.(map[string]any)
+			if text, ok := block["text"].(string); ok {
+				texts = append(texts, text)
+			} else if refusal, ok := block["refusal"].(string); ok {
+				texts = append(texts, refusal)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// parseDataURL splits a base64 data URL into its media type and data.
+func parseDataURL(url string) (mediaType, data string, ok bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+	mediaType, ok = strings.CutSuffix(meta, ";base64")
+	return mediaType, data, ok
+}
+
+// intOf reads an integer JSON number, 0 when absent.
+func intOf(value any) int64 {
+	switch v := value.(type) {
+	case json.Number:
+		n, _ := v.Int64()
+		return n
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
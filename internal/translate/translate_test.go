@@ -0,0 +1,304 @@
+package translate
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, data []byte) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("invalid JSON %s: %v", data, err)
+	}
+	return out
+}
+
+// events parses a server-sent event stream into its events, data decoded unless it is [DONE].
+func events(t *testing.T, stream string) []map[string]any {
+	t.Helper()
+	var out []map[string]any
+	for _, block := range strings.Split(strings.TrimSpace(stream), "\n\n") {
+		event := map[string]any{}
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event["event"] = name
+			} else if data, ok := strings.CutPrefix(line, "data: "); ok {
+				if data == "[DONE]" {
+					event["done"] = true
+				} else {
+					event["data"] = decode(t, []byte(data))
+				}
+			}
+		}
+		out = append(out, event)
+	}
+	return out
+}
+
+func TestChatRequestToMessages(t *testing.T) {
+	body := `{
+		"model": "claude", "max_tokens": 100, "stop": "END", "parallel_tool_calls": false,
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Weather in Paris and Rome?"},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call.1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}},
+				{"id": "call.2", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Rome\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "call.1", "content": "sunny"},
+			{"role": "tool", "tool_call_id": "call.2", "content": "rainy"}
+		],
+		"tools": [{"type": "function", "function": {"name": "weather", "description": "Get the weather", "parameters": {"type": "object"}}}],
+		"tool_choice": "required"
+	}`
+	out, err := ChatToMessages.Request([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decode(t, []byte(`{
+		"model": "claude", "max_tokens": 100, "stop_sequences": ["END"], "system": "Be brief.",
+		"messages": [
+			{"role": "user", "content": [{"type": "text", "text": "Weather in Paris and Rome?"}]},
+			{"role": "assistant", "content": [
+				{"type": "tool_use", "id": "call_1", "name": "weather", "input": {"city": "Paris"}},
+				{"type": "tool_use", "id": "call_2", "name": "weather", "input": {"city": "Rome"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "call_1", "content": "sunny"},
+				{"type": "tool_result", "tool_use_id": "call_2", "content": "rainy"}
+			]}
+		],
+		"tools": [{"name": "weather", "description": "Get the weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "any", "disable_parallel_tool_use": true}
+	}`))
+	if got := decode(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("Request() = %s", out)
+	}
+
+	if _, err := ChatToMessages.Request([]byte(`{"messages":[{"role":"assistant","tool_calls":[{"id":"a","function":{"name":"f","arguments":"{"}}]}]}`)); err == nil {
+		t.Error("Request() accepted invalid tool call arguments")
+	}
+}
+
+func TestMessagesRequestToChat(t *testing.T) {
+	body := `{
+		"model": "gpt", "max_tokens": 100, "stream": true, "system": [{"type": "text", "text": "Be brief."}],
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Let me check."},
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": [{"type": "text", "text": "sunny"}]},
+				{"type": "text", "text": "Thanks"}
+			]}
+		],
+		"tools": [{"name": "weather", "description": "Get the weather", "input_schema": {"type": "object"}}],
+		"tool_choice": {"type": "tool", "name": "weather", "disable_parallel_tool_use": true}
+	}`
+	out, err := MessagesToChat.Request([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decode(t, []byte(`{
+		"model": "gpt", "max_tokens": 100, "stream": true, "stream_options": {"include_usage": true},
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": "Checking.", "tool_calls": [
+				{"id": "toolu_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "tool_call_id": "toolu_1", "content": "sunny"},
+			{"role": "user", "content": [{"type": "text", "text": "Thanks"}]}
+		],
+		"tools": [{"type": "function", "function": {"name": "weather", "description": "Get the weather", "parameters": {"type": "object"}}}],
+		"tool_choice": {"type": "function", "function": {"name": "weather"}},
+		"parallel_tool_calls": false
+	}`))
+	if got := decode(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("Request() = %s", out)
+	}
+
+	if _, err := MessagesToChat.Request([]byte(`{"messages":[],"tools":[{"type":"web_search_20250305","name":"web_search"}]}`)); err == nil {
+		t.Error("Request() accepted a server tool")
+	}
+}
+
+func TestMessagesResponseToChat(t *testing.T) {
+	body := `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude",
+		"content": [
+			{"type": "text", "text": "Checking."},
+			{"type": "tool_use", "id": "toolu_1", "name": "weather", "input": {"city": "Paris"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "cache_read_input_tokens": 5, "output_tokens": 7}
+	}`
+	out, err := ChatToMessages.Response([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decode(t, out)
+	choice := got["choices"].([]any)[0].(map[string]any)
+	wantChoice := decode(t, []byte(`{"index": 0, "finish_reason": "tool_calls", "message": {
+		"role": "assistant", "content": "Checking.",
+		"tool_calls": [{"id": "toolu_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}]
+	}}`))
+	if !reflect.DeepEqual(choice, wantChoice) {
+		t.Errorf("choice = %v", choice)
+	}
+	if usage := got["usage"].(map[string]any); usage["prompt_tokens"] != 15.0 || usage["total_tokens"] != 22.0 {
+		t.Errorf("usage = %v", usage)
+	}
+
+	if _, err := ChatToMessages.Response([]byte(`{"type":"error","error":{"message":"overloaded"}}`)); err == nil {
+		t.Error("Response() accepted an error")
+	}
+}
+
+func TestChatResponseToMessages(t *testing.T) {
+	body := `{
+		"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt",
+		"choices": [{"index": 0, "finish_reason": "tool_calls", "message": {
+			"role": "assistant", "content": null,
+			"tool_calls": [{"id": "call_1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}]
+		}}],
+		"usage": {"prompt_tokens": 10, "completion_tokens": 7, "total_tokens": 17}
+	}`
+	out, err := MessagesToChat.Response([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := decode(t, []byte(`{
+		"id": "chatcmpl-1", "type": "message", "role": "assistant", "model": "gpt",
+		"content": [{"type": "tool_use", "id": "call_1", "name": "weather", "input": {"city": "Paris"}}],
+		"stop_reason": "tool_use", "stop_sequence": null,
+		"usage": {"input_tokens": 10, "output_tokens": 7}
+	}`))
+	if got := decode(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("Response() = %s", out)
+	}
+}
+
+// feed writes a stream to a converter in chunks of size bytes, splitting lines and events.
+func feed(converter *StreamConverter, stream string, size int) string {
+	var out strings.Builder
+	for start := 0; start < len(stream); start += size {
+		end := min(start+size, len(stream))
+		out.Write(converter.Write([]byte(stream[start:end])))
+	}
+	out.Write(converter.Close())
+	return out.String()
+}
+
+func TestMessagesToChatStream(t *testing.T) {
+	stream := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"id":"msg_1","model":"claude","usage":{"input_tokens":10,"output_tokens":1}}}` + "\n\n" +
+		"event: content_block_start\n" +
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Checking."}}` + "\n\n" +
+		`data: {"type":"content_block_stop","index":0}` + "\n\n" +
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"weather","input":{}}}` + "\n\n" +
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}` + "\n\n" +
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}` + "\n\n" +
+		`data: {"type":"content_block_stop","index":1}` + "\n\n" +
+		"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+		`data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":7}}` + "\n\n" +
+		`data: {"type":"message_stop"}` + "\n\n"
+
+	converter := ChatToMessages.NewStream([]byte(`{"stream":true,"stream_options":{"include_usage":true}}`))
+	got := events(t, feed(converter, stream, 7))
+	if len(got) != 8 {
+		t.Fatalf("got %d events: %v", len(got), got)
+	}
+	delta := func(i int) map[string]any {
+		return got[i]["data"].(map[string]any)["choices"].([]any)[0].(map[string]any)["delta"].(map[string]any)
+	}
+	if delta(0)["role"] != "assistant" || delta(1)["content"] != "Checking." {
+		t.Errorf("unexpected text chunks: %v %v", delta(0), delta(1))
+	}
+	start := delta(2)["tool_calls"].([]any)[0].(map[string]any)
+	if start["index"] != 0.0 || start["id"] != "toolu_1" || start["function"].(map[string]any)["name"] != "weather" {
+		t.Errorf("tool call start = %v", start)
+	}
+	var arguments string
+	for _, i := range []int{3, 4} {
+		call := delta(i)["tool_calls"].([]any)[0].(map[string]any)
+		arguments += call["function"].(map[string]any)["arguments"].(string)
+	}
+	if arguments != `{"city":"Paris"}` {
+		t.Errorf("arguments = %q", arguments)
+	}
+	if reason := got[5]["data"].(map[string]any)["choices"].([]any)[0].(map[string]any)["finish_reason"]; reason != "tool_calls" {
+		t.Errorf("finish_reason = %v", reason)
+	}
+	if usage := got[6]["data"].(map[string]any)["usage"].(map[string]any); usage["prompt_tokens"] != 10.0 || usage["completion_tokens"] != 7.0 {
+		t.Errorf("usage = %v", usage)
+	}
+	if got[7]["done"] != true {
+		t.Errorf("last event = %v", got[7])
+	}
+}
+
+func TestChatToMessagesStream(t *testing.T) {
+	stream := `data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"role":"assistant","content":"Checking."}}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]}}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":"}}]}}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"Paris\"}"}}]}}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"weather","arguments":"{\"city\":\"Rome\"}"}}]}}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}` + "\n\n" +
+		`data: {"id":"c1","model":"gpt","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":7}}` + "\n\n" +
+		"data: [DONE]\n\n"
+
+	got := events(t, feed(MessagesToChat.NewStream(nil), stream, 5))
+	var names []string
+	arguments := map[float64]string{}
+	tools := map[float64]string{}
+	for _, event := range got {
+		names = append(names, event["event"].(string))
+		data := event["data"].(map[string]any)
+		if block, ok := data["content_block"].(map[string]any); ok && block["type"] == "tool_use" {
+			tools[data["index"].(float64)] = block["id"].(string)
+		}
+		if delta, ok := data["delta"].(map[string]any); ok && delta["type"] == "input_json_delta" {
+			arguments[data["index"].(float64)] += delta["partial_json"].(string)
+		}
+		if event["event"] == "message_delta" {
+			if reason := data["delta"].(map[string]any)["stop_reason"]; reason != "tool_use" {
+				t.Errorf("stop_reason = %v", reason)
+			}
+			if usage := data["usage"].(map[string]any); usage["output_tokens"] != 7.0 {
+				t.Errorf("usage = %v", usage)
+			}
+		}
+	}
+	wantNames := []string{
+		"message_start",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_delta", "content_block_stop",
+		"content_block_start", "content_block_delta", "content_block_stop",
+		"message_delta", "message_stop",
+	}
+	if !reflect.DeepEqual(names, wantNames) {
+		t.Errorf("events = %v", names)
+	}
+	if tools[1] != "call_1" || tools[2] != "call_2" {
+		t.Errorf("tool blocks = %v", tools)
+	}
+	if arguments[1] != `{"city":"Paris"}` || arguments[2] != `{"city":"Rome"}` {
+		t.Errorf("arguments = %v", arguments)
+	}
+}
+
+func TestStreamWithoutStop(t *testing.T) {
+	stream := `data: {"id":"c1","model":"gpt","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}` + "\n\n"
+	got := events(t, feed(MessagesToChat.NewStream(nil), stream, len(stream)))
+	if last := got[len(got)-1]; last["event"] != "message_stop" {
+		t.Errorf("last event = %v", last)
+	}
+}
@@ -9,8 +9,12 @@ type ConfigManager interface {
 	GetLogConfig() LogConfig
 	GetDatabaseConfig() DatabaseConfig
 	GetEncryptionKey() string
+	GetEncryptionKeySecondary() string
+	GetGroupTemplatesFile() string
 	GetEffectiveServerConfig() ServerConfig
 	GetRedisDSN() string
+	GetLogArchiveConfig() LogArchiveConfig
+	GetRequestLogWriterConfig() RequestLogWriterConfig
 	Validate() error
 	DisplayServerConfig()
 	ReloadConfig() error
@@ -22,28 +26,99 @@ type SystemSettings struct {
 	AppUrl                         string `json:"app_url" default:"http://localhost:3001" name:"config.app_url" category:"config.category.basic" desc:"config.app_url_desc" validate:"required"`
 	ProxyKeys                      string `json:"proxy_keys" name:"config.proxy_keys" category:"config.category.basic" desc:"config.proxy_keys_desc" validate:"required"`
 	RequestLogRetentionDays        int    `json:"request_log_retention_days" default:"7" name:"config.log_retention_days" category:"config.category.basic" desc:"config.log_retention_days_desc" validate:"required,min=0"`
+	RequestLogRetentionAction      string `json:"request_log_retention_action" default:"delete" name:"config.log_retention_action" category:"config.category.basic" desc:"config.log_retention_action_desc" validate:"required"`
+	HourlyStatsRetentionDays       int    `json:"hourly_stats_retention_days" default:"0" name:"config.hourly_stats_retention_days" category:"config.category.basic" desc:"config.hourly_stats_retention_days_desc" validate:"required,min=0"`
+	ArchiveS3Endpoint              string `json:"archive_s3_endpoint" default:"" name:"config.archive_s3_endpoint" category:"config.category.basic" desc:"config.archive_s3_endpoint_desc"`
+	ArchiveS3Region                string `json:"archive_s3_region" default:"us-east-1" name:"config.archive_s3_region" category:"config.category.basic" desc:"config.archive_s3_region_desc"`
+	ArchiveS3Bucket                string `json:"archive_s3_bucket" default:"" name:"config.archive_s3_bucket" category:"config.category.basic" desc:"config.archive_s3_bucket_desc"`
+	ArchiveS3Prefix                string `json:"archive_s3_prefix" default:"" name:"config.archive_s3_prefix" category:"config.category.basic" desc:"config.archive_s3_prefix_desc"`
+	ArchiveS3AccessKey             string `json:"archive_s3_access_key" default:"" name:"config.archive_s3_access_key" category:"config.category.basic" desc:"config.archive_s3_access_key_desc"`
+	ArchiveS3SecretKey             string `json:"archive_s3_secret_key" default:"" name:"config.archive_s3_secret_key" category:"config.category.basic" desc:"config.archive_s3_secret_key_desc"`
 	RequestLogWriteIntervalMinutes int    `json:"request_log_write_interval_minutes" default:"1" name:"config.log_write_interval" category:"config.category.basic" desc:"config.log_write_interval_desc" validate:"required,min=0"`
 	EnableRequestBodyLogging       bool   `json:"enable_request_body_logging" default:"false" name:"config.enable_request_body_logging" category:"config.category.basic" desc:"config.enable_request_body_logging_desc"`
+	DebugCaptureRate               int    `json:"debug_capture_rate" default:"0" name:"config.debug_capture_rate" category:"config.category.basic" desc:"config.debug_capture_rate_desc" validate:"required,min=0,max=100"`
+	DebugCaptureUntil              string `json:"debug_capture_until" default:"" name:"config.debug_capture_until" category:"config.category.basic" desc:"config.debug_capture_until_desc"`
+	MonthlyRequestBudget           int    `json:"monthly_request_budget" default:"0" name:"config.monthly_request_budget" category:"config.category.basic" desc:"config.monthly_request_budget_desc" validate:"required,min=0"`
+	UsageSnapshotSchedule          string `json:"usage_snapshot_schedule" default:"" name:"config.usage_snapshot_schedule" category:"config.category.basic" desc:"config.usage_snapshot_schedule_desc"`
+	AnomalySigma                   int    `json:"anomaly_sigma" default:"3" name:"config.anomaly_sigma" category:"config.category.basic" desc:"config.anomaly_sigma_desc" validate:"required,min=0"`
+	AnomalyMinRequests             int    `json:"anomaly_min_requests" default:"20" name:"config.anomaly_min_requests" category:"config.category.basic" desc:"config.anomaly_min_requests_desc" validate:"required,min=1"`
+	AlertWebhookURL                string `json:"alert_webhook_url" default:"" name:"config.alert_webhook_url" category:"config.category.basic" desc:"config.alert_webhook_url_desc"`
+	TrashRetentionDays             int    `json:"trash_retention_days" default:"7" name:"config.trash_retention_days" category:"config.category.basic" desc:"config.trash_retention_days_desc" validate:"required,min=0"`
+	LogLevel                       string `json:"log_level" default:"" name:"config.log_level" category:"config.category.basic" desc:"config.log_level_desc"`
+	KeyMasking                     string `json:"key_masking" default:"last4" name:"config.key_masking" category:"config.category.basic" desc:"config.key_masking_desc" validate:"required"`
 
 	// 请求设置
-	RequestTimeout        int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
-	ConnectTimeout        int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
-	IdleConnTimeout       int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
-	ResponseHeaderTimeout int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
-	MaxIdleConns          int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
-	MaxIdleConnsPerHost   int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
-	ProxyURL              string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	RequestTimeout                int    `json:"request_timeout" default:"600" name:"config.request_timeout" category:"config.category.request" desc:"config.request_timeout_desc" validate:"required,min=1"`
+	ConnectTimeout                int    `json:"connect_timeout" default:"15" name:"config.connect_timeout" category:"config.category.request" desc:"config.connect_timeout_desc" validate:"required,min=1"`
+	IdleConnTimeout               int    `json:"idle_conn_timeout" default:"120" name:"config.idle_conn_timeout" category:"config.category.request" desc:"config.idle_conn_timeout_desc" validate:"required,min=1"`
+	ResponseHeaderTimeout         int    `json:"response_header_timeout" default:"600" name:"config.response_header_timeout" category:"config.category.request" desc:"config.response_header_timeout_desc" validate:"required,min=1"`
+	MaxIdleConns                  int    `json:"max_idle_conns" default:"100" name:"config.max_idle_conns" category:"config.category.request" desc:"config.max_idle_conns_desc" validate:"required,min=1"`
+	MaxIdleConnsPerHost           int    `json:"max_idle_conns_per_host" default:"50" name:"config.max_idle_conns_per_host" category:"config.category.request" desc:"config.max_idle_conns_per_host_desc" validate:"required,min=1"`
+	ProxyURL                      string `json:"proxy_url" name:"config.proxy_url" category:"config.category.request" desc:"config.proxy_url_desc"`
+	HTTPVersion                   string `json:"http_version" default:"auto" name:"config.http_version" category:"config.category.request" desc:"config.http_version_desc"`
+	TCPKeepAlive                  int    `json:"tcp_keepalive_seconds" default:"30" name:"config.tcp_keepalive" category:"config.category.request" desc:"config.tcp_keepalive_desc" validate:"required,min=0"`
+	DNSCacheTTL                   int    `json:"dns_cache_ttl_seconds" default:"0" name:"config.dns_cache_ttl" category:"config.category.request" desc:"config.dns_cache_ttl_desc" validate:"required,min=0"`
+	AllowedEndpoints              string `json:"allowed_endpoints" default:"" name:"config.allowed_endpoints" category:"config.category.request" desc:"config.allowed_endpoints_desc"`
+	AllowedModels                 string `json:"allowed_models" default:"" name:"config.allowed_models" category:"config.category.request" desc:"config.allowed_models_desc"`
+	DeniedModels                  string `json:"denied_models" default:"" name:"config.denied_models" category:"config.category.request" desc:"config.denied_models_desc"`
+	IPAllowlist                   string `json:"ip_allowlist" default:"" name:"config.ip_allowlist" category:"config.category.request" desc:"config.ip_allowlist_desc"`
+	IPDenylist                    string `json:"ip_denylist" default:"" name:"config.ip_denylist" category:"config.category.request" desc:"config.ip_denylist_desc"`
+	IPRateLimitPerMinute          int    `json:"ip_rate_limit_per_minute" default:"0" name:"config.ip_rate_limit" category:"config.category.request" desc:"config.ip_rate_limit_desc" validate:"required,min=0"`
+	AuthRateLimitPerMinute        int    `json:"auth_rate_limit_per_minute" default:"0" name:"config.auth_rate_limit" category:"config.category.request" desc:"config.auth_rate_limit_desc" validate:"required,min=0"`
+	IPBanThreshold                int    `json:"ip_ban_threshold" default:"0" name:"config.ip_ban_threshold" category:"config.category.request" desc:"config.ip_ban_threshold_desc" validate:"required,min=0"`
+	IPBanDurationSeconds          int    `json:"ip_ban_duration_seconds" default:"900" name:"config.ip_ban_duration" category:"config.category.request" desc:"config.ip_ban_duration_desc" validate:"required,min=1"`
+	HoneypotDecoyKeys             string `json:"honeypot_decoy_keys" default:"" name:"config.honeypot_decoy_keys" category:"config.category.request" desc:"config.honeypot_decoy_keys_desc"`
+	HoneypotPaths                 string `json:"honeypot_paths" default:"" name:"config.honeypot_paths" category:"config.category.request" desc:"config.honeypot_paths_desc"`
+	HoneypotBannedIPs             bool   `json:"honeypot_banned_ips" default:"false" name:"config.honeypot_banned_ips" category:"config.category.request" desc:"config.honeypot_banned_ips_desc"`
+	HoneypotDelayMs               int    `json:"honeypot_delay_ms" default:"3000" name:"config.honeypot_delay" category:"config.category.request" desc:"config.honeypot_delay_desc" validate:"required,min=0"`
+	HoneypotAutoDenyHits          int    `json:"honeypot_auto_deny_hits" default:"0" name:"config.honeypot_auto_deny_hits" category:"config.category.request" desc:"config.honeypot_auto_deny_hits_desc" validate:"required,min=0"`
+	CircuitBreakerThreshold       int    `json:"circuit_breaker_threshold" default:"5" name:"config.circuit_breaker_threshold" category:"config.category.request" desc:"config.circuit_breaker_threshold_desc" validate:"required,min=0"`
+	CircuitBreakerCooldownSeconds int    `json:"circuit_breaker_cooldown_seconds" default:"30" name:"config.circuit_breaker_cooldown" category:"config.category.request" desc:"config.circuit_breaker_cooldown_desc" validate:"required,min=1"`
+	StreamFirstByteTimeout        int    `json:"stream_first_byte_timeout_seconds" default:"0" name:"config.stream_first_byte_timeout" category:"config.category.request" desc:"config.stream_first_byte_timeout_desc" validate:"required,min=0"`
+	StreamIdleTimeout             int    `json:"stream_idle_timeout_seconds" default:"0" name:"config.stream_idle_timeout" category:"config.category.request" desc:"config.stream_idle_timeout_desc" validate:"required,min=0"`
+	StreamMaxDuration             int    `json:"stream_max_duration_seconds" default:"0" name:"config.stream_max_duration" category:"config.category.request" desc:"config.stream_max_duration_desc" validate:"required,min=0"`
+	StreamRetryOnStall            bool   `json:"stream_retry_on_stall" default:"false" name:"config.stream_retry_on_stall" category:"config.category.request" desc:"config.stream_retry_on_stall_desc"`
+	CompressResponses             bool   `json:"compress_responses" default:"false" name:"config.compress_responses" category:"config.category.request" desc:"config.compress_responses_desc"`
+	SimulateStreaming             bool   `json:"simulate_streaming" default:"false" name:"config.simulate_streaming" category:"config.category.request" desc:"config.simulate_streaming_desc"`
+	StreamingKillSwitch           bool   `json:"streaming_kill_switch" default:"false" name:"config.streaming_kill_switch" category:"config.category.request" desc:"config.streaming_kill_switch_desc"`
+	UpstreamHealthCheckPath       string `json:"upstream_health_check_path" name:"config.upstream_health_check_path" category:"config.category.request" desc:"config.upstream_health_check_path_desc"`
+	UpstreamHealthCheckInterval   int    `json:"upstream_health_check_interval_seconds" default:"30" name:"config.upstream_health_check_interval" category:"config.category.request" desc:"config.upstream_health_check_interval_desc" validate:"required,min=5"`
+	UpstreamHealthCheckStatus     int    `json:"upstream_health_check_expected_status" default:"200" name:"config.upstream_health_check_status" category:"config.category.request" desc:"config.upstream_health_check_status_desc" validate:"required,min=100,max=599"`
+	ResponseCacheTTL              int    `json:"response_cache_ttl_seconds" default:"0" name:"config.response_cache_ttl" category:"config.category.request" desc:"config.response_cache_ttl_desc" validate:"required,min=0"`
+	MaxRequestBodyKB              int    `json:"max_request_body_kb" default:"0" name:"config.max_request_body" category:"config.category.request" desc:"config.max_request_body_desc" validate:"required,min=0"`
+	GroupMaxConcurrentRequests    int    `json:"group_max_concurrent_requests" default:"0" name:"config.group_max_concurrent_requests" category:"config.category.request" desc:"config.group_max_concurrent_requests_desc" validate:"required,min=0"`
+	GroupMaxQueuedRequests        int    `json:"group_max_queued_requests" default:"0" name:"config.group_max_queued_requests" category:"config.category.request" desc:"config.group_max_queued_requests_desc" validate:"required,min=0"`
+	GroupQueueTimeout             int    `json:"group_queue_timeout_seconds" default:"30" name:"config.group_queue_timeout" category:"config.category.request" desc:"config.group_queue_timeout_desc" validate:"required,min=0"`
+	RequestPriority               string `json:"request_priority" default:"normal" name:"config.request_priority" category:"config.category.request" desc:"config.request_priority_desc"`
+	RequestIDHeader               string `json:"request_id_header" default:"X-Request-ID" name:"config.request_id_header" category:"config.category.request" desc:"config.request_id_header_desc"`
+	RateLimitHeaders              string `json:"rate_limit_headers" default:"x-ratelimit-*,anthropic-ratelimit-*,retry-after" name:"config.rate_limit_headers" category:"config.category.request" desc:"config.rate_limit_headers_desc"`
+	RateLimitHeadersAggregate     bool   `json:"rate_limit_headers_aggregate" default:"false" name:"config.rate_limit_headers_aggregate" category:"config.category.request" desc:"config.rate_limit_headers_aggregate_desc"`
 
 	// 密钥配置
 	MaxRetries                   int    `json:"max_retries" default:"3" name:"config.max_retries" category:"config.category.key" desc:"config.max_retries_desc" validate:"required,min=0"`
+	RetryBackoffBaseMs           int    `json:"retry_backoff_base_ms" default:"100" name:"config.retry_backoff_base" category:"config.category.key" desc:"config.retry_backoff_base_desc" validate:"required,min=0"`
+	RetryBackoffMaxMs            int    `json:"retry_backoff_max_ms" default:"2000" name:"config.retry_backoff_max" category:"config.category.key" desc:"config.retry_backoff_max_desc" validate:"required,min=0"`
 	BlacklistThreshold           int    `json:"blacklist_threshold" default:"3" name:"config.blacklist_threshold" category:"config.category.key" desc:"config.blacklist_threshold_desc" validate:"required,min=0"`
+	RateLimitMaxCooldown         int    `json:"rate_limit_max_cooldown_seconds" default:"3600" name:"config.rate_limit_max_cooldown" category:"config.category.key" desc:"config.rate_limit_max_cooldown_desc" validate:"required,min=0"`
+	RateLimitPerModel            bool   `json:"rate_limit_per_model" default:"false" name:"config.rate_limit_per_model" category:"config.category.key" desc:"config.rate_limit_per_model_desc"`
+	QuotaResetTime               string `json:"quota_reset_time" default:"" name:"config.quota_reset_time" category:"config.category.key" desc:"config.quota_reset_time_desc"`
+	BlacklistStatusThresholds    string `json:"blacklist_status_thresholds" name:"config.blacklist_status_thresholds" category:"config.category.key" desc:"config.blacklist_status_thresholds_desc"`
 	FailoverStatusCodes          string `json:"failover_status_codes" default:"400-403,405-999" name:"config.failover_status_codes" category:"config.category.key" desc:"config.failover_status_codes_desc"`
 	KeyValidationIntervalMinutes int    `json:"key_validation_interval_minutes" default:"60" name:"config.key_validation_interval" category:"config.category.key" desc:"config.key_validation_interval_desc" validate:"required,min=1"`
 	KeyValidationConcurrency     int    `json:"key_validation_concurrency" default:"10" name:"config.key_validation_concurrency" category:"config.category.key" desc:"config.key_validation_concurrency_desc" validate:"required,min=1"`
 	KeyValidationTimeoutSeconds  int    `json:"key_validation_timeout_seconds" default:"20" name:"config.key_validation_timeout" category:"config.category.key" desc:"config.key_validation_timeout_desc" validate:"required,min=1"`
+	KeyValidationMaxRPS          int    `json:"key_validation_max_rps" default:"0" name:"config.key_validation_max_rps" category:"config.category.key" desc:"config.key_validation_max_rps_desc" validate:"required,min=0"`
+	KeyValidationWindows         string `json:"key_validation_windows" default:"" name:"config.key_validation_windows" category:"config.category.key" desc:"config.key_validation_windows_desc"`
+	KeyValidationBudget          int    `json:"key_validation_budget" default:"0" name:"config.key_validation_budget" category:"config.category.key" desc:"config.key_validation_budget_desc" validate:"required,min=0"`
+	ReserveMinActiveKeys         int    `json:"reserve_min_active_keys" default:"0" name:"config.reserve_min_active_keys" category:"config.category.key" desc:"config.reserve_min_active_keys_desc" validate:"required,min=0"`
+	PoolMode                     string `json:"pool_mode" default:"simple" name:"config.pool_mode" category:"config.category.key" desc:"config.pool_mode_desc"`
+	CredentialRefresher          string `json:"credential_refresher" default:"" name:"config.credential_refresher" category:"config.category.key" desc:"config.credential_refresher_desc"`
+	ProviderStatusPolling        bool   `json:"provider_status_polling" default:"false" name:"config.provider_status_polling" category:"config.category.key" desc:"config.provider_status_polling_desc"`
 
 	// For cache
-	ProxyKeysMap map[string]struct{} `json:"-"`
+	ProxyKeysMap         map[string]struct{} `json:"-"`
+	HoneypotDecoyKeysMap map[string]struct{} `json:"-"`
+	HoneypotPathList     []string            `json:"-"`
 }
 
 // ServerConfig represents server configuration
@@ -55,11 +130,59 @@ type ServerConfig struct {
 	WriteTimeout            int    `json:"write_timeout"`
 	IdleTimeout             int    `json:"idle_timeout"`
 	GracefulShutdownTimeout int    `json:"graceful_shutdown_timeout"`
+	DrainDelay              int    `json:"drain_delay"`
+	ClusterMode             bool   `json:"cluster_mode"`
+	// ReadyRequiresKeys makes /readyz fail while no group has a key in rotation.
+	ReadyRequiresKeys bool `json:"readiness_require_active_keys"`
+	// GRPCPort is the port of the gRPC admin API, which is disabled when 0.
+	GRPCPort int `json:"grpc_port"`
+	// TrustedProxies are the proxies whose TrustedProxyHeaders are believed for the client IP.
+	TrustedProxies      []string `json:"trusted_proxies"`
+	TrustedProxyHeaders []string `json:"trusted_proxy_headers"`
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
 	Key string `json:"key"`
+	// KeyLogin lets AUTH_KEY sign in to the management API besides the admin account, as a
+	// break-glass option.
+	KeyLogin bool `json:"key_login"`
+	// SessionTTLHours is how long a login session of the admin account lasts.
+	SessionTTLHours int `json:"session_ttl_hours"`
+	// AdminUsername and AdminPassword create the admin account on first start.
+	AdminUsername string `json:"admin_username"`
+	AdminPassword string `json:"-"`
+	// OIDC signs in to the management UI with an OpenID Connect identity provider.
+	OIDC OIDCConfig `json:"oidc"`
+}
+
+// Roles of the callers of the management API
+const (
+	AdminRoleAdmin = "admin"
+	// AdminRoleViewer may only read.
+	AdminRoleViewer = "viewer"
+)
+
+// OIDCConfig represents single sign-on with an OpenID Connect identity provider
+type OIDCConfig struct {
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"-"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+	// AllowedEmailDomains restricts the users to verified emails of the domains, any when empty.
+	AllowedEmailDomains []string `json:"allowed_email_domains"`
+	// GroupsClaim is the ID token claim listing the groups of the user.
+	GroupsClaim string `json:"groups_claim"`
+	// RoleMapping maps groups to the role their members get; the highest role wins.
+	RoleMapping map[string]string `json:"role_mapping"`
+	// DefaultRole is the role of users in no mapped group, who are rejected when empty.
+	DefaultRole string `json:"default_role"`
+}
+
+// Enabled reports whether single sign-on is configured.
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != ""
 }
 
 // CORSConfig represents CORS configuration
@@ -74,6 +197,14 @@ type CORSConfig struct {
 // PerformanceConfig represents performance configuration
 type PerformanceConfig struct {
 	MaxConcurrentRequests int `json:"max_concurrent_requests"`
+	KeyStatusWorkers      int `json:"key_status_workers"`
+	KeyStatusQueueSize    int `json:"key_status_queue_size"`
+	// KeyStatusFlushInterval buffers key status updates in the store and flushes them every
+	// that many seconds, 0 applies them as they come.
+	KeyStatusFlushInterval int `json:"key_status_flush_interval"`
+	KeyStatusFlushBatch    int `json:"key_status_flush_batch"`
+	QueueTimeout           int `json:"queue_timeout"`
+	MemoryStoreMaxMB       int `json:"memory_store_max_mb"`
 }
 
 // LogConfig represents logging configuration
@@ -84,9 +215,38 @@ type LogConfig struct {
 	FilePath   string `json:"file_path"`
 }
 
+// Request log writer overflow policies.
+const (
+	RequestLogOverflowDrop  = "drop"
+	RequestLogOverflowSpill = "spill"
+)
+
+// RequestLogWriterConfig represents the buffering of request logs written in sync mode
+type RequestLogWriterConfig struct {
+	BufferSize      int    `json:"buffer_size"`
+	BatchSize       int    `json:"batch_size"`
+	FlushIntervalMs int    `json:"flush_interval_ms"`
+	Overflow        string `json:"overflow"`
+}
+
+// LogArchiveConfig represents where expired request logs are archived
+type LogArchiveConfig struct {
+	Dir         string `json:"dir"`
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3Prefix    string `json:"s3_prefix"`
+	S3AccessKey string `json:"-"`
+	S3SecretKey string `json:"-"`
+}
+
 // DatabaseConfig represents database configuration
 type DatabaseConfig struct {
-	DSN string `json:"dsn"`
+	DSN           string `json:"dsn"`
+	DegradedMode  bool   `json:"degraded_mode"`
+	RedisFailover bool   `json:"redis_failover"`
+	StartupWait   int    `json:"startup_wait_timeout"`
+	PGPubSub      bool   `json:"postgres_pubsub"`
 }
 
 type RetryError struct {
@@ -0,0 +1,234 @@
+// Package upstreamstats keeps the recent latency and status codes of each upstream of each
+// group, so that a degrading mirror can be told apart from the others.
+package upstreamstats
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Window is how far back the statistics go.
+	Window = time.Hour
+	// maxSamples caps the samples kept per upstream, the oldest are dropped first.
+	maxSamples = 1000
+)
+
+// Timing measures one upstream request with an httptrace.ClientTrace.
+type Timing struct {
+	start        time.Time
+	connectMs    int64
+	newConn      bool
+	ttfbMs       int64
+	firstByteSet bool
+	mu           sync.Mutex
+}
+
+// Trace starts timing a request and returns it with the trace attached to its context.
+func Trace(req *http.Request) (*http.Request, *Timing) {
+	t := &Timing{start: time.Now()}
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			// DNS, TCP and TLS of a new connection, reused connections cost nothing
+			if !info.Reused {
+				t.newConn = true
+				t.connectMs = time.Since(t.start).Milliseconds()
+			}
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.firstByteSet = true
+			t.ttfbMs = time.Since(t.start).Milliseconds()
+		},
+	}
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), t
+}
+
+type sample struct {
+	at         time.Time
+	connectMs  int64
+	newConn    bool
+	ttfbMs     int64
+	hasTTFB    bool
+	totalMs    int64
+	statusCode int
+}
+
+type key struct {
+	group    string
+	upstream string
+}
+
+// Tracker keeps the samples of the upstreams of this instance.
+type Tracker struct {
+	mu      sync.Mutex
+	samples map[key][]sample
+}
+
+// NewTracker creates an empty Tracker.
+func NewTracker() *Tracker {
+	return &Tracker{samples: make(map[key][]sample)}
+}
+
+// Record adds a finished request to the upstream of a group. statusCode is 0 for network errors.
+func (t *Tracker) Record(group, upstream string, timing *Timing, statusCode int) {
+	timing.mu.Lock()
+	s := sample{
+		at:         time.Now(),
+		connectMs:  timing.connectMs,
+		newConn:    timing.newConn,
+		ttfbMs:     timing.ttfbMs,
+		hasTTFB:    timing.firstByteSet,
+		totalMs:    time.Since(timing.start).Milliseconds(),
+		statusCode: statusCode,
+	}
+	timing.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := key{group: group, upstream: upstream}
+	samples := append(t.samples[k], s)
+	if len(samples) > maxSamples {
+		samples = slices.Delete(samples, 0, len(samples)-maxSamples)
+	}
+	t.samples[k] = samples
+}
+
+// Latency holds the median and 95th percentile of a duration in milliseconds.
+type Latency struct {
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+}
+
+// Stats summarizes the requests sent to one upstream of a group within the Window.
+type Stats struct {
+	Group    string `json:"group"`
+	Upstream string `json:"upstream"`
+	Requests int    `json:"requests"`
+	// ErrorRate is the percentage of network errors and 5xx responses.
+	ErrorRate float64 `json:"error_rate"`
+	// Connect only covers requests that opened a new connection.
+	Connect Latency `json:"connect_ms"`
+	TTFB    Latency `json:"ttfb_ms"`
+	Total   Latency `json:"total_ms"`
+	// StatusCodes counts the responses by status code, 0 for network errors.
+	StatusCodes map[int]int `json:"status_codes"`
+}
+
+// Snapshot returns the statistics of every upstream, or of the upstreams of one group when
+// group is not empty, sorted by group and upstream.
+func (t *Tracker) Snapshot(group string) []Stats {
+	cutoff := time.Now().Add(-Window)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := []Stats{}
+	for k, samples := range t.samples {
+		if group != "" && k.group != group {
+			continue
+		}
+		// Samples are in time order, drop the ones that left the window
+		first := sort.Search(len(samples), func(i int) bool { return samples[i].at.After(cutoff) })
+		samples = samples[first:]
+		if len(samples) == 0 {
+			delete(t.samples, k)
+			continue
+		}
+		t.samples[k] = samples
+		result = append(result, summarize(k, samples))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Group != result[j].Group {
+			return result[i].Group < result[j].Group
+		}
+		return result[i].Upstream < result[j].Upstream
+	})
+	return result
+}
+
+// Load summarizes the recent requests of a group across its upstreams.
+type Load struct {
+	Requests    int
+	RateLimited int
+	// AvgLatencyMs is the average time to first byte, or to the end of the response for
+	// requests without one.
+	AvgLatencyMs float64
+}
+
+// Load returns the load of a group over the last window, at most Window.
+func (t *Tracker) Load(group string, window time.Duration) Load {
+	cutoff := time.Now().Add(-min(window, Window))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var load Load
+	var totalMs int64
+	for k, samples := range t.samples {
+		if k.group != group {
+			continue
+		}
+		first := sort.Search(len(samples), func(i int) bool { return samples[i].at.After(cutoff) })
+		for _, s := range samples[first:] {
+			load.Requests++
+			if s.statusCode == http.StatusTooManyRequests {
+				load.RateLimited++
+			}
+			if s.hasTTFB {
+				totalMs += s.ttfbMs
+			} else {
+				totalMs += s.totalMs
+			}
+		}
+	}
+	if load.Requests > 0 {
+		load.AvgLatencyMs = float64(totalMs) / float64(load.Requests)
+	}
+	return load
+}
+
+func summarize(k key, samples []sample) Stats {
+	stats := Stats{Group: k.group, Upstream: k.upstream, Requests: len(samples), StatusCodes: make(map[int]int)}
+	var connect, ttfb, total []int64
+	errors := 0
+	for _, s := range samples {
+		stats.StatusCodes[s.statusCode]++
+		if s.statusCode == 0 || s.statusCode >= http.StatusInternalServerError {
+			errors++
+		}
+		if s.newConn {
+			connect = append(connect, s.connectMs)
+		}
+		if s.hasTTFB {
+			ttfb = append(ttfb, s.ttfbMs)
+		}
+		total = append(total, s.totalMs)
+	}
+	stats.ErrorRate = float64(errors) / float64(len(samples)) * 100
+	stats.Connect = latency(connect)
+	stats.TTFB = latency(ttfb)
+	stats.Total = latency(total)
+	return stats
+}
+
+func latency(values []int64) Latency {
+	if len(values) == 0 {
+		return Latency{}
+	}
+	slices.Sort(values)
+	return Latency{P50: percentile(values, 50), P95: percentile(values, 95)}
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []int64, p int) int64 {
+	rank := (len(sorted)*p + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,75 @@
+package upstreamstats
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTrackerSnapshot(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer upstream.Close()
+
+	tracker := NewTracker()
+	for _, path := range []string{"/ok", "/ok", "/ok", "/fail"} {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL+path, nil)
+		req, timing := Trace(req)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		tracker.Record("openai", upstream.URL, timing, resp.StatusCode)
+	}
+	tracker.Record("gemini", "https://mirror.example.com", &Timing{start: time.Now()}, 0)
+
+	stats := tracker.Snapshot("openai")
+	if len(stats) != 1 {
+		t.Fatalf("Snapshot() returned %d upstreams, want 1", len(stats))
+	}
+	got := stats[0]
+	if got.Requests != 4 || got.StatusCodes[http.StatusOK] != 3 || got.StatusCodes[http.StatusBadGateway] != 1 {
+		t.Errorf("Snapshot() = %+v, want 3 successes and 1 bad gateway", got)
+	}
+	if got.ErrorRate != 25 {
+		t.Errorf("ErrorRate = %v, want 25", got.ErrorRate)
+	}
+
+	all := tracker.Snapshot("")
+	if len(all) != 2 || all[0].Group != "gemini" || all[0].StatusCodes[0] != 1 || all[0].ErrorRate != 100 {
+		t.Errorf("Snapshot(\"\") = %+v, want the network error of gemini first", all)
+	}
+}
+
+func TestTrackerLoad(t *testing.T) {
+	tracker := NewTracker()
+	now := time.Now()
+	tracker.samples[key{group: "openai", upstream: "a"}] = []sample{
+		{at: now.Add(-10 * time.Minute), statusCode: http.StatusTooManyRequests, totalMs: 1000},
+		{at: now.Add(-time.Minute), statusCode: http.StatusTooManyRequests, ttfbMs: 100, hasTTFB: true, totalMs: 900},
+	}
+	tracker.samples[key{group: "openai", upstream: "b"}] = []sample{
+		{at: now, statusCode: http.StatusOK, totalMs: 300},
+	}
+	tracker.samples[key{group: "gemini", upstream: "a"}] = []sample{{at: now, statusCode: http.StatusOK}}
+
+	got := tracker.Load("openai", 5*time.Minute)
+	if want := (Load{Requests: 2, RateLimited: 1, AvgLatencyMs: 200}); got != want {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []int64{10, 20, 30, 40, 50, 60, 70, 80, 90, 100}
+	if got := latency(values); got.P50 != 50 || got.P95 != 100 {
+		t.Errorf("latency() = %+v, want p50 50 and p95 100", got)
+	}
+	if got := latency(nil); got != (Latency{}) {
+		t.Errorf("latency(nil) = %+v, want zero", got)
+	}
+}
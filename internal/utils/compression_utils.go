@@ -2,9 +2,13 @@ package utils
 
 import (
 	"bytes"
+	"compress/flate"
 	"compress/gzip"
+	"compress/zlib"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 
 	"github.com/andybalholm/brotli"
 	"github.com/klauspost/compress/zstd"
@@ -40,15 +44,7 @@ func DecompressResponse(contentEncoding string, data []byte) ([]byte, error) {
 		return data, nil
 	}
 
-	// Look up the decompressor
-	decompressor, exists := decompressorRegistry[contentEncoding]
-	if !exists {
-		logrus.Warnf("No decompressor registered for encoding '%s', returning original data", contentEncoding)
-		return data, nil
-	}
-
-	// Decompress
-	decompressed, err := decompressor.Decompress(data)
+	decompressed, err := Decompress(contentEncoding, data)
 	if err != nil {
 		logrus.WithError(err).Warnf("Failed to decompress with '%s', returning original data", contentEncoding)
 		return data, nil
@@ -59,6 +55,116 @@ func DecompressResponse(contentEncoding string, data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
+// Decompress decodes data with the encodings of a Content-Encoding header, which lists them
+// in the order they were applied. Unlike DecompressResponse it fails on an encoding that has
+// no registered decompressor.
+func Decompress(contentEncoding string, data []byte) ([]byte, error) {
+	encodings := strings.Split(contentEncoding, ",")
+	for i := len(encodings) - 1; i >= 0; i-- {
+		encoding := strings.ToLower(strings.TrimSpace(encodings[i]))
+		if encoding == "" || encoding == "identity" || len(data) == 0 {
+			continue
+		}
+		decompressor, exists := decompressorRegistry[encoding]
+		if !exists {
+			return nil, fmt.Errorf("no decompressor registered for encoding '%s'", encoding)
+		}
+		decompressed, err := decompressor.Decompress(data)
+		if err != nil {
+			return nil, err
+		}
+		data = decompressed
+	}
+	return data, nil
+}
+
+// DecodableEncodings is an Accept-Encoding value listing the encodings Decompress decodes.
+const DecodableEncodings = "gzip, deflate, br, zstd"
+
+// compressionEncodings are the encodings NewCompressWriter supports, in order of preference.
+var compressionEncodings = []string{"gzip", "br", "zstd", "deflate"}
+
+// NegotiateEncoding picks the encoding to compress a response with from the Accept-Encoding
+// header of a request, the preferred one among those with the highest quality, or "" when the
+// client accepts none.
+func NegotiateEncoding(acceptEncoding string) string {
+	qualities := parseAcceptEncoding(acceptEncoding)
+	best, bestQuality := "", 0.0
+	for _, encoding := range compressionEncodings {
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = encoding, quality
+		}
+	}
+	return best
+}
+
+// AcceptsEncoding reports whether a request with the Accept-Encoding header accepts a body in
+// contentEncoding, every encoding listed in it included.
+func AcceptsEncoding(acceptEncoding, contentEncoding string) bool {
+	qualities := parseAcceptEncoding(acceptEncoding)
+	for _, encoding := range strings.Split(contentEncoding, ",") {
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "" || encoding == "identity" {
+			continue
+		}
+		quality, ok := qualities[encoding]
+		if !ok {
+			quality, ok = qualities["*"]
+		}
+		if !ok || quality <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// parseAcceptEncoding maps the encodings of an Accept-Encoding header to their quality.
+func parseAcceptEncoding(header string) map[string]float64 {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		encoding, params, _ := strings.Cut(part, ";")
+		encoding = strings.ToLower(strings.TrimSpace(encoding))
+		if encoding == "" {
+			continue
+		}
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if q, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				quality = q
+			}
+		}
+		qualities[encoding] = quality
+	}
+	return qualities
+}
+
+// CompressWriter compresses what is written to it. Flush writes out the data compressed so
+// far, for streams, and Close ends the compressed data.
+type CompressWriter interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// NewCompressWriter returns a writer compressing into w with encoding, one of those
+// NegotiateEncoding picks.
+func NewCompressWriter(encoding string, w io.Writer) (CompressWriter, error) {
+	switch encoding {
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "deflate":
+		return zlib.NewWriter(w), nil
+	case "br":
+		return brotli.NewWriterLevel(w, brotli.DefaultCompression), nil
+	case "zstd":
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	}
+	return nil, fmt.Errorf("unsupported encoding '%s'", encoding)
+}
+
 // GzipDecompressor handles gzip compression
 type GzipDecompressor struct{}
 
@@ -93,17 +199,21 @@ func (b *BrotliDecompressor) Decompress(data []byte) ([]byte, error) {
 	return decompressed, nil
 }
 
-// DeflateDecompressor handles deflate compression (same as gzip without header)
+// DeflateDecompressor handles deflate compression
 type DeflateDecompressor struct{}
 
-// Decompress implements Decompressor interface for deflate
+// Decompress implements Decompressor interface for deflate. HTTP deflate is the zlib format,
+// some servers send raw DEFLATE data instead, which is accepted too.
 func (d *DeflateDecompressor) Decompress(data []byte) ([]byte, error) {
-	// For deflate, we can use the same logic as gzip
-	// In practice, deflate is raw DEFLATE format without gzip header
-	// For now, use gzip reader which handles both
-	reader, err := gzip.NewReader(bytes.NewReader(data))
+	reader, err := zlib.NewReader(bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create deflate reader: %w", err)
+		raw := flate.NewReader(bytes.NewReader(data))
+		defer raw.Close()
+		decompressed, rawErr := io.ReadAll(raw)
+		if rawErr != nil {
+			return nil, fmt.Errorf("failed to read deflate data: %w", err)
+		}
+		return decompressed, nil
 	}
 	defer reader.Close()
 
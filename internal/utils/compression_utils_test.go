@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"bytes"
+	"compress/flate"
+	"testing"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	body := []byte(`{"choices":[{"message":{"content":"hello"}}]}`)
+	for _, encoding := range compressionEncodings {
+		var buf bytes.Buffer
+		writer, err := NewCompressWriter(encoding, &buf)
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		writer.Write(body)
+		if err := writer.Close(); err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+		decoded, err := Decompress(encoding, buf.Bytes())
+		if err != nil || !bytes.Equal(decoded, body) {
+			t.Errorf("%s: Decompress() = %q, %v", encoding, decoded, err)
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	body := []byte("data: hello\n\n")
+	var gzipped, chained bytes.Buffer
+	writer, _ := NewCompressWriter("gzip", &gzipped)
+	writer.Write(body)
+	writer.Close()
+	writer, _ = NewCompressWriter("br", &chained)
+	writer.Write(gzipped.Bytes())
+	writer.Close()
+	if decoded, err := Decompress("gzip, br", chained.Bytes()); err != nil || !bytes.Equal(decoded, body) {
+		t.Errorf("Decompress(gzip, br) = %q, %v", decoded, err)
+	}
+
+	var raw bytes.Buffer
+	flateWriter, _ := flate.NewWriter(&raw, flate.DefaultCompression)
+	flateWriter.Write(body)
+	flateWriter.Close()
+	if decoded, err := Decompress("deflate", raw.Bytes()); err != nil || !bytes.Equal(decoded, body) {
+		t.Errorf("Decompress(raw deflate) = %q, %v", decoded, err)
+	}
+
+	if _, err := Decompress("compress", body); err == nil {
+		t.Error("Decompress(compress) succeeded, want an error")
+	}
+	if decoded, err := Decompress("identity", body); err != nil || !bytes.Equal(decoded, body) {
+		t.Errorf("Decompress(identity) = %q, %v", decoded, err)
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                          "",
+		"identity":                  "",
+		"gzip, deflate, br":         "gzip",
+		"br;q=1.0, gzip;q=0.8":      "br",
+		"zstd":                      "zstd",
+		"*":                         "gzip",
+		"gzip;q=0, *;q=0.5":         "br",
+		"GZIP;q=0.5, deflate;q=0.9": "deflate",
+	} {
+		if got := NegotiateEncoding(header); got != want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+
+	if !AcceptsEncoding("gzip, br", "gzip") || AcceptsEncoding("br", "gzip") || AcceptsEncoding("gzip;q=0", "gzip") || !AcceptsEncoding("", "") {
+		t.Error("AcceptsEncoding() does not honor the Accept-Encoding header")
+	}
+}
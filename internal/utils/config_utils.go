@@ -33,7 +33,7 @@ func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInf
 		validateTag := field.Tag.Get("validate")
 		categoryTag := field.Tag.Get("category")
 
-		var minValue *int
+		var minValue, maxValue *int
 		var required bool
 
 		rules := strings.Split(validateTag, ",")
@@ -46,19 +46,26 @@ func GenerateSettingsMetadata(s *types.SystemSettings) []models.SystemSettingInf
 				if val, err := strconv.Atoi(valStr); err == nil {
 					minValue = &val
 				}
+			} else if strings.HasPrefix(rule, "max=") {
+				valStr := strings.TrimPrefix(rule, "max=")
+				if val, err := strconv.Atoi(valStr); err == nil {
+					maxValue = &val
+				}
 			}
 		}
 
 		info := models.SystemSettingInfo{
-			Key:          jsonTag,
-			Name:         nameTag,
-			Value:        fieldValue.Interface(),
-			Type:         field.Type.String(),
-			DefaultValue: defaultTag,
-			Description:  descTag,
-			Category:     categoryTag,
-			MinValue:     minValue,
-			Required:     required,
+			Key:             jsonTag,
+			Name:            nameTag,
+			Value:           fieldValue.Interface(),
+			Type:            field.Type.String(),
+			DefaultValue:    defaultTag,
+			Description:     descTag,
+			Category:        categoryTag,
+			MinValue:        minValue,
+			MaxValue:        maxValue,
+			Required:        required,
+			RequiresRestart: field.Tag.Get("restart") == "true",
 		}
 		settingsInfo = append(settingsInfo, info)
 	}
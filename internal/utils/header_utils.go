@@ -3,6 +3,7 @@ package utils
 import (
 	"gpt-load/internal/models"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
@@ -17,6 +18,32 @@ type HeaderVariableContext struct {
 	APIKey   *models.APIKey
 }
 
+// headerVariables are the variables ResolveHeaderVariables replaces.
+var headerVariables = map[string]bool{
+	"${CLIENT_IP}":    true,
+	"${TIMESTAMP_MS}": true,
+	"${TIMESTAMP_S}":  true,
+	"${GROUP_NAME}":   true,
+	"${GROUP_ID}":     true,
+	"${API_KEY}":      true,
+	"${KEY_ID}":       true,
+	"${KEY_HASH}":     true,
+}
+
+var headerVariablePattern = regexp.MustCompile(`\$\{[^}]*\}`)
+
+// UnknownHeaderVariables returns the ${...} placeholders of a header value that are not
+// supported variables, and so would be sent as is.
+func UnknownHeaderVariables(value string) []string {
+	var unknown []string
+	for _, variable := range headerVariablePattern.FindAllString(value, -1) {
+		if !headerVariables[variable] {
+			unknown = append(unknown, variable)
+		}
+	}
+	return unknown
+}
+
 // ResolveHeaderVariables resolves dynamic variables in header values
 func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 	if ctx == nil {
@@ -35,10 +62,14 @@ func ResolveHeaderVariables(value string, ctx *HeaderVariableContext) string {
 
 	if ctx.Group != nil {
 		variables["${GROUP_NAME}"] = ctx.Group.Name
+		variables["${GROUP_ID}"] = strconv.FormatUint(uint64(ctx.Group.ID), 10)
 	}
 
 	if ctx.APIKey != nil {
 		variables["${API_KEY}"] = ctx.APIKey.KeyValue
+		// 密钥 ID 和哈希可用于上游侧追踪，不暴露密钥本身
+		variables["${KEY_ID}"] = strconv.FormatUint(uint64(ctx.APIKey.ID), 10)
+		variables["${KEY_HASH}"] = ctx.APIKey.KeyHash
 	}
 
 	// Replace variables in the value
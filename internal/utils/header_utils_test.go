@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnknownHeaderVariables(t *testing.T) {
+	got := UnknownHeaderVariables("Bearer ${API_KEY} from ${CLIENT_IP} via ${CLIENT_ADDR} ${}")
+	want := []string{"${CLIENT_ADDR}", "${}"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownHeaderVariables() = %v, want %v", got, want)
+	}
+	if got := UnknownHeaderVariables("gpt-load/${GROUP_NAME}"); got != nil {
+		t.Errorf("UnknownHeaderVariables() = %v, want none", got)
+	}
+}
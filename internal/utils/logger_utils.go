@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"gpt-load/internal/requestid"
 	"gpt-load/internal/types"
 	"io"
 	"os"
@@ -9,6 +10,22 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// ApplyLogLevel sets the log level, falling back to the fallback level when level is empty.
+func ApplyLogLevel(level, fallback string) {
+	if level == "" {
+		level = fallback
+	}
+	parsed, err := logrus.ParseLevel(level)
+	if err != nil {
+		logrus.Warnf("Invalid log level %q, keeping %s", level, logrus.GetLevel())
+		return
+	}
+	if parsed != logrus.GetLevel() {
+		logrus.SetLevel(parsed)
+		logrus.Infof("Log level set to %s", parsed)
+	}
+}
+
 // SetupLogger configures the logging system based on the provided configuration.
 func SetupLogger(configManager types.ConfigManager) {
 	logConfig := configManager.GetLogConfig()
@@ -33,6 +50,9 @@ func SetupLogger(configManager types.ConfigManager) {
 		})
 	}
 
+	// Add the request ID to the log lines of proxy requests
+	logrus.AddHook(requestid.LogHook{})
+
 	// Setup file logging if enabled
 	if logConfig.EnableFile {
 		logDir := filepath.Dir(logConfig.FilePath)
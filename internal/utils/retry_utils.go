@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Waits between the attempts of RetryWithBackoff, doubled after each failure up to the maximum.
+var (
+	retryInitialBackoff = time.Second
+	retryMaxBackoff     = 30 * time.Second
+)
+
+// RetryWithBackoff calls fn until it succeeds or ctx is done, waiting longer after each failure.
+// fn is always called at least once, and the last error is returned when ctx ends the retries.
+// Each failure is logged as a warning naming the operation.
+func RetryWithBackoff(ctx context.Context, operation string, fn func() error) error {
+	backoff := retryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil {
+			if attempt > 1 {
+				logrus.Infof("%s succeeded after %d attempts.", operation, attempt)
+			}
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		logrus.Warnf("%s failed (attempt %d), retrying in %s: %v", operation, attempt, backoff, err)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff = min(backoff*2, retryMaxBackoff)
+	}
+}
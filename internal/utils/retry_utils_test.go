@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoff(t *testing.T) {
+	retryInitialBackoff, retryMaxBackoff = time.Millisecond, 2*time.Millisecond
+	defer func() { retryInitialBackoff, retryMaxBackoff = time.Second, 30*time.Second }()
+
+	attempts := 0
+	err := RetryWithBackoff(context.Background(), "connect", func() error {
+		if attempts++; attempts < 3 {
+			return errors.New("not ready")
+		}
+		return nil
+	})
+	if err != nil || attempts != 3 {
+		t.Errorf("RetryWithBackoff() = %v after %d attempts, want success after 3", err, attempts)
+	}
+
+	// A context already done still allows a single attempt
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	wantErr := errors.New("down")
+	err = RetryWithBackoff(ctx, "connect", func() error {
+		attempts++
+		return wantErr
+	})
+	if !errors.Is(err, wantErr) || attempts != 1 {
+		t.Errorf("RetryWithBackoff() = %v after %d attempts, want the error after 1", err, attempts)
+	}
+}
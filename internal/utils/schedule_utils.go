@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DailyReset is a fixed time of day at which an upstream quota resets.
+type DailyReset struct {
+	Hour     int
+	Minute   int
+	Location *time.Location
+}
+
+// ParseDailyReset parses "HH:MM" optionally followed by an IANA time zone, e.g.
+// "00:00 America/Los_Angeles". Without a zone the time is in UTC. An empty spec returns nil.
+func ParseDailyReset(spec string) (*DailyReset, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if len(fields) > 2 {
+		return nil, fmt.Errorf("expected \"HH:MM [time zone]\"")
+	}
+
+	clock, err := time.Parse("15:04", fields[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid time of day %q, expected HH:MM", fields[0])
+	}
+
+	location := time.UTC
+	if len(fields) == 2 {
+		if location, err = time.LoadLocation(fields[1]); err != nil {
+			return nil, fmt.Errorf("unknown time zone %q", fields[1])
+		}
+	}
+
+	return &DailyReset{Hour: clock.Hour(), Minute: clock.Minute(), Location: location}, nil
+}
+
+// Last returns the most recent reset at or before now.
+func (r *DailyReset) Last(now time.Time) time.Time {
+	local := now.In(r.Location)
+	reset := time.Date(local.Year(), local.Month(), local.Day(), r.Hour, r.Minute, 0, 0, r.Location)
+	if reset.After(local) {
+		reset = time.Date(local.Year(), local.Month(), local.Day()-1, r.Hour, r.Minute, 0, 0, r.Location)
+	}
+	return reset
+}
+
+// Schedule periods of a SnapshotSchedule.
+const (
+	SchedulePeriodDaily   = "daily"
+	SchedulePeriodMonthly = "monthly"
+)
+
+// SnapshotSchedule runs every day, or on the 1st of every month, at a fixed time of day.
+type SnapshotSchedule struct {
+	Period string
+	At     DailyReset
+}
+
+// ParseSnapshotSchedule parses "daily" or "monthly" optionally followed by "HH:MM [time zone]",
+// e.g. "monthly 00:00 Asia/Shanghai". The time defaults to 00:00 UTC. An empty spec returns nil.
+func ParseSnapshotSchedule(spec string) (*SnapshotSchedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	period := strings.ToLower(fields[0])
+	if period != SchedulePeriodDaily && period != SchedulePeriodMonthly {
+		return nil, fmt.Errorf("invalid period %q, expected daily or monthly", fields[0])
+	}
+
+	at := &DailyReset{Location: time.UTC}
+	if len(fields) > 1 {
+		var err error
+		if at, err = ParseDailyReset(strings.Join(fields[1:], " ")); err != nil {
+			return nil, err
+		}
+	}
+
+	return &SnapshotSchedule{Period: period, At: *at}, nil
+}
+
+// Last returns the most recent scheduled time at or before now.
+func (s *SnapshotSchedule) Last(now time.Time) time.Time {
+	if s.Period == SchedulePeriodDaily {
+		return s.At.Last(now)
+	}
+
+	loc := s.At.Location
+	local := now.In(loc)
+	last := time.Date(local.Year(), local.Month(), 1, s.At.Hour, s.At.Minute, 0, 0, loc)
+	if last.After(local) {
+		last = time.Date(local.Year(), local.Month()-1, 1, s.At.Hour, s.At.Minute, 0, 0, loc)
+	}
+	return last
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// TimeWindow is a daily time range on some days of the week. A range ending before it
+// starts runs past midnight into the next day.
+type TimeWindow struct {
+	Days     [7]bool
+	Start    int // minutes after midnight
+	End      int // minutes after midnight, up to 24:00
+	Location *time.Location
+}
+
+// TimeWindows is a set of time windows, a time is in it when it is in any of them.
+type TimeWindows []TimeWindow
+
+// ParseTimeWindows parses comma separated windows of the form "[DAYS] HH:MM-HH:MM [time zone]",
+// where DAYS is a day or range of days such as "sat" or "mon-fri", e.g.
+// "mon-fri 01:00-06:00 Asia/Shanghai, sat-sun 00:00-24:00". Without days a window applies
+// every day, without a zone it is in UTC. An empty spec returns nil.
+func ParseTimeWindows(spec string) (TimeWindows, error) {
+	var windows TimeWindows
+	for _, part := range strings.Split(spec, ",") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		window := TimeWindow{Location: time.UTC}
+
+		if !strings.Contains(fields[0], ":") {
+			days, err := parseWeekdays(fields[0])
+			if err != nil {
+				return nil, err
+			}
+			window.Days = days
+			fields = fields[1:]
+		} else {
+			for i := range window.Days {
+				window.Days[i] = true
+			}
+		}
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid window %q, expected \"[DAYS] HH:MM-HH:MM [time zone]\"", strings.TrimSpace(part))
+		}
+
+		start, end, ok := strings.Cut(fields[0], "-")
+		var err error
+		if !ok {
+			return nil, fmt.Errorf("invalid time range %q, expected HH:MM-HH:MM", fields[0])
+		}
+		if window.Start, err = parseMinuteOfDay(start, false); err != nil {
+			return nil, err
+		}
+		if window.End, err = parseMinuteOfDay(end, true); err != nil {
+			return nil, err
+		}
+		if window.Start == window.End {
+			return nil, fmt.Errorf("time range %q is empty", fields[0])
+		}
+
+		if len(fields) == 2 {
+			if window.Location, err = time.LoadLocation(fields[1]); err != nil {
+				return nil, fmt.Errorf("unknown time zone %q", fields[1])
+			}
+		}
+		windows = append(windows, window)
+	}
+	return windows, nil
+}
+
+// Contains reports whether t is in one of the windows.
+func (w TimeWindows) Contains(t time.Time) bool {
+	for _, window := range w {
+		if window.contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	local := t.In(w.Location)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+	if w.Start < w.End {
+		return w.Days[day] && minute >= w.Start && minute < w.End
+	}
+	// The window runs past midnight: its end belongs to the day after a listed day
+	return (w.Days[day] && minute >= w.Start) || (w.Days[(day+6)%7] && minute < w.End)
+}
+
+// parseWeekdays parses a day of the week or a range of days, e.g. "fri-mon".
+func parseWeekdays(spec string) ([7]bool, error) {
+	var days [7]bool
+	first, last, isRange := strings.Cut(strings.ToLower(spec), "-")
+	if !isRange {
+		last = first
+	}
+	from, ok := weekdays[first]
+	to, ok2 := weekdays[last]
+	if !ok || !ok2 {
+		return days, fmt.Errorf("invalid days %q, expected a day such as mon or a range such as mon-fri", spec)
+	}
+	for day := from; ; day = (day + 1) % 7 {
+		days[day] = true
+		if day == to {
+			break
+		}
+	}
+	return days, nil
+}
+
+// parseMinuteOfDay parses HH:MM into minutes after midnight, allowing 24:00 as an end.
+func parseMinuteOfDay(value string, isEnd bool) (int, error) {
+	if isEnd && value == "24:00" {
+		return 24 * 60, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", value)
+	}
+	return clock.Hour()*60 + clock.Minute(), nil
+}
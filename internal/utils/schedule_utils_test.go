@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDailyResetLast(t *testing.T) {
+	reset, err := ParseDailyReset("00:00 America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("ParseDailyReset() error = %v", err)
+	}
+
+	// 2025-03-10 07:59 UTC is 00:59 PDT, just after midnight in Los Angeles
+	got := reset.Last(time.Date(2025, 3, 10, 7, 59, 0, 0, time.UTC))
+	if want := time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Last() = %v, want %v", got.UTC(), want)
+	}
+
+	// 2025-03-10 06:59 UTC is 23:59 PDT of the previous day
+	got = reset.Last(time.Date(2025, 3, 10, 6, 59, 0, 0, time.UTC))
+	if want := time.Date(2025, 3, 9, 8, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Last() = %v, want %v", got.UTC(), want)
+	}
+
+	for _, spec := range []string{"24:00", "7am", "00:00 Mars/Base", "00:00 UTC extra"} {
+		if _, err := ParseDailyReset(spec); err == nil {
+			t.Errorf("ParseDailyReset(%q) should fail", spec)
+		}
+	}
+	if r, err := ParseDailyReset(""); r != nil || err != nil {
+		t.Errorf("ParseDailyReset(\"\") = %v, %v, want nil, nil", r, err)
+	}
+}
+
+func TestSnapshotScheduleLast(t *testing.T) {
+	schedule, err := ParseSnapshotSchedule("monthly 00:00 Asia/Shanghai")
+	if err != nil {
+		t.Fatalf("ParseSnapshotSchedule() error = %v", err)
+	}
+
+	// 2025-03-01 00:30 in Shanghai is 2025-02-28 16:30 UTC
+	got := schedule.Last(time.Date(2025, 2, 28, 16, 30, 0, 0, time.UTC))
+	if want := time.Date(2025, 2, 28, 16, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Last() = %v, want %v", got.UTC(), want)
+	}
+
+	// Just before midnight falls back to the 1st of the previous month
+	got = schedule.Last(time.Date(2025, 2, 28, 15, 59, 0, 0, time.UTC))
+	if want := time.Date(2025, 1, 31, 16, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Last() = %v, want %v", got.UTC(), want)
+	}
+
+	daily, err := ParseSnapshotSchedule("daily")
+	if err != nil {
+		t.Fatalf("ParseSnapshotSchedule() error = %v", err)
+	}
+	got = daily.Last(time.Date(2025, 3, 10, 7, 59, 0, 0, time.UTC))
+	if want := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Last() = %v, want %v", got.UTC(), want)
+	}
+
+	for _, spec := range []string{"weekly", "monthly 7am", "daily 00:00 Mars/Base"} {
+		if _, err := ParseSnapshotSchedule(spec); err == nil {
+			t.Errorf("ParseSnapshotSchedule(%q) should fail", spec)
+		}
+	}
+}
+
+func TestTimeWindowsContains(t *testing.T) {
+	windows, err := ParseTimeWindows("mon-fri 22:00-06:00 Asia/Shanghai, sat-sun 00:00-24:00")
+	if err != nil {
+		t.Fatalf("ParseTimeWindows() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want bool
+	}{
+		// 2025-03-10 is a Monday
+		{"monday night in Shanghai", time.Date(2025, 3, 10, 15, 0, 0, 0, time.UTC), true},
+		{"tuesday early morning in Shanghai", time.Date(2025, 3, 10, 21, 59, 0, 0, time.UTC), true},
+		{"tuesday morning in Shanghai", time.Date(2025, 3, 10, 22, 0, 0, 0, time.UTC), false},
+		{"monday early morning in Shanghai, still sunday in UTC", time.Date(2025, 3, 9, 17, 0, 0, 0, time.UTC), true},
+		{"saturday afternoon", time.Date(2025, 3, 15, 14, 0, 0, 0, time.UTC), true},
+		{"saturday early morning after a friday night", time.Date(2025, 3, 14, 21, 0, 0, 0, time.UTC), true},
+		{"friday noon in Shanghai", time.Date(2025, 3, 14, 4, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		if got := windows.Contains(tt.at); got != tt.want {
+			t.Errorf("%s: Contains(%v) = %v, want %v", tt.name, tt.at, got, tt.want)
+		}
+	}
+
+	nights, _ := ParseTimeWindows("mon-fri 22:00-06:00")
+	if nights.Contains(time.Date(2025, 3, 10, 3, 0, 0, 0, time.UTC)) {
+		t.Error("the early morning of a monday should not be in a window starting on weekday nights")
+	}
+
+	daily, err := ParseTimeWindows("01:00-05:30")
+	if err != nil {
+		t.Fatalf("ParseTimeWindows() error = %v", err)
+	}
+	if !daily.Contains(time.Date(2025, 3, 12, 5, 29, 0, 0, time.UTC)) || daily.Contains(time.Date(2025, 3, 12, 5, 30, 0, 0, time.UTC)) {
+		t.Error("a window without days should apply every day up to its end")
+	}
+
+	for _, spec := range []string{"weekdays 01:00-02:00", "01:00", "01:00-01:00", "mon 01:00-25:00", "01:00-02:00 Mars/Base", "mon 01:00-02:00 UTC extra"} {
+		if _, err := ParseTimeWindows(spec); err == nil {
+			t.Errorf("ParseTimeWindows(%q) should fail", spec)
+		}
+	}
+	if w, err := ParseTimeWindows(" "); w != nil || err != nil {
+		t.Errorf("ParseTimeWindows(\" \") = %v, %v, want nil, nil", w, err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"gpt-load/internal/app"
 	"gpt-load/internal/commands"
 	"gpt-load/internal/container"
+	"gpt-load/internal/drain"
 	"gpt-load/internal/types"
 	"gpt-load/internal/utils"
 
@@ -41,6 +42,22 @@ func runCommand() {
 	switch command {
 	case "migrate-keys":
 		commands.RunMigrateKeys(args)
+	case "store-export":
+		commands.RunStoreExport(args)
+	case "store-import":
+		commands.RunStoreImport(args)
+	case "fixture-capture":
+		commands.RunFixtureCapture(args)
+	case "selftest":
+		commands.RunSelftest(args)
+	case "archive":
+		commands.RunArchive(args)
+	case "group":
+		commands.RunGroup(args)
+	case "key":
+		commands.RunKey(args)
+	case "settings":
+		commands.RunSettings(args)
 	case "help", "-h", "--help":
 		printHelp()
 	default:
@@ -60,6 +77,14 @@ func printHelp() {
 	fmt.Println()
 	fmt.Println("Available Commands:")
 	fmt.Println("  migrate-keys    Migrate encryption keys")
+	fmt.Println("  store-export    Export runtime store state to a file")
+	fmt.Println("  store-import    Import runtime store state from a file")
+	fmt.Println("  fixture-capture Record a logged request as a golden channel fixture")
+	fmt.Println("  selftest        Run end-to-end checks against a running instance")
+	fmt.Println("  archive         Inspect or restore archived request logs and hourly stats")
+	fmt.Println("  group           List groups with their key counts")
+	fmt.Println("  key             Import or validate the keys of a group")
+	fmt.Println("  settings        List or change system settings")
 	fmt.Println("  help            Display this help message")
 	fmt.Println()
 	fmt.Println("Use 'gpt-load <command> --help' for more information about a command.")
@@ -89,19 +114,23 @@ func runServer() {
 	}
 
 	// Create and run the application
-	if err := container.Invoke(func(application *app.App, configManager types.ConfigManager) {
+	if err := container.Invoke(func(application *app.App, configManager types.ConfigManager, drainController *drain.Controller) {
 		if err := application.Start(); err != nil {
 			logrus.Fatalf("Failed to start application: %v", err)
 		}
 
-		// Wait for interrupt signal for graceful shutdown
+		// Wait for interrupt signal or a drain request for graceful shutdown
 		quit := make(chan os.Signal, 1)
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-		<-quit
+		select {
+		case <-quit:
+		case <-drainController.Requested():
+		}
 
 		// Create a context with timeout for shutdown
 		serverConfig := configManager.GetEffectiveServerConfig()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(serverConfig.GracefulShutdownTimeout)*time.Second)
+		shutdownTimeout := time.Duration(serverConfig.GracefulShutdownTimeout+serverConfig.DrainDelay) * time.Second
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
 		// Perform graceful shutdown
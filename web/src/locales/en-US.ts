@@ -68,6 +68,15 @@ export default {
     loginButton: "Login",
     loginSuccess: "Login successful",
     authKeyRequired: "Please enter auth key",
+    welcomeAccountDesc: "Sign in with your admin account to continue",
+    usernamePlaceholder: "Username",
+    passwordPlaceholder: "Password",
+    totpCodePlaceholder: "6-digit code from your authenticator app",
+    credentialsRequired: "Please enter username and password",
+    totpCodeRequired: "Please enter the two-factor code",
+    useAuthKey: "Sign in with auth key",
+    useAccount: "Sign in with admin account",
+    useSSO: "Sign in with SSO",
   },
   nav: {
     dashboard: "Dashboard",
@@ -97,6 +106,7 @@ export default {
     last7Days: "Last 7 Days",
     last30Days: "Last 30 Days",
     systemStatus: "System Status",
+    maintenanceGroups: "Groups in maintenance",
     running: "Running",
     stopped: "Stopped",
     healthy: "Healthy",
@@ -114,6 +124,7 @@ export default {
     createAggregateGroup: "Create Aggregate Group",
     editAggregateGroup: "Edit Aggregate Group",
     aggregateGroup: "Aggregate",
+    maintenance: "Maintenance",
     standardGroup: "Standard Group",
     subGroups: "Sub Groups",
     subGroup: "Sub Group",
@@ -295,6 +306,10 @@ export default {
     customHeaders: "Custom Headers",
     emptyValue: "(empty)",
     paramOverrides: "Parameter Overrides",
+    channelDefinition: "Channel Definition",
+    channelDefinitionTooltip:
+      "Describes the custom channel in JSON: auth injection, path mappings, request/response transforms, error rules and the validation request.",
+    channelDefinitionInvalidJson: "Channel definition must be valid JSON format",
     enterModelName: "Enter model name",
     enterUpstreamUrl: "Enter upstream URL",
     enterValidationPath: "Enter validation endpoint path",
@@ -345,7 +360,10 @@ export default {
     supportedVariables: "Supported variables",
     clientIpVar: "Client IP address",
     groupNameVar: "Group name",
+    groupIdVar: "Group ID",
     apiKeyVar: "Current API key",
+    keyIdVar: "Current API key ID",
+    keyHashVar: "Hash of the current API key, as in request logs",
     timestampMsVar: "Milliseconds timestamp",
     timestampSVar: "Seconds timestamp",
     header: "Header",
@@ -357,6 +375,8 @@ export default {
     willRemoveFromRequest: "Will be removed from request",
     removeToggleTooltip:
       "Enable remove switch to delete this header, disable to add or override this header",
+    secretToggleTooltip:
+      "Secret values, such as gateway credentials, are encrypted at rest and masked when the group is read",
     addHeader: "Add Header",
     paramOverridesTooltip:
       "Define the API request parameters to be overridden using JSON format. These parameters will be merged with the original parameters when sending the request.",
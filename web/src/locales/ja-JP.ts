@@ -68,6 +68,15 @@ export default {
     loginButton: "ログイン",
     loginSuccess: "ログイン成功",
     authKeyRequired: "認証キーを入力してください",
+    welcomeAccountDesc: "続行するには管理者アカウントでログインしてください",
+    usernamePlaceholder: "ユーザー名",
+    passwordPlaceholder: "パスワード",
+    totpCodePlaceholder: "認証アプリの 6 桁のコード",
+    credentialsRequired: "ユーザー名とパスワードを入力してください",
+    totpCodeRequired: "二要素認証コードを入力してください",
+    useAuthKey: "認証キーでログイン",
+    useAccount: "管理者アカウントでログイン",
+    useSSO: "SSO でログイン",
   },
   nav: {
     dashboard: "ダッシュボード",
@@ -97,6 +106,7 @@ export default {
     last7Days: "過去7日間",
     last30Days: "過去30日間",
     systemStatus: "システムステータス",
+    maintenanceGroups: "メンテナンス中のグループ",
     running: "実行中",
     stopped: "停止",
     healthy: "正常",
@@ -114,6 +124,7 @@ export default {
     createAggregateGroup: "集約グループを作成",
     editAggregateGroup: "集約グループを編集",
     aggregateGroup: "集約グループ",
+    maintenance: "メンテナンス中",
     standardGroup: "標準グループ",
     subGroups: "サブグループ",
     subGroup: "サブグループ",
@@ -294,6 +305,10 @@ export default {
     customHeaders: "カスタムヘッダー",
     emptyValue: "(空)",
     paramOverrides: "パラメーターオーバーライド",
+    channelDefinition: "チャンネル定義",
+    channelDefinitionTooltip:
+      "カスタムチャンネルを JSON で記述します：認証の注入、パスマッピング、リクエスト/レスポンス変換、エラールール、検証リクエスト。",
+    channelDefinitionInvalidJson: "チャンネル定義は有効な JSON 形式である必要があります",
     enterModelName: "モデル名を入力してください",
     enterUpstreamUrl: "アップストリームURLを入力してください",
     enterValidationPath: "検証エンドポイントパスを入力してください",
@@ -344,7 +359,10 @@ export default {
     supportedVariables: "サポートされる変数",
     clientIpVar: "クライアントIPアドレス",
     groupNameVar: "グループ名",
+    groupIdVar: "グループID",
     apiKeyVar: "現在のAPIキー",
+    keyIdVar: "現在のAPIキーのID",
+    keyHashVar: "現在のAPIキーのハッシュ（リクエストログと同じ値）",
     timestampMsVar: "ミリ秒タイムスタンプ",
     timestampSVar: "秒タイムスタンプ",
     header: "ヘッダー",
@@ -356,6 +374,8 @@ export default {
     willRemoveFromRequest: "リクエストから削除されます",
     removeToggleTooltip:
       "削除スイッチを有効にするとこのヘッダーを削除、無効にするとこのヘッダーを追加または上書き",
+    secretToggleTooltip:
+      "シークレット値（ゲートウェイの認証情報など）は暗号化して保存され、グループの取得時にはマスクされます",
     addHeader: "ヘッダー追加",
     paramOverridesTooltip:
       "JSON形式を使用して、上書きするAPIリクエストパラメータを定義します。これらのパラメータは、リクエスト送信時に元のパラメータにマージされます。",
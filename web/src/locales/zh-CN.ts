@@ -68,6 +68,15 @@ export default {
     loginButton: "登录",
     loginSuccess: "登录成功",
     authKeyRequired: "请输入授权密钥",
+    welcomeAccountDesc: "请使用管理员账号登录以继续",
+    usernamePlaceholder: "用户名",
+    passwordPlaceholder: "密码",
+    totpCodePlaceholder: "身份验证器应用中的 6 位验证码",
+    credentialsRequired: "请输入用户名和密码",
+    totpCodeRequired: "请输入两步验证码",
+    useAuthKey: "使用授权密钥登录",
+    useAccount: "使用管理员账号登录",
+    useSSO: "使用单点登录",
   },
   nav: {
     dashboard: "仪表盘",
@@ -97,6 +106,7 @@ export default {
     last7Days: "最近7天",
     last30Days: "最近30天",
     systemStatus: "系统状态",
+    maintenanceGroups: "维护中的分组",
     running: "运行中",
     stopped: "已停止",
     healthy: "健康",
@@ -114,6 +124,7 @@ export default {
     createAggregateGroup: "创建聚合分组",
     editAggregateGroup: "编辑聚合分组",
     aggregateGroup: "聚合分组",
+    maintenance: "维护中",
     standardGroup: "标准分组",
     subGroups: "子分组",
     subGroup: "子分组",
@@ -290,6 +301,10 @@ export default {
     customHeaders: "自定义请求头",
     emptyValue: "(空值)",
     paramOverrides: "参数覆盖",
+    channelDefinition: "通道定义",
+    channelDefinitionTooltip:
+      "使用 JSON 描述自定义通道：鉴权注入、路径映射、请求/响应转换、错误分类规则以及验证请求。",
+    channelDefinitionInvalidJson: "通道定义必须是有效的 JSON 格式",
     enterModelName: "请输入模型名称",
     enterUpstreamUrl: "请输入上游地址",
     enterValidationPath: "请输入验证端点路径",
@@ -334,7 +349,10 @@ export default {
     supportedVariables: "支持动态变量",
     clientIpVar: "客户端IP地址",
     groupNameVar: "分组名称",
+    groupIdVar: "分组ID",
     apiKeyVar: "当前轮询的API密钥",
+    keyIdVar: "当前轮询的API密钥ID",
+    keyHashVar: "当前API密钥的哈希，与请求日志中一致",
     timestampMsVar: "毫秒时间戳",
     timestampSVar: "秒时间戳",
     header: "请求头",
@@ -344,6 +362,7 @@ export default {
     headerValuePlaceholder: "支持变量，例如：${CLIENT_IP}",
     willRemoveFromRequest: "将从请求中移除",
     removeToggleTooltip: "开启移除开关将删除此请求头，关闭则添加或覆盖此请求头",
+    secretToggleTooltip: "密钥值（如网关凭证）将加密存储，读取分组时会被遮蔽",
     addHeader: "添加请求头",
     paramOverridesTooltip:
       "使用JSON格式定义要覆盖的API请求参数。这些参数会在发送请求时合并到原始参数中",
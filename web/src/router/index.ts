@@ -49,7 +49,8 @@ router.beforeEach((to, _from, next) => {
     return next({ path: "/login" });
   }
 
-  if (to.path === "/login" && loggedIn) {
+  // 单点登录回调可能在已登录时返回新的会话令牌，交给登录页处理
+  if (to.path === "/login" && loggedIn && !to.hash.includes("sso_")) {
     return next({ path: "/" });
   }
 
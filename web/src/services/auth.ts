@@ -3,6 +3,19 @@ import { useState } from "@/utils/state";
 
 const AUTH_KEY = "authKey";
 
+export interface AccountCredentials {
+  username: string;
+  password: string;
+  totp_code?: string;
+}
+
+export type LoginResult = "success" | "totp_required" | "failed";
+
+export interface AuthOptions {
+  key_login: boolean;
+  sso: boolean;
+}
+
 export const useAuthKey = () => {
   return useState<string | null>(AUTH_KEY, () => null);
 };
@@ -10,11 +23,16 @@ export const useAuthKey = () => {
 export function useAuthService() {
   const authKey = useAuthKey();
 
+  const store = (token: string) => {
+    localStorage.setItem(AUTH_KEY, token);
+    authKey.value = token;
+  };
+
+  // 使用授权密钥登录（AUTH_KEY_LOGIN 启用时可用）
   const login = async (key: string): Promise<boolean> => {
     try {
       await http.post("/auth/login", { auth_key: key });
-      localStorage.setItem(AUTH_KEY, key);
-      authKey.value = key;
+      store(key);
       return true;
     } catch (_error) {
       // 错误已记录
@@ -22,7 +40,61 @@ export function useAuthService() {
     }
   };
 
-  const logout = (): void => {
+  // 使用管理员账号登录，保存返回的会话令牌
+  const loginWithAccount = async (credentials: AccountCredentials): Promise<LoginResult> => {
+    try {
+      const res = (await http.post("/auth/login", credentials)) as unknown as { token: string };
+      store(res.token);
+      return "success";
+    } catch (error) {
+      const data = (error as { response?: { data?: { totp_required?: boolean } } }).response?.data;
+      return data?.totp_required ? "totp_required" : "failed";
+    }
+  };
+
+  // 获取登录页可用的登录方式
+  const getAuthOptions = async (): Promise<AuthOptions> => {
+    try {
+      const res = await http.get("/auth/options");
+      return res.data;
+    } catch (_error) {
+      return { key_login: true, sso: false };
+    }
+  };
+
+  // 跳转到身份提供方进行单点登录
+  const loginWithSSO = (): void => {
+    window.location.href = "/api/auth/oidc/login";
+  };
+
+  // 单点登录回调把会话令牌或失败原因放在 URL 片段中，未携带时返回 null
+  const completeSSOLogin = (): LoginResult | null => {
+    const params = new URLSearchParams(window.location.hash.slice(1));
+    const token = params.get("sso_token");
+    const error = params.get("sso_error");
+    if (!token && !error) {
+      return null;
+    }
+    history.replaceState(null, "", window.location.pathname);
+    if (token) {
+      store(token);
+      return "success";
+    }
+    window.$message.error(error as string);
+    return "failed";
+  };
+
+  // endSession 同时结束服务端会话，失败时令牌仍会在过期后失效
+  const logout = (endSession = false): void => {
+    const token = localStorage.getItem(AUTH_KEY);
+    if (endSession && token?.startsWith("gls_")) {
+      http
+        .post("/auth/logout", null, {
+          hideMessage: true,
+          headers: { Authorization: `Bearer ${token}` },
+        })
+        .catch(() => undefined);
+    }
     localStorage.removeItem(AUTH_KEY);
     authKey.value = null;
   };
@@ -41,6 +113,10 @@ export function useAuthService() {
 
   return {
     login,
+    loginWithAccount,
+    getAuthOptions,
+    loginWithSSO,
+    completeSSOLogin,
     logout,
     checkLogin,
   };
@@ -12,7 +12,7 @@ export type KeyStatus = "active" | "invalid" | undefined;
 export type GroupType = "standard" | "aggregate";
 
 // 渠道类型
-export type ChannelType = "openai" | "openai-response" | "gemini" | "anthropic";
+export type ChannelType = "openai" | "openai-response" | "gemini" | "anthropic" | "custom";
 
 // 数据模型定义
 export interface APIKey {
@@ -31,12 +31,26 @@ export interface APIKey {
 export interface UpstreamInfo {
   url: string;
   weight: number;
+  auth_header?: string;
+  auth_tokens?: string[];
+  proxy_url?: string;
+  transport?: UpstreamTransport;
+}
+
+export interface UpstreamTransport {
+  max_idle_conns?: number;
+  max_idle_conns_per_host?: number;
+  idle_conn_timeout?: number;
+  http_version?: "auto" | "http1" | "http2";
+  tcp_keepalive_seconds?: number;
+  dns_cache_ttl_seconds?: number;
 }
 
 export interface HeaderRule {
   key: string;
   value: string;
   action: "set" | "remove";
+  secret?: boolean;
 }
 
 // 子分组配置（创建/更新时使用）
@@ -70,6 +84,7 @@ export interface Group {
   sort: number;
   test_model: string;
   channel_type: ChannelType;
+  channel_definition?: Record<string, unknown>;
   upstreams: UpstreamInfo[];
   validation_endpoint: string;
   config: Record<string, unknown>;
@@ -80,6 +95,8 @@ export interface Group {
   model_redirect_strict: boolean;
   header_rules?: HeaderRule[];
   proxy_keys: string;
+  enabled?: boolean;
+  maintenance_message?: string;
   group_type?: GroupType;
   sub_groups?: SubGroupInfo[]; // 子分组列表（仅聚合分组）
   sub_group_ids?: number[]; // 子分组ID列表
@@ -233,6 +250,15 @@ export interface DashboardStatsResponse {
   request_count: StatCard;
   error_rate: StatCard;
   security_warnings: SecurityWarning[];
+  maintenance_groups?: MaintenanceGroup[];
+}
+
+// 维护中（已停用）的分组
+export interface MaintenanceGroup {
+  id: number;
+  name: string;
+  display_name: string;
+  maintenance_message: string;
 }
 
 // 图表数据集